@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// centralIndex is the node index of a central agent once it has synced; the
+// admin API pulses only the nodes in it.
+var centralIndex atomic.Pointer[k8s.NodeIndex]
+
+// adminAPI is the agent's local HTTP API on ADMIN_ADDR.
+type adminAPI struct {
+	ctx       context.Context // the agent's; on-demand pulses outlive the request
+	ctrl      *k8s.Controller
+	mode      string
+	node      string // this node in node mode
+	policy    *policy.Policy
+	tokenFile string // ADMIN_TOKEN_FILE; empty disables authentication (loopback only)
+	started   time.Time
+}
+
+// adminConfig is the body of GET /v1/config.
+type adminConfig struct {
+	Mode       string         `json:"mode"`
+	Node       string         `json:"node,omitempty"`
+	Started    time.Time      `json:"started"`
+	Thresholds pulse.Snapshot `json:"thresholds"`
+	// Policy is the POLICY_FILE document; absent for the built-in policy.
+	Policy *policy.Policy `json:"policy,omitempty"`
+	// Settings are the agent settings given, with secrets redacted.
+	Settings map[string]string `json:"settings"`
+}
+
+// Admin server timeouts. Every handler answers at once (a pulse runs in the
+// background), so they only cut off slow or stalled clients.
+const (
+	adminReadHeaderTimeout = 5 * time.Second
+	adminReadTimeout       = 10 * time.Second
+	adminWriteTimeout      = 30 * time.Second
+	adminIdleTimeout       = 2 * time.Minute
+)
+
+// pulseAccepted is the body of a 202 from POST /v1/pulse.
+type pulseAccepted struct {
+	Node    string    `json:"node"`
+	Started time.Time `json:"started"`
+}
+
+// serveAdmin runs the ADMIN_ADDR API until ctx is cancelled:
+//
+//	GET  /v1/last-report?node=  the node's latest fresh pulse result
+//	POST /v1/pulse?node=        pulse the node now; 202, or 409 if one is running
+//	GET  /v1/config             thresholds, policy and settings in effect
+//
+// node defaults to this node in node mode, where no other node is accepted.
+// In central mode it is required and must be one of the replica's nodes.
+func serveAdmin(ctx context.Context, addr string, api *adminAPI) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/last-report", api.lastReport)
+	mux.HandleFunc("POST /v1/pulse", api.pulse)
+	mux.HandleFunc("GET /v1/config", api.config)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           api.authenticate(mux),
+		ReadHeaderTimeout: adminReadHeaderTimeout,
+		ReadTimeout:       adminReadTimeout,
+		WriteTimeout:      adminWriteTimeout,
+		IdleTimeout:       adminIdleTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			slog.Error("admin server shutdown error", "err", err)
+		}
+	}()
+
+	slog.Info("admin server listening", "addr", addr, "mode", api.mode)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("admin server failed", "err", err)
+	}
+}
+
+// loopbackAddr reports whether addr listens only on the loopback interface,
+// the one place the admin and debug servers may run without a token.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authenticate requires the bearer token in tokenFile, re-read on every
+// request so a rotated secret applies without a restart.
+func (a *adminAPI) authenticate(next http.Handler) http.Handler {
+	return requireToken("admin", a.tokenFile, next)
+}
+
+// requireToken wraps next in a check for the bearer token in tokenFile,
+// re-read on every request. An empty tokenFile disables the check; main only
+// allows that on a loopback address (see loopbackAddr). server names the
+// server in logs and errors.
+func requireToken(server, tokenFile string, next http.Handler) http.Handler {
+	if tokenFile == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			slog.Error("failed to read "+server+" token", "path", tokenFile, "err", err)
+			http.Error(w, server+" token unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		want := strings.TrimSpace(string(token))
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// target resolves the node a request is for, writing the error response and
+// returning "" if it is not one this agent pulses.
+func (a *adminAPI) target(w http.ResponseWriter, r *http.Request) string {
+	node := r.URL.Query().Get("node")
+	if a.mode == "node" {
+		if node != "" && node != a.node {
+			http.Error(w, "this agent only pulses "+a.node, http.StatusNotFound)
+			return ""
+		}
+		return a.node
+	}
+	if node == "" {
+		http.Error(w, "node is required in central mode", http.StatusBadRequest)
+		return ""
+	}
+	index := centralIndex.Load()
+	if index == nil {
+		http.Error(w, "node index not synced yet", http.StatusServiceUnavailable)
+		return ""
+	}
+	if !index.Has(node) {
+		http.Error(w, "node "+node+" is not pulsed by this replica", http.StatusNotFound)
+		return ""
+	}
+	return node
+}
+
+func (a *adminAPI) lastReport(w http.ResponseWriter, r *http.Request) {
+	node := a.target(w, r)
+	if node == "" {
+		return
+	}
+	v, ok := lastPulses.Load(node)
+	if !ok {
+		http.Error(w, "no pulse recorded for "+node+" since the agent started", http.StatusNotFound)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, v.(lastPulse))
+}
+
+// pulse runs ValidateNode on the node under its pulse lock, in the background:
+// poll /v1/last-report for the result.
+func (a *adminAPI) pulse(w http.ResponseWriter, r *http.Request) {
+	node := a.target(w, r)
+	if node == "" {
+		return
+	}
+	unlock, ok := lockNode(node)
+	if !ok {
+		http.Error(w, "pulse already in progress on "+node, http.StatusConflict)
+		return
+	}
+	accepted := pulseAccepted{Node: node, Started: time.Now().UTC()}
+	slog.Info("on-demand GPU pulse requested", "node", node, "remote", r.RemoteAddr)
+	go func() {
+		defer unlock()
+		if err := a.ctrl.ValidateNode(a.ctx, node); err != nil {
+			slog.Error("on-demand pulse failed", "node", node, "err", err)
+		}
+	}()
+	writeAdminJSON(w, http.StatusAccepted, accepted)
+}
+
+func (a *adminAPI) config(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, adminConfig{
+		Mode:       a.mode,
+		Node:       a.node,
+		Started:    a.started,
+		Thresholds: pulse.Active().Snapshot(),
+		Policy:     a.policy,
+		Settings:   givenSettings(os.Environ()),
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Warn("failed to write admin response", "err", err)
+	}
+}
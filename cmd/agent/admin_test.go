@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequireToken(t *testing.T) {
+	t.Parallel()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	cases := []struct {
+		name      string
+		tokenFile string
+		header    string
+		want      int
+	}{
+		{name: "no token file", want: http.StatusNoContent},
+		{name: "right token", tokenFile: tokenFile, header: "Bearer s3cret", want: http.StatusNoContent},
+		{name: "wrong token", tokenFile: tokenFile, header: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "no header", tokenFile: tokenFile, want: http.StatusUnauthorized},
+		{name: "unreadable token file", tokenFile: filepath.Join(t.TempDir(), "missing"), header: "Bearer s3cret", want: http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/cmdline", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		requireToken("debug", tc.tokenFile, ok).ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestLoopbackAddr(t *testing.T) {
+	t.Parallel()
+
+	for addr, want := range map[string]bool{
+		"localhost:6060": true,
+		"127.0.0.1:6060": true,
+		"[::1]:6060":     true,
+		":6060":          false,
+		"0.0.0.0:6060":   false,
+		"10.0.0.5:6060":  false,
+		"localhost":      false,
+	} {
+		if got := loopbackAddr(addr); got != want {
+			t.Errorf("loopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// inFlight maps each node with a pulse, audit or retry running under
+// withNodeLock to when it took the lock.
+var inFlight sync.Map
+
+// watchStatus is the node watch's progress, for /debug/state.
+var watchStatus struct {
+	sync.Mutex
+	synced    bool
+	syncedAt  time.Time
+	lastEvent time.Time
+	events    int64
+}
+
+// watchSynced records that the initial node list is cached.
+func watchSynced() {
+	watchStatus.Lock()
+	defer watchStatus.Unlock()
+	watchStatus.synced, watchStatus.syncedAt = true, time.Now().UTC()
+}
+
+// watchEvent records a node event delivered by the watch.
+func watchEvent() {
+	watchStatus.Lock()
+	defer watchStatus.Unlock()
+	watchStatus.lastEvent = time.Now().UTC()
+	watchStatus.events++
+}
+
+// lastPulses holds the latest fresh pulse result per node; see recordPulse.
+var lastPulses sync.Map
+
+// lastPulse is a node's latest fresh pulse result in /debug/state.
+type lastPulse struct {
+	At     time.Time          `json:"at"`
+	Reason string             `json:"reason,omitempty"` // failure_reason; empty for a pass
+	Error  string             `json:"error,omitempty"`
+	Report *pulse.PulseReport `json:"report,omitempty"`
+}
+
+// recordPulse is a k8s.ReportSink keeping each node's latest result.
+func recordPulse(_ context.Context, node *corev1.Node, report *pulse.PulseReport, reason string, err error) {
+	lp := lastPulse{At: time.Now().UTC(), Reason: reason, Report: report}
+	if err != nil {
+		lp.Error = err.Error()
+	}
+	lastPulses.Store(node.Name, lp)
+}
+
+// debugState is the body of /debug/state.
+type debugState struct {
+	Mode    string    `json:"mode"`
+	Started time.Time `json:"started"`
+	// InFlight lists the nodes holding their pulse lock, with how long.
+	InFlight []lockHolder `json:"in_flight"`
+	// Stages lists the timed CUDA stage calls running in this process,
+	// abandoned ones included. Subprocess and pod runners are not seen.
+	Stages []pulse.StageCall    `json:"stages"`
+	Watch  watchState           `json:"watch"`
+	Last   map[string]lastPulse `json:"last_pulse"`
+	Retry  []string             `json:"retries_scheduled"`
+}
+
+type lockHolder struct {
+	Node    string    `json:"node"`
+	Since   time.Time `json:"since"`
+	Seconds float64   `json:"seconds"`
+}
+
+type watchState struct {
+	Synced    bool      `json:"synced"`
+	SyncedAt  time.Time `json:"synced_at,omitempty"`
+	LastEvent time.Time `json:"last_event,omitempty"`
+	Events    int64     `json:"events"`
+}
+
+// snapshotState collects /debug/state.
+func snapshotState(mode string, started time.Time) debugState {
+	s := debugState{Mode: mode, Started: started, Stages: pulse.InFlightStages(), Last: map[string]lastPulse{}}
+	now := time.Now()
+	inFlight.Range(func(k, v any) bool {
+		since := v.(time.Time)
+		s.InFlight = append(s.InFlight, lockHolder{Node: k.(string), Since: since.UTC(), Seconds: now.Sub(since).Seconds()})
+		return true
+	})
+	sort.Slice(s.InFlight, func(i, j int) bool { return s.InFlight[i].Since.Before(s.InFlight[j].Since) })
+	lastPulses.Range(func(k, v any) bool {
+		s.Last[k.(string)] = v.(lastPulse)
+		return true
+	})
+	retries.Range(func(k, _ any) bool {
+		s.Retry = append(s.Retry, k.(string))
+		return true
+	})
+	sort.Strings(s.Retry)
+
+	watchStatus.Lock()
+	s.Watch = watchState{
+		Synced:    watchStatus.synced,
+		SyncedAt:  watchStatus.syncedAt,
+		LastEvent: watchStatus.lastEvent,
+		Events:    watchStatus.events,
+	}
+	watchStatus.Unlock()
+	return s
+}
+
+// Debug server timeouts. pprof refuses a CPU profile or trace longer than
+// the write timeout, so it caps those at just under two minutes; the default
+// 30s profile fits.
+const (
+	debugReadHeaderTimeout = 5 * time.Second
+	debugReadTimeout       = 10 * time.Second
+	debugWriteTimeout      = 2 * time.Minute
+	debugIdleTimeout       = 2 * time.Minute
+)
+
+// serveDebug runs the DEBUG_ADDR server until ctx is cancelled: the
+// net/http/pprof profiles under /debug/pprof/ and the agent's internal state
+// as JSON at /debug/state. It is kept off the metrics port so it can be bound
+// to localhost and reached with kubectl port-forward only. With tokenFile set
+// every request needs its bearer token (see requireToken): the profiles
+// include the command line, which can carry secrets given as flags.
+func serveDebug(ctx context.Context, addr, mode, tokenFile string) {
+	started := time.Now().UTC()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/state", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(snapshotState(mode, started)); err != nil {
+			slog.Warn("failed to write debug state", "err", err)
+		}
+	})
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           requireToken("debug", tokenFile, mux),
+		ReadHeaderTimeout: debugReadHeaderTimeout,
+		ReadTimeout:       debugReadTimeout,
+		WriteTimeout:      debugWriteTimeout,
+		IdleTimeout:       debugIdleTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			slog.Error("debug server shutdown error", "err", err)
+		}
+	}()
+
+	slog.Info("debug server listening", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("debug server failed", "err", err)
+	}
+}
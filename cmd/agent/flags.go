@@ -0,0 +1,458 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/chaos"
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// setting is one agent configuration variable. Every setting is read from its
+// environment variable and can also be given as a flag (the variable name in
+// lower case with dashes, e.g. --pulse-threshold-ms), so Helm charts can pass
+// configuration as container args. A flag wins over the variable.
+type setting struct {
+	env   string
+	check func(string) error // nil accepts any value
+	usage string
+
+	// thresholds marks settings resolved into pulse.Active at package init;
+	// setting one by flag re-resolves them.
+	thresholds bool
+	// internal settings are set by the agent for its runners and have no flag.
+	internal bool
+	// secret settings are redacted from the admin API's GET /v1/config.
+	secret bool
+}
+
+// settings is every environment variable the agent reads.
+var settings = []setting{
+	{env: "AGENT_MODE", check: oneOf("node", "central"), usage: "node (one agent per GPU node) or central (runner pods)"},
+	{env: "NODE_NAME", usage: "this node's name; node mode, from the downward API"},
+	{env: "GPU_NODE_SELECTOR", usage: "label selector for GPU nodes in central mode (default nvidia.com/gpu.present=true)"},
+	{env: "GPU_NODE_FIELD_SELECTOR", usage: "field selector narrowing the GPU nodes in central mode"},
+	{env: "GPU_POOL_LABEL", usage: "node label grouping GPU nodes into pools in central mode (default node.kubernetes.io/instance-type)"},
+	{env: "PULSE_DOMAIN_LABEL", usage: "node label naming the rack or power domain for PULSE_MAX_IN_FLIGHT_PER_DOMAIN"},
+	{env: "PULSE_MAX_IN_FLIGHT_PER_DOMAIN", check: positiveInt, usage: "most pulses running at once per domain (default unlimited)"},
+	{env: "PULSE_MAX_IN_FLIGHT", check: positiveInt, usage: "most pulses running at once in total (default unlimited)"},
+	{env: "PULSE_PRIORITY_ORDER", check: priorityCriteria, usage: "order queued pulses by these criteria: pool, pending-jobs, nvlink-domain (default first come first served)"},
+	{env: "PULSE_POOL_PRIORITY", check: poolWeights, usage: "pool=weight pairs for the pool criterion, higher first"},
+	{env: "PULSE_PRIORITY_MIN_POD_PRIORITY", check: integer, usage: "lowest pod priority counted as a pending job (default 1)"},
+	{env: "PULSE_NVLINK_DOMAIN_LABEL", usage: "node label naming the NVLink domain (default nvidia.com/gpu.clique)"},
+	{env: "CENTRAL_SHARDS", check: positiveInt, usage: "hash-shard GPU nodes across this many central controller replicas (default 1)"},
+	{env: "CENTRAL_SHARD", check: nonNegativeInt, usage: "this replica's shard (default: the ordinal suffix of POD_NAME)"},
+	{env: "NODE_LIST_PAGE_SIZE", check: positiveInt, usage: "nodes per page when central mode lists GPU nodes (default 500)"},
+	{env: "POD_NAMESPACE", usage: "namespace for runner pods and shard leases (default straggler-shield)"},
+	{env: "POD_NAME", usage: "replica identity in shard leases; its ordinal suffix is the default CENTRAL_SHARD"},
+	{env: "POLICY_FILE", usage: "path of the decision policy file"},
+	{env: "READER_TOKEN_FILE", usage: "file holding a read-only API token every read is made with; the pod's own service account then needs only the writes"},
+
+	{env: "PULSE_ISOLATION", check: oneOf("inprocess", "subprocess", "pod", "sim"), usage: "where the pulse runs: inprocess, subprocess or pod; sim simulates it for tests"},
+	{env: "PULSE_RUNNER_PATH", usage: "standalone pulse-runner binary for subprocess isolation"},
+	{env: "PULSE_RUNNER_TIMEOUT", check: positiveDuration, usage: "kill a subprocess pulse runner still running after this long (default 10m)"},
+	{env: "FAULT_INJECTION", check: faultSpec, usage: "staging only: fault=rate pairs to inject (api_error, patch_conflict, pulse_hang, pulse_crash) and pulse_hang_for"},
+	{env: "PULSE_POD_IMAGE", usage: "runner pod image for pod isolation"},
+	{env: "PULSE_POD_GPUS", check: nonNegativeInt, usage: "nvidia.com/gpu requested by runner pods"},
+	{env: "PULSE_POD_TIMEOUT", check: positiveDuration, usage: "runner pod timeout"},
+	{env: "CANARY_IMAGE", usage: "PyTorch image for two-node training canaries after turn-up; central mode (default disabled)"},
+	{env: "CANARY_MAX_STEP_TIME", check: positiveDuration, usage: "slowest acceptable median canary training step (default 1s)"},
+	{env: "CANARY_STEPS", check: positiveInt, usage: "timed canary training steps (default 50)"},
+	{env: "CANARY_PAIR_WAIT", check: positiveDuration, usage: "how long a validated node waits for a canary partner (default 10m)"},
+	{env: "CANARY_TIMEOUT", check: positiveDuration, usage: "canary job timeout (default 15m)"},
+	{env: "PULSE_RESULT_FRESHNESS", check: positiveDuration, usage: "reuse a node's pulse result this young"},
+	{env: "PULSE_MEASURE_NICE", check: niceValue, usage: "nice value for GEMM measurement threads, -20 to 19; negative needs CAP_SYS_NICE (default 0: unchanged)"},
+	{env: "PULSE_STATE_DIR", usage: "host directory for persistent state (default /var/lib/straggler-shield)"},
+	{env: pulse.ExternalChecksEnv, check: externalChecks, usage: "comma-separated external check executables, or .wasm modules run sandboxed, run on every pulse (see README: External checks)"},
+	{env: pulse.WASMMemoryEnv, check: positiveInt, usage: "memory limit for each WASM check, in MiB (default 64)"},
+	{env: pulse.ClockResetEnv, check: oneOf("true", "false"), usage: "reset stuck GPU clocks and check them again before quarantining (default true)"},
+	{env: pulse.MemoryReserveEnv, check: nonNegativeInt, usage: "container memory in MiB a pulse needs beyond what the agent uses, lowering GOMEMLIMIT and failing a pulse inconclusive when not free; 0 disables (default 256)"},
+	{env: pulse.CooldownRetriesEnv, check: nonNegativeInt, usage: "pre-flight checks run again while GPUs too hot at idle cool down; 0 fails them at once (default 3)"},
+	{env: pulse.CooldownWaitEnv, check: positiveDuration, usage: "wait before each cool-down check (default 2m)"},
+	{env: pulse.RemediationEnv, check: oneOf(pulse.RemediationGPUReset, pulse.RemediationDriverReload), usage: "on a failure that would quarantine, run gpu-reset or driver-reload and pulse again (default none)"},
+	{env: "PULSE_DEVICE_LABEL", check: oneOf(pulse.DeviceLabelIndex, pulse.DeviceLabelUUID), usage: "device label on per-device metrics: index or uuid (default index)"},
+
+	{env: "PULSE_THRESHOLD_MS", check: positiveInt, thresholds: true, usage: "mean GEMM latency ceiling in ms (default: detected per architecture)"},
+	{env: "PULSE_CV_MAX", check: positiveFloat, thresholds: true, usage: "coefficient of variation ceiling (default: calibrated per architecture)"},
+	{env: pulse.ArchTableEnv, check: archTable, thresholds: true, usage: "YAML or JSON file of per-architecture calibrations, matched before the built-in table"},
+	{env: "PULSE_BASELINE_FACTOR", check: baselineFactor, thresholds: true, usage: "fail a GPU slower than this multiple of the node's recorded baseline; 0 disables (default 2)"},
+	{env: "PULSE_JITTER_FLOOR", check: nonNegativeDuration, thresholds: true, usage: "run-to-run standard deviation below which CV is ignored; 0 disables (default 250µs)"},
+	{env: "PULSE_MAX_HOST_LOAD", check: nonNegativeFloat, thresholds: true, usage: "1-minute load average per CPU above which GEMM runs count as contaminated; 0 disables (default 1)"},
+	{env: "PULSE_MAX_CPU_STEAL", check: fraction, thresholds: true, usage: "CPU steal fraction above which GEMM runs count as contaminated; 0 disables (default 0.1)"},
+	{env: "P2P_MIN_GBS", check: positiveFloat, thresholds: true, usage: "minimum P2P bandwidth in GB/s (default 5)"},
+	{env: "IDLE_TEMP_MAX", check: positiveInt, thresholds: true, usage: "pre-flight idle temperature ceiling in °C (default 70)"},
+	{env: "PULSE_MIN_SM_UTIL", check: fraction, thresholds: true, usage: "peak SM utilization every GPU must reach during its GEMM runs, or the pulse is inconclusive; 0 disables (default 0)"},
+	{env: "PULSE_MIN_POWER_FRACTION", check: fraction, thresholds: true, usage: "fraction of its default power limit every GPU must draw at its peak during its GEMM runs; 0 disables (default 0, or the architecture table's)"},
+	{env: "PULSE_DEGRADED_FRACTION", check: positiveFloat, thresholds: true, usage: "degraded band as a fraction of each limit (default 0.8)"},
+	{env: "PULSE_TIMEOUT_PREFLIGHT", check: positiveDuration, thresholds: true, usage: "pre-flight stage timeout (default 30s)"},
+	{env: "PULSE_TIMEOUT_GEMM_RUN", check: positiveDuration, thresholds: true, usage: "single GEMM run timeout (default 60s)"},
+	{env: "PULSE_TIMEOUT_P2P_LINK", check: positiveDuration, thresholds: true, usage: "single P2P link timeout (default 30s)"},
+	{env: "PULSE_XID_CODES", check: xidCodes, usage: "comma-separated XID codes in the kernel log that quarantine, or none (default 48,61,62,64,74,79,92,95,119,120)"},
+	{env: "PULSE_TIMEOUT_CLOCK_CHECK", check: positiveDuration, thresholds: true, usage: "post-pulse clock check timeout (default 30s)"},
+	{env: "PULSE_TIMEOUT_EXTERNAL_CHECK", check: positiveDuration, thresholds: true, usage: "timeout for each external check (default 5m)"},
+
+	{env: "READY_WINDOW_SECONDS", check: positiveInt, usage: "pulse nodes whose Ready transition is this recent, in seconds (default 300); policy readyWindows overrides it per pool"},
+	{env: "PERIODIC_PULSE_INTERVAL", check: positiveDuration, usage: "re-pulse steady-state nodes this often"},
+	{env: "PERIODIC_MAX_STALENESS", check: positiveDuration, usage: "force a periodic pulse after this long (default 168h)"},
+	{env: "PERIODIC_RETRY_INTERVAL", check: positiveDuration, usage: "re-check for an idle gap this often (default 15m)"},
+	{env: "PULSE_BUSY_GUARD", check: oneOf("true", "false"), usage: "defer Ready-triggered pulses while GPU pods run on the node (default true)"},
+	{env: "PULSE_BUSY_RETRY_INTERVAL", check: positiveDuration, usage: "re-check a busy node for a deferred pulse this often (default 2m)"},
+	{env: "PULSE_BUSY_MAX_DEFER", check: positiveDuration, usage: "run a deferred pulse anyway after this long (default 6h)"},
+	{env: "PULSE_INCONCLUSIVE_RETRY_INTERVAL", check: positiveDuration, usage: "retry an inconclusive pulse after this long when the policy sets inconclusive: retry (default 10m)"},
+	{env: "DCGM_EXPORTER_URL", usage: "dcgm-exporter metrics URL for idle detection"},
+	{env: "DCGM_IDLE_UTIL_MAX", check: nonNegativeFloat, usage: "GPU utilisation % below which the node counts as idle (default 5)"},
+
+	{env: "QUARANTINE_RECHECK_INTERVAL", check: positiveDuration, usage: "re-pulse quarantined nodes this often"},
+	{env: "QUARANTINE_RECHECK_MAX_INTERVAL", check: positiveDuration, usage: "backoff ceiling for quarantine rechecks (default 6h)"},
+	{env: "QUARANTINE_CLEAR_PASSES", check: positiveInt, usage: "consecutive passes that clear a quarantine (default 3)"},
+	{env: "MARKER_AUDIT_INTERVAL", check: positiveDuration, usage: "check node taints, conditions, labels and annotations against the latest decision this often and repair drift"},
+	{env: "QUARANTINE_REQUIRED_FAILURES", check: positiveInt, usage: "consecutive straggler failures that quarantine a node (default 1)"},
+	{env: "QUARANTINE_MODE", check: oneOf("taint", "cordon"), usage: "quarantine with the taint or by cordoning the node (default taint)"},
+	{env: "QUARANTINE_TAINT_KEY", usage: "quarantine taint key"},
+	{env: "QUARANTINE_TAINT_VALUE", usage: "quarantine taint value (default: reason-measured-threshold-pulse code)"},
+	{env: "QUARANTINE_TAINT_EFFECT", check: oneOf("NoSchedule", "PreferNoSchedule", "NoExecute"), usage: "quarantine taint effect (default NoSchedule)"},
+	{env: "QUARANTINE_PREVIOUS_TAINT_KEYS", usage: "comma-separated earlier taint keys to migrate"},
+	{env: "QUARANTINE_EVICT_GPU_PODS", check: oneOf("true", "false"), usage: "evict GPU pods from hard-quarantined nodes (default false)"},
+	{env: "QUARANTINE_EVICTION_GRACE_PERIOD", check: positiveDuration, usage: "termination grace period for evicted pods (default: the pod's own)"},
+	{env: "QUARANTINE_MAX_PER_HOUR", check: positiveInt, usage: "most nodes quarantined cluster-wide within an hour; later failures are logged and alerted instead (default unlimited)"},
+	{env: "QUARANTINE_MAX_PERCENT", check: percentage, usage: "most GPU nodes quarantined at once, as a percentage (default unlimited)"},
+	{env: "QUARANTINE_GUARD_CONFIGMAP", usage: "ConfigMap in POD_NAMESPACE sharing the QUARANTINE_MAX_PER_HOUR window (default straggler-shield-quarantine-guard)"},
+	{env: "QUARANTINE_EVICTION_TIMEOUT", check: positiveDuration, usage: "how long to retry evictions blocked by a PodDisruptionBudget (default 10m)"},
+
+	{env: "RECONCILE_BUDGET", check: positiveDuration, usage: "log a phase breakdown for validating reconciles slower than this (default 60s)"},
+	{env: "DEBUG_ADDR", check: hostPort, usage: "serve pprof and the agent's internal state on this address, e.g. localhost:6060 (default off)"},
+	{env: "DEBUG_TOKEN_FILE", usage: "file holding the bearer token the debug server requires, re-read on every request (required unless DEBUG_ADDR is a loopback address)"},
+	{env: "ADMIN_ADDR", check: hostPort, usage: "serve the admin API (last report, on-demand pulse, config) on this address, e.g. localhost:8081 (default off)"},
+	{env: "ADMIN_TOKEN_FILE", usage: "file holding the bearer token the admin API requires, re-read on every request (default no authentication)"},
+	{env: "PULSE_RESULT_HISTORY", check: positiveInt, usage: "write a PulseResult per pulse and keep this many per node (needs the PulseResult CRD)"},
+
+	{env: "AGGREGATOR_URL", usage: "fleet aggregator base URL to push pulse reports to"},
+	{env: "AGGREGATOR_CLUSTER", usage: "cluster name reported to the aggregator (required with AGGREGATOR_URL)"},
+	{env: "AGGREGATOR_TOKEN_FILE", usage: "file holding the bearer token reports are pushed to AGGREGATOR_URL with, re-read on every push"},
+	{env: "AGGREGATOR_RACK_LABEL", usage: "node label holding the rack name (default topology.kubernetes.io/rack)"},
+	{env: "RESULT_WEBHOOK_URL", secret: true, usage: "URL to POST every fresh pulse result to as JSON"},
+	{env: "RESULT_WEBHOOK_EVENTS", check: oneOf("all", "failures"), usage: "results sent to RESULT_WEBHOOK_URL: all or failures (default all)"},
+	{env: "NOTIFY_WEBHOOK_URL", secret: true, usage: "URL to POST each quarantine and clear to as JSON"},
+	{env: "NOTIFY_WEBHOOK_TEMPLATE", usage: "Go template file rendering the NOTIFY_WEBHOOK_URL body (default the transition as JSON)"},
+	{env: "NOTIFY_SLACK_URL", secret: true, usage: "Slack incoming webhook URL for quarantines and clears"},
+	{env: "NOTIFY_PAGERDUTY_ROUTING_KEY", secret: true, usage: "PagerDuty Events API v2 routing key; quarantines trigger an incident per node and clears resolve it"},
+	{env: "NOTIFY_PAGERDUTY_URL", usage: "PagerDuty Events API endpoint (default https://events.pagerduty.com/v2/enqueue)"},
+	{env: "SLURM_REST_URL", usage: "slurmrestd base URL; quarantines drain the node in Slurm and clears resume it"},
+	{env: "SLURM_REST_API_VERSION", usage: "slurmrestd API version in the request path (default v0.0.40)"},
+	{env: "SLURM_USER", usage: "Slurm operator user sent as X-SLURM-USER-NAME"},
+	{env: "SLURM_JWT_FILE", usage: "file holding the JWT sent as X-SLURM-USER-TOKEN, re-read on every call"},
+	{env: "DELIVERY_MAX_ATTEMPTS", check: positiveInt, usage: "attempts per aggregator or webhook notification before it is dead-lettered (default 5)"},
+	{env: "DELIVERY_MAX_BACKOFF", check: positiveDuration, usage: "longest wait between notification retries (default 1m)"},
+	{env: "DELIVERY_DEAD_LETTER_DIR", usage: "directory for undeliverable notifications, retried at startup (default PULSE_STATE_DIR in node mode, none in central mode)"},
+
+	{env: pulse.PulseIDEnv, internal: true},
+	{env: pulse.ExpectedGPUsEnv, check: nonNegativeInt, internal: true},
+}
+
+func init() { addArchSettings() }
+
+// addArchSettings adds a PULSE_CV_MAX_<ARCH> setting for each architecture
+// key not yet known. It runs again once flags are applied, as a
+// PULSE_ARCH_TABLE flag can name keys the built-in table lacks.
+func addArchSettings() {
+	for _, arch := range pulse.ArchKeys() {
+		env := pulse.CVOverrideEnv(arch)
+		if slices.ContainsFunc(settings, func(s setting) bool { return s.env == env }) {
+			continue
+		}
+		settings = append(settings, setting{
+			env: env, check: positiveFloat, thresholds: true,
+			usage: "CV ceiling on " + arch + " GPUs, overriding PULSE_CV_MAX and the calibration",
+		})
+	}
+}
+
+// strictPrefixes are the variable prefixes the agent owns. A variable with
+// one of them that is not in settings is rejected as a likely typo.
+var strictPrefixes = []string{"PULSE_", "QUARANTINE_", "PERIODIC_"}
+
+// flagName is the flag mirroring env.
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// applyFlags parses args, exports each flag given to its environment variable
+// and then validates the whole environment (see validateEnv). Settings read at
+// package init are re-resolved if a flag changed them.
+func applyFlags(args []string) error {
+	fs := flag.NewFlagSet("straggler-shield", flag.ContinueOnError)
+	values := make(map[string]*string)
+	for _, s := range settings {
+		if !s.internal {
+			values[s.env] = fs.String(flagName(s.env), "", s.usage+" (env "+s.env+")")
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	reload := false
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if flagName(s.env) == f.Name {
+				err = errors.Join(err, os.Setenv(s.env, *values[s.env]))
+				reload = reload || s.thresholds
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	addArchSettings()
+	if err := validateEnv(os.Environ()); err != nil {
+		return err
+	}
+	if reload {
+		if err := pulse.Active().Set(pulse.EnvSnapshot()); err != nil {
+			return fmt.Errorf("pulse thresholds: %w", err)
+		}
+	}
+	return nil
+}
+
+// givenSettings returns the settings set in environ (os.Environ form), with
+// secret values redacted.
+func givenSettings(environ []string) map[string]string {
+	known := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		known[s.env] = s
+	}
+	given := make(map[string]string)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		s, ok := known[name]
+		if !ok || value == "" {
+			continue
+		}
+		if s.secret {
+			value = "<redacted>"
+		}
+		given[name] = value
+	}
+	return given
+}
+
+// validateEnv checks every setting in environ (os.Environ form) and rejects
+// unknown variables under strictPrefixes, suggesting the closest known name.
+// Empty values mean unset. All problems are reported together.
+func validateEnv(environ []string) error {
+	known := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		known[s.env] = s
+	}
+
+	var errs []error
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		s, ok := known[name]
+		if !ok {
+			if hasStrictPrefix(name) {
+				errs = append(errs, unknownSetting(name))
+			}
+			continue
+		}
+		if value == "" || s.check == nil {
+			continue
+		}
+		if err := s.check(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q: %w", name, value, err))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+func hasStrictPrefix(name string) bool {
+	for _, p := range strictPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownSetting reports an unrecognised variable, naming the closest known
+// one when it is within a few edits.
+func unknownSetting(name string) error {
+	best, bestDist := "", 4
+	for _, s := range settings {
+		if d := editDistance(name, s.env); d < bestDist {
+			best, bestDist = s.env, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown setting %s (did you mean %s?)", name, best)
+	}
+	return fmt.Errorf("unknown setting %s", name)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func positiveDuration(s string) error {
+	if v, err := time.ParseDuration(s); err != nil || v <= 0 {
+		return errors.New("want a positive duration")
+	}
+	return nil
+}
+
+func nonNegativeDuration(s string) error {
+	if v, err := time.ParseDuration(s); err != nil || v < 0 {
+		return errors.New("want a non-negative duration")
+	}
+	return nil
+}
+
+func positiveInt(s string) error {
+	if v, err := strconv.Atoi(s); err != nil || v <= 0 {
+		return errors.New("want a positive integer")
+	}
+	return nil
+}
+
+func integer(s string) error {
+	if _, err := strconv.ParseInt(s, 10, 32); err != nil {
+		return errors.New("want an integer")
+	}
+	return nil
+}
+
+func nonNegativeInt(s string) error {
+	if v, err := strconv.Atoi(s); err != nil || v < 0 {
+		return errors.New("want a non-negative integer")
+	}
+	return nil
+}
+
+func positiveFloat(s string) error {
+	if v, err := strconv.ParseFloat(s, 64); err != nil || v <= 0 {
+		return errors.New("want a positive number")
+	}
+	return nil
+}
+
+func nonNegativeFloat(s string) error {
+	if v, err := strconv.ParseFloat(s, 64); err != nil || v < 0 {
+		return errors.New("want a non-negative number")
+	}
+	return nil
+}
+
+// externalChecks rejects WASM checks in a binary that cannot run them; the
+// pulse would only ever be inconclusive.
+func externalChecks(s string) error {
+	if pulse.WASMSupported {
+		return nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		if strings.HasSuffix(strings.TrimSpace(p), ".wasm") {
+			return errors.New("lists a .wasm check, but this binary was built without the wazero tag")
+		}
+	}
+	return nil
+}
+
+func percentage(s string) error {
+	if v, err := strconv.ParseFloat(s, 64); err != nil || v <= 0 || v > 100 {
+		return errors.New("want a percentage above 0 and at most 100")
+	}
+	return nil
+}
+
+func fraction(s string) error {
+	if v, err := strconv.ParseFloat(s, 64); err != nil || v < 0 || v > 1 {
+		return errors.New("want a number from 0 to 1")
+	}
+	return nil
+}
+
+func niceValue(s string) error {
+	if v, err := strconv.Atoi(s); err != nil || v < -20 || v > 19 {
+		return errors.New("want an integer from -20 to 19")
+	}
+	return nil
+}
+
+func baselineFactor(s string) error {
+	if v, err := strconv.ParseFloat(s, 64); err != nil || (v != 0 && v <= 1) {
+		return errors.New("want a number above 1, or 0 to disable")
+	}
+	return nil
+}
+
+func archTable(s string) error {
+	_, err := pulse.LoadArchTable(s)
+	return err
+}
+
+func xidCodes(s string) error {
+	_, err := pulse.ParseXIDCodes(s)
+	return err
+}
+
+func priorityCriteria(s string) error {
+	_, err := k8s.ParsePriorityCriteria(s)
+	return err
+}
+
+func poolWeights(s string) error {
+	_, err := k8s.ParsePoolWeights(s)
+	return err
+}
+
+func faultSpec(s string) error {
+	_, err := chaos.Parse(s)
+	return err
+}
+
+func hostPort(s string) error {
+	if _, port, err := net.SplitHostPort(s); err != nil || port == "" {
+		return fmt.Errorf("want host:port, e.g. localhost:6060")
+	}
+	return nil
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(s string) error {
+		if !slices.Contains(values, s) {
+			return fmt.Errorf("want one of %s", strings.Join(values, ", "))
+		}
+		return nil
+	}
+}
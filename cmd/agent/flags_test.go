@@ -0,0 +1,140 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateEnv(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		environ []string
+		want    []string // substrings of the error; none for a valid environment
+	}{
+		{name: "known settings", environ: []string{"PULSE_THRESHOLD_MS=40", "QUARANTINE_MODE=cordon", "HOME=/root"}},
+		{name: "variables outside the strict prefixes are ignored", environ: []string{"AGGREGATOR_TYPO=1", "KUBERNETES_SERVICE_HOST=10.0.0.1"}},
+		{name: "empty means unset", environ: []string{"PULSE_THRESHOLD_MS=", "QUARANTINE_MODE="}},
+		{name: "typo suggests the closest setting", environ: []string{"PULSE_THRESHOLD=40"}, want: []string{"unknown setting PULSE_THRESHOLD (did you mean PULSE_THRESHOLD_MS?)"}},
+		{name: "unknown prefix without a close match", environ: []string{"PERIODIC_SOMETHING_ELSE_ENTIRELY=1"}, want: []string{"unknown setting PERIODIC_SOMETHING_ELSE_ENTIRELY"}},
+		{name: "invalid value", environ: []string{"PULSE_POD_TIMEOUT=-1s"}, want: []string{`PULSE_POD_TIMEOUT="-1s": want a positive duration`}},
+		{
+			name:    "every problem is reported",
+			environ: []string{"QUARANTINE_MODE=evict", "PULSE_THRESHOLD=40"},
+			want:    []string{"QUARANTINE_MODE=\"evict\"", "did you mean PULSE_THRESHOLD_MS?"},
+		},
+	}
+	for _, tc := range cases {
+		err := validateEnv(tc.environ)
+		if len(tc.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: accepted %v", tc.name, tc.environ)
+			continue
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: %v, want it to mention %q", tc.name, err, want)
+			}
+		}
+	}
+}
+
+// Not parallel: applyFlags exports flags into the process environment.
+func TestApplyFlags(t *testing.T) {
+	t.Setenv("PULSE_POD_TIMEOUT", "5m")
+	t.Setenv("QUARANTINE_CLEAR_PASSES", "2")
+
+	if err := applyFlags([]string{"--pulse-pod-timeout=7m"}); err != nil {
+		t.Fatalf("applyFlags: %v", err)
+	}
+	if got := os.Getenv("PULSE_POD_TIMEOUT"); got != "7m" {
+		t.Errorf("PULSE_POD_TIMEOUT = %q, want the flag's 7m over the variable", got)
+	}
+	if got := os.Getenv("QUARANTINE_CLEAR_PASSES"); got != "2" {
+		t.Errorf("QUARANTINE_CLEAR_PASSES = %q, want the variable kept without a flag", got)
+	}
+
+	for _, args := range [][]string{
+		{"--pulse-pod-timeout=soon"},
+		{"--pulse-no-such-flag=1"},
+		{"--pulse-id=abc"}, // internal settings have no flag
+		{"extra"},
+	} {
+		if err := applyFlags(args); err == nil {
+			t.Errorf("applyFlags(%q) accepted", args)
+		}
+	}
+}
+
+// TestSettingsCoverEnvReads checks that every variable the agent reads by
+// name is a setting, so validateEnv accepts it and it has a flag.
+func TestSettingsCoverEnvReads(t *testing.T) {
+	t.Parallel()
+
+	// The Go runtime's own variables, read only to respect them.
+	runtimeVars := []string{"GOMAXPROCS", "GOMEMLIMIT"}
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	read := 0
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 || !readsEnv(call.Fun) {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			name, _ := strconv.Unquote(lit.Value)
+			read++
+			if slices.Contains(runtimeVars, name) {
+				return true
+			}
+			if !slices.ContainsFunc(settings, func(s setting) bool { return s.env == name }) {
+				t.Errorf("%s reads %s, which is not in settings", fset.Position(call.Pos()), name)
+			}
+			return true
+		})
+	}
+	if read == 0 {
+		t.Fatal("found no environment reads")
+	}
+}
+
+// readsEnv reports whether fun is os.Getenv, os.LookupEnv or one of the
+// agent's env* helpers.
+func readsEnv(fun ast.Expr) bool {
+	switch f := fun.(type) {
+	case *ast.SelectorExpr:
+		pkg, ok := f.X.(*ast.Ident)
+		return ok && pkg.Name == "os" && (f.Sel.Name == "Getenv" || f.Sel.Name == "LookupEnv")
+	case *ast.Ident:
+		return strings.HasPrefix(f.Name, "env")
+	}
+	return false
+}
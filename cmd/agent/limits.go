@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// applyRuntimeLimits sizes the Go runtime to the agent's container, so the
+// agent is throttled or collects garbage before the kernel OOM-kills it:
+//
+//   - GOMAXPROCS drops to the cgroup CPU quota, rounded up. Go 1.23 sizes it
+//     by host CPUs, and a runtime that schedules 128 threads on a 1-CPU
+//     quota spends it being throttled.
+//   - The soft memory limit (GOMEMLIMIT) is the cgroup memory limit less the
+//     pulse's memory reserve (see pulse.MemoryReserveEnv), which the CUDA
+//     runtime allocates through CGO outside the Go heap. With the pulse in
+//     a runner pod nothing is reserved.
+//
+// Either is left alone when the operator set its variable, or the cgroup
+// sets no limit.
+func applyRuntimeLimits(isolation string) {
+	limits := pulse.ReadContainerLimits()
+	var reserve int64
+	if isolation != "pod" && isolation != "sim" {
+		reserve = pulse.MemoryReserve()
+	}
+
+	attrs := []any{"memory_limit_bytes", limits.MemoryBytes, "cpus", limits.CPUs, "pulse_memory_reserve_bytes", reserve}
+	if os.Getenv("GOMAXPROCS") == "" && limits.CPUs > 0 {
+		if n := int(math.Ceil(limits.CPUs)); n < runtime.GOMAXPROCS(0) {
+			runtime.GOMAXPROCS(n)
+		}
+	}
+	attrs = append(attrs, "gomaxprocs", runtime.GOMAXPROCS(0))
+	if os.Getenv("GOMEMLIMIT") == "" && limits.MemoryBytes > 0 {
+		soft := limits.MemoryBytes - reserve
+		if soft <= limits.MemoryBytes/4 {
+			// The reserve leaves the agent too little to run in; every
+			// pulse will fail the memory guard anyway.
+			slog.Warn(pulse.MemoryReserveEnv+" leaves under a quarter of the container memory limit — pulses will be inconclusive",
+				attrs...)
+			return
+		}
+		debug.SetMemoryLimit(soft)
+		attrs = append(attrs, "gomemlimit_bytes", soft)
+	}
+	slog.Info("runtime limits", attrs...)
+}
@@ -3,24 +3,36 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/justin-oleary/straggler-shield/pkg/aggregator"
+	"github.com/justin-oleary/straggler-shield/pkg/chaos"
+	"github.com/justin-oleary/straggler-shield/pkg/delivery"
 	"github.com/justin-oleary/straggler-shield/pkg/k8s"
 	_ "github.com/justin-oleary/straggler-shield/pkg/metrics" // register collectors
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 // nodeLocks ensures ReconcileNode never runs concurrently for the same node.
@@ -28,12 +40,67 @@ import (
 // while a pulse is already in flight.
 var nodeLocks sync.Map
 
+// retries holds the nodes with a reconcile retry scheduled — a pulse
+// deferred by the busy-node guard or an inconclusive pulse to run again — so
+// repeated triggers keep a single retry timer. busyRetryInterval is
+// PULSE_BUSY_RETRY_INTERVAL and inconclusiveRetryInterval is
+// PULSE_INCONCLUSIVE_RETRY_INTERVAL.
+var (
+	retries                   sync.Map
+	busyRetryInterval         time.Duration
+	inconclusiveRetryInterval time.Duration
+)
+
 func main() {
+	// Hidden subcommand: run one pulse and report it on stdout. Used by
+	// PULSE_ISOLATION=subprocess; must run before anything writes to stdout.
+	if len(os.Args) > 1 && os.Args[1] == pulse.ChildArg {
+		// The agent validated FAULT_INJECTION before starting this child.
+		faults, _ := chaos.FromEnv()
+		faults.CrashPoint()
+		if err := pulse.ServeRunner(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "pulse child: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
 
+	// Every setting below can also be given as a flag (see settings). A
+	// typo or malformed value stops the agent here instead of silently
+	// falling back to a default.
+	if err := applyFlags(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		slog.Error("invalid configuration", "err", err)
+		os.Exit(2)
+	}
+
+	// AGENT_MODE=node (default) runs one agent per GPU node as a DaemonSet,
+	// watching only its own node. AGENT_MODE=central runs a single controller
+	// that watches every GPU node and validates each through runner pods, so
+	// no privileged DaemonSet is needed.
+	mode := os.Getenv("AGENT_MODE")
 	nodeName := os.Getenv("NODE_NAME")
-	if nodeName == "" {
-		slog.Error("NODE_NAME not set — mount the node name via the downward API")
+	var scope metav1.ListOptions
+	switch mode {
+	case "", "node":
+		mode = "node"
+		if nodeName == "" {
+			slog.Error("NODE_NAME not set — mount the node name via the downward API")
+			os.Exit(1)
+		}
+		scope.FieldSelector = "metadata.name=" + nodeName
+	case "central":
+		scope.LabelSelector = os.Getenv("GPU_NODE_SELECTOR")
+		if scope.LabelSelector == "" {
+			scope.LabelSelector = "nvidia.com/gpu.present=true"
+		}
+		scope.FieldSelector = os.Getenv("GPU_NODE_FIELD_SELECTOR")
+	default:
+		slog.Error("invalid AGENT_MODE — expected node or central", "value", mode)
 		os.Exit(1)
 	}
 
@@ -42,6 +109,26 @@ func main() {
 		slog.Error("failed to load in-cluster config", "err", err)
 		os.Exit(1)
 	}
+
+	// FAULT_INJECTION is for staging clusters only: it fails a fraction of
+	// API requests and pulses on purpose; see pkg/chaos.
+	faults, err := chaos.FromEnv()
+	if err != nil {
+		slog.Error("invalid FAULT_INJECTION", "err", err)
+		os.Exit(1)
+	}
+	if faults != nil {
+		slog.Warn("FAULT_INJECTION set — injecting faults, never use in production", "faults", faults.String())
+		cfg.Wrap(faults.WrapTransport)
+	}
+	// READER_TOKEN_FILE makes every read with a second, read-only
+	// credential, so the pod's own service account needs only the writes.
+	if path := os.Getenv("READER_TOKEN_FILE"); path != "" {
+		if cfg, err = k8s.SplitConfig(cfg, path); err != nil {
+			slog.Error("invalid READER_TOKEN_FILE", "err", err)
+			os.Exit(1)
+		}
+	}
 	clientset, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		slog.Error("failed to create clientset", "err", err)
@@ -51,19 +138,1077 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	ctrl := k8s.NewController(clientset)
+	isolation := os.Getenv("PULSE_ISOLATION")
+	if mode == "central" {
+		// The central controller runs on no particular node; runner pods are
+		// the only way it can reach a node's GPUs.
+		switch isolation {
+		case "", "pod":
+			isolation = "pod"
+		case "sim":
+		default:
+			slog.Error("AGENT_MODE=central requires PULSE_ISOLATION=pod or sim", "value", isolation)
+			os.Exit(1)
+		}
+	}
+	applyRuntimeLimits(isolation)
+
+	// QUARANTINE_MODE=cordon sets spec.unschedulable instead of writing the
+	// quarantine taint, for remediation tooling that acts on cordons.
+	quarantineMode := k8s.QuarantineMode(os.Getenv("QUARANTINE_MODE"))
+	switch quarantineMode {
+	case "", k8s.QuarantineTaint, k8s.QuarantineCordon:
+	default:
+		slog.Error("invalid QUARANTINE_MODE — expected taint or cordon", "value", quarantineMode)
+		os.Exit(1)
+	}
+
+	var opts []k8s.Option
+	opts = append(opts, k8s.WithQuarantineMode(quarantineMode))
+	var pol *policy.Policy
+	if path := os.Getenv("POLICY_FILE"); path != "" {
+		p, err := policy.Load(path)
+		if err != nil {
+			slog.Error("failed to load policy", "err", err)
+			os.Exit(1)
+		}
+		pol = p
+		if p.SoftQuarantine && quarantineMode == k8s.QuarantineCordon {
+			slog.Warn("policy softQuarantine has no cordon equivalent — the first failure cordons the node")
+		}
+		if len(p.Taints) > 0 && quarantineMode == k8s.QuarantineCordon {
+			slog.Warn("policy taints have no cordon equivalent — every failure class cordons the node")
+		}
+		if p.WorkloadClasses != nil && quarantineMode == k8s.QuarantineCordon {
+			slog.Warn("policy workloadClasses have no cordon equivalent — every failure cordons the whole node")
+		}
+		opts = append(opts, k8s.WithPolicy(p))
+		if inconclusiveRetryInterval, err = envDuration("PULSE_INCONCLUSIVE_RETRY_INTERVAL", 10*time.Minute); err != nil {
+			slog.Error("invalid inconclusive retry interval", "err", err)
+			os.Exit(1)
+		}
+		if err := applyPolicyCVCeiling(p); err != nil {
+			slog.Error("invalid policy CV ceiling", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	taint, err := taintFromEnv()
+	if err != nil {
+		slog.Error("invalid quarantine taint configuration", "err", err)
+		os.Exit(1)
+	}
+	opts = append(opts, k8s.WithTaint(taint))
+	// QUARANTINE_PREVIOUS_TAINT_KEYS lists keys from earlier
+	// QUARANTINE_TAINT_KEY settings whose taints are moved to the current key
+	// at startup.
+	opts = append(opts, k8s.WithMarkerMigrations(taintMigrationsFromEnv(taint.Key)...))
+
+	// QUARANTINE_REQUIRED_FAILURES holds the taint until that many straggler
+	// failures in a row; the watch loop re-pulses a node while its streak is
+	// open.
+	if s := os.Getenv("QUARANTINE_REQUIRED_FAILURES"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			slog.Error("invalid QUARANTINE_REQUIRED_FAILURES — want a positive integer", "value", s)
+			os.Exit(1)
+		}
+		opts = append(opts, k8s.WithRequiredFailures(n))
+	}
+
+	// QUARANTINE_EVICT_GPU_PODS=true drains GPU pods off a node once it is
+	// hard-quarantined, through the Eviction API.
+	eviction, err := evictionFromEnv()
+	if err != nil {
+		slog.Error("invalid pod eviction configuration", "err", err)
+		os.Exit(1)
+	}
+	if eviction != nil {
+		opts = append(opts, k8s.WithPodEviction(*eviction))
+	}
+
+	// QUARANTINE_MAX_PER_HOUR and QUARANTINE_MAX_PERCENT cap quarantines
+	// cluster-wide; see guardFromEnv.
+	guard, err := guardFromEnv()
+	if err != nil {
+		slog.Error("invalid quarantine guard configuration", "err", err)
+		os.Exit(1)
+	}
+	if guard != nil {
+		opts = append(opts, k8s.WithQuarantineGuard(*guard))
+	}
+
+	var report k8s.NodeReportFunc
+	switch isolation {
+	case "", "inprocess":
+		// Isolated runners resolve thresholds in their own process, so only
+		// the in-process pulse uses this Config.
+		slog.Info("pulse thresholds", "thresholds", pulse.Active().Snapshot())
+	case "subprocess":
+		// A CGO fault now kills only the child; the agent records it as a
+		// pulse_crashed hard failure and keeps watching. PULSE_RUNNER_PATH
+		// points at a standalone pulse-runner; unset re-execs this binary.
+		runner := pulse.Runner{Path: os.Getenv("PULSE_RUNNER_PATH")}
+		if runner.Timeout, err = envDuration("PULSE_RUNNER_TIMEOUT", 0); err != nil {
+			slog.Error("invalid pulse runner configuration", "err", err)
+			os.Exit(1)
+		}
+		report = func(ctx context.Context, _ string) (*pulse.PulseReport, error) {
+			return runner.RunReport(ctx)
+		}
+	case "pod":
+		// The pulse runs in an ephemeral pod that holds the GPUs; the agent
+		// needs no device access, runtime class, or GPU resource request.
+		runner, err := podRunnerFromEnv(clientset)
+		if err != nil {
+			slog.Error("invalid pod runner configuration", "err", err)
+			os.Exit(1)
+		}
+		runner.TaintKey = taint.Key
+		report = runner.RunReport
+		// CANARY_IMAGE follows each pair of turned-up nodes with a small
+		// two-node training job; only the central controller sees pairs.
+		if mode == "central" {
+			canary, pairWait, err := canaryFromEnv(runner)
+			if err != nil {
+				slog.Error("invalid canary configuration", "err", err)
+				os.Exit(1)
+			}
+			if canary != nil {
+				opts = append(opts, k8s.WithCanary(canary, pairWait))
+			}
+		}
+	case "sim":
+		// Test clusters only: no GPU is touched, each node's verdict is
+		// read from its SimFailureAnnotation (see test/e2e).
+		slog.Warn("PULSE_ISOLATION=sim — pulses are simulated, no GPU is validated")
+		report = k8s.SimulatedPulse(clientset)
+	default:
+		slog.Error("invalid PULSE_ISOLATION — expected inprocess, subprocess, pod or sim", "value", isolation)
+		os.Exit(1)
+	}
+	if faults != nil {
+		// An isolated runner crashes itself: the subprocess child inherits
+		// FAULT_INJECTION. In-process, the crash takes the agent down.
+		if report == nil {
+			report = func(ctx context.Context, _ string) (*pulse.PulseReport, error) {
+				faults.CrashPoint()
+				return pulse.RunPulseReport(ctx)
+			}
+		}
+		report = faults.WrapPulse(report)
+	}
+	if report != nil {
+		opts = append(opts, k8s.WithNodeReport(report))
+	}
+
+	// PULSE_REMEDIATION resets the GPUs (or reloads the driver) after a
+	// failure that would quarantine and pulses again. The action runs in
+	// this agent, so the pulse must run on this node too, and in a child
+	// process: an in-process pulse leaves this process holding a CUDA
+	// context on every GPU, and a reset fails while any process holds one.
+	if action := os.Getenv(pulse.RemediationEnv); action != "" {
+		if isolation != "subprocess" {
+			slog.Error("PULSE_REMEDIATION requires PULSE_ISOLATION=subprocess", "value", isolation)
+			os.Exit(1)
+		}
+		opts = append(opts, k8s.WithRemediation(action, func(ctx context.Context, _ string) error {
+			return pulse.Remediate(ctx, action)
+		}))
+	}
+
+	// PULSE_RESULT_FRESHNESS reuses a node's last result for triggers that
+	// fire within the window (e.g. a Ready flap right after a pulse).
+	freshness, err := envDuration("PULSE_RESULT_FRESHNESS", 0)
+	if err != nil {
+		slog.Error("invalid pulse result cache configuration", "err", err)
+		os.Exit(1)
+	}
+	opts = append(opts, k8s.WithResultCache(freshness))
+
+	// PULSE_MAX_IN_FLIGHT_PER_DOMAIN and PULSE_MAX_IN_FLIGHT queue pulses
+	// beyond those limits, so a mass reboot does not put a whole rack or
+	// power domain (PULSE_DOMAIN_LABEL) under GEMM load at once. Only the
+	// central controller runs more than one pulse at a time.
+	dispatch, err := dispatchFromEnv()
+	if err != nil {
+		slog.Error("invalid pulse dispatch configuration", "err", err)
+		os.Exit(1)
+	}
+	// PULSE_PRIORITY_ORDER and PULSE_POOL_PRIORITY order the pulses those
+	// limits queue, so the most valuable capacity returns to service first.
+	prio, err := priorityFromEnv()
+	if err != nil {
+		slog.Error("invalid validation priority configuration", "err", err)
+		os.Exit(1)
+	}
+	if prio != nil {
+		dispatch.Order = prio.Before
+	}
+	opts = append(opts, k8s.WithPulseDispatch(dispatch))
+
+	// RECONCILE_BUDGET is how long a validating reconcile (get node, pulse,
+	// patch) may take before its breakdown is logged as a warning.
+	budget, err := envDuration("RECONCILE_BUDGET", k8s.DefaultReconcileBudget)
+	if err != nil {
+		slog.Error("invalid reconcile budget", "err", err)
+		os.Exit(1)
+	}
+	opts = append(opts, k8s.WithReconcileBudget(budget))
+
+	// Quarantine and clear decisions are journaled before they are applied
+	// and replayed until they land. In node mode the journal is kept in the
+	// host state directory so a decision survives an agent restart; the
+	// central controller has no host mount and keeps it in memory.
+	journalPath := ""
+	if mode == "node" {
+		journalPath = filepath.Join(pulse.StateDir(), "decisions.journal")
+	}
+	opts = append(opts, k8s.WithDecisionJournal(journalPath, 10*time.Second, 5*time.Minute))
+
+	// Notifications to the aggregator, the result webhook and the
+	// quarantine notifiers are retried and then dead-lettered; see
+	// deliveryFromEnv.
+	deliveryCfg, err := deliveryFromEnv(mode)
+	if err != nil {
+		slog.Error("invalid delivery configuration", "err", err)
+		os.Exit(1)
+	}
+
+	// AGGREGATOR_URL pushes every fresh pulse report to the fleet
+	// aggregator, tagged with AGGREGATOR_CLUSTER and the rack read from the
+	// node label AGGREGATOR_RACK_LABEL, with the ingest token in
+	// AGGREGATOR_TOKEN_FILE.
+	var publisher *aggregator.Publisher
+	if url := os.Getenv("AGGREGATOR_URL"); url != "" {
+		cluster := os.Getenv("AGGREGATOR_CLUSTER")
+		if cluster == "" {
+			slog.Error("AGGREGATOR_URL is set but AGGREGATOR_CLUSTER is not — name the cluster its reports belong to")
+			os.Exit(1)
+		}
+		rackLabel := os.Getenv("AGGREGATOR_RACK_LABEL")
+		if rackLabel == "" {
+			rackLabel = "topology.kubernetes.io/rack"
+		}
+		publisher = aggregator.NewPublisher(url, cluster, rackLabel, os.Getenv("AGGREGATOR_TOKEN_FILE"), deliveryCfg, slog.Default())
+		opts = append(opts, k8s.WithReportSink(publisher.Sink))
+	}
+
+	// RESULT_WEBHOOK_URL posts every fresh pulse result, or with
+	// RESULT_WEBHOOK_EVENTS=failures only the failures, to an external
+	// receiver.
+	var webhook *delivery.Webhook
+	if url := os.Getenv("RESULT_WEBHOOK_URL"); url != "" {
+		var failuresOnly bool
+		switch ev := os.Getenv("RESULT_WEBHOOK_EVENTS"); ev {
+		case "", "all":
+		case "failures":
+			failuresOnly = true
+		default:
+			slog.Error("invalid RESULT_WEBHOOK_EVENTS — want all or failures", "value", ev)
+			os.Exit(1)
+		}
+		webhook = delivery.NewWebhook(url, failuresOnly, deliveryCfg, slog.Default())
+		opts = append(opts, k8s.WithReportSink(webhook.Sink))
+	}
+
+	// NOTIFY_* post each quarantine and clear to on-call webhooks; see
+	// notifiersFromEnv.
+	notifiers, err := notifiersFromEnv(deliveryCfg)
+	if err != nil {
+		slog.Error("invalid notification configuration", "err", err)
+		os.Exit(1)
+	}
+	for _, n := range notifiers {
+		opts = append(opts, k8s.WithTransitionSink(n.Sink))
+	}
+
+	// SLURM_REST_URL drains quarantined nodes in Slurm and resumes them on
+	// clearance; see slurmFromEnv.
+	slurm, err := slurmFromEnv(deliveryCfg)
+	if err != nil {
+		slog.Error("invalid Slurm configuration", "err", err)
+		os.Exit(1)
+	}
+	if slurm != nil {
+		opts = append(opts, k8s.WithTransitionSink(slurm.Sink))
+	}
+
+	// PULSE_RESULT_HISTORY writes a PulseResult per pulse and keeps that
+	// many per node; the CRD in deploy/crd-pulseresult.yaml must be applied.
+	if s := os.Getenv("PULSE_RESULT_HISTORY"); s != "" {
+		keep, err := strconv.Atoi(s)
+		if err != nil || keep < 1 {
+			slog.Error("invalid PULSE_RESULT_HISTORY — want a positive integer", "value", s)
+			os.Exit(1)
+		}
+		dyn, err := dynamic.NewForConfig(cfg)
+		if err != nil {
+			slog.Error("failed to create dynamic client", "err", err)
+			os.Exit(1)
+		}
+		opts = append(opts, k8s.WithPulseResults(dyn, keep))
+	}
+
+	recorder, stopEvents := k8s.NewEventRecorder(clientset, "straggler-shield")
+	defer stopEvents()
+	opts = append(opts, k8s.WithEventRecorder(recorder))
+
+	// CENTRAL_SHARDS splits central mode across replicas, each holding the
+	// Lease for one shard of the nodes.
+	var election *k8s.ShardElection
+	var ring *k8s.ShardRing
+	if mode == "central" {
+		election, ring, err = shardFromEnv(clientset)
+		if err != nil {
+			slog.Error("invalid shard configuration", "err", err)
+			os.Exit(1)
+		}
+		if election != nil {
+			opts = append(opts, k8s.WithShard(election.Shard))
+		}
+	}
+
+	// PULSE_BUSY_GUARD defers triggered pulses while GPU workloads hold
+	// the node; see busyGuardFromEnv.
+	busy, err := busyGuardFromEnv(clientset)
+	if err != nil {
+		slog.Error("invalid busy-node guard configuration", "err", err)
+		os.Exit(1)
+	}
+	if busy != nil {
+		opts = append(opts, busy)
+	}
+
+	// DEBUG_ADDR serves pprof and the agent's internal state; see
+	// serveDebug. ADMIN_ADDR serves the admin API; see serveAdmin. The last
+	// pulse per node is only kept when one of them is set.
+	debugAddr, adminAddr := os.Getenv("DEBUG_ADDR"), os.Getenv("ADMIN_ADDR")
+	if debugAddr != "" || adminAddr != "" {
+		opts = append(opts, k8s.WithReportSink(recordPulse))
+	}
+
+	ctrl := k8s.NewController(clientset, opts...)
+
+	auditInterval, err := envDuration("MARKER_AUDIT_INTERVAL", 0)
+	if err != nil {
+		slog.Error("invalid marker audit interval", "err", err)
+		os.Exit(1)
+	}
 
 	go serveMetrics(ctx)
+	if debugAddr != "" {
+		tokenFile := os.Getenv("DEBUG_TOKEN_FILE")
+		if tokenFile == "" && !loopbackAddr(debugAddr) {
+			slog.Error("DEBUG_ADDR off the loopback interface requires DEBUG_TOKEN_FILE", "addr", debugAddr)
+			os.Exit(1)
+		}
+		go serveDebug(ctx, debugAddr, mode, tokenFile)
+	}
+	if adminAddr != "" {
+		tokenFile := os.Getenv("ADMIN_TOKEN_FILE")
+		if tokenFile == "" && !loopbackAddr(adminAddr) {
+			slog.Error("ADMIN_ADDR off the loopback interface requires ADMIN_TOKEN_FILE", "addr", adminAddr)
+			os.Exit(1)
+		}
+		go serveAdmin(ctx, adminAddr, &adminAPI{
+			ctx:       ctx,
+			ctrl:      ctrl,
+			mode:      mode,
+			node:      nodeName,
+			policy:    pol,
+			tokenFile: tokenFile,
+			started:   time.Now().UTC(),
+		})
+	}
+	go ctrl.RunJournalReplay(ctx)
+	go ctrl.RunCanaries(ctx)
+	if publisher != nil {
+		go publisher.Run(ctx)
+	}
+	if webhook != nil {
+		go webhook.Run(ctx)
+	}
+	for _, n := range notifiers {
+		go n.Run(ctx)
+	}
+	if slurm != nil {
+		go slurm.Run(ctx)
+	}
+
+	if mode == "node" {
+		periodic, err := periodicFromEnv(ctrl, clientset)
+		if err != nil {
+			slog.Error("invalid periodic pulse configuration", "err", err)
+			os.Exit(1)
+		}
+		if periodic != nil {
+			go periodic.Run(ctx, nodeName)
+		}
+		revalidator, err := revalidatorFromEnv(ctrl)
+		if err != nil {
+			slog.Error("invalid quarantine revalidation configuration", "err", err)
+			os.Exit(1)
+		}
+		if revalidator != nil {
+			go revalidator.Run(ctx, nodeName)
+		}
+		if auditInterval > 0 {
+			go runAudit(ctx, ctrl, auditInterval, func() []string { return []string{nodeName} })
+		}
+	}
+
+	if mode == "central" {
+		var owns func(string) bool
+		if election != nil {
+			owns = ring.Owns(election.Shard)
+		}
+		index, err := nodeIndexFromEnv(clientset, scope, taint.Key, owns)
+		if err != nil {
+			slog.Error("invalid node index configuration", "err", err)
+			os.Exit(1)
+		}
+		if election == nil {
+			slog.Info("straggler-shield starting", "mode", mode, "selector", scope.LabelSelector, "field_selector", scope.FieldSelector)
+			runCentral(ctx, ctrl, index, clientset, prio, auditInterval)
+			return
+		}
+		slog.Info("straggler-shield starting", "mode", mode, "selector", scope.LabelSelector, "field_selector", scope.FieldSelector,
+			"shard", election.Shard, "shards", ring.Shards(), "identity", election.Identity)
+		// A replica that loses its lease exits rather than campaigning
+		// again: the new holder may already be pulsing the shard's nodes.
+		if err := election.Run(ctx, func(ctx context.Context) { runCentral(ctx, ctrl, index, clientset, prio, auditInterval) }); err != nil {
+			slog.Error("stopping central controller", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	collectStaleMarkers(ctx, ctrl, clientset, scope)
+
+	slog.Info("straggler-shield starting", "mode", mode, "node", nodeName, "selector", scope.LabelSelector)
+	run(ctx, ctrl, clientset, scope)
+}
+
+// nodeIndexFromEnv builds the central controller's node cache over the nodes
+// in scope, indexed by the GPU_POOL_LABEL node label and paged
+// NODE_LIST_PAGE_SIZE nodes at a time. owns limits it to one shard; nil
+// covers every node.
+func nodeIndexFromEnv(clientset kubernetes.Interface, scope metav1.ListOptions, taintKey string, owns func(string) bool) (*k8s.NodeIndex, error) {
+	cfg := k8s.NodeIndexConfig{
+		LabelSelector: scope.LabelSelector,
+		FieldSelector: scope.FieldSelector,
+		PoolLabel:     os.Getenv("GPU_POOL_LABEL"),
+		TaintKey:      taintKey,
+		Owns:          owns,
+	}
+	if s := os.Getenv("NODE_LIST_PAGE_SIZE"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("NODE_LIST_PAGE_SIZE=%q: want a positive integer", s)
+		}
+		cfg.PageSize = n
+	}
+	return k8s.NewNodeIndex(clientset, cfg), nil
+}
+
+// shardFromEnv reads the sharding settings for central mode. Unsharded (nil)
+// unless CENTRAL_SHARDS is above 1. The replica's shard is CENTRAL_SHARD, or
+// else the StatefulSet ordinal at the end of POD_NAME (the hostname when
+// unset), and its Lease lives in POD_NAMESPACE (default straggler-shield).
+func shardFromEnv(clientset kubernetes.Interface) (*k8s.ShardElection, *k8s.ShardRing, error) {
+	shards := 1
+	if s := os.Getenv("CENTRAL_SHARDS"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, nil, fmt.Errorf("CENTRAL_SHARDS=%q: want a positive integer", s)
+		}
+		shards = n
+	}
+	if shards == 1 {
+		return nil, nil, nil
+	}
+	identity := os.Getenv("POD_NAME")
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	src, s := "CENTRAL_SHARD", os.Getenv("CENTRAL_SHARD")
+	if s == "" {
+		src, s = "pod name ordinal", identity[strings.LastIndex(identity, "-")+1:]
+	}
+	shard, err := strconv.Atoi(s)
+	if err != nil || shard < 0 || shard >= shards {
+		return nil, nil, fmt.Errorf("%s %q: want a shard in [0, %d)", src, s, shards)
+	}
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		namespace = "straggler-shield"
+	}
+	return &k8s.ShardElection{
+		Client:    clientset,
+		Namespace: namespace,
+		Shard:     shard,
+		Identity:  identity,
+	}, k8s.NewShardRing(shards), nil
+}
+
+// runCentral drives the central controller from index: one paginated list
+// and one watch for the whole fleet, instead of the raw watch run uses. Once
+// the initial list is cached, stale markers are collected from it, and node
+// counts per pool and state are exported every 30s, when the validation
+// priority, if any, is also refreshed. A positive audit runs the marker audit
+// over the indexed nodes at that interval.
+func runCentral(ctx context.Context, ctrl *k8s.Controller, index *k8s.NodeIndex, clientset kubernetes.Interface, prio *k8s.ValidationPriority, audit time.Duration) {
+	if err := index.OnChange(reconcileOnChange(ctx, ctrl)); err != nil {
+		slog.Error("failed to register node handler", "err", err)
+		os.Exit(1)
+	}
+	go index.Run(ctx)
+	if !index.WaitForSync(ctx) {
+		return
+	}
+	watchSynced()
+	centralIndex.Store(index)
+	nodes := index.Nodes()
+	slog.Info("node index synced", "nodes", len(nodes), "pools", len(index.Pools()))
+	refreshPriority(ctx, prio, clientset, nodes)
+	for _, node := range nodes {
+		if err := ctrl.CollectStaleMarkers(ctx, node.Name); err != nil {
+			slog.Warn("stale marker collection failed", "node", node.Name, "err", err)
+		}
+	}
+	if audit > 0 {
+		go runAudit(ctx, ctrl, audit, func() []string {
+			var names []string
+			for _, node := range index.Nodes() {
+				names = append(names, node.Name)
+			}
+			return names
+		})
+	}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		index.RecordCounts()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refreshPriority(ctx, prio, clientset, index.Nodes())
+		}
+	}
+}
+
+// runAudit checks the markers of every node nodes returns against each other
+// and the latest recorded decision every interval, repairing drift (see
+// Controller.AuditNode), until ctx is cancelled. A node with a pulse in
+// flight is skipped until the next round.
+func runAudit(ctx context.Context, ctrl *k8s.Controller, interval time.Duration, nodes func() []string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, name := range nodes() {
+			withNodeLock(name, func() {
+				if err := ctrl.AuditNode(ctx, name); err != nil {
+					slog.Warn("marker audit failed", "node", name, "err", err)
+				}
+			})
+		}
+	}
+}
+
+// refreshPriority recounts the pending demand and NVLink domains prio ranks
+// nodes by. A failed refresh keeps the previous counts.
+func refreshPriority(ctx context.Context, prio *k8s.ValidationPriority, clientset kubernetes.Interface, nodes []*corev1.Node) {
+	if prio == nil {
+		return
+	}
+	if err := prio.Refresh(ctx, clientset, nodes); err != nil {
+		slog.Warn("validation priority refresh failed", "err", err)
+	}
+}
+
+// applyPolicyCVCeiling applies the policy's cvCeilings entry for the detected
+// architecture unless PULSE_CV_MAX_<ARCH> is set. It is also exported to that
+// variable so subprocess runners, which resolve their own thresholds, apply
+// it too. Runner pods do not inherit it; set the variable on their image.
+func applyPolicyCVCeiling(p *policy.Policy) error {
+	th := pulse.Active().Snapshot()
+	arch := th.ThresholdProvenance.Matched
+	v, ok := p.CVCeiling(arch)
+	if !ok || os.Getenv(pulse.CVOverrideEnv(arch)) != "" {
+		return nil
+	}
+	th.MaxCV = v
+	th.ThresholdProvenance.MaxCVSource = pulse.SourcePolicy
+	if err := pulse.Active().Set(th); err != nil {
+		return err
+	}
+	return os.Setenv(pulse.CVOverrideEnv(arch), strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+// taintMigrationsFromEnv reads QUARANTINE_PREVIOUS_TAINT_KEYS, a
+// comma-separated list, into compatibility table rows moving each key to key.
+func taintMigrationsFromEnv(key string) []k8s.MarkerMigration {
+	var rows []k8s.MarkerMigration
+	for _, old := range strings.Split(os.Getenv("QUARANTINE_PREVIOUS_TAINT_KEYS"), ",") {
+		if old = strings.TrimSpace(old); old != "" {
+			rows = append(rows, k8s.MarkerMigration{Kind: k8s.MarkerTaint, Old: old, New: key, Note: "previous QUARANTINE_TAINT_KEY"})
+		}
+	}
+	return rows
+}
+
+// collectStaleMarkers migrates markers from earlier versions on every node in
+// scope before the watch starts. Failures are logged and do not block
+// startup; the next restart tries again.
+func collectStaleMarkers(ctx context.Context, ctrl *k8s.Controller, clientset kubernetes.Interface, scope metav1.ListOptions) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, scope)
+	if err != nil {
+		slog.Warn("stale marker collection skipped — cannot list nodes", "err", err)
+		return
+	}
+	for _, node := range nodes.Items {
+		if err := ctrl.CollectStaleMarkers(ctx, node.Name); err != nil {
+			slog.Warn("stale marker collection failed", "node", node.Name, "err", err)
+		}
+	}
+}
+
+// taintFromEnv reads the quarantine taint from QUARANTINE_TAINT_KEY,
+// QUARANTINE_TAINT_VALUE (empty = measured pulse duration) and
+// QUARANTINE_TAINT_EFFECT (NoSchedule, PreferNoSchedule or NoExecute).
+func taintFromEnv() (k8s.TaintConfig, error) {
+	t := k8s.DefaultTaint()
+	if s := os.Getenv("QUARANTINE_TAINT_KEY"); s != "" {
+		t.Key = s
+	}
+	t.Value = os.Getenv("QUARANTINE_TAINT_VALUE")
+	if s := os.Getenv("QUARANTINE_TAINT_EFFECT"); s != "" {
+		t.Effect = corev1.TaintEffect(s)
+	}
+	return t, t.Validate()
+}
+
+// podRunnerFromEnv builds the ephemeral runner pod executor from
+// PULSE_POD_IMAGE (required), POD_NAMESPACE, PULSE_POD_GPUS and
+// PULSE_POD_TIMEOUT.
+func podRunnerFromEnv(clientset kubernetes.Interface) (*k8s.PodRunner, error) {
+	r := &k8s.PodRunner{
+		Client:    clientset,
+		Namespace: os.Getenv("POD_NAMESPACE"),
+		Image:     os.Getenv("PULSE_POD_IMAGE"),
+	}
+	if r.Image == "" {
+		return nil, errors.New("PULSE_POD_IMAGE must be set when PULSE_ISOLATION=pod")
+	}
+	if r.Namespace == "" {
+		r.Namespace = "straggler-shield"
+	}
+	if s := os.Getenv("PULSE_POD_GPUS"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("PULSE_POD_GPUS=%q: want a non-negative integer", s)
+		}
+		r.GPUs = v
+	}
+	var err error
+	if r.Timeout, err = envDuration("PULSE_POD_TIMEOUT", 0); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// canaryFromEnv builds the distributed training canary. Disabled (nil) unless
+// CANARY_IMAGE is set; the pods go where runner's do and request as many
+// GPUs. CANARY_PAIR_WAIT (default 10m) is how long a passed node waits for a
+// partner.
+func canaryFromEnv(runner *k8s.PodRunner) (*k8s.CanaryRunner, time.Duration, error) {
+	image := os.Getenv("CANARY_IMAGE")
+	if image == "" {
+		return nil, 0, nil
+	}
+	c := &k8s.CanaryRunner{
+		Client:           runner.Client,
+		Namespace:        runner.Namespace,
+		Image:            image,
+		GPUs:             runner.GPUs,
+		RuntimeClassName: runner.RuntimeClassName,
+	}
+	if s := os.Getenv("CANARY_STEPS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			return nil, 0, fmt.Errorf("CANARY_STEPS=%q: want a positive integer", s)
+		}
+		c.Steps = v
+	}
+	var err error
+	if c.MaxStepTime, err = envDuration("CANARY_MAX_STEP_TIME", 0); err != nil {
+		return nil, 0, err
+	}
+	if c.Timeout, err = envDuration("CANARY_TIMEOUT", 0); err != nil {
+		return nil, 0, err
+	}
+	pairWait, err := envDuration("CANARY_PAIR_WAIT", 10*time.Minute)
+	if err != nil {
+		return nil, 0, err
+	}
+	return c, pairWait, nil
+}
 
-	slog.Info("straggler-shield starting", "node", nodeName)
-	run(ctx, ctrl, clientset, nodeName)
+// periodicFromEnv builds the job-aware periodic validator. Disabled (nil)
+// unless PERIODIC_PULSE_INTERVAL is set. PERIODIC_MAX_STALENESS (default 7d)
+// bounds how long busy GPUs may defer a pulse; PERIODIC_RETRY_INTERVAL
+// (default 15m) is how often a busy node is re-checked.
+func periodicFromEnv(ctrl *k8s.Controller, clientset kubernetes.Interface) (*k8s.PeriodicValidator, error) {
+	interval, err := envDuration("PERIODIC_PULSE_INTERVAL", 0)
+	if err != nil || interval == 0 {
+		return nil, err
+	}
+	p := &k8s.PeriodicValidator{
+		Client:   clientset,
+		Interval: interval,
+		Validate: func(ctx context.Context, nodeName string) {
+			withNodeLock(nodeName, func() {
+				err := ctrl.ValidateNode(ctx, nodeName)
+				switch {
+				case errors.Is(err, k8s.ErrRetryInconclusive):
+					scheduleRetry(ctx, ctrl, nodeName, inconclusiveRetryInterval)
+				case err != nil:
+					slog.Error("periodic validation failed", "node", nodeName, "err", err)
+				}
+			})
+		},
+	}
+	if p.MaxStaleness, err = envDuration("PERIODIC_MAX_STALENESS", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if p.RetryInterval, err = envDuration("PERIODIC_RETRY_INTERVAL", 15*time.Minute); err != nil {
+		return nil, err
+	}
+
+	if p.Idle, err = idleFromEnv(clientset); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// idleFromEnv builds the idle check shared by periodic pulses and the
+// busy-node guard: no running pod requests nvidia.com/gpu on the node.
+// DCGM_EXPORTER_URL adds a utilization check on top of the pod check, idle
+// meaning every GPU is at or below DCGM_IDLE_UTIL_MAX percent (default 5).
+func idleFromEnv(clientset kubernetes.Interface) (k8s.IdleFunc, error) {
+	idle := []k8s.IdleFunc{k8s.PodsIdle(clientset)}
+	if url := os.Getenv("DCGM_EXPORTER_URL"); url != "" {
+		maxUtil := 5.0
+		if s := os.Getenv("DCGM_IDLE_UTIL_MAX"); s != "" {
+			var err error
+			if maxUtil, err = strconv.ParseFloat(s, 64); err != nil || maxUtil < 0 {
+				return nil, fmt.Errorf("DCGM_IDLE_UTIL_MAX=%q: want a non-negative number", s)
+			}
+		}
+		idle = append(idle, k8s.DCGMIdle(url, maxUtil))
+	}
+	return k8s.AllIdle(idle...), nil
+}
+
+// busyGuardFromEnv builds the busy-node guard for triggered pulses, on
+// unless PULSE_BUSY_GUARD=false. A busy node is re-checked every
+// PULSE_BUSY_RETRY_INTERVAL (default 2m) and pulsed anyway once it has been
+// deferred for PULSE_BUSY_MAX_DEFER (default 6h).
+func busyGuardFromEnv(clientset kubernetes.Interface) (k8s.Option, error) {
+	if os.Getenv("PULSE_BUSY_GUARD") == "false" {
+		return nil, nil
+	}
+	var err error
+	if busyRetryInterval, err = envDuration("PULSE_BUSY_RETRY_INTERVAL", 2*time.Minute); err != nil {
+		return nil, err
+	}
+	maxDefer, err := envDuration("PULSE_BUSY_MAX_DEFER", 6*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	idle, err := idleFromEnv(clientset)
+	if err != nil {
+		return nil, err
+	}
+	return k8s.WithBusyGuard(idle, maxDefer), nil
+}
+
+// revalidatorFromEnv builds the quarantined-node revalidation loop. Disabled
+// (nil) unless QUARANTINE_RECHECK_INTERVAL is set. The wait doubles after each
+// failing pulse up to QUARANTINE_RECHECK_MAX_INTERVAL (default 6h), and the
+// taint is removed after QUARANTINE_CLEAR_PASSES (default 3) consecutive
+// passes.
+func revalidatorFromEnv(ctrl *k8s.Controller) (*k8s.QuarantineRevalidator, error) {
+	interval, err := envDuration("QUARANTINE_RECHECK_INTERVAL", 0)
+	if err != nil || interval == 0 {
+		return nil, err
+	}
+	maxInterval, err := envDuration("QUARANTINE_RECHECK_MAX_INTERVAL", 6*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	passes := 3
+	if s := os.Getenv("QUARANTINE_CLEAR_PASSES"); s != "" {
+		if passes, err = strconv.Atoi(s); err != nil || passes < 1 {
+			return nil, fmt.Errorf("QUARANTINE_CLEAR_PASSES=%q: want a positive integer", s)
+		}
+	}
+	return &k8s.QuarantineRevalidator{
+		Interval:    interval,
+		MaxInterval: max(maxInterval, interval),
+		Revalidate: func(ctx context.Context, nodeName string) (passed bool, err error) {
+			// A check skipped because a pulse is in flight is retried at the
+			// normal cadence rather than backed off.
+			passed = true
+			withNodeLock(nodeName, func() {
+				passed, err = ctrl.RevalidateQuarantined(ctx, nodeName, passes)
+			})
+			return passed, err
+		},
+	}, nil
+}
+
+// dispatchFromEnv reads the pulse concurrency limits. Unset limits are 0
+// (unlimited).
+func dispatchFromEnv() (k8s.DispatchConfig, error) {
+	cfg := k8s.DispatchConfig{DomainLabel: os.Getenv("PULSE_DOMAIN_LABEL")}
+	for key, limit := range map[string]*int{
+		"PULSE_MAX_IN_FLIGHT_PER_DOMAIN": &cfg.PerDomain,
+		"PULSE_MAX_IN_FLIGHT":            &cfg.Total,
+	} {
+		s := os.Getenv(key)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("%s=%q: want a positive integer", key, s)
+		}
+		*limit = n
+	}
+	return cfg, nil
+}
+
+// priorityFromEnv reads the order queued pulses are dispatched in. Disabled
+// (nil, first come first served) unless PULSE_PRIORITY_ORDER or
+// PULSE_POOL_PRIORITY is set. PULSE_PRIORITY_MIN_POD_PRIORITY (default 1)
+// keeps default-priority pods out of the pending-jobs count.
+func priorityFromEnv() (*k8s.ValidationPriority, error) {
+	order, weights := os.Getenv("PULSE_PRIORITY_ORDER"), os.Getenv("PULSE_POOL_PRIORITY")
+	if order == "" && weights == "" {
+		return nil, nil
+	}
+	prio := &k8s.ValidationPriority{
+		PoolLabel:         os.Getenv("GPU_POOL_LABEL"),
+		MinPodPriority:    1,
+		NVLinkDomainLabel: os.Getenv("PULSE_NVLINK_DOMAIN_LABEL"),
+	}
+	var err error
+	if order != "" {
+		if prio.Criteria, err = k8s.ParsePriorityCriteria(order); err != nil {
+			return nil, fmt.Errorf("PULSE_PRIORITY_ORDER: %w", err)
+		}
+	}
+	if prio.PoolWeights, err = k8s.ParsePoolWeights(weights); err != nil {
+		return nil, fmt.Errorf("PULSE_POOL_PRIORITY: %w", err)
+	}
+	if s := os.Getenv("PULSE_PRIORITY_MIN_POD_PRIORITY"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("PULSE_PRIORITY_MIN_POD_PRIORITY=%q: want an integer", s)
+		}
+		prio.MinPodPriority = int32(n)
+	}
+	return prio, nil
+}
+
+// deliveryFromEnv reads the notification retry settings shared by the
+// aggregator publisher, the result webhook and the quarantine notifiers. DELIVERY_DEAD_LETTER_DIR
+// defaults to the host state directory in node mode; the central controller
+// has no host mount and dead-letters only when it is set.
+func deliveryFromEnv(mode string) (delivery.Config, error) {
+	var cfg delivery.Config
+	if s := os.Getenv("DELIVERY_MAX_ATTEMPTS"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("DELIVERY_MAX_ATTEMPTS=%q: want a positive integer", s)
+		}
+		cfg.MaxAttempts = n
+	}
+	var err error
+	if cfg.MaxBackoff, err = envDuration("DELIVERY_MAX_BACKOFF", delivery.DefaultMaxBackoff); err != nil {
+		return cfg, err
+	}
+	cfg.DeadLetterDir = os.Getenv("DELIVERY_DEAD_LETTER_DIR")
+	if cfg.DeadLetterDir == "" && mode == "node" {
+		cfg.DeadLetterDir = pulse.StateDir()
+	}
+	return cfg, nil
+}
+
+// notifiersFromEnv builds a delivery.Notifier for each configured
+// quarantine notification receiver: NOTIFY_WEBHOOK_URL, posting the
+// transition as JSON or as rendered by the template file
+// NOTIFY_WEBHOOK_TEMPLATE; NOTIFY_SLACK_URL, a Slack incoming webhook; and
+// NOTIFY_PAGERDUTY_ROUTING_KEY, a PagerDuty Events API v2 integration at
+// NOTIFY_PAGERDUTY_URL (default the public endpoint).
+func notifiersFromEnv(cfg delivery.Config) ([]*delivery.Notifier, error) {
+	var notifiers []*delivery.Notifier
+	url, path := os.Getenv("NOTIFY_WEBHOOK_URL"), os.Getenv("NOTIFY_WEBHOOK_TEMPLATE")
+	switch {
+	case url == "" && path != "":
+		return nil, errors.New("NOTIFY_WEBHOOK_TEMPLATE is set but NOTIFY_WEBHOOK_URL is not")
+	case url != "":
+		tmpl, err := delivery.NotifyTemplate(delivery.FormatGeneric)
+		if path != "" {
+			text, rerr := os.ReadFile(path)
+			if rerr != nil {
+				return nil, fmt.Errorf("NOTIFY_WEBHOOK_TEMPLATE: %w", rerr)
+			}
+			if tmpl, err = delivery.ParseNotifyTemplate(string(text)); err != nil {
+				return nil, fmt.Errorf("NOTIFY_WEBHOOK_TEMPLATE %s: %w", path, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfg.Name = "notify"
+		notifiers = append(notifiers, delivery.NewNotifier(url, tmpl, "", cfg, slog.Default()))
+	}
+	if url := os.Getenv("NOTIFY_SLACK_URL"); url != "" {
+		tmpl, err := delivery.NotifyTemplate(delivery.FormatSlack)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Name = "slack"
+		notifiers = append(notifiers, delivery.NewNotifier(url, tmpl, "", cfg, slog.Default()))
+	}
+	if key := os.Getenv("NOTIFY_PAGERDUTY_ROUTING_KEY"); key != "" {
+		tmpl, err := delivery.NotifyTemplate(delivery.FormatPagerDuty)
+		if err != nil {
+			return nil, err
+		}
+		url := os.Getenv("NOTIFY_PAGERDUTY_URL")
+		if url == "" {
+			url = delivery.PagerDutyEventsURL
+		}
+		cfg.Name = "pagerduty"
+		notifiers = append(notifiers, delivery.NewNotifier(url, tmpl, key, cfg, slog.Default()))
+	}
+	return notifiers, nil
+}
+
+// slurmFromEnv builds the Slurm drainer. Disabled (nil) unless
+// SLURM_REST_URL is set. SLURM_USER and the JWT in SLURM_JWT_FILE
+// authenticate to slurmrestd; SLURM_REST_API_VERSION picks the API path.
+func slurmFromEnv(cfg delivery.Config) (*delivery.SlurmDrainer, error) {
+	sc := delivery.SlurmConfig{
+		URL:        os.Getenv("SLURM_REST_URL"),
+		APIVersion: os.Getenv("SLURM_REST_API_VERSION"),
+		User:       os.Getenv("SLURM_USER"),
+		TokenFile:  os.Getenv("SLURM_JWT_FILE"),
+	}
+	if sc.URL == "" {
+		if sc.User != "" || sc.TokenFile != "" || sc.APIVersion != "" {
+			return nil, errors.New("SLURM_USER, SLURM_JWT_FILE or SLURM_REST_API_VERSION is set but SLURM_REST_URL is not")
+		}
+		return nil, nil
+	}
+	if sc.TokenFile != "" {
+		if _, err := os.Stat(sc.TokenFile); err != nil {
+			return nil, fmt.Errorf("SLURM_JWT_FILE: %w", err)
+		}
+	}
+	cfg.Name = "slurm"
+	return delivery.NewSlurmDrainer(sc, cfg, slog.Default()), nil
+}
+
+// guardFromEnv reads the blast-radius guard. Disabled (nil) unless
+// QUARANTINE_MAX_PER_HOUR or QUARANTINE_MAX_PERCENT is set. The shared
+// window lives in the ConfigMap QUARANTINE_GUARD_CONFIGMAP in POD_NAMESPACE
+// (default straggler-shield).
+func guardFromEnv() (*k8s.QuarantineGuard, error) {
+	g := &k8s.QuarantineGuard{
+		Namespace: os.Getenv("POD_NAMESPACE"),
+		Name:      os.Getenv("QUARANTINE_GUARD_CONFIGMAP"),
+	}
+	if g.Namespace == "" {
+		g.Namespace = "straggler-shield"
+	}
+	if s := os.Getenv("QUARANTINE_MAX_PER_HOUR"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("QUARANTINE_MAX_PER_HOUR=%q: want a positive integer", s)
+		}
+		g.MaxPerHour = n
+	}
+	if s := os.Getenv("QUARANTINE_MAX_PERCENT"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 || v > 100 {
+			return nil, fmt.Errorf("QUARANTINE_MAX_PERCENT=%q: want a percentage above 0 and at most 100", s)
+		}
+		g.MaxPercent = v
+	}
+	if g.MaxPerHour == 0 && g.MaxPercent == 0 {
+		return nil, nil
+	}
+	return g, nil
+}
+
+// evictionFromEnv reads the post-quarantine drain settings. Disabled (nil)
+// unless QUARANTINE_EVICT_GPU_PODS is "true". QUARANTINE_EVICTION_GRACE_PERIOD
+// overrides each pod's termination grace period and
+// QUARANTINE_EVICTION_TIMEOUT (default 10m) bounds retries of evictions
+// blocked by a PodDisruptionBudget.
+func evictionFromEnv() (*k8s.EvictionConfig, error) {
+	switch s := os.Getenv("QUARANTINE_EVICT_GPU_PODS"); s {
+	case "", "false":
+		return nil, nil
+	case "true":
+	default:
+		return nil, fmt.Errorf("QUARANTINE_EVICT_GPU_PODS=%q: want true or false", s)
+	}
+	grace, err := envDuration("QUARANTINE_EVICTION_GRACE_PERIOD", 0)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := envDuration("QUARANTINE_EVICTION_TIMEOUT", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return &k8s.EvictionConfig{GracePeriod: grace, Timeout: timeout}, nil
+}
+
+// envDuration parses a positive Go duration from key, returning def if unset.
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%s=%q: want a positive duration", key, s)
+	}
+	return v, nil
 }
 
 // serveMetrics runs the Prometheus /metrics endpoint on :9090 until ctx is
 // cancelled. Exits cleanly on SIGINT/SIGTERM via srv.Shutdown.
 func serveMetrics(ctx context.Context) {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	// OpenMetrics must be negotiable for exemplars (pulse IDs on
+	// gpu_validator_pulse_duration_seconds) to be exposed.
+	mux.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	}))
 
 	srv := &http.Server{Addr: ":9090", Handler: mux}
 
@@ -80,68 +1225,66 @@ func serveMetrics(ctx context.Context) {
 	}
 }
 
-// run watches the node's Ready condition indefinitely, reconnecting with
-// exponential backoff whenever the API server closes the watch channel.
-// The API server closes watch streams server-side every 5–10 minutes by design;
-// this is normal and must never be treated as a fatal error.
-func run(ctx context.Context, ctrl *k8s.Controller, clientset kubernetes.Interface, nodeName string) {
-	const maxBackoff = 30 * time.Second
-	backoff := time.Second
+// nodeResync is how often the node informer re-delivers the cached node as
+// an update. Resyncs carry no Ready edge, but they re-check the markers
+// NeedsReconcile looks at, so an open failure streak is confirmed even if
+// the node object itself stops changing.
+const nodeResync = 5 * time.Minute
 
-	for {
-		if err := watchOnce(ctx, ctrl, clientset, nodeName); err != nil {
-			if ctx.Err() != nil {
-				return // context cancelled — clean shutdown
-			}
-			slog.Warn("watch ended, reconnecting", "node", nodeName, "err", err, "backoff", backoff)
-		}
-		if ctx.Err() != nil {
-			return
-		}
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(backoff):
-			backoff = min(backoff*2, maxBackoff)
-		}
+// run watches the Ready condition of the agent's own node (scope selects it
+// by name) until ctx is cancelled. A shared informer lists the node and then
+// watches from the listed resourceVersion; after a closed stream it resumes
+// from the last version it saw, and after an expired one it relists, so
+// reconnects neither miss nor replay events. Its reflector backs off on
+// errors by itself.
+func run(ctx context.Context, ctrl *k8s.Controller, clientset kubernetes.Interface, scope metav1.ListOptions) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, nodeResync,
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.LabelSelector = scope.LabelSelector
+			o.FieldSelector = scope.FieldSelector
+		}))
+	informer := factory.Core().V1().Nodes().Informer()
+	if _, err := informer.AddEventHandler(nodeHandler(ctx, ctrl)); err != nil {
+		slog.Error("failed to register node handler", "err", err)
+		os.Exit(1)
 	}
-}
-
-// watchOnce opens a single watch stream and processes node events until the
-// stream closes or the context is cancelled. A closed channel is returned as
-// nil so run() reconnects without logging a spurious error.
-func watchOnce(ctx context.Context, ctrl *k8s.Controller, clientset kubernetes.Interface, nodeName string) error {
-	w, err := clientset.CoreV1().Nodes().Watch(ctx, metav1.ListOptions{
-		FieldSelector: "metadata.name=" + nodeName,
-	})
-	if err != nil {
-		return fmt.Errorf("watch node %s: %w", nodeName, err)
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return // context cancelled — clean shutdown
 	}
-	defer w.Stop()
-
-	var wasReady bool
+	watchSynced()
+	slog.Info("node informer synced", "field_selector", scope.FieldSelector)
+	<-ctx.Done()
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case ev, ok := <-w.ResultChan():
-			if !ok {
-				return nil // server closed — caller reconnects
-			}
-			if ev.Type != watch.Modified && ev.Type != watch.Added {
-				continue
+// nodeHandler adapts reconcileOnChange to the informer's event handler.
+func nodeHandler(ctx context.Context, ctrl *k8s.Controller) cache.ResourceEventHandler {
+	onChange := reconcileOnChange(ctx, ctrl)
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if node, ok := obj.(*corev1.Node); ok {
+				onChange(nil, node)
 			}
-			node, ok := ev.Object.(*corev1.Node)
-			if !ok {
-				continue
+		},
+		UpdateFunc: func(oldObj, obj any) {
+			old, _ := oldObj.(*corev1.Node)
+			if node, ok := obj.(*corev1.Node); ok {
+				onChange(old, node)
 			}
+		},
+	}
+}
 
-			ready := k8s.IsNodeReady(node)
-			if ready && !wasReady {
-				go tryReconcile(ctx, ctrl, nodeName)
-			}
-			wasReady = ready
+// reconcileOnChange hands node changes that need a pulse to tryReconcile.
+// The Ready edge is taken from the previous copy of the node; an added node
+// has none, so a node that is already Ready at startup is reconciled.
+func reconcileOnChange(ctx context.Context, ctrl *k8s.Controller) func(old, node *corev1.Node) {
+	return func(old, node *corev1.Node) {
+		watchEvent()
+		ctrl.ObserveNode(node)
+		if ctrl.NeedsReconcile(node, old != nil && k8s.IsNodeReady(old)) {
+			go tryReconcile(ctx, ctrl, node.Name)
 		}
 	}
 }
@@ -149,17 +1292,60 @@ func watchOnce(ctx context.Context, ctrl *k8s.Controller, clientset kubernetes.I
 // tryReconcile acquires a per-node TryLock before calling ReconcileNode.
 // If a reconciliation is already in progress for this node, the event is
 // discarded — the in-flight pulse will apply or clear the taint based on its
-// result, and a duplicate run would observe the same GPU state anyway.
+// result, and a duplicate run would observe the same GPU state anyway. A
+// pulse deferred by the busy-node guard is retried after busyRetryInterval,
+// an inconclusive one after inconclusiveRetryInterval.
 func tryReconcile(ctx context.Context, ctrl *k8s.Controller, nodeName string) {
-	v, _ := nodeLocks.LoadOrStore(nodeName, &sync.Mutex{})
-	mu := v.(*sync.Mutex)
-	if !mu.TryLock() {
-		slog.Info("reconcile already in progress — discarding duplicate ready event", "node", nodeName)
+	withNodeLock(nodeName, func() {
+		err := ctrl.ReconcileNode(ctx, nodeName)
+		switch {
+		case errors.Is(err, k8s.ErrNodeBusy):
+			scheduleRetry(ctx, ctrl, nodeName, busyRetryInterval)
+		case errors.Is(err, k8s.ErrRetryInconclusive):
+			scheduleRetry(ctx, ctrl, nodeName, inconclusiveRetryInterval)
+		case err != nil:
+			slog.Error("reconcile failed", "node", nodeName, "err", err)
+		}
+	})
+}
+
+// scheduleRetry reconciles nodeName again after interval, unless a retry is
+// already scheduled for it.
+func scheduleRetry(ctx context.Context, ctrl *k8s.Controller, nodeName string, interval time.Duration) {
+	if _, scheduled := retries.LoadOrStore(nodeName, struct{}{}); scheduled {
 		return
 	}
-	defer mu.Unlock()
+	time.AfterFunc(interval, func() {
+		retries.Delete(nodeName)
+		if ctx.Err() == nil {
+			tryReconcile(ctx, ctrl, nodeName)
+		}
+	})
+}
 
-	if err := ctrl.ReconcileNode(ctx, nodeName); err != nil {
-		slog.Error("reconcile failed", "node", nodeName, "err", err)
+// withNodeLock runs fn under the node's TryLock, or skips it if another pulse
+// (Ready-triggered or periodic) is already in flight for the node.
+func withNodeLock(nodeName string, fn func()) {
+	unlock, ok := lockNode(nodeName)
+	if !ok {
+		slog.Info("pulse already in progress — discarding duplicate trigger", "node", nodeName)
+		return
+	}
+	defer unlock()
+	fn()
+}
+
+// lockNode takes the node's pulse lock if it is free, recording it in
+// inFlight until unlock is called.
+func lockNode(nodeName string) (unlock func(), ok bool) {
+	v, _ := nodeLocks.LoadOrStore(nodeName, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, false
 	}
+	inFlight.Store(nodeName, time.Now())
+	return func() {
+		inFlight.Delete(nodeName)
+		mu.Unlock()
+	}, true
 }
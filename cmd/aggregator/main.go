@@ -0,0 +1,123 @@
+// Command aggregator serves a fleet-wide view of straggler-shield state. It
+// polls every cluster listed in AGGREGATOR_CONFIG for node markers, and keeps
+// the pulse report history agents push to it (AGGREGATOR_URL on the agent).
+// At least one of the two must be in use, and it refuses to start otherwise;
+// without AGGREGATOR_CONFIG only the report API is served. Agents must
+// present the token in AGGREGATOR_INGEST_TOKEN_FILE to push reports.
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/justin-oleary/straggler-shield/pkg/aggregator"
+)
+
+// Server timeouts. A submission is at most 1 MiB, so a client that takes
+// longer than this is stalled, not slow.
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	var cfg *aggregator.Config
+	var err error
+	if path := os.Getenv("AGGREGATOR_CONFIG"); path != "" {
+		if cfg, err = aggregator.LoadConfig(path); err != nil {
+			slog.Error("failed to load aggregator config", "err", err)
+			os.Exit(1)
+		}
+	}
+	// AGGREGATOR_INGEST_TOKEN_FILE holds the bearer token agents push
+	// reports with (AGGREGATOR_TOKEN_FILE on the agent). Without it the
+	// report history is read-only.
+	tokenFile := os.Getenv("AGGREGATOR_INGEST_TOKEN_FILE")
+	if cfg == nil && tokenFile == "" {
+		slog.Error("nothing to aggregate — set AGGREGATOR_CONFIG to poll clusters, AGGREGATOR_INGEST_TOKEN_FILE to accept pushed reports, or both")
+		os.Exit(1)
+	}
+
+	// AGGREGATOR_HISTORY_PER_NODE bounds the pulse reports kept per node;
+	// AGGREGATOR_HISTORY_FILE persists them across restarts.
+	retain := 48
+	if v := os.Getenv("AGGREGATOR_HISTORY_PER_NODE"); v != "" {
+		if retain, err = strconv.Atoi(v); err != nil || retain < 1 {
+			slog.Error("invalid AGGREGATOR_HISTORY_PER_NODE — want a positive integer", "value", v)
+			os.Exit(1)
+		}
+	}
+	store, err := aggregator.NewReportStore(retain, os.Getenv("AGGREGATOR_HISTORY_FILE"))
+	if err != nil {
+		slog.Error("failed to load report history", "err", err)
+		os.Exit(1)
+	}
+
+	interval := 30 * time.Second
+	if v := os.Getenv("AGGREGATOR_REFRESH_INTERVAL"); v != "" {
+		if interval, err = time.ParseDuration(v); err != nil || interval <= 0 {
+			slog.Error("invalid AGGREGATOR_REFRESH_INTERVAL", "value", v)
+			os.Exit(1)
+		}
+	}
+	addr := os.Getenv("AGGREGATOR_LISTEN_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	reports := store.Handler(slog.Default(), tokenFile)
+	mux.Handle("/v1/reports", reports)
+	mux.Handle("/v1/fleet/", reports)
+	mux.Handle("GET /v1/openapi.yaml", aggregator.OpenAPIHandler())
+
+	clusters := 0
+	if cfg != nil {
+		fed, err := aggregator.NewFederation(cfg, slog.Default())
+		if err != nil {
+			slog.Error("failed to build cluster clients", "err", err)
+			os.Exit(1)
+		}
+		go fed.Run(ctx, interval)
+		mux.Handle("/", fed.Handler())
+		clusters = len(cfg.Clusters)
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			slog.Error("aggregator server shutdown error", "err", err)
+		}
+	}()
+
+	slog.Info("aggregator listening", "addr", addr, "clusters", clusters, "refresh_interval", interval, "history_per_node", retain, "ingest", tokenFile != "")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("aggregator server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+// Command alert-rules prints the recommended Prometheus alerts for
+// straggler-shield, generated from the metric names in pkg/metrics so the
+// rules change with the code:
+//
+//	alert-rules [--format=prometheusrule|rules] [--namespace=NS] [--job=JOB] > rules.yaml
+//
+// The default output is a PrometheusRule for the Prometheus Operator.
+// --format=rules prints a plain rules file for Prometheus' rule_files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	"sigs.k8s.io/yaml"
+)
+
+type prometheusRule struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   prometheusRuleMeta `json:"metadata"`
+	Spec       rulesFile          `json:"spec"`
+}
+
+type prometheusRuleMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type rulesFile struct {
+	Groups []metrics.RuleGroup `json:"groups"`
+}
+
+func main() {
+	d := metrics.DefaultAlertOptions
+	format := flag.String("format", "prometheusrule", "output format: prometheusrule or rules")
+	name := flag.String("name", "straggler-shield", "PrometheusRule name")
+	namespace := flag.String("namespace", "", "PrometheusRule namespace")
+	selector := flag.String("rule-label", "", "extra PrometheusRule label, key=value, for the operator's ruleSelector")
+	var o metrics.AlertOptions
+	flag.StringVar(&o.Job, "job", d.Job, "scrape job of the agent's /metrics endpoint")
+	flag.DurationVar(&o.MaxPulseAge, "max-pulse-age", d.MaxPulseAge, "alert when a node has gone this long without a pulse")
+	flag.IntVar(&o.QuarantineSpike, "quarantine-spike", d.QuarantineSpike, "alert when this many nodes are quarantined within --spike-window")
+	flag.DurationVar(&o.SpikeWindow, "spike-window", d.SpikeWindow, "window for --quarantine-spike")
+	flag.Float64Var(&o.CVRatio, "cv-ratio", d.CVRatio, "alert when a device's 6h average CV exceeds its 7d average by this factor")
+	flag.Float64Var(&o.CVFloor, "cv-floor", d.CVFloor, "ignore creeping CV below this value")
+	flag.Parse()
+
+	groups := metrics.AlertRules(o)
+	var out any
+	switch *format {
+	case "rules":
+		out = rulesFile{Groups: groups}
+	case "prometheusrule":
+		labels := map[string]string{"app.kubernetes.io/name": "straggler-shield"}
+		if *selector != "" {
+			k, v, ok := strings.Cut(*selector, "=")
+			if !ok || k == "" {
+				fatalf("--rule-label %q: want key=value", *selector)
+			}
+			labels[k] = v
+		}
+		out = prometheusRule{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+			Metadata:   prometheusRuleMeta{Name: *name, Namespace: *namespace, Labels: labels},
+			Spec:       rulesFile{Groups: groups},
+		}
+	default:
+		fatalf("--format %q: want prometheusrule or rules", *format)
+	}
+
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		fatalf("marshal rules: %v", err)
+	}
+	os.Stdout.Write(data)
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "alert-rules: "+format+"\n", args...)
+	os.Exit(2)
+}
@@ -6,7 +6,8 @@
 //
 // Usage:
 //
-//	benchmark [--scenario=<name>] [--count=<n>]
+//	benchmark [--scenario=<name>] [--count=<n>] [--fail-on-straggler] [--push-gateway=<url>]
+//	benchmark [--scenario=<name>] --duration=2h [--max-drift=<pct>] [--window=<d>]
 //
 // Scenarios:
 //
@@ -20,16 +21,44 @@
 // Output is a structured JSON report written to stdout. Each run's
 // measured_value and threshold_value fields are the literal numbers used
 // to make the quarantine decision — suitable for direct use as MFU evidence.
+//
+// Every invocation gets a random UUID, the report's run_id. With
+// --push-gateway the pulse metrics the runs recorded and a summary of the
+// report are pushed to that Pushgateway grouped under a run_id label, so
+// burn-in automation can join a report file with the series it produced.
+//
+// With --duration the benchmark soaks the node instead: it runs pulses back
+// to back until the duration has passed (or it is interrupted), then reports
+// a time series of latency per --window and the least-squares latency growth
+// per hour. Growth beyond --max-drift percent per hour, such as a thermal
+// ramp on a new rack, makes the verdict DRIFTING.
+//
+// Exit status is 0 once the report is written, whatever the verdict, and 1 on
+// a usage error. A failed push is reported on stderr and does not change it.
+// With --fail-on-straggler it follows the summary verdict
+// instead, so a provisioning pipeline can gate node handoff on it:
+//
+//	0  HEALTHY
+//	1  ERROR: a run could not measure the node (e.g. built without cuda, or
+//	   the GEMM never reached PULSE_MIN_SM_UTIL)
+//	2  STRAGGLER
+//	3  DEGRADED: every check passed, some within the degraded margin
+//	4  DRIFTING: every check passed, but latency grew beyond --max-drift
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/justin-oleary/straggler-shield/pkg/pulse"
 )
 
@@ -37,33 +66,86 @@ import (
 type runResult struct {
 	Run            int     `json:"run"`
 	ElapsedMS      int64   `json:"elapsed_ms"`
-	Verdict        string  `json:"verdict"` // "pass" | "fail"
+	Verdict        string  `json:"verdict"` // "pass" | "degraded" | "fail" | "error"
 	FailureReason  string  `json:"failure_reason,omitempty"`
 	MeasuredValue  float64 `json:"measured_value,omitempty"`
 	ThresholdValue float64 `json:"threshold_value,omitempty"`
-	Unit           string  `json:"unit,omitempty"` // "ms" | "cv" | "gbs"
+	Unit           string  `json:"unit,omitempty"` // "ms" | "cv" | "gbs" | "gpus" | "util" | "w" | "mib"
+	// GPU identifies the failing device for an RMA ticket, when known.
+	GPU *pulse.GPUIdentity `json:"gpu,omitempty"`
+	// ThresholdArch names the failing device's own calibration on a node
+	// mixing GPU models.
+	ThresholdArch string `json:"threshold_arch,omitempty"`
+
+	// Full evidence from the pulse report; only the real scenario fills these.
+	PulseID string               `json:"pulse_id,omitempty"`
+	Devices []pulse.DeviceResult `json:"devices,omitempty"`
+	Links   []pulse.LinkResult   `json:"links,omitempty"`
 }
 
 type reportSummary struct {
 	Total          int    `json:"total"`
 	Passed         int    `json:"passed"`
+	Degraded       int    `json:"degraded"`
 	Failed         int    `json:"failed"`
+	Errored        int    `json:"errored"`
 	WorstElapsedMS int64  `json:"worst_elapsed_ms"`
-	Verdict        string `json:"verdict"` // "HEALTHY" | "STRAGGLER"
+	Verdict        string `json:"verdict"` // "HEALTHY" | "DEGRADED" | "DRIFTING" | "STRAGGLER" | "ERROR"
+}
+
+// Exit statuses under --fail-on-straggler, by summary verdict.
+const (
+	exitHealthy   = 0
+	exitError     = 1
+	exitStraggler = 2
+	exitDegraded  = 3
+	exitDrifting  = 4
+)
+
+// exitCode maps a summary verdict to its exit status.
+func exitCode(verdict string) int {
+	switch verdict {
+	case "HEALTHY":
+		return exitHealthy
+	case "STRAGGLER":
+		return exitStraggler
+	case "DEGRADED":
+		return exitDegraded
+	case "DRIFTING":
+		return exitDrifting
+	default:
+		return exitError
+	}
 }
 
 type report struct {
-	Timestamp          string        `json:"timestamp"`
-	Hostname           string        `json:"hostname"`
-	GPUArch            string        `json:"gpu_arch"`
-	CalibratedThreshMS int64         `json:"calibrated_threshold_ms"`
-	Scenario           string        `json:"scenario"`
-	Runs               []runResult   `json:"runs"`
-	Summary            reportSummary `json:"summary"`
+	// RunID identifies the invocation; pushed series carry it as run_id.
+	RunID              string         `json:"run_id"`
+	Timestamp          string         `json:"timestamp"`
+	Hostname           string         `json:"hostname"`
+	GPUArch            string         `json:"gpu_arch"`
+	CalibratedThreshMS int64          `json:"calibrated_threshold_ms"`
+	Thresholds         pulse.Snapshot `json:"thresholds"`
+	// Checks are the settings outside the thresholds, so the header
+	// describes every check the runs applied.
+	Checks   pulse.CheckSettings `json:"checks"`
+	Scenario string              `json:"scenario"`
+	Runs     []runResult         `json:"runs"`
+	Summary  reportSummary       `json:"summary"`
+	Soak     *soakSummary        `json:"soak,omitempty"`
 }
 
-// scenario is a function that mimics the pulse.RunPulse signature.
-type scenario func() (time.Duration, error)
+// scenario is a function that mimics the pulse.RunPulseReport signature.
+type scenario func() (*pulse.PulseReport, error)
+
+// simulated adapts a RunPulse-style function, which yields no per-device
+// evidence, to a scenario.
+func simulated(fn func() (time.Duration, error)) scenario {
+	return func() (*pulse.PulseReport, error) {
+		elapsed, err := fn()
+		return &pulse.PulseReport{WorstMeanNS: elapsed.Nanoseconds()}, err
+	}
+}
 
 // scenarios maps CLI names to pulse functions. Simulated scenarios are
 // threshold-aware — elapsed values scale with the calibrated device threshold
@@ -71,19 +153,21 @@ type scenario func() (time.Duration, error)
 var scenarios = map[string]scenario{
 	// real: invokes the actual CUDA pipeline. Works with -tags cuda + GPU;
 	// returns a "built without cuda support" error in stub builds.
-	"real": pulse.RunPulse,
+	"real": func() (*pulse.PulseReport, error) {
+		return pulse.RunPulseReport(context.Background())
+	},
 
 	// healthy: mean latency at 25% of threshold — clearly passing on any arch.
-	"healthy": func() (time.Duration, error) {
+	"healthy": simulated(func() (time.Duration, error) {
 		elapsed := time.Duration(pulse.ThresholdMS()/4) * time.Millisecond
 		if elapsed < time.Millisecond {
 			elapsed = time.Millisecond
 		}
 		return elapsed, nil
-	},
+	}),
 
 	// straggler: mean latency at 5× threshold — unambiguous latency failure.
-	"straggler": func() (time.Duration, error) {
+	"straggler": simulated(func() (time.Duration, error) {
 		threshMS := pulse.ThresholdMS()
 		elapsed := time.Duration(threshMS*5) * time.Millisecond
 		return elapsed, &pulse.PulseFailure{
@@ -92,39 +176,62 @@ var scenarios = map[string]scenario{
 			ThresholdValue: float64(threshMS),
 			Unit:           "ms",
 		}
-	},
+	}),
 
 	// high-variance: mean at 33% of threshold (passes latency check) but
-	// CV = 0.35 — a textbook fail-slow Falcon-paper pattern.
-	"high-variance": func() (time.Duration, error) {
+	// CV at 1.75× the active ceiling (0.35 against the default 0.20) — a
+	// textbook fail-slow Falcon-paper pattern.
+	"high-variance": simulated(func() (time.Duration, error) {
 		elapsed := time.Duration(pulse.ThresholdMS()/3) * time.Millisecond
 		if elapsed < time.Millisecond {
 			elapsed = time.Millisecond
 		}
+		maxCV := pulse.Active().MaxCV()
 		return elapsed, &pulse.PulseFailure{
-			Cause:          fmt.Errorf("GPU 0: %w (cv=0.350)", pulse.ErrHighVariance),
-			MeasuredValue:  0.350,
-			ThresholdValue: 0.20,
+			Cause:          fmt.Errorf("GPU 0: %w (cv=%.3f)", pulse.ErrHighVariance, 1.75*maxCV),
+			MeasuredValue:  1.75 * maxCV,
+			ThresholdValue: maxCV,
 			Unit:           "cv",
 		}
-	},
+	}),
 
-	// p2p-degraded: NVLink ring segment 2→3 measuring 1.2 GB/s against the
-	// 5 GB/s minimum — simulates a partially failed NVSwitch fabric port.
-	"p2p-degraded": func() (time.Duration, error) {
+	// p2p-degraded: NVLink ring segment 2→3 measuring a quarter of the
+	// active minimum (1.25 GB/s against the default 5 GB/s) — simulates a
+	// partially failed NVSwitch fabric port.
+	"p2p-degraded": simulated(func() (time.Duration, error) {
+		minGBs := pulse.Active().MinP2PBandwidthGBs()
 		return 0, &pulse.PulseFailure{
-			Cause:          fmt.Errorf("GPU 2→3: %w (1.20 GB/s < 5.0 GB/s minimum)", pulse.ErrInterconnectDegraded),
-			MeasuredValue:  1.20,
-			ThresholdValue: 5.0,
+			Cause:          fmt.Errorf("GPU 2→3: %w (%.2f GB/s < %.1f GB/s minimum)", pulse.ErrInterconnectDegraded, minGBs/4, minGBs),
+			MeasuredValue:  minGBs / 4,
+			ThresholdValue: minGBs,
 			Unit:           "gbs",
 		}
-	},
+	}),
 }
 
 func main() {
+	// On MIG nodes the real pulse re-executes this binary once per instance
+	// (see pulse.MIGInstanceEnv); serve those children before parsing flags.
+	if len(os.Args) > 1 && os.Args[1] == pulse.ChildArg {
+		if err := pulse.ServeRunner(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "pulse child: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	scenarioName := flag.String("scenario", "real",
 		"pulse scenario: real, healthy, straggler, high-variance, p2p-degraded")
 	count := flag.Int("count", 3, "number of benchmark runs")
+	failOnStraggler := flag.Bool("fail-on-straggler", false,
+		"exit non-zero unless the verdict is HEALTHY: 1 error, 2 straggler, 3 degraded, 4 drifting")
+	duration := flag.Duration("duration", 0,
+		"soak: run pulses back to back for this long instead of --count runs, e.g. 2h")
+	window := flag.Duration("window", 10*time.Minute, "soak: time-series window")
+	maxDrift := flag.Float64("max-drift", 10,
+		"soak: latency growth in percent per hour above which the verdict is DRIFTING")
+	pushGateway := flag.String("push-gateway", "",
+		"Pushgateway URL to push the run's metrics to, grouped by run_id, e.g. http://pushgateway:9091")
 	flag.Parse()
 
 	fn, ok := scenarios[*scenarioName]
@@ -136,25 +243,54 @@ func main() {
 		fmt.Fprintf(os.Stderr, "--count must be >= 1\n")
 		os.Exit(1)
 	}
+	if *duration < 0 || *window < time.Second || *maxDrift < 0 {
+		fmt.Fprintf(os.Stderr, "--duration and --max-drift must be >= 0, --window >= 1s\n")
+		os.Exit(1)
+	}
 
 	hostname, _ := os.Hostname()
 
-	runs := execute(fn, *count)
+	var (
+		runs   []runResult
+		soaked *soakSummary
+	)
+	if *duration > 0 {
+		// An interrupted soak still reports what it measured.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		runs, soaked = soak(ctx, fn, *duration, *window, *maxDrift)
+		stop()
+	} else {
+		runs = execute(fn, *count)
+	}
 	r := report{
+		RunID:              uuid.NewString(),
 		Timestamp:          time.Now().UTC().Format(time.RFC3339),
 		Hostname:           hostname,
 		GPUArch:            pulse.DetectGPUName(),
 		CalibratedThreshMS: pulse.ThresholdMS(),
+		Thresholds:         pulse.Active().Snapshot(),
+		Checks:             pulse.ActiveChecks(),
 		Scenario:           *scenarioName,
 		Runs:               runs,
-		Summary:            summarize(runs),
+		Summary:            summarize(runs, soaked),
+		Soak:               soaked,
 	}
 
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(r); err != nil {
 		fmt.Fprintf(os.Stderr, "json encode: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+	if *pushGateway != "" {
+		// The report is already written; a failed push must not turn
+		// the verdict's exit status into an error.
+		if err := pushMetrics(*pushGateway, r); err != nil {
+			fmt.Fprintf(os.Stderr, "push to %s: %v\n", *pushGateway, err)
+		}
+	}
+	if *failOnStraggler {
+		os.Exit(exitCode(r.Summary.Verdict))
 	}
 }
 
@@ -162,44 +298,75 @@ func main() {
 func execute(fn scenario, count int) []runResult {
 	results := make([]runResult, 0, count)
 	for i := 1; i <= count; i++ {
-		elapsed, err := fn()
-		r := runResult{
-			Run:       i,
-			ElapsedMS: elapsed.Milliseconds(),
-		}
-		if err == nil {
-			r.Verdict = "pass"
-		} else {
-			r.Verdict = "fail"
-			r.FailureReason = err.Error()
-			var detail *pulse.PulseFailure
-			if errors.As(err, &detail) {
-				r.MeasuredValue = detail.MeasuredValue
-				r.ThresholdValue = detail.ThresholdValue
-				r.Unit = detail.Unit
-			}
-		}
+		r, _ := runOnce(fn, i)
 		results = append(results, r)
 	}
 	return results
 }
 
-// summarize aggregates run results into a top-level verdict.
-func summarize(runs []runResult) reportSummary {
+// runOnce runs fn as run i and records its result and worst mean latency.
+func runOnce(fn scenario, i int) (runResult, time.Duration) {
+	report, err := fn()
+	r := runResult{
+		Run:       i,
+		ElapsedMS: report.Elapsed().Milliseconds(),
+		PulseID:   report.PulseID,
+		Devices:   report.Devices,
+		Links:     report.Links,
+	}
+	if err == nil {
+		r.Verdict = "pass"
+	} else {
+		switch {
+		case errors.Is(err, pulse.ErrNearThreshold):
+			r.Verdict = "degraded"
+		case errors.Is(err, pulse.ErrNoCUDA), errors.Is(err, pulse.ErrInconclusive):
+			r.Verdict = "error"
+		default:
+			r.Verdict = "fail"
+		}
+		r.FailureReason = err.Error()
+		var detail *pulse.PulseFailure
+		if errors.As(err, &detail) {
+			r.MeasuredValue = detail.MeasuredValue
+			r.ThresholdValue = detail.ThresholdValue
+			r.Unit = detail.Unit
+			r.GPU = detail.GPU
+			r.ThresholdArch = detail.Arch
+		}
+	}
+	return r, report.Elapsed()
+}
+
+// summarize aggregates run results, and the soak summary when there is one,
+// into a top-level verdict.
+func summarize(runs []runResult, soaked *soakSummary) reportSummary {
 	s := reportSummary{Total: len(runs)}
 	for _, r := range runs {
-		if r.Verdict == "pass" {
+		switch r.Verdict {
+		case "pass":
 			s.Passed++
-		} else {
+		case "degraded":
+			s.Degraded++
+		case "error":
+			s.Errored++
+		default:
 			s.Failed++
 		}
 		if r.ElapsedMS > s.WorstElapsedMS {
 			s.WorstElapsedMS = r.ElapsedMS
 		}
 	}
-	if s.Failed > 0 {
+	switch {
+	case s.Failed > 0:
 		s.Verdict = "STRAGGLER"
-	} else {
+	case s.Errored > 0:
+		s.Verdict = "ERROR"
+	case soaked != nil && soaked.Drifting:
+		s.Verdict = "DRIFTING"
+	case s.Degraded > 0:
+		s.Verdict = "DEGRADED"
+	default:
 		s.Verdict = "HEALTHY"
 	}
 	return s
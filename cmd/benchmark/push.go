@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// pushJob is the Pushgateway job the benchmark pushes under.
+const pushJob = "straggler-shield-benchmark"
+
+// pushMetrics pushes the pulse metrics the runs recorded, with a summary of
+// r, to the Pushgateway at url. The process's own go_* and process_* series
+// describe a one-shot benchmark, not the node, and are left out. Every series is grouped under r.RunID as the
+// run_id label, so each invocation's series stay apart and join its report
+// file. A Pushgateway drops exemplars, so the label is the only link.
+func pushMetrics(url string, r report) error {
+	runs := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpu_validator_benchmark_runs",
+		Help: "Benchmark runs in the invocation, by verdict.",
+	}, []string{"verdict"})
+	for verdict, n := range map[string]int{
+		"pass":     r.Summary.Passed,
+		"degraded": r.Summary.Degraded,
+		"fail":     r.Summary.Failed,
+		"error":    r.Summary.Errored,
+	} {
+		runs.WithLabelValues(verdict).Set(float64(n))
+	}
+	worst := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gpu_validator_benchmark_worst_elapsed_ms",
+		Help: "Worst elapsed time of any run in the invocation, in milliseconds.",
+	})
+	worst.Set(float64(r.Summary.WorstElapsedMS))
+
+	summary := prometheus.NewRegistry()
+	summary.MustRegister(runs, worst)
+	return push.New(url, pushJob).
+		Gatherer(prometheus.Gatherers{pulseMetrics, summary}).
+		Grouping("run_id", r.RunID).
+		Push()
+}
+
+// pulseMetrics gathers the pulse metrics (see pkg/metrics) from the default
+// registry, where they are registered, without the runtime collectors
+// registered alongside them.
+var pulseMetrics = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	kept := mfs[:0]
+	for _, mf := range mfs {
+		if strings.HasPrefix(mf.GetName(), "gpu_validator_") {
+			kept = append(kept, mf)
+		}
+	}
+	return kept, err
+})
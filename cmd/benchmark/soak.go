@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// soakWindow is one point of the soak time series: the runs that started
+// within Window of StartS seconds into the soak.
+type soakWindow struct {
+	StartS int64   `json:"start_s"`
+	Runs   int     `json:"runs"`
+	Failed int     `json:"failed"`
+	MeanMS float64 `json:"mean_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+// soakSummary reports how the worst mean latency moved over a soak. The slope
+// is a least-squares fit of every measured run's latency against time, so one
+// slow run moves it far less than a sustained thermal ramp does.
+type soakSummary struct {
+	DurationS          int64        `json:"duration_s"`
+	WindowS            int64        `json:"window_s"`
+	Windows            []soakWindow `json:"windows"`
+	StartMS            float64      `json:"start_ms"` // fitted latency at the start
+	SlopeMSPerHour     float64      `json:"slope_ms_per_hour"`
+	DriftPctPerHour    float64      `json:"drift_pct_per_hour"` // slope as a percentage of start_ms
+	MaxDriftPctPerHour float64      `json:"max_drift_pct_per_hour"`
+	Drifting           bool         `json:"drifting"`
+}
+
+// soakSample is a run's latency and when it started, relative to the soak.
+type soakSample struct {
+	at        time.Duration
+	latencyMS float64
+	measured  bool // false for runs that produced no latency, e.g. errors
+	failed    bool
+}
+
+// soak runs fn back to back until duration has passed or ctx is cancelled,
+// always finishing the pulse in flight. It returns every run and a summary
+// of the latency drift over the soak.
+func soak(ctx context.Context, fn scenario, duration, window time.Duration, maxDriftPct float64) ([]runResult, *soakSummary) {
+	var (
+		runs    []runResult
+		samples []soakSample
+	)
+	start := time.Now()
+	for i := 1; time.Since(start) < duration && ctx.Err() == nil; i++ {
+		at := time.Since(start)
+		r, elapsed := runOnce(fn, i)
+		runs = append(runs, r)
+		samples = append(samples, soakSample{
+			at:        at,
+			latencyMS: float64(elapsed) / float64(time.Millisecond),
+			measured:  elapsed > 0 && r.Verdict != "error",
+			failed:    r.Verdict == "fail",
+		})
+	}
+	s := summarizeSoak(samples, window, maxDriftPct)
+	s.DurationS = int64(time.Since(start).Seconds())
+	return runs, s
+}
+
+// summarizeSoak buckets samples into windows and fits the latency slope.
+// Drifting is set when the fitted growth exceeds maxDriftPct per hour; fewer
+// than two measured runs give no fit.
+func summarizeSoak(samples []soakSample, window time.Duration, maxDriftPct float64) *soakSummary {
+	s := &soakSummary{WindowS: int64(window.Seconds()), MaxDriftPctPerHour: maxDriftPct}
+
+	var xs, ys []float64
+	for _, sample := range samples {
+		idx := int(sample.at / window)
+		for len(s.Windows) <= idx {
+			s.Windows = append(s.Windows, soakWindow{StartS: int64(len(s.Windows)) * s.WindowS})
+		}
+		w := &s.Windows[idx]
+		if sample.failed {
+			w.Failed++
+		}
+		if !sample.measured {
+			continue
+		}
+		w.MeanMS = (w.MeanMS*float64(w.Runs) + sample.latencyMS) / float64(w.Runs+1)
+		w.Runs++
+		w.MaxMS = max(w.MaxMS, sample.latencyMS)
+		xs = append(xs, sample.at.Hours())
+		ys = append(ys, sample.latencyMS)
+	}
+
+	slope, intercept, ok := fitLine(xs, ys)
+	if !ok || intercept <= 0 {
+		return s
+	}
+	s.StartMS = intercept
+	s.SlopeMSPerHour = slope
+	s.DriftPctPerHour = slope / intercept * 100
+	s.Drifting = s.DriftPctPerHour > maxDriftPct
+	return s
+}
+
+// fitLine returns the least-squares line through (xs, ys). ok is false when
+// there are fewer than two distinct x values.
+func fitLine(xs, ys []float64) (slope, intercept float64, ok bool) {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0, 0, false
+	}
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/n, sy/n
+	var sxx, sxy float64
+	for i := range xs {
+		dx := xs[i] - mx
+		sxx += dx * dx
+		sxy += dx * (ys[i] - my)
+	}
+	if sxx == 0 {
+		return 0, 0, false
+	}
+	slope = sxy / sxx
+	return slope, my - slope*mx, true
+}
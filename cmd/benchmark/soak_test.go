@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFitLine(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name                 string
+		xs, ys               []float64
+		wantSlope, wantInter float64
+		wantOK               bool
+	}{
+		{name: "no samples"},
+		{name: "one sample", xs: []float64{1}, ys: []float64{20}},
+		{name: "all at the same x", xs: []float64{2, 2, 2}, ys: []float64{10, 20, 30}},
+		{name: "exact line", xs: []float64{0, 1, 2, 3}, ys: []float64{20, 22, 24, 26}, wantSlope: 2, wantInter: 20, wantOK: true},
+		{name: "flat", xs: []float64{0, 0.5, 1}, ys: []float64{25, 25, 25}, wantInter: 25, wantOK: true},
+		{name: "noisy", xs: []float64{0, 1, 2, 3}, ys: []float64{21, 21, 25, 25}, wantSlope: 1.6, wantInter: 20.6, wantOK: true},
+	}
+	for _, tc := range cases {
+		slope, intercept, ok := fitLine(tc.xs, tc.ys)
+		if ok != tc.wantOK || math.Abs(slope-tc.wantSlope) > 1e-9 || math.Abs(intercept-tc.wantInter) > 1e-9 {
+			t.Errorf("%s: fitLine = %v, %v, %v, want %v, %v, %v", tc.name, slope, intercept, ok, tc.wantSlope, tc.wantInter, tc.wantOK)
+		}
+	}
+}
+
+func TestSummarizeSoak(t *testing.T) {
+	t.Parallel()
+
+	run := func(at time.Duration, latencyMS float64) soakSample {
+		return soakSample{at: at, latencyMS: latencyMS, measured: true}
+	}
+	cases := []struct {
+		name         string
+		samples      []soakSample
+		wantWindows  []soakWindow
+		wantSlope    float64
+		wantDriftPct float64
+		wantDrifting bool
+	}{
+		{
+			name: "no samples",
+		},
+		{
+			name:        "one sample gives no fit",
+			samples:     []soakSample{run(time.Minute, 20)},
+			wantWindows: []soakWindow{{StartS: 0, Runs: 1, MeanMS: 20, MaxMS: 20}},
+		},
+		{
+			name:        "samples at the same time give no fit",
+			samples:     []soakSample{run(time.Minute, 20), run(time.Minute, 30)},
+			wantWindows: []soakWindow{{StartS: 0, Runs: 2, MeanMS: 25, MaxMS: 30}},
+		},
+		{
+			// 20ms rising 10% an hour, against a 5% limit. The error run
+			// counts as failed but is left out of the fit.
+			name: "thermal ramp drifts",
+			samples: []soakSample{
+				run(0, 20), run(30*time.Minute, 21),
+				{at: 40 * time.Minute, failed: true},
+				run(time.Hour, 22),
+			},
+			wantWindows: []soakWindow{
+				{StartS: 0, Runs: 1, MeanMS: 20, MaxMS: 20},
+				{StartS: 1800, Runs: 1, Failed: 1, MeanMS: 21, MaxMS: 21},
+				{StartS: 3600, Runs: 1, MeanMS: 22, MaxMS: 22},
+			},
+			wantSlope:    2,
+			wantDriftPct: 10,
+			wantDrifting: true,
+		},
+		{
+			// Windows with no runs still appear, so the series has no gaps.
+			name:         "steady latency within the limit",
+			samples:      []soakSample{run(0, 20), run(time.Hour, 20.5)},
+			wantWindows:  []soakWindow{{StartS: 0, Runs: 1, MeanMS: 20, MaxMS: 20}, {StartS: 1800}, {StartS: 3600, Runs: 1, MeanMS: 20.5, MaxMS: 20.5}},
+			wantSlope:    0.5,
+			wantDriftPct: 2.5,
+		},
+	}
+	for _, tc := range cases {
+		s := summarizeSoak(tc.samples, 30*time.Minute, 5)
+		if s.WindowS != 1800 || s.MaxDriftPctPerHour != 5 {
+			t.Errorf("%s: window %ds, limit %v, want 1800s and 5", tc.name, s.WindowS, s.MaxDriftPctPerHour)
+		}
+		if len(s.Windows) != len(tc.wantWindows) {
+			t.Errorf("%s: windows = %+v, want %+v", tc.name, s.Windows, tc.wantWindows)
+		} else {
+			for i := range s.Windows {
+				if s.Windows[i] != tc.wantWindows[i] {
+					t.Errorf("%s: window %d = %+v, want %+v", tc.name, i, s.Windows[i], tc.wantWindows[i])
+				}
+			}
+		}
+		if math.Abs(s.SlopeMSPerHour-tc.wantSlope) > 1e-9 || math.Abs(s.DriftPctPerHour-tc.wantDriftPct) > 1e-9 || s.Drifting != tc.wantDrifting {
+			t.Errorf("%s: slope %v, drift %v%%/h, drifting %v; want %v, %v, %v", tc.name, s.SlopeMSPerHour, s.DriftPctPerHour, s.Drifting, tc.wantSlope, tc.wantDriftPct, tc.wantDrifting)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Command kubectl-straggler is a kubectl plugin for operating straggler-shield:
+//
+//	kubectl straggler status [-l selector] [--all]
+//	kubectl straggler clear NODE --reason TEXT
+//	kubectl straggler pulse NODE
+//	kubectl straggler policy diff -f PROPOSED --aggregator URL
+//
+// status lists quarantined, suspected, scoped and degraded nodes with the
+// evidence the controller recorded. clear lifts a quarantine by hand and
+// leaves an audit record on the node. pulse asks the node's agent for an on-demand pulse.
+// policy diff replays the aggregator's pulse history through the current and
+// a proposed policy and lists the nodes whose state would change.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const usage = `usage: kubectl straggler [flags] <command> [args]
+
+commands:
+  status [-l selector] [--all]   list quarantined, suspected, scoped and degraded nodes
+  clear NODE --reason TEXT       remove the quarantine taint, with an audit record
+  pulse NODE                     request an on-demand pulse from the node's agent
+  policy diff -f FILE --aggregator URL
+                                 list the nodes a proposed policy would move to another state
+
+flags:
+`
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("kubectl-straggler", flag.ContinueOnError)
+	kubeconfig := fs.String("kubeconfig", "", "path to the kubeconfig file")
+	kubeContext := fs.String("context", "", "kubeconfig context to use")
+	taintKey := fs.String("taint-key", k8s.DefaultTaintKey, "quarantine taint key the agents write (QUARANTINE_TAINT_KEY)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no command given")
+	}
+
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: *kubeconfig, Precedence: clientcmd.NewDefaultClientConfigLoadingRules().Precedence},
+		&clientcmd.ConfigOverrides{CurrentContext: *kubeContext},
+	)
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	if cmd == "policy" {
+		// The cluster is optional here: the history comes from the
+		// aggregator, and the nodes only lend their labels.
+		client, err := newClient(loader)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v; policy expressions see no node labels\n", err)
+		}
+		return policyCmd(ctx, client, rest, out)
+	}
+
+	client, err := newClient(loader)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "status":
+		return statusCmd(ctx, client, *taintKey, rest, out)
+	case "clear":
+		return clearCmd(ctx, client, operator(loader, *kubeContext), *taintKey, rest, out)
+	case "pulse":
+		return pulseCmd(ctx, client, rest, out)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func newClient(loader clientcmd.ClientConfig) (kubernetes.Interface, error) {
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build client: %w", err)
+	}
+	return client, nil
+}
+
+func statusCmd(ctx context.Context, client kubernetes.Interface, taintKey string, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	selector := fs.String("l", "", "label selector for the nodes to list")
+	all := fs.Bool("all", false, "include healthy nodes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: *selector})
+	if err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tSTATE\tSINCE\tREASON\tEVIDENCE\tNOTES")
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		state := k8s.QuarantineStateForKey(node, taintKey)
+		if state == k8s.StateHealthy && !*all {
+			continue
+		}
+		since, reason, evidence := "-", "-", "-"
+		if cond := k8s.StragglerCondition(node); cond != nil {
+			since = age(cond.LastTransitionTime.Time)
+			reason, evidence = cond.Reason, cond.Message
+		}
+		if state == k8s.StateDegraded {
+			evidence = node.Labels[k8s.DegradedLabel]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", node.Name, state, since, reason, evidence, notes(node))
+	}
+	return w.Flush()
+}
+
+// notes summarises pending operator and controller state on node.
+func notes(node *corev1.Node) string {
+	var n []string
+	if at, ok := node.Annotations[k8s.PulseRequestAnnotation]; ok {
+		n = append(n, "pulse requested "+at)
+	}
+	if v := node.Annotations[k8s.ConsecutiveFailuresAnnotation]; v != "" {
+		n = append(n, v+" consecutive failures")
+	}
+	if v := node.Annotations[k8s.ConsecutivePassesAnnotation]; v != "" {
+		n = append(n, v+" consecutive passes")
+	}
+	if len(n) == 0 {
+		return "-"
+	}
+	return strings.Join(n, ", ")
+}
+
+func clearCmd(ctx context.Context, client kubernetes.Interface, defaultBy, taintKey string, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("clear", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the quarantine is lifted; recorded on the node (required)")
+	by := fs.String("by", defaultBy, "who is lifting it, recorded on the node")
+	if err := fs.Parse(reorder(args)); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("clear takes exactly one node name")
+	}
+	if *reason == "" {
+		return errors.New("clear requires --reason")
+	}
+
+	node := fs.Arg(0)
+	err := k8s.ForceClear(ctx, client, node, taintKey, k8s.ForceClearRecord{By: *by, Reason: *reason})
+	if err != nil {
+		return fmt.Errorf("clear %s: %w", node, err)
+	}
+	fmt.Fprintf(out, "node/%s quarantine cleared (recorded in %s)\n", node, k8s.ForceClearedAnnotation)
+	return nil
+}
+
+func pulseCmd(ctx context.Context, client kubernetes.Interface, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("pulse takes exactly one node name")
+	}
+	node := args[0]
+	if err := k8s.RequestPulse(ctx, client, node, time.Now()); err != nil {
+		return fmt.Errorf("request pulse on %s: %w", node, err)
+	}
+	fmt.Fprintf(out, "node/%s pulse requested; the agent removes %s once it has run\n", node, k8s.PulseRequestAnnotation)
+	return nil
+}
+
+// operator names the person running the plugin for the audit record: the
+// kubeconfig user of the context in use, or the local user.
+func operator(loader clientcmd.ClientConfig, contextName string) string {
+	if raw, err := loader.RawConfig(); err == nil {
+		if contextName == "" {
+			contextName = raw.CurrentContext
+		}
+		if ctx, ok := raw.Contexts[contextName]; ok && ctx.AuthInfo != "" {
+			return ctx.AuthInfo
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// reorder moves flags ahead of positional arguments so `clear NODE --reason x`
+// parses like `clear --reason x NODE`.
+func reorder(args []string) []string {
+	var flags, pos []string
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") {
+			flags = append(flags, args[i])
+			if !strings.Contains(args[i], "=") && i+1 < len(args) {
+				flags = append(flags, args[i+1])
+				i++
+			}
+			continue
+		}
+		pos = append(pos, args[i])
+	}
+	return append(flags, pos...)
+}
+
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return time.Since(t).Round(time.Second).String()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/justin-oleary/straggler-shield/pkg/aggregator/client"
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func policyCmd(ctx context.Context, kube kubernetes.Interface, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "diff" {
+		return errors.New("policy takes one subcommand: diff")
+	}
+	fs := flag.NewFlagSet("policy diff", flag.ContinueOnError)
+	proposedPath := fs.String("f", "", "proposed policy file (required)")
+	currentPath := fs.String("current", "", "policy file the agents run now (POLICY_FILE); empty for the built-in policy")
+	aggregatorURL := fs.String("aggregator", "", "aggregator base URL (required)")
+	cluster := fs.String("cluster", "", "only nodes of this aggregator cluster")
+	all := fs.Bool("all", false, "include nodes whose state would not change")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *proposedPath == "" || *aggregatorURL == "" {
+		return errors.New("policy diff requires -f and --aggregator")
+	}
+
+	current := policy.Default()
+	if *currentPath != "" {
+		p, err := policy.Load(*currentPath)
+		if err != nil {
+			return fmt.Errorf("current policy: %w", err)
+		}
+		current = p
+	}
+	proposed, err := policy.Load(*proposedPath)
+	if err != nil {
+		return fmt.Errorf("proposed policy: %w", err)
+	}
+
+	agg := client.New(*aggregatorURL)
+	nodes, err := agg.ListWorstNodes(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Cluster != nodes[j].Cluster {
+			return nodes[i].Cluster < nodes[j].Cluster
+		}
+		return nodes[i].Node < nodes[j].Node
+	})
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tNODE\tPULSES\tCURRENT\tPROPOSED\tREASON")
+	evaluated, changed := 0, 0
+	for _, nr := range nodes {
+		if *cluster != "" && nr.Cluster != *cluster {
+			continue
+		}
+		history, err := agg.ListReports(ctx, nr.Cluster, nr.Node)
+		if err != nil {
+			return fmt.Errorf("list reports of %s/%s: %w", nr.Cluster, nr.Node, err)
+		}
+		pulses := make([]k8s.RecordedPulse, len(history))
+		for i, sub := range history {
+			pulses[i] = k8s.RecordedPulse{Reason: sub.Reason, Report: sub.Report}
+		}
+		node := lookupNode(ctx, kube, nr.Node)
+		before := k8s.PredictState(current, node, pulses)
+		after := k8s.PredictState(proposed, node, pulses)
+		evaluated++
+		moved := predicted(before) != predicted(after)
+		if moved {
+			changed++
+		}
+		if !moved && !*all {
+			continue
+		}
+		reason := after.Reason
+		if before.Reason != after.Reason {
+			reason = orDash(before.Reason) + " → " + orDash(after.Reason)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", nr.Cluster, nr.Node, len(pulses), predicted(before), predicted(after), orDash(reason))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d of %d nodes would change state\n", changed, evaluated)
+	return nil
+}
+
+// lookupNode returns the node for the policy expression, or one carrying only
+// its name if the cluster is unreachable or does not have it.
+func lookupNode(ctx context.Context, kube kubernetes.Interface, name string) *corev1.Node {
+	if kube != nil {
+		if node, err := kube.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{}); err == nil {
+			return node
+		}
+	}
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+// predicted renders a prediction's state, with the unfit workload classes of
+// a scoped one.
+func predicted(p k8s.Prediction) string {
+	if p.State != k8s.StateScoped {
+		return p.State
+	}
+	classes := make([]string, len(p.Classes))
+	for i, c := range p.Classes {
+		classes[i] = string(c)
+	}
+	return p.State + "(" + strings.Join(classes, ",") + ")"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+// pulse-runner is a standalone one-shot GPU pulse executor. It runs the full
+// pulse pipeline once and writes a single pulse.RunnerResult JSON object to
+// stdout, then exits.
+//
+// The agent invokes it when PULSE_RUNNER_PATH is set, which allows the agent
+// to be built without -tags cuda while only the runner image carries the CUDA
+// toolchain and libgpupulse. It is equally usable by hand:
+//
+//	pulse-runner | jq .
+//
+// The exit status is 0 whenever a result was written — the verdict is in the
+// JSON. A non-zero status means no result could be produced.
+//
+// With --result-file the result is also written to that path; the agent's
+// ephemeral runner pods pass /dev/termination-log so the result is readable
+// from the pod status without pods/log access.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/justin-oleary/straggler-shield/pkg/chaos"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+func main() {
+	resultFile := flag.String("result-file", "", "also write the result JSON to this path")
+	flag.Parse()
+
+	var w io.Writer = os.Stdout
+	if *resultFile != "" {
+		f, err := os.Create(*resultFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pulse-runner: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = io.MultiWriter(os.Stdout, f)
+	}
+
+	// FAULT_INJECTION=pulse_crash=<rate> kills the runner before it writes
+	// a result, as a CGO fault would; the agent records pulse_crashed.
+	faults, err := chaos.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pulse-runner: %s: %v\n", chaos.EnvVar, err)
+		os.Exit(1)
+	}
+	faults.CrashPoint()
+
+	if err := pulse.ServeRunner(w); err != nil {
+		fmt.Fprintf(os.Stderr, "pulse-runner: %v\n", err)
+		os.Exit(1)
+	}
+}
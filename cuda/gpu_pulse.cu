@@ -41,8 +41,10 @@ extern "C" int gpu_device_count(void)
     return n;
 }
 
-extern "C" int run_gpu_pulse(int device_id)
+extern "C" int run_gpu_pulse(int device_id, float *elapsed_ms)
 {
+    int rc = GPU_PULSE_OK;
+
     if (cudaSetDevice(device_id) != cudaSuccess)
         return GPU_PULSE_ERR_CUDA;
 
@@ -77,9 +79,26 @@ extern "C" int run_gpu_pulse(int device_id)
         matmul<<<grid, block>>>(d_A, d_B, d_C);
         cudaDeviceSynchronize();
 
-        // measured pass — Go wall-clock times the full C call
-        matmul<<<grid, block>>>(d_A, d_B, d_C);
-        cudaDeviceSynchronize();
+        // measured pass — timed on the device so host jitter cannot leak
+        // into the mean or the CV
+        cudaEvent_t t_start, t_stop;
+        if (cudaEventCreate(&t_start) != cudaSuccess) {
+            rc = GPU_PULSE_ERR_CUDA;
+        } else if (cudaEventCreate(&t_stop) != cudaSuccess) {
+            cudaEventDestroy(t_start);
+            rc = GPU_PULSE_ERR_CUDA;
+        } else {
+            if (cudaEventRecord(t_start) != cudaSuccess)
+                rc = GPU_PULSE_ERR_CUDA;
+            matmul<<<grid, block>>>(d_A, d_B, d_C);
+            if (cudaEventRecord(t_stop) != cudaSuccess ||
+                cudaEventSynchronize(t_stop) != cudaSuccess ||
+                cudaEventElapsedTime(elapsed_ms, t_start, t_stop) != cudaSuccess)
+                rc = GPU_PULSE_ERR_CUDA;
+
+            cudaEventDestroy(t_start);
+            cudaEventDestroy(t_stop);
+        }
     }
 
     cudaFree(d_A);
@@ -87,7 +106,7 @@ extern "C" int run_gpu_pulse(int device_id)
     cudaFree(d_C);
     free(h_A);
     free(h_B);
-    return GPU_PULSE_OK;
+    return rc;
 
 oom:
     free(h_A);
@@ -136,25 +155,32 @@ extern "C" int run_p2p_check(int src_device, int dst_device, double *bandwidth_g
     cudaMemcpyPeer(dst_buf, dst_device, src_buf, src_device, transfer_size);
     cudaDeviceSynchronize();
 
+    int rc = GPU_PULSE_OK;
     cudaEvent_t t_start, t_stop;
-    cudaEventCreate(&t_start);
-    cudaEventCreate(&t_stop);
-
-    cudaEventRecord(t_start);
-    cudaMemcpyPeer(dst_buf, dst_device, src_buf, src_device, transfer_size);
-    cudaEventRecord(t_stop);
-    cudaEventSynchronize(t_stop);
-
-    float elapsed_ms;
-    cudaEventElapsedTime(&elapsed_ms, t_start, t_stop);
-    *bandwidth_gbs = ((double)transfer_size / (elapsed_ms * 1e-3)) / 1e9;
-
-    cudaEventDestroy(t_start);
-    cudaEventDestroy(t_stop);
+    if (cudaEventCreate(&t_start) != cudaSuccess) {
+        rc = GPU_PULSE_ERR_CUDA;
+    } else if (cudaEventCreate(&t_stop) != cudaSuccess) {
+        cudaEventDestroy(t_start);
+        rc = GPU_PULSE_ERR_CUDA;
+    } else {
+        cudaEventRecord(t_start);
+        cudaMemcpyPeer(dst_buf, dst_device, src_buf, src_device, transfer_size);
+        cudaEventRecord(t_stop);
+
+        float elapsed_ms;
+        if (cudaEventSynchronize(t_stop) != cudaSuccess ||
+            cudaEventElapsedTime(&elapsed_ms, t_start, t_stop) != cudaSuccess)
+            rc = GPU_PULSE_ERR_CUDA;
+        else
+            *bandwidth_gbs = ((double)transfer_size / (elapsed_ms * 1e-3)) / 1e9;
+
+        cudaEventDestroy(t_start);
+        cudaEventDestroy(t_stop);
+    }
     cudaSetDevice(src_device);
     cudaFree(src_buf);
     cudaSetDevice(dst_device);
     cudaFree(dst_buf);
 
-    return GPU_PULSE_OK;
+    return rc;
 }
@@ -16,11 +16,14 @@ int gpu_device_count(void);
 
 // run_gpu_pulse launches a 2048×2048 tiled GEMM on the specified device.
 // One warm-up pass fires first to force P0 and JIT-compile PTX; the timed
-// pass follows. Blocks on cudaDeviceSynchronize before returning.
+// pass follows. The timed pass is bracketed by CUDA events, so the result is
+// device time: host allocation, copies, the CGO transition and Go scheduling
+// are excluded. Blocks until the timed pass completes.
 //
-// device_id: 0-based GPU index (must be < gpu_device_count())
-// returns:   GPU_PULSE_OK (0) on success, GPU_PULSE_ERR_* (>0) on failure
-int run_gpu_pulse(int device_id);
+// device_id:  0-based GPU index (must be < gpu_device_count())
+// elapsed_ms: output — device time of the timed pass in milliseconds
+// returns:    GPU_PULSE_OK (0) on success, GPU_PULSE_ERR_* (>0) on failure
+int run_gpu_pulse(int device_id, float *elapsed_ms);
 
 // run_p2p_check times a 100 MiB cudaMemcpyPeer transfer from src_device to
 // dst_device after a warm-up pass. Requires NVLink or PCIe peer access.
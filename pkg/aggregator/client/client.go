@@ -0,0 +1,226 @@
+// Package client is a Go client for the aggregator API described by
+// pkg/aggregator/openapi.yaml. Each method is one operation of the document,
+// named after its operationId, and uses the aggregator package's types for
+// request and response bodies. TestClientCoversSpec keeps the two in step.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/aggregator"
+)
+
+// Client calls one aggregator.
+type Client struct {
+	base      string
+	http      *http.Client
+	tokenFile string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc instead of a client with a 30s
+// timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithTokenFile sends the bearer token in path with every request, re-read
+// each time. SubmitReport needs it; the read operations do not.
+func WithTokenFile(path string) Option {
+	return func(c *Client) { c.tokenFile = path }
+}
+
+// New returns a Client for the aggregator at baseURL, e.g.
+// "http://straggler-aggregator.monitoring:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		base: strings.TrimSuffix(baseURL, "/"),
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// APIError is a response with an unexpected status. Message is the body the
+// aggregator sent, which names the invalid parameter for a 400.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("aggregator returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// ListClusters returns per-cluster summaries (GET /v1/clusters).
+func (c *Client) ListClusters(ctx context.Context) ([]aggregator.ClusterSummary, error) {
+	var out []aggregator.ClusterSummary
+	if err := c.get(ctx, "/v1/clusters", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListNodes returns node statuses, filtered by cluster and state when they
+// are not empty (GET /v1/nodes).
+func (c *Client) ListNodes(ctx context.Context, cluster, state string) ([]aggregator.NodeStatus, error) {
+	q := url.Values{}
+	setNonEmpty(q, "cluster", cluster)
+	setNonEmpty(q, "state", state)
+	var out []aggregator.NodeStatus
+	if err := c.get(ctx, "/v1/nodes", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SubmitReport records a pulse report (POST /v1/reports).
+func (c *Client) SubmitReport(ctx context.Context, sub aggregator.Submission) error {
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return fmt.Errorf("marshal submission: %w", err)
+	}
+	return c.do(ctx, http.MethodPost, "/v1/reports", nil, bytes.NewReader(body), http.StatusAccepted, nil)
+}
+
+// ListReports returns a node's retained reports, newest first
+// (GET /v1/reports).
+func (c *Client) ListReports(ctx context.Context, cluster, node string) ([]aggregator.Submission, error) {
+	q := url.Values{"cluster": {cluster}, "node": {node}}
+	var out []aggregator.Submission
+	if err := c.get(ctx, "/v1/reports", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListWorstNodes returns up to limit nodes ranked by their latest pulse
+// (GET /v1/fleet/worst). A negative limit uses the server default of 20.
+func (c *Client) ListWorstNodes(ctx context.Context, limit int) ([]aggregator.NodeReport, error) {
+	q := url.Values{}
+	if limit >= 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out []aggregator.NodeReport
+	if err := c.get(ctx, "/v1/fleet/worst", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRackStats returns the failure rate per rack over the last since
+// (GET /v1/fleet/racks). Zero uses the server default of 24h.
+func (c *Client) ListRackStats(ctx context.Context, since time.Duration) ([]aggregator.GroupStats, error) {
+	return c.groupStats(ctx, "/v1/fleet/racks", since)
+}
+
+// ListArchStats returns the failure rate per GPU architecture over the last
+// since (GET /v1/fleet/archs). Zero uses the server default of 24h.
+func (c *Client) ListArchStats(ctx context.Context, since time.Duration) ([]aggregator.GroupStats, error) {
+	return c.groupStats(ctx, "/v1/fleet/archs", since)
+}
+
+// ListThresholdStats returns the straggler thresholds per cluster and GPU
+// architecture over the last since (GET /v1/fleet/thresholds). Zero uses
+// the server default of 24h.
+func (c *Client) ListThresholdStats(ctx context.Context, since time.Duration) ([]aggregator.ThresholdStats, error) {
+	q := url.Values{}
+	if since > 0 {
+		q.Set("since", since.String())
+	}
+	var out []aggregator.ThresholdStats
+	if err := c.get(ctx, "/v1/fleet/thresholds", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOpenAPI returns the aggregator's OpenAPI document
+// (GET /v1/openapi.yaml).
+func (c *Client) GetOpenAPI(ctx context.Context) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.do(ctx, http.MethodGet, "/v1/openapi.yaml", nil, nil, http.StatusOK, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Client) groupStats(ctx context.Context, path string, since time.Duration) ([]aggregator.GroupStats, error) {
+	q := url.Values{}
+	if since > 0 {
+		q.Set("since", since.String())
+	}
+	var out []aggregator.GroupStats
+	if err := c.get(ctx, path, q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, q url.Values, out any) error {
+	var buf bytes.Buffer
+	if err := c.do(ctx, http.MethodGet, path, q, nil, http.StatusOK, &buf); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(buf.Bytes(), out); err != nil {
+		return fmt.Errorf("decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+// do sends the request and copies the response body to out, if set. A status
+// other than want is an *APIError.
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, body io.Reader, want int, out *bytes.Buffer) error {
+	u := c.base + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.tokenFile != "" {
+		token, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return fmt.Errorf("read token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+func setNonEmpty(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/justin-oleary/straggler-shield/pkg/aggregator"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// newServer serves the aggregator API the way cmd/aggregator mounts it, and
+// returns the file holding its ingest token.
+func newServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := aggregator.NewReportStore(5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fed, err := aggregator.NewFederation(&aggregator.Config{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	reports := store.Handler(logger, tokenFile)
+	mux.Handle("/v1/reports", reports)
+	mux.Handle("/v1/fleet/", reports)
+	mux.Handle("GET /v1/openapi.yaml", aggregator.OpenAPIHandler())
+	mux.Handle("/", fed.Handler())
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, tokenFile
+}
+
+// TestClientCoversSpec checks that every operation in openapi.yaml has a
+// Client method named after its operationId and is served by the handlers.
+func TestClientCoversSpec(t *testing.T) {
+	t.Parallel()
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+		} `json:"paths"`
+	}
+	if err := yaml.Unmarshal(aggregator.OpenAPISpec, &spec); err != nil {
+		t.Fatalf("parse openapi.yaml: %v", err)
+	}
+	srv, _ := newServer(t)
+	client := reflect.TypeOf(&Client{})
+	operations := 0
+	for path, methods := range spec.Paths {
+		for method, op := range methods {
+			operations++
+			name := strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+			if _, ok := client.MethodByName(name); !ok {
+				t.Errorf("%s %s: no Client.%s for operationId %q", strings.ToUpper(method), path, name, op.OperationID)
+			}
+			req, _ := http.NewRequest(strings.ToUpper(method), srv.URL+path, http.NoBody)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+				t.Errorf("%s %s: status %d, want the operation served", strings.ToUpper(method), path, resp.StatusCode)
+			}
+		}
+	}
+	if exported := client.NumMethod(); exported != operations {
+		t.Errorf("Client has %d methods, spec has %d operations", exported, operations)
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	srv, tokenFile := newServer(t)
+	c := New(srv.URL+"/", WithTokenFile(tokenFile))
+
+	sub := aggregator.Submission{
+		Cluster: "west", Node: "gpu-node-0", Rack: "r7", Arch: "H100", Reason: "high_variance",
+		Report: &pulse.PulseReport{
+			PulseID: "p1", WorstMeanNS: int64(9 * time.Millisecond),
+			Thresholds: pulse.Snapshot{StragglerThreshold: 35 * time.Millisecond},
+		},
+	}
+	var apiErr *APIError
+	if err := New(srv.URL).SubmitReport(ctx, sub); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("SubmitReport without a token = %v, want a 401 APIError", err)
+	}
+	if err := c.SubmitReport(ctx, sub); err != nil {
+		t.Fatalf("SubmitReport: %v", err)
+	}
+
+	reports, err := c.ListReports(ctx, "west", "gpu-node-0")
+	if err != nil || len(reports) != 1 || reports[0].Report.PulseID != "p1" {
+		t.Errorf("ListReports = %+v, %v", reports, err)
+	}
+	worst, err := c.ListWorstNodes(ctx, -1)
+	if err != nil || len(worst) != 1 || worst[0].Node != "gpu-node-0" {
+		t.Errorf("ListWorstNodes = %+v, %v", worst, err)
+	}
+	racks, err := c.ListRackStats(ctx, time.Hour)
+	if err != nil || len(racks) != 1 || racks[0].Key != "r7" || racks[0].Failures != 1 {
+		t.Errorf("ListRackStats = %+v, %v", racks, err)
+	}
+	archs, err := c.ListArchStats(ctx, 0)
+	if err != nil || len(archs) != 1 || archs[0].Key != "H100" {
+		t.Errorf("ListArchStats = %+v, %v", archs, err)
+	}
+	if th, err := c.ListThresholdStats(ctx, time.Hour); err != nil || len(th) != 1 || th[0].Arch != "H100" || len(th[0].ThresholdsNS) != 1 {
+		t.Errorf("ListThresholdStats = %+v, %v", th, err)
+	}
+	if _, err := c.ListClusters(ctx); err != nil {
+		t.Errorf("ListClusters: %v", err)
+	}
+	if _, err := c.ListNodes(ctx, "west", "quarantined"); err != nil {
+		t.Errorf("ListNodes: %v", err)
+	}
+	if doc, err := c.GetOpenAPI(ctx); err != nil || !bytes.Equal(doc, aggregator.OpenAPISpec) {
+		t.Errorf("GetOpenAPI = %d bytes, %v; want the embedded document", len(doc), err)
+	}
+
+	if _, err := c.ListReports(ctx, "", ""); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("ListReports without a node = %v, want a 400 APIError", err)
+	}
+}
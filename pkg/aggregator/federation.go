@@ -0,0 +1,264 @@
+// Package aggregator gives a central SRE team one view of straggler-shield
+// state across many clusters. The Federation polls each registered cluster's
+// API server for GPU nodes and summarises the quarantine markers the
+// controllers wrote (taints, labels, the GPUStraggler condition).
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+// ClusterConfig registers one cluster with the aggregator. Exactly one of
+// Kubeconfig or InCluster must be set.
+type ClusterConfig struct {
+	Name       string `json:"name"`
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	Context    string `json:"context,omitempty"` // kubeconfig context; empty = current
+	InCluster  bool   `json:"inCluster,omitempty"`
+
+	// TaintKey is the quarantine taint key the cluster's controllers write
+	// (QUARANTINE_TAINT_KEY); empty = k8s.DefaultTaintKey.
+	TaintKey string `json:"taintKey,omitempty"`
+}
+
+// Config is the aggregator configuration file.
+//
+//	nodeSelector: nvidia.com/gpu.present=true
+//	clusters:
+//	  - name: us-east-1
+//	    kubeconfig: /etc/aggregator/us-east-1.kubeconfig
+//	  - name: local
+//	    inCluster: true
+type Config struct {
+	NodeSelector string          `json:"nodeSelector,omitempty"`
+	Clusters     []ClusterConfig `json:"clusters"`
+}
+
+// LoadConfig reads and validates an aggregator config file (YAML or JSON).
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read aggregator config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse aggregator config %s: %w", path, err)
+	}
+	if len(cfg.Clusters) == 0 {
+		return nil, fmt.Errorf("aggregator config %s: no clusters registered", path)
+	}
+	seen := make(map[string]bool)
+	for _, c := range cfg.Clusters {
+		if c.Name == "" {
+			return nil, fmt.Errorf("aggregator config %s: cluster with empty name", path)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("aggregator config %s: duplicate cluster %q", path, c.Name)
+		}
+		seen[c.Name] = true
+		if (c.Kubeconfig == "") == !c.InCluster {
+			return nil, fmt.Errorf("cluster %q: set exactly one of kubeconfig or inCluster", c.Name)
+		}
+	}
+	if cfg.NodeSelector == "" {
+		cfg.NodeSelector = "nvidia.com/gpu.present=true"
+	}
+	return &cfg, nil
+}
+
+// NodeStatus is one GPU node as seen by the aggregator.
+type NodeStatus struct {
+	Cluster   string    `json:"cluster"`
+	Node      string    `json:"node"`
+	State     string    `json:"state"` // k8s.State* value
+	Reason    string    `json:"reason,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Since     time.Time `json:"since,omitempty"`
+	LastPulse string    `json:"last_pulse,omitempty"`
+}
+
+// ClusterSummary is the per-cluster rollup.
+type ClusterSummary struct {
+	Cluster     string         `json:"cluster"`
+	Nodes       int            `json:"nodes"`
+	States      map[string]int `json:"states"`
+	FailureRate float64        `json:"failure_rate"` // non-healthy / total
+	Error       string         `json:"error,omitempty"`
+	RefreshedAt time.Time      `json:"refreshed_at"`
+}
+
+type member struct {
+	name     string
+	client   kubernetes.Interface
+	taintKey string
+}
+
+// Federation polls every registered cluster and keeps the latest snapshot.
+type Federation struct {
+	selector string
+	members  []member
+	logger   *slog.Logger
+
+	mu        sync.RWMutex
+	nodes     map[string][]NodeStatus // by cluster
+	summaries map[string]ClusterSummary
+}
+
+// NewFederation builds clients for every cluster in cfg.
+func NewFederation(cfg *Config, logger *slog.Logger) (*Federation, error) {
+	f := &Federation{
+		selector:  cfg.NodeSelector,
+		logger:    logger,
+		nodes:     make(map[string][]NodeStatus),
+		summaries: make(map[string]ClusterSummary),
+	}
+	for _, c := range cfg.Clusters {
+		rc, err := restConfig(c)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: %w", c.Name, err)
+		}
+		cs, err := kubernetes.NewForConfig(rc)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: create clientset: %w", c.Name, err)
+		}
+		f.members = append(f.members, member{name: c.Name, client: cs, taintKey: c.TaintKey})
+	}
+	return f, nil
+}
+
+func restConfig(c ClusterConfig) (*rest.Config, error) {
+	if c.InCluster {
+		return rest.InClusterConfig()
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: c.Kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: c.Context},
+	).ClientConfig()
+}
+
+// Run refreshes every cluster each interval until ctx is cancelled.
+func (f *Federation) Run(ctx context.Context, interval time.Duration) {
+	for {
+		f.Refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Refresh polls all clusters concurrently. A failing cluster keeps its
+// previous node list and reports the error in its summary, so one region's
+// API outage does not blank the fleet view.
+func (f *Federation) Refresh(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, m := range f.members {
+		wg.Add(1)
+		go func(m member) {
+			defer wg.Done()
+			f.refreshCluster(ctx, m)
+		}(m)
+	}
+	wg.Wait()
+}
+
+func (f *Federation) refreshCluster(ctx context.Context, m member) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	list, err := m.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: f.selector})
+	now := time.Now()
+	if err != nil {
+		f.logger.Warn("cluster refresh failed", "cluster", m.name, "err", err)
+		f.mu.Lock()
+		s := f.summaries[m.name]
+		s.Cluster, s.Error = m.name, err.Error()
+		f.summaries[m.name] = s
+		f.mu.Unlock()
+		return
+	}
+
+	nodes := make([]NodeStatus, 0, len(list.Items))
+	summary := ClusterSummary{Cluster: m.name, States: make(map[string]int), RefreshedAt: now}
+	taintKey := m.taintKey
+	if taintKey == "" {
+		taintKey = k8s.DefaultTaintKey
+	}
+	for i := range list.Items {
+		n := &list.Items[i]
+		st := NodeStatus{
+			Cluster:   m.name,
+			Node:      n.Name,
+			State:     k8s.QuarantineStateForKey(n, taintKey),
+			LastPulse: n.Annotations[k8s.LastPulseAnnotation],
+		}
+		if cond := k8s.StragglerCondition(n); cond != nil {
+			st.Reason, st.Message, st.Since = cond.Reason, cond.Message, cond.LastTransitionTime.Time
+		}
+		nodes = append(nodes, st)
+		summary.States[st.State]++
+	}
+	summary.Nodes = len(nodes)
+	if summary.Nodes > 0 {
+		summary.FailureRate = float64(summary.Nodes-summary.States[k8s.StateHealthy]) / float64(summary.Nodes)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+
+	f.mu.Lock()
+	f.nodes[m.name] = nodes
+	f.summaries[m.name] = summary
+	f.mu.Unlock()
+	recordSummary(summary)
+}
+
+// Summaries returns the latest per-cluster rollups, sorted by cluster name.
+func (f *Federation) Summaries() []ClusterSummary {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]ClusterSummary, 0, len(f.summaries))
+	for _, s := range f.summaries {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Cluster < out[j].Cluster })
+	return out
+}
+
+// Nodes returns the latest node statuses, optionally filtered by cluster and
+// state (empty = any).
+func (f *Federation) Nodes(cluster, state string) []NodeStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var out []NodeStatus
+	for name, nodes := range f.nodes {
+		if cluster != "" && name != cluster {
+			continue
+		}
+		for _, n := range nodes {
+			if state == "" || n.State == state {
+				out = append(out, n)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Cluster != out[j].Cluster {
+			return out[i].Cluster < out[j].Cluster
+		}
+		return out[i].Node < out[j].Node
+	})
+	return out
+}
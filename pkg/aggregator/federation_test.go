@@ -0,0 +1,62 @@
+package aggregator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+)
+
+func gpuNode(name string, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"nvidia.com/gpu.present": "true"},
+		},
+		Spec: corev1.NodeSpec{Taints: taints},
+	}
+}
+
+func TestFederationRefresh(t *testing.T) {
+	t.Parallel()
+
+	zombie := corev1.Taint{Key: "sunk.coreweave.com/zombie-quarantine", Effect: corev1.TaintEffectNoSchedule}
+	suspect := corev1.Taint{Key: "sunk.coreweave.com/zombie-quarantine", Effect: corev1.TaintEffectPreferNoSchedule}
+
+	f := &Federation{
+		selector: "nvidia.com/gpu.present=true",
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		members: []member{
+			{name: "east", client: fake.NewSimpleClientset(gpuNode("a"), gpuNode("b", zombie))},
+			{name: "west", client: fake.NewSimpleClientset(gpuNode("c", suspect), gpuNode("d"), gpuNode("e"), gpuNode("f"))},
+		},
+		nodes:     make(map[string][]NodeStatus),
+		summaries: make(map[string]ClusterSummary),
+	}
+	f.Refresh(context.Background())
+
+	sums := f.Summaries()
+	if len(sums) != 2 || sums[0].Cluster != "east" || sums[1].Cluster != "west" {
+		t.Fatalf("summaries = %+v", sums)
+	}
+	if got := sums[0].FailureRate; got != 0.5 {
+		t.Errorf("east failure rate = %v, want 0.5", got)
+	}
+	if got := sums[1].States[k8s.StateSuspected]; got != 1 {
+		t.Errorf("west suspected = %d, want 1", got)
+	}
+
+	quarantined := f.Nodes("", k8s.StateQuarantined)
+	if len(quarantined) != 1 || quarantined[0].Node != "b" {
+		t.Errorf("quarantined nodes = %+v, want [b]", quarantined)
+	}
+	if got := len(f.Nodes("west", "")); got != 4 {
+		t.Errorf("west nodes = %d, want 4", got)
+	}
+}
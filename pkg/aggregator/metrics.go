@@ -0,0 +1,42 @@
+package aggregator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+)
+
+// FleetNodes is the per-cluster count of GPU nodes in each quarantine state,
+// so fleet dashboards and alerts can be built on the aggregator alone.
+var FleetNodes = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "gpu_aggregator_nodes",
+		Help: "GPU nodes per cluster by straggler-shield quarantine state.",
+	},
+	[]string{"cluster", "state"},
+)
+
+// ReportsReceived counts pulse reports pushed by agents, by cluster and
+// result (pass or fail).
+var ReportsReceived = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gpu_aggregator_reports_total",
+		Help: "Pulse reports received from straggler-shield agents by cluster and result.",
+	},
+	[]string{"cluster", "result"},
+)
+
+func recordSubmission(s *Submission) {
+	result := "pass"
+	if s.Failed() {
+		result = "fail"
+	}
+	ReportsReceived.WithLabelValues(s.Cluster, result).Inc()
+}
+
+func recordSummary(s ClusterSummary) {
+	for _, state := range []string{k8s.StateHealthy, k8s.StateDegraded, k8s.StateScoped, k8s.StateSuspected, k8s.StateQuarantined} {
+		FleetNodes.WithLabelValues(s.Cluster, state).Set(float64(s.States[state]))
+	}
+}
@@ -0,0 +1,20 @@
+package aggregator
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPISpec is the OpenAPI 3 document for the aggregator API, served at
+// GET /v1/openapi.yaml.
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
+
+// OpenAPIHandler serves OpenAPISpec.
+func OpenAPIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(OpenAPISpec)
+	})
+}
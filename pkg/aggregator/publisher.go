@@ -0,0 +1,90 @@
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/delivery"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gpuProductLabel is set by GPU feature discovery; it names the node's GPU
+// when a report carries no matched architecture.
+const gpuProductLabel = "nvidia.com/gpu.product"
+
+// Publisher pushes an agent's pulse reports to an aggregator's
+// POST /v1/reports. Sink queues a report and Run sends it, so a slow or
+// unreachable aggregator never delays a quarantine decision. Failed sends
+// are retried and then dead-lettered as cfg sets (see delivery.Queue).
+type Publisher struct {
+	url       string
+	cluster   string
+	rackLabel string
+	tokenFile string
+	client    *http.Client
+	q         *delivery.Queue[Submission]
+}
+
+// NewPublisher returns a Publisher posting to the aggregator at baseURL on
+// behalf of cluster. rackLabel is the node label holding the rack name;
+// empty leaves the rack unset. tokenFile holds the aggregator's ingest token,
+// re-read on every send; empty sends none. cfg.Name defaults to
+// "aggregator".
+func NewPublisher(baseURL, cluster, rackLabel, tokenFile string, cfg delivery.Config, logger *slog.Logger) *Publisher {
+	if cfg.Name == "" {
+		cfg.Name = "aggregator"
+	}
+	p := &Publisher{
+		url:       strings.TrimSuffix(baseURL, "/") + "/v1/reports",
+		cluster:   cluster,
+		rackLabel: rackLabel,
+		tokenFile: tokenFile,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+	p.q = delivery.New(cfg, p.send, logger)
+	return p
+}
+
+// Sink queues a pulse result for the aggregator. It has the k8s.ReportSink
+// signature.
+func (p *Publisher) Sink(_ context.Context, node *corev1.Node, report *pulse.PulseReport, reason string, _ error) {
+	sub := Submission{
+		Cluster: p.cluster,
+		Node:    node.Name,
+		Arch:    report.Thresholds.ThresholdProvenance.Matched,
+		Reason:  reason,
+		Report:  report,
+	}
+	if p.rackLabel != "" {
+		sub.Rack = node.Labels[p.rackLabel]
+	}
+	if sub.Arch == "" {
+		sub.Arch = node.Labels[gpuProductLabel]
+	}
+	p.q.Enqueue(sub)
+}
+
+// Run sends queued reports until ctx is cancelled; see delivery.Queue.Run.
+func (p *Publisher) Run(ctx context.Context) { p.q.Run(ctx) }
+
+func (p *Publisher) send(ctx context.Context, sub Submission) error {
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return delivery.Permanent(fmt.Errorf("marshal submission: %w", err))
+	}
+	var token string
+	if p.tokenFile != "" {
+		// Unreadable is retried: the Secret may not be mounted yet.
+		if token, err = readToken(p.tokenFile); err != nil {
+			return fmt.Errorf("read ingest token: %w", err)
+		}
+	}
+	return delivery.PostJSONToken(ctx, p.client, p.url, token, body, func(status int) bool { return status == http.StatusAccepted })
+}
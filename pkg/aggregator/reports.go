@@ -0,0 +1,398 @@
+package aggregator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// Submission is one pulse result pushed by an agent to POST /v1/reports.
+// Where the Federation sees only the markers a controller left on the node,
+// a submission carries the full per-device report.
+type Submission struct {
+	Cluster string `json:"cluster"`
+	Node    string `json:"node"`
+	Rack    string `json:"rack,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+	// Reason is the failure_reason the agent classified the result as; empty
+	// for a pass.
+	Reason     string             `json:"reason,omitempty"`
+	ReceivedAt time.Time          `json:"received_at"`
+	Report     *pulse.PulseReport `json:"report"`
+}
+
+// Failed reports whether the submission is a failing pulse. A near-threshold
+// result passed and an inconclusive one measured nothing; neither is counted.
+func (s *Submission) Failed() bool { return failedReason(s.Reason) }
+
+func failedReason(reason string) bool {
+	return reason != "" && reason != "near_threshold" && reason != "inconclusive"
+}
+
+func (s *Submission) validate() error {
+	switch {
+	case s.Cluster == "":
+		return errors.New("cluster is required")
+	case s.Node == "":
+		return errors.New("node is required")
+	case s.Report == nil:
+		return errors.New("report is required")
+	}
+	return nil
+}
+
+// NodeReport is a node's latest submission with its history rolled up, as
+// listed by ReportStore.Worst.
+type NodeReport struct {
+	Cluster   string    `json:"cluster"`
+	Node      string    `json:"node"`
+	Rack      string    `json:"rack,omitempty"`
+	Arch      string    `json:"arch,omitempty"`
+	PulseID   string    `json:"pulse_id"`
+	LastPulse time.Time `json:"last_pulse"`
+	Reason    string    `json:"reason,omitempty"`
+	// WorstMeanNS is the slowest device mean of the latest pulse and
+	// LatencyRatio its fraction of the straggler threshold it ran against.
+	WorstMeanNS  int64   `json:"worst_mean_ns"`
+	LatencyRatio float64 `json:"latency_ratio"`
+	// Pulses and Failures count the retained history.
+	Pulses   int `json:"pulses"`
+	Failures int `json:"failures"`
+}
+
+// GroupStats is the pulse failure rate of one rack or architecture.
+type GroupStats struct {
+	Key          string  `json:"key"`
+	Nodes        int     `json:"nodes"`
+	Pulses       int     `json:"pulses"`
+	Failures     int     `json:"failures"`
+	FailureRate  float64 `json:"failure_rate"`  // failures / pulses
+	FailingNodes int     `json:"failing_nodes"` // nodes whose latest pulse failed
+}
+
+// ThresholdStats is the straggler threshold one cluster applied to one GPU
+// architecture over a window, and how close its pulses came to it. The same
+// architecture with different thresholds across clusters is threshold drift.
+type ThresholdStats struct {
+	Cluster string `json:"cluster"`
+	Arch    string `json:"arch"`
+	Nodes   int    `json:"nodes"`
+	Pulses  int    `json:"pulses"`
+	// ThresholdsNS are the distinct thresholds reported, ascending; more
+	// than one means the cluster's own nodes disagree.
+	ThresholdsNS     []int64 `json:"thresholds_ns"`
+	MeanLatencyRatio float64 `json:"mean_latency_ratio"` // slowest device mean over the threshold
+	MaxLatencyRatio  float64 `json:"max_latency_ratio"`
+}
+
+type nodeKey struct{ cluster, node string }
+
+// ReportStore keeps the last retain submissions of every node. With a path,
+// submissions are appended to that file as JSON lines and reloaded by
+// NewReportStore, so history survives an aggregator restart. The file is
+// compacted to the retained submissions on load and whenever it has grown to
+// twice their number.
+type ReportStore struct {
+	retain int
+	path   string
+
+	mu       sync.RWMutex
+	history  map[nodeKey][]Submission // oldest first
+	retained int                      // submissions held across all nodes
+	stale    int                      // lines in the file no longer retained
+}
+
+// NewReportStore returns a store keeping retain submissions per node,
+// persisted at path if it is non-empty.
+func NewReportStore(retain int, path string) (*ReportStore, error) {
+	if retain < 1 {
+		return nil, fmt.Errorf("report history must keep at least one pulse per node, got %d", retain)
+	}
+	s := &ReportStore{retain: retain, path: path, history: make(map[nodeKey][]Submission)}
+	if path == "" {
+		return s, nil
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add records sub, stamping ReceivedAt if unset. An error means the
+// submission was held in memory but could not be persisted.
+func (s *ReportStore) Add(sub Submission) error {
+	if err := sub.validate(); err != nil {
+		return err
+	}
+	if sub.ReceivedAt.IsZero() {
+		sub.ReceivedAt = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.insert(sub)
+	recordSubmission(&sub)
+	if s.path == "" {
+		return nil
+	}
+	if s.stale >= s.retained {
+		return s.rewrite()
+	}
+	return s.append(&sub)
+}
+
+// insert adds sub to its node's history, dropping the oldest beyond retain.
+// Callers hold mu.
+func (s *ReportStore) insert(sub Submission) {
+	k := nodeKey{sub.Cluster, sub.Node}
+	h := append(s.history[k], sub)
+	if len(h) > s.retain {
+		h = h[len(h)-s.retain:]
+		s.stale++
+	} else {
+		s.retained++
+	}
+	s.history[k] = h
+}
+
+// History returns node's retained submissions, newest first.
+func (s *ReportStore) History(cluster, node string) []Submission {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h := s.history[nodeKey{cluster, node}]
+	out := make([]Submission, len(h))
+	for i := range h {
+		out[len(h)-1-i] = h[i]
+	}
+	return out
+}
+
+// Worst returns up to limit nodes ranked by their latest pulse: failing
+// nodes first, then by how close the slowest device came to the straggler
+// threshold. limit <= 0 returns every node.
+func (s *ReportStore) Worst(limit int) []NodeReport {
+	s.mu.RLock()
+	out := make([]NodeReport, 0, len(s.history))
+	for k, h := range s.history {
+		last := &h[len(h)-1]
+		nr := NodeReport{
+			Cluster: k.cluster, Node: k.node, Rack: last.Rack, Arch: last.Arch,
+			PulseID: last.Report.PulseID, LastPulse: last.ReceivedAt, Reason: last.Reason,
+			WorstMeanNS: last.Report.WorstMeanNS, Pulses: len(h),
+		}
+		if th := last.Report.Thresholds.StragglerThreshold; th > 0 {
+			nr.LatencyRatio = float64(last.Report.WorstMeanNS) / float64(th)
+		}
+		for i := range h {
+			if h[i].Failed() {
+				nr.Failures++
+			}
+		}
+		out = append(out, nr)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if fi, fj := failedReason(out[i].Reason), failedReason(out[j].Reason); fi != fj {
+			return fi
+		}
+		if out[i].LatencyRatio != out[j].LatencyRatio {
+			return out[i].LatencyRatio > out[j].LatencyRatio
+		}
+		if out[i].Cluster != out[j].Cluster {
+			return out[i].Cluster < out[j].Cluster
+		}
+		return out[i].Node < out[j].Node
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// ByRack returns failure rates per rack over submissions received since
+// since, sorted by key. Nodes without a rack are grouped under "".
+func (s *ReportStore) ByRack(since time.Time) []GroupStats {
+	return s.group(since, func(sub *Submission) string { return sub.Rack })
+}
+
+// ByArch returns failure rates per GPU architecture over submissions received
+// since since, sorted by key.
+func (s *ReportStore) ByArch(since time.Time) []GroupStats {
+	return s.group(since, func(sub *Submission) string { return sub.Arch })
+}
+
+// Thresholds returns the thresholds per cluster and GPU architecture over
+// submissions received since since, sorted by architecture and then cluster
+// so drift across clusters is adjacent. Submissions without a threshold are
+// left out.
+func (s *ReportStore) Thresholds(since time.Time) []ThresholdStats {
+	type key struct{ cluster, arch string }
+	s.mu.RLock()
+	groups := make(map[key]*ThresholdStats)
+	for k, h := range s.history {
+		seen := make(map[key]bool)
+		for i := range h {
+			sub := &h[i]
+			th := sub.Report.Thresholds.StragglerThreshold
+			if sub.ReceivedAt.Before(since) || th <= 0 {
+				continue
+			}
+			gk := key{k.cluster, sub.Arch}
+			g := groups[gk]
+			if g == nil {
+				g = &ThresholdStats{Cluster: gk.cluster, Arch: gk.arch}
+				groups[gk] = g
+			}
+			if !seen[gk] {
+				g.Nodes++
+				seen[gk] = true
+			}
+			g.Pulses++
+			if !slices.Contains(g.ThresholdsNS, th.Nanoseconds()) {
+				g.ThresholdsNS = append(g.ThresholdsNS, th.Nanoseconds())
+			}
+			ratio := float64(sub.Report.WorstMeanNS) / float64(th.Nanoseconds())
+			g.MeanLatencyRatio += ratio // summed here, divided below
+			g.MaxLatencyRatio = max(g.MaxLatencyRatio, ratio)
+		}
+	}
+	s.mu.RUnlock()
+
+	out := make([]ThresholdStats, 0, len(groups))
+	for _, g := range groups {
+		g.MeanLatencyRatio /= float64(g.Pulses)
+		slices.Sort(g.ThresholdsNS)
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Arch != out[j].Arch {
+			return out[i].Arch < out[j].Arch
+		}
+		return out[i].Cluster < out[j].Cluster
+	})
+	return out
+}
+
+func (s *ReportStore) group(since time.Time, key func(*Submission) string) []GroupStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	groups := make(map[string]*GroupStats)
+	for _, h := range s.history {
+		seen := make(map[string]bool)
+		for i := range h {
+			sub := &h[i]
+			if sub.ReceivedAt.Before(since) {
+				continue
+			}
+			g := groups[key(sub)]
+			if g == nil {
+				g = &GroupStats{Key: key(sub)}
+				groups[g.Key] = g
+			}
+			if !seen[g.Key] {
+				g.Nodes++
+				seen[g.Key] = true
+			}
+			g.Pulses++
+			if sub.Failed() {
+				g.Failures++
+			}
+		}
+		if last := &h[len(h)-1]; seen[key(last)] && last.Failed() {
+			groups[key(last)].FailingNodes++
+		}
+	}
+
+	out := make([]GroupStats, 0, len(groups))
+	for _, g := range groups {
+		g.FailureRate = float64(g.Failures) / float64(g.Pulses)
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// append writes sub as one line of the history file. Callers hold mu.
+func (s *ReportStore) append(sub *Submission) error {
+	line, err := json.Marshal(sub)
+	if err != nil {
+		return fmt.Errorf("marshal submission: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open report history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("append report history: %w", err)
+	}
+	return nil
+}
+
+// rewrite atomically replaces the history file with the retained
+// submissions. Callers hold mu.
+func (s *ReportStore) rewrite() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create report history: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, h := range s.history {
+		for i := range h {
+			if err := enc.Encode(&h[i]); err != nil {
+				f.Close()
+				return fmt.Errorf("write report history: %w", err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("write report history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write report history: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace report history: %w", err)
+	}
+	s.stale = 0
+	return nil
+}
+
+// load replays the history file and compacts it. A missing file is an empty
+// history; a line that does not parse (a torn final write) is skipped.
+func (s *ReportStore) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open report history: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), maxSubmissionBytes)
+	for sc.Scan() {
+		var sub Submission
+		if json.Unmarshal(sc.Bytes(), &sub) != nil || sub.validate() != nil {
+			continue
+		}
+		s.insert(sub)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read report history %s: %w", s.path, err)
+	}
+	return s.rewrite()
+}
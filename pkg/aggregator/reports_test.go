@@ -0,0 +1,183 @@
+package aggregator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/justin-oleary/straggler-shield/pkg/delivery"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+func submission(node, rack, arch, reason string, worst time.Duration) Submission {
+	return Submission{
+		Cluster: "east", Node: node, Rack: rack, Arch: arch, Reason: reason,
+		Report: &pulse.PulseReport{
+			PulseID:     node + "-pulse",
+			WorstMeanNS: worst.Nanoseconds(),
+			Thresholds:  pulse.Snapshot{StragglerThreshold: 100 * time.Millisecond},
+		},
+	}
+}
+
+func TestReportStoreRollups(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewReportStore(3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []Submission{
+		submission("a", "r1", "H100", "", 30*time.Millisecond),
+		submission("a", "r1", "H100", "", 40*time.Millisecond),
+		submission("b", "r1", "H100", "high_variance", 50*time.Millisecond),
+		submission("c", "r2", "A100", "near_threshold", 90*time.Millisecond),
+		submission("d", "r2", "A100", "", 20*time.Millisecond),
+	} {
+		if err := s.Add(sub); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	for range 3 {
+		_ = s.Add(submission("d", "r2", "A100", "", 10*time.Millisecond))
+	}
+
+	if h := s.History("east", "d"); len(h) != 3 {
+		t.Errorf("history of d = %d entries, want 3 retained", len(h))
+	}
+	if h := s.History("east", "a"); len(h) != 2 || h[0].Report.WorstMeanNS != (40*time.Millisecond).Nanoseconds() {
+		t.Errorf("history of a = %+v, want newest first", h)
+	}
+
+	worst := s.Worst(3)
+	if len(worst) != 3 || worst[0].Node != "b" || worst[1].Node != "c" || worst[2].Node != "a" {
+		t.Fatalf("worst = %+v, want b (failing), c (0.9), a (0.4)", worst)
+	}
+	if worst[1].LatencyRatio != 0.9 {
+		t.Errorf("c latency ratio = %v, want 0.9", worst[1].LatencyRatio)
+	}
+
+	racks := s.ByRack(time.Time{})
+	if len(racks) != 2 || racks[0].Key != "r1" || racks[0].Nodes != 2 || racks[0].Pulses != 3 || racks[0].Failures != 1 || racks[0].FailingNodes != 1 {
+		t.Errorf("r1 = %+v, want 2 nodes, 3 pulses, 1 failure, 1 failing node", racks[0])
+	}
+	if racks[1].Failures != 0 {
+		t.Errorf("r2 = %+v, near-threshold counted as a failure", racks[1])
+	}
+	if archs := s.ByArch(time.Now().Add(time.Hour)); len(archs) != 0 {
+		t.Errorf("archs since the future = %+v, want none", archs)
+	}
+
+	// West runs its H100s at a looser threshold than east.
+	west := submission("e", "r9", "H100", "", 60*time.Millisecond)
+	west.Cluster, west.Report.Thresholds.StragglerThreshold = "west", 120*time.Millisecond
+	if err := s.Add(west); err != nil {
+		t.Fatal(err)
+	}
+	th := s.Thresholds(time.Time{})
+	if len(th) != 3 || th[0].Arch != "A100" || th[1].Cluster != "east" || th[2].Cluster != "west" {
+		t.Fatalf("thresholds = %+v, want A100/east, H100/east, H100/west", th)
+	}
+	if h := th[1]; h.Nodes != 2 || h.Pulses != 3 || len(h.ThresholdsNS) != 1 || h.ThresholdsNS[0] != (100*time.Millisecond).Nanoseconds() || math.Abs(h.MeanLatencyRatio-0.4) > 1e-9 || h.MaxLatencyRatio != 0.5 {
+		t.Errorf("H100/east = %+v, want 2 nodes, 3 pulses at 100ms, mean ratio 0.4, max 0.5", h)
+	}
+	if h := th[2]; h.ThresholdsNS[0] != (120*time.Millisecond).Nanoseconds() || h.MaxLatencyRatio != 0.5 {
+		t.Errorf("H100/west = %+v, want 120ms and ratio 0.5", h)
+	}
+}
+
+func TestReportStorePersists(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "reports.jsonl")
+	s, err := NewReportStore(2, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10 {
+		if err := s.Add(submission("a", "r1", "H100", "", time.Duration(i)*time.Millisecond)); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	reloaded, err := NewReportStore(2, path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	h := reloaded.History("east", "a")
+	if len(h) != 2 || h[0].Report.WorstMeanNS != (9*time.Millisecond).Nanoseconds() {
+		t.Errorf("reloaded history = %+v, want the last two", h)
+	}
+}
+
+func TestPublisherPostsToStore(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := NewReportStore(5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.Handler(logger, tokenFile))
+	defer srv.Close()
+
+	pub := NewPublisher(srv.URL+"/", "west", "rack", tokenFile, delivery.Config{}, logger)
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "gpu-node-0",
+		Labels: map[string]string{"rack": "r7", gpuProductLabel: "NVIDIA-B200"},
+	}}
+	report := &pulse.PulseReport{PulseID: "p1", WorstMeanNS: int64(time.Millisecond)}
+	pub.Sink(context.Background(), node, report, "latency_threshold_exceeded", pulse.ErrStragglerDetected)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pub.Run(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.History("west", "gpu-node-0")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	h := s.History("west", "gpu-node-0")
+	if len(h) != 1 || h[0].Rack != "r7" || h[0].Arch != "NVIDIA-B200" || !h[0].Failed() || h[0].ReceivedAt.IsZero() {
+		t.Errorf("stored = %+v", h)
+	}
+
+	post := func(h http.Handler, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/reports", http.NoBody)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for _, tc := range []struct {
+		name      string
+		tokenFile string
+		token     string
+		want      int
+	}{
+		{"empty submission", tokenFile, "s3cret", http.StatusBadRequest},
+		{"no token", tokenFile, "", http.StatusUnauthorized},
+		{"wrong token", tokenFile, "guess", http.StatusUnauthorized},
+		{"token file missing", filepath.Join(t.TempDir(), "absent"), "s3cret", http.StatusServiceUnavailable},
+		{"ingest not configured", "", "s3cret", http.StatusForbidden},
+	} {
+		if got := post(s.Handler(logger, tc.tokenFile), tc.token); got != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
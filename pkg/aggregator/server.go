@@ -0,0 +1,183 @@
+package aggregator
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+)
+
+// Handler serves the fleet API:
+//
+//	GET /v1/clusters                         per-cluster summaries
+//	GET /v1/nodes?cluster=<name>&state=<s>   node statuses, optionally filtered
+//	GET /                                    HTML overview of the same data
+func (f *Federation) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/clusters", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, f.Summaries())
+	})
+	mux.HandleFunc("GET /v1/nodes", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		writeJSON(w, f.Nodes(q.Get("cluster"), q.Get("state")))
+	})
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = overview.Execute(w, struct {
+			Clusters []ClusterSummary
+			Nodes    []NodeStatus
+		}{f.Summaries(), unhealthy(f.Nodes("", ""))})
+	})
+	return mux
+}
+
+// maxSubmissionBytes bounds one POST /v1/reports body. A full report from an
+// eight-GPU node is a few KiB.
+const maxSubmissionBytes = 1 << 20
+
+// readToken reads the bearer token in path, without the trailing newline a
+// mounted Secret usually has.
+func readToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// requireToken serves next only to requests bearing the token in tokenFile,
+// re-read on every request so a rotated secret applies without a restart.
+// Without a tokenFile every request is refused.
+func requireToken(tokenFile string, logger *slog.Logger, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tokenFile == "" {
+			http.Error(w, "report ingest is disabled: the aggregator has no ingest token", http.StatusForbidden)
+			return
+		}
+		want, err := readToken(tokenFile)
+		if err != nil {
+			logger.Error("failed to read ingest token", "path", tokenFile, "err", err)
+			http.Error(w, "ingest token unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Handler serves the report API. Submissions must carry the bearer token in
+// tokenFile; with no tokenFile, POST /v1/reports is refused and only the
+// read paths are served.
+//
+//	POST /v1/reports                         record a Submission from an agent
+//	GET  /v1/reports?cluster=<c>&node=<n>    the node's history, newest first
+//	GET  /v1/fleet/worst?limit=<n>           worst nodes by latest pulse (default 20)
+//	GET  /v1/fleet/racks?since=<duration>    failure rate by rack (default 24h)
+//	GET /v1/fleet/archs?since=<duration>    failure rate by GPU architecture
+//	GET  /v1/fleet/thresholds?since=<d>      thresholds by cluster and architecture
+func (s *ReportStore) Handler(logger *slog.Logger, tokenFile string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/reports", requireToken(tokenFile, logger, func(w http.ResponseWriter, r *http.Request) {
+		var sub Submission
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmissionBytes)).Decode(&sub); err != nil {
+			http.Error(w, "decode submission: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		sub.ReceivedAt = time.Time{}
+		if err := sub.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.Add(sub); err != nil {
+			// Held in memory; only the history file missed it.
+			logger.Warn("failed to persist pulse report", "cluster", sub.Cluster, "node", sub.Node, "err", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	mux.HandleFunc("GET /v1/reports", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("cluster") == "" || q.Get("node") == "" {
+			http.Error(w, "cluster and node are required", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, s.History(q.Get("cluster"), q.Get("node")))
+	})
+	mux.HandleFunc("GET /v1/fleet/worst", func(w http.ResponseWriter, r *http.Request) {
+		limit := 20
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, fmt.Sprintf("invalid limit %q", v), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		writeJSON(w, s.Worst(limit))
+	})
+	for path, group := range map[string]func(time.Time) any{
+		"GET /v1/fleet/racks":      func(since time.Time) any { return s.ByRack(since) },
+		"GET /v1/fleet/archs":      func(since time.Time) any { return s.ByArch(since) },
+		"GET /v1/fleet/thresholds": func(since time.Time) any { return s.Thresholds(since) },
+	} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			window := 24 * time.Hour
+			if v := r.URL.Query().Get("since"); v != "" {
+				d, err := time.ParseDuration(v)
+				if err != nil || d <= 0 {
+					http.Error(w, fmt.Sprintf("invalid since %q", v), http.StatusBadRequest)
+					return
+				}
+				window = d
+			}
+			writeJSON(w, group(time.Now().Add(-window)))
+		})
+	}
+	return mux
+}
+
+func unhealthy(nodes []NodeStatus) []NodeStatus {
+	var out []NodeStatus
+	for _, n := range nodes {
+		if n.State != k8s.StateHealthy {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// overview renders failure rates, stored as fractions, as percentages.
+var overview = template.Must(template.New("overview").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.2f%%", 100*f) },
+}).Parse(`<!doctype html>
+<html><head><title>straggler-shield fleet</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px}</style>
+</head><body>
+<h1>Clusters</h1>
+<table><tr><th>Cluster</th><th>Nodes</th><th>Healthy</th><th>Degraded</th><th>Scoped</th><th>Suspected</th><th>Quarantined</th><th>Failure rate</th><th>Refreshed</th><th>Error</th></tr>
+{{range .Clusters}}<tr><td>{{.Cluster}}</td><td>{{.Nodes}}</td><td>{{index .States "healthy"}}</td><td>{{index .States "degraded"}}</td><td>{{index .States "scoped"}}</td><td>{{index .States "suspected"}}</td><td>{{index .States "quarantined"}}</td><td>{{percent .FailureRate}}</td><td>{{.RefreshedAt.Format "2006-01-02 15:04:05"}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+<h1>Unhealthy nodes</h1>
+<table><tr><th>Cluster</th><th>Node</th><th>State</th><th>Reason</th><th>Since</th><th>Message</th></tr>
+{{range .Nodes}}<tr><td>{{.Cluster}}</td><td>{{.Node}}</td><td>{{.State}}</td><td>{{.Reason}}</td><td>{{.Since.Format "2006-01-02 15:04:05"}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
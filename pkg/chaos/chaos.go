@@ -0,0 +1,226 @@
+// Package chaos injects faults into a running agent so its resilience paths
+// — API retries, taint patch conflict retries, the decision journal, crash
+// containment — can be exercised in a staging cluster. It is enabled only by
+// FAULT_INJECTION and must never be set in production.
+//
+// The package imports nothing from client-go, so pulse-runner can link it
+// for its CrashPoint.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// EnvVar holds the fault specification, e.g.
+//
+//	FAULT_INJECTION=api_error=0.05,patch_conflict=0.3,pulse_hang=0.1,pulse_hang_for=2m
+//
+// Subprocess runners inherit it, so pulse_crash also fires in the child.
+const EnvVar = "FAULT_INJECTION"
+
+// Fault names, used as keys in EnvVar and as the fault label of
+// gpu_validator_faults_injected_total.
+const (
+	// APIError fails an API request with 503 Service Unavailable before it
+	// reaches the server.
+	APIError = "api_error"
+	// PatchConflict fails a node PATCH or PUT with 409 Conflict, as if
+	// another writer had changed the node first.
+	PatchConflict = "patch_conflict"
+	// PulseHang blocks a pulse for pulse_hang_for, or until it is cancelled.
+	PulseHang = "pulse_hang"
+	// PulseCrash panics the process running the pulse, as a CGO fault would.
+	PulseCrash = "pulse_crash"
+)
+
+// Injector decides which operations fail. Each rate is the probability, 0 to
+// 1, that one operation gets the fault. A nil *Injector injects nothing.
+type Injector struct {
+	Rates map[string]float64
+	// HangFor bounds a PulseHang; zero hangs until the pulse's context ends.
+	HangFor time.Duration
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// Parse reads a comma-separated list of fault=rate pairs, plus an optional
+// pulse_hang_for=<duration>. An empty spec returns nil.
+func Parse(spec string) (*Injector, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	in := &Injector{Rates: make(map[string]float64), rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: want fault=rate", pair)
+		}
+		switch key {
+		case APIError, PatchConflict, PulseHang, PulseCrash:
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("%s=%s: want a rate between 0 and 1", key, value)
+			}
+			in.Rates[key] = rate
+		case "pulse_hang_for":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("pulse_hang_for=%s: want a positive duration", value)
+			}
+			in.HangFor = d
+		default:
+			return nil, fmt.Errorf("unknown fault %q (want %s, %s, %s, %s or pulse_hang_for)", key, APIError, PatchConflict, PulseHang, PulseCrash)
+		}
+	}
+	return in, nil
+}
+
+// FromEnv parses EnvVar. It returns nil when fault injection is off.
+func FromEnv() (*Injector, error) {
+	return Parse(os.Getenv(EnvVar))
+}
+
+// String lists the enabled faults, for the startup warning.
+func (in *Injector) String() string {
+	if in == nil {
+		return ""
+	}
+	var parts []string
+	for _, f := range []string{APIError, PatchConflict, PulseHang, PulseCrash} {
+		if rate := in.Rates[f]; rate > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%g", f, rate))
+		}
+	}
+	if in.HangFor > 0 {
+		parts = append(parts, "pulse_hang_for="+in.HangFor.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// fire reports whether this operation gets fault, and counts and logs it
+// when it does.
+func (in *Injector) fire(fault string, attrs ...any) bool {
+	if in == nil || in.Rates[fault] <= 0 {
+		return false
+	}
+	in.mu.Lock()
+	hit := in.rand.Float64() < in.Rates[fault]
+	in.mu.Unlock()
+	if hit {
+		metrics.FaultsInjected.WithLabelValues(fault).Inc()
+		slog.Warn("injecting fault", append([]any{"fault", fault}, attrs...)...)
+	}
+	return hit
+}
+
+// WrapTransport fails API requests with APIError and node writes with
+// PatchConflict. It fits rest.Config.Wrap.
+func (in *Injector) WrapTransport(next http.RoundTripper) http.RoundTripper {
+	if in == nil {
+		return next
+	}
+	return faultTransport{in: in, next: next}
+}
+
+type faultTransport struct {
+	in   *Injector
+	next http.RoundTripper
+}
+
+func (t faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if nodeWrite(req) && t.in.fire(PatchConflict, "method", req.Method, "path", req.URL.Path) {
+		return statusResponse(req, http.StatusConflict, "Conflict", "injected conflict: the object has been modified"), nil
+	}
+	if t.in.fire(APIError, "method", req.Method, "path", req.URL.Path) {
+		return statusResponse(req, http.StatusServiceUnavailable, "ServiceUnavailable", "injected API server error"), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+func nodeWrite(req *http.Request) bool {
+	return (req.Method == http.MethodPatch || req.Method == http.MethodPut) && strings.HasPrefix(req.URL.Path, "/api/v1/nodes/")
+}
+
+// statusResponse is the metav1.Status failure the API server itself would
+// send, so client-go decodes it into the same StatusError.
+func statusResponse(req *http.Request, code int, reason, msg string) *http.Response {
+	body, _ := json.Marshal(map[string]any{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"status":     "Failure",
+		"message":    msg,
+		"reason":     reason,
+		"code":       code,
+	})
+	return &http.Response{
+		StatusCode:    code,
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// ReportFunc runs one pulse for a node; k8s.NodeReportFunc has this
+// signature.
+type ReportFunc = func(ctx context.Context, nodeName string) (*pulse.PulseReport, error)
+
+// WrapPulse hangs a fraction of pulses (PulseHang) before running fn. The
+// hang ends with the pulse's context error, or with an ErrStageTimeout after
+// HangFor, as a wedged GEMM stage would.
+func (in *Injector) WrapPulse(fn ReportFunc) ReportFunc {
+	if in == nil {
+		return fn
+	}
+	return func(ctx context.Context, nodeName string) (*pulse.PulseReport, error) {
+		if in.fire(PulseHang, "node", nodeName) {
+			if in.HangFor <= 0 {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			t := time.NewTimer(in.HangFor)
+			defer t.Stop()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-t.C:
+				return nil, fmt.Errorf("%w: injected hang of %v", pulse.ErrStageTimeout, in.HangFor)
+			}
+		}
+		return fn(ctx, nodeName)
+	}
+}
+
+// CrashPoint kills the calling process for a fraction of pulses
+// (PulseCrash). Call it where the pulse runs: in the agent for in-process
+// pulses, in the runner for isolated ones, which the agent then reports as
+// pulse_crashed.
+func (in *Injector) CrashPoint() {
+	if in.fire(PulseCrash) {
+		// A goroutine panic cannot be recovered by the caller, like a
+		// SIGSEGV in libgpupulse.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			panic("chaos: injected pulse crash")
+		}()
+		<-done
+	}
+}
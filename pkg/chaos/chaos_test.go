@@ -0,0 +1,111 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	in, err := Parse("api_error=0.05, patch_conflict=1,pulse_hang_for=2m")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := in.String(), "api_error=0.05,patch_conflict=1,pulse_hang_for=2m0s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if in, err := Parse(""); in != nil || err != nil {
+		t.Errorf("Parse(\"\") = %v, %v; want nil, nil", in, err)
+	}
+	for _, spec := range []string{"api_error", "api_error=2", "pulse_hang_for=-1s", "disk_full=0.1"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestWrapTransport(t *testing.T) {
+	t.Parallel()
+
+	var reached int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"Node","apiVersion":"v1","metadata":{"name":"gpu-node-0"}}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	conflicts := newClient(t, srv.URL, "patch_conflict=1")
+	if _, err := conflicts.CoreV1().Nodes().Get(ctx, "gpu-node-0", metav1.GetOptions{}); err != nil {
+		t.Errorf("Get with only patch conflicts injected: %v", err)
+	}
+	_, err := conflicts.CoreV1().Nodes().Patch(ctx, "gpu-node-0", types.MergePatchType, []byte(`{}`), metav1.PatchOptions{})
+	if !apierrors.IsConflict(err) {
+		t.Errorf("Patch error = %v, want a conflict", err)
+	}
+
+	failing := newClient(t, srv.URL, "api_error=1")
+	if _, err := failing.CoreV1().Nodes().Get(ctx, "gpu-node-0", metav1.GetOptions{}); !apierrors.IsServiceUnavailable(err) {
+		t.Errorf("Get error = %v, want service unavailable", err)
+	}
+	if reached != 1 {
+		t.Errorf("server reached %d times, want only the uninjected Get", reached)
+	}
+}
+
+func newClient(t *testing.T, host, spec string) kubernetes.Interface {
+	t.Helper()
+	in, err := Parse(spec)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	cfg := &rest.Config{Host: host}
+	cfg.Wrap(in.WrapTransport)
+	c, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewForConfig: %v", err)
+	}
+	return c
+}
+
+func TestWrapPulse(t *testing.T) {
+	t.Parallel()
+
+	ran := false
+	fn := func(context.Context, string) (*pulse.PulseReport, error) {
+		ran = true
+		return &pulse.PulseReport{}, nil
+	}
+
+	in, _ := Parse("pulse_hang=1,pulse_hang_for=10ms")
+	if _, err := in.WrapPulse(fn)(context.Background(), "gpu-node-0"); !errors.Is(err, pulse.ErrStageTimeout) || ran {
+		t.Errorf("hung pulse = %v, ran %v; want ErrStageTimeout without running", err, ran)
+	}
+
+	in, _ = Parse("pulse_hang=1")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := in.WrapPulse(fn)(ctx, "gpu-node-0"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unbounded hang = %v, want the context's error", err)
+	}
+
+	var off *Injector
+	if _, err := off.WrapPulse(fn)(context.Background(), "gpu-node-0"); err != nil || !ran {
+		t.Errorf("nil injector: err %v, ran %v; want the pulse to run", err, ran)
+	}
+	off.CrashPoint()
+}
@@ -0,0 +1,412 @@
+// Package delivery sends notifications to external receivers in the
+// background, so a slow receiver never delays a quarantine decision. Failed
+// sends are retried, then dead-lettered and retried at the next start.
+package delivery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+)
+
+// Defaults for Config fields left zero.
+const (
+	DefaultQueueSize      = 64
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+)
+
+// Config tunes a Queue.
+type Config struct {
+	// Name identifies the receiver in logs, metrics and the dead-letter
+	// file name, e.g. "aggregator" or "webhook".
+	Name string
+	// QueueSize is how many notifications wait to be sent before new ones
+	// are dead-lettered. Zero uses DefaultQueueSize.
+	QueueSize int
+	// MaxAttempts is how many times a notification is sent before it is
+	// dead-lettered. Zero uses DefaultMaxAttempts.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; each later retry
+	// waits twice as long, up to MaxBackoff. Zero uses the defaults.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// DeadLetterDir holds <Name>.deadletter.jsonl. Empty logs undeliverable
+	// notifications and drops them.
+	DeadLetterDir string
+}
+
+// permanentError marks a send failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the Queue dead-letters the notification at once
+// instead of retrying it, e.g. for a 4xx response.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// deadLetter is one line of the dead-letter file.
+type deadLetter[T any] struct {
+	Receiver string    `json:"receiver"`
+	FailedAt time.Time `json:"failed_at"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Item     T         `json:"item"`
+}
+
+// entry is a queued item and its place in enqueue order.
+type entry[T any] struct {
+	item T
+	seq  uint64
+}
+
+// Queue delivers notifications of type T, one at a time and in order, with
+// send. T must marshal to JSON for the dead-letter file.
+type Queue[T any] struct {
+	cfg    Config
+	send   func(ctx context.Context, item T) error
+	key    func(T) string
+	queue  chan entry[T]
+	mu     sync.Mutex // serialises dead-letter file writes
+	logger *slog.Logger
+
+	seqMu    sync.Mutex
+	seq      uint64
+	latest   map[string]uint64 // key -> seq of its newest item
+	lettered map[string]uint64 // key -> seq of its newest dead letter
+}
+
+// New returns a Queue sending with send. Call Run to start delivery.
+func New[T any](cfg Config, send func(ctx context.Context, item T) error, logger *slog.Logger) *Queue[T] {
+	return NewKeyed(cfg, nil, send, logger)
+}
+
+// NewKeyed returns a Queue whose items are state for a key, e.g. a drain
+// or resume for a node: a newer item for a key supersedes the older ones,
+// which are then dropped rather than sent or dead-lettered. A delivered
+// item removes the older dead letters for its key, and redelivery skips
+// dead letters for keys queued since the start. A nil key is New.
+func NewKeyed[T any](cfg Config, key func(T) string, send func(ctx context.Context, item T) error, logger *slog.Logger) *Queue[T] {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	return &Queue[T]{
+		cfg:      cfg,
+		send:     send,
+		key:      key,
+		queue:    make(chan entry[T], cfg.QueueSize),
+		logger:   logger.With("receiver", cfg.Name),
+		latest:   map[string]uint64{},
+		lettered: map[string]uint64{},
+	}
+}
+
+// Enqueue queues item without blocking. A full queue dead-letters it.
+func (q *Queue[T]) Enqueue(item T) {
+	q.seqMu.Lock()
+	q.seq++
+	e := entry[T]{item: item, seq: q.seq}
+	if q.key != nil {
+		q.latest[q.key(item)] = e.seq
+	}
+	q.seqMu.Unlock()
+	select {
+	case q.queue <- e:
+	default:
+		q.deadLetter(e, 0, errors.New("delivery queue full"))
+	}
+}
+
+// superseded reports whether a newer item for e's key has been queued.
+func (q *Queue[T]) superseded(e entry[T]) bool {
+	if q.key == nil {
+		return false
+	}
+	q.seqMu.Lock()
+	defer q.seqMu.Unlock()
+	return q.latest[q.key(e.item)] != e.seq
+}
+
+// Run redelivers the dead letters left by an earlier run, then sends queued
+// notifications until ctx is cancelled. Notifications still queued or being
+// retried at that point are dead-lettered, so a restart does not lose them.
+func (q *Queue[T]) Run(ctx context.Context) {
+	q.redeliver()
+	for {
+		select {
+		case <-ctx.Done():
+			q.drain(ctx.Err())
+			return
+		case e := <-q.queue:
+			q.deliver(ctx, e)
+		}
+	}
+}
+
+// deliver sends e, retrying with backoff, and dead-letters it if every
+// attempt fails. It stops once e is superseded.
+func (q *Queue[T]) deliver(ctx context.Context, e entry[T]) {
+	wait := q.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		if q.superseded(e) {
+			q.drop(attempt - 1)
+			return
+		}
+		err := q.send(ctx, e.item)
+		if err == nil {
+			metrics.Deliveries.WithLabelValues(q.cfg.Name, "delivered").Inc()
+			q.delivered(e)
+			return
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= q.cfg.MaxAttempts || ctx.Err() != nil {
+			q.deadLetter(e, attempt, err)
+			return
+		}
+		metrics.Deliveries.WithLabelValues(q.cfg.Name, "retried").Inc()
+		q.logger.Warn("delivery failed — retrying", "attempt", attempt, "retry_in", wait, "err", err)
+		select {
+		case <-ctx.Done():
+			q.deadLetter(e, attempt, err)
+			return
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, q.cfg.MaxBackoff)
+	}
+}
+
+// drain dead-letters everything still queued.
+func (q *Queue[T]) drain(cause error) {
+	for {
+		select {
+		case e := <-q.queue:
+			q.deadLetter(e, 0, cause)
+		default:
+			return
+		}
+	}
+}
+
+// path is the dead-letter file, or "" without one.
+func (q *Queue[T]) path() string {
+	if q.cfg.DeadLetterDir == "" {
+		return ""
+	}
+	return filepath.Join(q.cfg.DeadLetterDir, q.cfg.Name+".deadletter.jsonl")
+}
+
+// drop counts and logs a superseded item.
+func (q *Queue[T]) drop(attempts int) {
+	metrics.Deliveries.WithLabelValues(q.cfg.Name, "superseded").Inc()
+	q.logger.Info("notification superseded by a newer one — dropped", "attempts", attempts)
+}
+
+// delivered removes the dead letters older than e for e's key.
+func (q *Queue[T]) delivered(e entry[T]) {
+	if q.key == nil {
+		return
+	}
+	k := q.key(e.item)
+	q.seqMu.Lock()
+	seq, ok := q.lettered[k]
+	if ok && seq < e.seq {
+		delete(q.lettered, k)
+	}
+	q.seqMu.Unlock()
+	if !ok || seq > e.seq {
+		return
+	}
+	path := q.path()
+	if err := q.purge(path, k); err != nil {
+		q.logger.Error("failed to remove superseded dead letters", "path", path, "key", k, "err", err)
+	}
+}
+
+// deadLetter appends e to the dead-letter file, or logs its loss when there
+// is none. A superseded e is dropped instead.
+func (q *Queue[T]) deadLetter(e entry[T], attempts int, cause error) {
+	if q.superseded(e) {
+		q.drop(attempts)
+		return
+	}
+	metrics.Deliveries.WithLabelValues(q.cfg.Name, "dead_lettered").Inc()
+	path := q.path()
+	if path == "" {
+		q.logger.Error("notification undeliverable — dropped (no dead-letter directory)", "attempts", attempts, "err", cause)
+		return
+	}
+	q.logger.Error("notification undeliverable — dead-lettered", "attempts", attempts, "err", cause, "path", path)
+
+	line, err := json.Marshal(deadLetter[T]{
+		Receiver: q.cfg.Name, FailedAt: time.Now().UTC(), Attempts: attempts, Error: cause.Error(), Item: e.item,
+	})
+	if err == nil {
+		err = q.appendLine(path, line)
+	}
+	if err != nil {
+		q.logger.Error("failed to write dead letter — notification lost", "path", path, "err", err)
+		return
+	}
+	if q.key != nil {
+		q.seqMu.Lock()
+		q.lettered[q.key(e.item)] = max(q.lettered[q.key(e.item)], e.seq)
+		q.seqMu.Unlock()
+	}
+}
+
+func (q *Queue[T]) appendLine(path string, line []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// purge rewrites the dead-letter file without the letters for key.
+func (q *Queue[T]) purge(path, key string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters, err := q.readDeadLetters(path)
+	if err != nil {
+		return err
+	}
+	var buf []byte
+	for _, l := range letters {
+		if q.key(l.Item) == key {
+			continue
+		}
+		line, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if len(buf) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// redeliver moves the dead letters back onto the queue and removes the file.
+// Those that do not fit, or fail again, are dead-lettered anew. A torn final
+// line (crash mid-append) is ignored. For a keyed Queue only the newest
+// letter per key is sent, and none for a key queued since the start.
+func (q *Queue[T]) redeliver() {
+	path := q.path()
+	if path == "" {
+		return
+	}
+	letters, err := q.readDeadLetters(path)
+	if err != nil {
+		q.logger.Error("failed to read dead letters — left in place", "path", path, "err", err)
+		return
+	}
+	if len(letters) == 0 {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		q.logger.Error("failed to clear dead letters — left in place", "path", path, "err", err)
+		return
+	}
+	letters = q.newest(letters)
+	q.logger.Info("redelivering dead letters", "count", len(letters), "path", path)
+	for _, l := range letters {
+		q.Enqueue(l.Item)
+	}
+}
+
+// newest drops the letters superseded by a later letter or a queued item for
+// the same key.
+func (q *Queue[T]) newest(letters []deadLetter[T]) []deadLetter[T] {
+	if q.key == nil {
+		return letters
+	}
+	q.seqMu.Lock()
+	queued := make(map[string]bool, len(q.latest))
+	for k := range q.latest {
+		queued[k] = true
+	}
+	q.seqMu.Unlock()
+	last := make(map[string]int, len(letters))
+	for i, l := range letters {
+		last[q.key(l.Item)] = i
+	}
+	var kept []deadLetter[T]
+	for i, l := range letters {
+		if k := q.key(l.Item); last[k] == i && !queued[k] {
+			kept = append(kept, l)
+		}
+	}
+	if n := len(letters) - len(kept); n > 0 {
+		metrics.Deliveries.WithLabelValues(q.cfg.Name, "superseded").Add(float64(n))
+		q.logger.Info("superseded dead letters dropped", "count", n)
+	}
+	return kept
+}
+
+func (q *Queue[T]) readDeadLetters(path string) ([]deadLetter[T], error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var letters []deadLetter[T]
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		var l deadLetter[T]
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			if !sc.Scan() {
+				break // torn tail
+			}
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		letters = append(letters, l)
+	}
+	return letters, sc.Err()
+}
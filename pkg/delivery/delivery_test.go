@@ -0,0 +1,385 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// recorder is a send func that fails while failing is set and records what
+// it delivered.
+type recorder struct {
+	mu        sync.Mutex
+	failing   error
+	attempts  int
+	delivered []string
+	done      chan struct{}
+}
+
+func (r *recorder) send(_ context.Context, item string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.failing != nil {
+		return r.failing
+	}
+	r.delivered = append(r.delivered, item)
+	r.done <- struct{}{}
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		failing       error
+		wantAttempts  int
+		wantDelivered bool
+	}{
+		{name: "transient failures retried", failing: errors.New("connection refused"), wantAttempts: 3},
+		{name: "permanent failure dead-lettered at once", failing: Permanent(errors.New("400 Bad Request")), wantAttempts: 1},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			cfg := Config{Name: "test", MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, DeadLetterDir: dir}
+			rec := &recorder{failing: tc.failing, done: make(chan struct{}, 4)}
+			q := New(cfg, rec.send, discard)
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan struct{})
+			go func() { q.Run(ctx); close(stopped) }()
+
+			q.Enqueue("quarantined gpu-node-0")
+			path := filepath.Join(dir, "test.deadletter.jsonl")
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				if _, err := os.Stat(path); err == nil {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			<-stopped
+			if rec.attempts != tc.wantAttempts {
+				t.Errorf("attempts = %d, want %d", rec.attempts, tc.wantAttempts)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("dead-letter file: %v", err)
+			}
+			var letter deadLetter[string]
+			if err := json.Unmarshal(data, &letter); err != nil || letter.Item != "quarantined gpu-node-0" || letter.Attempts != tc.wantAttempts {
+				t.Fatalf("dead letter = %+v, %v", letter, err)
+			}
+
+			// The receiver recovers: a new queue redelivers the dead letter
+			// and clears the file.
+			rec.mu.Lock()
+			rec.failing = nil
+			rec.mu.Unlock()
+			ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+			go New(cfg, rec.send, discard).Run(ctx)
+			select {
+			case <-rec.done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("dead letter not redelivered")
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("dead-letter file left after redelivery: %v", err)
+			}
+		})
+	}
+}
+
+func TestQueueFullDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	q := New(Config{Name: "test", QueueSize: 1, DeadLetterDir: dir}, func(context.Context, string) error { return nil }, discard)
+	q.Enqueue("a")
+	q.Enqueue("b") // nothing is draining the queue
+	letters, err := q.readDeadLetters(filepath.Join(dir, "test.deadletter.jsonl"))
+	if err != nil || len(letters) != 1 || letters[0].Item != "b" {
+		t.Errorf("dead letters = %+v, %v; want b", letters, err)
+	}
+}
+
+func TestKeyedQueueSupersedes(t *testing.T) {
+	t.Parallel()
+
+	node := func(item string) string { return strings.Fields(item)[0] }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Redelivery sends only the newest letter per node, and none for a node
+	// queued since the start.
+	dir := t.TempDir()
+	cfg := Config{Name: "test", DeadLetterDir: dir}
+	path := filepath.Join(dir, "test.deadletter.jsonl")
+	var file []byte
+	for _, item := range []string{"n1 drain", "n2 drain", "n1 resume"} {
+		line, _ := json.Marshal(deadLetter[string]{Receiver: "test", Item: item})
+		file = append(append(file, line...), '\n')
+	}
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := &recorder{done: make(chan struct{}, 4)}
+	q := NewKeyed(cfg, node, rec.send, discard)
+	q.Enqueue("n2 resume")
+	go q.Run(ctx)
+	for range 2 {
+		select {
+		case <-rec.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("notifications not delivered")
+		}
+	}
+	rec.mu.Lock()
+	if got := strings.Join(rec.delivered, ","); got != "n2 resume,n1 resume" {
+		t.Errorf("delivered %q, want n2 resume,n1 resume", got)
+	}
+	rec.mu.Unlock()
+
+	// A delivered resume removes the node's older dead-lettered drain.
+	dir = t.TempDir()
+	path = filepath.Join(dir, "test.deadletter.jsonl")
+	q = NewKeyed(Config{Name: "test", QueueSize: 1, DeadLetterDir: dir}, node, func(context.Context, string) error { return nil }, discard)
+	q.Enqueue("n0 drain")
+	q.Enqueue("n1 drain") // queue full: dead-lettered
+	q.Enqueue("n0 resume")
+	if letters, err := q.readDeadLetters(path); err != nil || len(letters) != 2 {
+		t.Fatalf("dead letters = %+v, %v; want n1 drain and n0 resume", letters, err)
+	}
+	q.deliver(ctx, <-q.queue) // n0 drain: superseded, dropped
+	q.Enqueue("n1 resume")
+	q.deliver(ctx, <-q.queue)
+	letters, err := q.readDeadLetters(path)
+	if err != nil || len(letters) != 1 || letters[0].Item != "n0 resume" {
+		t.Errorf("dead letters = %+v, %v; want only n0 resume", letters, err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var calls int
+	events := make(chan ResultEvent, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var ev ResultEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		events <- ev
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	hook := NewWebhook(srv.URL, true, Config{InitialBackoff: time.Millisecond}, discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hook.Run(ctx)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-0"}}
+	hook.Sink(ctx, node, &pulse.PulseReport{PulseID: "p1"}, "", nil) // a pass: not sent
+	hook.Sink(ctx, node, &pulse.PulseReport{PulseID: "p2"}, "high_variance", pulse.ErrHighVariance)
+
+	select {
+	case ev := <-events:
+		if ev.PulseID != "p2" || ev.Passed || ev.Reason != "high_variance" || ev.Node != "gpu-node-0" {
+			t.Errorf("event = %+v, want the p2 failure", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered after a 503")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("receiver calls = %d, want 2 (a 503 retried once)", calls)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan map[string]any, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		bodies <- body
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	tmpl, err := NotifyTemplate(FormatPagerDuty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNotifier(srv.URL, tmpl, "rk-1", Config{InitialBackoff: time.Millisecond}, discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.Run(ctx)
+
+	n.Sink(ctx, k8s.Transition{Node: "gpu-node-0", Event: k8s.TransitionQuarantined, Reason: "high_variance", PulseID: "p1", Message: "cv 0.31 over 0.2"})
+	n.Sink(ctx, k8s.Transition{Node: "gpu-node-0", Event: k8s.TransitionCleared, PulseID: "p2", Message: "passed"})
+
+	for _, want := range []string{"trigger", "resolve"} {
+		select {
+		case body := <-bodies:
+			payload, _ := body["payload"].(map[string]any)
+			if body["event_action"] != want || body["routing_key"] != "rk-1" || body["dedup_key"] != "straggler-shield/gpu-node-0" || payload["source"] != "gpu-node-0" {
+				t.Errorf("%s body = %v", want, body)
+			}
+			if want == "trigger" && payload["summary"] != "gpu-node-0 quarantined (high_variance): cv 0.31 over 0.2" {
+				t.Errorf("summary = %v", payload["summary"])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not delivered", want)
+		}
+	}
+}
+
+func TestNotifyTemplates(t *testing.T) {
+	t.Parallel()
+
+	tr := k8s.Transition{Node: "gpu-node-0", Event: k8s.TransitionQuarantined, Reason: "xid", Message: `Xid 79 "fallen off the bus"`}
+	for _, format := range []string{FormatGeneric, FormatSlack, FormatPagerDuty} {
+		tmpl, err := NotifyTemplate(format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if _, err := (&Notifier{tmpl: tmpl}).render(tr); err != nil {
+			t.Errorf("%s: %v", format, err)
+		}
+	}
+	if _, err := NotifyTemplate("email"); err == nil {
+		t.Error("NotifyTemplate(email) succeeded")
+	}
+
+	custom, err := ParseNotifyTemplate(`{"host": {{json .Node}}, "what": {{json .Event}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := (&Notifier{tmpl: custom}).render(tr); err != nil || string(body) != `{"host": "gpu-node-0", "what": "quarantined"}` {
+		t.Errorf("custom render = %s, %v", body, err)
+	}
+	broken, _ := ParseNotifyTemplate(`host={{.Node}}`)
+	if _, err := (&Notifier{tmpl: broken}).render(tr); err == nil {
+		t.Error("a template rendering invalid JSON was accepted")
+	}
+}
+
+// fakeSlurmrestd serves the node endpoints of slurmrestd over nodes, keyed
+// by name, and records every update it applies.
+func fakeSlurmrestd(t *testing.T, nodes map[string]*slurmNode, updates *[]string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("X-SLURM-USER-NAME") != "slurm" || r.Header.Get("X-SLURM-USER-TOKEN") != "jwt-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name := filepath.Base(r.URL.Path)
+		node, ok := nodes[name]
+		if !ok || filepath.Dir(r.URL.Path) != "/slurm/"+DefaultSlurmAPIVersion+"/node" {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{"errors": []map[string]string{{"error": "Invalid node name specified", "description": name}}})
+			return
+		}
+		if r.Method == http.MethodPost {
+			var update struct {
+				State  []string `json:"state"`
+				Reason string   `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				t.Errorf("decode update: %v", err)
+			}
+			*updates = append(*updates, name+" "+update.State[0]+" "+update.Reason)
+			node.State, node.Reason = []string{"IDLE"}, ""
+			if update.State[0] == "DRAIN" {
+				node.State, node.Reason = []string{"IDLE", "DRAIN"}, update.Reason
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"nodes": []*slurmNode{node}, "errors": []any{}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSlurmDrainer(t *testing.T) {
+	t.Parallel()
+
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("jwt-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	nodes := map[string]*slurmNode{
+		"gpu-node-0": {Name: "gpu-node-0", State: []string{"IDLE"}},
+		"gpu-node-1": {Name: "gpu-node-1", State: []string{"IDLE", "DRAIN"}, Reason: "admin: PSU swap"},
+	}
+	var updates []string
+	srv := fakeSlurmrestd(t, nodes, &updates)
+	d := NewSlurmDrainer(SlurmConfig{URL: srv.URL + "/", User: "slurm", TokenFile: token}, Config{}, discard)
+	ctx := context.Background()
+
+	for _, tr := range []k8s.Transition{
+		// The Slurm node name drops the domain.
+		{Node: "gpu-node-0.cluster.local", Event: k8s.TransitionQuarantined, Reason: "high_variance", PulseID: "p1"},
+		{Node: "gpu-node-0", Event: k8s.TransitionCleared, PulseID: "p2"},
+		// An administrator's drain survives a clear.
+		{Node: "gpu-node-1", Event: k8s.TransitionCleared, PulseID: "p3"},
+	} {
+		if err := d.send(ctx, tr); err != nil {
+			t.Fatalf("send %s %s: %v", tr.Event, tr.Node, err)
+		}
+	}
+	want := []string{"gpu-node-0 DRAIN straggler-shield: high_variance (pulse p1)", "gpu-node-0 RESUME "}
+	if len(updates) != len(want) || updates[0] != want[0] || updates[1] != want[1] {
+		t.Errorf("updates = %q, want %q", updates, want)
+	}
+	if n := nodes["gpu-node-1"]; n.Reason != "admin: PSU swap" {
+		t.Errorf("administrator's drain changed to %+v", n)
+	}
+
+	err := d.send(ctx, k8s.Transition{Node: "gpu-node-9", Event: k8s.TransitionQuarantined, Reason: "xid"})
+	var permanent *permanentError
+	if !errors.As(err, &permanent) || !strings.Contains(err.Error(), "Invalid node name specified") {
+		t.Errorf("unknown node err = %v, want a permanent error carrying slurmrestd's", err)
+	}
+}
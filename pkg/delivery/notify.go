@@ -0,0 +1,129 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+)
+
+// Notification formats: the request body a Notifier posts.
+const (
+	// FormatGeneric posts the k8s.Transition as JSON.
+	FormatGeneric = "generic"
+	// FormatSlack posts a Slack incoming-webhook message.
+	FormatSlack = "slack"
+	// FormatPagerDuty posts a PagerDuty Events API v2 event: a quarantine
+	// triggers an incident keyed by node, and the clear resolves it.
+	FormatPagerDuty = "pagerduty"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Built-in body templates by format. They see a NotifyData.
+var notifyTemplates = map[string]string{
+	FormatGeneric: `{{json .Transition}}`,
+	FormatSlack:   `{"text": {{json (summary .)}}}`,
+	FormatPagerDuty: `{"routing_key": {{json .RoutingKey}},` +
+		` "event_action": {{if eq .Event "cleared"}}"resolve"{{else}}"trigger"{{end}},` +
+		` "dedup_key": {{json (print "straggler-shield/" .Node)}},` +
+		` "payload": {"summary": {{json (summary .)}}, "source": {{json .Node}}, "severity": "critical",` +
+		` "timestamp": {{json .Time}}, "custom_details": {{json .Transition}}}}`,
+}
+
+// NotifyData is what a notification template is executed with. Besides the
+// Transition's fields, a template can call json, which marshals its
+// argument, and summary, a one-line description such as "gpu-node-7
+// quarantined (high_variance): <message>".
+type NotifyData struct {
+	k8s.Transition
+	// RoutingKey is the PagerDuty integration key, if configured.
+	RoutingKey string
+}
+
+// ParseNotifyTemplate parses a body template for a Notifier. The body it
+// renders must be JSON.
+func ParseNotifyTemplate(text string) (*template.Template, error) {
+	return template.New("notify").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"summary": summary,
+	}).Parse(text)
+}
+
+// NotifyTemplate returns the built-in template for format.
+func NotifyTemplate(format string) (*template.Template, error) {
+	text, ok := notifyTemplates[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown notification format %q: want %s, %s or %s", format, FormatGeneric, FormatSlack, FormatPagerDuty)
+	}
+	return ParseNotifyTemplate(text)
+}
+
+func summary(d NotifyData) string {
+	s := d.Node + " " + d.Event
+	if d.Reason != "" {
+		s += " (" + d.Reason + ")"
+	}
+	return s + ": " + d.Message
+}
+
+// Notifier posts quarantine and clear transitions to a webhook through a
+// Queue, rendering each body with a template. Responses are handled as for
+// Webhook.
+type Notifier struct {
+	url        string
+	tmpl       *template.Template
+	routingKey string
+	client     *http.Client
+	q          *Queue[k8s.Transition]
+}
+
+// NewNotifier returns a Notifier posting bodies rendered by tmpl (see
+// NotifyTemplate and ParseNotifyTemplate) to url. routingKey is handed to
+// the template as NotifyData.RoutingKey. cfg.Name defaults to "notify".
+func NewNotifier(url string, tmpl *template.Template, routingKey string, cfg Config, logger *slog.Logger) *Notifier {
+	if cfg.Name == "" {
+		cfg.Name = "notify"
+	}
+	n := &Notifier{url: url, tmpl: tmpl, routingKey: routingKey, client: &http.Client{Timeout: 10 * time.Second}}
+	n.q = New(cfg, n.send, logger)
+	return n
+}
+
+// Sink queues a transition for the webhook. It has the k8s.TransitionSink
+// signature.
+func (n *Notifier) Sink(_ context.Context, t k8s.Transition) { n.q.Enqueue(t) }
+
+// Run delivers queued transitions until ctx is cancelled; see Queue.Run.
+func (n *Notifier) Run(ctx context.Context) { n.q.Run(ctx) }
+
+func (n *Notifier) send(ctx context.Context, t k8s.Transition) error {
+	body, err := n.render(t)
+	if err != nil {
+		return Permanent(err)
+	}
+	return PostJSON(ctx, n.client, n.url, body, func(status int) bool { return status/100 == 2 })
+}
+
+// render executes the template for t and checks that the body is JSON.
+func (n *Notifier) render(t k8s.Transition) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := n.tmpl.Execute(&buf, NotifyData{Transition: t, RoutingKey: n.routingKey}); err != nil {
+		return nil, fmt.Errorf("render notification: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("render notification: template produced invalid JSON: %s", strings.TrimSpace(buf.String()))
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,191 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+)
+
+// DefaultSlurmAPIVersion is the slurmrestd API version used when
+// SlurmConfig.APIVersion is empty (Slurm 24.05 and later).
+const DefaultSlurmAPIVersion = "v0.0.40"
+
+// SlurmReasonPrefix starts the reason of every drain a SlurmDrainer sets, so
+// a clear resumes only nodes straggler-shield drained.
+const SlurmReasonPrefix = "straggler-shield: "
+
+// SlurmConfig locates slurmrestd and the credentials to call it with.
+type SlurmConfig struct {
+	// URL is slurmrestd's base URL, e.g. http://slurmrestd.slurm:6820.
+	URL string
+	// APIVersion is the path version, e.g. v0.0.40. Empty uses
+	// DefaultSlurmAPIVersion.
+	APIVersion string
+	// User is sent as X-SLURM-USER-NAME. It must be a Slurm operator or
+	// administrator to drain and resume nodes.
+	User string
+	// TokenFile holds the JWT sent as X-SLURM-USER-TOKEN. It is read for
+	// every request, so a rotated token is picked up. Empty sends none,
+	// e.g. behind an authenticating proxy.
+	TokenFile string
+}
+
+// SlurmDrainer drains a node in Slurm when it is quarantined and resumes it
+// when it is cleared, through the slurmrestd REST API, like scontrol update
+// state=drain and state=resume. A Kubernetes taint does not stop slurmd
+// from accepting jobs. Calls go through a Queue, so an unreachable
+// slurmrestd never delays a quarantine.
+//
+// The Slurm node name is the Kubernetes node name up to its first dot. A
+// clear resumes the node only if it is still drained with a reason starting
+// with SlurmReasonPrefix: a drain set by an administrator is left alone.
+// Only the newest transition for a node is kept, so a dead-lettered drain
+// is not replayed after the node's resume.
+type SlurmDrainer struct {
+	cfg    SlurmConfig
+	client *http.Client
+	logger *slog.Logger
+	q      *Queue[k8s.Transition]
+}
+
+// NewSlurmDrainer returns a SlurmDrainer calling the slurmrestd in sc.
+// cfg.Name defaults to "slurm".
+func NewSlurmDrainer(sc SlurmConfig, cfg Config, logger *slog.Logger) *SlurmDrainer {
+	if sc.APIVersion == "" {
+		sc.APIVersion = DefaultSlurmAPIVersion
+	}
+	sc.URL = strings.TrimSuffix(sc.URL, "/")
+	if cfg.Name == "" {
+		cfg.Name = "slurm"
+	}
+	d := &SlurmDrainer{cfg: sc, client: &http.Client{Timeout: 10 * time.Second}, logger: logger}
+	d.q = NewKeyed(cfg, func(t k8s.Transition) string { return slurmName(t.Node) }, d.send, logger)
+	return d
+}
+
+// slurmName is the Slurm name of a Kubernetes node.
+func slurmName(node string) string {
+	name, _, _ := strings.Cut(node, ".")
+	return name
+}
+
+// Sink queues a transition for slurmrestd. It has the k8s.TransitionSink
+// signature.
+func (d *SlurmDrainer) Sink(_ context.Context, t k8s.Transition) { d.q.Enqueue(t) }
+
+// Run applies queued transitions until ctx is cancelled; see Queue.Run.
+func (d *SlurmDrainer) Run(ctx context.Context) { d.q.Run(ctx) }
+
+// slurmNode is the part of a slurmrestd node the drainer reads.
+type slurmNode struct {
+	Name   string   `json:"name"`
+	State  []string `json:"state"`
+	Reason string   `json:"reason"`
+}
+
+// slurmResponse is the envelope of every slurmrestd response.
+type slurmResponse struct {
+	Nodes  []slurmNode `json:"nodes"`
+	Errors []struct {
+		Error       string `json:"error"`
+		Description string `json:"description"`
+	} `json:"errors"`
+}
+
+func (d *SlurmDrainer) send(ctx context.Context, t k8s.Transition) error {
+	name := slurmName(t.Node)
+	switch t.Event {
+	case k8s.TransitionQuarantined:
+		reason := SlurmReasonPrefix + t.Reason
+		if t.PulseID != "" {
+			reason += " (pulse " + t.PulseID + ")"
+		}
+		return d.update(ctx, name, "DRAIN", reason)
+	case k8s.TransitionCleared:
+		var resp slurmResponse
+		if err := d.do(ctx, http.MethodGet, name, nil, &resp); err != nil {
+			return err
+		}
+		i := slices.IndexFunc(resp.Nodes, func(n slurmNode) bool { return n.Name == name })
+		if i < 0 {
+			return Permanent(fmt.Errorf("slurm node %s not found", name))
+		}
+		if n := resp.Nodes[i]; !slices.Contains(n.State, "DRAIN") || !strings.HasPrefix(n.Reason, SlurmReasonPrefix) {
+			d.logger.Info("slurm node not drained by straggler-shield — leaving it as it is",
+				"node", name, "state", n.State, "slurm_reason", n.Reason)
+			return nil
+		}
+		return d.update(ctx, name, "RESUME", "")
+	default:
+		return Permanent(fmt.Errorf("unknown transition %q", t.Event))
+	}
+}
+
+// update sets a node's state, as scontrol update nodename=name state=state.
+func (d *SlurmDrainer) update(ctx context.Context, name, state, reason string) error {
+	body, err := json.Marshal(struct {
+		State  []string `json:"state"`
+		Reason string   `json:"reason,omitempty"`
+	}{[]string{state}, reason})
+	if err != nil {
+		return Permanent(fmt.Errorf("marshal node update: %w", err))
+	}
+	return d.do(ctx, http.MethodPost, name, body, &slurmResponse{})
+}
+
+// do calls the slurmrestd node endpoint for name and decodes the response
+// into out. Errors slurmrestd reports in the body fail the call.
+func (d *SlurmDrainer) do(ctx context.Context, method, name string, body []byte, out *slurmResponse) error {
+	u := fmt.Sprintf("%s/slurm/%s/node/%s", d.cfg.URL, d.cfg.APIVersion, url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("build request: %w", err))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if d.cfg.User != "" {
+		req.Header.Set("X-SLURM-USER-NAME", d.cfg.User)
+	}
+	if d.cfg.TokenFile != "" {
+		token, err := os.ReadFile(d.cfg.TokenFile)
+		if err != nil {
+			return fmt.Errorf("read slurm token: %w", err)
+		}
+		req.Header.Set("X-SLURM-USER-TOKEN", strings.TrimSpace(string(token)))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	_ = json.Unmarshal(data, out)
+	var errs []string
+	for _, e := range out.Errors {
+		errs = append(errs, strings.TrimSpace(e.Error+": "+e.Description))
+	}
+	if err := responseError(resp, func(status int) bool { return status/100 == 2 }); err != nil {
+		if len(errs) > 0 {
+			return fmt.Errorf("%w: %s", err, strings.Join(errs, "; "))
+		}
+		return err
+	}
+	if len(errs) > 0 {
+		return Permanent(errors.New("slurmrestd: " + strings.Join(errs, "; ")))
+	}
+	return nil
+}
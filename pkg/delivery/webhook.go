@@ -0,0 +1,117 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ResultEvent is the JSON body a Webhook posts for each pulse result.
+type ResultEvent struct {
+	Node    string             `json:"node"`
+	PulseID string             `json:"pulse_id"`
+	Passed  bool               `json:"passed"`
+	Reason  string             `json:"reason,omitempty"` // failure_reason label value
+	Error   string             `json:"error,omitempty"`
+	Time    time.Time          `json:"time"`
+	Report  *pulse.PulseReport `json:"report"`
+}
+
+// Webhook posts pulse results to an external receiver through a Queue. Any
+// 2xx response is a delivery. 408, 429 and 5xx responses and network errors
+// are retried; other responses are dead-lettered at once.
+type Webhook struct {
+	url          string
+	failuresOnly bool
+	client       *http.Client
+	q            *Queue[ResultEvent]
+}
+
+// NewWebhook returns a Webhook posting to url. With failuresOnly, passing
+// results are not sent. cfg.Name defaults to "webhook".
+func NewWebhook(url string, failuresOnly bool, cfg Config, logger *slog.Logger) *Webhook {
+	if cfg.Name == "" {
+		cfg.Name = "webhook"
+	}
+	w := &Webhook{url: url, failuresOnly: failuresOnly, client: &http.Client{Timeout: 10 * time.Second}}
+	w.q = New(cfg, w.send, logger)
+	return w
+}
+
+// Sink queues a pulse result for the webhook. It has the k8s.ReportSink
+// signature.
+func (w *Webhook) Sink(_ context.Context, node *corev1.Node, report *pulse.PulseReport, reason string, err error) {
+	if err == nil && w.failuresOnly {
+		return
+	}
+	ev := ResultEvent{
+		Node:    node.Name,
+		PulseID: report.PulseID,
+		Passed:  err == nil,
+		Reason:  reason,
+		Time:    time.Now().UTC(),
+		Report:  report,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	w.q.Enqueue(ev)
+}
+
+// Run delivers queued results until ctx is cancelled; see Queue.Run.
+func (w *Webhook) Run(ctx context.Context) { w.q.Run(ctx) }
+
+func (w *Webhook) send(ctx context.Context, ev ResultEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return Permanent(fmt.Errorf("marshal event: %w", err))
+	}
+	return PostJSON(ctx, w.client, w.url, body, func(status int) bool { return status/100 == 2 })
+}
+
+// PostJSON posts body to url. A response ok rejects is an error, permanent
+// (see Permanent) unless it is 408, 429 or 5xx.
+func PostJSON(ctx context.Context, client *http.Client, url string, body []byte, ok func(status int) bool) error {
+	return PostJSONToken(ctx, client, url, "", body, ok)
+}
+
+// PostJSONToken is PostJSON sending token as a bearer token, if not empty.
+func PostJSONToken(ctx context.Context, client *http.Client, url, token string, body []byte, ok func(status int) bool) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("build request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return responseError(resp, ok)
+}
+
+// responseError returns nil for a response ok accepts, and otherwise an
+// error that is permanent unless the status is 408, 429 or 5xx.
+func responseError(resp *http.Response, ok func(status int) bool) error {
+	if ok(resp.StatusCode) {
+		return nil
+	}
+	err := fmt.Errorf("receiver returned %s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return err
+	default:
+		return Permanent(err)
+	}
+}
@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Operator actions, used by the kubectl-straggler plugin. They talk to the API
+// server directly, not through a Controller; the agents see their effect on
+// the next watch event.
+const (
+	// PulseRequestAnnotation asks the agent for an on-demand pulse on the
+	// node. The value is the RFC 3339 time of the request. ReconcileNode
+	// pulses a node carrying it regardless of the TriggerPolicy and removes
+	// it afterwards.
+	PulseRequestAnnotation = "straggler-shield.io/pulse-requested"
+
+	// ForceClearedAnnotation records the last quarantine lifted by hand with
+	// ForceClear, as a JSON ForceClearRecord.
+	ForceClearedAnnotation = "straggler-shield.io/force-cleared"
+)
+
+// ErrNotQuarantined means ForceClear found neither the quarantine taint nor a
+// GPUStraggler=True condition on the node.
+var ErrNotQuarantined = errors.New("node is not quarantined")
+
+// ForceClearRecord is the audit trail ForceClear leaves on the node.
+type ForceClearRecord struct {
+	By       string    `json:"by"`
+	Reason   string    `json:"reason,omitempty"`
+	At       time.Time `json:"at"`
+	Evidence string    `json:"evidence,omitempty"` // GPUStraggler message at the time
+}
+
+// RequestPulse sets PulseRequestAnnotation on nodeName.
+func RequestPulse(ctx context.Context, client kubernetes.Interface, nodeName string, at time.Time) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]string{
+			PulseRequestAnnotation: at.UTC().Format(time.RFC3339),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal pulse request patch: %w", err)
+	}
+	if _, err := client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("patch node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+// ForceClear lifts a quarantine by hand: it sets GPUStraggler to False with
+// reason ForceCleared, then removes the taintKey taint, any cordon
+// straggler-shield set (CordonedAnnotation), the degraded label and taint and
+// the workload-class unfit markings, and records rec in
+// ForceClearedAnnotation and a clear decision in DecisionAnnotation. The
+// condition goes first: were the taint removed first, an agent watching the
+// node in between would see GPUStraggler=True without it, take that for an
+// external removal and re-pulse the node. If the second patch fails, calling
+// ForceClear again finishes the job. The agents drop the node from
+// metrics.Quarantined when they see the condition (see ObserveNode).
+// rec.At and rec.Evidence are filled in if empty.
+func ForceClear(ctx context.Context, client kubernetes.Interface, nodeName, taintKey string, rec ForceClearRecord) error {
+	node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get node %s: %w", nodeName, err)
+	}
+	tainted := findTaintByKey(node.Spec.Taints, taintKey) != nil
+	_, cordoned := node.Annotations[CordonedAnnotation]
+	degraded := findTaintByKey(node.Spec.Taints, DegradedLabel) != nil || node.Labels[DegradedLabel] != ""
+	unfit := UnfitClasses(node)
+	scoped := len(unfit) > 0 || len(removeUnfitTaints(slices.Clone(node.Spec.Taints))) != len(node.Spec.Taints)
+	if !tainted && !cordoned && !degraded && !scoped && !quarantineRecorded(node) {
+		return ErrNotQuarantined
+	}
+	if rec.At.IsZero() {
+		rec.At = time.Now().UTC()
+	}
+	if cond := StragglerCondition(node); rec.Evidence == "" && cond != nil {
+		rec.Evidence = cond.Message
+	}
+	audit, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal force-clear record: %w", err)
+	}
+	msg := "quarantine cleared by " + rec.By
+	if rec.Reason != "" {
+		msg += ": " + rec.Reason
+	}
+	decision, err := json.Marshal(Decision{Op: opClear, Evidence: msg, At: rec.At})
+	if err != nil {
+		return fmt.Errorf("marshal decision: %w", err)
+	}
+
+	status, err := json.Marshal(map[string]any{"status": map[string]any{
+		"conditions": upsertCondition(node.Status.Conditions, corev1.NodeCondition{
+			Type:               zombieCondition,
+			Status:             corev1.ConditionFalse,
+			Reason:             "ForceCleared",
+			Message:            msg,
+			LastTransitionTime: metav1.Now(),
+		}),
+	}})
+	if err != nil {
+		return fmt.Errorf("marshal force-clear status patch: %w", err)
+	}
+	if _, err := client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, status, metav1.PatchOptions{}, "status"); err != nil {
+		return fmt.Errorf("patch node %s status: %w", nodeName, err)
+	}
+
+	labels := map[string]*string{}
+	if node.Labels[DegradedLabel] != "" {
+		labels[DegradedLabel] = nil
+	}
+	for _, class := range unfit {
+		labels[UnfitKey(class)] = nil
+	}
+	meta := map[string]any{"labels": labels, "annotations": map[string]*string{
+		ForceClearedAnnotation:        ptr(string(audit)),
+		DecisionAnnotation:            ptr(string(decision)),
+		ConsecutivePassesAnnotation:   nil,
+		ConsecutiveFailuresAnnotation: nil,
+		CordonedAnnotation:            nil,
+	}}
+	var edit func([]corev1.Taint) []corev1.Taint
+	var spec map[string]any
+	if tainted || degraded || scoped {
+		edit = func(taints []corev1.Taint) []corev1.Taint {
+			return removeUnfitTaints(removeTaintByKey(removeTaintByKey(taints, taintKey), DegradedLabel))
+		}
+	}
+	if cordoned {
+		spec = map[string]any{"unschedulable": false}
+	}
+	if err := patchNodeSpec(ctx, client, node, edit, spec, meta); err != nil {
+		return fmt.Errorf("patch node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+// PulseRequested reports whether node carries PulseRequestAnnotation. The
+// watch loop uses it to hand such nodes to ReconcileNode.
+func (c *Controller) PulseRequested(node *corev1.Node) bool {
+	_, ok := node.Annotations[PulseRequestAnnotation]
+	return ok
+}
+
+// clearPulseRequest removes PulseRequestAnnotation once the requested pulse
+// has run. Best effort: a lost write repeats the pulse.
+func (c *Controller) clearPulseRequest(ctx context.Context, node *corev1.Node) {
+	patch, _ := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]*string{PulseRequestAnnotation: nil}},
+	})
+	if _, err := c.client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		c.logger.Warn("failed to clear pulse request", "node", node.Name, "err", err)
+	}
+}
+
+func ptr(s string) *string { return &s }
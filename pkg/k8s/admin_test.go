@@ -0,0 +1,121 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestForceClear(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		node    *corev1.Node
+		wantErr error
+	}{
+		{name: "quarantined node is cleared", node: quarantinedNode("gpu-node-0", 2*time.Hour)},
+		{name: "operator hold is cleared", node: func() *corev1.Node {
+			n := freshNode("gpu-node-1", 2*time.Hour)
+			n.Spec.Taints = []corev1.Taint{{Key: zombieTaintKey, Effect: corev1.TaintEffectNoSchedule}}
+			return n
+		}()},
+		{name: "degraded and scoped markers are cleared", node: func() *corev1.Node {
+			n := quarantinedNode("gpu-node-3", 2*time.Hour)
+			n.Labels = map[string]string{DegradedLabel: "near_threshold", UnfitKey(policy.WorkloadTraining): "interconnect_degraded"}
+			n.Spec.Taints = append(n.Spec.Taints,
+				corev1.Taint{Key: DegradedLabel, Value: "near_threshold", Effect: corev1.TaintEffectPreferNoSchedule},
+				corev1.Taint{Key: UnfitKey(policy.WorkloadTraining), Value: "interconnect_degraded", Effect: corev1.TaintEffectNoSchedule})
+			return n
+		}()},
+		{name: "healthy node is refused", node: freshNode("gpu-node-2", 2*time.Hour), wantErr: ErrNotQuarantined},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(tc.node)
+			err := ForceClear(context.Background(), client, tc.node.Name, zombieTaintKey, ForceClearRecord{By: "oncall", Reason: "RMA replaced"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ForceClear err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), tc.node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get node: %v", err)
+			}
+			if len(got.Spec.Taints) != 0 {
+				t.Errorf("taints left after ForceClear: %v", got.Spec.Taints)
+			}
+			if _, ok := got.Labels[DegradedLabel]; ok || len(UnfitClasses(got)) > 0 {
+				t.Errorf("labels left after ForceClear: %v", got.Labels)
+			}
+			if cond := StragglerCondition(got); cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != "ForceCleared" {
+				t.Errorf("GPUStraggler = %+v, want False/ForceCleared", cond)
+			}
+			var rec ForceClearRecord
+			if err := json.Unmarshal([]byte(got.Annotations[ForceClearedAnnotation]), &rec); err != nil || rec.By != "oncall" || rec.Reason != "RMA replaced" || rec.At.IsZero() {
+				t.Errorf("audit record = %+v (%v)", rec, err)
+			}
+
+			// The agent must not read the clear as an external removal, and
+			// drops the node from the quarantined gauge when it sees it.
+			pulses := 0
+			ctrl := NewController(client, WithPulseFunc(func() (time.Duration, error) { pulses++; return 10 * time.Millisecond, nil }))
+			ctrl.setQuarantined(got.Name, "latency_threshold_exceeded")
+			ctrl.ObserveNode(got)
+			if _, ok := ctrl.quarantined.Load(got.Name); ok {
+				t.Error("force-cleared node still in the quarantined gauge")
+			}
+			if ctrl.TaintRemovedExternally(got) {
+				t.Error("force-cleared node reported as externally untainted")
+			}
+			if err := ctrl.ReconcileNode(context.Background(), got.Name); err != nil || pulses != 0 {
+				t.Errorf("ReconcileNode after clear: err=%v pulses=%d, want no pulse", err, pulses)
+			}
+		})
+	}
+}
+
+func TestRequestPulse(t *testing.T) {
+	t.Parallel()
+
+	// A steady-state node the default trigger would skip.
+	node := freshNode("gpu-node-0", 2*time.Hour)
+	client := fake.NewSimpleClientset(node)
+	pulses := 0
+	ctrl := NewController(client, WithPulseFunc(func() (time.Duration, error) { pulses++; return 10 * time.Millisecond, nil }))
+
+	if err := RequestPulse(context.Background(), client, node.Name, time.Now()); err != nil {
+		t.Fatalf("RequestPulse: %v", err)
+	}
+	for range 2 {
+		if err := ctrl.ReconcileNode(context.Background(), node.Name); err != nil {
+			t.Fatalf("ReconcileNode: %v", err)
+		}
+	}
+	if pulses != 1 {
+		t.Errorf("pulses = %d, want exactly 1 for one request", pulses)
+	}
+
+	got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get node: %v", err)
+	}
+	if ctrl.PulseRequested(got) {
+		t.Error("pulse request not removed after the pulse ran")
+	}
+}
@@ -0,0 +1,77 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Disagreements between the straggler-shield markers on a node, used as the
+// kind label of metrics.MarkerDisagreements. Other writers share the node
+// object — operators with kubectl, remediation tooling, a central-mode
+// controller alongside the node agents — so the taint and the GPUStraggler
+// condition can drift apart. Each kind is logged and surfaced as a
+// QuarantineMarkersDisagree Event instead of being silently overwritten.
+const (
+	// disagreeTaintRemoved: GPUStraggler=True but the quarantine taint is
+	// gone, i.e. someone lifted the quarantine outside straggler-shield. The
+	// node is re-evaluated.
+	disagreeTaintRemoved = "taint_removed_externally"
+
+	// disagreeTaintUnrecorded: the quarantine taint is present but no
+	// GPUStraggler=True condition records why. Straggler-shield did not put
+	// it there (an operator hold, or another tool sharing the key), so a
+	// passing pulse leaves it for its owner.
+	disagreeTaintUnrecorded = "taint_without_condition"
+
+	// disagreeNewerQuarantine: GPUStraggler turned True after the passing
+	// pulse started, so another writer quarantined the node on newer
+	// evidence. The pass is not applied over it.
+	disagreeNewerQuarantine = "newer_quarantine"
+)
+
+const eventMarkersDisagree = "QuarantineMarkersDisagree"
+
+// quarantineRecorded reports whether node carries a GPUStraggler=True
+// condition, which straggler-shield writes with every quarantine taint.
+func quarantineRecorded(node *corev1.Node) bool {
+	cond := StragglerCondition(node)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
+
+// TaintRemovedExternally reports whether node is recorded as quarantined
+// (GPUStraggler=True) but no longer carries the quarantine taint, or in
+// QuarantineCordon mode is no longer cordoned. The watch loop uses it to hand
+// such nodes to ReconcileNode, which re-evaluates them.
+func (c *Controller) TaintRemovedExternally(node *corev1.Node) bool {
+	return quarantineRecorded(node) && !c.quarantineMarked(node)
+}
+
+// clearBlocked audits node before a passing pulse that started at since
+// clears its quarantine. It returns the disagreement kind and a description
+// if the taint must stay, or "" if the clear may proceed. A cordon without
+// the condition is not a disagreement: removeTaint lifts only cordons
+// straggler-shield set.
+func (c *Controller) clearBlocked(node *corev1.Node, since time.Time) (kind, msg string) {
+	taint := findTaintByKey(node.Spec.Taints, c.taint.Key)
+	cond := StragglerCondition(node)
+	recorded := cond != nil && cond.Status == corev1.ConditionTrue
+	switch {
+	case taint != nil && !recorded && c.mode != QuarantineCordon:
+		return disagreeTaintUnrecorded, fmt.Sprintf("%s taint has no GPUStraggler=True condition — not written by straggler-shield, leaving it in place", c.taint.Key)
+	case recorded && !since.IsZero() && cond.LastTransitionTime.Time.After(since):
+		return disagreeNewerQuarantine, fmt.Sprintf("GPUStraggler turned True at %s (%s), after this pulse started at %s — keeping the newer quarantine",
+			cond.LastTransitionTime.UTC().Format(time.RFC3339), cond.Reason, since.UTC().Format(time.RFC3339))
+	}
+	return "", ""
+}
+
+// disagreement logs, counts and emits an Event for a marker disagreement.
+func (c *Controller) disagreement(node *corev1.Node, kind, msg string) {
+	c.logger.Warn("straggler-shield markers disagree", "node_name", node.Name, "kind", kind, "detail", msg)
+	metrics.MarkerDisagreements.WithLabelValues(kind).Inc()
+	c.event(node, corev1.EventTypeWarning, eventMarkersDisagree, msg)
+}
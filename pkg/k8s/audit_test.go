@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMarkerDisagreements(t *testing.T) {
+	t.Parallel()
+
+	// untainted returns a steady-state node recorded as quarantined whose
+	// taint someone removed with kubectl.
+	untainted := func(name string) *corev1.Node {
+		n := quarantinedNode(name, 2*time.Hour)
+		n.Spec.Taints = nil
+		return n
+	}
+	// held returns a steady-state node carrying the quarantine taint without
+	// a GPUStraggler condition, as after `kubectl taint`.
+	held := func(name string) *corev1.Node {
+		n := freshNode(name, 2*time.Hour)
+		n.Spec.Taints = []corev1.Taint{{Key: zombieTaintKey, Effect: corev1.TaintEffectNoSchedule}}
+		return n
+	}
+	// requarantined returns a node quarantined by another writer after the
+	// pulse under test started.
+	requarantined := func(name string) *corev1.Node {
+		n := quarantinedNode(name, 2*time.Hour)
+		n.Status.Conditions[1].LastTransitionTime = metav1.NewTime(time.Now().Add(time.Hour))
+		return n
+	}
+
+	cases := []struct {
+		name       string
+		node       *corev1.Node
+		pulseErr   error
+		validate   bool // ValidateNode instead of ReconcileNode
+		wantPulses int
+		wantTaint  bool
+		wantCond   corev1.ConditionStatus // GPUStraggler status afterwards
+		wantEvent  string                 // reason of the first Event; "" = none
+	}{
+		{
+			name:       "external removal is re-evaluated and re-quarantined",
+			node:       untainted("gpu-node-0"),
+			pulseErr:   pulse.ErrStragglerDetected,
+			wantPulses: 1,
+			wantTaint:  true,
+			wantCond:   corev1.ConditionTrue,
+			wantEvent:  eventMarkersDisagree,
+		},
+		{
+			name:       "external removal is re-evaluated and the condition cleared",
+			node:       untainted("gpu-node-1"),
+			wantPulses: 1,
+			wantCond:   corev1.ConditionFalse,
+			wantEvent:  eventMarkersDisagree,
+		},
+		{
+			name:       "operator hold is left in place",
+			node:       held("gpu-node-2"),
+			validate:   true,
+			wantPulses: 1,
+			wantTaint:  true,
+			wantEvent:  eventMarkersDisagree,
+		},
+		{
+			name:       "pass does not override a newer quarantine",
+			node:       requarantined("gpu-node-3"),
+			validate:   true,
+			wantPulses: 1,
+			wantTaint:  true,
+			wantCond:   corev1.ConditionTrue,
+			wantEvent:  eventMarkersDisagree,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(tc.node)
+			rec := record.NewFakeRecorder(8)
+			pulses := 0
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) { pulses++; return 10 * time.Millisecond, tc.pulseErr }),
+				WithEventRecorder(rec),
+			)
+
+			reconcile := ctrl.ReconcileNode
+			if tc.validate {
+				reconcile = ctrl.ValidateNode
+			}
+			if err := reconcile(context.Background(), tc.node.Name); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if pulses != tc.wantPulses {
+				t.Errorf("pulses = %d, want %d", pulses, tc.wantPulses)
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), tc.node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get node: %v", err)
+			}
+			if hasTaint := findTaint(got, zombieTaintKey) != nil; hasTaint != tc.wantTaint {
+				t.Errorf("hasTaint=%v, want %v", hasTaint, tc.wantTaint)
+			}
+			var status corev1.ConditionStatus
+			if cond := StragglerCondition(got); cond != nil {
+				status = cond.Status
+			}
+			if status != tc.wantCond {
+				t.Errorf("GPUStraggler = %q, want %q", status, tc.wantCond)
+			}
+
+			select {
+			case ev := <-rec.Events:
+				if !strings.Contains(ev, " "+tc.wantEvent+" ") {
+					t.Errorf("event = %q, want reason %s", ev, tc.wantEvent)
+				}
+			default:
+				if tc.wantEvent != "" {
+					t.Errorf("no event, want %s", tc.wantEvent)
+				}
+			}
+		})
+	}
+}
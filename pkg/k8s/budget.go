@@ -0,0 +1,150 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// DefaultReconcileBudget is the time a validating reconcile may take before
+// its latency breakdown is logged: validation should add under a minute to
+// node turn-up.
+const DefaultReconcileBudget = 60 * time.Second
+
+// WithReconcileBudget sets the reconcile latency budget. A reconcile that
+// runs a pulse and takes longer logs a warning with the time it spent
+// getting the node, pulsing and patching. 0 disables the log; the
+// gpu_validator_reconcile_* metrics are recorded either way.
+func WithReconcileBudget(d time.Duration) Option {
+	return func(c *Controller) { c.budget = d }
+}
+
+// reconcileTiming accumulates where one reconcile spent its time. It travels
+// in the context, so node reads and patches made anywhere below the entry
+// point are counted.
+type reconcileTiming struct {
+	start   time.Time
+	getNode time.Duration
+	gets    int
+	// dispatch is the wait for a pulse slot (WithPulseDispatch).
+	dispatch time.Duration
+	pulse    time.Duration
+	patch    time.Duration
+	patches  int
+	// pulseID is set once the reconcile reaches a pulse, fresh or cached;
+	// reconciles that never do are not recorded.
+	pulseID string
+	cached  bool
+}
+
+type timingKey struct{}
+
+func timingFrom(ctx context.Context) *reconcileTiming {
+	t, _ := ctx.Value(timingKey{}).(*reconcileTiming)
+	return t
+}
+
+// beginReconcile starts timing a reconcile. Inside one already being timed
+// it returns a nil timing, so the outer entry point reports the whole.
+func beginReconcile(ctx context.Context) (context.Context, *reconcileTiming) {
+	if timingFrom(ctx) != nil {
+		return ctx, nil
+	}
+	t := &reconcileTiming{start: time.Now()}
+	return context.WithValue(ctx, timingKey{}, t), t
+}
+
+func (t *reconcileTiming) addGet(d time.Duration) {
+	if t != nil {
+		t.getNode += d
+		t.gets++
+	}
+}
+
+func (t *reconcileTiming) addPatch(d time.Duration) {
+	if t != nil {
+		t.patch += d
+		t.patches++
+	}
+}
+
+func (t *reconcileTiming) addDispatch(d time.Duration) {
+	if t != nil {
+		t.dispatch += d
+	}
+}
+
+func (t *reconcileTiming) addPulse(pulseID string, cached bool, d time.Duration) {
+	if t != nil {
+		t.pulseID, t.cached = pulseID, cached
+		t.pulse += d
+	}
+}
+
+// endReconcile records t in the reconcile metrics and logs the breakdown if
+// the total exceeded the budget. A nil t, or a reconcile that ran no pulse,
+// is ignored.
+func (c *Controller) endReconcile(nodeName string, t *reconcileTiming) {
+	if t == nil || t.pulseID == "" {
+		return
+	}
+	total := time.Since(t.start)
+	other := max(total-t.getNode-t.dispatch-t.pulse-t.patch, 0)
+	metrics.ReconcilePhaseDuration.WithLabelValues("get_node").Observe(t.getNode.Seconds())
+	metrics.ReconcilePhaseDuration.WithLabelValues("dispatch").Observe(t.dispatch.Seconds())
+	metrics.ReconcilePhaseDuration.WithLabelValues("pulse").Observe(t.pulse.Seconds())
+	metrics.ReconcilePhaseDuration.WithLabelValues("patch").Observe(t.patch.Seconds())
+	metrics.ReconcilePhaseDuration.WithLabelValues("other").Observe(other.Seconds())
+	metrics.ReconcileDuration.Observe(total.Seconds())
+	if c.budget <= 0 || total <= c.budget {
+		return
+	}
+	metrics.ReconcileOverBudget.Inc()
+	c.logger.Warn("reconcile exceeded latency budget",
+		"node", nodeName,
+		"pulse_id", t.pulseID,
+		"cached", t.cached,
+		"budget", c.budget,
+		"total", total,
+		"get_node", t.getNode,
+		"gets", t.gets,
+		"dispatch", t.dispatch,
+		"pulse", t.pulse,
+		"patch", t.patch,
+		"patches", t.patches,
+		"other", other,
+	)
+}
+
+// timedClient counts Node reads and patches against the reconcile timing in
+// their context. Every other call passes straight through.
+type timedClient struct{ kubernetes.Interface }
+
+func (c timedClient) CoreV1() corev1client.CoreV1Interface {
+	return timedCoreV1{c.Interface.CoreV1()}
+}
+
+type timedCoreV1 struct{ corev1client.CoreV1Interface }
+
+func (c timedCoreV1) Nodes() corev1client.NodeInterface {
+	return timedNodes{c.CoreV1Interface.Nodes()}
+}
+
+type timedNodes struct{ corev1client.NodeInterface }
+
+func (n timedNodes) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Node, error) {
+	defer func(start time.Time) { timingFrom(ctx).addGet(time.Since(start)) }(time.Now())
+	return n.NodeInterface.Get(ctx, name, opts)
+}
+
+func (n timedNodes) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Node, error) {
+	defer func(start time.Time) { timingFrom(ctx).addPatch(time.Since(start)) }(time.Now())
+	return n.NodeInterface.Patch(ctx, name, pt, data, opts, subresources...)
+}
@@ -0,0 +1,85 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReconcileBudget(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		budget   time.Duration
+		wantWarn bool
+	}{
+		{name: "over budget logs the breakdown", budget: time.Millisecond, wantWarn: true},
+		{name: "within budget stays quiet", budget: time.Minute},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute))
+			ctrl := NewController(client,
+				WithNodePulse(func(context.Context, string) (time.Duration, error) {
+					time.Sleep(5 * time.Millisecond)
+					return 812 * time.Millisecond, pulse.ErrStragglerDetected
+				}),
+				WithReconcileBudget(tc.budget),
+			)
+			var logBuf bytes.Buffer
+			ctrl.withLogger(slog.New(slog.NewJSONHandler(&logBuf, nil)))
+
+			if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+				t.Fatalf("ValidateNode: %v", err)
+			}
+
+			var warn map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(logBuf.String()), "\n") {
+				var rec map[string]any
+				if json.Unmarshal([]byte(line), &rec) == nil && rec["msg"] == "reconcile exceeded latency budget" {
+					warn = rec
+				}
+			}
+			if !tc.wantWarn {
+				if warn != nil {
+					t.Errorf("unexpected budget warning: %v", warn)
+				}
+				return
+			}
+			if warn == nil {
+				t.Fatalf("no budget warning in logs:\n%s", logBuf.String())
+			}
+			// ValidateNode reads the node and decide re-reads it; the pulse
+			// records its time, the quarantine patches the taint and condition.
+			if warn["gets"] != float64(2) || warn["patches"] != float64(3) || warn["cached"] != false {
+				t.Errorf("warning = %v, want 2 gets and 3 patches of a fresh pulse", warn)
+			}
+			if pulseNS, _ := warn["pulse"].(float64); time.Duration(pulseNS) < 5*time.Millisecond {
+				t.Errorf("pulse phase = %v, want at least the 5ms the executor slept", time.Duration(pulseNS))
+			}
+		})
+	}
+}
+
+func TestReconcileTimingNotNested(t *testing.T) {
+	t.Parallel()
+
+	ctx, outer := beginReconcile(context.Background())
+	inner, timing := beginReconcile(ctx)
+	if timing != nil || timingFrom(inner) != outer {
+		t.Fatal("nested reconcile started its own timing; want the outer one to report the whole")
+	}
+	timingFrom(context.Background()).addPatch(time.Second) // nil-safe outside a reconcile
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrNodeBusy is returned by ReconcileNode when a triggered pulse was
+// deferred because GPU workloads hold the node (see WithBusyGuard). The
+// caller retries the reconcile later; the deferred pulse is remembered, so
+// it still runs once the trigger window has passed.
+var ErrNodeBusy = errors.New("GPU workloads running — pulse deferred")
+
+// busyGuard defers triggered pulses on nodes whose GPUs are in use.
+type busyGuard struct {
+	idle     IdleFunc
+	maxDefer time.Duration
+	// deferred maps each node with a deferred pulse to when it was first
+	// deferred.
+	deferred sync.Map
+}
+
+// WithBusyGuard checks idle before each pulse ReconcileNode's TriggerPolicy
+// starts, and defers the pulse with ErrNodeBusy while the node is busy: a
+// GEMM burn under a training job would disturb the job and read the
+// contention as a straggler. After maxDefer the pulse runs anyway, so a node
+// that comes back straight into a job is still validated; zero waits
+// indefinitely. Pulses for re-evaluations, failure streaks and on-demand
+// requests are not guarded. Deferrals are kept in memory only.
+func WithBusyGuard(idle IdleFunc, maxDefer time.Duration) Option {
+	return func(c *Controller) { c.busy = &busyGuard{idle: idle, maxDefer: maxDefer} }
+}
+
+// PulseDeferred reports whether a triggered pulse on nodeName is waiting for
+// its GPUs to free up.
+func (c *Controller) PulseDeferred(nodeName string) bool {
+	if c.busy == nil {
+		return false
+	}
+	_, ok := c.busy.deferred.Load(nodeName)
+	return ok
+}
+
+// deferIfBusy returns ErrNodeBusy while node's GPUs are in use and it has
+// not been deferred for maxDefer yet. An idle check that fails counts as
+// busy.
+func (c *Controller) deferIfBusy(ctx context.Context, node *corev1.Node) error {
+	if c.busy == nil {
+		return nil
+	}
+	idle, err := c.busy.idle(ctx, node.Name)
+	if err != nil {
+		c.logger.Warn("idle check failed — treating node as busy", "node", node.Name, "err", err)
+		idle = false
+	}
+	v, _ := c.busy.deferred.LoadOrStore(node.Name, time.Now())
+	since := v.(time.Time)
+	if idle {
+		c.busy.deferred.Delete(node.Name)
+		return nil
+	}
+	if c.busy.maxDefer > 0 && time.Since(since) >= c.busy.maxDefer {
+		c.logger.Warn("GPUs still busy after max defer — forcing triggered pulse",
+			"node", node.Name, "deferred_since", since, "max_defer", c.busy.maxDefer)
+		c.busy.deferred.Delete(node.Name)
+		return nil
+	}
+	c.logger.Info("GPU workloads running — deferring triggered pulse", "node", node.Name, "deferred_since", since)
+	return fmt.Errorf("%w on %s", ErrNodeBusy, node.Name)
+}
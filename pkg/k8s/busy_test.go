@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBusyGuard(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute), gpuPod("llm-0", "gpu-node-0", 8))
+	calls := 0
+	// The trigger fires once, as the Ready window would for a node that
+	// came back straight into a job and stays busy past the window.
+	triggered := false
+	ctrl := NewController(clientset,
+		WithPulseFunc(func() (time.Duration, error) { calls++; return 10 * time.Millisecond, nil }),
+		WithTrigger(TriggerFunc(func(*corev1.Node, TriggerHistory) bool {
+			fire := !triggered
+			triggered = true
+			return fire
+		})),
+		WithBusyGuard(PodsIdle(clientset), time.Hour),
+	)
+
+	for i := 0; i < 2; i++ {
+		if err := ctrl.ReconcileNode(ctx, "gpu-node-0"); !errors.Is(err, ErrNodeBusy) {
+			t.Fatalf("ReconcileNode on a busy node = %v, want ErrNodeBusy", err)
+		}
+	}
+	if calls != 0 || !ctrl.PulseDeferred("gpu-node-0") {
+		t.Fatalf("busy node: %d pulse(s), deferred %v; want none and deferred", calls, ctrl.PulseDeferred("gpu-node-0"))
+	}
+
+	if err := clientset.CoreV1().Pods("training").Delete(ctx, "llm-0", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.ReconcileNode(ctx, "gpu-node-0"); err != nil {
+		t.Fatalf("ReconcileNode once idle: %v", err)
+	}
+	if calls != 1 || ctrl.PulseDeferred("gpu-node-0") {
+		t.Errorf("idle node: %d pulse(s), deferred %v; want the deferred pulse run once", calls, ctrl.PulseDeferred("gpu-node-0"))
+	}
+}
+
+func TestBusyGuardMaxDefer(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute), gpuPod("llm-0", "gpu-node-0", 8))
+	calls := 0
+	ctrl := NewController(clientset,
+		WithPulseFunc(func() (time.Duration, error) { calls++; return 10 * time.Millisecond, nil }),
+		WithBusyGuard(PodsIdle(clientset), 20*time.Millisecond),
+	)
+
+	if err := ctrl.ReconcileNode(context.Background(), "gpu-node-0"); !errors.Is(err, ErrNodeBusy) {
+		t.Fatalf("first ReconcileNode = %v, want ErrNodeBusy", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := ctrl.ReconcileNode(context.Background(), "gpu-node-0"); err != nil {
+		t.Fatalf("ReconcileNode past max defer: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("pulse called %d time(s), want 1 forced pulse", calls)
+	}
+}
@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// resultCache remembers the most recent pulse verdict per node so triggers
+// that fire back to back (a Ready flap followed by a periodic or requested
+// pulse) reuse it instead of burning the GPUs twice. A nil *resultCache is a
+// disabled cache.
+type resultCache struct {
+	mu        sync.Mutex
+	freshness time.Duration
+	now       func() time.Time
+	entries   map[string]cachedPulse
+}
+
+type cachedPulse struct {
+	at     time.Time
+	report *pulse.PulseReport
+	err    error
+}
+
+func newResultCache(freshness time.Duration) *resultCache {
+	return &resultCache{freshness: freshness, now: time.Now, entries: make(map[string]cachedPulse)}
+}
+
+// WithResultCache reuses a node's last pulse result, pass or fail, for
+// freshness after it completed. The verdict is re-applied to the node as if
+// the pulse had just run. Zero disables caching (the default).
+func WithResultCache(freshness time.Duration) Option {
+	return func(c *Controller) {
+		c.cache = nil
+		if freshness > 0 {
+			c.cache = newResultCache(freshness)
+		}
+	}
+}
+
+// lookup returns nodeName's cached result and its age if still fresh.
+func (rc *resultCache) lookup(nodeName string) (cachedPulse, time.Duration, bool) {
+	if rc == nil {
+		return cachedPulse{}, 0, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	e, ok := rc.entries[nodeName]
+	if !ok {
+		return cachedPulse{}, 0, false
+	}
+	age := rc.now().Sub(e.at)
+	if age >= rc.freshness {
+		delete(rc.entries, nodeName)
+		return cachedPulse{}, 0, false
+	}
+	return e, age, true
+}
+
+// forget drops nodeName's cached result.
+func (rc *resultCache) forget(nodeName string) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.entries, nodeName)
+}
+
+// store records a completed pulse for nodeName.
+func (rc *resultCache) store(nodeName string, report *pulse.PulseReport, err error) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries[nodeName] = cachedPulse{at: rc.now(), report: report, err: err}
+}
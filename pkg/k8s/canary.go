@@ -0,0 +1,432 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrCanaryFailed means a canary training job across two freshly validated
+// nodes failed or stepped slower than CanaryRunner.MaxStepTime. The canary
+// cannot tell which of the two nodes is at fault, so both get the verdict.
+var ErrCanaryFailed = errors.New("distributed training canary failed")
+
+// errCanaryNotAdmitted means a canary pod was rejected by the kubelet,
+// typically because a workload took the GPUs first. It says nothing about
+// the nodes, so no verdict is applied.
+var errCanaryNotAdmitted = errors.New("canary pod not admitted")
+
+// canaryPodLabel marks canary pods; its value is the pair's pulse ID.
+const canaryPodLabel = "straggler-shield.io/canary"
+
+// canaryPort is the torchrun rendezvous port on the rank 0 pod.
+const canaryPort = 29500
+
+// canaryScript is a data-parallel training loop: a two-layer MLP wrapped in
+// DistributedDataParallel, so every step all-reduces the gradients over NCCL
+// across both nodes. Global rank 0 writes the slowest rank's median step
+// time to the termination message.
+const canaryScript = `import json, os, time
+import torch
+import torch.distributed as dist
+
+dist.init_process_group("nccl")
+local = int(os.environ["LOCAL_RANK"])
+torch.cuda.set_device(local)
+net = torch.nn.Sequential(torch.nn.Linear(4096, 4096), torch.nn.ReLU(), torch.nn.Linear(4096, 4096)).cuda()
+model = torch.nn.parallel.DistributedDataParallel(net, device_ids=[local])
+opt = torch.optim.SGD(model.parameters(), lr=0.01)
+x = torch.randn(64, 4096, device="cuda")
+steps, warmup = int(os.environ["CANARY_STEPS"]), 5
+times = []
+for i in range(warmup + steps):
+    torch.cuda.synchronize()
+    start = time.perf_counter()
+    opt.zero_grad()
+    model(x).square().mean().backward()
+    opt.step()
+    torch.cuda.synchronize()
+    if i >= warmup:
+        times.append(time.perf_counter() - start)
+step = torch.tensor([sorted(times)[len(times) // 2]], device="cuda")
+dist.all_reduce(step, op=dist.ReduceOp.MAX)
+if dist.get_rank() == 0:
+    with open("/dev/termination-log", "w") as f:
+        json.dump({"step_ns": int(step.item() * 1e9), "steps": steps, "world_size": dist.get_world_size()}, f)
+dist.destroy_process_group()
+`
+
+// canaryResult is the termination message of the rank 0 canary pod.
+type canaryResult struct {
+	StepNS    int64 `json:"step_ns"`
+	Steps     int   `json:"steps"`
+	WorldSize int   `json:"world_size"`
+}
+
+// CanaryRunner runs a small real distributed training job across two nodes:
+// one pod per node running torchrun, rank 0's pod IP as the rendezvous. It
+// exercises the framework stack a pulse does not (PyTorch, NCCL, the pod
+// network between nodes) and fails if the median step time exceeds
+// MaxStepTime. The pods use the pod network, so MaxStepTime must allow for
+// its bandwidth.
+type CanaryRunner struct {
+	Client    kubernetes.Interface
+	Namespace string
+
+	// Image must provide python3 with PyTorch and torchrun, e.g. an NGC
+	// PyTorch container.
+	Image string
+
+	// GPUs is the nvidia.com/gpu request per node. Zero requests every
+	// allocatable GPU.
+	GPUs int64
+
+	// RuntimeClassName is applied to the pods; defaults to "nvidia".
+	RuntimeClassName string
+
+	// MaxStepTime is the slowest acceptable median training step. Defaults
+	// to 1s.
+	MaxStepTime time.Duration
+
+	// Steps is the number of timed training steps. Defaults to 50.
+	Steps int
+
+	// Timeout bounds scheduling plus execution of the job. Defaults to 15m.
+	Timeout time.Duration
+
+	// PollInterval is how often pod status is checked. Defaults to 2s.
+	PollInterval time.Duration
+}
+
+// Run runs the canary on nodes a (rank 0) and b and returns a report whose
+// WorstMeanNS is the median step time. The pods are always deleted before
+// returning. A failed or hung job or a slow step returns an error wrapping
+// ErrCanaryFailed; any other error means the canary did not run. The report
+// is never nil.
+func (r *CanaryRunner) Run(ctx context.Context, a, b string) (*pulse.PulseReport, error) {
+	id := pulse.PulseIDFrom(ctx)
+	report := &pulse.PulseReport{PulseID: id, StartedAt: time.Now().UTC()}
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, r.timeout())
+	defer cancel()
+
+	var pods []*corev1.Pod
+	defer func() {
+		// As in PodRunner: a fresh context, so shutdown cannot orphan a pod
+		// holding the GPUs.
+		delCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, p := range pods {
+			_ = r.Client.CoreV1().Pods(r.Namespace).Delete(delCtx, p.Name, metav1.DeleteOptions{})
+		}
+	}()
+
+	var master string
+	for rank, nodeName := range []string{a, b} {
+		gpus, err := r.gpus(ctx, nodeName)
+		if err != nil {
+			return report, err
+		}
+		pod, err := r.Client.CoreV1().Pods(r.Namespace).Create(ctx, r.podSpec(nodeName, rank, gpus, master, id), metav1.CreateOptions{})
+		if err != nil {
+			return report, fmt.Errorf("create canary pod on %s: %w", nodeName, err)
+		}
+		pods = append(pods, pod)
+		if rank == 0 {
+			if master, err = r.waitForIP(ctx, pod.Name); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	done, err := r.waitForPods(ctx, pods)
+	if errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil {
+		// Both pods were admitted and never finished: a hung collective is
+		// a failure, not a canary that did not run.
+		return report, fmt.Errorf("%w: job did not finish within %v", ErrCanaryFailed, r.timeout())
+	}
+	if err != nil {
+		return report, err
+	}
+	msg := terminationMessage(done[0])
+	var res canaryResult
+	if err := json.Unmarshal([]byte(msg), &res); err != nil || res.StepNS <= 0 {
+		return report, fmt.Errorf("%w: rank 0 pod %s wrote no result: %s", ErrCanaryFailed, done[0].Name, strings.TrimSpace(msg))
+	}
+	report.WorstMeanNS = res.StepNS
+	if step := time.Duration(res.StepNS); step > r.maxStepTime() {
+		return report, &pulse.PulseFailure{
+			Cause:          fmt.Errorf("%w: median step %v over %v across %d ranks", ErrCanaryFailed, step, r.maxStepTime(), res.WorldSize),
+			MeasuredValue:  float64(step.Milliseconds()),
+			ThresholdValue: float64(r.maxStepTime().Milliseconds()),
+			Unit:           "ms",
+		}
+	}
+	return report, nil
+}
+
+func (r *CanaryRunner) gpus(ctx context.Context, nodeName string) (int64, error) {
+	if r.GPUs > 0 {
+		return r.GPUs, nil
+	}
+	node, err := r.Client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("get node %s for gpu count: %w", nodeName, err)
+	}
+	q := node.Status.Allocatable[gpuResource]
+	if q.Value() < 1 {
+		return 0, fmt.Errorf("node %s advertises no %s", nodeName, gpuResource)
+	}
+	return q.Value(), nil
+}
+
+// waitForIP returns the rank 0 pod's IP once it is assigned.
+func (r *CanaryRunner) waitForIP(ctx context.Context, name string) (string, error) {
+	var ip string
+	err := wait.PollUntilContextCancel(ctx, r.pollInterval(), true, func(ctx context.Context) (bool, error) {
+		p, err := r.Client.CoreV1().Pods(r.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil // transient — keep polling until the timeout
+		}
+		if err := canaryPodFailed(p); err != nil {
+			return false, err
+		}
+		ip = p.Status.PodIP
+		return ip != "", nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("canary pod %s: %w", name, err)
+	}
+	return ip, nil
+}
+
+// waitForPods waits until every pod has succeeded, or returns the first
+// failure.
+func (r *CanaryRunner) waitForPods(ctx context.Context, pods []*corev1.Pod) ([]*corev1.Pod, error) {
+	done := make([]*corev1.Pod, len(pods))
+	err := wait.PollUntilContextCancel(ctx, r.pollInterval(), true, func(ctx context.Context) (bool, error) {
+		for i, pod := range pods {
+			if done[i] != nil {
+				continue
+			}
+			p, err := r.Client.CoreV1().Pods(r.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, nil
+			}
+			if err := canaryPodFailed(p); err != nil {
+				return false, err
+			}
+			if p.Status.Phase == corev1.PodSucceeded {
+				done[i] = p
+			}
+		}
+		for _, p := range done {
+			if p == nil {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("canary job: %w", err)
+	}
+	return done, nil
+}
+
+// canaryPodFailed returns the error for a failed canary pod, or nil.
+func canaryPodFailed(p *corev1.Pod) error {
+	if p.Status.Phase != corev1.PodFailed {
+		return nil
+	}
+	if strings.HasPrefix(p.Status.Reason, "OutOf") || p.Status.Reason == "UnexpectedAdmissionError" {
+		return fmt.Errorf("%w: pod %s on %s: %s", errCanaryNotAdmitted, p.Name, p.Spec.NodeName, p.Status.Message)
+	}
+	return fmt.Errorf("%w: pod %s on %s ended Failed: %s", ErrCanaryFailed, p.Name, p.Spec.NodeName, strings.TrimSpace(terminationMessage(p)))
+}
+
+func (r *CanaryRunner) podSpec(nodeName string, rank int, gpus int64, master, id string) *corev1.Pod {
+	runtimeClass := r.RuntimeClassName
+	if runtimeClass == "" {
+		runtimeClass = "nvidia"
+	}
+	env := []corev1.EnvVar{
+		{Name: "CANARY_SCRIPT", Value: canaryScript},
+		{Name: "CANARY_STEPS", Value: strconv.Itoa(r.steps())},
+		{Name: "NODE_RANK", Value: strconv.Itoa(rank)},
+		{Name: "GPUS", Value: strconv.FormatInt(gpus, 10)},
+		{Name: pulse.PulseIDEnv, Value: id},
+	}
+	if rank == 0 {
+		env = append(env, corev1.EnvVar{Name: "MASTER_ADDR", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+		}})
+	} else {
+		env = append(env, corev1.EnvVar{Name: "MASTER_ADDR", Value: master})
+	}
+	limits := corev1.ResourceList{gpuResource: *resource.NewQuantity(gpus, resource.DecimalSI)}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "canary-",
+			Namespace:    r.Namespace,
+			Labels:       map[string]string{canaryPodLabel: id},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:         nodeName,
+			RestartPolicy:    corev1.RestartPolicyNever,
+			RuntimeClassName: &runtimeClass,
+			Containers: []corev1.Container{{
+				// Named like the runner's container so terminationMessage
+				// finds it.
+				Name:  "pulse",
+				Image: r.Image,
+				Command: []string{"sh", "-c", `printf '%s' "$CANARY_SCRIPT" > /tmp/canary.py && exec torchrun ` +
+					`--nnodes=2 --nproc_per_node="$GPUS" --node_rank="$NODE_RANK" ` +
+					`--master_addr="$MASTER_ADDR" --master_port=` + strconv.Itoa(canaryPort) + ` /tmp/canary.py`},
+				Ports:                    []corev1.ContainerPort{{Name: "rendezvous", ContainerPort: canaryPort}},
+				TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+				Env:                      env,
+				Resources:                corev1.ResourceRequirements{Limits: limits, Requests: limits},
+			}},
+		},
+	}
+}
+
+func (r *CanaryRunner) maxStepTime() time.Duration {
+	if r.MaxStepTime > 0 {
+		return r.MaxStepTime
+	}
+	return time.Second
+}
+
+func (r *CanaryRunner) steps() int {
+	if r.Steps > 0 {
+		return r.Steps
+	}
+	return 50
+}
+
+func (r *CanaryRunner) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return 15 * time.Minute
+}
+
+func (r *CanaryRunner) pollInterval() time.Duration {
+	if r.PollInterval > 0 {
+		return r.PollInterval
+	}
+	return 2 * time.Second
+}
+
+// canaryQueue pairs nodes that passed a triggered validation for canaries;
+// see WithCanary.
+type canaryQueue struct {
+	runner   *CanaryRunner
+	pairWait time.Duration
+	nodes    chan string
+}
+
+// WithCanary runs a CanaryRunner job across each pair of nodes that pass a
+// validation started by the trigger policy (node turn-up), as they pass. A
+// node with no partner within pairWait gets no canary. A failed canary is
+// applied to both nodes as a canary_failed failure under the policy, which
+// degrades them by default. Start RunCanaries to run the jobs.
+func WithCanary(r *CanaryRunner, pairWait time.Duration) Option {
+	return func(c *Controller) {
+		c.canary = &canaryQueue{runner: r, pairWait: pairWait, nodes: make(chan string, 64)}
+	}
+}
+
+type canaryCandidateKey struct{}
+
+// withCanaryCandidate marks a validation whose pass makes the node a canary
+// candidate.
+func withCanaryCandidate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, canaryCandidateKey{}, true)
+}
+
+// offerCanary queues nodeName for a canary if the validation in ctx was
+// triggered by the trigger policy. A full queue drops the node.
+func (c *Controller) offerCanary(ctx context.Context, nodeName string) {
+	if c.canary == nil || ctx.Value(canaryCandidateKey{}) == nil {
+		return
+	}
+	select {
+	case c.canary.nodes <- nodeName:
+	default:
+		c.logger.Warn("canary queue full — node gets no canary", "node", nodeName)
+		metrics.Canaries.WithLabelValues("dropped").Inc()
+	}
+}
+
+// RunCanaries pairs validated nodes and runs a canary for each pair until ctx
+// is cancelled. It returns at once without WithCanary.
+func (c *Controller) RunCanaries(ctx context.Context) {
+	if c.canary == nil {
+		return
+	}
+	var pending string
+	var expired <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			c.logger.Info("no validated partner for canary — skipping", "node", pending, "waited", c.canary.pairWait)
+			metrics.Canaries.WithLabelValues("unpaired").Inc()
+			pending, expired = "", nil
+		case node := <-c.canary.nodes:
+			if pending == "" || pending == node {
+				pending, expired = node, time.After(c.canary.pairWait)
+				continue
+			}
+			go c.runCanary(ctx, pending, node)
+			pending, expired = "", nil
+		}
+	}
+}
+
+// runCanary runs the canary on a and b and applies a failure to both.
+func (c *Controller) runCanary(ctx context.Context, a, b string) {
+	id := pulse.NewPulseID()
+	ctx = pulse.WithPulseID(ctx, id)
+	c.logger.Info("running distributed training canary", "nodes", []string{a, b}, "pulse_id", id)
+	report, err := c.canary.runner.Run(ctx, a, b)
+	switch {
+	case ctx.Err() != nil:
+		return
+	case err == nil:
+		c.logger.Info("canary passed", "nodes", []string{a, b}, "pulse_id", id, "step", report.Elapsed())
+		metrics.Canaries.WithLabelValues("passed").Inc()
+		return
+	case !errors.Is(err, ErrCanaryFailed):
+		c.logger.Warn("canary did not run — no verdict applied", "nodes", []string{a, b}, "pulse_id", id, "err", err)
+		metrics.Canaries.WithLabelValues("skipped").Inc()
+		return
+	}
+	metrics.Canaries.WithLabelValues("failed").Inc()
+	for _, name := range []string{a, b} {
+		node, gerr := c.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if gerr != nil {
+			c.logger.Error("canary failed but node could not be read", "node", name, "pulse_id", id, "err", gerr)
+			continue
+		}
+		if aerr := c.apply(ctx, node, report, false, err); aerr != nil {
+			c.logger.Error("applying canary failure failed", "node", name, "pulse_id", id, "err", aerr)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeCanaryPods stands in for the kubelet: each canary pod gets an IP and
+// ends at once, rank 0 reporting step through its termination message, the
+// pod on failNode ending with failure.
+func fakeCanaryPods(clientset *fake.Clientset, step time.Duration, failNode string, failure corev1.PodStatus) map[string]*corev1.Pod {
+	created := make(map[string]*corev1.Pod)
+	clientset.PrependReactor("create", "pods", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		p := a.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		p.Name = p.GenerateName + p.Spec.NodeName
+		p.Status.PodIP = "10.0.0." + fmt.Sprint(len(created)+1)
+		p.Status.Phase = corev1.PodSucceeded
+		msg := fmt.Sprintf(`{"step_ns":%d,"steps":50,"world_size":16}`, step.Nanoseconds())
+		if p.Spec.NodeName == failNode {
+			p.Status.Phase, p.Status.Reason, p.Status.Message = corev1.PodFailed, failure.Reason, failure.Message
+			msg = "NCCL error: unhandled system error"
+		}
+		p.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "pulse",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: msg}},
+		}}
+		created[p.Spec.NodeName] = p.DeepCopy()
+		return false, nil, nil
+	})
+	return created
+}
+
+func canaryNode(name string) *corev1.Node {
+	node := freshNode(name, time.Minute)
+	node.Status.Allocatable = corev1.ResourceList{gpuResource: resource.MustParse("8")}
+	return node
+}
+
+func TestCanaryRunner(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		step       time.Duration
+		failNode   string
+		failure    corev1.PodStatus
+		wantFailed bool
+		wantErr    bool
+	}{
+		{name: "fast steps pass", step: 40 * time.Millisecond},
+		{name: "slow steps fail", step: 2 * time.Second, wantFailed: true, wantErr: true},
+		{name: "failed rank fails", step: 40 * time.Millisecond, failNode: "gpu-node-1", wantFailed: true, wantErr: true},
+		{
+			name: "pod not admitted is not a verdict", step: 40 * time.Millisecond, failNode: "gpu-node-1",
+			failure: corev1.PodStatus{Reason: "OutOfnvidia.com/gpu", Message: "Node didn't have enough resource"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clientset := fake.NewSimpleClientset(canaryNode("gpu-node-0"), canaryNode("gpu-node-1"))
+			created := fakeCanaryPods(clientset, tc.step, tc.failNode, tc.failure)
+			r := &CanaryRunner{Client: clientset, Namespace: "straggler-shield", Image: "pytorch:test", PollInterval: time.Millisecond}
+
+			report, err := r.Run(context.Background(), "gpu-node-0", "gpu-node-1")
+			if (err != nil) != tc.wantErr || errors.Is(err, ErrCanaryFailed) != tc.wantFailed {
+				t.Fatalf("Run error = %v, want error %v, ErrCanaryFailed %v", err, tc.wantErr, tc.wantFailed)
+			}
+			if tc.failNode == "" && report.Elapsed() != tc.step {
+				t.Errorf("step = %v, want %v", report.Elapsed(), tc.step)
+			}
+			if got := envValue(created["gpu-node-1"], "MASTER_ADDR"); got != "10.0.0.1" {
+				t.Errorf("rank 1 MASTER_ADDR = %q, want rank 0's pod IP", got)
+			}
+			if got := created["gpu-node-0"].Spec.Containers[0].Resources.Limits[gpuResource]; got.Value() != 8 {
+				t.Errorf("gpu limit = %v, want all 8 allocatable", got.String())
+			}
+			pods, _ := clientset.CoreV1().Pods("straggler-shield").List(context.Background(), metav1.ListOptions{})
+			if len(pods.Items) != 0 {
+				t.Errorf("canary pods not cleaned up: %d remain", len(pods.Items))
+			}
+		})
+	}
+}
+
+func envValue(p *corev1.Pod, name string) string {
+	if p == nil {
+		return ""
+	}
+	for _, e := range p.Spec.Containers[0].Env {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+func TestCanaryFailureDegradesPair(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset(canaryNode("gpu-node-0"), canaryNode("gpu-node-1"), canaryNode("gpu-node-2"))
+	fakeCanaryPods(clientset, 2*time.Second, "", corev1.PodStatus{})
+	ctrl := NewController(clientset,
+		WithPulseFunc(func() (time.Duration, error) { return 10 * time.Millisecond, nil }),
+		WithCanary(&CanaryRunner{Client: clientset, Namespace: "straggler-shield", Image: "pytorch:test", PollInterval: time.Millisecond}, time.Minute),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ctrl.RunCanaries(ctx)
+
+	for _, name := range []string{"gpu-node-0", "gpu-node-1"} {
+		if err := ctrl.ReconcileNode(ctx, name); err != nil {
+			t.Fatalf("ReconcileNode(%s): %v", name, err)
+		}
+	}
+	// A scheduled revalidation is not a turn-up: no canary.
+	if err := ctrl.ValidateNode(ctx, "gpu-node-2"); err != nil {
+		t.Fatalf("ValidateNode: %v", err)
+	}
+
+	for _, name := range []string{"gpu-node-0", "gpu-node-1"} {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			node, _ := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+			if node.Labels[DegradedLabel] == "canary_failed" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s labels = %v, want %s=canary_failed", name, node.Labels, DegradedLabel)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	node, _ := clientset.CoreV1().Nodes().Get(ctx, "gpu-node-2", metav1.GetOptions{})
+	if _, ok := node.Labels[DegradedLabel]; ok {
+		t.Errorf("gpu-node-2 degraded by a canary it was not part of")
+	}
+}
@@ -0,0 +1,103 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// QuarantineMode is how a quarantined node is kept from new work.
+type QuarantineMode string
+
+const (
+	// QuarantineTaint applies the quarantine taint (see TaintConfig). The
+	// default.
+	QuarantineTaint QuarantineMode = "taint"
+	// QuarantineCordon sets spec.unschedulable, for clusters whose
+	// remediation tooling acts on cordoned nodes rather than custom taints.
+	QuarantineCordon QuarantineMode = "cordon"
+)
+
+// CordonedAnnotation is set, to the time of the cordon, on a node that
+// straggler-shield cordoned. A passing pulse uncordons only such nodes: a
+// node an operator cordoned, for maintenance or otherwise, stays cordoned.
+const CordonedAnnotation = "straggler-shield.io/cordoned"
+
+// WithQuarantineMode selects how nodes are quarantined. In QuarantineCordon
+// mode the node is cordoned and the GPUStraggler condition is recorded as
+// usual; the taint is not written. Policy soft quarantine has no cordon
+// equivalent, so the first failure cordons. Whatever the mode, a passing
+// pulse lifts both markers straggler-shield left, so switching modes does
+// not strand nodes.
+func WithQuarantineMode(m QuarantineMode) Option {
+	return func(c *Controller) {
+		if m != "" {
+			c.mode = m
+		}
+	}
+}
+
+// quarantineMarked reports whether node carries the marker the configured
+// mode quarantines with: the taint, or a cordon. A cordon counts whoever set
+// it, as a taint does.
+func (c *Controller) quarantineMarked(node *corev1.Node) bool {
+	return c.markedIn(node, node.Spec.Taints)
+}
+
+// markedIn is quarantineMarked with taints in place of node's own, for
+// callers that have modified them.
+func (c *Controller) markedIn(node *corev1.Node, taints []corev1.Taint) bool {
+	if c.mode == QuarantineCordon {
+		return node.Spec.Unschedulable
+	}
+	return findTaintByKey(taints, c.taint.Key) != nil
+}
+
+// markerName describes the quarantine marker for log and Event messages.
+func (c *Controller) markerName() string {
+	if c.mode == QuarantineCordon {
+		return "cordon"
+	}
+	return c.taint.Key + " taint"
+}
+
+// cordon is applyTaint for QuarantineCordon: it cordons the node, marking the
+// cordon as straggler-shield's, and records the GPUStraggler condition. A node
+// that is already cordoned only has the condition recorded; its cordon is not
+// claimed, so a pass leaves it for whoever set it. Idempotent.
+func (c *Controller) cordon(ctx context.Context, nodeName string, node *corev1.Node, failureReason string, elapsed time.Duration, evidence string) error {
+	const reason = "StragglerDetected"
+	if node.Spec.Unschedulable {
+		if quarantineRecorded(node) {
+			return nil // already quarantined
+		}
+		return c.recordQuarantine(ctx, node, reason, elapsed, evidence)
+	}
+
+	annotations := decisionAnnotations(opQuarantine, failureReason, elapsed, evidence)
+	annotations[CordonedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": annotations},
+		"spec":     map[string]any{"unschedulable": true},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal cordon patch: %w", err)
+	}
+	if _, err := c.client.CoreV1().Nodes().Patch(
+		ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{},
+	); err != nil {
+		return patchError("apply_taint", "patch node spec (cordon)", err)
+	}
+	if err := c.recordQuarantine(ctx, node, reason, elapsed, evidence); err != nil {
+		return err
+	}
+
+	c.event(node, corev1.EventTypeWarning, eventQuarantined, "node cordoned — "+evidence)
+	c.startEviction(ctx, node)
+	return nil
+}
@@ -0,0 +1,117 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCordonMode(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		cordoned      bool // an operator cordoned the node before the pulse
+		wantUncordons bool
+	}{
+		{name: "cordons and uncordons", wantUncordons: true},
+		{name: "operator cordon survives the pass", cordoned: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node := freshNode("gpu-node-0", time.Minute)
+			node.Spec.Unschedulable = tc.cordoned
+			client := fake.NewSimpleClientset(node)
+			var pulseErr error = pulse.ErrStragglerDetected
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulseErr }),
+				WithQuarantineMode(QuarantineCordon),
+			)
+			get := func() *corev1.Node {
+				t.Helper()
+				got, err := client.CoreV1().Nodes().Get(context.Background(), "gpu-node-0", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("Get node: %v", err)
+				}
+				return got
+			}
+
+			if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+				t.Fatalf("ValidateNode (fail): %v", err)
+			}
+			got := get()
+			_, ours := got.Annotations[CordonedAnnotation]
+			switch {
+			case !got.Spec.Unschedulable || !quarantineRecorded(got):
+				t.Fatalf("after a failure: unschedulable=%v, GPUStraggler recorded=%v; want both", got.Spec.Unschedulable, quarantineRecorded(got))
+			case len(got.Spec.Taints) != 0:
+				t.Errorf("taints = %v, want none in cordon mode", got.Spec.Taints)
+			case ours == tc.cordoned:
+				t.Errorf("%s annotation present = %v, want it only on a cordon straggler-shield set", CordonedAnnotation, ours)
+			}
+			if st := QuarantineState(got); st != StateQuarantined {
+				t.Errorf("QuarantineState = %s, want %s", st, StateQuarantined)
+			}
+
+			pulseErr = nil
+			if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+				t.Fatalf("ValidateNode (pass): %v", err)
+			}
+			got = get()
+			if quarantineRecorded(got) {
+				t.Error("GPUStraggler still True after a pass")
+			}
+			if got.Spec.Unschedulable == tc.wantUncordons {
+				t.Errorf("unschedulable = %v after a pass, want %v", got.Spec.Unschedulable, !tc.wantUncordons)
+			}
+			if _, ok := got.Annotations[CordonedAnnotation]; ok {
+				t.Errorf("%s left after a pass", CordonedAnnotation)
+			}
+		})
+	}
+}
+
+func TestCordonRemovedExternally(t *testing.T) {
+	t.Parallel()
+
+	node := quarantinedNode("gpu-node-0", time.Minute)
+	node.Spec.Taints = nil
+	ctrl := NewController(fake.NewSimpleClientset(node), WithQuarantineMode(QuarantineCordon))
+	if !ctrl.TaintRemovedExternally(node) {
+		t.Error("GPUStraggler=True on an uncordoned node not reported as an external removal")
+	}
+	node.Spec.Unschedulable = true
+	if ctrl.TaintRemovedExternally(node) {
+		t.Error("cordoned, recorded node reported as an external removal")
+	}
+}
+
+func TestForceClearUncordons(t *testing.T) {
+	t.Parallel()
+
+	node := quarantinedNode("gpu-node-0", time.Minute)
+	node.Spec.Taints = nil
+	node.Spec.Unschedulable = true
+	node.Annotations = map[string]string{CordonedAnnotation: "2026-10-17T09:00:00Z"}
+	client := fake.NewSimpleClientset(node)
+	if err := ForceClear(context.Background(), client, "gpu-node-0", DefaultTaintKey, ForceClearRecord{By: "oncall"}); err != nil {
+		t.Fatalf("ForceClear: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(context.Background(), "gpu-node-0", metav1.GetOptions{})
+	if _, ok := got.Annotations[CordonedAnnotation]; got.Spec.Unschedulable || ok {
+		t.Errorf("unschedulable=%v, annotation present=%v after ForceClear; want the cordon lifted", got.Spec.Unschedulable, ok)
+	}
+	if err := ForceClear(context.Background(), client, "gpu-node-0", DefaultTaintKey, ForceClearRecord{By: "oncall"}); !errors.Is(err, ErrNotQuarantined) {
+		t.Errorf("second ForceClear = %v, want ErrNotQuarantined", err)
+	}
+}
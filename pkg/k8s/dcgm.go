@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// dcgmUtilMetric is the dcgm-exporter gauge for GPU utilization (percent).
+const dcgmUtilMetric = "DCGM_FI_DEV_GPU_UTIL"
+
+// DCGMIdle returns an IdleFunc that scrapes a dcgm-exporter endpoint and
+// reports the node idle only if every GPU's utilization is at or below
+// maxUtilPct. urlTemplate may contain "{node}", replaced with the node name,
+// for exporters reached through a per-node address. This catches GPU work
+// that the pod list cannot see, such as processes started outside Kubernetes.
+func DCGMIdle(urlTemplate string, maxUtilPct float64) IdleFunc {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(ctx context.Context, nodeName string) (bool, error) {
+		url := strings.ReplaceAll(urlTemplate, "{node}", nodeName)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, fmt.Errorf("dcgm request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("scrape dcgm-exporter %s: %w", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return false, fmt.Errorf("scrape dcgm-exporter %s: %s", url, resp.Status)
+		}
+
+		parser := expfmt.NewTextParser(model.UTF8Validation)
+		families, err := parser.TextToMetricFamilies(resp.Body)
+		if err != nil {
+			return false, fmt.Errorf("parse dcgm-exporter metrics: %w", err)
+		}
+		fam, ok := families[dcgmUtilMetric]
+		if !ok {
+			return false, fmt.Errorf("dcgm-exporter at %s does not export %s", url, dcgmUtilMetric)
+		}
+		for _, m := range fam.GetMetric() {
+			if m.GetGauge().GetValue() > maxUtilPct {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrNotDispatched is returned when a reconcile's context ends while its
+// pulse is waiting for a dispatch slot (see WithPulseDispatch). No pulse ran,
+// so no verdict is applied.
+var ErrNotDispatched = errors.New("pulse not dispatched")
+
+// DispatchConfig limits how many pulses run at once. Each in-flight pulse
+// holds a node's GPUs at full power, so after a mass reboot (power
+// restoration, a rack coming back) an unlimited controller would put every
+// GPU in a rack or power domain under GEMM load at the same moment.
+type DispatchConfig struct {
+	// DomainLabel is the node label naming the node's rack or power domain.
+	// Nodes without it share the domain "". Empty puts every node in "".
+	DomainLabel string
+	// PerDomain is the most pulses in flight per domain. 0 is unlimited.
+	PerDomain int
+	// Total is the most pulses in flight across all domains. 0 is unlimited.
+	Total int
+	// Order decides which queued pulse gets the next free slot: a before b
+	// if Order(a, b). Ties, and a nil Order, go first come first served.
+	// See ValidationPriority.
+	Order func(a, b *corev1.Node) bool
+}
+
+// WithPulseDispatch queues pulses beyond the limits in cfg instead of
+// starting them. A queued pulse waits, with the node's lock held so
+// duplicate triggers are still discarded, until a slot in its domain frees
+// up and no pulse ahead of it in cfg.Order can use it. The wait is reported
+// as the "dispatch" reconcile phase and in DispatchWait. Cached results
+// (WithResultCache) need no slot. Without limits nothing queues, but pulses
+// in flight are still counted by domain.
+func WithPulseDispatch(cfg DispatchConfig) Option {
+	return func(c *Controller) {
+		c.dispatch = newDispatcher(cfg)
+	}
+}
+
+// dispatcher hands out pulse slots per domain and in total, to queued
+// pulses in priority order.
+type dispatcher struct {
+	cfg DispatchConfig
+
+	mu       sync.Mutex
+	seq      uint64
+	total    int
+	inFlight map[string]int
+	queue    []*dispatchWaiter
+}
+
+// dispatchWaiter is one pulse waiting for a slot. ready is closed once the
+// slot is granted.
+type dispatchWaiter struct {
+	node   *corev1.Node
+	domain string
+	seq    uint64
+	ready  chan struct{}
+}
+
+func newDispatcher(cfg DispatchConfig) *dispatcher {
+	return &dispatcher{cfg: cfg, inFlight: map[string]int{}}
+}
+
+// domainOf returns node's domain under cfg.DomainLabel.
+func (d *dispatcher) domainOf(node *corev1.Node) string {
+	if d.cfg.DomainLabel == "" {
+		return ""
+	}
+	return node.Labels[d.cfg.DomainLabel]
+}
+
+// acquire blocks until node may start a pulse and returns the function that
+// gives the slot back. A nil dispatcher never blocks.
+func (d *dispatcher) acquire(ctx context.Context, node *corev1.Node) (release func(), err error) {
+	if d == nil {
+		return func() {}, nil
+	}
+	w := &dispatchWaiter{node: node, domain: d.domainOf(node), ready: make(chan struct{})}
+	metrics.PulsesQueued.WithLabelValues(w.domain).Inc()
+	defer metrics.PulsesQueued.WithLabelValues(w.domain).Dec()
+
+	d.mu.Lock()
+	d.seq++
+	w.seq = d.seq
+	d.queue = append(d.queue, w)
+	d.grantLocked()
+	d.mu.Unlock()
+
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		d.mu.Lock()
+		select {
+		case <-w.ready:
+			// Granted as ctx ended; give the slot straight back.
+			d.releaseLocked(w.domain)
+		default:
+			d.dequeueLocked(w)
+		}
+		d.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s waiting on domain %q: %w", ErrNotDispatched, node.Name, w.domain, ctx.Err())
+	}
+	metrics.PulsesInFlight.WithLabelValues(w.domain).Inc()
+	return func() {
+		metrics.PulsesInFlight.WithLabelValues(w.domain).Dec()
+		d.mu.Lock()
+		d.releaseLocked(w.domain)
+		d.mu.Unlock()
+	}, nil
+}
+
+// grantLocked hands free slots to queued pulses, best first. A pulse whose
+// domain is full is passed over for later ones in other domains; once the
+// total limit is reached nothing more is granted.
+func (d *dispatcher) grantLocked() {
+	if len(d.queue) == 0 {
+		return
+	}
+	sort.SliceStable(d.queue, func(i, j int) bool {
+		a, b := d.queue[i], d.queue[j]
+		if d.cfg.Order != nil {
+			if d.cfg.Order(a.node, b.node) {
+				return true
+			}
+			if d.cfg.Order(b.node, a.node) {
+				return false
+			}
+		}
+		return a.seq < b.seq
+	})
+	kept := d.queue[:0]
+	for _, w := range d.queue {
+		if (d.cfg.Total <= 0 || d.total < d.cfg.Total) &&
+			(d.cfg.PerDomain <= 0 || d.inFlight[w.domain] < d.cfg.PerDomain) {
+			d.total++
+			d.inFlight[w.domain]++
+			close(w.ready)
+			continue
+		}
+		kept = append(kept, w)
+	}
+	clear(d.queue[len(kept):])
+	d.queue = kept
+}
+
+func (d *dispatcher) releaseLocked(domain string) {
+	d.total--
+	if d.inFlight[domain]--; d.inFlight[domain] == 0 {
+		delete(d.inFlight, domain)
+	}
+	d.grantLocked()
+}
+
+func (d *dispatcher) dequeueLocked(w *dispatchWaiter) {
+	for i, q := range d.queue {
+		if q == w {
+			d.queue = append(d.queue[:i], d.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// waitForDispatch claims a pulse slot for node, counting the wait against
+// the reconcile timing. Waits of a second or more are logged.
+func (c *Controller) waitForDispatch(ctx context.Context, node *corev1.Node) (release func(), err error) {
+	start := time.Now()
+	release, err = c.dispatch.acquire(ctx, node)
+	waited := time.Since(start)
+	timingFrom(ctx).addDispatch(waited)
+	if c.dispatch != nil {
+		metrics.DispatchWait.Observe(waited.Seconds())
+	}
+	if err == nil && waited >= time.Second {
+		c.logger.Info("pulse dispatched after waiting for a slot", "node", node.Name,
+			"domain", c.dispatch.domainOf(node), "waited", waited)
+	}
+	return release, err
+}
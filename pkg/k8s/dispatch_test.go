@@ -0,0 +1,161 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPulseDispatchLimits(t *testing.T) {
+	t.Parallel()
+
+	racks := map[string]string{"gpu-node-0": "a", "gpu-node-1": "a", "gpu-node-2": "a", "gpu-node-3": "b", "gpu-node-4": "b"}
+	var objs []*corev1.Node
+	for name, rack := range racks {
+		node := freshNode(name, time.Minute)
+		node.Labels = map[string]string{"rack": rack}
+		objs = append(objs, node)
+	}
+	client := fake.NewSimpleClientset()
+	for _, node := range objs {
+		if _, err := client.CoreV1().Nodes().Create(context.Background(), node, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	peak := map[string]int{}
+	total, peakTotal := 0, 0
+	ctrl := NewController(client,
+		WithNodePulse(func(_ context.Context, nodeName string) (time.Duration, error) {
+			rack := racks[nodeName]
+			mu.Lock()
+			running[rack]++
+			total++
+			peak[rack] = max(peak[rack], running[rack])
+			peakTotal = max(peakTotal, total)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			running[rack]--
+			total--
+			mu.Unlock()
+			return 8 * time.Millisecond, nil
+		}),
+		WithPulseDispatch(DispatchConfig{DomainLabel: "rack", PerDomain: 2, Total: 3}),
+	)
+
+	var wg sync.WaitGroup
+	for name := range racks {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := ctrl.ValidateNode(context.Background(), name); err != nil {
+				t.Errorf("ValidateNode(%s): %v", name, err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if peak["a"] > 2 || peak["b"] > 2 || peakTotal > 3 {
+		t.Errorf("peak in flight: rack a %d, rack b %d, total %d; want at most 2 per rack and 3 in total", peak["a"], peak["b"], peakTotal)
+	}
+	if peakTotal < 2 {
+		t.Errorf("peak in flight %d, want pulses to overlap up to the limits", peakTotal)
+	}
+}
+
+func TestPulseDispatchCancelled(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute), freshNode("gpu-node-1", time.Minute))
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	ctrl := NewController(client,
+		WithNodePulse(func(_ context.Context, nodeName string) (time.Duration, error) {
+			if nodeName == "gpu-node-0" {
+				close(started)
+				<-unblock
+			}
+			return 8 * time.Millisecond, nil
+		}),
+		WithPulseDispatch(DispatchConfig{Total: 1}),
+	)
+	go func() { _ = ctrl.ValidateNode(context.Background(), "gpu-node-0") }()
+	<-started
+
+	// gpu-node-1 queues behind gpu-node-0 until its context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := ctrl.ValidateNode(ctx, "gpu-node-1")
+	close(unblock)
+	if !errors.Is(err, ErrNotDispatched) {
+		t.Fatalf("ValidateNode = %v, want ErrNotDispatched", err)
+	}
+	node, _ := client.CoreV1().Nodes().Get(context.Background(), "gpu-node-1", metav1.GetOptions{})
+	if findTaint(node, zombieTaintKey) != nil || quarantineRecorded(node) {
+		t.Error("undispatched pulse quarantined the node")
+	}
+}
+
+func TestValidationBacklogMetrics(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute), freshNode("gpu-node-1", time.Minute))
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	ctrl := NewController(client,
+		WithNodePulse(func(_ context.Context, nodeName string) (time.Duration, error) {
+			if nodeName == "gpu-node-0" {
+				close(started)
+				<-unblock
+			}
+			return 8 * time.Millisecond, nil
+		}),
+		WithPulseDispatch(DispatchConfig{Total: 1}),
+		WithShard(41), // a label no other test uses
+	)
+	pending := metrics.ValidationsPending.WithLabelValues("41")
+	done := metrics.Validations.WithLabelValues("41")
+	before := testutil.ToFloat64(done)
+
+	// gpu-node-1 queues behind gpu-node-0.
+	var wg sync.WaitGroup
+	for _, name := range []string{"gpu-node-0", "gpu-node-1"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := ctrl.ValidateNode(context.Background(), name); err != nil {
+				t.Errorf("ValidateNode(%s): %v", name, err)
+			}
+		}(name)
+		if name == "gpu-node-0" {
+			<-started
+		}
+	}
+	for testutil.ToFloat64(pending) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if got := testutil.ToFloat64(done) - before; got != 0 {
+		t.Errorf("%s = %v while both validations are pending, want 0", metrics.ValidationsName, got)
+	}
+	close(unblock)
+	wg.Wait()
+
+	if got := testutil.ToFloat64(pending); got != 0 {
+		t.Errorf("%s = %v after both verdicts, want 0", metrics.ValidationsPendingName, got)
+	}
+	if got := testutil.ToFloat64(done) - before; got != 2 {
+		t.Errorf("%s = %v, want 2", metrics.ValidationsName, got)
+	}
+}
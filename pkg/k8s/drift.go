@@ -0,0 +1,161 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DecisionAnnotation holds the latest quarantine or clear decision applied
+// to a node, as JSON (see Decision). AuditNode checks the node's markers
+// against it.
+const DecisionAnnotation = "straggler-shield.io/decision"
+
+// Drift found by AuditNode, used as the kind label of
+// metrics.MarkerDisagreements alongside the kinds in audit.go.
+const (
+	// driftConditionMissing: the latest decision quarantined the node and
+	// the taint is in place, but GPUStraggler=True is gone. The condition
+	// is restored from the decision.
+	driftConditionMissing = "condition_missing"
+
+	// driftStaleQuarantine: the latest decision cleared the node, but its
+	// quarantine markers predate that clear. They are removed.
+	driftStaleQuarantine = "stale_quarantine"
+
+	// driftLabelMissing: a degraded or unfit taint without the label that
+	// mirrors it. The label is restored from the taint value.
+	driftLabelMissing = "label_missing"
+)
+
+// decisionSkew is how long after a decision's spec patch its GPUStraggler
+// condition may still be written; a later condition change is another
+// writer's.
+const decisionSkew = 30 * time.Second
+
+// Decision is the latest decision applied to a node, recorded in
+// DecisionAnnotation.
+type Decision struct {
+	Op        string    `json:"op"`               // "quarantine" or "clear"
+	Reason    string    `json:"reason,omitempty"` // failure_reason of a quarantine
+	ElapsedMS int64     `json:"elapsed_ms,omitempty"`
+	Evidence  string    `json:"evidence,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// NodeDecision returns the decision recorded on node, if any.
+func NodeDecision(node *corev1.Node) (Decision, bool) {
+	var d Decision
+	s, ok := node.Annotations[DecisionAnnotation]
+	if !ok || json.Unmarshal([]byte(s), &d) != nil {
+		return Decision{}, false
+	}
+	return d, true
+}
+
+// decisionAnnotations returns the annotation patch recording a decision in
+// DecisionAnnotation. It rides on the spec patch that applies the decision,
+// so recording it costs no extra write.
+func decisionAnnotations(op, reason string, elapsed time.Duration, evidence string) map[string]any {
+	value, _ := json.Marshal(Decision{Op: op, Reason: reason, ElapsedMS: elapsed.Milliseconds(), Evidence: evidence, At: time.Now().UTC()})
+	return map[string]any{DecisionAnnotation: string(value)}
+}
+
+// AuditNode checks that nodeName's taints, GPUStraggler condition, labels and
+// annotations agree with each other and with the latest decision recorded on
+// it, and repairs drift:
+//
+//   - A quarantine whose taint was removed outside straggler-shield while
+//     GPUStraggler is still True is re-evaluated with a pulse, as
+//     ReconcileNode does when the watch sees it happen.
+//   - A quarantine taint straggler-shield decided whose condition is gone
+//     gets the condition back.
+//   - Quarantine markers older than the latest clear decision are removed.
+//   - A degraded or unfit taint without its label gets the label back.
+//   - Stale annotations are collected as by CollectStaleMarkers.
+//
+// Markers that changed after the latest decision came from a newer writer and
+// are left alone, as is a node whose decision is still pending in the journal
+// (see WithDecisionJournal). Each repair is logged, counted in
+// metrics.MarkerDisagreements and recorded as a QuarantineMarkersDisagree
+// Event. Run it periodically as a backstop for missed watch events and
+// partial writes; the caller serialises it with the node's pulses.
+func (c *Controller) AuditNode(ctx context.Context, nodeName string) error {
+	if c.journal.isPending(nodeName) {
+		return nil // replay will bring the node in line with it
+	}
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get node %s: %w", nodeName, err)
+	}
+
+	if c.TaintRemovedExternally(node) {
+		ctx, timing := beginReconcile(ctx)
+		defer c.endReconcile(nodeName, timing)
+		c.disagreement(node, disagreeTaintRemoved,
+			fmt.Sprintf("%s removed outside straggler-shield while GPUStraggler=True, found by audit — re-evaluating", c.markerName()))
+		return c.validate(ctx, node)
+	}
+
+	if d, ok := NodeDecision(node); ok {
+		if err := c.auditDecision(ctx, node, d); err != nil {
+			return err
+		}
+	}
+	if err := c.auditLabels(ctx, node); err != nil {
+		return err
+	}
+	return c.CollectStaleMarkers(ctx, nodeName)
+}
+
+// auditDecision repairs node's quarantine markers from the latest decision.
+func (c *Controller) auditDecision(ctx context.Context, node *corev1.Node, d Decision) error {
+	cond := StragglerCondition(node)
+	if cond != nil && cond.LastTransitionTime.After(d.At.Add(decisionSkew)) {
+		return nil // a newer writer
+	}
+	marked := c.quarantineMarked(node)
+	switch {
+	case d.Op == opQuarantine && marked && !quarantineRecorded(node):
+		if c.mode == QuarantineCordon {
+			if _, ours := node.Annotations[CordonedAnnotation]; !ours {
+				return nil
+			}
+		}
+		reason := "StragglerDetected"
+		if t := findTaintByKey(node.Spec.Taints, c.taint.Key); t != nil && t.Effect == corev1.TaintEffectPreferNoSchedule {
+			reason = "StragglerSuspected"
+		}
+		c.disagreement(node, driftConditionMissing,
+			fmt.Sprintf("%s decided at %s has no GPUStraggler=True condition — restoring it", c.markerName(), d.At.UTC().Format(time.RFC3339)))
+		return c.recordQuarantine(ctx, node, reason, time.Duration(d.ElapsedMS)*time.Millisecond, d.Evidence)
+	case d.Op == opClear && quarantineRecorded(node):
+		c.disagreement(node, driftStaleQuarantine,
+			fmt.Sprintf("GPUStraggler=True predates the clear decided at %s — removing the quarantine", d.At.UTC().Format(time.RFC3339)))
+		return c.clearQuarantine(ctx, node, d.At, d.Evidence)
+	}
+	return nil
+}
+
+// auditLabels restores the label of each degraded or unfit taint on node
+// that lacks it.
+func (c *Controller) auditLabels(ctx context.Context, node *corev1.Node) error {
+	labels := map[string]*string{}
+	for _, t := range node.Spec.Taints {
+		if t.Key != DegradedLabel && !isUnfitKey(t.Key) {
+			continue
+		}
+		if _, ok := node.Labels[t.Key]; !ok {
+			labels[t.Key] = &t.Value
+			c.disagreement(node, driftLabelMissing, fmt.Sprintf("%s taint has no %s label — restoring it", t.Key, t.Key))
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return c.patchLabels(ctx, node.Name, labels)
+}
@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAuditNode(t *testing.T) {
+	t.Parallel()
+
+	decided := func(n *corev1.Node, op string, ago time.Duration) *corev1.Node {
+		value, _ := json.Marshal(Decision{Op: op, At: time.Now().Add(-ago)})
+		n.Annotations = map[string]string{DecisionAnnotation: string(value)}
+		return n
+	}
+	withoutCondition := func(n *corev1.Node) *corev1.Node {
+		n.Status.Conditions = n.Status.Conditions[:1]
+		return n
+	}
+	cases := []struct {
+		name       string
+		node       *corev1.Node
+		pulseErr   error
+		wantTaint  bool
+		wantStatus corev1.ConditionStatus
+		wantLabel  string
+	}{
+		{
+			name:       "condition lost after a quarantine is restored",
+			node:       withoutCondition(decided(quarantinedNode("n", time.Hour), opQuarantine, time.Hour)),
+			wantTaint:  true,
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:      "taint without a quarantine decision is an operator hold",
+			node:      withoutCondition(quarantinedNode("n", time.Hour)),
+			wantTaint: true,
+		},
+		{
+			name:       "quarantine older than the latest clear is removed",
+			node:       decided(quarantinedNode("n", time.Hour), opClear, time.Minute),
+			wantStatus: corev1.ConditionFalse,
+		},
+		{
+			name:       "quarantine newer than the latest clear is kept",
+			node:       decided(quarantinedNode("n", time.Hour), opClear, 3*time.Hour),
+			wantTaint:  true,
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "removed taint is re-evaluated",
+			node: func() *corev1.Node {
+				n := decided(quarantinedNode("n", time.Hour), opQuarantine, time.Hour)
+				n.Spec.Taints = nil
+				return n
+			}(),
+			pulseErr:   pulse.ErrStragglerDetected,
+			wantTaint:  true,
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "degraded taint gets its label back",
+			node: func() *corev1.Node {
+				n := freshNode("n", time.Hour)
+				n.Spec.Taints = []corev1.Taint{{Key: DegradedLabel, Value: "high_variance", Effect: corev1.TaintEffectPreferNoSchedule}}
+				return n
+			}(),
+			wantLabel: "high_variance",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(tc.node)
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, tc.pulseErr }),
+			)
+			if err := ctrl.AuditNode(context.Background(), "n"); err != nil {
+				t.Fatalf("AuditNode: %v", err)
+			}
+			got, err := client.CoreV1().Nodes().Get(context.Background(), "n", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tainted := findTaint(got, zombieTaintKey) != nil; tainted != tc.wantTaint {
+				t.Errorf("tainted = %v, want %v", tainted, tc.wantTaint)
+			}
+			var status corev1.ConditionStatus
+			if cond := StragglerCondition(got); cond != nil {
+				status = cond.Status
+			}
+			if status != tc.wantStatus {
+				t.Errorf("GPUStraggler = %q, want %q", status, tc.wantStatus)
+			}
+			if label := got.Labels[DegradedLabel]; label != tc.wantLabel {
+				t.Errorf("%s label = %q, want %q", DegradedLabel, label, tc.wantLabel)
+			}
+		})
+	}
+}
+
+func TestDecisionRecorded(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(freshNode("n", time.Minute))
+	var pulseErr error = pulse.ErrStragglerDetected
+	ctrl := NewController(client, WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulseErr }))
+	for _, want := range []struct{ op, reason string }{
+		{opQuarantine, "latency_threshold_exceeded"},
+		{opClear, ""},
+	} {
+		if err := ctrl.ValidateNode(context.Background(), "n"); err != nil {
+			t.Fatalf("ValidateNode: %v", err)
+		}
+		got, err := client.CoreV1().Nodes().Get(context.Background(), "n", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d, ok := NodeDecision(got); !ok || d.Op != want.op || d.Reason != want.reason {
+			t.Errorf("decision = %+v, %v, want %s %q", d, ok, want.op, want.reason)
+		}
+		pulseErr = nil
+	}
+}
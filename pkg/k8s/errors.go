@@ -0,0 +1,45 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Patch failures from applyTaint and removeTaint wrap one of these so callers
+// can tell an RBAC regression from a transient conflict with errors.Is. The
+// underlying API error stays in the chain.
+var (
+	// ErrPatchConflict means the node changed underneath the patch (409); the
+	// next trigger will retry against the current object.
+	ErrPatchConflict = errors.New("node patch conflict")
+
+	// ErrForbidden means the agent's service account lacks the RBAC verb for
+	// the node or its status subresource (403). Retrying does not help.
+	ErrForbidden = errors.New("node patch forbidden")
+
+	// ErrNodeNotFound means the node was deleted before the patch landed.
+	ErrNodeNotFound = errors.New("node not found")
+)
+
+// patchError wraps a failed node patch with its class sentinel and counts it
+// in metrics.PatchFailures under op. what describes the patch for the message.
+func patchError(op, what string, err error) error {
+	class, sentinel := "other", error(nil)
+	switch {
+	case apierrors.IsConflict(err):
+		class, sentinel = "conflict", ErrPatchConflict
+	case apierrors.IsForbidden(err):
+		class, sentinel = "forbidden", ErrForbidden
+	case apierrors.IsNotFound(err):
+		class, sentinel = "not_found", ErrNodeNotFound
+	}
+	metrics.PatchFailures.WithLabelValues(op, class).Inc()
+	if sentinel == nil {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	return fmt.Errorf("%s: %w: %w", what, sentinel, err)
+}
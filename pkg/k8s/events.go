@@ -0,0 +1,151 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Event reasons emitted on the Node object, visible in kubectl describe node
+// and kubectl get events --field-selector involvedObject.kind=Node.
+const (
+	eventQuarantined = "StragglerQuarantined"
+	eventPassed      = "PulsePassed"
+)
+
+// Event aggregation and rate limits. Every pulse message differs (it carries
+// the pulse ID), so without aggregation a flapping node writes a new Event
+// per pulse and can flood the events store during an incident.
+const (
+	// eventAggregateAfter distinct messages with the same reason on the
+	// same node within eventAggregateWindow are folded into one Event whose
+	// count goes up with each repeat, e.g. "StragglerQuarantined (x7)". Its
+	// message is the latest one, prefixed "(combined from similar events)".
+	eventAggregateAfter  = 2
+	eventAggregateWindow = time.Hour
+
+	// eventBurst Events per node may be written at once; after that one
+	// more each eventRefill. Events over the limit are dropped.
+	eventBurst  = 10
+	eventRefill = time.Minute
+)
+
+// NewEventRecorder returns a recorder that writes Events through client as
+// component, aggregated and rate limited per node (see eventAggregateAfter).
+// Call stop on shutdown to flush and release the broadcaster.
+func NewEventRecorder(client kubernetes.Interface, component string) (recorder record.EventRecorder, stop func()) {
+	b := record.NewBroadcaster(record.WithCorrelatorOptions(record.CorrelatorOptions{
+		MaxEvents:            eventAggregateAfter,
+		MaxIntervalInSeconds: int(eventAggregateWindow / time.Second),
+		BurstSize:            eventBurst,
+		QPS:                  float32(time.Second) / float32(eventRefill),
+	}))
+	b.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return b.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component}), b.Shutdown
+}
+
+// WithEventRecorder emits a Kubernetes Event on the Node each time the
+// controller quarantines or clears it. Without it no Events are emitted.
+func WithEventRecorder(r record.EventRecorder) Option {
+	return func(c *Controller) { c.recorder = r }
+}
+
+// event records an Event on node if a recorder is configured.
+func (c *Controller) event(node *corev1.Node, eventType, reason, message string) {
+	if c.recorder == nil {
+		return
+	}
+	c.recorder.Event(node, eventType, reason, message)
+}
+
+// failureEvidence is the Event and GPUStraggler condition message for a
+// failed pulse: the reason, the measured and threshold values, the failing
+// GPU's identity and its throttle reasons when err carries a PulseFailure,
+// and the pulse ID that leads back to the controller
+// logs. When more than one device or link failed, each is listed after it,
+// so a partial-node failure is visible from the API alone, followed by any
+// remediation attempted before the verdict.
+func failureEvidence(logReason string, report *pulse.PulseReport, err error) string {
+	var msg string
+	var detail *pulse.PulseFailure
+	switch {
+	case errors.As(err, &detail) && (detail.Unit == "xid" || detail.Unit == "rows" || detail.Unit == "check"):
+		// XIDs, row remaps and unitless external checks have no threshold;
+		// the error names the GPU.
+		msg = fmt.Sprintf("%v (pulse %s)", err, report.PulseID)
+	case detail != nil:
+		msg = fmt.Sprintf("%s: measured %g %s, threshold %g %s", logReason, detail.MeasuredValue, detail.Unit, detail.ThresholdValue, detail.Unit)
+		if detail.Arch != "" {
+			msg += fmt.Sprintf(" (%s calibration)", detail.Arch)
+		}
+		if detail.GPU != nil {
+			msg += fmt.Sprintf(" on GPU %s", detail.GPU)
+		}
+		if len(detail.ThrottleReasons) > 0 {
+			msg += fmt.Sprintf(" with throttle reasons %s", strings.Join(detail.ThrottleReasons, ", "))
+		}
+		msg += fmt.Sprintf(" (pulse %s)", report.PulseID)
+	default:
+		msg = fmt.Sprintf("%s after %v: %v (pulse %s)", logReason, report.Elapsed(), err, report.PulseID)
+	}
+	if failures := report.Failures(); len(failures) > 1 {
+		errs := make([]string, len(failures))
+		for i, f := range failures {
+			errs[i] = f.Error
+		}
+		msg += fmt.Sprintf("; %d components failed: %s", len(failures), strings.Join(errs, "; "))
+	}
+	switch rem := report.Remediation; {
+	case rem == nil:
+	case rem.Error != "":
+		msg += fmt.Sprintf("; remediation %s gave no verdict: %s", rem.Action, rem.Error)
+	default:
+		msg += fmt.Sprintf("; still failing after %s (pulse %s failed first: %s)", rem.Action, rem.FailedPulseID, rem.FailedError)
+	}
+	return msg + cooldownEvidence(report) + clockResetEvidence(report)
+}
+
+// clockResetEvidence describes a clock reset tried during the pulse, or
+// returns "".
+func clockResetEvidence(report *pulse.PulseReport) string {
+	switch reset := report.ClockReset; {
+	case reset == nil:
+		return ""
+	case reset.Recovered:
+		return fmt.Sprintf("; clocks recovered after a clock reset (first check: %s)", reset.FirstError)
+	case reset.Error != "":
+		return fmt.Sprintf("; clock reset gave no second check: %s", reset.Error)
+	default:
+		return fmt.Sprintf("; clocks still stuck after a clock reset (first check: %s)", reset.FirstError)
+	}
+}
+
+// cooldownEvidence describes the pre-flight checks run again while hot GPUs
+// cooled, or returns "".
+func cooldownEvidence(report *pulse.PulseReport) string {
+	switch cd := report.Cooldown; {
+	case cd == nil:
+		return ""
+	case cd.Recovered:
+		return fmt.Sprintf("; GPUs cooled after %v (first check: %s)", time.Duration(cd.WaitedNS), cd.FirstError)
+	default:
+		return fmt.Sprintf("; GPUs still hot after %d checks over %v", cd.Rechecks, time.Duration(cd.WaitedNS))
+	}
+}
+
+// passEvidence is the Event message for a passing pulse.
+func passEvidence(report *pulse.PulseReport) string {
+	if th := report.Thresholds.StragglerThreshold; th > 0 {
+		return fmt.Sprintf("GPU pulse passed: worst mean %v, threshold %v (pulse %s)", report.Elapsed(), th, report.PulseID) + cooldownEvidence(report) + clockResetEvidence(report)
+	}
+	return fmt.Sprintf("GPU pulse passed: worst mean %v (pulse %s)", report.Elapsed(), report.PulseID) + cooldownEvidence(report) + clockResetEvidence(report)
+}
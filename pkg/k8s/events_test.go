@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEventRecorderAggregates(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", time.Minute)
+	clientset := fake.NewSimpleClientset(node)
+	recorder, stop := NewEventRecorder(clientset, "straggler-shield")
+	defer stop()
+
+	// A flapping node: seven quarantines, each with its own pulse ID.
+	for i := 0; i < 7; i++ {
+		recorder.Event(node, corev1.EventTypeWarning, eventQuarantined, fmt.Sprintf("latency_threshold_exceeded (pulse p%d)", i))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, err := clientset.CoreV1().Events(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var counts []int32
+		var combined string
+		for _, e := range events.Items {
+			counts = append(counts, e.Count)
+			if e.Count > 1 {
+				combined = e.Message
+			}
+		}
+		// The first quarantine is kept as is; the rest are one counted
+		// series carrying the latest message.
+		if len(counts) == 2 && counts[0]+counts[1] == 7 && strings.Contains(combined, "pulse p6") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("events have counts %v, want the first alone and the other 6 combined (latest message %q)", counts, combined)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClockResetEvidence(t *testing.T) {
+	t.Parallel()
+
+	stuck := "post-pulse GPU 3: SM clock 600MHz below 50% of max 1980MHz"
+	failed := &pulse.PulseReport{PulseID: "p1", ClockReset: &pulse.ClockReset{FirstError: stuck}}
+	err := fmt.Errorf("%w: %s", pulse.ErrStragglerDetected, stuck)
+	if got := failureEvidence("latency threshold exceeded", failed, err); !strings.Contains(got, "clocks still stuck after a clock reset (first check: "+stuck) {
+		t.Errorf("failure evidence = %q, want both clock checks", got)
+	}
+
+	recovered := &pulse.PulseReport{PulseID: "p2", ClockReset: &pulse.ClockReset{FirstError: stuck, Recovered: true}}
+	if got := passEvidence(recovered); !strings.HasPrefix(got, "GPU pulse passed") || !strings.Contains(got, "clocks recovered after a clock reset (first check: "+stuck) {
+		t.Errorf("pass evidence = %q, want the recovery and the first check", got)
+	}
+
+	if got := passEvidence(&pulse.PulseReport{PulseID: "p3"}); strings.Contains(got, "clock reset") {
+		t.Errorf("pass evidence = %q, want no clock reset", got)
+	}
+}
+
+func TestCooldownEvidence(t *testing.T) {
+	t.Parallel()
+
+	hot := "pre-flight GPU 2: GPU idle temperature above pre-flight ceiling: 84°C exceeds 70°C (thermal recovery incomplete)"
+	cooled := &pulse.PulseReport{PulseID: "p1", Cooldown: &pulse.Cooldown{FirstError: hot, Rechecks: 2, WaitedNS: int64(4 * time.Minute), Recovered: true}}
+	if got := passEvidence(cooled); !strings.Contains(got, "GPUs cooled after 4m0s (first check: "+hot) {
+		t.Errorf("pass evidence = %q, want the cool-down and the first check", got)
+	}
+
+	still := &pulse.PulseReport{PulseID: "p2", Cooldown: &pulse.Cooldown{FirstError: hot, Rechecks: 3, WaitedNS: int64(6 * time.Minute)}}
+	err := fmt.Errorf("%w: 81°C exceeds 70°C", pulse.ErrIdleTemperature)
+	if got := failureEvidence("GPU idle temperature above pre-flight ceiling", still, err); !strings.Contains(got, "GPUs still hot after 3 checks over 6m0s") {
+		t.Errorf("failure evidence = %q, want the checks run", got)
+	}
+}
+
+func TestThrottleReasonEvidence(t *testing.T) {
+	t.Parallel()
+
+	err := &pulse.PulseFailure{
+		Cause:           fmt.Errorf("%w: post-pulse GPU 1: %w: SM clock 600MHz below 50%% of max 1980MHz", pulse.ErrStragglerDetected, pulse.ErrClockDerated),
+		MeasuredValue:   600,
+		ThresholdValue:  500,
+		Unit:            "ms",
+		ThrottleReasons: []string{"sw_power_cap", "hw_power_brake_slowdown"},
+	}
+	got := failureEvidence("GPU SM clock derated under load", &pulse.PulseReport{PulseID: "p1"}, err)
+	if want := "with throttle reasons sw_power_cap, hw_power_brake_slowdown (pulse p1)"; !strings.Contains(got, want) {
+		t.Errorf("failure evidence = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// eventEvicted is the Event reason recorded on the Node when GPU pods are
+// drained after a quarantine.
+const eventEvicted = "GPUPodsEvicted"
+
+// EvictionConfig tunes the drain started by WithPodEviction.
+type EvictionConfig struct {
+	// GracePeriod overrides each pod's terminationGracePeriodSeconds. 0
+	// keeps the pod's own.
+	GracePeriod time.Duration
+	// Timeout bounds how long evictions blocked by a PodDisruptionBudget are
+	// retried. Pods still running after it are left in place and logged.
+	// Default 10m.
+	Timeout time.Duration
+	// RetryInterval is the wait between attempts on blocked pods. Default 10s.
+	RetryInterval time.Duration
+}
+
+// WithPodEviction drains a node once it is hard-quarantined (a NoSchedule or
+// NoExecute taint; a soft PreferNoSchedule quarantine is left alone). The
+// taint keeps new GPU pods off a fail-slow node, but pods already on it keep
+// dragging their jobs. Every pod requesting nvidia.com/gpu is evicted
+// through the Eviction API, so PodDisruptionBudgets are honoured: a pod
+// whose budget is exhausted is retried until cfg.Timeout. DaemonSet pods,
+// mirror pods, runner pods and pods that tolerate the quarantine taint are
+// skipped. The drain runs in the background and never affects the verdict.
+func WithPodEviction(cfg EvictionConfig) Option {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Minute
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 10 * time.Second
+	}
+	return func(c *Controller) { c.eviction = &cfg }
+}
+
+// startEviction drains nodeName in the background if WithPodEviction is set
+// and no drain is already running for it.
+func (c *Controller) startEviction(ctx context.Context, node *corev1.Node) {
+	if c.eviction == nil {
+		return
+	}
+	if _, running := c.draining.LoadOrStore(node.Name, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer c.draining.Delete(node.Name)
+		c.evictGPUPods(ctx, node)
+	}()
+}
+
+// evictGPUPods evicts node's GPU pods, retrying PDB-blocked ones every
+// RetryInterval until none remain or Timeout passes. It returns the number
+// evicted.
+func (c *Controller) evictGPUPods(ctx context.Context, node *corev1.Node) int {
+	cfg := c.eviction
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	evicted := 0
+	for {
+		pods, err := c.evictablePods(ctx, node)
+		if err != nil {
+			c.logger.Warn("cannot list GPU pods to evict", "node", node.Name, "err", err)
+			return evicted
+		}
+		var blocked []string
+		for _, pod := range pods {
+			switch err := c.evict(ctx, pod); {
+			case err == nil:
+				evicted++
+				metrics.PodEvictions.WithLabelValues("evicted").Inc()
+				c.logger.Info("evicted GPU pod from quarantined node", "node", node.Name, "pod", pod.Namespace+"/"+pod.Name)
+			case apierrors.IsNotFound(err):
+				// already gone
+			case apierrors.IsTooManyRequests(err):
+				// The pod's PodDisruptionBudget allows no disruption now.
+				blocked = append(blocked, pod.Namespace+"/"+pod.Name)
+			default:
+				metrics.PodEvictions.WithLabelValues("failed").Inc()
+				c.logger.Warn("failed to evict GPU pod", "node", node.Name, "pod", pod.Namespace+"/"+pod.Name, "err", err)
+			}
+		}
+		if len(blocked) == 0 {
+			break
+		}
+		if !sleepCtx(ctx, cfg.RetryInterval) {
+			metrics.PodEvictions.WithLabelValues("blocked").Add(float64(len(blocked)))
+			c.logger.Warn("gave up evicting GPU pods blocked by PodDisruptionBudgets", "node", node.Name,
+				"pods", blocked, "timeout", cfg.Timeout)
+			break
+		}
+	}
+	if evicted > 0 {
+		c.event(node, corev1.EventTypeNormal, eventEvicted,
+			fmt.Sprintf("evicted %d GPU pod(s) from quarantined node", evicted))
+	}
+	return evicted
+}
+
+// evictablePods lists the pods on node that WithPodEviction drains.
+func (c *Controller) evictablePods(ctx context.Context, node *corev1.Node) ([]*corev1.Pod, error) {
+	list, err := c.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + node.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list pods on %s: %w", node.Name, err)
+	}
+	var pods []*corev1.Pod
+	for i := range list.Items {
+		pod := &list.Items[i]
+		switch {
+		case pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed:
+		case pod.DeletionTimestamp != nil:
+		case !requestsGPU(pod):
+		case pod.Labels[runnerPodLabel] != "":
+		case pod.Annotations[corev1.MirrorPodAnnotationKey] != "":
+		case ownedByDaemonSet(pod):
+		case c.toleratesQuarantine(node, pod):
+		default:
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func (c *Controller) evict(ctx context.Context, pod *corev1.Pod) error {
+	eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+	if g := c.eviction.GracePeriod; g > 0 {
+		seconds := int64(g.Seconds())
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &seconds}
+	}
+	return c.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+}
+
+func ownedByDaemonSet(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// toleratesQuarantine reports whether pod tolerates node's quarantine taint:
+// it was placed there, or is allowed to stay, on purpose. The taint's value
+// and effect are matched too, so a pod tolerating one failure class (see
+// policy Taints) stays only on nodes quarantined for it, and a NoSchedule
+// toleration does not cover a NoExecute class taint. Nothing tolerates a
+// cordon.
+func (c *Controller) toleratesQuarantine(node *corev1.Node, pod *corev1.Pod) bool {
+	if c.mode == QuarantineCordon {
+		return false
+	}
+	taint := corev1.Taint{Key: c.taint.Key, Effect: c.taint.Effect}
+	if t := findTaintByKey(node.Spec.Taints, c.taint.Key); t != nil {
+		taint = *t
+	}
+	for i := range pod.Spec.Tolerations {
+		if pod.Spec.Tolerations[i].ToleratesTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
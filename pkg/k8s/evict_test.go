@@ -0,0 +1,152 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func gpuPod(name, nodeName string, gpus int64) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "training"},
+		Spec: corev1.PodSpec{
+			NodeName:   nodeName,
+			Containers: []corev1.Container{{Name: "main"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if gpus > 0 {
+		pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{gpuResource: *resource.NewQuantity(gpus, resource.DecimalSI)}
+	}
+	return pod
+}
+
+func TestEvictGPUPods(t *testing.T) {
+	t.Parallel()
+
+	daemon := gpuPod("dcgm-exporter", "gpu-node-0", 1)
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "dcgm-exporter"}}
+	tolerant := gpuPod("burn-in", "gpu-node-0", 8)
+	tolerant.Spec.Tolerations = []corev1.Toleration{{Key: zombieTaintKey, Operator: corev1.TolerationOpExists}}
+	done := gpuPod("finished", "gpu-node-0", 8)
+	done.Status.Phase = corev1.PodSucceeded
+	client := fake.NewSimpleClientset(
+		gpuPod("trainer-0", "gpu-node-0", 8),
+		gpuPod("trainer-1", "gpu-node-0", 8),
+		gpuPod("sidecar", "gpu-node-0", 0),
+		gpuPod("trainer-2", "gpu-node-1", 8),
+		daemon, tolerant, done,
+	)
+
+	// trainer-1's PodDisruptionBudget refuses the first attempt.
+	var mu sync.Mutex
+	var evicted []string
+	refused := false
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		ev := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		mu.Lock()
+		defer mu.Unlock()
+		if ev.Name == "trainer-1" && !refused {
+			refused = true
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		if g := ev.DeleteOptions; g == nil || *g.GracePeriodSeconds != 30 {
+			t.Errorf("eviction of %s delete options = %+v, want a 30s grace period", ev.Name, g)
+		}
+		evicted = append(evicted, ev.Name)
+		return true, nil, client.Tracker().Delete(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, ev.Namespace, ev.Name)
+	})
+	// The fake does not apply field selectors.
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj, err := client.Tracker().List(schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+			schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, "")
+		if err != nil {
+			return true, nil, err
+		}
+		list := obj.(*corev1.PodList)
+		var onNode []corev1.Pod
+		for _, p := range list.Items {
+			if p.Spec.NodeName == "gpu-node-0" {
+				onNode = append(onNode, p)
+			}
+		}
+		list.Items = onNode
+		return true, list, nil
+	})
+
+	ctrl := NewController(client, WithPodEviction(EvictionConfig{GracePeriod: 30 * time.Second, RetryInterval: time.Millisecond}))
+	n := ctrl.evictGPUPods(context.Background(), freshNode("gpu-node-0", time.Minute))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n != 2 || len(evicted) != 2 || evicted[0] != "trainer-0" || evicted[1] != "trainer-1" {
+		t.Errorf("evicted %d: %v, want trainer-0 then trainer-1 after its budget allowed it", n, evicted)
+	}
+}
+
+func TestQuarantineStartsEviction(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		soft      bool
+		wantDrain bool
+	}{
+		{name: "hard quarantine drains GPU pods", wantDrain: true},
+		{name: "soft quarantine leaves them running", soft: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute), gpuPod("trainer-0", "gpu-node-0", 8))
+			drained := make(chan string, 1)
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				drained <- action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction).Name
+				return true, nil, nil
+			})
+			ctrl := NewController(client,
+				WithPolicy(&policy.Policy{SoftQuarantine: tc.soft}),
+				WithNodePulse(func(context.Context, string) (time.Duration, error) {
+					return 812 * time.Millisecond, pulse.ErrStragglerDetected
+				}),
+				WithPodEviction(EvictionConfig{RetryInterval: time.Millisecond}),
+			)
+			if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+				t.Fatalf("ValidateNode: %v", err)
+			}
+
+			select {
+			case name := <-drained:
+				if !tc.wantDrain {
+					t.Errorf("evicted %s after a soft quarantine", name)
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tc.wantDrain {
+					t.Error("no eviction after a hard quarantine")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// Marker kinds in a MarkerMigration.
+const (
+	MarkerTaint      = "taint"
+	MarkerLabel      = "label"
+	MarkerAnnotation = "annotation"
+	MarkerCondition  = "condition"
+)
+
+// MarkerMigration is one row of the compatibility table applied by
+// CollectStaleMarkers: a node marker written by an earlier straggler-shield
+// version or configuration, and the marker that replaces it.
+type MarkerMigration struct {
+	Kind string // MarkerTaint, MarkerLabel, MarkerAnnotation or MarkerCondition
+	Old  string // retired taint key, label or annotation key, or condition type
+	New  string // replacement of the same kind; "" removes Old
+
+	// Note says why Old was retired. It is logged with each migration.
+	Note string
+}
+
+// WithMarkerMigrations adds rows to the compatibility table, e.g. taint keys
+// used by a previous QUARANTINE_TAINT_KEY setting.
+func WithMarkerMigrations(rows ...MarkerMigration) Option {
+	return func(c *Controller) { c.migrations = append(c.migrations, rows...) }
+}
+
+// compatibilityTable returns the migrations CollectStaleMarkers applies: rows
+// added with WithMarkerMigrations, plus a move of the built-in taint key to
+// the configured one when WithTaint changed it. Rows retiring the current
+// taint key are dropped so a stale setting cannot lift live quarantines.
+func (c *Controller) compatibilityTable() []MarkerMigration {
+	rows := make([]MarkerMigration, 0, len(c.migrations)+1)
+	if c.taint.Key != DefaultTaintKey {
+		rows = append(rows, MarkerMigration{
+			Kind: MarkerTaint, Old: DefaultTaintKey, New: c.taint.Key,
+			Note: "quarantine taint key changed from the default",
+		})
+	}
+	for _, m := range c.migrations {
+		if m.Kind == MarkerTaint && m.Old == c.taint.Key {
+			continue
+		}
+		rows = append(rows, m)
+	}
+	return rows
+}
+
+// CollectStaleMarkers migrates or removes markers left on nodeName by earlier
+// straggler-shield versions and configurations, according to the
+// compatibility table, and drops markers whose state no longer exists (a
+// consecutive-passes count on a node that is not quarantined, a failure
+// streak on one that is or with WithRequiredFailures off). Run it once
+// at startup, before the watch loop.
+//
+// A retired quarantine taint is only moved when the GPUStraggler condition
+// shows straggler-shield wrote it; a replacement already present on the node
+// wins over the migrated value. A taint migration is written only if the
+// node is unchanged since it was read; on a conflict the node is read and
+// the table applied again.
+func (c *Controller) CollectStaleMarkers(ctx context.Context, nodeName string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return c.collectStaleMarkers(ctx, nodeName)
+	})
+}
+
+func (c *Controller) collectStaleMarkers(ctx context.Context, nodeName string) error {
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get node %s: %w", nodeName, err)
+	}
+
+	labels := map[string]*string{}
+	annotations := map[string]*string{}
+	taints := append([]corev1.Taint(nil), node.Spec.Taints...)
+	conditions := append([]corev1.NodeCondition(nil), node.Status.Conditions...)
+	var specChanged, statusChanged bool
+
+	migrated := func(m MarkerMigration) {
+		c.logger.Info("migrating stale straggler-shield marker",
+			"node", nodeName, "kind", m.Kind, "old", m.Old, "new", m.New, "note", m.Note)
+	}
+
+	// Conditions first, so a taint row sees a GPUStraggler condition that a
+	// condition row has just migrated.
+	table := c.compatibilityTable()
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].Kind == MarkerCondition && table[j].Kind != MarkerCondition
+	})
+	for _, m := range table {
+		switch m.Kind {
+		case MarkerTaint:
+			old := findTaintByKey(taints, m.Old)
+			if old == nil || !hasStragglerTrue(conditions) {
+				continue
+			}
+			moved := *old
+			taints = removeTaintByKey(taints, m.Old)
+			if m.New != "" && findTaintByKey(taints, m.New) == nil {
+				moved.Key = m.New
+				taints = append(taints, moved)
+			}
+			specChanged = true
+		case MarkerLabel:
+			if !migrateMapKey(node.Labels, labels, m) {
+				continue
+			}
+		case MarkerAnnotation:
+			if !migrateMapKey(node.Annotations, annotations, m) {
+				continue
+			}
+		case MarkerCondition:
+			var moved *corev1.NodeCondition
+			kept := conditions[:0]
+			for _, cond := range conditions {
+				if cond.Type == corev1.NodeConditionType(m.Old) {
+					cond := cond
+					moved = &cond
+					continue
+				}
+				kept = append(kept, cond)
+			}
+			conditions = kept
+			if moved == nil {
+				continue
+			}
+			if m.New != "" && !hasCondition(conditions, corev1.NodeConditionType(m.New)) {
+				moved.Type = corev1.NodeConditionType(m.New)
+				conditions = append(conditions, *moved)
+			}
+			statusChanged = true
+		default:
+			c.logger.Warn("unknown marker kind in compatibility table — skipped", "kind", m.Kind, "old", m.Old)
+			continue
+		}
+		migrated(m)
+	}
+
+	if _, ok := node.Annotations[ConsecutivePassesAnnotation]; ok && !c.markedIn(node, taints) {
+		annotations[ConsecutivePassesAnnotation] = nil
+		migrated(MarkerMigration{Kind: MarkerAnnotation, Old: ConsecutivePassesAnnotation, Note: "node is not quarantined"})
+	}
+	if _, ok := node.Annotations[ConsecutiveFailuresAnnotation]; ok && (c.requiredFailures <= 1 || c.markedIn(node, taints)) {
+		annotations[ConsecutiveFailuresAnnotation] = nil
+		migrated(MarkerMigration{Kind: MarkerAnnotation, Old: ConsecutiveFailuresAnnotation, Note: "node is quarantined or failure streaks are off"})
+	}
+
+	if len(labels)+len(annotations) > 0 || specChanged {
+		patch := map[string]any{}
+		meta := map[string]any{}
+		if len(labels) > 0 {
+			meta["labels"] = labels
+		}
+		if len(annotations) > 0 {
+			meta["annotations"] = annotations
+		}
+		if specChanged {
+			// The list replaces the node's: see patchNodeSpec.
+			patch["spec"] = map[string]any{"taints": taints}
+			if node.ResourceVersion != "" {
+				meta["resourceVersion"] = node.ResourceVersion
+			}
+		}
+		if len(meta) > 0 {
+			patch["metadata"] = meta
+		}
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return fmt.Errorf("marshal marker migration patch: %w", err)
+		}
+		if _, err := c.client.CoreV1().Nodes().Patch(
+			ctx, nodeName, types.MergePatchType, data, metav1.PatchOptions{},
+		); err != nil {
+			return fmt.Errorf("patch node %s (migrate markers): %w", nodeName, err)
+		}
+	}
+	if statusChanged {
+		data, err := json.Marshal(map[string]any{
+			"status": map[string]any{"conditions": conditions},
+		})
+		if err != nil {
+			return fmt.Errorf("marshal condition migration patch: %w", err)
+		}
+		if _, err := c.client.CoreV1().Nodes().Patch(
+			ctx, nodeName, types.MergePatchType, data, metav1.PatchOptions{}, "status",
+		); err != nil {
+			return fmt.Errorf("patch node %s status (migrate conditions): %w", nodeName, err)
+		}
+	}
+	return nil
+}
+
+// migrateMapKey records in patch the move of label or annotation m.Old in
+// current to m.New, keeping an existing m.New. It reports whether Old was
+// present.
+func migrateMapKey(current map[string]string, patch map[string]*string, m MarkerMigration) bool {
+	v, ok := current[m.Old]
+	if !ok {
+		return false
+	}
+	patch[m.Old] = nil
+	if _, exists := current[m.New]; m.New != "" && !exists {
+		patch[m.New] = &v
+	}
+	return true
+}
+
+func removeTaintByKey(taints []corev1.Taint, key string) []corev1.Taint {
+	out := make([]corev1.Taint, 0, len(taints))
+	for _, t := range taints {
+		if t.Key != key {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func hasCondition(conditions []corev1.NodeCondition, t corev1.NodeConditionType) bool {
+	for _, cond := range conditions {
+		if cond.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// hasStragglerTrue is quarantineRecorded over a condition list.
+func hasStragglerTrue(conditions []corev1.NodeCondition) bool {
+	for _, cond := range conditions {
+		if cond.Type == zombieCondition {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
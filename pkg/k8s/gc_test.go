@@ -0,0 +1,134 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCollectStaleMarkers(t *testing.T) {
+	t.Parallel()
+
+	const key = "example.com/gpu-unhealthy"
+
+	cases := []struct {
+		name  string
+		node  func() *corev1.Node
+		taint TaintConfig
+		rows  []MarkerMigration
+		check func(t *testing.T, got *corev1.Node)
+	}{
+		{
+			name:  "default-key quarantine moves to the configured key",
+			node:  func() *corev1.Node { return quarantinedNode("gpu-node-0", time.Hour) },
+			taint: TaintConfig{Key: key, Effect: corev1.TaintEffectNoSchedule},
+			check: func(t *testing.T, got *corev1.Node) {
+				if findTaint(got, zombieTaintKey) != nil {
+					t.Error("default-key taint still present")
+				}
+				if tt := findTaint(got, key); tt == nil || tt.Value != "820ms" || tt.Effect != corev1.TaintEffectNoSchedule {
+					t.Errorf("%s taint = %v, want the migrated 820ms/NoSchedule", key, tt)
+				}
+			},
+		},
+		{
+			name: "taint straggler-shield did not record is left alone",
+			node: func() *corev1.Node {
+				n := freshNode("gpu-node-1", time.Hour)
+				n.Spec.Taints = []corev1.Taint{{Key: zombieTaintKey, Effect: corev1.TaintEffectNoSchedule}}
+				return n
+			},
+			taint: TaintConfig{Key: key, Effect: corev1.TaintEffectNoSchedule},
+			check: func(t *testing.T, got *corev1.Node) {
+				if findTaint(got, zombieTaintKey) == nil || findTaint(got, key) != nil {
+					t.Errorf("taints = %v, want only the untouched %s", got.Spec.Taints, zombieTaintKey)
+				}
+			},
+		},
+		{
+			name: "previous key is removed when the current one is already set",
+			node: func() *corev1.Node {
+				n := quarantinedNode("gpu-node-2", time.Hour)
+				n.Spec.Taints = append(n.Spec.Taints, corev1.Taint{Key: "old.example.com/zombie", Effect: corev1.TaintEffectNoExecute})
+				return n
+			},
+			rows: []MarkerMigration{{Kind: MarkerTaint, Old: "old.example.com/zombie", New: zombieTaintKey}},
+			check: func(t *testing.T, got *corev1.Node) {
+				if findTaint(got, "old.example.com/zombie") != nil {
+					t.Error("previous-key taint still present")
+				}
+				if tt := findTaint(got, zombieTaintKey); tt == nil || tt.Effect != corev1.TaintEffectNoSchedule {
+					t.Errorf("current taint = %v, want the existing NoSchedule one", tt)
+				}
+			},
+		},
+		{
+			name: "retired condition type and label are renamed",
+			node: func() *corev1.Node {
+				n := freshNode("gpu-node-3", time.Hour)
+				n.Labels = map[string]string{"straggler-shield.io/suspect": "high_variance"}
+				n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{
+					Type: "ZombieGPU", Status: corev1.ConditionTrue, Reason: "StragglerDetected",
+				})
+				n.Spec.Taints = []corev1.Taint{{Key: zombieTaintKey, Effect: corev1.TaintEffectNoSchedule}}
+				return n
+			},
+			rows: []MarkerMigration{
+				{Kind: MarkerLabel, Old: "straggler-shield.io/suspect", New: DegradedLabel},
+				{Kind: MarkerCondition, Old: "ZombieGPU", New: string(zombieCondition)},
+			},
+			check: func(t *testing.T, got *corev1.Node) {
+				if _, ok := got.Labels["straggler-shield.io/suspect"]; ok || got.Labels[DegradedLabel] != "high_variance" {
+					t.Errorf("labels = %v, want only %s=high_variance", got.Labels, DegradedLabel)
+				}
+				if hasCondition(got.Status.Conditions, "ZombieGPU") || !quarantineRecorded(got) {
+					t.Errorf("conditions = %v, want ZombieGPU renamed to GPUStraggler=True", got.Status.Conditions)
+				}
+			},
+		},
+		{
+			name: "pass count on an unquarantined node is dropped",
+			node: func() *corev1.Node {
+				n := freshNode("gpu-node-4", time.Hour)
+				n.Annotations = map[string]string{ConsecutivePassesAnnotation: "2", LastPulseAnnotation: "2026-01-01T00:00:00Z"}
+				return n
+			},
+			check: func(t *testing.T, got *corev1.Node) {
+				if _, ok := got.Annotations[ConsecutivePassesAnnotation]; ok {
+					t.Error("stale consecutive-passes annotation still present")
+				}
+				if _, ok := got.Annotations[LastPulseAnnotation]; !ok {
+					t.Error("unrelated annotation removed")
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node := tc.node()
+			client := fake.NewSimpleClientset(node)
+			opts := []Option{WithMarkerMigrations(tc.rows...)}
+			if tc.taint.Key != "" {
+				opts = append(opts, WithTaint(tc.taint))
+			}
+			ctrl := NewController(client, opts...)
+			if err := ctrl.CollectStaleMarkers(context.Background(), node.Name); err != nil {
+				t.Fatalf("CollectStaleMarkers: %v", err)
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get node: %v", err)
+			}
+			tc.check(t, got)
+		})
+	}
+}
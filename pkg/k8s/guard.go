@@ -0,0 +1,180 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultGuardConfigMap names the ConfigMap holding the quarantine window
+// shared by every agent (see QuarantineGuard).
+const DefaultGuardConfigMap = "straggler-shield-quarantine-guard"
+
+// guardWindow is the period MaxPerHour counts quarantines over.
+const guardWindow = time.Hour
+
+// guardKey is the ConfigMap data key holding the window's quarantines.
+const guardKey = "quarantines"
+
+// Guard limits, the "limit" label of metrics.QuarantinesSuppressed.
+const (
+	guardPerHour = "per_hour"
+	guardPercent = "percent"
+)
+
+// eventSuppressed is the Warning Event on a node whose quarantine the guard
+// held back.
+const eventSuppressed = "QuarantineSuppressed"
+
+// QuarantineGuard is a cluster-wide circuit breaker on quarantines. A bad
+// driver rollout or threshold change fails every node it touches, and
+// tainting them all would take the cluster's capacity with it. Past either
+// limit a new quarantine is logged, counted and recorded as an Event
+// instead of applied. Nodes already quarantined are not affected.
+//
+// Every agent shares the window through a ConfigMap, updated with
+// optimistic concurrency, so the limits hold across a DaemonSet and across
+// central shards. If the ConfigMap or the node list cannot be read the
+// quarantine goes ahead: the guard must not stop a real failure from being
+// fenced because the API server is struggling.
+type QuarantineGuard struct {
+	// MaxPerHour is the most nodes quarantined within any hour. 0 is
+	// unlimited.
+	MaxPerHour int
+	// MaxPercent is the most GPU nodes, as a percentage of those with
+	// nvidia.com/gpu capacity, quarantined at once. 0 is unlimited.
+	MaxPercent float64
+	// Namespace and Name locate the shared ConfigMap. Name defaults to
+	// DefaultGuardConfigMap.
+	Namespace string
+	Name      string
+}
+
+// WithQuarantineGuard holds back quarantines beyond the limits in g.
+func WithQuarantineGuard(g QuarantineGuard) Option {
+	if g.Name == "" {
+		g.Name = DefaultGuardConfigMap
+	}
+	return func(c *Controller) { c.guard = &g }
+}
+
+// guardEntry is one quarantine in the shared window.
+type guardEntry struct {
+	Node string    `json:"node"`
+	At   time.Time `json:"at"`
+}
+
+// admitQuarantine reports whether node may be quarantined, recording it in
+// the shared window if so. A refusal is logged, counted and recorded as an
+// Event. Without a guard every quarantine is admitted.
+func (c *Controller) admitQuarantine(ctx context.Context, node *corev1.Node, reason string) bool {
+	if c.guard == nil {
+		return true
+	}
+	limit, detail, err := c.guard.admit(ctx, c, node.Name)
+	switch {
+	case err != nil:
+		c.logger.Warn("quarantine guard unavailable — quarantining anyway", "node_name", node.Name, "err", err)
+		return true
+	case limit == "":
+		return true
+	}
+	metrics.QuarantinesSuppressed.WithLabelValues(limit).Inc()
+	c.logger.Error("quarantine suppressed by the blast-radius guard — node left schedulable",
+		"node_name", node.Name, "failure_reason", reason, "limit", limit, "detail", detail)
+	c.event(node, corev1.EventTypeWarning, eventSuppressed,
+		fmt.Sprintf("%s quarantine not applied: %s", reason, detail))
+	return false
+}
+
+// admit checks the limits for nodeName and records it in the window. It
+// returns the limit that refused it with a description, or "" if admitted.
+func (g *QuarantineGuard) admit(ctx context.Context, c *Controller, nodeName string) (limit, detail string, err error) {
+	if g.MaxPercent > 0 {
+		nodes, err := c.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return "", "", fmt.Errorf("list nodes: %w", err)
+		}
+		var gpuNodes, quarantined int
+		for i := range nodes.Items {
+			n := &nodes.Items[i]
+			if q, ok := n.Status.Capacity[gpuResource]; !ok || q.IsZero() {
+				continue
+			}
+			gpuNodes++
+			if quarantineRecorded(n) && n.Name != nodeName {
+				quarantined++
+			}
+		}
+		if pct := float64(quarantined+1) * 100 / float64(max(gpuNodes, 1)); pct > g.MaxPercent {
+			return guardPercent, fmt.Sprintf("%d of %d GPU nodes would be quarantined (%.1f%%), over the %g%% limit",
+				quarantined+1, gpuNodes, pct, g.MaxPercent), nil
+		}
+	}
+	if g.MaxPerHour <= 0 {
+		return "", "", nil
+	}
+
+	cms := c.client.CoreV1().ConfigMaps(g.Namespace)
+	for attempt := 0; ; attempt++ {
+		cm, err := cms.Get(ctx, g.Name, metav1.GetOptions{})
+		found := err == nil
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: g.Name, Namespace: g.Namespace}}
+		} else if err != nil {
+			return "", "", fmt.Errorf("get configmap %s/%s: %w", g.Namespace, g.Name, err)
+		}
+
+		now := time.Now().UTC()
+		var window []guardEntry
+		if s := cm.Data[guardKey]; s != "" {
+			if err := json.Unmarshal([]byte(s), &window); err != nil {
+				c.logger.Warn("quarantine guard window unreadable — starting a new one", "configmap", g.Name, "err", err)
+			}
+		}
+		// A node quarantined again within the hour is not a new one: it
+		// only refreshes its entry.
+		kept, again := window[:0], false
+		for _, e := range window {
+			switch {
+			case now.Sub(e.At) >= guardWindow:
+			case e.Node == nodeName:
+				again = true
+			default:
+				kept = append(kept, e)
+			}
+		}
+		if !again && len(kept) >= g.MaxPerHour {
+			return guardPerHour, fmt.Sprintf("%d nodes quarantined in the last hour, at the limit of %d", len(kept), g.MaxPerHour), nil
+		}
+		data, err := json.Marshal(append(kept, guardEntry{Node: nodeName, At: now}))
+		if err != nil {
+			return "", "", fmt.Errorf("marshal guard window: %w", err)
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[guardKey] = string(data)
+
+		if found {
+			_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+		} else {
+			_, err = cms.Create(ctx, cm, metav1.CreateOptions{})
+		}
+		switch {
+		case err == nil:
+			return "", "", nil
+		case (apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)) && attempt < 5:
+			continue // another agent updated the window first
+		default:
+			return "", "", fmt.Errorf("update configmap %s/%s: %w", g.Namespace, g.Name, err)
+		}
+	}
+}
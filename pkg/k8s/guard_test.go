@@ -0,0 +1,112 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// gpuNodes returns n fresh nodes named prefix-<i> with 8 GPUs each.
+func gpuNodes(prefix string, n int) []runtime.Object {
+	nodes := make([]runtime.Object, n)
+	for i := range nodes {
+		node := freshNode(fmt.Sprintf("%s-%d", prefix, i), time.Minute)
+		node.Status.Capacity = corev1.ResourceList{gpuResource: resource.MustParse("8")}
+		nodes[i] = node
+	}
+	return nodes
+}
+
+func TestQuarantineGuard(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		guard QuarantineGuard
+		limit string
+	}{
+		{"per hour", QuarantineGuard{MaxPerHour: 2, Namespace: "straggler-shield"}, guardPerHour},
+		// 3 of 5 GPU nodes is 60%.
+		{"percent", QuarantineGuard{MaxPercent: 50}, guardPercent},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			prefix := "gpu-guard-" + tc.limit
+			client := fake.NewSimpleClientset(gpuNodes(prefix, 5)...)
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulse.ErrHighVariance }),
+				WithQuarantineGuard(tc.guard),
+			)
+			suppressed := testutil.ToFloat64(metrics.QuarantinesSuppressed.WithLabelValues(tc.limit))
+
+			// The first node fails twice: a repeat is not a new quarantine.
+			for _, i := range []int{0, 0, 1, 2} {
+				if err := ctrl.ValidateNode(context.Background(), fmt.Sprintf("%s-%d", prefix, i)); err != nil {
+					t.Fatalf("ValidateNode(%d): %v", i, err)
+				}
+			}
+			for i, want := range []bool{true, true, false} {
+				node, err := client.CoreV1().Nodes().Get(context.Background(), fmt.Sprintf("%s-%d", prefix, i), metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := findTaint(node, zombieTaintKey) != nil; got != want {
+					t.Errorf("node %d tainted = %v, want %v", i, got, want)
+				}
+			}
+			if got := testutil.ToFloat64(metrics.QuarantinesSuppressed.WithLabelValues(tc.limit)) - suppressed; got != 1 {
+				t.Errorf("suppressed %s = %v, want 1", tc.limit, got)
+			}
+		})
+	}
+}
+
+func TestQuarantineGuardWindow(t *testing.T) {
+	t.Parallel()
+
+	old, _ := json.Marshal([]guardEntry{
+		{Node: "gpu-window-9", At: time.Now().Add(-2 * time.Hour)},
+		{Node: "gpu-window-8", At: time.Now().Add(-10 * time.Minute)},
+	})
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultGuardConfigMap, Namespace: "ns", ResourceVersion: "1"},
+		Data:       map[string]string{guardKey: string(old)},
+	}
+	client := fake.NewSimpleClientset(cm)
+	ctrl := NewController(client, WithQuarantineGuard(QuarantineGuard{MaxPerHour: 2, Namespace: "ns"}))
+
+	if limit, _, err := ctrl.guard.admit(context.Background(), ctrl, "gpu-window-0"); err != nil || limit != "" {
+		t.Fatalf("admit = %q, %v; want admitted: the 2h-old entry has expired", limit, err)
+	}
+	if limit, detail, err := ctrl.guard.admit(context.Background(), ctrl, "gpu-window-1"); err != nil || limit != guardPerHour {
+		t.Fatalf("admit = %q (%s), %v; want refused per hour", limit, detail, err)
+	}
+	if limit, _, _ := ctrl.guard.admit(context.Background(), ctrl, "gpu-window-8"); limit != "" {
+		t.Errorf("a node already in the window was refused by %s", limit)
+	}
+
+	got, err := client.CoreV1().ConfigMaps("ns").Get(context.Background(), DefaultGuardConfigMap, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var window []guardEntry
+	if err := json.Unmarshal([]byte(got.Data[guardKey]), &window); err != nil {
+		t.Fatal(err)
+	}
+	if len(window) != 2 || window[0].Node != "gpu-window-0" || window[1].Node != "gpu-window-8" {
+		t.Errorf("window = %+v, want gpu-window-0 and a refreshed gpu-window-8", window)
+	}
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConsecutiveFailuresAnnotation counts straggler failures in a row on a node
+// that is not yet quarantined, when WithRequiredFailures asks for more than
+// one. Kept on the node so a restart of the agent does not reset the streak;
+// removed on any pass and once the taint is applied.
+const ConsecutiveFailuresAnnotation = "straggler-shield.io/consecutive-failures"
+
+// WithRequiredFailures quarantines a node only after n straggler failures in
+// a row, so a single noisy measurement cannot taint a healthy node. Failures
+// short of n are recorded in ConsecutiveFailuresAnnotation and leave the node
+// schedulable; any pass clears the streak. Hard failures (ECC errors, thermal,
+// crashes, stage timeouts) and failures on a node that is already tainted
+// still act at once. n <= 1 quarantines on the first failure (the default).
+func WithRequiredFailures(n int) Option {
+	return func(c *Controller) { c.requiredFailures = n }
+}
+
+// streakFailure reports whether err is a straggler failure a streak can hold
+// back. ECC errors and incomplete thermal recovery match IsStragglerErr too,
+// but they are hardware faults, not noise, and quarantine at once.
+func streakFailure(err error) bool {
+	return pulse.IsStragglerErr(err) &&
+		!errors.Is(err, pulse.ErrECCErrors) &&
+		!errors.Is(err, pulse.ErrThermalRecovery)
+}
+
+// FailureStreakOpen reports whether node has straggler failures recorded
+// that have not yet reached the required count. The watch loop uses it to
+// hand such nodes back to ReconcileNode, which pulses them again to confirm
+// or dismiss the failure.
+func (c *Controller) FailureStreakOpen(node *corev1.Node) bool {
+	return c.requiredFailures > 1 &&
+		consecutiveFailures(node) > 0 &&
+		!c.quarantineMarked(node)
+}
+
+// holdQuarantine advances node's failure streak and reports whether the
+// quarantine must be held back because the streak is still short. A reused
+// (cached) result is not an independent observation: it neither advances nor
+// completes a streak. Once the streak is complete it is removed, as the taint
+// now carries the state.
+func (c *Controller) holdQuarantine(ctx context.Context, node *corev1.Node, pulseID string, cached bool) bool {
+	if c.requiredFailures <= 1 || c.quarantineMarked(node) {
+		c.setConsecutiveFailures(ctx, node, 0)
+		return false
+	}
+	if cached {
+		return true
+	}
+	failures := consecutiveFailures(node) + 1
+	if failures < c.requiredFailures {
+		c.logger.Warn("GPU pulse failed — holding quarantine until enough consecutive failures",
+			"node", node.Name, "pulse_id", pulseID, "failures", failures, "required", c.requiredFailures)
+		c.setConsecutiveFailures(ctx, node, failures)
+		return true
+	}
+	c.setConsecutiveFailures(ctx, node, 0)
+	return false
+}
+
+// consecutiveFailures reads ConsecutiveFailuresAnnotation; missing or
+// malformed values count as zero.
+func consecutiveFailures(node *corev1.Node) int {
+	n, err := strconv.Atoi(node.Annotations[ConsecutiveFailuresAnnotation])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// setConsecutiveFailures writes n to ConsecutiveFailuresAnnotation, removing
+// it for zero. Best effort: a lost write only shortens or repeats a streak.
+func (c *Controller) setConsecutiveFailures(ctx context.Context, node *corev1.Node, n int) {
+	c.setCounter(ctx, node, ConsecutiveFailuresAnnotation, n)
+}
@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRequiredFailures(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		required   int
+		pulses     []error // outcome of each successive pulse
+		wantTaint  bool
+		wantStreak int // ConsecutiveFailuresAnnotation afterwards
+	}{
+		{
+			name:       "failures short of the streak leave the node schedulable",
+			required:   3,
+			pulses:     []error{pulse.ErrStragglerDetected, pulse.ErrStragglerDetected},
+			wantStreak: 2,
+		},
+		{
+			name:      "a full streak quarantines and is cleared",
+			required:  3,
+			pulses:    []error{pulse.ErrStragglerDetected, pulse.ErrStragglerDetected, pulse.ErrStragglerDetected},
+			wantTaint: true,
+		},
+		{
+			name:       "a pass restarts the streak",
+			required:   3,
+			pulses:     []error{pulse.ErrStragglerDetected, pulse.ErrStragglerDetected, nil, pulse.ErrStragglerDetected},
+			wantStreak: 1,
+		},
+		{
+			name:      "a hard failure quarantines at once",
+			required:  3,
+			pulses:    []error{pulse.ErrPulseCrashed},
+			wantTaint: true,
+		},
+		{
+			name:      "an ECC fault quarantines at once",
+			required:  3,
+			pulses:    []error{fmt.Errorf("pre-flight GPU 0: %w: 2 since last boot", pulse.ErrECCErrors)},
+			wantTaint: true,
+		},
+		{
+			name:       "a derated clock counts towards the streak",
+			required:   3,
+			pulses:     []error{fmt.Errorf("%w: %w", pulse.ErrStragglerDetected, pulse.ErrClockDerated)},
+			wantStreak: 1,
+		},
+		{
+			name:      "default quarantines on the first failure",
+			pulses:    []error{pulse.ErrStragglerDetected},
+			wantTaint: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node := freshNode("gpu-node-0", time.Minute)
+			client := fake.NewSimpleClientset(node)
+			next := 0
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) {
+					err := tc.pulses[next]
+					next++
+					return 600 * time.Millisecond, err
+				}),
+				WithRequiredFailures(tc.required),
+			)
+
+			for range tc.pulses {
+				if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+					t.Fatalf("ValidateNode: %v", err)
+				}
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get node: %v", err)
+			}
+			if hasTaint := findTaint(got, zombieTaintKey) != nil; hasTaint != tc.wantTaint {
+				t.Errorf("hasTaint=%v, want %v", hasTaint, tc.wantTaint)
+			}
+			if streak := consecutiveFailures(got); streak != tc.wantStreak {
+				t.Errorf("failure streak = %d, want %d", streak, tc.wantStreak)
+			}
+			if open := ctrl.FailureStreakOpen(got); open != (tc.wantStreak > 0) {
+				t.Errorf("FailureStreakOpen = %v, want %v", open, tc.wantStreak > 0)
+			}
+		})
+	}
+}
+
+func TestReconcileNodeConfirmsOpenStreak(t *testing.T) {
+	t.Parallel()
+
+	// A steady-state node the default trigger would skip.
+	node := freshNode("gpu-node-0", 2*time.Hour)
+	node.Annotations = map[string]string{ConsecutiveFailuresAnnotation: "1"}
+	client := fake.NewSimpleClientset(node)
+	ctrl := NewController(client,
+		WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulse.ErrStragglerDetected }),
+		WithRequiredFailures(2),
+	)
+
+	if err := ctrl.ReconcileNode(context.Background(), node.Name); err != nil {
+		t.Fatalf("ReconcileNode: %v", err)
+	}
+	got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get node: %v", err)
+	}
+	if findTaint(got, zombieTaintKey) == nil {
+		t.Error("confirming failure did not quarantine the node")
+	}
+	if _, ok := got.Annotations[ConsecutiveFailuresAnnotation]; ok {
+		t.Error("failure streak annotation left on a quarantined node")
+	}
+}
@@ -0,0 +1,47 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrRetryInconclusive is returned by ReconcileNode and ValidateNode when the
+// pulse reached no verdict (pulse.IsInconclusive) and the policy handles
+// that with policy.HandlingRetry. The node is left as it is; the caller
+// reconciles again later, and the retry runs even once the trigger window
+// has passed.
+var ErrRetryInconclusive = errors.New("pulse inconclusive — retrying later")
+
+// RetryPending reports whether nodeName has an inconclusive pulse waiting to
+// be retried.
+func (c *Controller) RetryPending(nodeName string) bool {
+	_, ok := c.retries.Load(nodeName)
+	return ok
+}
+
+// retryInconclusive handles an inconclusive pulse under
+// policy.HandlingRetry. It returns ErrRetryInconclusive until the node has
+// had policy.MaxInconclusiveRetries inconclusive pulses in a row, then nil
+// so the failure is acted on by its severity.
+func (c *Controller) retryInconclusive(node *corev1.Node, pulseID, reason string, err error) error {
+	n := 1
+	if v, ok := c.retries.Load(node.Name); ok {
+		n = v.(int) + 1
+	}
+	if n > policy.MaxInconclusiveRetries {
+		c.retries.Delete(node.Name)
+		c.logger.Warn("GPU pulse still inconclusive — acting on its severity", "node_name", node.Name,
+			"pulse_id", pulseID, "failure_reason", reason, "retries", policy.MaxInconclusiveRetries)
+		return nil
+	}
+	c.retries.Store(node.Name, n)
+	metrics.CheckFailures.WithLabelValues(reason, string(policy.HandlingRetry)).Inc()
+	c.logger.Warn("GPU pulse inconclusive — retrying later", "node_name", node.Name,
+		"pulse_id", pulseID, "failure_reason", reason, "attempt", n, "err", err)
+	return fmt.Errorf("%w on %s: %v", ErrRetryInconclusive, node.Name, err)
+}
@@ -0,0 +1,66 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRetryInconclusive(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute))
+	calls := 0
+	// The trigger fires once; every retry after it must bypass the trigger.
+	triggered := false
+	ctrl := NewController(clientset,
+		WithPulseFunc(func() (time.Duration, error) {
+			calls++
+			return 0, fmt.Errorf("%w: pre-flight GPU stats unavailable", pulse.ErrInconclusive)
+		}),
+		WithTrigger(TriggerFunc(func(*corev1.Node, TriggerHistory) bool {
+			fire := !triggered
+			triggered = true
+			return fire
+		})),
+		WithPolicy(&policy.Policy{Inconclusive: policy.HandlingRetry}),
+	)
+
+	for i := 0; i < policy.MaxInconclusiveRetries; i++ {
+		if err := ctrl.ReconcileNode(ctx, "gpu-node-0"); !errors.Is(err, ErrRetryInconclusive) {
+			t.Fatalf("ReconcileNode %d = %v, want ErrRetryInconclusive", i, err)
+		}
+	}
+	node, err := clientset.CoreV1().Nodes().Get(ctx, "gpu-node-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := StragglerCondition(node); c != nil {
+		t.Fatalf("condition %+v while retrying, want the node left as it was", c)
+	}
+
+	// Out of retries: the default severity for inconclusive (warn) applies.
+	if err := ctrl.ReconcileNode(ctx, "gpu-node-0"); err != nil {
+		t.Fatalf("ReconcileNode past the retries: %v", err)
+	}
+	if calls != policy.MaxInconclusiveRetries+1 || ctrl.RetryPending("gpu-node-0") {
+		t.Errorf("%d pulse(s), retry pending %v; want %d and none", calls, ctrl.RetryPending("gpu-node-0"), policy.MaxInconclusiveRetries+1)
+	}
+	node, err = clientset.CoreV1().Nodes().Get(ctx, "gpu-node-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := StragglerCondition(node); c == nil || c.Reason != "WarnOnly" {
+		t.Errorf("condition %+v, want WarnOnly", c)
+	}
+}
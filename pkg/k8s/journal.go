@@ -0,0 +1,322 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Journal operations. opQuarantine, opScope and opClear are decisions; opDone
+// commits the decision with the same Seq once its patches have landed.
+// opScope is a quarantine scoped to the workload classes the policy maps its
+// reason to (see applyWorkloadScope).
+const (
+	opQuarantine = "quarantine"
+	opScope      = "scope"
+	opClear      = "clear"
+	opDone       = "done"
+)
+
+// decision is one journal record. Each line of the journal file is one JSON
+// decision.
+type decision struct {
+	Seq       int64     `json:"seq"`
+	Node      string    `json:"node"`
+	Op        string    `json:"op"`
+	Reason    string    `json:"reason,omitempty"`      // failure_reason of a quarantine
+	Value     string    `json:"taint_value,omitempty"` // its default taint value (see TaintValue)
+	ElapsedMS int64     `json:"elapsed_ms,omitempty"`
+	Evidence  string    `json:"evidence,omitempty"`
+	PulseID   string    `json:"pulse_id,omitempty"` // the pulse behind it
+	Started   time.Time `json:"pulse_started"`      // its start
+	At        time.Time `json:"at"`
+
+	attempts int       // replay attempts since this process loaded it
+	next     time.Time // zero after a restart: replayed immediately
+}
+
+// decisionJournal is a write-ahead log of quarantine and clear decisions.
+// Each decision is appended (and synced) before its patches are sent and
+// committed after they land, so a decision whose patches never landed (API
+// outage, agent killed mid-patch) is still pending when the journal is next
+// read. Only the latest decision per node is pending: a clear supersedes an
+// unapplied quarantine and vice versa.
+type decisionJournal struct {
+	mu      sync.Mutex
+	path    string // "" = in-memory only
+	base    time.Duration
+	max     time.Duration
+	now     func() time.Time
+	seq     int64
+	pending map[string]*decision
+}
+
+// WithDecisionJournal records every quarantine and clear decision in a
+// write-ahead journal and replays unapplied ones from RunJournalReplay, with
+// exponential backoff from base up to max, until the API server accepts them.
+// With a non-empty path the journal persists there (e.g. in the host-mounted
+// state directory) and pending decisions are replayed after a restart. A
+// journal that cannot be read is logged and started empty.
+func WithDecisionJournal(path string, base, max time.Duration) Option {
+	return func(c *Controller) {
+		j := &decisionJournal{path: path, base: base, max: max, now: time.Now, pending: make(map[string]*decision)}
+		if err := j.load(c.logger); err != nil {
+			c.logger.Warn("failed to load decision journal — starting empty", "path", path, "err", err)
+		}
+		c.journal = j
+	}
+}
+
+// begin appends a decision for nodeName and returns its sequence number for
+// commit. It supersedes any pending decision for the node.
+func (j *decisionJournal) begin(nodeName, op, reason, value string, elapsed time.Duration, report *pulse.PulseReport, evidence string) (int64, error) {
+	if j == nil {
+		return 0, nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	now := j.now()
+	d := &decision{Seq: j.seq, Node: nodeName, Op: op, Reason: reason, Value: value, ElapsedMS: elapsed.Milliseconds(), Evidence: evidence, PulseID: report.PulseID, Started: report.StartedAt, At: now.UTC()}
+	d.next = now.Add(j.base) // the caller is applying it now
+	j.pending[nodeName] = d
+	return d.Seq, j.append(d)
+}
+
+// commit marks decision seq for nodeName applied. A decision superseded in
+// the meantime stays pending.
+func (j *decisionJournal) commit(nodeName string, seq int64) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if d, ok := j.pending[nodeName]; ok && d.Seq == seq {
+		delete(j.pending, nodeName)
+	}
+	if len(j.pending) == 0 {
+		return j.rewrite() // nothing pending: truncate
+	}
+	return j.append(&decision{Seq: seq, Node: nodeName, Op: opDone, At: j.now().UTC()})
+}
+
+// isPending reports whether a decision for nodeName is waiting to be
+// replayed. Nil-safe.
+func (j *decisionJournal) isPending(nodeName string) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, ok := j.pending[nodeName]
+	return ok
+}
+
+// due returns the pending decisions whose next replay time has passed.
+func (j *decisionJournal) due() []decision {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	var out []decision
+	for _, d := range j.pending {
+		if !now.Before(d.next) {
+			out = append(out, *d)
+		}
+	}
+	return out
+}
+
+// failed schedules the next replay of decision seq with exponential backoff.
+func (j *decisionJournal) failed(nodeName string, seq int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	d, ok := j.pending[nodeName]
+	if !ok || d.Seq != seq {
+		return // committed or superseded meanwhile
+	}
+	d.attempts++
+	wait := j.base
+	for i := 0; i < d.attempts && wait < j.max; i++ {
+		wait *= 2
+	}
+	d.next = j.now().Add(min(wait, j.max))
+}
+
+// append writes one record and syncs it. Caller holds mu.
+func (j *decisionJournal) append(d *decision) error {
+	if j.path == "" {
+		return nil
+	}
+	line, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// rewrite atomically replaces the journal with only the pending decisions.
+// Caller holds mu.
+func (j *decisionJournal) rewrite() error {
+	if j.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, d := range j.pending {
+		if err := enc.Encode(d); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// load replays the journal file into pending and compacts it. A missing file
+// is an empty journal; a torn final line (crash mid-append) is ignored, and
+// any other line that does not parse is logged and skipped, so one corrupt
+// record does not cost the decisions around it.
+func (j *decisionJournal) load(logger *slog.Logger) error {
+	if j.path == "" {
+		return nil
+	}
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	var corrupt error // the last line's, logged once a later line shows it is not the tail
+	for line := 1; sc.Scan(); line++ {
+		if corrupt != nil {
+			logger.Warn("skipping corrupt decision journal line", "path", j.path, "line", line-1, "err", corrupt)
+			corrupt = nil
+		}
+		var d decision
+		if err := json.Unmarshal(sc.Bytes(), &d); err != nil {
+			corrupt = err
+			continue
+		}
+		j.seq = max(j.seq, d.Seq)
+		switch d.Op {
+		case opQuarantine, opScope, opClear:
+			j.pending[d.Node] = &d
+		case opDone:
+			if p, ok := j.pending[d.Node]; ok && p.Seq == d.Seq {
+				delete(j.pending, d.Node)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return j.rewrite()
+}
+
+// RunJournalReplay re-applies pending decisions until ctx is cancelled. It is
+// a no-op without WithDecisionJournal. Each node is fetched fresh so patches
+// are computed against its current state; a deleted node's decision is
+// dropped. A replayed decision that changes the node's GPUStraggler condition
+// goes to the transition sinks, as in decide.
+func (c *Controller) RunJournalReplay(ctx context.Context) {
+	if c.journal == nil {
+		return
+	}
+	for {
+		for _, d := range c.journal.due() {
+			c.replay(ctx, d)
+		}
+		if !sleepCtx(ctx, c.journal.base) {
+			return
+		}
+	}
+}
+
+func (c *Controller) replay(ctx context.Context, d decision) {
+	// The measurements behind the decision are not journaled; sinks get its
+	// pulse ID, reason and evidence.
+	report := &pulse.PulseReport{PulseID: d.PulseID, StartedAt: d.Started}
+	node, err := c.client.CoreV1().Nodes().Get(ctx, d.Node, metav1.GetOptions{})
+	if err == nil {
+		switch d.Op {
+		case opQuarantine:
+			recorded := quarantineRecorded(node)
+			if err = c.applyTaint(ctx, d.Node, node, d.Reason, d.Value, time.Duration(d.ElapsedMS)*time.Millisecond, d.Evidence); err == nil {
+				c.setQuarantined(d.Node, d.Reason)
+				if !recorded {
+					c.notify(ctx, node, TransitionQuarantined, d.Reason, report, nil, d.Evidence)
+				}
+			}
+		case opScope:
+			classes, _ := c.policy.ImpactedClasses(d.Reason)
+			if err = c.applyWorkloadScope(ctx, node, d.Reason, classes, time.Duration(d.ElapsedMS)*time.Millisecond, d.Evidence); err == nil {
+				c.setQuarantined(d.Node, d.Reason)
+			}
+		case opClear:
+			blocked, _ := c.clearBlocked(node, d.Started)
+			lifts := quarantineRecorded(node) && blocked == ""
+			if err = c.clearQuarantine(ctx, node, d.Started, d.Evidence); err == nil && lifts {
+				c.notify(ctx, node, TransitionCleared, "", report, nil, d.Evidence)
+			}
+		}
+	}
+	if apierrors.IsNotFound(err) || errors.Is(err, ErrNodeNotFound) {
+		c.logger.Warn("dropping journaled decision for deleted node", "node", d.Node, "op", d.Op)
+		c.logJournalErr(d.Node, c.journal.commit(d.Node, d.Seq))
+		return
+	}
+	if err != nil {
+		c.logger.Warn("journaled decision still failing", "node", d.Node, "op", d.Op, "attempts", d.attempts+1, "decided_at", d.At, "err", err)
+		c.journal.failed(d.Node, d.Seq)
+		return
+	}
+	c.logger.Info("journaled decision applied", "node", d.Node, "op", d.Op, "attempts", d.attempts+1, "decided_at", d.At)
+	c.logJournalErr(d.Node, c.journal.commit(d.Node, d.Seq))
+}
+
+// logJournalErr logs a failed journal write. The in-memory state is still
+// authoritative; only restart durability is affected.
+func (c *Controller) logJournalErr(nodeName string, err error) {
+	if err != nil {
+		c.logger.Warn("failed to write decision journal", "node", nodeName, "err", err)
+	}
+}
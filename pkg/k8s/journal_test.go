@@ -0,0 +1,201 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestJournaledQuarantineSurvivesRestart journals a quarantine while the API
+// server rejects patches, then replays it from a fresh controller reading the
+// same journal, as after an agent restart.
+func TestJournaledQuarantineSurvivesRestart(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "decisions.journal")
+	client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute))
+	down := true
+	client.PrependReactor("patch", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if down && strings.Contains(string(action.(k8stesting.PatchAction).GetPatch()), "taints") {
+			return true, nil, apierrors.NewServiceUnavailable("etcd leader election")
+		}
+		return false, nil, nil
+	})
+
+	ctrl := NewController(client,
+		WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulse.ErrStragglerDetected }),
+		WithDecisionJournal(path, time.Second, time.Minute),
+	)
+	if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err == nil {
+		t.Fatal("ValidateNode succeeded with the API server down")
+	}
+
+	down = false
+	restarted := NewController(client, WithDecisionJournal(path, time.Second, time.Minute))
+	due := restarted.journal.due()
+	if len(due) != 1 || due[0].Node != "gpu-node-0" || due[0].Op != opQuarantine {
+		t.Fatalf("replayable decisions after restart = %+v, want one quarantine of gpu-node-0", due)
+	}
+	restarted.replay(context.Background(), due[0])
+
+	got, err := client.CoreV1().Nodes().Get(context.Background(), "gpu-node-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get node: %v", err)
+	}
+	if taint := findTaint(got, zombieTaintKey); taint == nil || withoutPulseID(taint.Value) != "latency_threshold_exceeded-600-0" {
+		t.Errorf("zombie taint = %v, want value latency_threshold_exceeded-600-0-<pulse>", taint)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("journal not compacted after replay:\n%s", data)
+	}
+}
+
+// TestReplayNotifies replays a journaled quarantine and clear and checks
+// each reaches the transition sinks once, when it changes the node.
+func TestReplayNotifies(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute))
+	down := true
+	client.PrependReactor("patch", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if down {
+			return true, nil, apierrors.NewServiceUnavailable("etcd leader election")
+		}
+		return false, nil, nil
+	})
+	pulseErr := pulse.ErrStragglerDetected
+	var got []Transition
+	ctrl := NewController(client,
+		WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulseErr }),
+		WithDecisionJournal("", time.Second, time.Minute),
+		WithTransitionSink(func(_ context.Context, tr Transition) { got = append(got, tr) }),
+	)
+
+	replay := func(op string) {
+		t.Helper()
+		if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err == nil {
+			t.Fatalf("%s: ValidateNode succeeded with the API server down", op)
+		}
+		if len(got) != 0 {
+			t.Fatalf("%s: transitions %+v before the patches landed", op, got)
+		}
+		down = false
+		d := *ctrl.journal.pending["gpu-node-0"]
+		if d.Op != op || d.PulseID == "" {
+			t.Fatalf("pending decision = %+v, want a %s with its pulse ID", d, op)
+		}
+		ctrl.replay(context.Background(), d)
+		ctrl.replay(context.Background(), d) // landed already: not a transition
+		down = true
+	}
+
+	replay(opQuarantine)
+	if len(got) != 1 || got[0].Event != TransitionQuarantined || got[0].Reason != "latency_threshold_exceeded" || got[0].PulseID == "" || got[0].Message == "" {
+		t.Fatalf("transitions after the quarantine replay = %+v, want one quarantine", got)
+	}
+	quarantined := got[0]
+	got = nil
+
+	pulseErr = nil
+	replay(opClear)
+	if len(got) != 1 || got[0].Event != TransitionCleared || got[0].PulseID == quarantined.PulseID {
+		t.Errorf("transitions after the clear replay = %+v, want one clear", got)
+	}
+}
+
+func TestDecisionJournal(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		apiErr   error
+		thenPass bool
+		wantOp   string // pending op afterwards; "" = nothing pending
+	}{
+		{name: "transient failure stays pending", apiErr: apierrors.NewTimeoutError("slow", 1), wantOp: opQuarantine},
+		{name: "forbidden is not replayed", apiErr: apierrors.NewForbidden(nodeGR, "gpu-node-0", errors.New("rbac"))},
+		{name: "later pass supersedes the quarantine", apiErr: apierrors.NewTimeoutError("slow", 1), thenPass: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute))
+			client.PrependReactor("patch", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if strings.Contains(string(action.(k8stesting.PatchAction).GetPatch()), "taints") {
+					return true, nil, tc.apiErr
+				}
+				return false, nil, nil
+			})
+			pulseErr := pulse.ErrStragglerDetected
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) { return time.Millisecond, pulseErr }),
+				WithDecisionJournal("", time.Second, time.Minute),
+			)
+
+			_ = ctrl.ValidateNode(context.Background(), "gpu-node-0")
+			if tc.thenPass {
+				pulseErr = nil
+				if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+					t.Fatalf("passing ValidateNode: %v", err)
+				}
+			}
+
+			gotOp := ""
+			if d, ok := ctrl.journal.pending["gpu-node-0"]; ok {
+				gotOp = d.Op
+			}
+			if gotOp != tc.wantOp {
+				t.Errorf("pending op = %q, want %q", gotOp, tc.wantOp)
+			}
+		})
+	}
+}
+
+func TestDecisionJournalLoadSkipsCorruptLines(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "decisions.journal")
+	data := `{"seq":1,"node":"a","op":"quarantine","at":"2026-01-01T00:00:00Z"}
+{"seq":2,"node":"b","op":"quarantine","at":"2026-01-01T00:00:00Z"}
+{"seq":2,"node":"b","op":"do}{
+{"seq":3,"node":"c","op":"quarantine","at":"2026-01-01T00:00:00Z"}
+{"seq":1,"node":"a","op":"done","at":"2026-01-01T00:00:01Z"}
+{"seq":4,"node":"c","op":"cle`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var logBuf bytes.Buffer
+	j := &decisionJournal{path: path, now: time.Now, pending: make(map[string]*decision)}
+	if err := j.load(slog.New(slog.NewTextHandler(&logBuf, nil))); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	// The corrupt middle line is skipped, not the line after it; the torn
+	// tail is dropped quietly.
+	if len(j.pending) != 2 || j.pending["b"] == nil || j.pending["c"] == nil || j.pending["c"].Op != opQuarantine {
+		t.Errorf("pending = %+v, want b's and c's quarantines", j.pending)
+	}
+	if j.seq != 3 {
+		t.Errorf("seq = %d, want 3", j.seq)
+	}
+	if got := strings.Count(logBuf.String(), "skipping corrupt decision journal line"); got != 1 || !strings.Contains(logBuf.String(), "line=3") {
+		t.Errorf("log = %q, want one warning for line 3", logBuf.String())
+	}
+}
@@ -0,0 +1,217 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NodeIndex index names.
+const (
+	IndexPool  = "pool"  // value of NodeIndexConfig.PoolLabel; "" when unset
+	IndexState = "state" // QuarantineStateForKey
+)
+
+// DefaultPoolLabel groups nodes into pools when NodeIndexConfig.PoolLabel is
+// empty. Node pools are usually one instance type, so it works without any
+// provider-specific label.
+const DefaultPoolLabel = "node.kubernetes.io/instance-type"
+
+// NodeIndexConfig selects and indexes the nodes a NodeIndex caches.
+type NodeIndexConfig struct {
+	// LabelSelector and FieldSelector are applied server-side to both the
+	// list and the watch, so non-GPU nodes are never sent to the controller.
+	LabelSelector string
+	FieldSelector string
+	// PoolLabel is the node label indexed under IndexPool.
+	PoolLabel string
+	// TaintKey is the quarantine taint key indexed under IndexState.
+	TaintKey string
+	// PageSize is the number of nodes fetched per page when the initial list
+	// (or a relist) cannot be served from the API server's watch cache.
+	// 0 uses the client-go default of 500.
+	PageSize int64
+	// Owns reports whether this controller is responsible for a node, e.g.
+	// ShardRing.Owns for one shard of a sharded controller. Every replica
+	// still caches every selected node; only owned ones reach OnChange
+	// handlers and the query methods. nil owns every node.
+	Owns func(nodeName string) bool
+}
+
+// NodeIndex is a single list-and-watch over the selected nodes, kept in a
+// local cache indexed by pool and quarantine state. A central controller uses
+// it instead of one watch or GET per node: a 5,000-node cluster costs one
+// paginated list and one watch stream.
+type NodeIndex struct {
+	informer cache.SharedIndexInformer
+	cfg      NodeIndexConfig
+}
+
+// NewNodeIndex returns a NodeIndex over the nodes cfg selects. Call Run to
+// start it.
+func NewNodeIndex(client kubernetes.Interface, cfg NodeIndexConfig) *NodeIndex {
+	if cfg.PoolLabel == "" {
+		cfg.PoolLabel = DefaultPoolLabel
+	}
+	if cfg.TaintKey == "" {
+		cfg.TaintKey = DefaultTaintKey
+	}
+	scope := func(opts *metav1.ListOptions) {
+		opts.LabelSelector = cfg.LabelSelector
+		opts.FieldSelector = cfg.FieldSelector
+		// The reflector pages only when it sets a limit; replace its
+		// default page size with ours.
+		if opts.Limit > 0 && cfg.PageSize > 0 {
+			opts.Limit = cfg.PageSize
+		}
+	}
+	lw := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			scope(&opts)
+			return client.CoreV1().Nodes().List(context.Background(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			scope(&opts)
+			return client.CoreV1().Nodes().Watch(context.Background(), opts)
+		},
+	}
+	x := &NodeIndex{cfg: cfg}
+	x.informer = cache.NewSharedIndexInformer(lw, &corev1.Node{}, 0, cache.Indexers{
+		IndexPool: func(obj any) ([]string, error) {
+			return []string{obj.(*corev1.Node).Labels[cfg.PoolLabel]}, nil
+		},
+		IndexState: func(obj any) ([]string, error) {
+			return []string{QuarantineStateForKey(obj.(*corev1.Node), cfg.TaintKey)}, nil
+		},
+	})
+	// Image lists and managed fields are most of a GPU node's size and
+	// nothing here reads them.
+	_ = x.informer.SetTransform(func(obj any) (any, error) {
+		if node, ok := obj.(*corev1.Node); ok {
+			node.ManagedFields = nil
+			node.Status.Images = nil
+		}
+		return obj, nil
+	})
+	return x
+}
+
+// OnChange calls fn for every owned node added or updated, with the previously
+// cached copy as old (nil when the node is new to the index, including each
+// node of the initial list). Register handlers before Run.
+func (x *NodeIndex) OnChange(fn func(old, node *corev1.Node)) error {
+	_, err := x.informer.AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj any) bool {
+			node, ok := obj.(*corev1.Node)
+			return ok && x.owns(node.Name)
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj any) {
+				fn(nil, obj.(*corev1.Node))
+			},
+			UpdateFunc: func(oldObj, newObj any) {
+				old, _ := oldObj.(*corev1.Node)
+				fn(old, newObj.(*corev1.Node))
+			},
+		},
+	})
+	return err
+}
+
+// Run lists and watches nodes until ctx is cancelled, relisting and
+// re-watching as needed.
+func (x *NodeIndex) Run(ctx context.Context) {
+	x.informer.Run(ctx.Done())
+}
+
+// WaitForSync blocks until the initial list is in the cache. It returns
+// false if ctx is cancelled first.
+func (x *NodeIndex) WaitForSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), x.informer.HasSynced)
+}
+
+// Nodes returns every owned node in the cache, sorted by name. The nodes are shared with
+// the cache and must not be modified.
+func (x *NodeIndex) Nodes() []*corev1.Node {
+	return x.nodesOf(x.informer.GetIndexer().List())
+}
+
+// Has reports whether nodeName is an owned node in the cache.
+func (x *NodeIndex) Has(nodeName string) bool {
+	_, exists, err := x.informer.GetIndexer().GetByKey(nodeName)
+	return err == nil && exists && x.owns(nodeName)
+}
+
+// ByPool returns the owned nodes in pool, sorted by name.
+func (x *NodeIndex) ByPool(pool string) []*corev1.Node {
+	return x.byIndex(IndexPool, pool)
+}
+
+// ByState returns the owned nodes in quarantine state (one of the State*
+// values), sorted by name.
+func (x *NodeIndex) ByState(state string) []*corev1.Node {
+	return x.byIndex(IndexState, state)
+}
+
+// Pools returns the pool names of owned nodes, sorted. Nodes without the
+// pool label are in pool "".
+func (x *NodeIndex) Pools() []string {
+	pools := x.informer.GetIndexer().ListIndexFuncValues(IndexPool)
+	if x.cfg.Owns != nil {
+		pools = pools[:0]
+		seen := map[string]bool{}
+		for _, node := range x.Nodes() {
+			if pool := node.Labels[x.cfg.PoolLabel]; !seen[pool] {
+				seen[pool] = true
+				pools = append(pools, pool)
+			}
+		}
+	}
+	sort.Strings(pools)
+	return pools
+}
+
+// RecordCounts sets metrics.Nodes to the number of owned nodes per pool and
+// quarantine state. Summed across the replicas of a sharded controller, the
+// counts cover the fleet once.
+func (x *NodeIndex) RecordCounts() {
+	counts := map[[2]string]int{}
+	for _, node := range x.Nodes() {
+		counts[[2]string{node.Labels[x.cfg.PoolLabel], QuarantineStateForKey(node, x.cfg.TaintKey)}]++
+	}
+	metrics.Nodes.Reset()
+	for k, n := range counts {
+		metrics.Nodes.WithLabelValues(k[0], k[1]).Set(float64(n))
+	}
+}
+
+func (x *NodeIndex) byIndex(name, value string) []*corev1.Node {
+	objs, err := x.informer.GetIndexer().ByIndex(name, value)
+	if err != nil {
+		return nil // only for an unknown index name
+	}
+	return x.nodesOf(objs)
+}
+
+func (x *NodeIndex) owns(nodeName string) bool {
+	return x.cfg.Owns == nil || x.cfg.Owns(nodeName)
+}
+
+func (x *NodeIndex) nodesOf(objs []any) []*corev1.Node {
+	nodes := make([]*corev1.Node, 0, len(objs))
+	for _, obj := range objs {
+		if node, ok := obj.(*corev1.Node); ok && x.owns(node.Name) {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeIndex(t *testing.T) {
+	t.Parallel()
+
+	gpuNode := func(node *corev1.Node, pool string) *corev1.Node {
+		node.Labels = map[string]string{"nvidia.com/gpu.present": "true", "pool": pool}
+		node.Status.Images = []corev1.ContainerImage{{Names: []string{"nvcr.io/nvidia/pytorch:24.01"}}}
+		return node
+	}
+	cpu := freshNode("cpu-node-0", time.Hour)
+	client := fake.NewSimpleClientset(
+		gpuNode(freshNode("gpu-node-0", time.Hour), "h100"),
+		gpuNode(freshNode("gpu-node-1", time.Hour), "h100"),
+		gpuNode(quarantinedNode("gpu-node-2", time.Hour), "a100"),
+		cpu,
+	)
+	index := NewNodeIndex(client, NodeIndexConfig{LabelSelector: "nvidia.com/gpu.present=true", PoolLabel: "pool", PageSize: 2})
+
+	var mu sync.Mutex
+	added := map[string]bool{}
+	updated := make(chan string, 10)
+	if err := index.OnChange(func(old, node *corev1.Node) {
+		if old == nil {
+			mu.Lock()
+			added[node.Name] = true
+			mu.Unlock()
+			return
+		}
+		updated <- node.Name
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go index.Run(ctx)
+	if !index.WaitForSync(ctx) {
+		t.Fatal("index never synced")
+	}
+
+	if nodes := index.Nodes(); len(nodes) != 3 || nodes[0].Name != "gpu-node-0" || len(nodes[0].Status.Images) != 0 {
+		t.Errorf("nodes = %d, want the 3 GPU nodes by name with image lists dropped", len(nodes))
+	}
+	if !index.Has("gpu-node-1") || index.Has(cpu.Name) || index.Has("gpu-node-9") {
+		t.Errorf("Has = %v, %v, %v, want only the GPU node", index.Has("gpu-node-1"), index.Has(cpu.Name), index.Has("gpu-node-9"))
+	}
+	if pools := index.Pools(); len(pools) != 2 || pools[0] != "a100" || pools[1] != "h100" {
+		t.Errorf("pools = %v, want [a100 h100]", pools)
+	}
+	if h100 := index.ByPool("h100"); len(h100) != 2 {
+		t.Errorf("h100 pool = %d nodes, want 2", len(h100))
+	}
+	if q := index.ByState(StateQuarantined); len(q) != 1 || q[0].Name != "gpu-node-2" {
+		t.Errorf("quarantined = %v, want gpu-node-2", q)
+	}
+
+	// A quarantine moves the node between state indexes.
+	node, _ := client.CoreV1().Nodes().Get(ctx, "gpu-node-0", metav1.GetOptions{})
+	node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{Key: DefaultTaintKey, Effect: corev1.TaintEffectPreferNoSchedule})
+	if _, err := client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-updated:
+		if name != "gpu-node-0" {
+			t.Errorf("update for %s, want gpu-node-0", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update seen")
+	}
+	if s := index.ByState(StateSuspected); len(s) != 1 || s[0].Name != "gpu-node-0" {
+		t.Errorf("suspected = %v, want gpu-node-0", s)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(added) != 3 || added[cpu.Name] {
+		t.Errorf("added = %v, want the 3 GPU nodes only", added)
+	}
+}
@@ -0,0 +1,184 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LastPulseAnnotation records when the node last completed a pulse (RFC 3339).
+// PeriodicValidator reads it at startup so the staleness guarantee survives
+// agent restarts.
+const LastPulseAnnotation = "straggler-shield.io/last-pulse"
+
+// IdleFunc reports whether a node's GPUs are free for a pulse right now.
+type IdleFunc func(ctx context.Context, nodeName string) (bool, error)
+
+// PeriodicValidator re-pulses a steady-state node on a fixed cadence, but only
+// in gaps between GPU workloads: a GEMM burn on a training-active node would
+// spike memory bandwidth and perturb the job. If no idle window appears
+// within MaxStaleness of the last pulse, the pulse is forced anyway so a node
+// pinned by a long-running job cannot go unvalidated indefinitely.
+type PeriodicValidator struct {
+	Client kubernetes.Interface
+
+	// Validate runs one pulse on the node; normally wraps Controller.ValidateNode
+	// behind the caller's per-node lock.
+	Validate func(ctx context.Context, nodeName string)
+
+	// Idle gates each pulse. Nil treats the node as always idle.
+	Idle IdleFunc
+
+	Interval      time.Duration // target time between pulses
+	MaxStaleness  time.Duration // force a pulse once this much time has passed
+	RetryInterval time.Duration // re-check for an idle gap this often while busy
+
+	Logger *slog.Logger
+}
+
+// Run schedules pulses for nodeName until ctx is cancelled.
+func (p *PeriodicValidator) Run(ctx context.Context, nodeName string) {
+	logger := p.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	last := p.lastPulse(ctx, nodeName)
+	for {
+		wait := time.Until(last.Add(p.Interval))
+		for {
+			if !sleepCtx(ctx, wait) {
+				return
+			}
+			idle := true
+			if p.Idle != nil {
+				var err error
+				idle, err = p.Idle(ctx, nodeName)
+				if err != nil {
+					// Can't tell — treat as busy; staleness still bounds the delay.
+					logger.Warn("idle check failed — treating node as busy", "node", nodeName, "err", err)
+					idle = false
+				}
+			}
+			stale := time.Since(last) >= p.MaxStaleness
+			if idle || stale {
+				if !idle {
+					logger.Warn("no idle window within max staleness — forcing periodic pulse",
+						"node", nodeName, "last_pulse", last, "max_staleness", p.MaxStaleness)
+				}
+				break
+			}
+			logger.Info("GPU workloads running — deferring periodic pulse", "node", nodeName, "retry_in", p.RetryInterval)
+			wait = min(p.RetryInterval, time.Until(last.Add(p.MaxStaleness)))
+		}
+
+		p.Validate(ctx, nodeName)
+		last = time.Now()
+	}
+}
+
+// lastPulse reads LastPulseAnnotation from the node. A missing or unreadable
+// value counts as "just pulsed" — the Ready-transition pulse covers new nodes.
+func (p *PeriodicValidator) lastPulse(ctx context.Context, nodeName string) time.Time {
+	node, err := p.Client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return time.Now()
+	}
+	t, err := time.Parse(time.RFC3339, node.Annotations[LastPulseAnnotation])
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
+
+// recordLastPulse stamps LastPulseAnnotation and LastPulseBootIDAnnotation on
+// the node, with the pulse's RiskScoreAnnotation. Best effort: a failure only
+// shortens the staleness memory across restarts and leaves trigger policies
+// with an older history.
+func (c *Controller) recordLastPulse(ctx context.Context, node *corev1.Node, report *pulse.PulseReport, err error) {
+	annotations := map[string]string{LastPulseAnnotation: time.Now().UTC().Format(time.RFC3339)}
+	if bootID := node.Status.NodeInfo.BootID; bootID != "" {
+		annotations[LastPulseBootIDAnnotation] = bootID
+	}
+	recordRisk(ctx, node.Name, report, err, annotations)
+	patch, _ := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": annotations},
+	})
+	if _, err := c.client.CoreV1().Nodes().Patch(
+		ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{},
+	); err != nil {
+		c.logger.Warn("failed to record last pulse time", "node", node.Name, "err", err)
+	}
+}
+
+// PodsIdle returns an IdleFunc that reports a node busy while any running pod
+// other than our own runner pods requests nvidia.com/gpu.
+func PodsIdle(client kubernetes.Interface) IdleFunc {
+	return func(ctx context.Context, nodeName string) (bool, error) {
+		pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			FieldSelector: "spec.nodeName=" + nodeName + ",status.phase=Running",
+		})
+		if err != nil {
+			return false, fmt.Errorf("list pods on %s: %w", nodeName, err)
+		}
+		for i := range pods.Items {
+			if _, ours := pods.Items[i].Labels[runnerPodLabel]; ours {
+				continue
+			}
+			if requestsGPU(&pods.Items[i]) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// AllIdle combines idle checks; the node is idle only if every check agrees.
+func AllIdle(checks ...IdleFunc) IdleFunc {
+	return func(ctx context.Context, nodeName string) (bool, error) {
+		for _, check := range checks {
+			idle, err := check(ctx, nodeName)
+			if err != nil || !idle {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+func requestsGPU(pod *corev1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if q, ok := c.Resources.Limits[gpuResource]; ok && !q.IsZero() {
+			return true
+		}
+		if q, ok := c.Resources.Requests[gpuResource]; ok && !q.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// sleepCtx sleeps for d (returning immediately if d <= 0) and reports false
+// if ctx was cancelled first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
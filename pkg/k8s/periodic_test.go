@@ -0,0 +1,183 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// runPeriodic starts p.Run for gpu-node-0 and returns a channel receiving
+// the time of each pulse, and a stop func that cancels Run and waits for it.
+func runPeriodic(t *testing.T, p *PeriodicValidator, pulseTime time.Duration) (<-chan time.Time, func()) {
+	t.Helper()
+	pulses := make(chan time.Time, 16)
+	var inFlight atomic.Int32
+	p.Validate = func(ctx context.Context, nodeName string) {
+		if inFlight.Add(1) > 1 {
+			t.Errorf("periodic pulses of %s overlap", nodeName)
+		}
+		time.Sleep(pulseTime)
+		inFlight.Add(-1)
+		pulses <- time.Now()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() { p.Run(ctx, "gpu-node-0"); close(stopped) }()
+	return pulses, func() {
+		cancel()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after cancel")
+		}
+	}
+}
+
+func nextPulse(t *testing.T, pulses <-chan time.Time) time.Time {
+	t.Helper()
+	select {
+	case at := <-pulses:
+		return at
+	case <-time.After(5 * time.Second):
+		t.Fatal("no periodic pulse")
+		return time.Time{}
+	}
+}
+
+func TestPeriodicValidatorInterval(t *testing.T) {
+	t.Parallel()
+
+	// Without a last-pulse annotation the node counts as just pulsed. A
+	// pulse that outlasts the interval does not overlap the next one.
+	const interval = 30 * time.Millisecond
+	p := &PeriodicValidator{
+		Client:       fake.NewSimpleClientset(freshNode("gpu-node-0", time.Hour)),
+		Interval:     interval,
+		MaxStaleness: time.Hour,
+	}
+	start := time.Now()
+	pulses, stop := runPeriodic(t, p, 2*interval)
+	defer stop()
+
+	prev := start
+	for i := 0; i < 3; i++ {
+		at := nextPulse(t, pulses)
+		if gap := at.Sub(prev); gap < interval {
+			t.Errorf("pulse %d came %v after the previous, want at least %v", i, gap, interval)
+		}
+		prev = at
+	}
+}
+
+func TestPeriodicValidatorLastPulseAnnotation(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", time.Hour)
+	node.Annotations = map[string]string{LastPulseAnnotation: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)}
+	p := &PeriodicValidator{
+		Client:       fake.NewSimpleClientset(node),
+		Interval:     time.Hour,
+		MaxStaleness: 24 * time.Hour,
+	}
+	pulses, stop := runPeriodic(t, p, 0)
+	defer stop()
+
+	// Overdue since before the restart: pulsed at once, not an hour later.
+	nextPulse(t, pulses)
+}
+
+func TestPeriodicValidatorBusy(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		busyChecks   int32 // idle checks answered busy before the node frees up
+		maxStaleness time.Duration
+		idleErr      error
+		wantChecks   int32
+	}{
+		{name: "deferred until idle", busyChecks: 3, maxStaleness: time.Hour, wantChecks: 4},
+		{name: "forced once stale", busyChecks: 1 << 30, maxStaleness: 50 * time.Millisecond},
+		{name: "failed check counts as busy", busyChecks: 1 << 30, maxStaleness: 50 * time.Millisecond, idleErr: errors.New("apiserver unavailable")},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var checks atomic.Int32
+			p := &PeriodicValidator{
+				Client: fake.NewSimpleClientset(freshNode("gpu-node-0", time.Hour)),
+				Idle: func(context.Context, string) (bool, error) {
+					n := checks.Add(1)
+					if tc.idleErr != nil {
+						return false, tc.idleErr
+					}
+					return n > tc.busyChecks, nil
+				},
+				Interval:      10 * time.Millisecond,
+				MaxStaleness:  tc.maxStaleness,
+				RetryInterval: 5 * time.Millisecond,
+			}
+			if tc.maxStaleness < time.Hour {
+				// The staleness bound, not the retry interval, ends the wait.
+				p.RetryInterval = time.Hour
+			}
+			start := time.Now()
+			pulses, stop := runPeriodic(t, p, 0)
+			at := nextPulse(t, pulses)
+			stop()
+
+			if tc.wantChecks != 0 && checks.Load() < tc.wantChecks {
+				t.Errorf("pulsed after %d idle checks, want %d", checks.Load(), tc.wantChecks)
+			}
+			if tc.maxStaleness < time.Hour && at.Sub(start) < tc.maxStaleness {
+				t.Errorf("busy node pulsed after %v, before max staleness %v", at.Sub(start), tc.maxStaleness)
+			}
+		})
+	}
+}
+
+func TestPeriodicValidatorCancel(t *testing.T) {
+	t.Parallel()
+
+	p := &PeriodicValidator{
+		Client:       fake.NewSimpleClientset(freshNode("gpu-node-0", time.Hour)),
+		Interval:     time.Hour,
+		MaxStaleness: 24 * time.Hour,
+	}
+	pulses, stop := runPeriodic(t, p, 0)
+	time.Sleep(10 * time.Millisecond)
+	stop() // fails the test if Run is still waiting out the interval
+	select {
+	case <-pulses:
+		t.Error("pulsed before the interval elapsed")
+	default:
+	}
+}
+
+func TestPodsIdleSkipsRunnerPods(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	runner := gpuPod("pulse-gpu-node-0", "gpu-node-0", 8)
+	runner.Labels = map[string]string{runnerPodLabel: "gpu-node-0"}
+	clientset := fake.NewSimpleClientset(runner, gpuPod("sidecar", "gpu-node-0", 0))
+	idle := PodsIdle(clientset)
+
+	// Our own runner pod, mid-pulse, does not make the node busy.
+	if ok, err := idle(ctx, "gpu-node-0"); err != nil || !ok {
+		t.Errorf("PodsIdle with only a runner pod = %v, %v; want idle", ok, err)
+	}
+	if _, err := clientset.CoreV1().Pods("training").Create(ctx, gpuPod("llm-0", "gpu-node-0", 8), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := idle(ctx, "gpu-node-0"); err != nil || ok {
+		t.Errorf("PodsIdle with a training pod = %v, %v; want busy", ok, err)
+	}
+}
@@ -0,0 +1,232 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// gpuResource is the extended resource advertised by the NVIDIA device plugin.
+const gpuResource = corev1.ResourceName("nvidia.com/gpu")
+
+// pulseStateDir is the host directory where the runner persists state between
+// pulses (the GPU count; see pulse.ErrDeviceCountDecreased). It matches the
+// runner's PULSE_STATE_DIR default and the DaemonSet's hostPath, so node and
+// pod isolation modes share one history.
+const pulseStateDir = "/var/lib/straggler-shield"
+
+var hostPathDirOrCreate = corev1.HostPathDirectoryOrCreate
+
+// runnerPodLabel marks pods created by PodRunner so they can be found and
+// garbage-collected by label.
+const runnerPodLabel = "straggler-shield.io/pulse-runner"
+
+// PodRunner executes the pulse in a short-lived pod pinned to the target node.
+// The pod requests the node's GPUs through the device plugin and runs
+// cmd/pulse-runner, so the agent itself needs neither GPU access nor the
+// nvidia runtime class and can run unprivileged. The result is read from the
+// container's termination message (the runner's --result-file).
+type PodRunner struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Image     string
+
+	// GPUs is the nvidia.com/gpu request. Zero requests every allocatable GPU
+	// on the node so the pulse validates the whole device set.
+	GPUs int64
+
+	// RuntimeClassName is applied to the runner pod; defaults to "nvidia".
+	RuntimeClassName string
+
+	// TaintKey is the quarantine taint the runner pod tolerates, so it can
+	// revalidate a quarantined node; defaults to DefaultTaintKey.
+	TaintKey string
+
+	// Timeout bounds scheduling plus execution of the pod. Defaults to 10m.
+	Timeout time.Duration
+
+	// PollInterval is how often pod status is checked. Defaults to 2s.
+	PollInterval time.Duration
+}
+
+// Run creates the runner pod on nodeName, waits for it to terminate, and
+// decodes its pulse.RunnerResult. It satisfies NodePulseFunc. The pod is
+// always deleted before returning. A pod that fails without a parseable
+// result returns pulse.ErrPulseCrashed. Failures that say nothing about the
+// GPUs return pulse.ErrInconclusive: the API server rejecting the pod, or
+// the pod not starting within Timeout, e.g. unschedulable or stuck pulling
+// its image. A pod still running at Timeout returns pulse.ErrStageTimeout.
+func (r *PodRunner) Run(ctx context.Context, nodeName string) (time.Duration, error) {
+	report, err := r.RunReport(ctx, nodeName)
+	return report.Elapsed(), err
+}
+
+// RunReport is Run returning the runner's full pulse.PulseReport; it
+// satisfies NodeReportFunc. The report is never nil.
+func (r *PodRunner) RunReport(ctx context.Context, nodeName string) (*pulse.PulseReport, error) {
+	id := pulse.PulseIDFrom(ctx)
+	start := time.Now()
+	failed := func(err error) (*pulse.PulseReport, error) {
+		return &pulse.PulseReport{PulseID: id, StartedAt: start.UTC(), WorstMeanNS: time.Since(start).Nanoseconds()}, err
+	}
+
+	gpus := r.GPUs
+	if gpus == 0 {
+		node, err := r.Client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return failed(fmt.Errorf("%w: get node %s for gpu count: %w", pulse.ErrInconclusive, nodeName, err))
+		}
+		q := node.Status.Allocatable[gpuResource]
+		gpus = q.Value()
+		if gpus < 1 {
+			return failed(fmt.Errorf("node %s advertises no %s", nodeName, gpuResource))
+		}
+	}
+
+	pod, err := r.Client.CoreV1().Pods(r.Namespace).Create(ctx, r.podSpec(nodeName, gpus, id), metav1.CreateOptions{})
+	if err != nil {
+		return failed(fmt.Errorf("%w: create runner pod on %s: %w", pulse.ErrInconclusive, nodeName, err))
+	}
+	defer func() {
+		// Use a fresh context — ctx may already be cancelled on shutdown and
+		// an orphaned runner would hold the node's GPUs.
+		delCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = r.Client.CoreV1().Pods(r.Namespace).Delete(delCtx, pod.Name, metav1.DeleteOptions{})
+	}()
+
+	var done, last *corev1.Pod
+	err = wait.PollUntilContextTimeout(ctx, r.pollInterval(), r.timeout(), true, func(ctx context.Context) (bool, error) {
+		p, err := r.Client.CoreV1().Pods(r.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil // transient — keep polling until the timeout
+		}
+		last = p
+		if p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed {
+			done = p
+			return true, nil
+		}
+		return false, nil
+	})
+	switch {
+	case err == nil:
+	case ctx.Err() == nil && last != nil && last.Status.Phase == corev1.PodRunning:
+		return failed(fmt.Errorf("runner pod %s on %s: %w after %v", pod.Name, nodeName, pulse.ErrStageTimeout, r.timeout()))
+	default:
+		return failed(fmt.Errorf("%w: runner pod %s on %s did not start%s: %w", pulse.ErrInconclusive, pod.Name, nodeName, pendingReason(last), err))
+	}
+
+	msg := terminationMessage(done)
+	if msg == "" {
+		return failed(fmt.Errorf("%w: runner pod %s ended %s without a result", pulse.ErrPulseCrashed, done.Name, done.Status.Phase))
+	}
+	res, err := pulse.ReadRunnerResult(strings.NewReader(msg))
+	if err != nil {
+		return failed(fmt.Errorf("%w: runner pod %s: %v", pulse.ErrPulseCrashed, done.Name, err))
+	}
+	res.Record(id)
+	return res.PulseReport(id), res.Err()
+}
+
+func (r *PodRunner) podSpec(nodeName string, gpus int64, pulseID string) *corev1.Pod {
+	runtimeClass := r.RuntimeClassName
+	if runtimeClass == "" {
+		runtimeClass = "nvidia"
+	}
+	taintKey := r.TaintKey
+	if taintKey == "" {
+		taintKey = DefaultTaintKey
+	}
+	limits := corev1.ResourceList{gpuResource: *resource.NewQuantity(gpus, resource.DecimalSI)}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "pulse-runner-",
+			Namespace:    r.Namespace,
+			Labels:       map[string]string{runnerPodLabel: nodeName},
+		},
+		Spec: corev1.PodSpec{
+			// NodeName bypasses the scheduler, which would otherwise refuse
+			// to place the runner on a node we have just quarantined.
+			NodeName:         nodeName,
+			RestartPolicy:    corev1.RestartPolicyNever,
+			RuntimeClassName: &runtimeClass,
+			Tolerations: []corev1.Toleration{
+				{Key: taintKey, Operator: corev1.TolerationOpExists},
+				{Key: "node.kubernetes.io/unschedulable", Operator: corev1.TolerationOpExists},
+			},
+			Containers: []corev1.Container{{
+				Name:                     "pulse",
+				Image:                    r.Image,
+				Command:                  []string{"pulse-runner", "--result-file", corev1.TerminationMessagePathDefault},
+				TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+				Env: []corev1.EnvVar{
+					{Name: pulse.PulseIDEnv, Value: pulseID},
+					{Name: pulse.ExpectedGPUsEnv, Value: strconv.FormatInt(gpus, 10)},
+				},
+				Resources:    corev1.ResourceRequirements{Limits: limits, Requests: limits},
+				VolumeMounts: []corev1.VolumeMount{{Name: "state", MountPath: pulseStateDir}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "state",
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{
+					Path: pulseStateDir,
+					Type: &hostPathDirOrCreate,
+				}},
+			}},
+		},
+	}
+}
+
+// pendingReason says why p has not started, e.g. " (Unschedulable: 0/8
+// nodes are available)", or "" if its status does not say.
+func pendingReason(p *corev1.Pod) string {
+	if p == nil {
+		return ""
+	}
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			return fmt.Sprintf(" (%s: %s)", c.Reason, c.Message)
+		}
+	}
+	for _, cs := range p.Status.ContainerStatuses {
+		if w := cs.State.Waiting; w != nil && w.Reason != "" {
+			return fmt.Sprintf(" (%s: %s)", w.Reason, w.Message)
+		}
+	}
+	return ""
+}
+
+// terminationMessage returns the pulse container's termination message, or ""
+// if it has not terminated or wrote nothing.
+func terminationMessage(p *corev1.Pod) string {
+	for _, cs := range p.Status.ContainerStatuses {
+		if cs.Name == "pulse" && cs.State.Terminated != nil {
+			return cs.State.Terminated.Message
+		}
+	}
+	return ""
+}
+
+func (r *PodRunner) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return 10 * time.Minute
+}
+
+func (r *PodRunner) pollInterval() time.Duration {
+	if r.PollInterval > 0 {
+		return r.PollInterval
+	}
+	return 2 * time.Second
+}
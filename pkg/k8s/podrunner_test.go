@@ -0,0 +1,123 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPodRunner(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", time.Minute)
+	node.Status.Allocatable = corev1.ResourceList{gpuResource: resource.MustParse("8")}
+	clientset := fake.NewSimpleClientset(node)
+
+	// Stand in for the kubelet: the runner pod "completes" as soon as it is
+	// created, reporting a straggler verdict through its termination message.
+	result, _ := json.Marshal(pulse.NewRunnerResult(90*time.Millisecond, nil, &pulse.PulseFailure{
+		Cause:          pulse.ErrStragglerDetected,
+		MeasuredValue:  90,
+		ThresholdValue: 35,
+		Unit:           "ms",
+	}))
+	var created *corev1.Pod
+	clientset.PrependReactor("create", "pods", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		p := a.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		p.Name = p.GenerateName + "test"
+		p.Status.Phase = corev1.PodSucceeded
+		p.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "pulse",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: string(result)}},
+		}}
+		created = p.DeepCopy()
+		return false, nil, nil // let the tracker store it
+	})
+
+	r := &PodRunner{Client: clientset, Namespace: "straggler-shield", Image: "runner:test", PollInterval: time.Millisecond}
+	elapsed, err := r.Run(context.Background(), node.Name)
+
+	if !errors.Is(err, pulse.ErrStragglerDetected) {
+		t.Fatalf("Run error = %v, want ErrStragglerDetected", err)
+	}
+	if elapsed != 90*time.Millisecond {
+		t.Errorf("elapsed=%v, want 90ms", elapsed)
+	}
+	if created == nil {
+		t.Fatal("runner pod was never created")
+	}
+	if created.Spec.NodeName != node.Name {
+		t.Errorf("pod pinned to %q, want %q", created.Spec.NodeName, node.Name)
+	}
+	if got := created.Spec.Containers[0].Resources.Limits[gpuResource]; got.Value() != 8 {
+		t.Errorf("gpu limit=%v, want all 8 allocatable", got.String())
+	}
+
+	pods, _ := clientset.CoreV1().Pods("straggler-shield").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 0 {
+		t.Errorf("runner pod not cleaned up: %d pod(s) remain", len(pods.Items))
+	}
+}
+
+func TestPodRunnerNotStarted(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		status  corev1.PodStatus
+		want    error
+		wantMsg string
+	}{
+		{
+			name: "unschedulable",
+			status: corev1.PodStatus{Phase: corev1.PodPending, Conditions: []corev1.PodCondition{{
+				Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: "Insufficient nvidia.com/gpu",
+			}}},
+			want: pulse.ErrInconclusive, wantMsg: "Insufficient nvidia.com/gpu",
+		},
+		{
+			name: "image pull",
+			status: corev1.PodStatus{Phase: corev1.PodPending, ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "pulse", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}}},
+			want: pulse.ErrInconclusive, wantMsg: "ImagePullBackOff",
+		},
+		{name: "hung", status: corev1.PodStatus{Phase: corev1.PodRunning}, want: pulse.ErrStageTimeout},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clientset := fake.NewSimpleClientset()
+			clientset.PrependReactor("create", "pods", func(a k8stesting.Action) (bool, runtime.Object, error) {
+				p := a.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+				p.Name = p.GenerateName + "test"
+				p.Status = tc.status
+				return false, nil, nil
+			})
+			r := &PodRunner{Client: clientset, Namespace: "straggler-shield", Image: "runner:test", GPUs: 8, Timeout: 20 * time.Millisecond, PollInterval: time.Millisecond}
+			_, err := r.Run(context.Background(), "gpu-node-0")
+
+			// Neither is a GPU verdict: both stay out of pre_flight_failure.
+			if !errors.Is(err, tc.want) || !pulse.IsInconclusive(err) || !strings.Contains(err.Error(), tc.wantMsg) {
+				t.Errorf("Run error = %v, want %v mentioning %q", err, tc.want, tc.wantMsg)
+			}
+			if reason, _ := classify(err); reason == "pre_flight_failure" {
+				t.Errorf("classified as %s", reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package k8s
+
+import (
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// RecordedPulse is a pulse result replayed by PredictState: the
+// failure_reason it was classified as, empty for a pass, and its report.
+type RecordedPulse struct {
+	Reason string
+	Report *pulse.PulseReport
+}
+
+// Prediction is the state PredictState replays a node's pulses to.
+type Prediction struct {
+	State string // one of the State* values
+	// Reason is the latest pulse's failure_reason, judged against the
+	// policy's CV ceiling; empty for a pass.
+	Reason string
+	// Classes are the workload classes the node is unfit for when State is
+	// StateScoped.
+	Classes []policy.WorkloadClass
+}
+
+// predicted are the markers a replay has left on a node.
+type predicted struct {
+	effect   corev1.TaintEffect // quarantine taint effect, "" for none
+	value    string             // class taint value (see policy.Taint)
+	classes  []policy.WorkloadClass
+	degraded bool
+}
+
+// PredictState replays pulses, newest first as the aggregator lists them,
+// through the decisions a controller running p makes, and returns the state
+// node would be left in. It is for reviewing a policy change before rollout
+// (kubectl straggler policy diff): each pulse is judged against p's CV
+// ceiling (see pulse.RejudgeCV), then acted on by its severity, inconclusive
+// handling, soft quarantine, class taints and workload classes as the
+// controller would. Controller settings outside the policy take their
+// defaults: a quarantine is a NoSchedule taint applied on the first failure.
+// node supplies the name, labels and annotations the policy expression sees
+// and may carry only a name.
+func PredictState(p *policy.Policy, node *corev1.Node, pulses []RecordedPulse) Prediction {
+	var (
+		m            predicted
+		reason       string
+		inconclusive int
+	)
+	for i := len(pulses) - 1; i >= 0; i-- {
+		rp := pulses[i]
+		reason = rp.Reason
+		if limit, ok := policyCVCeiling(p, rp.Report); ok {
+			reason = pulse.RejudgeCV(rp.Report, reason, limit)
+		}
+		if reason == "" {
+			m, inconclusive = predicted{}, 0
+			continue
+		}
+
+		handling := p.InconclusiveHandling()
+		isInconclusive := reason == "inconclusive" || reason == "stage_timeout"
+		if !isInconclusive {
+			inconclusive = 0
+		}
+		isInconclusive = isInconclusive && handling != ""
+		if isInconclusive && handling == policy.HandlingRetry {
+			if inconclusive++; inconclusive <= policy.MaxInconclusiveRetries {
+				continue
+			}
+			inconclusive = 0
+		}
+
+		// A failing expression falls back to the severities map, as it
+		// does in the controller.
+		sev, _ := p.Decide(reason, node, rp.Report)
+		if isInconclusive && handling.Severity() != "" {
+			sev = handling.Severity()
+		}
+		switch sev {
+		case policy.SeverityWarn, policy.SeverityDegrade, policy.SeverityDegradeLabel:
+			// An inconclusive pulse keeps a quarantine; any other
+			// failure below quarantine lifts it (see downgrade).
+			if m.effect != "" && (reason == "inconclusive" || reason == "stage_timeout") {
+				continue
+			}
+			m.effect, m.value = "", ""
+			m.degraded = m.degraded || sev != policy.SeverityWarn
+		default:
+			if classes, scoped := p.ImpactedClasses(reason); scoped {
+				m.effect, m.value, m.classes = "", "", classes
+				continue
+			}
+			m.quarantine(p, reason)
+		}
+	}
+	return Prediction{State: m.state(), Reason: reason, Classes: m.classes}
+}
+
+// quarantine applies a quarantine for reason as applyTaint does.
+func (m *predicted) quarantine(p *policy.Policy, reason string) {
+	effect, value := corev1.TaintEffectNoSchedule, ""
+	class, classTaint := p.TaintFor(reason)
+	if classTaint {
+		value = class.Value
+		if class.Effect != "" {
+			effect = class.Effect
+		}
+	}
+	existing := m.effect != ""
+	replaced := existing && m.value != value && (classTaint || p.IsClassTaintValue(m.value))
+	switch {
+	case existing && !replaced && effectRank(m.effect) >= effectRank(effect):
+		return
+	case !existing && p != nil && p.SoftQuarantine && effect != corev1.TaintEffectPreferNoSchedule:
+		effect = corev1.TaintEffectPreferNoSchedule
+	}
+	m.effect, m.value = effect, value
+}
+
+// state mirrors QuarantineState for the predicted markers.
+func (m *predicted) state() string {
+	switch {
+	case m.effect == corev1.TaintEffectPreferNoSchedule:
+		return StateSuspected
+	case m.effect != "":
+		return StateQuarantined
+	case len(m.classes) > 0:
+		return StateScoped
+	case m.degraded:
+		return StateDegraded
+	}
+	return StateHealthy
+}
+
+// policyCVCeiling returns the CV ceiling a pulse would have run against under
+// p. ok is false when p does not decide it: there is no report, or the
+// ceiling the pulse ran against came from an env override or was set at
+// runtime, both of which win over the policy.
+func policyCVCeiling(p *policy.Policy, r *pulse.PulseReport) (float64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	prov := r.Thresholds.ThresholdProvenance
+	switch prov.MaxCVSource {
+	case pulse.SourceEnv, pulse.SourceRuntime:
+		return 0, false
+	}
+	if v, ok := p.CVCeiling(prov.Matched); ok {
+		return v, true
+	}
+	if prov.MaxCVSource == pulse.SourcePolicy {
+		// Dropped from the policy: back to the calibrated ceiling.
+		return pulse.CalibratedMaxCV(prov.Matched), true
+	}
+	return 0, false
+}
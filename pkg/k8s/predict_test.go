@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPredictState(t *testing.T) {
+	t.Parallel()
+
+	// An H100 pulse whose second GPU ran at CV 0.25 against the calibrated
+	// 0.25 ceiling, which it passed inside the degraded band.
+	jittery := &pulse.PulseReport{
+		Thresholds: pulse.Snapshot{
+			StragglerThreshold:  35 * time.Millisecond,
+			MaxCV:               0.25,
+			DegradedFraction:    0.8,
+			ThresholdProvenance: pulse.Provenance{Matched: "H100", MaxCVSource: pulse.SourceDetected},
+		},
+		Devices: []pulse.DeviceResult{
+			{Device: 0, MeanNS: int64(20 * time.Millisecond), CV: 0.02},
+			{Device: 1, MeanNS: int64(20 * time.Millisecond), CV: 0.25},
+		},
+	}
+	pass := RecordedPulse{Report: &pulse.PulseReport{}}
+	fail := func(reason string) RecordedPulse { return RecordedPulse{Reason: reason, Report: &pulse.PulseReport{}} }
+
+	cases := []struct {
+		name   string
+		policy *policy.Policy
+		pulses []RecordedPulse // newest first
+		want   string
+	}{
+		{"no policy quarantines", nil, []RecordedPulse{fail("latency_threshold_exceeded")}, StateQuarantined},
+		{"pass clears", nil, []RecordedPulse{pass, fail("latency_threshold_exceeded")}, StateHealthy},
+		{"warn leaves the node alone", &policy.Policy{Severities: map[string]policy.Severity{"high_variance": policy.SeverityWarn}},
+			[]RecordedPulse{fail("high_variance")}, StateHealthy},
+		{"warn lifts an earlier quarantine", &policy.Policy{Severities: map[string]policy.Severity{"high_variance": policy.SeverityWarn}},
+			[]RecordedPulse{fail("high_variance"), fail("pulse_crashed")}, StateHealthy},
+		{"degrade replaces an earlier quarantine", &policy.Policy{Severities: map[string]policy.Severity{"high_variance": policy.SeverityDegrade}},
+			[]RecordedPulse{fail("high_variance"), fail("pulse_crashed")}, StateDegraded},
+		{"inconclusive keeps an earlier quarantine", &policy.Policy{Inconclusive: policy.HandlingWarn},
+			[]RecordedPulse{fail("inconclusive"), fail("pulse_crashed")}, StateQuarantined},
+		{"near threshold degrades by default", nil, []RecordedPulse{{Reason: "near_threshold", Report: jittery}}, StateDegraded},
+		{"soft quarantine suspects a first failure", &policy.Policy{SoftQuarantine: true},
+			[]RecordedPulse{fail("latency_threshold_exceeded")}, StateSuspected},
+		{"soft quarantine confirms a second", &policy.Policy{SoftQuarantine: true},
+			[]RecordedPulse{fail("latency_threshold_exceeded"), fail("high_variance")}, StateQuarantined},
+		{"inconclusive retried", &policy.Policy{Inconclusive: policy.HandlingRetry},
+			[]RecordedPulse{fail("stage_timeout"), fail("inconclusive")}, StateHealthy},
+		{"inconclusive retries run out", &policy.Policy{Inconclusive: policy.HandlingRetry},
+			[]RecordedPulse{fail("stage_timeout"), fail("inconclusive"), fail("inconclusive"), fail("stage_timeout")}, StateQuarantined},
+		{"workload classes scope the fabric", &policy.Policy{WorkloadClasses: &policy.WorkloadClasses{}},
+			[]RecordedPulse{fail("interconnect_degraded")}, StateScoped},
+		{"class taint effect", &policy.Policy{Taints: map[string]policy.Taint{
+			"interconnect_degraded": {Value: "fabric", Effect: corev1.TaintEffectPreferNoSchedule}}},
+			[]RecordedPulse{fail("interconnect_degraded")}, StateSuspected},
+		{"tighter CV ceiling fails the jittery pulse", &policy.Policy{CVCeilings: map[string]float64{"H100": 0.20}},
+			[]RecordedPulse{{Reason: "near_threshold", Report: jittery}}, StateQuarantined},
+		{"looser CV ceiling passes it", &policy.Policy{CVCeilings: map[string]float64{"H100": 0.40}},
+			[]RecordedPulse{{Reason: "near_threshold", Report: jittery}}, StateHealthy},
+		{"expression sees the node", &policy.Policy{Expression: `node.labels[?"burn-in"].orValue("") == "true" ? "warn" : ""`},
+			[]RecordedPulse{fail("pulse_crashed")}, StateHealthy},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if tc.policy != nil {
+				if err := tc.policy.Validate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-0", Labels: map[string]string{"burn-in": "true"}}}
+			if got := PredictState(tc.policy, node, tc.pulses); got.State != tc.want {
+				t.Errorf("PredictState = %+v, want state %s", got, tc.want)
+			}
+		})
+	}
+}
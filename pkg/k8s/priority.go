@@ -0,0 +1,211 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Validation priority criteria, for ValidationPriority.Criteria.
+const (
+	// PriorityPool ranks nodes by ValidationPriority.PoolWeights.
+	PriorityPool = "pool"
+	// PriorityPendingJobs ranks nodes by the GPUs requested by pending,
+	// high-priority pods that select the node's pool.
+	PriorityPendingJobs = "pending-jobs"
+	// PriorityNVLinkDomain ranks nodes by the number of nodes in their
+	// NVLink domain, largest first: a multi-node NVLink domain (e.g. a
+	// GB200 NVL72 rack) only serves its jobs once every member is back.
+	PriorityNVLinkDomain = "nvlink-domain"
+)
+
+// DefaultPriorityCriteria is the order used when ValidationPriority.Criteria
+// is empty.
+var DefaultPriorityCriteria = []string{PriorityPool, PriorityPendingJobs, PriorityNVLinkDomain}
+
+// DefaultNVLinkDomainLabel is the label GPU feature discovery sets to a
+// node's NVLink clique on multi-node NVLink systems.
+const DefaultNVLinkDomainLabel = "nvidia.com/gpu.clique"
+
+// ValidationPriority orders queued pulses (DispatchConfig.Order) so that
+// after a mass reboot the most valuable capacity is validated, and returns
+// to service, first. Nodes are compared on each criterion in turn; the
+// first that differs decides.
+//
+// PriorityPendingJobs and PriorityNVLinkDomain use counts from the last
+// Refresh; until then every node ties on them.
+type ValidationPriority struct {
+	// Criteria lists the criteria in order of precedence. Empty uses
+	// DefaultPriorityCriteria.
+	Criteria []string
+	// PoolLabel is the node label naming the node's pool. Empty uses
+	// DefaultPoolLabel.
+	PoolLabel string
+	// PoolWeights ranks pools for PriorityPool, higher first. Unlisted
+	// pools weigh 0.
+	PoolWeights map[string]int
+	// MinPodPriority is the lowest pod priority counted by
+	// PriorityPendingJobs.
+	MinPodPriority int32
+	// NVLinkDomainLabel names the node's NVLink domain. Empty uses
+	// DefaultNVLinkDomainLabel. Nodes without it are a domain of one.
+	NVLinkDomainLabel string
+
+	mu          sync.RWMutex
+	pendingGPUs map[string]int64 // by pool
+	domainSize  map[string]int   // by NVLink domain
+}
+
+// ParsePoolWeights parses "pool=weight,..." into ValidationPriority.PoolWeights.
+func ParsePoolWeights(s string) (map[string]int, error) {
+	weights := map[string]int{}
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		pool, w, ok := strings.Cut(f, "=")
+		n, err := strconv.Atoi(w)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("pool weight %q: want pool=integer", f)
+		}
+		weights[pool] = n
+	}
+	return weights, nil
+}
+
+// ParsePriorityCriteria parses a comma-separated criteria list into
+// ValidationPriority.Criteria.
+func ParsePriorityCriteria(s string) ([]string, error) {
+	var criteria []string
+	for _, c := range strings.Split(s, ",") {
+		switch c = strings.TrimSpace(c); c {
+		case PriorityPool, PriorityPendingJobs, PriorityNVLinkDomain:
+			criteria = append(criteria, c)
+		default:
+			return nil, fmt.Errorf("priority criterion %q: want %s, %s or %s", c, PriorityPool, PriorityPendingJobs, PriorityNVLinkDomain)
+		}
+	}
+	return criteria, nil
+}
+
+// Before reports whether a should be validated before b.
+func (p *ValidationPriority) Before(a, b *corev1.Node) bool {
+	criteria := p.Criteria
+	if len(criteria) == 0 {
+		criteria = DefaultPriorityCriteria
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, c := range criteria {
+		if x, y := p.score(c, a), p.score(c, b); x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+func (p *ValidationPriority) score(criterion string, node *corev1.Node) int64 {
+	switch criterion {
+	case PriorityPool:
+		return int64(p.PoolWeights[node.Labels[p.poolLabel()]])
+	case PriorityPendingJobs:
+		return p.pendingGPUs[node.Labels[p.poolLabel()]]
+	case PriorityNVLinkDomain:
+		if domain, ok := node.Labels[p.domainLabel()]; ok {
+			return int64(p.domainSize[domain])
+		}
+		return 1
+	}
+	return 0
+}
+
+// Refresh recounts the pending GPU demand per pool and the size of each
+// NVLink domain among nodes, the nodes the controller watches. Pending demand is the GPUs requested by
+// unscheduled pods at MinPodPriority or above that select a pool through a
+// nodeSelector on PoolLabel; pods that could land in any pool do not change
+// the order.
+func (p *ValidationPriority) Refresh(ctx context.Context, client kubernetes.Interface, nodes []*corev1.Node) error {
+	domains := map[string]int{}
+	for _, node := range nodes {
+		if domain, ok := node.Labels[p.domainLabel()]; ok {
+			domains[domain]++
+		}
+	}
+
+	pending := map[string]int64{}
+	if p.uses(PriorityPendingJobs) {
+		pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			FieldSelector: "status.phase=Pending,spec.nodeName=",
+		})
+		if err != nil {
+			return fmt.Errorf("list pending pods: %w", err)
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			pool, ok := pod.Spec.NodeSelector[p.poolLabel()]
+			if !ok || pod.Status.Phase != corev1.PodPending || pod.Spec.NodeName != "" || podPriority(pod) < p.MinPodPriority {
+				continue
+			}
+			pending[pool] += gpusRequested(pod)
+		}
+	}
+
+	p.mu.Lock()
+	p.pendingGPUs, p.domainSize = pending, domains
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *ValidationPriority) uses(criterion string) bool {
+	if len(p.Criteria) == 0 {
+		return true
+	}
+	for _, c := range p.Criteria {
+		if c == criterion {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ValidationPriority) poolLabel() string {
+	if p.PoolLabel == "" {
+		return DefaultPoolLabel
+	}
+	return p.PoolLabel
+}
+
+func (p *ValidationPriority) domainLabel() string {
+	if p.NVLinkDomainLabel == "" {
+		return DefaultNVLinkDomainLabel
+	}
+	return p.NVLinkDomainLabel
+}
+
+func podPriority(pod *corev1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// gpusRequested returns the nvidia.com/gpu count pod asks for, summed over
+// its containers. Extended resources have equal requests and limits; either
+// is read.
+func gpusRequested(pod *corev1.Pod) int64 {
+	var n int64
+	for _, c := range pod.Spec.Containers {
+		if q, ok := c.Resources.Limits[gpuResource]; ok {
+			n += q.Value()
+		} else if q, ok := c.Resources.Requests[gpuResource]; ok {
+			n += q.Value()
+		}
+	}
+	return n
+}
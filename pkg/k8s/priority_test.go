@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func labelledNode(name string, labels map[string]string) *corev1.Node {
+	node := freshNode(name, time.Minute)
+	node.Labels = labels
+	return node
+}
+
+func pendingGPUPod(name, pool string, gpus int64, priority int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "training"},
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{DefaultPoolLabel: pool},
+			Priority:     &priority,
+			Containers: []corev1.Container{{
+				Name: "main",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{gpuResource: *resource.NewQuantity(gpus, resource.DecimalSI)},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+}
+
+func TestValidationPriority(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*corev1.Node{
+		labelledNode("train-0", map[string]string{DefaultPoolLabel: "train", DefaultNVLinkDomainLabel: "nvl-a"}),
+		labelledNode("train-1", map[string]string{DefaultPoolLabel: "train", DefaultNVLinkDomainLabel: "nvl-a"}),
+		labelledNode("train-2", map[string]string{DefaultPoolLabel: "train", DefaultNVLinkDomainLabel: "nvl-b"}),
+		labelledNode("infer-0", map[string]string{DefaultPoolLabel: "infer"}),
+		labelledNode("batch-0", map[string]string{DefaultPoolLabel: "batch"}),
+	}
+	byName := map[string]*corev1.Node{}
+	for _, n := range nodes {
+		byName[n.Name] = n
+	}
+	scheduled := pendingGPUPod("scheduled", "batch", 64, 100)
+	scheduled.Spec.NodeName = "batch-0"
+	scheduled.Status.Phase = corev1.PodRunning
+	client := fake.NewSimpleClientset(
+		pendingGPUPod("infer-job", "infer", 8, 100),
+		pendingGPUPod("batch-job", "batch", 16, 100),
+		pendingGPUPod("best-effort", "batch", 32, 0), // below MinPodPriority
+		scheduled,
+	)
+
+	cases := []struct {
+		name     string
+		criteria []string
+		weights  map[string]int
+		before   [][2]string // each pair: first before second
+	}{
+		{
+			name:     "pool weight first",
+			criteria: []string{PriorityPool, PriorityPendingJobs},
+			weights:  map[string]int{"infer": 10, "train": 5},
+			before:   [][2]string{{"infer-0", "train-0"}, {"train-0", "batch-0"}},
+		},
+		{
+			name:     "pending jobs",
+			criteria: []string{PriorityPendingJobs},
+			before:   [][2]string{{"batch-0", "infer-0"}, {"infer-0", "train-0"}},
+		},
+		{
+			name:     "larger NVLink domain first",
+			criteria: []string{PriorityNVLinkDomain},
+			before:   [][2]string{{"train-0", "train-2"}},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &ValidationPriority{Criteria: tc.criteria, PoolWeights: tc.weights, MinPodPriority: 1}
+			if err := p.Refresh(context.Background(), client, nodes); err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+			for _, pair := range tc.before {
+				a, b := byName[pair[0]], byName[pair[1]]
+				if !p.Before(a, b) || p.Before(b, a) {
+					t.Errorf("Before(%s, %s) = %v, Before(%s, %s) = %v; want %s first",
+						a.Name, b.Name, p.Before(a, b), b.Name, a.Name, p.Before(b, a), a.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePriorityCriteria(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParsePriorityCriteria("pending-jobs, pool")
+	if err != nil || len(got) != 2 || got[0] != PriorityPendingJobs || got[1] != PriorityPool {
+		t.Errorf("ParsePriorityCriteria = %v, %v; want [pending-jobs pool]", got, err)
+	}
+	if _, err := ParsePriorityCriteria("pool,age"); err == nil {
+		t.Error("unknown criterion accepted")
+	}
+	if _, err := ParsePoolWeights("infer=10,train"); err == nil {
+		t.Error("pool weight without a value accepted")
+	}
+}
+
+func TestPulseDispatchPriority(t *testing.T) {
+	t.Parallel()
+
+	p := &ValidationPriority{Criteria: []string{PriorityPool}, PoolWeights: map[string]int{"infer": 10, "train": 5}}
+	d := newDispatcher(DispatchConfig{Total: 1, Order: p.Before})
+	hold, err := d.acquire(context.Background(), freshNode("gpu-node-0", time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, pool := range []string{"batch", "train", "infer"} {
+		node := labelledNode(pool+"-0", map[string]string{DefaultPoolLabel: pool})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := d.acquire(context.Background(), node)
+			if err != nil {
+				t.Errorf("acquire(%s): %v", node.Name, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, node.Name)
+			mu.Unlock()
+			release()
+		}()
+		// Queue in arrival order, lowest priority first.
+		for queued := 0; queued <= i; {
+			time.Sleep(time.Millisecond)
+			d.mu.Lock()
+			queued = len(d.queue)
+			d.mu.Unlock()
+		}
+	}
+	hold()
+	wg.Wait()
+
+	want := []string{"infer-0", "train-0", "batch-0"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("dispatch order = %v, want %v", order, want)
+		}
+	}
+}
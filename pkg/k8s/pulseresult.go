@@ -0,0 +1,188 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// PulseResultGVR is the PulseResult custom resource defined in
+// deploy/crd-pulseresult.yaml. PulseResults are cluster-scoped, like Nodes.
+var PulseResultGVR = schema.GroupVersionResource{Group: "straggler-shield.io", Version: "v1alpha1", Resource: "pulseresults"}
+
+// PulseResultNodeLabel names the node a PulseResult belongs to, so
+// `kubectl get pulseresults -l straggler-shield.io/node=NODE` lists its history.
+// A node name longer than a label value allows is shortened (see
+// nodeLabelValue); Spec.NodeName always holds it in full.
+const PulseResultNodeLabel = "straggler-shield.io/node"
+
+// PulseResult outcomes, in Spec.Result and the straggler-shield.io/result label.
+const (
+	ResultPassed   = "Passed"
+	ResultDegraded = "Degraded" // passed within the degraded band (near_threshold)
+	ResultFailed   = "Failed"
+)
+
+const pulseResultOutcomeLabel = "straggler-shield.io/result"
+
+// PulseResult is the durable record of one pulse, written by the controller
+// so evidence outlives the agent's logs.
+type PulseResult struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              PulseResultSpec `json:"spec"`
+}
+
+// PulseResultSpec summarises the pulse for printer columns and selectors and
+// carries the full report.
+type PulseResultSpec struct {
+	NodeName  string    `json:"nodeName"`
+	PulseID   string    `json:"pulseID"`
+	StartedAt time.Time `json:"startedAt"`
+	Result    string    `json:"result"`
+	// Reason is the failure_reason; empty for a pass.
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// WorstMean is the slowest device mean as a duration string.
+	WorstMean string             `json:"worstMean"`
+	Report    *pulse.PulseReport `json:"report"`
+}
+
+// WithPulseResults writes a PulseResult for every fresh pulse and keeps the
+// newest keep per node, deleting older ones. The record is owned by the Node,
+// so it is garbage-collected with it. Writes are best effort: a failure is
+// logged and never affects the verdict.
+func WithPulseResults(client dynamic.Interface, keep int) Option {
+	return func(c *Controller) {
+		c.sinks = append(c.sinks, func(ctx context.Context, node *corev1.Node, report *pulse.PulseReport, reason string, err error) {
+			c.writePulseResult(ctx, client, keep, node, report, reason, err)
+		})
+	}
+}
+
+func (c *Controller) writePulseResult(ctx context.Context, client dynamic.Interface, keep int, node *corev1.Node, report *pulse.PulseReport, reason string, err error) {
+	obj, merr := newPulseResult(node, report, reason, err)
+	if merr != nil {
+		c.logger.Warn("failed to build PulseResult", "node", node.Name, "pulse_id", report.PulseID, "err", merr)
+		return
+	}
+	results := client.Resource(PulseResultGVR)
+	if _, cerr := results.Create(ctx, obj, metav1.CreateOptions{}); cerr != nil {
+		c.logger.Warn("failed to write PulseResult", "node", node.Name, "pulse_id", report.PulseID, "err", cerr)
+		return
+	}
+
+	list, lerr := results.List(ctx, metav1.ListOptions{LabelSelector: PulseResultNodeLabel + "=" + nodeLabelValue(node.Name)})
+	if lerr != nil {
+		c.logger.Warn("failed to list PulseResults for pruning", "node", node.Name, "err", lerr)
+		return
+	}
+	items := list.Items
+	if len(items) <= keep {
+		return
+	}
+	startedAt := func(u *unstructured.Unstructured) string {
+		s, _, _ := unstructured.NestedString(u.Object, "spec", "startedAt")
+		return s
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if a, b := startedAt(&items[i]), startedAt(&items[j]); a != b {
+			t1, _ := time.Parse(time.RFC3339Nano, a)
+			t2, _ := time.Parse(time.RFC3339Nano, b)
+			return t1.Before(t2)
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+	for _, old := range items[:len(items)-keep] {
+		if derr := results.Delete(ctx, old.GetName(), metav1.DeleteOptions{}); derr != nil {
+			c.logger.Warn("failed to prune PulseResult", "node", node.Name, "name", old.GetName(), "err", derr)
+		}
+	}
+}
+
+// newPulseResult builds the PulseResult for report as an unstructured object.
+func newPulseResult(node *corev1.Node, report *pulse.PulseReport, reason string, err error) (*unstructured.Unstructured, error) {
+	result, message := ResultPassed, passEvidence(report)
+	switch {
+	case reason == "near_threshold":
+		result, message = ResultDegraded, err.Error()
+	case err != nil:
+		_, logReason := classify(err)
+		result, message = ResultFailed, failureEvidence(logReason, report, err)
+	}
+	started := report.StartedAt
+	if started.IsZero() {
+		started = time.Now().UTC()
+	}
+
+	pr := PulseResult{
+		TypeMeta: metav1.TypeMeta{APIVersion: PulseResultGVR.GroupVersion().String(), Kind: "PulseResult"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: pulseResultName(node.Name, report.PulseID),
+			Labels: map[string]string{
+				PulseResultNodeLabel:    nodeLabelValue(node.Name),
+				pulseResultOutcomeLabel: result,
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1", Kind: "Node", Name: node.Name, UID: node.UID,
+			}},
+		},
+		Spec: PulseResultSpec{
+			NodeName:  node.Name,
+			PulseID:   report.PulseID,
+			StartedAt: started,
+			Result:    result,
+			Reason:    reason,
+			Message:   message,
+			WorstMean: report.Elapsed().String(),
+			Report:    report,
+		},
+	}
+	if node.UID == "" {
+		pr.OwnerReferences = nil
+	}
+	data, merr := json.Marshal(pr)
+	if merr != nil {
+		return nil, fmt.Errorf("marshal PulseResult: %w", merr)
+	}
+	obj := &unstructured.Unstructured{}
+	if uerr := obj.UnmarshalJSON(data); uerr != nil {
+		return nil, fmt.Errorf("decode PulseResult: %w", uerr)
+	}
+	return obj, nil
+}
+
+// pulseResultName is <node>-<first 12 characters of the pulse ID>, with the
+// node name shortened to keep the whole within the 253-character limit.
+func pulseResultName(nodeName, pulseID string) string {
+	if len(pulseID) > 12 {
+		pulseID = pulseID[:12]
+	}
+	if limit := 253 - len(pulseID) - 1; len(nodeName) > limit {
+		nodeName = nodeName[:limit]
+	}
+	return nodeName + "-" + pulseID
+}
+
+// nodeLabelValue returns nodeName if it fits in a label value, and otherwise
+// its first 54 characters followed by a hash of the whole name.
+func nodeLabelValue(nodeName string) string {
+	if len(nodeName) <= 63 {
+		return nodeName
+	}
+	h := fnv.New32a()
+	h.Write([]byte(nodeName))
+	return fmt.Sprintf("%s-%08x", strings.TrimRight(nodeName[:54], ".-"), h.Sum32())
+}
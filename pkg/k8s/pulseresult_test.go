@@ -0,0 +1,98 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPulseResults(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", time.Minute)
+	node.UID = "0b5e7c1a"
+	client := fake.NewSimpleClientset(node)
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{PulseResultGVR: "PulseResultList"})
+
+	outcomes := []error{nil, pulse.ErrHighVariance, nil}
+	next := 0
+	ctrl := NewController(client,
+		WithNodeReport(func(ctx context.Context, _ string) (*pulse.PulseReport, error) {
+			err := outcomes[next]
+			next++
+			return &pulse.PulseReport{
+				PulseID:     pulse.PulseIDFrom(ctx),
+				StartedAt:   time.Now().Add(time.Duration(next) * time.Second).UTC(),
+				WorstMeanNS: (40 * time.Millisecond).Nanoseconds(),
+				Devices:     []pulse.DeviceResult{{Device: 0, MeanNS: (40 * time.Millisecond).Nanoseconds(), CV: 0.3}},
+			}, err
+		}),
+		WithPulseResults(dyn, 2),
+	)
+	for range outcomes {
+		if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+			t.Fatalf("ValidateNode: %v", err)
+		}
+	}
+
+	list, err := dyn.Resource(PulseResultGVR).List(context.Background(), metav1.ListOptions{LabelSelector: PulseResultNodeLabel + "=" + node.Name})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list.Items) != 2 {
+		t.Fatalf("PulseResults = %d, want the newest 2", len(list.Items))
+	}
+	var results []PulseResult
+	for _, item := range list.Items {
+		data, _ := item.MarshalJSON()
+		var pr PulseResult
+		if err := json.Unmarshal(data, &pr); err != nil {
+			t.Fatalf("decode PulseResult: %v", err)
+		}
+		results = append(results, pr)
+	}
+
+	var failed *PulseResult
+	for i := range results {
+		pr := &results[i]
+		if pr.Spec.Report == nil || len(pr.Spec.Report.Devices) != 1 || pr.Spec.NodeName != node.Name {
+			t.Errorf("PulseResult %s spec = %+v", pr.Name, pr.Spec)
+		}
+		if len(pr.OwnerReferences) != 1 || pr.OwnerReferences[0].UID != node.UID {
+			t.Errorf("PulseResult %s owners = %+v, want the Node", pr.Name, pr.OwnerReferences)
+		}
+		if pr.Spec.Result == ResultFailed {
+			failed = pr
+		}
+	}
+	if failed == nil {
+		t.Fatal("failing pulse was pruned; want the newest two kept")
+	}
+	if failed.Spec.Reason != "high_variance" || !strings.Contains(failed.Spec.Message, "fail-slow variance") || failed.Labels[pulseResultOutcomeLabel] != ResultFailed {
+		t.Errorf("failed PulseResult = %+v", failed)
+	}
+}
+
+func TestNodeLabelValue(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("gpu-node.", 10)
+	if got := nodeLabelValue("gpu-node-0"); got != "gpu-node-0" {
+		t.Errorf("short name = %q, want it unchanged", got)
+	}
+	got := nodeLabelValue(long)
+	if len(got) > 63 || got == nodeLabelValue(long+"x") {
+		t.Errorf("long name = %q, want at most 63 characters and distinct per node", got)
+	}
+}
@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// remediationTimeout bounds a remediation action. A GPU reset takes
+// seconds; a driver reload on a loaded HGX system can take a minute.
+const remediationTimeout = 2 * time.Minute
+
+// eventRemediated is the Normal Event on a node whose failure a remediation
+// cleared.
+const eventRemediated = "Remediated"
+
+// RemediateFunc tries to clear the fault behind a failed pulse on nodeName,
+// for example by resetting its GPUs (see pulse.Remediate).
+type RemediateFunc func(ctx context.Context, nodeName string) error
+
+// remediation is the action tried on a failed pulse; see WithRemediation.
+type remediation struct {
+	action string
+	fn     RemediateFunc
+}
+
+// WithRemediation runs fn when a fresh pulse fails with a verdict the policy
+// would quarantine, then pulses the node again. Many fail-slow states clear
+// after a GPU reset: if the re-run passes the node is never quarantined. If
+// it fails, its failure is acted on, and the condition message records the
+// attempt. action names fn in reports, logs and metrics, e.g.
+// pulse.RemediationGPUReset. Inconclusive and cached results are never
+// remediated.
+func WithRemediation(action string, fn RemediateFunc) Option {
+	return func(c *Controller) { c.remediation = &remediation{action: action, fn: fn} }
+}
+
+// remediable reports whether a pulse result should be remediated before it
+// is applied.
+func (c *Controller) remediable(node *corev1.Node, report *pulse.PulseReport, cached bool, err error) bool {
+	if c.remediation == nil || err == nil || cached || pulse.IsInconclusive(err) {
+		return false
+	}
+	promReason, _ := classify(err)
+	sev, _ := c.policy.Decide(promReason, node, report)
+	return sev == policy.SeverityQuarantine
+}
+
+// remediate runs the remediation for node's failed pulse and pulses it
+// again, returning the result to apply with its Remediation set. If the
+// action fails or the re-run gives no verdict, the original failure is
+// returned instead.
+func (c *Controller) remediate(ctx context.Context, node *corev1.Node, report *pulse.PulseReport, err error) (*pulse.PulseReport, error) {
+	rem := &pulse.Remediation{Action: c.remediation.action, FailedPulseID: report.PulseID, FailedError: err.Error()}
+	c.logger.Warn("GPU pulse failed — remediating before acting on it",
+		"node_name", node.Name, "pulse_id", report.PulseID, "action", rem.Action, "err", err)
+
+	rctx, cancel := context.WithTimeout(ctx, remediationTimeout)
+	rerr := c.remediation.fn(rctx, node.Name)
+	cancel()
+	if rerr != nil {
+		return c.remediationFailed(node, report, err, rem, fmt.Errorf("%s failed: %w", rem.Action, rerr))
+	}
+
+	// The failure is cached; the re-run must not reuse it.
+	c.cache.forget(node.Name)
+	retry, _, retryErr := c.pulseOrCached(ctx, node)
+	switch {
+	case retry == nil:
+		return c.remediationFailed(node, report, err, rem, fmt.Errorf("re-run not started: %w", retryErr))
+	case pulse.IsInconclusive(retryErr):
+		return c.remediationFailed(node, report, err, rem, fmt.Errorf("re-run %s inconclusive: %w", retry.PulseID, retryErr))
+	}
+	retry.Remediation = rem
+	if retryErr != nil {
+		metrics.Remediations.WithLabelValues(rem.Action, "failed").Inc()
+		c.logger.Warn("GPU pulse still failing after remediation",
+			"node_name", node.Name, "pulse_id", retry.PulseID, "action", rem.Action, "err", retryErr)
+		return retry, retryErr
+	}
+	metrics.Remediations.WithLabelValues(rem.Action, "cleared").Inc()
+	c.logger.Info("remediation cleared the GPU pulse failure",
+		"node_name", node.Name, "pulse_id", retry.PulseID, "failed_pulse_id", rem.FailedPulseID, "action", rem.Action)
+	c.event(node, corev1.EventTypeNormal, eventRemediated,
+		fmt.Sprintf("%s cleared pulse %s failure: %s (pulse %s passed)", rem.Action, rem.FailedPulseID, rem.FailedError, retry.PulseID))
+	return retry, nil
+}
+
+// remediationFailed records a remediation that gave no verdict and returns
+// the original failure with it attached.
+func (c *Controller) remediationFailed(node *corev1.Node, report *pulse.PulseReport, err error, rem *pulse.Remediation, cause error) (*pulse.PulseReport, error) {
+	rem.Error = cause.Error()
+	report.Remediation = rem
+	metrics.Remediations.WithLabelValues(rem.Action, "error").Inc()
+	c.logger.Warn("remediation gave no verdict — acting on the original failure",
+		"node_name", node.Name, "pulse_id", report.PulseID, "action", rem.Action, "err", cause)
+	return report, err
+}
@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRemediation(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		pulses      []error // one per pulse, in order
+		actionErr   error
+		severities  map[string]policy.Severity
+		wantResult  string // "" = not remediated
+		wantTainted bool
+		wantMessage string
+	}{
+		{
+			name:       "reset clears the failure",
+			pulses:     []error{pulse.ErrHighVariance, nil},
+			wantResult: "cleared",
+		},
+		{
+			name:        "still failing after the reset",
+			pulses:      []error{pulse.ErrHighVariance, pulse.ErrStragglerDetected},
+			wantResult:  "failed",
+			wantTainted: true,
+			wantMessage: "still failing after gpu-reset (pulse p-1 failed first: " + pulse.ErrHighVariance.Error() + ")",
+		},
+		{
+			name:        "reset fails",
+			pulses:      []error{pulse.ErrHighVariance},
+			actionErr:   errors.New("GPU 0 is in use"),
+			wantResult:  "error",
+			wantTainted: true,
+			wantMessage: "remediation gpu-reset gave no verdict: gpu-reset failed: GPU 0 is in use",
+		},
+		{
+			name:        "re-run inconclusive",
+			pulses:      []error{pulse.ErrHighVariance, pulse.ErrInconclusive},
+			wantResult:  "error",
+			wantTainted: true,
+			wantMessage: "re-run p-2 inconclusive",
+		},
+		{
+			name:       "warn severity is not remediated",
+			pulses:     []error{pulse.ErrHighVariance},
+			severities: map[string]policy.Severity{"high_variance": policy.SeverityWarn},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node := freshNode("gpu-remediate", time.Minute)
+			client := fake.NewSimpleClientset(node)
+			recorder := record.NewFakeRecorder(10)
+			pulses, resets := 0, 0
+			ctrl := NewController(client,
+				WithNodeReport(func(context.Context, string) (*pulse.PulseReport, error) {
+					err := tc.pulses[pulses]
+					pulses++
+					return &pulse.PulseReport{PulseID: fmt.Sprintf("p-%d", pulses)}, err
+				}),
+				WithRemediation(pulse.RemediationGPUReset, func(context.Context, string) error {
+					resets++
+					return tc.actionErr
+				}),
+				WithPolicy(&policy.Policy{Severities: tc.severities}),
+				WithResultCache(time.Hour),
+				WithEventRecorder(recorder),
+			)
+			var before float64
+			if tc.wantResult != "" {
+				before = testutil.ToFloat64(metrics.Remediations.WithLabelValues(pulse.RemediationGPUReset, tc.wantResult))
+			}
+
+			if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+				t.Fatalf("ValidateNode: %v", err)
+			}
+			if pulses != len(tc.pulses) {
+				t.Errorf("ran %d pulses, want %d", pulses, len(tc.pulses))
+			}
+			if remediated := resets > 0; remediated != (tc.wantResult != "") {
+				t.Errorf("remediated = %v, want %v", remediated, tc.wantResult != "")
+			}
+			if tc.wantResult != "" {
+				if got := testutil.ToFloat64(metrics.Remediations.WithLabelValues(pulse.RemediationGPUReset, tc.wantResult)) - before; got != 1 {
+					t.Errorf("remediations{result=%q} rose by %v, want 1", tc.wantResult, got)
+				}
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tainted := findTaint(got, zombieTaintKey) != nil; tainted != tc.wantTainted {
+				t.Errorf("tainted = %v, want %v", tainted, tc.wantTainted)
+			}
+			if tc.wantMessage != "" {
+				if cond := StragglerCondition(got); cond == nil || !strings.Contains(cond.Message, tc.wantMessage) {
+					t.Errorf("condition = %+v, want a message with %q", cond, tc.wantMessage)
+				}
+			}
+			if tc.wantResult == "cleared" {
+				if ev := <-recorder.Events; !strings.HasPrefix(ev, "Normal Remediated gpu-reset cleared pulse p-1 failure") {
+					t.Errorf("event = %q, want a Remediated event", ev)
+				}
+			}
+		})
+	}
+}
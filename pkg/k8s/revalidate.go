@@ -0,0 +1,141 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ConsecutivePassesAnnotation counts passing revalidation pulses on a node that
+// is still quarantined. Kept on the node so a restart of the agent does not
+// reset the count; removed once the taint is cleared or a pulse fails.
+const ConsecutivePassesAnnotation = "straggler-shield.io/consecutive-passes"
+
+// QuarantineRevalidator re-pulses a quarantined node until it has passed
+// RequiredPasses times in a row, then clears the quarantine. Without it a
+// tainted node stays out of service until its next Ready transition, even if
+// the fault was transient (a thermal event that has since recovered).
+//
+// Checks start at Interval. Each failed pulse doubles the wait up to
+// MaxInterval so a node that is really broken is not burned continually;
+// a pass resets it to Interval so the confirming passes follow quickly.
+type QuarantineRevalidator struct {
+	// Revalidate runs one revalidation pulse and reports whether it passed;
+	// normally wraps Controller.RevalidateQuarantined behind the caller's
+	// per-node lock. err is reserved for API failures.
+	Revalidate func(ctx context.Context, nodeName string) (passed bool, err error)
+
+	Interval    time.Duration // wait between checks after a pass
+	MaxInterval time.Duration // backoff ceiling after repeated failures
+
+	Logger *slog.Logger
+}
+
+// Run revalidates nodeName until ctx is cancelled. Checks on a node that is
+// not quarantined only cost a Get.
+func (r *QuarantineRevalidator) Run(ctx context.Context, nodeName string) {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	wait := r.Interval
+	for sleepCtx(ctx, wait) {
+		passed, err := r.Revalidate(ctx, nodeName)
+		switch {
+		case err != nil:
+			logger.Error("quarantine revalidation failed", "node", nodeName, "err", err)
+			wait = min(wait*2, r.MaxInterval)
+		case !passed:
+			wait = min(wait*2, r.MaxInterval)
+			logger.Info("quarantined node still failing — backing off", "node", nodeName, "next_check", wait)
+		default:
+			wait = r.Interval
+		}
+	}
+}
+
+// RevalidateQuarantined pulses nodeName if it carries the quarantine taint and
+// clears the quarantine once requiredPasses consecutive pulses have passed.
+// Passes short of that are recorded in ConsecutivePassesAnnotation and leave
+// the taint in place. A failure is applied like any other (including soft
+// quarantine escalation) and restarts the count. A node that is not
+// quarantined by straggler-shield (no taint, or a taint without a
+// GPUStraggler=True condition) is reported as passed without running a pulse.
+func (c *Controller) RevalidateQuarantined(ctx context.Context, nodeName string, requiredPasses int) (passed bool, err error) {
+	ctx, timing := beginReconcile(ctx)
+	defer c.endReconcile(nodeName, timing)
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("get node %s: %w", nodeName, err)
+	}
+	if !c.quarantineMarked(node) || !quarantineRecorded(node) {
+		return true, nil // not quarantined, or a marker straggler-shield did not write
+	}
+
+	c.logger.Info("revalidating quarantined node", "node", nodeName)
+	report, cached, pulseErr := c.pulseOrCached(ctx, node)
+	if errors.Is(pulseErr, ErrNotDispatched) {
+		return false, pulseErr
+	}
+	if pulseErr != nil {
+		return false, c.apply(ctx, node, report, cached, pulseErr)
+	}
+	if cached {
+		// Not an independent observation; it must not count twice.
+		return true, nil
+	}
+
+	passes := consecutivePasses(node) + 1
+	if passes < requiredPasses {
+		c.logger.Info("quarantined node passed — holding taint until enough consecutive passes",
+			"node", nodeName, "pulse_id", report.PulseID, "passes", passes, "required", requiredPasses)
+		c.setConsecutivePasses(ctx, node, passes)
+		return true, nil
+	}
+	return true, c.apply(ctx, node, report, cached, nil)
+}
+
+// consecutivePasses reads ConsecutivePassesAnnotation; missing or malformed
+// values count as zero.
+func consecutivePasses(node *corev1.Node) int {
+	n, err := strconv.Atoi(node.Annotations[ConsecutivePassesAnnotation])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// setConsecutivePasses writes n to ConsecutivePassesAnnotation, removing it
+// for zero. Best effort: a lost write only delays or repeats a clearance.
+func (c *Controller) setConsecutivePasses(ctx context.Context, node *corev1.Node, n int) {
+	c.setCounter(ctx, node, ConsecutivePassesAnnotation, n)
+}
+
+// setCounter writes n to the count annotation key on node, removing it for
+// zero. Failures are logged, not returned.
+func (c *Controller) setCounter(ctx context.Context, node *corev1.Node, key string, n int) {
+	var value *string
+	if n > 0 {
+		s := strconv.Itoa(n)
+		value = &s
+	} else if _, ok := node.Annotations[key]; !ok {
+		return
+	}
+	patch, _ := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]*string{key: value}},
+	})
+	if _, err := c.client.CoreV1().Nodes().Patch(
+		ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{},
+	); err != nil {
+		c.logger.Warn("failed to record count annotation", "node", node.Name, "annotation", key, "err", err)
+	}
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRevalidateQuarantinedRequiresConsecutivePasses(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		results    []error // one pulse result per revalidation
+		wantTaint  bool
+		wantPasses string // ConsecutivePassesAnnotation afterwards; "" = absent
+	}{
+		{
+			name:       "single pass holds the taint",
+			results:    []error{nil},
+			wantTaint:  true,
+			wantPasses: "1",
+		},
+		{
+			name:      "enough consecutive passes clear the taint",
+			results:   []error{nil, nil, nil},
+			wantTaint: false,
+		},
+		{
+			name:       "failure restarts the count",
+			results:    []error{nil, nil, pulse.ErrStragglerDetected, nil},
+			wantTaint:  true,
+			wantPasses: "1",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(quarantinedNode("gpu-node-0", time.Hour))
+			call := 0
+			ctrl := newControllerWithPulse(client, func() (time.Duration, error) {
+				err := tc.results[call]
+				call++
+				return 10 * time.Millisecond, err
+			})
+
+			for i, want := range tc.results {
+				passed, err := ctrl.RevalidateQuarantined(context.Background(), "gpu-node-0", 3)
+				if err != nil {
+					t.Fatalf("revalidation %d: %v", i, err)
+				}
+				if passed != (want == nil) {
+					t.Errorf("revalidation %d: passed=%v, want %v", i, passed, want == nil)
+				}
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), "gpu-node-0", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get node: %v", err)
+			}
+			if hasTaint := findTaint(got, zombieTaintKey) != nil; hasTaint != tc.wantTaint {
+				t.Errorf("hasTaint=%v, want %v", hasTaint, tc.wantTaint)
+			}
+			if p := got.Annotations[ConsecutivePassesAnnotation]; p != tc.wantPasses {
+				t.Errorf("%s=%q, want %q", ConsecutivePassesAnnotation, p, tc.wantPasses)
+			}
+		})
+	}
+}
+
+func TestRevalidateQuarantinedSkipsHealthyNode(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Hour))
+	ctrl := newControllerWithPulse(client, func() (time.Duration, error) {
+		t.Error("pulse ran on a node that is not quarantined")
+		return 0, nil
+	})
+	passed, err := ctrl.RevalidateQuarantined(context.Background(), "gpu-node-0", 3)
+	if err != nil || !passed {
+		t.Errorf("RevalidateQuarantined = %v, %v; want true, nil", passed, err)
+	}
+}
@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+)
+
+// RiskScoreAnnotation holds the node's straggler risk score from its last
+// fresh pulse, a decimal from 0.00 to 1.00 (see pulse.Risk). Unlike the taint
+// and the degraded label it is never acted on here: it is for schedulers and
+// capacity tools that make soft decisions, such as preferring low-risk nodes
+// for long jobs or draining high-risk ones first.
+const RiskScoreAnnotation = "straggler-shield.io/risk-score"
+
+// recordRisk sets the node's RiskScore gauge and adds RiskScoreAnnotation to
+// annotations. A pulse cut short by shutdown, or one that measured nothing
+// because the binary was built without CUDA, says nothing about the risk and
+// leaves both alone.
+func recordRisk(ctx context.Context, nodeName string, report *pulse.PulseReport, err error, annotations map[string]string) {
+	if ctx.Err() != nil || errors.Is(err, pulse.ErrNoCUDA) {
+		return
+	}
+	score := pulse.Risk(report, err)
+	metrics.RiskScore.WithLabelValues(nodeName).Set(score)
+	annotations[RiskScoreAnnotation] = strconv.FormatFloat(score, 'f', 2, 64)
+}
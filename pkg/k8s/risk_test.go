@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func riskReport(mean time.Duration, cv float64, tempC, eccDelta int) *pulse.PulseReport {
+	return &pulse.PulseReport{
+		Thresholds:  pulse.Snapshot{StragglerThreshold: 20 * time.Millisecond, MaxCV: 0.2, MaxIdleTempC: 80},
+		WorstMeanNS: mean.Nanoseconds(),
+		Devices:     []pulse.DeviceResult{{Device: 0, MeanNS: mean.Nanoseconds(), CV: cv}},
+		GPUs:        []pulse.GPUReading{{Device: 0, TempC: tempC, ECCCorrectedDelta: eccDelta}},
+	}
+}
+
+func TestRecordRiskBoundaries(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		report *pulse.PulseReport
+		err    error
+		want   string
+	}{
+		{name: "at the floor of every limit", report: riskReport(10*time.Millisecond, 0.1, 40, 0), want: "0.00"},
+		{name: "just past the latency floor", report: riskReport(10*time.Millisecond+100*time.Microsecond, 0.1, 40, 0), want: "0.01"},
+		{name: "latency at the threshold", report: riskReport(20*time.Millisecond, 0.1, 40, 0), want: "1.00"},
+		{name: "latency past the threshold is clamped", report: riskReport(60*time.Millisecond, 0.1, 40, 0), want: "1.00"},
+		{name: "CV at the limit", report: riskReport(8*time.Millisecond, 0.2, 40, 0), want: "1.00"},
+		{name: "ECC growth at the limit", report: riskReport(8*time.Millisecond, 0.05, 40, 100), want: "1.00"},
+		{name: "unset thresholds score zero", report: &pulse.PulseReport{WorstMeanNS: int64(time.Second)}, want: "0.00"},
+		{name: "inconclusive pulse is scored from its measurements", report: riskReport(15*time.Millisecond, 0.05, 40, 0), err: pulse.ErrInconclusive, want: "0.50"},
+		{name: "failed pulse with no report", err: fmt.Errorf("GPU 0: %w", pulse.ErrStragglerDetected), want: "1.00"},
+	}
+	for i, tc := range cases {
+		i, tc := i, tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			nodeName := "gpu-node-risk-" + strconv.Itoa(i)
+			annotations := map[string]string{}
+			recordRisk(context.Background(), nodeName, tc.report, tc.err, annotations)
+			if got := annotations[RiskScoreAnnotation]; got != tc.want {
+				t.Errorf("annotation %q, want %q", got, tc.want)
+			}
+			if got := testutil.ToFloat64(metrics.RiskScore.WithLabelValues(nodeName)); strconv.FormatFloat(got, 'f', 2, 64) != tc.want {
+				t.Errorf("gauge %v, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRecordRiskOrdering(t *testing.T) {
+	t.Parallel()
+
+	// Each pulse is worse than the one before it, so the score must never
+	// fall from one to the next and must rise wherever a new signal enters
+	// the margin.
+	steps := []struct {
+		name   string
+		report *pulse.PulseReport
+		err    error
+	}{
+		{name: "healthy", report: riskReport(8*time.Millisecond, 0.05, 40, 0)},
+		{name: "slow", report: riskReport(13*time.Millisecond, 0.05, 40, 0)},
+		{name: "slower", report: riskReport(16*time.Millisecond, 0.05, 40, 0)},
+		{name: "slower and noisy", report: riskReport(16*time.Millisecond, 0.14, 40, 0)},
+		{name: "slower, noisy and hot", report: riskReport(16*time.Millisecond, 0.14, 60, 0)},
+		{name: "slower, noisy, hot and correcting", report: riskReport(16*time.Millisecond, 0.14, 60, 30)},
+		{name: "near-threshold pass", report: riskReport(19*time.Millisecond, 0.14, 60, 30), err: pulse.ErrNearThreshold},
+		{name: "failed", report: riskReport(25*time.Millisecond, 0.14, 60, 30), err: pulse.ErrStragglerDetected},
+	}
+	prev := -1.0
+	for _, step := range steps {
+		annotations := map[string]string{}
+		recordRisk(context.Background(), "gpu-node-risk-ordering", step.report, step.err, annotations)
+		score, err := strconv.ParseFloat(annotations[RiskScoreAnnotation], 64)
+		if err != nil {
+			t.Fatalf("%s: annotation %q: %v", step.name, annotations[RiskScoreAnnotation], err)
+		}
+		if score <= prev && score < 1 {
+			t.Errorf("%s: score %v, want above the previous %v", step.name, score, prev)
+		}
+		prev = score
+	}
+	if prev != 1 {
+		t.Errorf("failed pulse scored %v, want 1", prev)
+	}
+}
+
+func TestRecordRiskLeavesScoreAlone(t *testing.T) {
+	t.Parallel()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	cases := []struct {
+		name string
+		ctx  context.Context
+		err  error
+	}{
+		{name: "pulse cut short by shutdown", ctx: cancelled, err: context.Canceled},
+		{name: "binary built without CUDA", ctx: context.Background(), err: pulse.ErrNoCUDA},
+	}
+	for _, tc := range cases {
+		annotations := map[string]string{RiskScoreAnnotation: "0.25"}
+		recordRisk(tc.ctx, "gpu-node-risk-untouched", riskReport(40*time.Millisecond, 0.5, 90, 500), tc.err, annotations)
+		if got := annotations[RiskScoreAnnotation]; got != "0.25" {
+			t.Errorf("%s: annotation changed to %q", tc.name, got)
+		}
+	}
+}
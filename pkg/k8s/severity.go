@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DegradedLabel marks a node whose failure was policy-mapped to degrade or
+// degrade-label. The value is the failure reason code. The degraded tier also
+// applies a PreferNoSchedule taint under the same key.
+const DegradedLabel = "straggler-shield.io/degraded"
+
+// recordWarning handles a failure whose policy severity is warn: the node
+// stays schedulable and untainted, and the failure is recorded in the
+// GPUStraggler condition with status False so tooling keyed on
+// GPUStraggler=True does not treat it as quarantined. An earlier quarantine
+// is lifted (see downgrade).
+func (c *Controller) recordWarning(ctx context.Context, node *corev1.Node, reason string, cause error) error {
+	if err := c.downgrade(ctx, node, reason, nil); err != nil {
+		return err
+	}
+	return c.patchCondition(ctx, node, corev1.NodeCondition{
+		Type:               zombieCondition,
+		Status:             corev1.ConditionFalse,
+		Reason:             "WarnOnly",
+		Message:            fmt.Sprintf("%s (policy severity warn, not quarantined): %v", reason, cause),
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// applyDegradedLabel handles a failure whose policy severity is degrade-label:
+// the node is labelled with the reason and the condition records it, but no
+// taint is applied. An earlier quarantine is lifted (see downgrade).
+func (c *Controller) applyDegradedLabel(ctx context.Context, node *corev1.Node, reason string, cause error) error {
+	return c.applyDegraded(ctx, node, reason, cause, nil)
+}
+
+// applyDegradedTier handles a failure whose policy severity is degrade: the
+// degraded label plus a PreferNoSchedule taint, steering new large jobs away
+// without evicting capacity. An earlier quarantine is lifted in the same
+// patch that adds the taint (see downgrade).
+func (c *Controller) applyDegradedTier(ctx context.Context, node *corev1.Node, reason string, cause error) error {
+	return c.applyDegraded(ctx, node, reason, cause, &corev1.Taint{
+		Key:    DegradedLabel,
+		Value:  reason,
+		Effect: corev1.TaintEffectPreferNoSchedule,
+	})
+}
+
+// applyDegraded labels node degraded for reason, adding taint unless a
+// degraded taint is already present, and records the condition.
+func (c *Controller) applyDegraded(ctx context.Context, node *corev1.Node, reason string, cause error, taint *corev1.Taint) error {
+	if err := c.downgrade(ctx, node, reason, taint); err != nil {
+		return err
+	}
+	if node.Labels[DegradedLabel] != reason {
+		if err := c.patchLabels(ctx, node.Name, map[string]*string{DegradedLabel: &reason}); err != nil {
+			return err
+		}
+	}
+	return c.patchCondition(ctx, node, corev1.NodeCondition{
+		Type:               zombieCondition,
+		Status:             corev1.ConditionFalse,
+		Reason:             "Degraded",
+		Message:            fmt.Sprintf("%s (degraded, not quarantined): %v", reason, cause),
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// downgrade lifts a quarantine straggler-shield holds on node before a
+// failure the policy maps below quarantine is recorded: the quarantine taint,
+// when GPUStraggler=True records it as ours, and any cordon straggler-shield
+// set. Left in place, the taint would sit behind the False condition written
+// next, and clearBlocked would take it for someone else's. add, if non-nil
+// and not already present by key, is applied in the same patch, together
+// with a clear decision when anything was lifted.
+func (c *Controller) downgrade(ctx context.Context, node *corev1.Node, reason string, add *corev1.Taint) error {
+	tainted := quarantineRecorded(node) && findTaintByKey(node.Spec.Taints, c.taint.Key) != nil
+	_, cordoned := node.Annotations[CordonedAnnotation]
+	adding := add != nil && findTaintByKey(node.Spec.Taints, add.Key) == nil
+	if !tainted && !cordoned && !adding {
+		return nil
+	}
+
+	var edit func([]corev1.Taint) []corev1.Taint
+	var spec, meta map[string]any
+	if tainted || adding {
+		edit = func(taints []corev1.Taint) []corev1.Taint {
+			if tainted {
+				taints = removeTaintByKey(taints, c.taint.Key)
+			}
+			if add != nil && findTaintByKey(taints, add.Key) == nil {
+				taints = append(taints, *add)
+			}
+			return taints
+		}
+	}
+	if tainted || cordoned {
+		annotations := decisionAnnotations(opClear, reason, 0, reason+" is mapped below quarantine by the policy; quarantine lifted")
+		if cordoned {
+			spec = map[string]any{"unschedulable": false}
+			annotations[CordonedAnnotation] = nil
+		}
+		meta = map[string]any{"annotations": annotations}
+	}
+	if err := patchNodeSpec(ctx, c.client, node, edit, spec, meta); err != nil {
+		return fmt.Errorf("patch node spec: %w", err)
+	}
+	if tainted || cordoned {
+		c.clearQuarantined(node.Name)
+		c.logger.Info("quarantine lifted — failure mapped below quarantine", "node_name", node.Name, "failure_reason", reason)
+	}
+	return nil
+}
+
+// clearDegraded strips the degraded label and PreferNoSchedule taint after a
+// passing pulse. Idempotent.
+func (c *Controller) clearDegraded(ctx context.Context, node *corev1.Node) error {
+	if findTaintByKey(node.Spec.Taints, DegradedLabel) != nil {
+		// patchTaints updates node, so removeTaint, which edits the same
+		// taint list next, starts from what was written.
+		if err := c.patchTaints(ctx, node, func(taints []corev1.Taint) []corev1.Taint {
+			return removeTaintByKey(taints, DegradedLabel)
+		}); err != nil {
+			return err
+		}
+	}
+	if _, ok := node.Labels[DegradedLabel]; !ok {
+		return nil
+	}
+	return c.patchLabels(ctx, node.Name, map[string]*string{DegradedLabel: nil})
+}
+
+// patchTaints sets the node's taint list to edit(current taints); see
+// patchNodeSpec.
+func (c *Controller) patchTaints(ctx context.Context, node *corev1.Node, edit func([]corev1.Taint) []corev1.Taint) error {
+	if err := patchNodeSpec(ctx, c.client, node, edit, nil, nil); err != nil {
+		return fmt.Errorf("patch node spec: %w", err)
+	}
+	return nil
+}
+
+func findTaintByKey(taints []corev1.Taint, key string) *corev1.Taint {
+	for i := range taints {
+		if taints[i].Key == key {
+			return &taints[i]
+		}
+	}
+	return nil
+}
+
+// patchLabels merge-patches node labels; a nil value deletes the label.
+func (c *Controller) patchLabels(ctx context.Context, nodeName string, labels map[string]*string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"labels": labels},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal label patch: %w", err)
+	}
+	if _, err := c.client.CoreV1().Nodes().Patch(
+		ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{},
+	); err != nil {
+		return fmt.Errorf("patch node labels: %w", err)
+	}
+	return nil
+}
+
+// patchCondition upserts a single condition in the node status subresource.
+func (c *Controller) patchCondition(ctx context.Context, node *corev1.Node, cond corev1.NodeCondition) error {
+	type statusPatch struct {
+		Status struct {
+			Conditions []corev1.NodeCondition `json:"conditions"`
+		} `json:"status"`
+	}
+	st := statusPatch{}
+	st.Status.Conditions = upsertCondition(node.Status.Conditions, cond)
+	statusBytes, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("marshal status patch: %w", err)
+	}
+	if _, err := c.client.CoreV1().Nodes().Patch(
+		ctx, node.Name, types.MergePatchType, statusBytes,
+		metav1.PatchOptions{}, "status",
+	); err != nil {
+		return fmt.Errorf("patch node status: %w", err)
+	}
+	return nil
+}
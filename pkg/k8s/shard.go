@@ -0,0 +1,192 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// ErrLeadershipLost is returned by ShardElection.Run when the replica stops
+// renewing its shard lease before ctx is cancelled. Another replica may
+// already be reconciling the shard, so the caller should stop and exit.
+var ErrLeadershipLost = errors.New("shard lease lost")
+
+// shardPoints is the number of points each shard places on the ring. More
+// points even out shard sizes; 128 keeps each within a few percent of
+// nodes/shards for fleets of thousands of nodes.
+const shardPoints = 128
+
+// ShardRing assigns node names to shards by consistent hashing. Every
+// replica built with the same shard count agrees on the assignment without
+// coordination, and changing the count moves only about 1/shards of the
+// nodes.
+type ShardRing struct {
+	shards int
+	points []uint64
+	owner  []int // owner[i] is the shard at points[i]
+}
+
+// NewShardRing returns a ring over shards shards. shards < 1 is treated as 1.
+func NewShardRing(shards int) *ShardRing {
+	shards = max(shards, 1)
+	r := &ShardRing{shards: shards}
+	type point struct {
+		hash  uint64
+		shard int
+	}
+	pts := make([]point, 0, shards*shardPoints)
+	for s := 0; s < shards; s++ {
+		for v := 0; v < shardPoints; v++ {
+			pts = append(pts, point{hashKey("shard-" + strconv.Itoa(s) + "-" + strconv.Itoa(v)), s})
+		}
+	}
+	sort.Slice(pts, func(i, j int) bool { return pts[i].hash < pts[j].hash })
+	for _, p := range pts {
+		r.points = append(r.points, p.hash)
+		r.owner = append(r.owner, p.shard)
+	}
+	return r
+}
+
+// Shards returns the number of shards on the ring.
+func (r *ShardRing) Shards() int { return r.shards }
+
+// Shard returns the shard that owns nodeName: the first ring point at or
+// after the name's hash, wrapping around.
+func (r *ShardRing) Shard(nodeName string) int {
+	h := hashKey(nodeName)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owner[i]
+}
+
+// Owns returns a predicate reporting whether shard owns a node, for
+// NodeIndexConfig.Owns.
+func (r *ShardRing) Owns(shard int) func(nodeName string) bool {
+	return func(nodeName string) bool { return r.Shard(nodeName) == shard }
+}
+
+// hashKey hashes s onto the ring. FNV alone leaves names that differ only in
+// a trailing index (gpu-node-17, gpu-node-18) close together, so its output
+// is run through the MurmurHash3 finalizer to spread them.
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// WithShard labels the controller's backlog and throughput metrics
+// (ValidationsPending, Validations) with shard, the shard this replica
+// reconciles in sharded central mode.
+func WithShard(shard int) Option {
+	return func(c *Controller) {
+		c.shard = strconv.Itoa(shard)
+	}
+}
+
+// ShardLeaseName is the Lease a replica must hold to reconcile shard.
+func ShardLeaseName(shard int) string {
+	return fmt.Sprintf("straggler-shield-shard-%d", shard)
+}
+
+// ShardElection elects one replica per shard through a coordination.k8s.io
+// Lease, so a shard is never reconciled by two replicas at once, e.g. while
+// a StatefulSet pod is being replaced.
+type ShardElection struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Shard     int
+	// Identity names this replica in the Lease; the pod name.
+	Identity string
+	// LeaseDuration, RenewDeadline and RetryPeriod default to the
+	// client-go recommended 15s, 10s and 2s. The Lease stores its duration
+	// in whole seconds.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Run campaigns for the shard's Lease and calls lead once it is held, with a
+// context cancelled if the Lease is lost. It blocks until ctx is cancelled,
+// returning nil and releasing the Lease, or until leadership is lost,
+// returning ErrLeadershipLost. Either way lead has returned by then.
+func (e *ShardElection) Run(ctx context.Context, lead func(ctx context.Context)) error {
+	shard := strconv.Itoa(e.Shard)
+	// The elector starts OnStartedLeading in its own goroutine and does not
+	// wait for it; leading tracks it so Run returns only after lead does.
+	var (
+		mu      sync.Mutex
+		stopped bool
+		leading sync.WaitGroup
+	)
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: e.Namespace, Name: ShardLeaseName(e.Shard)},
+		Client:     e.Client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: e.Identity},
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            ShardLeaseName(e.Shard),
+		LeaseDuration:   orDefault(e.LeaseDuration, 15*time.Second),
+		RenewDeadline:   orDefault(e.RenewDeadline, 10*time.Second),
+		RetryPeriod:     orDefault(e.RetryPeriod, 2*time.Second),
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				mu.Lock()
+				if stopped {
+					mu.Unlock()
+					return
+				}
+				leading.Add(1)
+				mu.Unlock()
+				defer leading.Done()
+				metrics.ShardLeader.WithLabelValues(shard).Set(1)
+				lead(ctx)
+			},
+			OnStoppedLeading: func() {
+				metrics.ShardLeader.WithLabelValues(shard).Set(0)
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("shard %d election: %w", e.Shard, err)
+	}
+	metrics.ShardLeader.WithLabelValues(shard).Set(0)
+	elector.Run(ctx)
+	mu.Lock()
+	stopped = true
+	mu.Unlock()
+	leading.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("shard %d: %w", e.Shard, ErrLeadershipLost)
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,120 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShardRing(t *testing.T) {
+	t.Parallel()
+
+	const nodes = 5000
+	ring := NewShardRing(4)
+	sizes := make([]int, 4)
+	before := make([]int, nodes)
+	for i := range before {
+		before[i] = ring.Shard(fmt.Sprintf("gpu-node-%d", i))
+		sizes[before[i]]++
+	}
+	for shard, n := range sizes {
+		if n < nodes/4*7/10 || n > nodes/4*13/10 {
+			t.Errorf("shard %d owns %d of %d nodes, want about %d", shard, n, nodes, nodes/4)
+		}
+	}
+	if again := NewShardRing(4); again.Shard("gpu-node-17") != before[17] {
+		t.Error("rings with the same shard count disagree")
+	}
+
+	// Adding a fifth shard moves nodes only onto it.
+	grown := NewShardRing(5)
+	moved := 0
+	for i, was := range before {
+		if now := grown.Shard(fmt.Sprintf("gpu-node-%d", i)); now != was {
+			moved++
+			if now != 4 {
+				t.Fatalf("gpu-node-%d moved from shard %d to %d, want only moves to the new shard", i, was, now)
+			}
+		}
+	}
+	if moved > nodes*3/10 {
+		t.Errorf("%d of %d nodes moved on adding a shard, want about a fifth", moved, nodes)
+	}
+}
+
+func TestNodeIndexOwns(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		freshNode("gpu-node-0", time.Hour),
+		freshNode("gpu-node-1", time.Hour),
+		quarantinedNode("gpu-node-2", time.Hour),
+	)
+	index := NewNodeIndex(client, NodeIndexConfig{Owns: func(name string) bool { return name != "gpu-node-1" }})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go index.Run(ctx)
+	if !index.WaitForSync(ctx) {
+		t.Fatal("index never synced")
+	}
+
+	if nodes := index.Nodes(); len(nodes) != 2 || nodes[0].Name != "gpu-node-0" || nodes[1].Name != "gpu-node-2" {
+		t.Errorf("nodes = %v, want gpu-node-0 and gpu-node-2", nodes)
+	}
+	if ready := index.ByState(StateHealthy); len(ready) != 1 {
+		t.Errorf("healthy = %d nodes, want only the owned gpu-node-0", len(ready))
+	}
+}
+
+func TestShardElection(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset()
+	elect := func(identity string) *ShardElection {
+		return &ShardElection{
+			Client: client, Namespace: "straggler-shield", Shard: 2, Identity: identity,
+			// Lease durations are stored in whole seconds.
+			LeaseDuration: time.Second, RenewDeadline: 800 * time.Millisecond, RetryPeriod: 100 * time.Millisecond,
+		}
+	}
+
+	leading := make(chan string, 2)
+	lead := func(identity string) func(context.Context) {
+		return func(ctx context.Context) {
+			leading <- identity
+			<-ctx.Done()
+		}
+	}
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan error, 1)
+	go func() { doneA <- elect("central-a").Run(ctxA, lead("central-a")) }()
+	if got := <-leading; got != "central-a" {
+		t.Fatalf("%s leads, want central-a", got)
+	}
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go func() { _ = elect("central-b").Run(ctxB, lead("central-b")) }()
+	select {
+	case got := <-leading:
+		t.Fatalf("%s leads while central-a holds the lease", got)
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	// Stopping A releases the lease to B.
+	cancelA()
+	if err := <-doneA; err != nil {
+		t.Errorf("Run after cancel = %v, want nil", err)
+	}
+	select {
+	case got := <-leading:
+		if got != "central-b" {
+			t.Errorf("%s leads, want central-b", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("central-b never took over the released lease")
+	}
+}
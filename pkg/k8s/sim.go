@@ -0,0 +1,184 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SimFailureAnnotation selects the outcome of a simulated pulse on the node
+// (see SimulatedPulse): one of SimFailureClasses, or absent for a pass.
+const SimFailureAnnotation = "straggler-shield.io/sim-failure"
+
+// simFailures builds the error a simulated pulse returns for each failure
+// class, keyed by the reason code classify gives it. Values are scaled to th
+// so the evidence reads like a real pulse on the configured thresholds.
+var simFailures = map[string]func(th pulse.Snapshot) error{
+	"latency_threshold_exceeded": func(th pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:          fmt.Errorf("GPU 0: %w (mean=%v, simulated)", pulse.ErrStragglerDetected, 5*th.StragglerThreshold),
+			MeasuredValue:  float64((5 * th.StragglerThreshold).Milliseconds()),
+			ThresholdValue: float64(th.StragglerThreshold.Milliseconds()),
+			Unit:           "ms",
+		}
+	},
+	"clock_derated": func(th pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause: fmt.Errorf("%w: post-pulse GPU 0: %w: SM clock 600MHz below %.0f%% of max 1980MHz (simulated)",
+				pulse.ErrStragglerDetected, pulse.ErrClockDerated, th.MinClockFraction*100),
+			MeasuredValue:  float64((th.StragglerThreshold / 4).Milliseconds()),
+			ThresholdValue: float64(th.StragglerThreshold.Milliseconds()),
+			Unit:           "ms",
+		}
+	},
+	"high_variance": func(th pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:          fmt.Errorf("GPU 0: %w (cv=%.3f, simulated)", pulse.ErrHighVariance, 2*th.MaxCV),
+			MeasuredValue:  2 * th.MaxCV,
+			ThresholdValue: th.MaxCV,
+			Unit:           "cv",
+		}
+	},
+	"interconnect_degraded": func(th pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:          fmt.Errorf("GPU 0→1: %w (%.2f GB/s, simulated)", pulse.ErrInterconnectDegraded, th.MinP2PBandwidthGBs/4),
+			MeasuredValue:  th.MinP2PBandwidthGBs / 4,
+			ThresholdValue: th.MinP2PBandwidthGBs,
+			Unit:           "gbs",
+		}
+	},
+	"pcie_degraded": func(pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:          fmt.Errorf("pre-flight: %w: GPU 0: link x4 Gen4, expected x16 Gen4 (simulated)", pulse.ErrPCIeDegraded),
+			MeasuredValue:  4,
+			ThresholdValue: 16,
+			Unit:           "lanes",
+		}
+	},
+	"low_power": func(th pulse.Snapshot) error {
+		// An H100 SXM5 capped at half its floor below its 700W limit.
+		fraction := th.MinPowerFraction
+		if fraction <= 0 {
+			fraction = 0.3
+		}
+		floor := fraction * 700
+		return &pulse.PulseFailure{
+			Cause: fmt.Errorf("GPU 0: %w: peak draw %.0fW below %.0f%% of the 700W default limit (simulated)",
+				pulse.ErrLowPower, floor/2, 100*fraction),
+			MeasuredValue:  floor / 2,
+			ThresholdValue: floor,
+			Unit:           "w",
+		}
+	},
+	"near_threshold": func(th pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:          fmt.Errorf("GPU 0: %w (simulated)", pulse.ErrNearThreshold),
+			MeasuredValue:  float64((th.StragglerThreshold * 9 / 10).Milliseconds()),
+			ThresholdValue: float64(th.StragglerThreshold.Milliseconds()),
+			Unit:           "ms",
+		}
+	},
+	"inconclusive": func(th pulse.Snapshot) error {
+		floor := max(th.MinSMUtilization, 0.5)
+		return &pulse.PulseFailure{
+			Cause:          fmt.Errorf("GPU 0: %w (simulated)", pulse.ErrInconclusive),
+			MeasuredValue:  floor / 2,
+			ThresholdValue: floor,
+			Unit:           "util",
+		}
+	},
+	"stage_timeout": func(th pulse.Snapshot) error {
+		return fmt.Errorf("gemm_run: %w after %v (simulated)", pulse.ErrStageTimeout, th.GEMMRunTimeout)
+	},
+	"pulse_crashed": func(pulse.Snapshot) error {
+		return fmt.Errorf("%w: signal: segmentation fault (simulated)", pulse.ErrPulseCrashed)
+	},
+	"gpu_count_decreased": func(pulse.Snapshot) error {
+		return fmt.Errorf("%w: 7 visible, 8 at last passing pulse (simulated)", pulse.ErrDeviceCountDecreased)
+	},
+	"misconfiguration": func(pulse.Snapshot) error {
+		return fmt.Errorf("%w: 1 visible, node advertises 8 (simulated)", pulse.ErrGPUsNotVisible)
+	},
+	"external_check": func(pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause: fmt.Errorf("external check vendor-diag: %w: GPU 0: fabric test failed (simulated)", pulse.ErrExternalCheck),
+			Unit:  "check",
+		}
+	},
+	"ecc_errors": func(pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:         fmt.Errorf("pre-flight GPU 0: %w: 2 since last boot or clean pulse (simulated)", pulse.ErrECCErrors),
+			MeasuredValue: 2,
+			Unit:          "ecc",
+		}
+	},
+	"thermal_recovery": func(th pulse.Snapshot) error {
+		return fmt.Errorf("pre-flight GPU 0: %w: %d°C exceeds %d°C (thermal recovery incomplete, simulated)", pulse.ErrThermalRecovery, th.MaxIdleTempC+10, th.MaxIdleTempC)
+	},
+	"xid_error": func(pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:         fmt.Errorf("pre-flight GPU 0: %w: Xid 79 (simulated)", pulse.ErrXIDEvent),
+			MeasuredValue: 79,
+			Unit:          "xid",
+		}
+	},
+	"row_remap_failure": func(pulse.Snapshot) error {
+		return &pulse.PulseFailure{
+			Cause:         fmt.Errorf("pre-flight: %w: GPU 0: row remap pending until the GPU is reset; 1 uncorrectable, 0 correctable row(s) remapped (simulated)", pulse.ErrRowRemap),
+			MeasuredValue: 1,
+			Unit:          "rows",
+		}
+	},
+	"pre_flight_failure": func(pulse.Snapshot) error {
+		return fmt.Errorf("pre-flight: nvidia-smi: exit status 15 (simulated)")
+	},
+}
+
+// SimFailureClasses lists the failure classes SimulatedPulse produces, in
+// sorted order. Each is the failure_reason the controller records for it.
+func SimFailureClasses() []string {
+	classes := make([]string, 0, len(simFailures))
+	for class := range simFailures {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// SimulatedPulse returns a NodeReportFunc that measures nothing. Each pulse
+// reads the node's SimFailureAnnotation and fails with that class, or passes
+// at a quarter of the latency threshold if it is absent. An unknown class
+// fails as pre_flight_failure naming it. It lets the end-to-end suite drive
+// every failure path on a cluster without GPUs; never use it on real nodes.
+func SimulatedPulse(client kubernetes.Interface) NodeReportFunc {
+	return func(ctx context.Context, nodeName string) (*pulse.PulseReport, error) {
+		th := pulse.Active().Snapshot()
+		report := &pulse.PulseReport{
+			PulseID:     pulse.PulseIDFrom(ctx),
+			StartedAt:   time.Now().UTC(),
+			WorstMeanNS: (th.StragglerThreshold / 4).Nanoseconds(),
+			DeviceCount: 1,
+			Thresholds:  th,
+			Devices:     []pulse.DeviceResult{{Device: 0, MeanNS: (th.StragglerThreshold / 4).Nanoseconds(), CV: th.MaxCV / 4}},
+		}
+		node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return report, fmt.Errorf("get node %s: %w", nodeName, err)
+		}
+		class, ok := node.Annotations[SimFailureAnnotation]
+		if !ok {
+			return report, nil
+		}
+		fail, ok := simFailures[class]
+		if !ok {
+			return report, fmt.Errorf("unknown %s %q", SimFailureAnnotation, class)
+		}
+		return report, fail(th)
+	}
+}
@@ -0,0 +1,49 @@
+package k8s
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestSimulatedPulse checks that each simulated failure class is recorded
+// under its own name, which is what the end-to-end suite asserts on.
+func TestSimulatedPulse(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-sim", time.Minute)
+	clientset := fake.NewSimpleClientset(node)
+	sim := SimulatedPulse(clientset)
+
+	if report, err := sim(context.Background(), node.Name); err != nil || report.Elapsed() <= 0 {
+		t.Fatalf("no annotation: elapsed %v, err %v; want a pass", report.Elapsed(), err)
+	}
+	for _, class := range SimFailureClasses() {
+		node.Annotations = map[string]string{SimFailureAnnotation: class}
+		if _, err := clientset.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		_, err := sim(context.Background(), node.Name)
+		if reason, _ := classify(err); reason != class {
+			t.Errorf("class %s: classified as %s (err %v)", class, reason, err)
+		}
+	}
+	// Every reason a pulse is classified under can be keyed on in a policy.
+	// canary_failed comes from the canary, not a pulse.
+	for _, reason := range policy.Reasons {
+		if reason != "canary_failed" && !slices.Contains(SimFailureClasses(), reason) {
+			t.Errorf("policy reason %s has no simulated class", reason)
+		}
+	}
+	for _, class := range SimFailureClasses() {
+		if !slices.Contains(policy.Reasons, class) {
+			t.Errorf("class %s is missing from policy.Reasons", class)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ReportSink receives every fresh pulse result the controller acts on, e.g.
+// to forward it to the fleet aggregator. reason is the failure_reason label
+// value and err the pulse error, both empty for a pass. Cached results are not
+// delivered again. A sink is called on the reconcile path, before the verdict
+// is applied, and must not block for long.
+type ReportSink func(ctx context.Context, node *corev1.Node, report *pulse.PulseReport, reason string, err error)
+
+// WithReportSink delivers each fresh pulse result to sink. It can be given
+// more than once; sinks are called in order.
+func WithReportSink(sink ReportSink) Option {
+	return func(c *Controller) { c.sinks = append(c.sinks, sink) }
+}
+
+// publish hands a fresh result to the report sink, if one is set.
+func (c *Controller) publish(ctx context.Context, node *corev1.Node, report *pulse.PulseReport, cached bool, err error) {
+	if len(c.sinks) == 0 || cached {
+		return
+	}
+	reason := ""
+	if err != nil {
+		reason, _ = classify(err)
+	}
+	for _, sink := range c.sinks {
+		sink(ctx, node, report, reason, err)
+	}
+}
+
+// Transition events.
+const (
+	TransitionQuarantined = "quarantined"
+	TransitionCleared     = "cleared"
+)
+
+// Transition is a node entering or leaving quarantine, as handed to a
+// TransitionSink. MeasuredValue, ThresholdValue, Unit, GPU, ThresholdArch and
+// ThrottleReasons come from the pulse failure and are empty when it carried none, and on a clear.
+type Transition struct {
+	Node           string             `json:"node"`
+	Event          string             `json:"event"`            // TransitionQuarantined or TransitionCleared
+	Reason         string             `json:"reason,omitempty"` // failure_reason label value
+	PulseID        string             `json:"pulse_id"`
+	MeasuredValue  float64            `json:"measured_value,omitempty"`
+	ThresholdValue float64            `json:"threshold_value,omitempty"`
+	Unit           string             `json:"unit,omitempty"`
+	GPU            *pulse.GPUIdentity `json:"gpu,omitempty"`
+	// ThresholdArch is the failing device's own calibration on a node
+	// mixing GPU models (see pulse.PulseFailure).
+	ThresholdArch string `json:"threshold_arch,omitempty"`
+	// ThrottleReasons are the clock throttle reasons active on the failing
+	// GPU (see pulse.PulseFailure).
+	ThrottleReasons []string `json:"throttle_reasons,omitempty"`
+	// Message is the Event and condition message, e.g. failureEvidence.
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// TransitionSink receives every quarantine and clear the controller applies,
+// e.g. to page on-call. Unlike a ReportSink it is only called when the
+// node's GPUStraggler condition changes: a repeat failure on a quarantined
+// node, a soft taint escalating and a blocked clear are not transitions.
+// A decision replayed from the journal is delivered when it lands, without
+// the measurements behind it. A sink is called on the reconcile path and
+// must not block for long.
+type TransitionSink func(ctx context.Context, t Transition)
+
+// WithTransitionSink delivers each quarantine and clear to sink. It can be
+// given more than once; sinks are called in order.
+func WithTransitionSink(sink TransitionSink) Option {
+	return func(c *Controller) { c.transitionSinks = append(c.transitionSinks, sink) }
+}
+
+// notify hands a transition of node to the transition sinks. cause is the
+// pulse error of a quarantine, nil for a clear.
+func (c *Controller) notify(ctx context.Context, node *corev1.Node, event, reason string, report *pulse.PulseReport, cause error, evidence string) {
+	if len(c.transitionSinks) == 0 {
+		return
+	}
+	t := Transition{
+		Node:    node.Name,
+		Event:   event,
+		Reason:  reason,
+		PulseID: report.PulseID,
+		Message: evidence,
+		Time:    time.Now().UTC(),
+	}
+	var detail *pulse.PulseFailure
+	if errors.As(cause, &detail) {
+		t.MeasuredValue, t.ThresholdValue, t.Unit, t.GPU = detail.MeasuredValue, detail.ThresholdValue, detail.Unit, detail.GPU
+		t.ThresholdArch, t.ThrottleReasons = detail.Arch, detail.ThrottleReasons
+	}
+	for _, sink := range c.transitionSinks {
+		sink(ctx, t)
+	}
+}
@@ -0,0 +1,77 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReportSink(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", time.Minute)
+	client := fake.NewSimpleClientset(node)
+	var reasons []string
+	ctrl := NewController(client,
+		WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulse.ErrHighVariance }),
+		WithResultCache(time.Minute),
+		WithReportSink(func(_ context.Context, n *corev1.Node, report *pulse.PulseReport, reason string, _ error) {
+			if n.Name != node.Name || report == nil {
+				t.Errorf("sink got node %q report %v", n.Name, report)
+			}
+			reasons = append(reasons, reason)
+		}),
+	)
+
+	for range 2 {
+		if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+			t.Fatalf("ValidateNode: %v", err)
+		}
+	}
+	if len(reasons) != 1 || reasons[0] != "high_variance" {
+		t.Errorf("sink reasons = %v, want one high_variance (cached result not re-sent)", reasons)
+	}
+}
+
+func TestTransitionSink(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", time.Minute)
+	client := fake.NewSimpleClientset(node)
+	pulseErr := error(&pulse.PulseFailure{
+		Cause:          pulse.ErrHighVariance,
+		MeasuredValue:  0.31,
+		ThresholdValue: 0.2,
+		Unit:           "cv",
+		GPU:            &pulse.GPUIdentity{UUID: "GPU-5d5ba0d6"},
+	})
+	var got []Transition
+	ctrl := NewController(client,
+		WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulseErr }),
+		WithTransitionSink(func(_ context.Context, tr Transition) { got = append(got, tr) }),
+	)
+
+	// Two failures quarantine once; two passes clear once.
+	for _, err := range []error{pulseErr, pulseErr, nil, nil} {
+		pulseErr = err
+		if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+			t.Fatalf("ValidateNode: %v", err)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("transitions = %+v, want a quarantine and a clear", got)
+	}
+	q, c := got[0], got[1]
+	if q.Event != TransitionQuarantined || q.Node != node.Name || q.Reason != "high_variance" ||
+		q.MeasuredValue != 0.31 || q.ThresholdValue != 0.2 || q.Unit != "cv" || q.GPU == nil || q.PulseID == "" || q.Message == "" {
+		t.Errorf("quarantine = %+v", q)
+	}
+	if c.Event != TransitionCleared || c.Reason != "" || c.Unit != "" || c.PulseID == q.PulseID {
+		t.Errorf("clear = %+v", c)
+	}
+}
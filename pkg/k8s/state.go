@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Node quarantine states as reported by QuarantineState.
+const (
+	StateQuarantined = "quarantined" // zombie taint with NoSchedule/NoExecute, or a quarantine cordon
+	StateSuspected   = "suspected"   // zombie taint with PreferNoSchedule (soft quarantine)
+	StateScoped      = "scoped"      // unfit for some workload classes, no zombie taint
+	StateDegraded    = "degraded"    // degraded label, no zombie taint
+	StateHealthy     = "healthy"
+)
+
+// QuarantineState summarises the straggler-shield markers on a node into one
+// of the State* values. Exported for fleet tooling (aggregator, kubectl
+// plugin) that reads nodes written by the controller.
+func QuarantineState(node *corev1.Node) string {
+	return QuarantineStateForKey(node, DefaultTaintKey)
+}
+
+// QuarantineStateForKey is QuarantineState for a controller configured with a
+// different quarantine taint key (see WithTaint). A cordoned node counts as
+// quarantined if straggler-shield cordoned it or recorded GPUStraggler=True
+// on it (QuarantineCordon).
+func QuarantineStateForKey(node *corev1.Node, taintKey string) string {
+	if t := findTaintByKey(node.Spec.Taints, taintKey); t != nil {
+		if t.Effect == corev1.TaintEffectPreferNoSchedule {
+			return StateSuspected
+		}
+		return StateQuarantined
+	}
+	if _, ours := node.Annotations[CordonedAnnotation]; node.Spec.Unschedulable && (ours || quarantineRecorded(node)) {
+		return StateQuarantined
+	}
+	if len(UnfitClasses(node)) > 0 {
+		return StateScoped
+	}
+	if _, ok := node.Labels[DegradedLabel]; ok {
+		return StateDegraded
+	}
+	return StateHealthy
+}
+
+// StragglerCondition returns the node's GPUStraggler condition, or nil.
+func StragglerCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == zombieCondition {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -8,54 +8,163 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
 	"github.com/justin-oleary/straggler-shield/pkg/pulse"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
-	zombieTaintKey  = "sunk.coreweave.com/zombie-quarantine"
+	zombieTaintKey  = DefaultTaintKey
 	zombieCondition = corev1.NodeConditionType("GPUStraggler")
 )
 
 // readyTransitionWindow is how recently a Ready transition must have occurred
 // for us to treat the node as "just joined or rebooted."
 // Override with READY_WINDOW_SECONDS (integer seconds).
-var readyTransitionWindow = func() time.Duration {
+func readyTransitionWindow() time.Duration {
 	if s := os.Getenv("READY_WINDOW_SECONDS"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil && v > 0 {
 			return time.Duration(v) * time.Second
 		}
 	}
 	return 5 * time.Minute
-}()
+}
 
 // pulseFunc is the GPU pulse runner signature.
 // Defined as a type so tests can inject a mock without CGO or a real GPU.
 type pulseFunc func() (time.Duration, error)
 
+// NodePulseFunc runs a pulse against the GPUs of the named node. Executors
+// that do not run in-process (runner pods) need the node and a context;
+// local executors ignore both.
+type NodePulseFunc func(ctx context.Context, nodeName string) (time.Duration, error)
+
+// NodeReportFunc is NodePulseFunc returning the full pulse.PulseReport, so the
+// controller can log complete per-device evidence. Implementations must
+// return a non-nil report.
+type NodeReportFunc func(ctx context.Context, nodeName string) (*pulse.PulseReport, error)
+
 // Controller runs GPU pulse validation when nodes (re)join the cluster.
 type Controller struct {
 	client   kubernetes.Interface
-	runPulse pulseFunc
-	logger   *slog.Logger
+	runPulse NodeReportFunc
+	policy   *policy.Policy
+	taint    TaintConfig
+	mode     QuarantineMode
+	trigger  TriggerPolicy
+	recorder record.EventRecorder
+	cache    *resultCache
+	journal  *decisionJournal
+	// migrations are extra compatibility table rows for CollectStaleMarkers.
+	migrations []MarkerMigration
+	// requiredFailures is the straggler failure streak that quarantines a
+	// node; see WithRequiredFailures.
+	requiredFailures int
+	sinks            []ReportSink
+	transitionSinks  []TransitionSink
+	// guard holds back quarantines past its limits; nil admits all. See
+	// WithQuarantineGuard.
+	guard *QuarantineGuard
+	// budget is the reconcile latency budget; see WithReconcileBudget.
+	budget time.Duration
+	// dispatch limits concurrent pulses; nil runs them all at once. See
+	// WithPulseDispatch.
+	dispatch *dispatcher
+	// shard labels the backlog metrics; see WithShard.
+	shard string
+	// eviction drains hard-quarantined nodes when set; draining holds the
+	// nodes with a drain in progress. See WithPodEviction.
+	eviction *EvictionConfig
+	draining sync.Map
+	// quarantined maps each node this controller quarantined to the reason
+	// label it holds at 1 in metrics.Quarantined.
+	quarantined sync.Map
+	// canary pairs validated nodes for canary jobs; nil disables them. See
+	// WithCanary.
+	canary *canaryQueue
+	// busy defers triggered pulses on busy nodes; nil never defers. See
+	// WithBusyGuard.
+	busy *busyGuard
+	// retries counts the inconclusive pulses in a row on each node retried
+	// under policy.HandlingRetry.
+	retries sync.Map
+	// remediation is tried on failures before they quarantine; nil tries
+	// none. See WithRemediation.
+	remediation *remediation
+	logger      *slog.Logger
+}
+
+// Option configures optional Controller behaviour at construction time.
+type Option func(*Controller)
+
+// WithPulseFunc replaces the in-process CUDA pulse, e.g. with
+// pulse.RunPulseIsolated to contain CGO crashes in a child process.
+func WithPulseFunc(fn func() (time.Duration, error)) Option {
+	return WithNodePulse(func(context.Context, string) (time.Duration, error) { return fn() })
+}
+
+// WithNodePulse replaces the pulse executor with a node-aware one. The
+// controller only sees the worst-case duration; prefer WithNodeReport.
+func WithNodePulse(fn NodePulseFunc) Option {
+	return WithNodeReport(func(ctx context.Context, nodeName string) (*pulse.PulseReport, error) {
+		elapsed, err := fn(ctx, nodeName)
+		return &pulse.PulseReport{PulseID: pulse.PulseIDFrom(ctx), WorstMeanNS: elapsed.Nanoseconds()}, err
+	})
+}
+
+// WithNodeReport replaces the pulse executor with a report-returning one such
+// as PodRunner.RunReport.
+func WithNodeReport(fn NodeReportFunc) Option {
+	return func(c *Controller) { c.runPulse = fn }
+}
+
+// WithPolicy sets the decision policy. Without it every failure quarantines.
+// The policy's per-pool Ready windows apply to the default trigger; a
+// trigger passed to WithTrigger is left as it is.
+func WithPolicy(p *policy.Policy) Option {
+	return func(c *Controller) {
+		c.policy = p
+		if t, ok := c.trigger.(ReadyWindowTrigger); ok {
+			t.PoolLabel, t.PoolWindows = p.ReadyWindowPools()
+			c.trigger = t
+		}
+	}
+}
+
+// WithTrigger replaces the Ready-window heuristic that decides which watch
+// events run a pulse, e.g. to key on boot ID changes, labels or an external
+// signal. See TriggerPolicy.
+func WithTrigger(t TriggerPolicy) Option {
+	return func(c *Controller) { c.trigger = t }
 }
 
 // NewController returns a Controller wired to the real CUDA pulse.
-func NewController(client kubernetes.Interface) *Controller {
-	return &Controller{client: client, runPulse: pulse.RunPulse, logger: slog.Default()}
+func NewController(client kubernetes.Interface, opts ...Option) *Controller {
+	c := &Controller{client: client, policy: policy.Default(), taint: DefaultTaint(), mode: QuarantineTaint, trigger: DefaultTrigger(), budget: DefaultReconcileBudget, logger: slog.Default()}
+	WithNodeReport(func(ctx context.Context, _ string) (*pulse.PulseReport, error) {
+		return pulse.RunPulseReport(ctx)
+	})(c)
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.client = timedClient{c.client}
+	return c
 }
 
 // newControllerWithPulse injects a custom pulse function.
 // Only for use in unit tests — avoids CGO and GPU dependencies.
 func newControllerWithPulse(client kubernetes.Interface, fn pulseFunc) *Controller {
-	return &Controller{client: client, runPulse: fn, logger: slog.Default()}
+	return NewController(client, WithPulseFunc(fn))
 }
 
 // withLogger swaps the controller's logger. Used in tests to capture structured
@@ -67,70 +176,337 @@ func (c *Controller) withLogger(l *slog.Logger) *Controller {
 
 // ReconcileNode is the primary entry point. It should be called whenever a node
 // transitions to Ready (watch event or informer sync). It:
-//  1. Checks whether the node just joined or rebooted.
+//  1. Asks the TriggerPolicy whether to validate (default: the node just
+//     joined or rebooted).
 //  2. Runs pulse.RunPulse() against the local GPU.
 //  3. Removes the zombie quarantine taint if the pulse passes.
 //  4. Applies the taint and emits a structured MFU evidence log if it fails.
+//
+// A node still recorded as quarantined whose taint was removed outside
+// straggler-shield is re-evaluated regardless of the TriggerPolicy, so the
+// taint and the GPUStraggler condition are brought back into agreement. So
+// is a node with an open failure streak (see WithRequiredFailures) or an
+// on-demand pulse request (PulseRequestAnnotation).
+//
+// With WithBusyGuard, a triggered pulse on a node running GPU workloads is
+// deferred with ErrNodeBusy, and run by a later ReconcileNode once the GPUs
+// are free. An inconclusive pulse retried under policy.HandlingRetry returns
+// ErrRetryInconclusive and is run again by a later ReconcileNode.
 func (c *Controller) ReconcileNode(ctx context.Context, nodeName string) error {
+	ctx, timing := beginReconcile(ctx)
+	defer c.endReconcile(nodeName, timing)
 	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("get node %s: %w", nodeName, err)
 	}
 
-	if !justBecameReady(node, readyTransitionWindow) {
+	if c.TaintRemovedExternally(node) {
+		c.disagreement(node, disagreeTaintRemoved,
+			fmt.Sprintf("%s removed outside straggler-shield while GPUStraggler=True — re-evaluating", c.markerName()))
+		return c.validate(ctx, node)
+	}
+	if c.PulseRequested(node) {
+		c.logger.Info("on-demand pulse requested — running GPU pulse", "node", nodeName,
+			"requested_at", node.Annotations[PulseRequestAnnotation])
+		err := c.validate(ctx, node)
+		c.clearPulseRequest(ctx, node)
+		return err
+	}
+	if c.FailureStreakOpen(node) {
+		c.logger.Info("failure streak open — re-running GPU pulse to confirm", "node", nodeName,
+			"failures", consecutiveFailures(node), "required", c.requiredFailures)
+		return c.validate(ctx, node)
+	}
+	retry := c.RetryPending(nodeName)
+	deferred := c.PulseDeferred(nodeName)
+	if !retry && !deferred && !c.trigger.ShouldValidate(node, HistoryFromNode(node)) {
 		return nil // steady-state node — nothing to do
 	}
+	if err := c.deferIfBusy(ctx, node); err != nil {
+		return err
+	}
+
+	switch {
+	case retry:
+		c.logger.Info("retrying inconclusive GPU pulse", "node", nodeName)
+	case deferred:
+		c.logger.Info("GPUs free — running deferred GPU pulse", "node", nodeName)
+	default:
+		c.logger.Info("validation triggered — running GPU pulse", "node", nodeName)
+	}
+	return c.validate(withCanaryCandidate(ctx), node)
+}
+
+// ValidateNode runs the pulse on nodeName unconditionally, bypassing the
+// Ready-window check, and applies or clears the quarantine like ReconcileNode.
+// Used by PeriodicValidator for steady-state revalidation.
+func (c *Controller) ValidateNode(ctx context.Context, nodeName string) error {
+	ctx, timing := beginReconcile(ctx)
+	defer c.endReconcile(nodeName, timing)
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get node %s: %w", nodeName, err)
+	}
 
-	c.logger.Info("node ready after join/reboot — running GPU pulse", "node", nodeName)
+	c.logger.Info("running scheduled GPU pulse", "node", nodeName)
+	return c.validate(ctx, node)
+}
 
-	elapsed, err := c.runPulse()
+// validate runs the pulse against node and applies or clears the quarantine
+// taint based on the result. Each run gets a pulse ID, carried to the pulse
+// executor through ctx and logged with the verdict, so the exemplar on a
+// PulseDuration observation leads back to these log records.
+//
+// With WithResultCache, a result younger than the freshness window is reused
+// under its original pulse ID. Its verdict is re-applied (all patches are
+// idempotent) but not counted again in the failure metrics. With
+// WithRemediation, a failure that would quarantine is remediated and the
+// pulse re-run first.
+func (c *Controller) validate(ctx context.Context, node *corev1.Node) error {
+	metrics.ValidationsPending.WithLabelValues(c.shard).Inc()
+	defer metrics.ValidationsPending.WithLabelValues(c.shard).Dec()
+	report, cached, err := c.pulseOrCached(ctx, node)
+	if errors.Is(err, ErrNotDispatched) {
+		return err
+	}
+	if c.remediable(node, report, cached, err) {
+		report, err = c.remediate(ctx, node, report, err)
+	}
+	defer metrics.Validations.WithLabelValues(c.shard).Inc()
+	return c.apply(ctx, node, report, cached, err)
+}
+
+// apply acts on a pulse result: a pass clears any quarantine or degraded
+// marking, a failure is handled according to its policy severity. A failure
+// also restarts the consecutive-pass count kept by RevalidateQuarantined, and
+// a pass ends any failure streak. An inconclusive failure is handled by the
+// policy's Inconclusive handling when one is set, which may retry it instead.
+func (c *Controller) apply(ctx context.Context, node *corev1.Node, report *pulse.PulseReport, cached bool, err error) error {
+	nodeName := node.Name
+	pulseID := report.PulseID
+	elapsed := report.Elapsed()
+	c.publish(ctx, node, report, cached, err)
+	if !cached {
+		metrics.LastPulse.WithLabelValues(nodeName).SetToCurrentTime()
+	}
+	if !pulse.IsInconclusive(err) {
+		c.retries.Delete(nodeName)
+	}
 	if err == nil {
-		c.logger.Info("GPU pulse passed", "node", nodeName, "elapsed", elapsed)
-		return c.removeTaint(ctx, nodeName, node)
-	}
-
-	if pulse.IsStragglerErr(err) {
-		logReason := "latency threshold exceeded"
-		promReason := "latency_threshold_exceeded"
-		switch {
-		case errors.Is(err, pulse.ErrHighVariance):
-			logReason = "fail-slow variance pattern (high CV across runs)"
-			promReason = "high_variance"
-		case errors.Is(err, pulse.ErrInterconnectDegraded):
-			logReason = "NVLink/P2P interconnect degraded"
-			promReason = "interconnect_degraded"
+		c.logger.Info("GPU pulse passed", "node", nodeName, "pulse_id", pulseID, "elapsed", elapsed, "report", report)
+		c.setConsecutiveFailures(ctx, node, 0)
+		if !cached {
+			c.offerCanary(ctx, nodeName)
 		}
+		return c.decide(ctx, node, opClear, "", report, nil, passEvidence(report))
+	}
 
-		// Build the structured MFU evidence log. If the error carries a
-		// PulseFailure, include the exact measured and threshold values so
-		// the log record is self-contained proof of why the node was caught.
-		logArgs := []any{
-			"node_name", nodeName,
-			"failure_reason", logReason,
-			"elapsed_ms", elapsed.Milliseconds(),
+	promReason, logReason := classify(err)
+	handling := c.policy.InconclusiveHandling()
+	inconclusive := handling != "" && pulse.IsInconclusive(err)
+	if inconclusive && handling == policy.HandlingRetry {
+		if rerr := c.retryInconclusive(node, pulseID, promReason, err); rerr != nil {
+			return rerr
 		}
+	}
+
+	c.setConsecutivePasses(ctx, node, 0)
+
+	sev, perr := c.policy.Decide(promReason, node, report)
+	if perr != nil {
+		c.logger.Warn("policy expression failed — using the severities map", "node_name", nodeName, "failure_reason", promReason, "err", perr)
+	}
+	if inconclusive && handling.Severity() != "" {
+		sev = handling.Severity()
+	}
+	if !cached {
+		metrics.CheckFailures.WithLabelValues(promReason, string(sev)).Inc()
 		var detail *pulse.PulseFailure
 		if errors.As(err, &detail) {
+			for _, r := range detail.ThrottleReasons {
+				metrics.ThrottleReasons.WithLabelValues(r).Inc()
+			}
+		}
+	}
+
+	// Build the structured MFU evidence log. If the error carries a
+	// PulseFailure, include the exact measured and threshold values so
+	// the log record is self-contained proof of why the node was caught.
+	// The full report follows with every device and link measurement.
+	logArgs := []any{
+		"node_name", nodeName,
+		"pulse_id", pulseID,
+		"failure_reason", logReason,
+		"elapsed_ms", elapsed.Milliseconds(),
+	}
+	var detail *pulse.PulseFailure
+	if errors.As(err, &detail) {
+		logArgs = append(logArgs,
+			"measured_value", detail.MeasuredValue,
+			"threshold_value", detail.ThresholdValue,
+			"unit", detail.Unit,
+		)
+		if detail.GPU != nil {
 			logArgs = append(logArgs,
-				"measured_value", detail.MeasuredValue,
-				"threshold_value", detail.ThresholdValue,
-				"unit", detail.Unit,
+				"gpu_uuid", detail.GPU.UUID,
+				"gpu_serial", detail.GPU.Serial,
+				"gpu_pci_bus_id", detail.GPU.PCIBusID,
 			)
 		}
-		c.logger.Warn("zombie node quarantined", logArgs...)
+		if detail.Arch != "" {
+			logArgs = append(logArgs, "threshold_arch", detail.Arch)
+		}
+		if len(detail.ThrottleReasons) > 0 {
+			logArgs = append(logArgs, "throttle_reasons", detail.ThrottleReasons)
+		}
+	}
+	logArgs = append(logArgs, "report", report)
 
-		metrics.StragglerTotal.WithLabelValues(promReason).Inc()
-		return c.applyTaint(ctx, nodeName, node, elapsed)
+	// Mapped below quarantine, a failure lifts an earlier quarantine; an
+	// inconclusive pulse has not shown the node healthy, so it keeps it.
+	if below := sev == policy.SeverityWarn || sev == policy.SeverityDegradeLabel || sev == policy.SeverityDegrade; below && pulse.IsInconclusive(err) && quarantineRecorded(node) {
+		c.logger.Warn("GPU check inconclusive — keeping the earlier quarantine", append(logArgs, "err", err)...)
+		return nil
+	}
+
+	switch sev {
+	case policy.SeverityWarn:
+		c.logger.Warn("GPU check failed — warn-only policy, node left schedulable", append(logArgs, "err", err)...)
+		return c.recordWarning(ctx, node, promReason, err)
+	case policy.SeverityDegradeLabel:
+		c.logger.Warn("GPU check failed — labelling node degraded", append(logArgs, "err", err)...)
+		return c.applyDegradedLabel(ctx, node, promReason, err)
+	case policy.SeverityDegrade:
+		c.logger.Warn("GPU check failed — moving node to degraded tier", append(logArgs, "err", err)...)
+		return c.applyDegradedTier(ctx, node, promReason, err)
 	}
 
-	// Hard failure (ECC errors, thermal, CUDA crash) — also quarantine.
+	if classes, scoped := c.policy.ImpactedClasses(promReason); scoped && c.mode != QuarantineCordon {
+		if streakFailure(err) && c.holdQuarantine(ctx, node, pulseID, cached) {
+			return nil
+		}
+		c.logger.Warn("GPU check failed — quarantining node for impacted workload classes only",
+			append(logArgs, "workload_classes", classes, "err", err)...)
+		if !cached {
+			metrics.StragglerTotal.WithLabelValues(promReason).Inc()
+		}
+		return c.decide(ctx, node, opScope, promReason, report, err, failureEvidence(logReason, report, err))
+	}
+
+	if streakFailure(err) {
+		if c.holdQuarantine(ctx, node, pulseID, cached) {
+			return nil
+		}
+		c.logger.Warn("zombie node quarantined", logArgs...)
+		if !cached {
+			metrics.StragglerTotal.WithLabelValues(promReason).Inc()
+		}
+		return c.decide(ctx, node, opQuarantine, promReason, report, err, failureEvidence(logReason, report, err))
+	}
+
+	// Hard failure (ECC errors, thermal, CUDA crash, stage timeout) — also quarantine.
 	c.logger.Error("GPU pulse hard failure — quarantining node",
 		"node_name", nodeName,
-		"failure_reason", "pre_flight_failure",
+		"pulse_id", pulseID,
+		"failure_reason", promReason,
 		"err", err,
+		"report", report,
 	)
-	metrics.StragglerTotal.WithLabelValues("pre_flight_failure").Inc()
-	return c.applyTaint(ctx, nodeName, node, elapsed)
+	if !cached {
+		metrics.StragglerTotal.WithLabelValues(promReason).Inc()
+	}
+	c.setConsecutiveFailures(ctx, node, 0)
+	return c.decide(ctx, node, opQuarantine, promReason, report, err, failureEvidence(logReason, report, err))
+}
+
+// pulseOrCached returns node's pulse result, reusing a fresh cached one if available
+// (cached = true) and otherwise running the executor under a new pulse ID.
+// The returned report is never nil unless the error is ErrNotDispatched.
+func (c *Controller) pulseOrCached(ctx context.Context, node *corev1.Node) (report *pulse.PulseReport, cached bool, err error) {
+	if hit, age, ok := c.cache.lookup(node.Name); ok {
+		c.logger.Info("reusing recent pulse result", "node", node.Name, "pulse_id", hit.report.PulseID, "age", age)
+		timingFrom(ctx).addPulse(hit.report.PulseID, true, 0)
+		return hit.report, true, hit.err
+	}
+
+	pulseID := pulse.NewPulseID()
+	ctx = pulse.WithPulseID(ctx, pulseID)
+	if q, ok := node.Status.Capacity[gpuResource]; ok {
+		ctx = pulse.WithExpectedGPUs(ctx, int(q.Value()))
+	}
+
+	release, err := c.waitForDispatch(ctx, node)
+	if err != nil {
+		return nil, false, err
+	}
+	started := time.Now()
+	report, err = c.runPulse(ctx, node.Name)
+	release()
+	if report == nil {
+		report = &pulse.PulseReport{}
+	}
+	if report.StartedAt.IsZero() {
+		report.StartedAt = started.UTC()
+	}
+	if report.PulseID == "" {
+		report.PulseID = pulseID
+	}
+	timingFrom(ctx).addPulse(report.PulseID, false, time.Since(started))
+	c.recordLastPulse(ctx, node, report, err)
+	// A pulse cut short by shutdown says nothing about the GPUs, and an
+	// inconclusive one must not stand in for its retry.
+	if ctx.Err() == nil && !pulse.IsInconclusive(err) {
+		c.cache.store(node.Name, report, err)
+	}
+	return report, false, err
+}
+
+// classify maps a pulse error to its Prometheus reason code and a
+// human-readable log reason. The reason code is also the key for per-check
+// severities in policy.
+func classify(err error) (promReason, logReason string) {
+	switch {
+	case errors.Is(err, pulse.ErrHighVariance):
+		return "high_variance", "fail-slow variance pattern (high CV across runs)"
+	case errors.Is(err, pulse.ErrInterconnectDegraded):
+		return "interconnect_degraded", "NVLink/P2P interconnect degraded"
+	case errors.Is(err, pulse.ErrClockDerated):
+		return "clock_derated", "GPU SM clock derated under load"
+	case errors.Is(err, pulse.ErrStragglerDetected):
+		return "latency_threshold_exceeded", "latency threshold exceeded"
+	case errors.Is(err, pulse.ErrStageTimeout):
+		return "stage_timeout", "stage_timeout"
+	case errors.Is(err, pulse.ErrPulseCrashed):
+		return "pulse_crashed", "pulse_crashed"
+	case errors.Is(err, pulse.ErrGPUsNotVisible):
+		return "misconfiguration", "agent container sees fewer GPUs than the node advertises"
+	case errors.Is(err, pulse.ErrEnumerationMismatch):
+		return "misconfiguration", "GPU enumeration mismatch — driver or container runtime misconfigured"
+	case errors.Is(err, pulse.ErrDeviceCountDecreased):
+		return "gpu_count_decreased", "GPU count decreased since last passing pulse"
+	case errors.Is(err, pulse.ErrXIDEvent):
+		return "xid_error", "NVIDIA XID error in kernel log"
+	case errors.Is(err, pulse.ErrRowRemap):
+		return "row_remap_failure", "HBM row remap failed or pending"
+	case errors.Is(err, pulse.ErrECCErrors):
+		return "ecc_errors", "uncorrectable ECC errors since last boot or clean pulse"
+	case errors.Is(err, pulse.ErrPCIeDegraded):
+		return "pcie_degraded", "PCIe link trained below its maximum width"
+	case errors.Is(err, pulse.ErrLowPower):
+		return "low_power", "GPU power draw under load below the expected fraction of its TDP"
+	case errors.Is(err, pulse.ErrThermalRecovery):
+		return "thermal_recovery", "GPU idle temperature above pre-flight ceiling"
+	case errors.Is(err, pulse.ErrExternalCheck):
+		return "external_check", "operator-supplied external check failed"
+	case errors.Is(err, pulse.ErrNearThreshold):
+		return "near_threshold", "passed but within degraded margin of threshold"
+	case errors.Is(err, pulse.ErrInconclusive):
+		return "inconclusive", "pulse could not check every GPU — verdict inconclusive"
+	case errors.Is(err, ErrCanaryFailed):
+		return "canary_failed", "distributed training canary failed"
+	default:
+		return "pre_flight_failure", "pre_flight_failure"
+	}
 }
 
 // justBecameReady returns true when the node's Ready=True condition transitioned
@@ -155,48 +531,193 @@ func IsNodeReady(node *corev1.Node) bool {
 	return false
 }
 
-// applyTaint adds the zombie-quarantine NoSchedule taint to the node spec and
-// records a GPUStraggler condition in the status subresource. Idempotent.
-func (c *Controller) applyTaint(ctx context.Context, nodeName string, node *corev1.Node, elapsed time.Duration) error {
-	// skip if already tainted
-	for _, t := range node.Spec.Taints {
-		if t.Key == zombieTaintKey {
-			return nil
+// decide journals a quarantine, scoped quarantine (opScope) or clear decision
+// for node (see WithDecisionJournal), applies it, and commits it once
+// applied. reason is the failure_reason of a quarantine, recorded in
+// metrics.Quarantined, and
+// cause its pulse error. A decision that fails stays journaled for
+// RunJournalReplay; the error is still returned. Forbidden and vanished-node
+// failures are committed anyway: replaying cannot fix RBAC, and a deleted
+// node needs no patch. A new quarantine the guard refuses (see
+// WithQuarantineGuard) is committed without being applied, as is a new
+// scoped one. A quarantine or clear that changes the node's GPUStraggler
+// condition goes to the transition sinks (see WithTransitionSink); a scoped
+// quarantine leaves the node schedulable and does not.
+//
+// The node is re-read first: the copy the pulse started from can be minutes
+// old, and patches computed from it would drop taints or conditions other
+// writers added in the meantime.
+func (c *Controller) decide(ctx context.Context, node *corev1.Node, op, reason string, report *pulse.PulseReport, cause error, evidence string) error {
+	elapsed := report.Elapsed()
+	var value string
+	if op == opQuarantine {
+		value = newTaintValue(reason, report, cause).String()
+	}
+	seq, jerr := c.journal.begin(node.Name, op, reason, value, elapsed, report, evidence)
+	c.logJournalErr(node.Name, jerr)
+
+	fresh, err := c.client.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		err = fmt.Errorf("get node %s: %w: %w", node.Name, ErrNodeNotFound, err)
+	case err != nil:
+		err = fmt.Errorf("get node %s: %w", node.Name, err)
+	case op == opQuarantine:
+		// Read before the patches, which edit fresh's conditions in place.
+		recorded := quarantineRecorded(fresh)
+		if !recorded && !c.admitQuarantine(ctx, fresh, reason) {
+			break
+		}
+		if err = c.applyTaint(ctx, node.Name, fresh, reason, value, elapsed, evidence); err == nil {
+			c.setQuarantined(node.Name, reason)
+			if !recorded {
+				c.notify(ctx, fresh, TransitionQuarantined, reason, report, cause, evidence)
+			}
+		}
+	case op == opScope:
+		// The rest of the node stays schedulable, so nothing is notified.
+		if !quarantineRecorded(fresh) && len(UnfitClasses(fresh)) == 0 && !c.admitQuarantine(ctx, fresh, reason) {
+			break
 		}
+		classes, _ := c.policy.ImpactedClasses(reason)
+		if err = c.applyWorkloadScope(ctx, fresh, reason, classes, elapsed, evidence); err == nil {
+			c.setQuarantined(node.Name, reason)
+		}
+	default:
+		blocked, _ := c.clearBlocked(fresh, report.StartedAt)
+		lifts := quarantineRecorded(fresh) && blocked == ""
+		if err = c.clearQuarantine(ctx, fresh, report.StartedAt, evidence); err == nil && lifts {
+			c.notify(ctx, fresh, TransitionCleared, "", report, nil, evidence)
+		}
+	}
+	if err == nil || errors.Is(err, ErrForbidden) || errors.Is(err, ErrNodeNotFound) {
+		c.logJournalErr(node.Name, c.journal.commit(node.Name, seq))
+		return err
+	}
+	if c.journal != nil {
+		c.logger.Warn("decision not applied — journaled for replay", "node_name", node.Name, "op", op, "err", err)
 	}
+	return err
+}
 
-	type specPatch struct {
-		Spec struct {
-			Taints []corev1.Taint `json:"taints"`
-		} `json:"spec"`
+// clearQuarantine lifts every straggler-shield marking after a passing pulse
+// that started at since, unless the markers on node disagree (see
+// clearBlocked). A disagreement is reported and leaves the quarantine in
+// place without an error: it is not a failed patch and replaying it would
+// not change the outcome.
+func (c *Controller) clearQuarantine(ctx context.Context, node *corev1.Node, since time.Time, evidence string) error {
+	kind, msg := c.clearBlocked(node, since)
+	if kind == disagreeNewerQuarantine {
+		c.disagreement(node, kind, msg)
+		return nil
 	}
-	sp := specPatch{}
-	sp.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
-		Key:    zombieTaintKey,
-		Value:  elapsed.String(),
-		Effect: corev1.TaintEffectNoSchedule,
-	})
-	specBytes, err := json.Marshal(sp)
-	if err != nil {
-		return fmt.Errorf("marshal taint patch: %w", err)
+	if err := c.clearDegraded(ctx, node); err != nil {
+		return err
 	}
-	if _, err := c.client.CoreV1().Nodes().Patch(
-		ctx, nodeName, types.MergePatchType, specBytes, metav1.PatchOptions{},
-	); err != nil {
-		return fmt.Errorf("patch node spec: %w", err)
+	if err := c.clearWorkloadScope(ctx, node); err != nil {
+		return err
+	}
+	if kind != "" {
+		c.disagreement(node, kind, msg)
+		return nil
+	}
+	return c.removeTaint(ctx, node.Name, node, evidence)
+}
+
+// applyTaint adds the quarantine taint (see TaintConfig; NoSchedule by
+// default) to the node spec and records a GPUStraggler condition in the
+// status subresource. Idempotent.
+//
+// With policy softQuarantine, a first offense gets PreferNoSchedule instead,
+// and a failure on a node already carrying the soft taint confirms it and
+// escalates to the configured effect. A taint is never downgraded within a
+// failure class. The policy's Taints set the value and effect for reason; a
+// failure of another class than the taint in place replaces it, since the
+// newest pulse decides which workloads may still tolerate the node.
+// Otherwise the value is defaultValue, a TaintValue, or the pulse duration
+// for a decision journaled without one.
+//
+// Each new or escalated taint emits a StragglerQuarantined Warning Event on
+// the node carrying evidence. In QuarantineCordon mode the node is cordoned
+// instead (see cordon).
+func (c *Controller) applyTaint(ctx context.Context, nodeName string, node *corev1.Node, failureReason, defaultValue string, elapsed time.Duration, evidence string) error {
+	if c.mode == QuarantineCordon {
+		return c.cordon(ctx, nodeName, node, failureReason, elapsed, evidence)
+	}
+	effect, value := c.taint.Effect, c.taint.Value
+	class, classTaint := c.policy.TaintFor(failureReason)
+	if classTaint {
+		value = class.Value
+		if class.Effect != "" {
+			effect = class.Effect
+		}
+	}
+	reason := "StragglerDetected"
+	existing := findTaintByKey(node.Spec.Taints, c.taint.Key)
+	replaced := existing != nil && existing.Value != value && (classTaint || c.policy.IsClassTaintValue(existing.Value))
+	if replaced {
+		c.logger.Warn("quarantined node failed with another failure class — replacing the taint",
+			"node_name", nodeName, "failure_reason", failureReason, "old_value", existing.Value, "value", value)
+		existing = nil
+	}
+	if existing != nil && effectRank(existing.Effect) >= effectRank(effect) {
+		if quarantineRecorded(node) {
+			return nil // already fully quarantined
+		}
+		// The taint is in place (an operator hold, or our own spec patch
+		// whose status patch failed) but the condition is not: record it.
+		return c.recordQuarantine(ctx, node, reason, elapsed, evidence)
+	}
+	if existing != nil {
+		c.logger.Warn("soft-quarantined node failed again — escalating", "node_name", nodeName, "effect", effect)
+	} else if c.policy.SoftQuarantine && effect != corev1.TaintEffectPreferNoSchedule && !replaced {
+		effect = corev1.TaintEffectPreferNoSchedule
+		reason = "StragglerSuspected"
+	}
+	if value == "" {
+		value = defaultValue
+	}
+	if value == "" {
+		value = elapsed.String()
 	}
 
-	// record why the node was quarantined
+	if err := patchNodeSpec(ctx, c.client, node, func(taints []corev1.Taint) []corev1.Taint {
+		return append(removeTaintByKey(taints, c.taint.Key), corev1.Taint{
+			Key:    c.taint.Key,
+			Value:  value,
+			Effect: effect,
+		})
+	}, nil, map[string]any{"annotations": decisionAnnotations(opQuarantine, failureReason, elapsed, evidence)}); err != nil {
+		return patchError("apply_taint", "patch node spec", err)
+	}
+	if err := c.recordQuarantine(ctx, node, reason, elapsed, evidence); err != nil {
+		return err
+	}
+
+	c.event(node, corev1.EventTypeWarning, eventQuarantined, fmt.Sprintf("%s taint applied — %s", effect, evidence))
+	if effect != corev1.TaintEffectPreferNoSchedule {
+		c.startEviction(ctx, node)
+	}
+	return nil
+}
+
+// recordQuarantine sets the GPUStraggler condition to True, recording why the
+// node was quarantined. Without it a passing pulse treats the taint as not
+// written by straggler-shield and leaves it in place.
+func (c *Controller) recordQuarantine(ctx context.Context, node *corev1.Node, reason string, elapsed time.Duration, evidence string) error {
 	type statusPatch struct {
 		Status struct {
 			Conditions []corev1.NodeCondition `json:"conditions"`
 		} `json:"status"`
 	}
+	if evidence == "" {
+		evidence = fmt.Sprintf("GPU pulse took %s", elapsed)
+	}
 	cond := corev1.NodeCondition{
 		Type:               zombieCondition,
 		Status:             corev1.ConditionTrue,
-		Reason:             "StragglerDetected",
-		Message:            fmt.Sprintf("GPU pulse took %s (threshold 500ms)", elapsed),
+		Reason:             reason,
+		Message:            evidence,
 		LastTransitionTime: metav1.Now(),
 	}
 	st := statusPatch{}
@@ -206,43 +727,43 @@ func (c *Controller) applyTaint(ctx context.Context, nodeName string, node *core
 		return fmt.Errorf("marshal status patch: %w", err)
 	}
 	if _, err := c.client.CoreV1().Nodes().Patch(
-		ctx, nodeName, types.MergePatchType, statusBytes,
+		ctx, node.Name, types.MergePatchType, statusBytes,
 		metav1.PatchOptions{}, "status",
 	); err != nil {
-		return fmt.Errorf("patch node status: %w", err)
+		return patchError("apply_taint", "patch node status", err)
 	}
-
 	return nil
 }
 
 // removeTaint strips the zombie-quarantine taint and clears the GPUStraggler
 // condition. Called when a previously quarantined node passes the pulse. Idempotent.
-func (c *Controller) removeTaint(ctx context.Context, nodeName string, node *corev1.Node) error {
-	filtered := make([]corev1.Taint, 0, len(node.Spec.Taints))
-	for _, t := range node.Spec.Taints {
-		if t.Key != zombieTaintKey {
-			filtered = append(filtered, t)
-		}
-	}
-	if len(filtered) == len(node.Spec.Taints) {
+// A GPUStraggler=True condition left behind by an externally removed taint is
+// cleared too. Emits a PulsePassed Normal Event carrying evidence when a
+// quarantine is lifted.
+//
+// A cordon straggler-shield set (CordonedAnnotation) is lifted as well, in
+// either QuarantineMode; any other cordon is left in place.
+func (c *Controller) removeTaint(ctx context.Context, nodeName string, node *corev1.Node, evidence string) error {
+	tainted := findTaintByKey(node.Spec.Taints, c.taint.Key) != nil
+	_, cordoned := node.Annotations[CordonedAnnotation]
+	if !tainted && !cordoned && !quarantineRecorded(node) {
 		return nil // zombie taint was not present
 	}
 
-	type specPatch struct {
-		Spec struct {
-			Taints []corev1.Taint `json:"taints"`
-		} `json:"spec"`
-	}
-	sp := specPatch{}
-	sp.Spec.Taints = filtered
-	specBytes, err := json.Marshal(sp)
-	if err != nil {
-		return fmt.Errorf("marshal taint removal patch: %w", err)
-	}
-	if _, err := c.client.CoreV1().Nodes().Patch(
-		ctx, nodeName, types.MergePatchType, specBytes, metav1.PatchOptions{},
-	); err != nil {
-		return fmt.Errorf("patch node spec (remove taint): %w", err)
+	if tainted || cordoned {
+		var edit func([]corev1.Taint) []corev1.Taint
+		var spec map[string]any
+		annotations := decisionAnnotations(opClear, "", 0, evidence)
+		if tainted {
+			edit = func(taints []corev1.Taint) []corev1.Taint { return removeTaintByKey(taints, c.taint.Key) }
+		}
+		if cordoned {
+			spec = map[string]any{"unschedulable": false}
+			annotations[CordonedAnnotation] = nil
+		}
+		if err := patchNodeSpec(ctx, c.client, node, edit, spec, map[string]any{"annotations": annotations}); err != nil {
+			return patchError("remove_taint", "patch node spec (remove taint)", err)
+		}
 	}
 
 	// clear the condition
@@ -268,13 +789,65 @@ func (c *Controller) removeTaint(ctx context.Context, nodeName string, node *cor
 		ctx, nodeName, types.MergePatchType, statusBytes,
 		metav1.PatchOptions{}, "status",
 	); err != nil {
-		return fmt.Errorf("patch node status (clear condition): %w", err)
+		return patchError("remove_taint", "patch node status (clear condition)", err)
 	}
 
+	c.setConsecutivePasses(ctx, node, 0)
+	c.clearQuarantined(nodeName)
 	c.logger.Info("zombie taint removed — node cleared for Slurm", "node_name", nodeName)
+	c.event(node, corev1.EventTypeNormal, eventPassed, evidence)
 	return nil
 }
 
+// setQuarantined sets node's metrics.Quarantined series for reason to 1,
+// and to 0 for the reason of an earlier quarantine.
+func (c *Controller) setQuarantined(nodeName, reason string) {
+	if prev, ok := c.quarantined.Swap(nodeName, reason); ok && prev.(string) != reason {
+		metrics.Quarantined.WithLabelValues(nodeName, prev.(string)).Set(0)
+	}
+	metrics.Quarantined.WithLabelValues(nodeName, reason).Set(1)
+}
+
+// ObserveNode updates what the controller keeps about node from a copy the
+// watch delivered: a node no longer quarantined, for example one cleared by
+// hand with ForceClear, is set to 0 in metrics.Quarantined, and one
+// quarantined before this process started is added to it.
+func (c *Controller) ObserveNode(node *corev1.Node) {
+	if !quarantineRecorded(node) && len(UnfitClasses(node)) == 0 {
+		if !c.quarantineMarked(node) {
+			c.clearQuarantined(node.Name)
+		}
+		return
+	}
+	if _, known := c.quarantined.Load(node.Name); known {
+		return
+	}
+	if reason := c.quarantineReason(node); reason != "" {
+		c.setQuarantined(node.Name, reason)
+	}
+}
+
+// quarantineReason is the failure reason node was quarantined for, from its
+// decision annotation or else its taint value, or "" if neither says.
+func (c *Controller) quarantineReason(node *corev1.Node) string {
+	if d, ok := NodeDecision(node); ok && (d.Op == opQuarantine || d.Op == opScope) && d.Reason != "" {
+		return d.Reason
+	}
+	if t := findTaintByKey(node.Spec.Taints, c.taint.Key); t != nil {
+		if v, err := ParseTaintValue(t.Value); err == nil {
+			return v.Reason
+		}
+	}
+	return ""
+}
+
+// clearQuarantined sets node's metrics.Quarantined series to 0.
+func (c *Controller) clearQuarantined(nodeName string) {
+	if prev, ok := c.quarantined.LoadAndDelete(nodeName); ok {
+		metrics.Quarantined.WithLabelValues(nodeName, prev.(string)).Set(0)
+	}
+}
+
 func upsertCondition(conditions []corev1.NodeCondition, c corev1.NodeCondition) []corev1.NodeCondition {
 	for i, existing := range conditions {
 		if existing.Type == c.Type {
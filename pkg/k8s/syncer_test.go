@@ -3,16 +3,29 @@ package k8s
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
 	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestReconcileNode(t *testing.T) {
@@ -28,11 +41,16 @@ func TestReconcileNode(t *testing.T) {
 		pulseDuration time.Duration
 		pulseErr      error
 
+		// decision policy; nil = default (quarantine on every failure)
+		policy *policy.Policy
+
 		// expected observable state after ReconcileNode
 		wantTaint      bool
 		wantEffect     corev1.TaintEffect // only checked when wantTaint == true
 		wantPulseCalls int
 		wantLogReason  string // substring expected in structured log output; empty = skip check
+		wantDegraded   string // expected degraded label value; empty = label absent
+		wantSoftTaint  bool   // expect the degraded-tier PreferNoSchedule taint
 	}{
 		{
 			// Node rebooted after NVSentinel intervention; it had a zombie taint
@@ -84,6 +102,150 @@ func TestReconcileNode(t *testing.T) {
 			wantPulseCalls: 1,
 			wantLogReason:  "fail-slow variance pattern",
 		},
+		{
+			// nvidia-smi hung during preflight. The node could not be validated,
+			// so it is quarantined as a hard failure with the timeout recorded as
+			// its own reason rather than folded into pre_flight_failure.
+			name:           "hung stage — quarantined with stage_timeout reason",
+			node:           freshNode("gpu-node-4", 1*time.Minute),
+			pulseDuration:  0,
+			pulseErr:       fmt.Errorf("preflight: %w after 30s", pulse.ErrStageTimeout),
+			wantTaint:      true,
+			wantEffect:     corev1.TaintEffectNoSchedule,
+			wantPulseCalls: 1,
+			wantLogReason:  "stage_timeout",
+		},
+		{
+			// A GPU fell off the bus across the reboot. The seven survivors
+			// pass, but the lost capacity is its own quarantine reason.
+			name:           "GPU count shrank — quarantined with gpu_count_decreased reason",
+			node:           freshNode("gpu-node-10", 1*time.Minute),
+			pulseErr:       fmt.Errorf("%w: 7 visible, 8 at last passing pulse", pulse.ErrDeviceCountDecreased),
+			wantTaint:      true,
+			wantEffect:     corev1.TaintEffectNoSchedule,
+			wantPulseCalls: 1,
+			wantLogReason:  "gpu_count_decreased",
+		},
+		{
+			// Operators are evaluating the P2P check and have set it to warn.
+			// The node must stay schedulable; the failure is still logged.
+			name:           "warn-only severity — interconnect failure leaves node untainted",
+			node:           freshNode("gpu-node-5", 1*time.Minute),
+			pulseErr:       pulse.ErrInterconnectDegraded,
+			policy:         &policy.Policy{Severities: map[string]policy.Severity{"interconnect_degraded": policy.SeverityWarn}},
+			wantTaint:      false,
+			wantPulseCalls: 1,
+			wantLogReason:  "warn-only policy",
+		},
+		{
+			// Variance failures mapped to degrade-label get the label instead
+			// of the NoSchedule taint.
+			name:           "degrade-label severity — variance failure labels node",
+			node:           freshNode("gpu-node-6", 1*time.Minute),
+			pulseErr:       pulse.ErrHighVariance,
+			policy:         &policy.Policy{Severities: map[string]policy.Severity{"high_variance": policy.SeverityDegradeLabel}},
+			wantTaint:      false,
+			wantPulseCalls: 1,
+			wantDegraded:   "high_variance",
+		},
+		{
+			// Every device passed, but mean latency sits at 90% of the
+			// threshold. The default policy moves the node to the degraded
+			// tier: label plus PreferNoSchedule taint, no quarantine.
+			name:           "near-threshold pass — degraded tier by default",
+			node:           freshNode("gpu-node-7", 1*time.Minute),
+			pulseDuration:  32 * time.Millisecond,
+			pulseErr:       pulse.ErrNearThreshold,
+			wantTaint:      false,
+			wantPulseCalls: 1,
+			wantDegraded:   "near_threshold",
+			wantSoftTaint:  true,
+		},
+		{
+			// Every check passed, but the GEMM never loaded the GPU past the
+			// utilization floor. The default policy records it without
+			// touching scheduling.
+			name:           "inconclusive pulse — warn by default",
+			node:           freshNode("gpu-node-11", 1*time.Minute),
+			pulseErr:       pulse.ErrInconclusive,
+			wantTaint:      false,
+			wantPulseCalls: 1,
+			wantLogReason:  "warn-only policy",
+		},
+		{
+			// The policy's inconclusive handling overrides the severity of
+			// every inconclusive reason, including the quarantining default
+			// for stage timeouts.
+			name:           "inconclusive handling warn — stage timeout leaves node untainted",
+			node:           freshNode("gpu-node-12", 1*time.Minute),
+			pulseErr:       fmt.Errorf("clock_check: %w after 30s", pulse.ErrStageTimeout),
+			policy:         &policy.Policy{Inconclusive: policy.HandlingWarn},
+			wantTaint:      false,
+			wantPulseCalls: 1,
+			wantLogReason:  "warn-only policy",
+		},
+		{
+			name:           "inconclusive handling quarantine — utilization floor taints node",
+			node:           freshNode("gpu-node-13", 1*time.Minute),
+			pulseErr:       pulse.ErrInconclusive,
+			policy:         &policy.Policy{Inconclusive: policy.HandlingQuarantine},
+			wantTaint:      true,
+			wantEffect:     corev1.TaintEffectNoSchedule,
+			wantPulseCalls: 1,
+		},
+		{
+			// A quarantined node fails again, now with a reason the policy
+			// maps to warn. The quarantine is lifted rather than left behind
+			// a False condition.
+			name:           "warn-only severity — lifts an earlier quarantine",
+			node:           quarantinedNode("gpu-node-14", 1*time.Minute),
+			pulseErr:       pulse.ErrInterconnectDegraded,
+			policy:         &policy.Policy{Severities: map[string]policy.Severity{"interconnect_degraded": policy.SeverityWarn}},
+			wantTaint:      false,
+			wantPulseCalls: 1,
+			wantLogReason:  "quarantine lifted",
+		},
+		{
+			name:           "degrade severity — replaces an earlier quarantine",
+			node:           quarantinedNode("gpu-node-15", 1*time.Minute),
+			pulseErr:       pulse.ErrHighVariance,
+			policy:         &policy.Policy{Severities: map[string]policy.Severity{"high_variance": policy.SeverityDegrade}},
+			wantTaint:      false,
+			wantPulseCalls: 1,
+			wantDegraded:   "high_variance",
+			wantSoftTaint:  true,
+		},
+		{
+			// An inconclusive pulse has not shown the node healthy.
+			name:           "inconclusive pulse — keeps an earlier quarantine",
+			node:           quarantinedNode("gpu-node-16", 1*time.Minute),
+			pulseErr:       pulse.ErrInconclusive,
+			wantTaint:      true,
+			wantEffect:     corev1.TaintEffectNoSchedule,
+			wantPulseCalls: 1,
+			wantLogReason:  "keeping the earlier quarantine",
+		},
+		{
+			// Soft quarantine: a first offense only discourages scheduling.
+			name:           "soft quarantine — first offense gets PreferNoSchedule",
+			node:           freshNode("gpu-node-8", 1*time.Minute),
+			pulseErr:       pulse.ErrStragglerDetected,
+			policy:         &policy.Policy{SoftQuarantine: true},
+			wantTaint:      true,
+			wantEffect:     corev1.TaintEffectPreferNoSchedule,
+			wantPulseCalls: 1,
+		},
+		{
+			// Soft quarantine: failing again while soft-tainted confirms the
+			// straggler and escalates to NoSchedule.
+			name:           "soft quarantine — confirmed failure escalates to NoSchedule",
+			node:           softQuarantinedNode("gpu-node-9", 1*time.Minute),
+			pulseErr:       pulse.ErrStragglerDetected,
+			policy:         &policy.Policy{SoftQuarantine: true},
+			wantTaint:      true,
+			wantEffect:     corev1.TaintEffectNoSchedule,
+			wantPulseCalls: 1,
+		},
 	}
 
 	for _, tc := range cases {
@@ -98,6 +260,9 @@ func TestReconcileNode(t *testing.T) {
 				calls++
 				return tc.pulseDuration, tc.pulseErr
 			})
+			if tc.policy != nil {
+				WithPolicy(tc.policy)(ctrl)
+			}
 
 			// Inject a per-test logger backed by a buffer when we need to assert
 			// the structured log reason. Using a scoped logger avoids the data
@@ -133,6 +298,17 @@ func TestReconcileNode(t *testing.T) {
 				t.Errorf("taint effect=%v, want %v", taint.Effect, tc.wantEffect)
 			}
 
+			if gotLabel := got.Labels[DegradedLabel]; gotLabel != tc.wantDegraded {
+				t.Errorf("degraded label=%q, want %q", gotLabel, tc.wantDegraded)
+			}
+			soft := findTaint(got, DegradedLabel)
+			if (soft != nil) != tc.wantSoftTaint {
+				t.Errorf("degraded taint present=%v, want %v", soft != nil, tc.wantSoftTaint)
+			}
+			if soft != nil && soft.Effect != corev1.TaintEffectPreferNoSchedule {
+				t.Errorf("degraded taint effect=%v, want PreferNoSchedule", soft.Effect)
+			}
+
 			if tc.wantLogReason != "" {
 				logged := logBuf.String()
 				if !strings.Contains(logged, tc.wantLogReason) {
@@ -143,6 +319,215 @@ func TestReconcileNode(t *testing.T) {
 	}
 }
 
+func TestValidateLogsPulseIDSeenByExecutor(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(freshNode("gpu-node-1", time.Minute))
+	var seen string
+	ctrl := NewController(client, WithNodePulse(func(ctx context.Context, _ string) (time.Duration, error) {
+		seen = pulse.PulseIDFrom(ctx)
+		return 10 * time.Millisecond, nil
+	}))
+	var logBuf bytes.Buffer
+	ctrl.withLogger(slog.New(slog.NewJSONHandler(&logBuf, nil)))
+
+	if err := ctrl.ValidateNode(context.Background(), "gpu-node-1"); err != nil {
+		t.Fatalf("ValidateNode: %v", err)
+	}
+	if seen == "" || !strings.Contains(logBuf.String(), `"pulse_id":"`+seen+`"`) {
+		t.Errorf("executor saw pulse ID %q, not found in logs:\n%s", seen, logBuf.String())
+	}
+}
+
+func TestValidateEmitsEvents(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		node      *corev1.Node
+		pulseErr  error
+		wantEvent string // "" = no event
+	}{
+		{
+			name: "quarantine carries measured and threshold values",
+			node: freshNode("gpu-node-0", time.Minute),
+			pulseErr: &pulse.PulseFailure{
+				Cause:          pulse.ErrStragglerDetected,
+				MeasuredValue:  812,
+				ThresholdValue: 35,
+				Unit:           "ms",
+			},
+			wantEvent: "Warning StragglerQuarantined NoSchedule taint applied — latency threshold exceeded: measured 812 ms, threshold 35 ms",
+		},
+		{
+			name: "quarantine names the failing GPU's hardware",
+			node: freshNode("gpu-node-4", time.Minute),
+			pulseErr: &pulse.PulseFailure{
+				Cause:          pulse.ErrHighVariance,
+				MeasuredValue:  0.41,
+				ThresholdValue: 0.2,
+				Unit:           "cv",
+				GPU:            &pulse.GPUIdentity{UUID: "GPU-5d5ba0d6", Serial: "1652221001234", PCIBusID: "00000000:07:00.0"},
+			},
+			wantEvent: "Warning StragglerQuarantined NoSchedule taint applied — fail-slow variance pattern (high CV across runs): measured 0.41 cv, threshold 0.2 cv on GPU GPU-5d5ba0d6, serial 1652221001234, PCI 00000000:07:00.0 (pulse ",
+		},
+		{
+			name:      "clearance",
+			node:      quarantinedNode("gpu-node-1", time.Minute),
+			wantEvent: "Normal PulsePassed GPU pulse passed: worst mean 10ms",
+		},
+		{
+			name: "passing node that was never quarantined stays quiet",
+			node: freshNode("gpu-node-2", time.Minute),
+		},
+		{
+			name:     "already quarantined node is not re-announced",
+			node:     quarantinedNode("gpu-node-3", time.Minute),
+			pulseErr: pulse.ErrStragglerDetected,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := record.NewFakeRecorder(4)
+			ctrl := NewController(fake.NewSimpleClientset(tc.node),
+				WithPulseFunc(func() (time.Duration, error) { return 10 * time.Millisecond, tc.pulseErr }),
+				WithEventRecorder(rec),
+			)
+			if err := ctrl.ValidateNode(context.Background(), tc.node.Name); err != nil {
+				t.Fatalf("ValidateNode: %v", err)
+			}
+
+			select {
+			case got := <-rec.Events:
+				if tc.wantEvent == "" {
+					t.Errorf("unexpected event %q", got)
+				} else if !strings.HasPrefix(got, tc.wantEvent) {
+					t.Errorf("event = %q, want prefix %q", got, tc.wantEvent)
+				}
+			default:
+				if tc.wantEvent != "" {
+					t.Errorf("no event, want %q", tc.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestResultCacheReusesFreshVerdict(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		gap       time.Duration // time between the two validations
+		wantCalls int
+	}{
+		{name: "within freshness window reuses result", gap: 30 * time.Second, wantCalls: 1},
+		{name: "stale result re-runs pulse", gap: 2 * time.Minute, wantCalls: 2},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(freshNode("gpu-node-0", time.Minute))
+			calls := 0
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) {
+					calls++
+					return 600 * time.Millisecond, pulse.ErrStragglerDetected
+				}),
+				WithResultCache(time.Minute),
+			)
+			now := time.Now()
+			ctrl.cache.now = func() time.Time { return now }
+
+			if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+				t.Fatalf("first ValidateNode: %v", err)
+			}
+			now = now.Add(tc.gap)
+			if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+				t.Fatalf("second ValidateNode: %v", err)
+			}
+
+			if calls != tc.wantCalls {
+				t.Errorf("pulse called %d time(s), want %d", calls, tc.wantCalls)
+			}
+			got, err := client.CoreV1().Nodes().Get(context.Background(), "gpu-node-0", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get node: %v", err)
+			}
+			if findTaint(got, zombieTaintKey) == nil {
+				t.Error("cached failure did not keep the node quarantined")
+			}
+		})
+	}
+}
+
+func TestTaintPatchErrorsAreClassified(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		node     *corev1.Node
+		pulseErr error
+		apiErr   error
+		want     error
+	}{
+		{
+			name:     "forbidden apply",
+			node:     freshNode("gpu-node-0", time.Minute),
+			pulseErr: pulse.ErrStragglerDetected,
+			apiErr:   apierrors.NewForbidden(nodeGR, "gpu-node-0", errors.New("rbac")),
+			want:     ErrForbidden,
+		},
+		{
+			name:     "conflicting apply",
+			node:     freshNode("gpu-node-0", time.Minute),
+			pulseErr: pulse.ErrStragglerDetected,
+			apiErr:   apierrors.NewConflict(nodeGR, "gpu-node-0", errors.New("stale")),
+			want:     ErrPatchConflict,
+		},
+		{
+			name:   "remove on deleted node",
+			node:   quarantinedNode("gpu-node-0", time.Minute),
+			apiErr: apierrors.NewNotFound(nodeGR, "gpu-node-0"),
+			want:   ErrNodeNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(tc.node)
+			client.PrependReactor("patch", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if p, ok := action.(k8stesting.PatchAction); ok && strings.Contains(string(p.GetPatch()), "taints") {
+					return true, nil, tc.apiErr
+				}
+				return false, nil, nil
+			})
+			ctrl := newControllerWithPulse(client, func() (time.Duration, error) { return time.Millisecond, tc.pulseErr })
+
+			err := ctrl.ValidateNode(context.Background(), tc.node.Name)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+			if !errors.Is(err, tc.apiErr) {
+				t.Errorf("err = %v, API error dropped from chain", err)
+			}
+		})
+	}
+}
+
+// nodeGR is the GroupResource for building API errors on nodes.
+var nodeGR = schema.GroupResource{Resource: "nodes"}
+
 // freshNode returns a node whose Ready condition just transitioned at -age.
 func freshNode(name string, age time.Duration) *corev1.Node {
 	return &corev1.Node{
@@ -161,13 +546,27 @@ func freshNode(name string, age time.Duration) *corev1.Node {
 }
 
 // quarantinedNode returns a freshly-Ready node that already carries the zombie
-// taint — simulating a node that was quarantined in a previous failure cycle
-// and has just rebooted.
+// taint and its GPUStraggler=True condition — simulating a node that was
+// quarantined in a previous failure cycle and has just rebooted.
 func quarantinedNode(name string, age time.Duration) *corev1.Node {
 	n := freshNode(name, age)
 	n.Spec.Taints = []corev1.Taint{
 		{Key: zombieTaintKey, Effect: corev1.TaintEffectNoSchedule, Value: "820ms"},
 	}
+	n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{
+		Type:               zombieCondition,
+		Status:             corev1.ConditionTrue,
+		Reason:             "StragglerDetected",
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-age - time.Minute)),
+	})
+	return n
+}
+
+// softQuarantinedNode returns a freshly-Ready node carrying the zombie taint
+// with PreferNoSchedule — a first offense under soft quarantine.
+func softQuarantinedNode(name string, age time.Duration) *corev1.Node {
+	n := quarantinedNode(name, age)
+	n.Spec.Taints[0].Effect = corev1.TaintEffectPreferNoSchedule
 	return n
 }
 
@@ -180,3 +579,187 @@ func findTaint(node *corev1.Node, key string) *corev1.Taint {
 	}
 	return nil
 }
+
+func TestClassifyPreFlight(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{&pulse.PulseFailure{Cause: fmt.Errorf("pre-flight: %w: Xid 79 on 0000:07:00.0", pulse.ErrXIDEvent), MeasuredValue: 79, Unit: "xid"}, "xid_error"},
+		{fmt.Errorf("pre-flight: %w: GPU 2: row remap failed (no spare rows left in the bank)", pulse.ErrRowRemap), "row_remap_failure"},
+		{fmt.Errorf("pre-flight GPU 0: %w: 2 since last boot or clean pulse", pulse.ErrECCErrors), "ecc_errors"},
+		{errors.New("pre-flight: nvidia-smi: exit status 15"), "pre_flight_failure"},
+	}
+	for _, tc := range cases {
+		reason, _ := classify(tc.err)
+		if reason != tc.want {
+			t.Errorf("classify(%v) = %s, want %s", tc.err, reason, tc.want)
+		}
+		if !slices.Contains(policy.Reasons, reason) {
+			t.Errorf("reason %s is missing from policy.Reasons", reason)
+		}
+	}
+}
+
+func TestQuarantineConditionListsFailedComponents(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", time.Minute)
+	client := fake.NewSimpleClientset(node)
+	failure := &pulse.PulseFailure{
+		Cause:          fmt.Errorf("GPU 1: %w (mean=612ms)", pulse.ErrStragglerDetected),
+		MeasuredValue:  612,
+		ThresholdValue: 500,
+		Unit:           "ms",
+	}
+	ctrl := NewController(client, WithNodeReport(func(context.Context, string) (*pulse.PulseReport, error) {
+		return &pulse.PulseReport{
+			PulseID:     "p-1",
+			WorstMeanNS: (612 * time.Millisecond).Nanoseconds(),
+			Devices: []pulse.DeviceResult{
+				{Device: 0},
+				{Device: 1, Error: failure.Error()},
+				{Device: 3, Error: "GPU 3: straggler detected: GPU pulse latency exceeded threshold (mean=540ms)"},
+			},
+		}, failure
+	}))
+
+	if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+		t.Fatalf("ValidateNode: %v", err)
+	}
+	got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get node: %v", err)
+	}
+	cond := StragglerCondition(got)
+	if cond == nil {
+		t.Fatal("no GPUStraggler condition")
+	}
+	for _, want := range []string{"measured 612 ms", "2 components failed", "GPU 1: ", "GPU 3: "} {
+		if !strings.Contains(cond.Message, want) {
+			t.Errorf("condition message %q lacks %q", cond.Message, want)
+		}
+	}
+}
+
+func TestQuarantinedGauge(t *testing.T) {
+	t.Parallel()
+
+	const node = "gpu-node-quarantined-gauge"
+	var pulseErr error
+	ctrl := NewController(fake.NewSimpleClientset(freshNode(node, time.Minute)),
+		WithPulseFunc(func() (time.Duration, error) { return 10 * time.Millisecond, pulseErr }),
+	)
+	validate := func(err error) {
+		t.Helper()
+		pulseErr = err
+		if err := ctrl.ValidateNode(context.Background(), node); err != nil {
+			t.Fatalf("ValidateNode: %v", err)
+		}
+	}
+
+	validate(pulse.ErrHighVariance)
+	if got := quarantinedSeries(t, node); len(got) != 1 || got["high_variance"] != 1 {
+		t.Errorf("after quarantine: series %v, want high_variance=1", got)
+	}
+	validate(pulse.ErrStragglerDetected)
+	if got := quarantinedSeries(t, node); len(got) != 2 || got["latency_threshold_exceeded"] != 1 || got["high_variance"] != 0 {
+		t.Errorf("after a new reason: series %v, want latency_threshold_exceeded=1 and high_variance=0", got)
+	}
+	validate(nil)
+	if got := quarantinedSeries(t, node); len(got) != 2 || got["latency_threshold_exceeded"] != 0 || got["high_variance"] != 0 {
+		t.Errorf("after clear: series %v, want both reasons at 0", got)
+	}
+}
+
+func TestQuarantinedGaugeSeededFromNodes(t *testing.T) {
+	t.Parallel()
+
+	// Quarantined by an earlier controller: one node records the reason in
+	// its decision annotation, the other only in its taint value.
+	decided := quarantinedNode("gpu-node-seed-0", time.Hour)
+	decided.Annotations = map[string]string{}
+	for k, v := range decisionAnnotations(opQuarantine, "high_variance", 0, "") {
+		decided.Annotations[k] = v.(string)
+	}
+	tainted := quarantinedNode("gpu-node-seed-1", time.Hour)
+	tainted.Spec.Taints[0].Value = TaintValue{Reason: "pcie_degraded", Measured: 8, Threshold: 16}.String()
+	ctrl := NewController(fake.NewSimpleClientset(decided, tainted))
+
+	ctrl.ObserveNode(decided)
+	ctrl.ObserveNode(tainted)
+	if got := quarantinedSeries(t, decided.Name); len(got) != 1 || got["high_variance"] != 1 {
+		t.Errorf("%s series %v, want high_variance=1", decided.Name, got)
+	}
+	if got := quarantinedSeries(t, tainted.Name); len(got) != 1 || got["pcie_degraded"] != 1 {
+		t.Errorf("%s series %v, want pcie_degraded=1", tainted.Name, got)
+	}
+
+	// Cleared outside the controller, as by ForceClear: the seeded series
+	// drops to 0.
+	cleared := freshNode(decided.Name, time.Hour)
+	ctrl.ObserveNode(cleared)
+	if got := quarantinedSeries(t, decided.Name); len(got) != 1 || got["high_variance"] != 0 {
+		t.Errorf("after clear: series %v, want high_variance=0", got)
+	}
+}
+
+// quarantinedSeries returns node's metrics.Quarantined series by reason.
+func quarantinedSeries(t *testing.T, node string) map[string]float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 64)
+	go func() { metrics.Quarantined.Collect(ch); close(ch) }()
+	series := map[string]float64{}
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		labels := map[string]string{}
+		for _, l := range pb.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["node"] == node {
+			series[labels["reason"]] = pb.GetGauge().GetValue()
+		}
+	}
+	return series
+}
+
+func TestRiskScore(t *testing.T) {
+	t.Parallel()
+
+	const nodeName = "gpu-node-risk-score"
+	clientset := fake.NewSimpleClientset(freshNode(nodeName, time.Minute))
+	th := pulse.Snapshot{StragglerThreshold: 20 * time.Millisecond, MaxCV: 0.2, MaxIdleTempC: 80}
+	var report *pulse.PulseReport
+	var pulseErr error
+	ctrl := NewController(clientset,
+		WithNodeReport(func(context.Context, string) (*pulse.PulseReport, error) { return report, pulseErr }),
+	)
+	validate := func(mean time.Duration, err error) (string, float64) {
+		t.Helper()
+		report = &pulse.PulseReport{Thresholds: th, WorstMeanNS: mean.Nanoseconds()}
+		pulseErr = err
+		if err := ctrl.ValidateNode(context.Background(), nodeName); err != nil {
+			t.Fatalf("ValidateNode: %v", err)
+		}
+		node, _ := clientset.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+		return node.Annotations[RiskScoreAnnotation], testutil.ToFloat64(metrics.RiskScore.WithLabelValues(nodeName))
+	}
+
+	if ann, gauge := validate(8*time.Millisecond, nil); ann != "0.00" || gauge != 0 {
+		t.Errorf("healthy pulse: annotation %q, gauge %v; want 0.00", ann, gauge)
+	}
+	if ann, gauge := validate(15*time.Millisecond, nil); ann != "0.50" || gauge != 0.5 {
+		t.Errorf("pulse in the margin: annotation %q, gauge %v; want 0.50", ann, gauge)
+	}
+	if ann, gauge := validate(40*time.Millisecond, pulse.ErrStragglerDetected); ann != "1.00" || gauge != 1 {
+		t.Errorf("failed pulse: annotation %q, gauge %v; want 1.00", ann, gauge)
+	}
+	if ann, _ := validate(0, pulse.ErrNoCUDA); ann != "1.00" {
+		t.Errorf("pulse without CUDA changed the annotation to %q", ann)
+	}
+}
@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// DefaultTaintKey is the quarantine taint key used unless WithTaint overrides
+// it.
+const DefaultTaintKey = "sunk.coreweave.com/zombie-quarantine"
+
+// TaintConfig is the quarantine taint the controller writes. Clusters with an
+// existing remediation convention (a shared "gpu-unhealthy" key, NoExecute to
+// drain running pods) set it to match instead of forking.
+type TaintConfig struct {
+	Key string
+
+	// Value is written as the taint value. Empty, the default, writes a
+	// structured reason code (see TaintValue).
+	Value string
+
+	// Effect is the full-quarantine effect: NoSchedule (default), NoExecute
+	// to also evict running pods, or PreferNoSchedule. Policy soft
+	// quarantine still applies PreferNoSchedule on a first offense unless
+	// Effect already is PreferNoSchedule.
+	Effect corev1.TaintEffect
+}
+
+// DefaultTaint returns the built-in quarantine taint.
+func DefaultTaint() TaintConfig {
+	return TaintConfig{Key: DefaultTaintKey, Effect: corev1.TaintEffectNoSchedule}
+}
+
+// Validate checks that t can be written to a node.
+func (t TaintConfig) Validate() error {
+	if errs := validation.IsQualifiedName(t.Key); len(errs) > 0 {
+		return fmt.Errorf("taint key %q: %s", t.Key, strings.Join(errs, "; "))
+	}
+	if t.Value != "" {
+		if errs := validation.IsValidLabelValue(t.Value); len(errs) > 0 {
+			return fmt.Errorf("taint value %q: %s", t.Value, strings.Join(errs, "; "))
+		}
+	}
+	switch t.Effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		return nil
+	default:
+		return fmt.Errorf("taint effect %q: want NoSchedule, PreferNoSchedule or NoExecute", t.Effect)
+	}
+}
+
+// WithTaint replaces the quarantine taint. t must pass Validate. Runner pods
+// (PodRunner.TaintKey) and fleet tooling (QuarantineStateForKey) must be given
+// the same key.
+func WithTaint(t TaintConfig) Option {
+	return func(c *Controller) { c.taint = t }
+}
+
+// effectRank orders taint effects by severity so applyTaint only escalates.
+func effectRank(e corev1.TaintEffect) int {
+	switch e {
+	case corev1.TaintEffectPreferNoSchedule:
+		return 1
+	case corev1.TaintEffectNoSchedule:
+		return 2
+	case corev1.TaintEffectNoExecute:
+		return 3
+	}
+	return 0
+}
+
+// patchNodeSpec merge-patches node's taints to edit(current taints), along
+// with the other spec fields and metadata in spec and meta (either may be
+// nil). A merge patch replaces the whole taint list, so it carries node's
+// resourceVersion: if the node changed since it was read, for example another
+// controller added its own taint, the API server rejects the patch with a
+// conflict. The node is then read again and edit re-applied, rather than a
+// stale list written over the other writer's taints. node is updated to the
+// taints written. A nil edit leaves the taints alone and patches spec and
+// meta without the precondition.
+func patchNodeSpec(ctx context.Context, client kubernetes.Interface, node *corev1.Node, edit func([]corev1.Taint) []corev1.Taint, spec, meta map[string]any) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		s, m := maps.Clone(spec), maps.Clone(meta)
+		if s == nil {
+			s = map[string]any{}
+		}
+		if m == nil {
+			m = map[string]any{}
+		}
+		if edit != nil {
+			s["taints"] = edit(append([]corev1.Taint(nil), node.Spec.Taints...))
+			if node.ResourceVersion != "" {
+				m["resourceVersion"] = node.ResourceVersion
+			}
+		}
+		patch := map[string]any{}
+		if len(s) > 0 {
+			patch["spec"] = s
+		}
+		if len(m) > 0 {
+			patch["metadata"] = m
+		}
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return fmt.Errorf("marshal node patch: %w", err)
+		}
+		updated, err := client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, data, metav1.PatchOptions{})
+		if apierrors.IsConflict(err) {
+			fresh, gerr := client.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+			if gerr != nil {
+				return gerr
+			}
+			node.Spec.Taints, node.ResourceVersion = fresh.Spec.Taints, fresh.ResourceVersion
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		node.Spec.Taints, node.ResourceVersion = updated.Spec.Taints, updated.ResourceVersion
+		return nil
+	})
+}
@@ -0,0 +1,270 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestConfiguredTaint(t *testing.T) {
+	t.Parallel()
+
+	const key = "example.com/gpu-unhealthy"
+	cases := []struct {
+		name       string
+		taint      TaintConfig
+		soft       bool
+		existing   corev1.TaintEffect // "" = node starts untainted
+		wantEffect corev1.TaintEffect
+		wantValue  string
+	}{
+		{
+			name:       "custom key, NoExecute, fixed value",
+			taint:      TaintConfig{Key: key, Value: "straggler", Effect: corev1.TaintEffectNoExecute},
+			wantEffect: corev1.TaintEffectNoExecute,
+			wantValue:  "straggler",
+		},
+		{
+			name:       "empty value records reason code",
+			taint:      TaintConfig{Key: key, Effect: corev1.TaintEffectNoSchedule},
+			wantEffect: corev1.TaintEffectNoSchedule,
+			wantValue:  "latency_threshold_exceeded-600-0",
+		},
+		{
+			name:       "soft quarantine escalates to configured effect",
+			taint:      TaintConfig{Key: key, Effect: corev1.TaintEffectNoExecute},
+			soft:       true,
+			existing:   corev1.TaintEffectPreferNoSchedule,
+			wantEffect: corev1.TaintEffectNoExecute,
+			wantValue:  "latency_threshold_exceeded-600-0",
+		},
+		{
+			name:       "existing harder taint is not downgraded",
+			taint:      TaintConfig{Key: key, Effect: corev1.TaintEffectPreferNoSchedule},
+			existing:   corev1.TaintEffectNoExecute,
+			wantEffect: corev1.TaintEffectNoExecute,
+			wantValue:  "820ms",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node := freshNode("gpu-node-0", time.Minute)
+			if tc.existing != "" {
+				node.Spec.Taints = []corev1.Taint{{Key: key, Value: "820ms", Effect: tc.existing}}
+			}
+			client := fake.NewSimpleClientset(node)
+			ctrl := NewController(client,
+				WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulse.ErrStragglerDetected }),
+				WithPolicy(&policy.Policy{SoftQuarantine: tc.soft}),
+				WithTaint(tc.taint),
+			)
+			if err := ctrl.ValidateNode(context.Background(), "gpu-node-0"); err != nil {
+				t.Fatalf("ValidateNode: %v", err)
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), "gpu-node-0", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get node: %v", err)
+			}
+			if findTaint(got, zombieTaintKey) != nil {
+				t.Error("default taint key written despite WithTaint")
+			}
+			taint := findTaint(got, key)
+			if taint == nil {
+				t.Fatalf("no %s taint (taints: %v)", key, got.Spec.Taints)
+			}
+			if taint.Effect != tc.wantEffect || withoutPulseID(taint.Value) != tc.wantValue {
+				t.Errorf("taint = %s/%s, want %s/%s", taint.Value, taint.Effect, tc.wantValue, tc.wantEffect)
+			}
+			if st := QuarantineStateForKey(got, key); st == StateHealthy {
+				t.Errorf("QuarantineStateForKey = %s", st)
+			}
+		})
+	}
+}
+
+func TestTaintConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		taint   TaintConfig
+		wantErr bool
+	}{
+		{name: "default", taint: DefaultTaint()},
+		{name: "NoExecute", taint: TaintConfig{Key: "gpu-unhealthy", Effect: corev1.TaintEffectNoExecute}},
+		{name: "invalid key", taint: TaintConfig{Key: "not a key", Effect: corev1.TaintEffectNoSchedule}, wantErr: true},
+		{name: "invalid value", taint: TaintConfig{Key: DefaultTaintKey, Value: "has space", Effect: corev1.TaintEffectNoSchedule}, wantErr: true},
+		{name: "unknown effect", taint: TaintConfig{Key: DefaultTaintKey, Effect: "NoRun"}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc.taint.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestTaintPatchConflict has another controller taint the node just before
+// each of our taint patches lands. The fake clientset ignores
+// resourceVersion, so a reactor enforces the precondition the API server
+// would.
+func TestTaintPatchConflict(t *testing.T) {
+	t.Parallel()
+
+	const nodeName = "gpu-node-conflict"
+	node := freshNode(nodeName, time.Minute)
+	node.ResourceVersion = "1"
+	clientset := fake.NewSimpleClientset(node)
+	nodes := corev1.SchemeGroupVersion.WithResource("nodes")
+	// other adds another controller's taint to the stored node.
+	version := 1
+	other := func() {
+		current, _ := clientset.Tracker().Get(nodes, "", nodeName)
+		n := current.(*corev1.Node).DeepCopy()
+		version++
+		n.ResourceVersion = fmt.Sprint(version)
+		n.Spec.Taints = append(n.Spec.Taints, corev1.Taint{Key: fmt.Sprintf("example.com/other-%d", version), Effect: corev1.TaintEffectNoSchedule})
+		if err := clientset.Tracker().Update(nodes, n, ""); err != nil {
+			t.Error(err)
+		}
+	}
+	conflicts, race := 0, false
+	clientset.PrependReactor("patch", "nodes", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		var patch struct {
+			Metadata struct {
+				ResourceVersion string `json:"resourceVersion"`
+			} `json:"metadata"`
+		}
+		_ = json.Unmarshal(a.(k8stesting.PatchAction).GetPatch(), &patch)
+		if race && patch.Metadata.ResourceVersion != "" {
+			race = false
+			other()
+		}
+		stored, err := clientset.Tracker().Get(nodes, "", nodeName)
+		if err != nil {
+			return true, nil, err
+		}
+		if rv := patch.Metadata.ResourceVersion; rv != "" && rv != stored.(*corev1.Node).ResourceVersion {
+			conflicts++
+			return true, nil, apierrors.NewConflict(nodes.GroupResource(), nodeName, fmt.Errorf("resourceVersion %s is stale", rv))
+		}
+		return false, nil, nil
+	})
+
+	var pulseErr error
+	ctrl := NewController(clientset, WithPulseFunc(func() (time.Duration, error) { return 10 * time.Millisecond, pulseErr }))
+	validate := func(err error) []corev1.Taint {
+		t.Helper()
+		pulseErr, race = err, true
+		if err := ctrl.ValidateNode(context.Background(), nodeName); err != nil {
+			t.Fatalf("ValidateNode: %v", err)
+		}
+		got, _ := clientset.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+		return got.Spec.Taints
+	}
+
+	taints := validate(pulse.ErrStragglerDetected)
+	if findTaintByKey(taints, DefaultTaintKey) == nil || findTaintByKey(taints, "example.com/other-2") == nil {
+		t.Errorf("after quarantine: taints = %v, want ours and the other controller's", taints)
+	}
+	taints = validate(nil)
+	if findTaintByKey(taints, DefaultTaintKey) != nil || findTaintByKey(taints, "example.com/other-2") == nil || findTaintByKey(taints, "example.com/other-3") == nil {
+		t.Errorf("after clear: taints = %v, want only the other controller's", taints)
+	}
+	if conflicts != 2 {
+		t.Errorf("conflicts = %d, want one per patch", conflicts)
+	}
+}
+
+func TestClassTaints(t *testing.T) {
+	t.Parallel()
+
+	p := &policy.Policy{Taints: map[string]policy.Taint{"interconnect_degraded": {Value: "fabric"}}}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	node := freshNode("gpu-node-0", time.Minute)
+	client := fake.NewSimpleClientset(node)
+	var pulseErr error
+	ctrl := NewController(client,
+		WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulseErr }),
+		WithPolicy(p),
+	)
+
+	// A pod that can run without the fabric tolerates this class only.
+	inference := gpuPod("inference", node.Name, 1)
+	inference.Spec.Tolerations = []corev1.Toleration{{Key: zombieTaintKey, Operator: corev1.TolerationOpEqual, Value: "fabric"}}
+
+	for _, step := range []struct {
+		err       error
+		wantValue string
+		tolerated bool
+	}{
+		{pulse.ErrInterconnectDegraded, "fabric", true},
+		// The GEMM now fails too: the class taint gives way to the default.
+		{pulse.ErrHighVariance, "high_variance-600-0", false},
+		{pulse.ErrInterconnectDegraded, "fabric", true},
+	} {
+		pulseErr = step.err
+		if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+			t.Fatalf("ValidateNode(%v): %v", step.err, err)
+		}
+		got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		taint := findTaint(got, zombieTaintKey)
+		if taint == nil || withoutPulseID(taint.Value) != step.wantValue || taint.Effect != corev1.TaintEffectNoSchedule {
+			t.Errorf("after %v: taint = %+v, want %s=%s:NoSchedule", step.err, taint, zombieTaintKey, step.wantValue)
+		}
+		if got := ctrl.toleratesQuarantine(got, inference); got != step.tolerated {
+			t.Errorf("after %v: inference pod tolerated = %v, want %v", step.err, got, step.tolerated)
+		}
+	}
+}
+
+func TestToleratesQuarantineEffect(t *testing.T) {
+	t.Parallel()
+
+	ctrl := NewController(fake.NewSimpleClientset())
+	node := freshNode("gpu-node-0", time.Minute)
+	node.Spec.Taints = []corev1.Taint{{Key: zombieTaintKey, Value: "fabric", Effect: corev1.TaintEffectNoExecute}}
+
+	for _, tc := range []struct {
+		name       string
+		toleration corev1.Toleration
+		want       bool
+	}{
+		{"NoSchedule only", corev1.Toleration{Key: zombieTaintKey, Operator: corev1.TolerationOpEqual, Value: "fabric", Effect: corev1.TaintEffectNoSchedule}, false},
+		{"NoExecute", corev1.Toleration{Key: zombieTaintKey, Operator: corev1.TolerationOpEqual, Value: "fabric", Effect: corev1.TaintEffectNoExecute}, true},
+		{"any effect", corev1.Toleration{Key: zombieTaintKey, Operator: corev1.TolerationOpEqual, Value: "fabric"}, true},
+		{"another class", corev1.Toleration{Key: zombieTaintKey, Operator: corev1.TolerationOpEqual, Value: "jitter", Effect: corev1.TaintEffectNoExecute}, false},
+	} {
+		pod := gpuPod("inference", node.Name, 1)
+		pod.Spec.Tolerations = []corev1.Toleration{tc.toleration}
+		if got := ctrl.toleratesQuarantine(node, pod); got != tc.want {
+			t.Errorf("%s: tolerated = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// TaintValue is the structured value of the quarantine taint when TaintConfig
+// and the policy leave it empty. Controllers that read the taint, rather
+// than the GPUStraggler condition, use ParseTaintValue to act on why a node
+// was quarantined.
+//
+// A taint value must be a valid label value: at most 63 characters of
+// letters, digits, '-', '_' and '.'. So the fields are positional and
+// dash-separated rather than key=value pairs:
+//
+//	high_variance-0.35-0.2-3f9a1c0b7d2e
+//
+// is reason, measured value, threshold and pulse ID prefix. Values too long
+// to fit are both written as NaN:
+//
+//	latency_threshold_exceeded-NaN-NaN-3f9a1c0b7d2e
+type TaintValue struct {
+	// Reason is the failure_reason code, e.g. "high_variance".
+	Reason string
+	// Measured and Threshold are the failing check's values, in its unit
+	// (see pulse.PulseFailure). A failure without them records the pulse
+	// duration in milliseconds as Measured and a zero Threshold.
+	Measured  float64
+	Threshold float64
+	// PulseID is a prefix of the pulse's ID, enough to find its logs and
+	// audit record; empty when unknown or when it did not fit.
+	PulseID string
+}
+
+// taintPulseIDLen is the pulse ID prefix written into a taint value.
+const taintPulseIDLen = 12
+
+// taintNoValues stands in for the measured value and threshold when they do
+// not fit. It parses back as NaN, never as a real measurement.
+const taintNoValues = "NaN"
+
+var taintReasonRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// newTaintValue builds the taint value for a quarantine for reason, measured
+// by report and failed with cause.
+func newTaintValue(reason string, report *pulse.PulseReport, cause error) TaintValue {
+	v := TaintValue{Reason: reason, Measured: float64(report.Elapsed().Milliseconds())}
+	var detail *pulse.PulseFailure
+	if errors.As(cause, &detail) {
+		v.Measured, v.Threshold = detail.MeasuredValue, detail.ThresholdValue
+	}
+	if report != nil {
+		v.PulseID = report.PulseID
+	}
+	return v
+}
+
+// String formats v as a taint value. Numbers are rounded to three decimals
+// and the pulse ID is cut to fit, or dropped, so the result is always a valid
+// label value. Numbers that cannot fit are both written as NaN. It is empty
+// if Reason is not a reason code short enough to fit.
+func (v TaintValue) String() string {
+	s := v.Reason + "-" + taintNumber(v.Measured) + "-" + taintNumber(v.Threshold)
+	if len(s) > validation.LabelValueMaxLength {
+		s = v.Reason + "-" + taintNoValues + "-" + taintNoValues
+	}
+	if !taintReasonRe.MatchString(v.Reason) || len(s) > validation.LabelValueMaxLength {
+		return ""
+	}
+	id := strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' && r < 'A' || r > 'Z' && r < 'a' || r > 'z' {
+			return -1
+		}
+		return r
+	}, v.PulseID)
+	id = id[:min(len(id), taintPulseIDLen, validation.LabelValueMaxLength-len(s)-1)]
+	if id == "" {
+		return s
+	}
+	return s + "-" + id
+}
+
+// taintNumber formats f for a taint value: no sign, exponent or trailing
+// zeros. Negative and non-finite values are written as 0.
+func taintNumber(f float64) string {
+	if f <= 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+// ParseTaintValue parses a taint value written by the controller (see
+// TaintValue). Values written as NaN because they did not fit parse as NaN;
+// check with math.IsNaN before using them. A fixed value from TaintConfig or a policy class, or the
+// bare pulse duration earlier releases wrote, is not in that form and
+// returns an error.
+func ParseTaintValue(s string) (TaintValue, error) {
+	fields := strings.Split(s, "-")
+	if len(fields) != 3 && len(fields) != 4 {
+		return TaintValue{}, fmt.Errorf("taint value %q: want reason-measured-threshold[-pulse]", s)
+	}
+	if !taintReasonRe.MatchString(fields[0]) {
+		return TaintValue{}, fmt.Errorf("taint value %q: reason %q is not a reason code", s, fields[0])
+	}
+	v := TaintValue{Reason: fields[0]}
+	var err error
+	if v.Measured, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return TaintValue{}, fmt.Errorf("taint value %q: measured: %w", s, err)
+	}
+	if v.Threshold, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return TaintValue{}, fmt.Errorf("taint value %q: threshold: %w", s, err)
+	}
+	if len(fields) == 4 {
+		if fields[3] == "" {
+			return TaintValue{}, fmt.Errorf("taint value %q: empty pulse ID", s)
+		}
+		v.PulseID = fields[3]
+	}
+	return v, nil
+}
@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// withoutPulseID strips the pulse ID from a structured taint value, which
+// differs on every pulse. Other values are returned unchanged.
+func withoutPulseID(value string) string {
+	v, err := ParseTaintValue(value)
+	if err != nil {
+		return value
+	}
+	v.PulseID = ""
+	return v.String()
+}
+
+func TestTaintValue(t *testing.T) {
+	t.Parallel()
+
+	report := &pulse.PulseReport{PulseID: "3f9a1c0b7d2e4a5b8c9d0e1f2a3b4c5d", WorstMeanNS: int64(600 * time.Millisecond)}
+	detail := &pulse.PulseFailure{Cause: pulse.ErrHighVariance, MeasuredValue: 0.35, ThresholdValue: 0.2, Unit: "cv"}
+	cases := []struct {
+		name   string
+		reason string
+		report *pulse.PulseReport
+		cause  error
+		want   string
+	}{
+		{"pulse failure", "high_variance", report, fmt.Errorf("gemm: %w", detail), "high_variance-0.35-0.2-3f9a1c0b7d2e"},
+		{"no detail records duration", "latency_threshold_exceeded", report, pulse.ErrStragglerDetected, "latency_threshold_exceeded-600-0-3f9a1c0b7d2e"},
+		{"no pulse ID", "high_variance", &pulse.PulseReport{}, detail, "high_variance-0.35-0.2"},
+		{"rounded", "low_power", report, &pulse.PulseFailure{MeasuredValue: 212.34567, ThresholdValue: 245}, "low_power-212.346-245-3f9a1c0b7d2e"},
+		{"negative", "external_check", report, &pulse.PulseFailure{MeasuredValue: -1}, "external_check-0-0-3f9a1c0b7d2e"},
+		{"invalid reason", "not a reason", report, detail, ""},
+	}
+	for _, tc := range cases {
+		got := newTaintValue(tc.reason, tc.report, tc.cause).String()
+		if got != tc.want {
+			t.Errorf("%s: value = %q, want %q", tc.name, got, tc.want)
+		}
+		if got == "" {
+			continue
+		}
+		if errs := validation.IsValidLabelValue(got); len(errs) > 0 {
+			t.Errorf("%s: %q is not a valid taint value: %v", tc.name, got, errs)
+		}
+		if _, err := ParseTaintValue(got); err != nil {
+			t.Errorf("%s: ParseTaintValue(%q) = %v", tc.name, got, err)
+		}
+	}
+}
+
+func TestTaintValueFits(t *testing.T) {
+	t.Parallel()
+
+	v := TaintValue{Reason: "latency_threshold_exceeded", Measured: 123456789.123, Threshold: 98765432.1, PulseID: "3f9a1c0b7d2e4a5b"}
+	got := v.String()
+	if len(got) > validation.LabelValueMaxLength || !strings.HasPrefix(got, "latency_threshold_exceeded-123456789.123-98765432.1-") {
+		t.Errorf("value = %q, want the pulse ID cut to fit %d characters", got, validation.LabelValueMaxLength)
+	}
+	v.Measured = 1e40
+	got = v.String()
+	if got != "latency_threshold_exceeded-NaN-NaN-3f9a1c0b7d2e" {
+		t.Errorf("value = %q, want the numbers written as NaN when they cannot fit", got)
+	}
+	parsed, err := ParseTaintValue(got)
+	if err != nil || parsed.Reason != v.Reason || !math.IsNaN(parsed.Measured) || !math.IsNaN(parsed.Threshold) || parsed.PulseID != "3f9a1c0b7d2e" {
+		t.Errorf("ParseTaintValue(%q) = %+v, %v, want NaN values", got, parsed, err)
+	}
+}
+
+func TestParseTaintValue(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseTaintValue("high_variance-0.35-0.2-3f9a1c0b7d2e")
+	if want := (TaintValue{Reason: "high_variance", Measured: 0.35, Threshold: 0.2, PulseID: "3f9a1c0b7d2e"}); err != nil || got != want {
+		t.Errorf("ParseTaintValue = %+v, %v, want %+v", got, err, want)
+	}
+	for _, value := range []string{
+		"600ms",                 // written by earlier releases
+		"fabric",                // a policy class value
+		"high_variance-0.35",    // threshold missing
+		"high_variance-x-0.2",   // measured not a number
+		"High-Variance-0.35-1",  // not a reason code
+		"high_variance-1-2-",    // empty pulse ID
+		"high_variance-1-2-a-b", // too many fields
+	} {
+		if v, err := ParseTaintValue(value); err == nil {
+			t.Errorf("ParseTaintValue(%q) = %+v, want an error", value, v)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package k8s
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// LastPulseBootIDAnnotation records the node's boot ID at the time of the
+// last pulse, so trigger policies can tell whether the node has rebooted
+// since it was last validated.
+const LastPulseBootIDAnnotation = "straggler-shield.io/last-pulse-boot-id"
+
+// TriggerHistory is what the controller remembers about a node's previous
+// validations. It is read back from node annotations, so it survives agent
+// restarts and is shared between agents watching the same node.
+type TriggerHistory struct {
+	// LastPulse is when the node last completed a pulse; zero if never.
+	LastPulse time.Time
+	// LastPulseBootID is node.Status.NodeInfo.BootID at that pulse; empty if
+	// unknown.
+	LastPulseBootID string
+}
+
+// HistoryFromNode reads the TriggerHistory annotations from node.
+func HistoryFromNode(node *corev1.Node) TriggerHistory {
+	var h TriggerHistory
+	if t, err := time.Parse(time.RFC3339, node.Annotations[LastPulseAnnotation]); err == nil {
+		h.LastPulse = t
+	}
+	h.LastPulseBootID = node.Annotations[LastPulseBootIDAnnotation]
+	return h
+}
+
+// TriggerPolicy decides whether a watch event on a node should run a pulse.
+// NeedsReconcile and ReconcileNode consult it for every event; ValidateNode
+// bypasses it.
+// Implementations must be cheap and must not call the API server — they run
+// on the watch loop for every node update.
+type TriggerPolicy interface {
+	ShouldValidate(node *corev1.Node, history TriggerHistory) bool
+}
+
+// TriggerFunc adapts an ordinary function to TriggerPolicy.
+type TriggerFunc func(node *corev1.Node, history TriggerHistory) bool
+
+// ShouldValidate calls f(node, history).
+func (f TriggerFunc) ShouldValidate(node *corev1.Node, history TriggerHistory) bool {
+	return f(node, history)
+}
+
+// ReadyWindowTrigger is the default policy: validate a Ready node that just
+// joined or rebooted, i.e. one created within Window or whose Ready
+// condition turned True within it. Nodes that have been stable longer are
+// left alone so a training-active node is never pulsed by a routine status
+// update.
+//
+// Some distros refresh the Ready condition's LastTransitionTime on
+// unrelated condition updates, so a recent transition alone is not proof of
+// a reboot. Kubernetes does not publish the kubelet's start time, but the
+// node's boot ID changes with every boot: a node already pulsed under its
+// current boot ID is not pulsed again. Nodes that report no boot ID, and
+// nodes never pulsed, fall back to the timestamps alone.
+type ReadyWindowTrigger struct {
+	Window time.Duration
+	// PoolWindows overrides Window for nodes whose PoolLabel value is
+	// listed, e.g. a longer window for HGX systems that take ten minutes
+	// to POST. PoolLabel empty uses DefaultPoolLabel.
+	PoolLabel   string
+	PoolWindows map[string]time.Duration
+}
+
+// ShouldValidate implements TriggerPolicy.
+func (t ReadyWindowTrigger) ShouldValidate(node *corev1.Node, history TriggerHistory) bool {
+	window := t.window(node)
+	joined := IsNodeReady(node) && time.Since(node.CreationTimestamp.Time) < window
+	if !joined && !justBecameReady(node, window) {
+		return false
+	}
+	bootID := node.Status.NodeInfo.BootID
+	return bootID == "" || bootID != history.LastPulseBootID
+}
+
+// window returns the Ready window that applies to node.
+func (t ReadyWindowTrigger) window(node *corev1.Node) time.Duration {
+	label := t.PoolLabel
+	if label == "" {
+		label = DefaultPoolLabel
+	}
+	if w, ok := t.PoolWindows[node.Labels[label]]; ok {
+		return w
+	}
+	return t.Window
+}
+
+// NeedsReconcile reports whether a watch event on node needs ReconcileNode.
+// wasReady is the node's previously seen Ready state. A Ready edge always
+// does, as do markers that need attention regardless: a quarantine taint
+// removed by hand is re-evaluated at once rather than left out of step with
+// the GPUStraggler condition, an open failure streak is confirmed or
+// dismissed by the next pulse, and `kubectl straggler pulse` requests are
+// served. A trigger set with WithTrigger is asked on every other event, since
+// it may key on labels, boot IDs or an external signal rather than Ready.
+// The default ReadyWindowTrigger is not: between edges it would pulse a node
+// inside its window on every status update.
+func (c *Controller) NeedsReconcile(node *corev1.Node, wasReady bool) bool {
+	if IsNodeReady(node) && !wasReady {
+		return true
+	}
+	if c.TaintRemovedExternally(node) || c.FailureStreakOpen(node) || c.PulseRequested(node) {
+		return true
+	}
+	if _, ok := c.trigger.(ReadyWindowTrigger); ok {
+		return false
+	}
+	return c.trigger.ShouldValidate(node, HistoryFromNode(node))
+}
+
+// DefaultTrigger returns a ReadyWindowTrigger using READY_WINDOW_SECONDS
+// (default 5 minutes).
+func DefaultTrigger() TriggerPolicy {
+	return ReadyWindowTrigger{Window: readyTransitionWindow()}
+}
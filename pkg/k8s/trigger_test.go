@@ -0,0 +1,192 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// bootIDTrigger is a site-specific policy of the kind WithTrigger is for:
+// validate whenever the node has rebooted since its last pulse, regardless of
+// how long ago the Ready transition was.
+var bootIDTrigger = TriggerFunc(func(node *corev1.Node, h TriggerHistory) bool {
+	return node.Status.NodeInfo.BootID != h.LastPulseBootID
+})
+
+func TestReconcileNodeCustomTrigger(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name           string
+		bootID         string
+		lastBootID     string
+		wantPulseCalls int
+	}{
+		{
+			// Ready for hours, so the default trigger would skip it, but the
+			// boot ID shows a reboot the Ready window missed.
+			name:           "rebooted since last pulse — validated",
+			bootID:         "boot-b",
+			lastBootID:     "boot-a",
+			wantPulseCalls: 1,
+		},
+		{
+			name:           "same boot — skipped",
+			bootID:         "boot-a",
+			lastBootID:     "boot-a",
+			wantPulseCalls: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node := freshNode("gpu-node-0", 6*time.Hour)
+			node.Status.NodeInfo.BootID = tc.bootID
+			node.Annotations = map[string]string{LastPulseBootIDAnnotation: tc.lastBootID}
+			clientset := fake.NewSimpleClientset(node)
+
+			calls := 0
+			ctrl := NewController(clientset,
+				WithPulseFunc(func() (time.Duration, error) { calls++; return 0, nil }),
+				WithTrigger(bootIDTrigger),
+			)
+			if err := ctrl.ReconcileNode(context.Background(), node.Name); err != nil {
+				t.Fatalf("ReconcileNode: %v", err)
+			}
+			if calls != tc.wantPulseCalls {
+				t.Errorf("pulse called %d time(s), want %d", calls, tc.wantPulseCalls)
+			}
+
+			got, err := clientset.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantPulseCalls > 0 && got.Annotations[LastPulseBootIDAnnotation] != tc.bootID {
+				t.Errorf("boot ID annotation = %q, want %q", got.Annotations[LastPulseBootIDAnnotation], tc.bootID)
+			}
+		})
+	}
+}
+
+// TestNeedsReconcileCustomTrigger drives a trigger that does not key on
+// Ready from the watch event through the pulse: a node Ready for hours
+// reboots without the watch seeing a Ready edge.
+func TestNeedsReconcileCustomTrigger(t *testing.T) {
+	t.Parallel()
+
+	node := freshNode("gpu-node-0", 6*time.Hour)
+	node.Status.NodeInfo.BootID = "boot-b"
+	node.Annotations = map[string]string{LastPulseBootIDAnnotation: "boot-a"}
+	clientset := fake.NewSimpleClientset(node)
+	calls := 0
+	ctrl := NewController(clientset,
+		WithPulseFunc(func() (time.Duration, error) { calls++; return 0, nil }),
+		WithTrigger(bootIDTrigger),
+	)
+
+	if !ctrl.NeedsReconcile(node, true) {
+		t.Fatal("NeedsReconcile = false for a reboot the custom trigger keys on")
+	}
+	if err := ctrl.ReconcileNode(context.Background(), node.Name); err != nil {
+		t.Fatalf("ReconcileNode: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("pulse called %d time(s), want 1", calls)
+	}
+
+	// The pulse recorded the boot ID, so the next update is steady state.
+	got, err := clientset.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctrl.NeedsReconcile(got, true) {
+		t.Error("NeedsReconcile = true after the pulse recorded the boot ID")
+	}
+
+	// The default trigger only fires on the Ready edge, not on every update
+	// inside its window.
+	fresh := freshNode("gpu-node-1", time.Minute)
+	def := NewController(fake.NewSimpleClientset(fresh))
+	if def.NeedsReconcile(fresh, true) {
+		t.Error("default trigger: NeedsReconcile = true without a Ready edge")
+	}
+	if !def.NeedsReconcile(fresh, false) {
+		t.Error("default trigger: NeedsReconcile = false on the Ready edge")
+	}
+}
+
+func TestReadyWindowPools(t *testing.T) {
+	t.Parallel()
+
+	p := &policy.Policy{ReadyWindows: &policy.ReadyWindows{Pools: map[string]string{"hgx-b200": "15m"}}}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		pool      string
+		wantPulse bool
+	}{
+		// Ready 10 minutes ago: inside the HGX window, outside the default.
+		{"hgx-b200", true},
+		{"g6.xlarge", false},
+	} {
+		node := freshNode("gpu-pool-"+tc.pool, 10*time.Minute)
+		node.Labels = map[string]string{DefaultPoolLabel: tc.pool}
+		calls := 0
+		ctrl := NewController(fake.NewSimpleClientset(node),
+			WithPulseFunc(func() (time.Duration, error) { calls++; return 0, nil }),
+			WithPolicy(p),
+		)
+		if err := ctrl.ReconcileNode(context.Background(), node.Name); err != nil {
+			t.Fatalf("ReconcileNode(%s): %v", tc.pool, err)
+		}
+		if got := calls > 0; got != tc.wantPulse {
+			t.Errorf("pool %s pulsed = %v, want %v", tc.pool, got, tc.wantPulse)
+		}
+	}
+}
+
+func TestReadyWindowTrigger(t *testing.T) {
+	t.Parallel()
+
+	trigger := ReadyWindowTrigger{Window: 5 * time.Minute}
+	for _, tc := range []struct {
+		name       string
+		readyAge   time.Duration
+		createdAge time.Duration // 0 = no creation timestamp
+		notReady   bool
+		bootID     string
+		lastBootID string
+		want       bool
+	}{
+		{name: "just became Ready", readyAge: time.Minute, want: true},
+		{name: "stable", readyAge: 6 * time.Hour},
+		{name: "rebooted since last pulse", readyAge: time.Minute, bootID: "boot-b", lastBootID: "boot-a", want: true},
+		// LastTransitionTime refreshed by an unrelated condition update.
+		{name: "already pulsed this boot", readyAge: time.Minute, bootID: "boot-a", lastBootID: "boot-a"},
+		{name: "just created", readyAge: 6 * time.Hour, createdAge: time.Minute, want: true},
+		{name: "just created, not Ready", readyAge: time.Minute, createdAge: time.Minute, notReady: true},
+		{name: "just created, already pulsed", readyAge: time.Minute, createdAge: time.Minute, bootID: "boot-a", lastBootID: "boot-a"},
+	} {
+		node := freshNode("gpu-node-0", tc.readyAge)
+		if tc.createdAge > 0 {
+			node.CreationTimestamp = metav1.NewTime(time.Now().Add(-tc.createdAge))
+		}
+		if tc.notReady {
+			node.Status.Conditions[0].Status = corev1.ConditionFalse
+		}
+		node.Status.NodeInfo.BootID = tc.bootID
+		if got := trigger.ShouldValidate(node, TriggerHistory{LastPulseBootID: tc.lastBootID}); got != tc.want {
+			t.Errorf("%s: ShouldValidate = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
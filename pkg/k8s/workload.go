@@ -0,0 +1,145 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UnfitPrefix starts the key of the taint and label marking a node unfit for
+// one workload class, e.g. straggler-shield.io/unfit-training. The value is
+// the failure reason code. A workload declares its class by tolerating the
+// unfit taints of every other class.
+const UnfitPrefix = "straggler-shield.io/unfit-"
+
+// eventScoped is the Warning Event on a node quarantined for some workload
+// classes only.
+const eventScoped = "WorkloadClassesQuarantined"
+
+// UnfitKey returns the unfit taint and label key for a workload class.
+func UnfitKey(class policy.WorkloadClass) string {
+	return UnfitPrefix + string(class)
+}
+
+// UnfitClasses returns the workload classes node is marked unfit for, in
+// policy.WorkloadClassesAll order.
+func UnfitClasses(node *corev1.Node) []policy.WorkloadClass {
+	var classes []policy.WorkloadClass
+	for _, class := range policy.WorkloadClassesAll {
+		if _, ok := node.Labels[UnfitKey(class)]; ok {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// applyWorkloadScope handles a quarantine the policy scopes to some workload
+// classes (see policy.WorkloadClasses): each impacted class gets an unfit
+// taint, with the configured quarantine effect, and label, and the unfit
+// markings of classes the failure leaves alone are removed. The rest of the
+// node stays schedulable, so a whole-node quarantine straggler-shield holds
+// is lifted in the same patch: its taint, and any cordon it set. Running pods
+// are not evicted unless the effect is NoExecute. evidence ends the
+// condition message. Idempotent.
+func (c *Controller) applyWorkloadScope(ctx context.Context, node *corev1.Node, reason string, classes []policy.WorkloadClass, elapsed time.Duration, evidence string) error {
+	labels := map[string]any{}
+	for _, class := range policy.WorkloadClassesAll {
+		key := UnfitKey(class)
+		switch {
+		case slices.Contains(classes, class):
+			labels[key] = reason
+		case node.Labels[key] != "":
+			labels[key] = nil
+		}
+	}
+	tainted := quarantineRecorded(node) && findTaintByKey(node.Spec.Taints, c.taint.Key) != nil
+	_, cordoned := node.Annotations[CordonedAnnotation]
+	changed := tainted || cordoned || !slices.Equal(UnfitClasses(node), classes)
+	for _, class := range classes {
+		t := findTaintByKey(node.Spec.Taints, UnfitKey(class))
+		changed = changed || t == nil || t.Value != reason || t.Effect != c.taint.Effect || node.Labels[UnfitKey(class)] != reason
+	}
+	if changed {
+		annotations := decisionAnnotations(opScope, reason, elapsed, evidence)
+		var spec map[string]any
+		if cordoned {
+			spec = map[string]any{"unschedulable": false}
+			annotations[CordonedAnnotation] = nil
+		}
+		if err := patchNodeSpec(ctx, c.client, node, func(taints []corev1.Taint) []corev1.Taint {
+			taints = removeUnfitTaints(taints)
+			if tainted {
+				taints = removeTaintByKey(taints, c.taint.Key)
+			}
+			for _, class := range classes {
+				taints = append(taints, corev1.Taint{Key: UnfitKey(class), Value: reason, Effect: c.taint.Effect})
+			}
+			return taints
+		}, spec, map[string]any{"labels": labels, "annotations": annotations}); err != nil {
+			return patchError("apply_taint", "patch node spec (workload classes)", err)
+		}
+	}
+
+	fit := slices.DeleteFunc(slices.Clone(policy.WorkloadClassesAll), func(class policy.WorkloadClass) bool {
+		return slices.Contains(classes, class)
+	})
+	msg := fmt.Sprintf("%s (unfit for %s, kept for %s): %s", reason, joinClasses(classes), joinClasses(fit), evidence)
+	if err := c.patchCondition(ctx, node, corev1.NodeCondition{
+		Type:               zombieCondition,
+		Status:             corev1.ConditionFalse,
+		Reason:             "WorkloadScoped",
+		Message:            msg,
+		LastTransitionTime: metav1.Now(),
+	}); err != nil {
+		return err
+	}
+	if changed {
+		c.event(node, corev1.EventTypeWarning, eventScoped, msg)
+	}
+	return nil
+}
+
+// clearWorkloadScope strips every unfit taint and label after a passing
+// pulse. Idempotent.
+func (c *Controller) clearWorkloadScope(ctx context.Context, node *corev1.Node) error {
+	classes := UnfitClasses(node)
+	tainted := len(removeUnfitTaints(slices.Clone(node.Spec.Taints))) != len(node.Spec.Taints)
+	if len(classes) == 0 && !tainted {
+		return nil
+	}
+	labels := map[string]any{}
+	for _, class := range classes {
+		labels[UnfitKey(class)] = nil
+	}
+	// patchNodeSpec updates node, so removeTaint, which edits the same
+	// taint list next, starts from what was written.
+	if err := patchNodeSpec(ctx, c.client, node, removeUnfitTaints, nil, map[string]any{"labels": labels}); err != nil {
+		return patchError("remove_taint", "patch node spec (workload classes)", err)
+	}
+	return nil
+}
+
+// isUnfitKey reports whether key is an unfit taint or label key.
+func isUnfitKey(key string) bool {
+	return strings.HasPrefix(key, UnfitPrefix)
+}
+
+// removeUnfitTaints drops every unfit taint from taints.
+func removeUnfitTaints(taints []corev1.Taint) []corev1.Taint {
+	return slices.DeleteFunc(taints, func(t corev1.Taint) bool { return isUnfitKey(t.Key) })
+}
+
+func joinClasses(classes []policy.WorkloadClass) string {
+	s := make([]string, len(classes))
+	for i, class := range classes {
+		s[i] = string(class)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,82 @@
+package k8s
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWorkloadScope(t *testing.T) {
+	t.Parallel()
+
+	p := &policy.Policy{WorkloadClasses: &policy.WorkloadClasses{}}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	node := freshNode("gpu-node-0", time.Minute)
+	client := fake.NewSimpleClientset(node)
+	var pulseErr error
+	ctrl := NewController(client,
+		WithPulseFunc(func() (time.Duration, error) { return 600 * time.Millisecond, pulseErr }),
+		WithPolicy(p),
+		WithDecisionJournal("", time.Second, time.Minute),
+	)
+
+	for _, step := range []struct {
+		err       error
+		wantUnfit []policy.WorkloadClass
+		wantState string
+	}{
+		// The fabric only carries collectives: single-GPU and inference
+		// work keeps the node.
+		{pulse.ErrInterconnectDegraded, []policy.WorkloadClass{policy.WorkloadTraining}, StateScoped},
+		{pulse.ErrHighVariance, []policy.WorkloadClass{policy.WorkloadTraining, policy.WorkloadInference}, StateScoped},
+		{pulse.ErrInterconnectDegraded, []policy.WorkloadClass{policy.WorkloadTraining}, StateScoped},
+		{nil, nil, StateHealthy},
+		// A failure impacting every class quarantines the whole node.
+		{pulse.ErrStragglerDetected, nil, StateQuarantined},
+		// A scoped failure then replaces the whole-node quarantine.
+		{pulse.ErrInterconnectDegraded, []policy.WorkloadClass{policy.WorkloadTraining}, StateScoped},
+	} {
+		pulseErr = step.err
+		if err := ctrl.ValidateNode(context.Background(), node.Name); err != nil {
+			t.Fatalf("ValidateNode(%v): %v", step.err, err)
+		}
+		got, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unfit := UnfitClasses(got); !slices.Equal(unfit, step.wantUnfit) {
+			t.Errorf("after %v: unfit for %v, want %v", step.err, unfit, step.wantUnfit)
+		}
+		var tainted []policy.WorkloadClass
+		for _, class := range policy.WorkloadClassesAll {
+			if taint := findTaint(got, UnfitKey(class)); taint != nil {
+				if taint.Effect != corev1.TaintEffectNoSchedule {
+					t.Errorf("after %v: %s effect = %s, want NoSchedule", step.err, taint.Key, taint.Effect)
+				}
+				tainted = append(tainted, class)
+			}
+		}
+		if !slices.Equal(tainted, step.wantUnfit) {
+			t.Errorf("after %v: unfit taints for %v, want %v", step.err, tainted, step.wantUnfit)
+		}
+		if state := QuarantineState(got); state != step.wantState {
+			t.Errorf("after %v: state = %s, want %s", step.err, state, step.wantState)
+		}
+		if step.wantState == StateScoped && findTaint(got, zombieTaintKey) != nil {
+			t.Errorf("after %v: quarantine taint left on a scoped node: %v", step.err, got.Spec.Taints)
+		}
+		if ctrl.journal.isPending(node.Name) {
+			t.Errorf("after %v: decision still pending", step.err)
+		}
+	}
+}
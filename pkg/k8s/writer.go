@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// SplitConfig returns a copy of cfg that makes every read (GET, which
+// covers list and watch) with the token in readerTokenFile and every other
+// request with cfg's own credentials. Pointed at a read-only service
+// account, the reader token can be kept in a long-lived Secret while the
+// mutating credential stays the pod's own projected token, which expires
+// and rotates. Both sides keep cfg's transport wrappers (fault injection).
+// The file is re-read as the token rotates; it must exist and not be empty.
+func SplitConfig(cfg *rest.Config, readerTokenFile string) (*rest.Config, error) {
+	token, err := os.ReadFile(readerTokenFile)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(token)) == "" {
+		return nil, fmt.Errorf("%s is empty", readerTokenFile)
+	}
+	readCfg := rest.CopyConfig(cfg)
+	readCfg.BearerToken, readCfg.BearerTokenFile = "", readerTokenFile
+	read, err := rest.TransportFor(readCfg)
+	if err != nil {
+		return nil, fmt.Errorf("reader transport: %w", err)
+	}
+	write, err := rest.TransportFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("writer transport: %w", err)
+	}
+	return &rest.Config{
+		Host:          cfg.Host,
+		APIPath:       cfg.APIPath,
+		ContentConfig: cfg.ContentConfig,
+		UserAgent:     cfg.UserAgent,
+		QPS:           cfg.QPS,
+		Burst:         cfg.Burst,
+		RateLimiter:   cfg.RateLimiter,
+		Timeout:       cfg.Timeout,
+		Transport:     splitTransport{read: read, write: write},
+	}, nil
+}
+
+// splitTransport sends reads through read and every other request through
+// write.
+type splitTransport struct {
+	read, write http.RoundTripper
+}
+
+func (t splitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return t.read.RoundTrip(req)
+	}
+	return t.write.RoundTrip(req)
+}
@@ -0,0 +1,78 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// TestSplitConfig checks that reads carry the reader token and writes the
+// config's own.
+func TestSplitConfig(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	auth := make(map[string]string) // method -> Authorization
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth[r.Method] = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		node := corev1.Node{TypeMeta: metav1.TypeMeta{Kind: "Node", APIVersion: "v1"}, ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-0"}}
+		_ = json.NewEncoder(w).Encode(node)
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("reader-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := SplitConfig(&rest.Config{Host: srv.URL, BearerToken: "writer-token"}, tokenFile)
+	if err != nil {
+		t.Fatalf("SplitConfig: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := client.CoreV1().Nodes().Get(ctx, "gpu-node-0", metav1.GetOptions{}); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := client.CoreV1().Nodes().Patch(ctx, "gpu-node-0", types.MergePatchType, []byte(`{}`), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+
+	if got := auth[http.MethodGet]; got != "Bearer reader-token" {
+		t.Errorf("GET Authorization = %q, want the reader token", got)
+	}
+	if got := auth[http.MethodPatch]; got != "Bearer writer-token" {
+		t.Errorf("PATCH Authorization = %q, want the writer token", got)
+	}
+}
+
+func TestSplitConfigRejectsEmptyToken(t *testing.T) {
+	t.Parallel()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SplitConfig(&rest.Config{Host: "https://example.invalid"}, tokenFile); err == nil {
+		t.Error("SplitConfig accepted an empty token file")
+	}
+	if _, err := SplitConfig(&rest.Config{Host: "https://example.invalid"}, tokenFile+".missing"); err == nil {
+		t.Error("SplitConfig accepted a missing token file")
+	}
+}
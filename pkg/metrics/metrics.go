@@ -8,6 +8,40 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// Metric names. They are exported so the alerting rules built by
+// AlertRules reference the same series the collectors register.
+const (
+	PulseDurationName         = "gpu_validator_pulse_duration_seconds"
+	PulseCVName               = "gpu_validator_pulse_cv"
+	PulseSMUtilName           = "gpu_validator_pulse_sm_utilization"
+	PulseMemoryUtilName       = "gpu_validator_pulse_memory_utilization"
+	LastPulseName             = "gpu_validator_last_pulse_timestamp_seconds"
+	StragglerTotalName        = "gpu_validator_straggler_detected_total"
+	QuarantinedName           = "gpu_validator_quarantined"
+	RiskScoreName             = "gpu_validator_risk_score"
+	CheckFailuresName         = "gpu_validator_check_failures_total"
+	ChecksSkippedName         = "gpu_validator_checks_skipped_total"
+	PatchFailuresName         = "gpu_validator_patch_failures_total"
+	MarkerDisagreementsName   = "gpu_validator_marker_disagreements_total"
+	ReconcilePhaseName        = "gpu_validator_reconcile_phase_seconds"
+	ReconcileDurationName     = "gpu_validator_reconcile_duration_seconds"
+	ReconcileOverBudgetName   = "gpu_validator_reconcile_over_budget_total"
+	NodesName                 = "gpu_validator_nodes"
+	PodEvictionsName          = "gpu_validator_pod_evictions_total"
+	ShardLeaderName           = "gpu_validator_shard_leader"
+	PulsesInFlightName        = "gpu_validator_pulses_in_flight"
+	PulsesQueuedName          = "gpu_validator_pulses_queued"
+	DispatchWaitName          = "gpu_validator_dispatch_wait_seconds"
+	ValidationsPendingName    = "gpu_validator_validations_pending"
+	ValidationsName           = "gpu_validator_validations_total"
+	DeliveriesName            = "gpu_validator_deliveries_total"
+	CanariesName              = "gpu_validator_canaries_total"
+	FaultsInjectedName        = "gpu_validator_faults_injected_total"
+	QuarantinesSuppressedName = "gpu_validator_quarantines_suppressed_total"
+	RemediationsName          = "gpu_validator_remediations_total"
+	ThrottleReasonsName       = "gpu_validator_throttle_reasons_total"
+)
+
 var (
 	// PulseDuration is a per-device histogram of mean GEMM latency across the
 	// five timed runs. The "device" label is the 0-based GPU index. Buckets
@@ -15,7 +49,7 @@ var (
 	// thermal stalls without underflow or overflow.
 	PulseDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "gpu_validator_pulse_duration_seconds",
+			Name:    PulseDurationName,
 			Help:    "Mean wall-clock duration of GPU GEMM pulse runs per device.",
 			Buckets: prometheus.ExponentialBuckets(0.001, 2, 18),
 		},
@@ -27,24 +61,340 @@ var (
 	// produces CV well below 5%. Values above 20% trigger ErrHighVariance.
 	PulseCV = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "gpu_validator_pulse_cv",
+			Name: PulseCVName,
 			Help: "Coefficient of variation (σ/μ) across GEMM pulse runs per device. >0.20 triggers quarantine.",
 		},
 		[]string{"device"},
 	)
 
+	// PulseSMUtilization and PulseMemoryUtilization are the peak SM and
+	// memory utilization (0–1) seen on each device during its last GEMM
+	// runs. A passing pulse that stayed well below 1 did not stress the
+	// device, e.g. because the GEMM is too small for the SKU.
+	PulseSMUtilization = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: PulseSMUtilName,
+			Help: "Peak SM utilization (0-1) per device during the last GEMM pulse runs.",
+		},
+		[]string{"device"},
+	)
+	PulseMemoryUtilization = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: PulseMemoryUtilName,
+			Help: "Peak memory utilization (0-1) per device during the last GEMM pulse runs.",
+		},
+		[]string{"device"},
+	)
+
+	// LastPulse is the Unix time of the last fresh pulse on each node, pass
+	// or fail, by node name. A verdict reused from the result cache does not
+	// move it, so it goes stale when pulses stop running.
+	LastPulse = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: LastPulseName,
+			Help: "Unix time of the last GPU pulse run on each node.",
+		},
+		[]string{"node"},
+	)
+
 	// StragglerTotal counts quarantine events labelled by failure reason.
 	//
 	// Observed reason values:
 	//   latency_threshold_exceeded   — mean GEMM latency > 500ms
+	//   clock_derated                — SM clock stuck below its floor, or the
+	//                                  hardware thermal slowdown, under load
 	//   high_variance                — CV > 20% (fail-slow pattern)
 	//   interconnect_degraded        — NVLink/P2P bandwidth below threshold
-	//   pre_flight_failure           — ECC errors or thermal recovery incomplete
+	//   pcie_degraded                — PCIe link below its maximum width
+	//   low_power                    — GPU power draw under load below the
+	//                                  expected fraction of its TDP
+	//   ecc_errors                   — uncorrectable ECC errors since the last
+	//                                  boot or clean pulse
+	//   thermal_recovery             — GPU still above the idle temperature
+	//                                  ceiling after the cool-down checks
+	//   xid_error                    — a quarantining XID in the kernel log
+	//   row_remap_failure            — an HBM row remap failed or pending
+	//   pre_flight_failure           — any other pre-flight fault
+	//   stage_timeout                — a pipeline stage exceeded its timeout
+	//   pulse_crashed                — isolated pulse child died without a result
+	//   misconfiguration             — CUDA, NVML and driver disagree on GPU count
+	//   gpu_count_decreased          — fewer GPUs visible than at the last pass
+	//   near_threshold               — passed, but within the degraded margin
+	//                                  (degraded tier by default; only counted
+	//                                  here if policy escalates it to quarantine)
+	//   canary_failed                — a two-node training canary failed or was
+	//                                  slow (degraded tier by default, like
+	//                                  near_threshold)
+	//   inconclusive                 — the GEMM never reached the SM utilization
+	//                                  floor (warn by default; only counted
+	//                                  here if policy escalates it)
+	//   external_check               — an operator-supplied check failed
 	StragglerTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "gpu_validator_straggler_detected_total",
+			Name: StragglerTotalName,
 			Help: "Total number of nodes quarantined by the GPU validator, by failure reason.",
 		},
 		[]string{"reason"},
 	)
+
+	// Quarantined is 1 for each node the controller holds quarantined,
+	// labelled by the failure reason (the StragglerTotal reason values). The
+	// series drops to 0 once the quarantine clears or the node is
+	// quarantined again for another reason. Unlike StragglerTotal it shows
+	// which nodes are quarantined now. Quarantines from before a restart are
+	// reported once the node watch sees the node.
+	Quarantined = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: QuarantinedName,
+			Help: "1 while the node is quarantined by the GPU validator and 0 once it clears, by node and failure reason.",
+		},
+		[]string{"node", "reason"},
+	)
+
+	// RiskScore is each node's 0–1 straggler risk from its last fresh pulse
+	// (see pulse.Risk), by node name. It rises as measurements approach
+	// their limits, before any check fails.
+	RiskScore = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: RiskScoreName,
+			Help: "Straggler risk score (0-1) of each node from its last GPU pulse.",
+		},
+		[]string{"node"},
+	)
+
+	// CheckFailures counts every failed validation by reason and by the
+	// policy severity applied to it (quarantine, degrade-label, warn). Unlike
+	// StragglerTotal it includes failures that did not taint the node, so
+	// warn-only checks remain visible while their false-positive rate is
+	// being evaluated.
+	CheckFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: CheckFailuresName,
+			Help: "Total number of failed GPU validations, by failure reason and applied policy severity.",
+		},
+		[]string{"reason", "severity"},
+	)
+
+	// ThrottleReasons counts the clock throttle reasons active on GPUs that
+	// failed the clock check, by reason, e.g. {reason="sw_power_cap"}. A
+	// failure with several reasons counts once for each. It tells a fleet
+	// short of power from one short of cooling.
+	ThrottleReasons = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: ThrottleReasonsName,
+			Help: "Clock throttle reasons active on GPUs that failed the clock check, by reason.",
+		},
+		[]string{"reason"},
+	)
+
+	// ChecksSkipped counts pulse checks skipped because what they need was
+	// unavailable, by check and why, e.g. {check="clocks",
+	// why="max_clock_unreported"} or {check="p2p", why="single_gpu"}. It
+	// shows how much of the validation surface actually runs on the fleet;
+	// see pulse.SkippedCheck for the label values.
+	ChecksSkipped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: ChecksSkippedName,
+			Help: "Total number of pulse checks skipped because what they need was unavailable, by check and reason.",
+		},
+		[]string{"check", "why"},
+	)
+
+	// PatchFailures counts failed node patches by operation (apply_taint,
+	// remove_taint) and error class (conflict, forbidden, not_found, other).
+	// Alert on forbidden: it means an RBAC regression and recurs on every
+	// quarantine, while conflicts are transient.
+	PatchFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: PatchFailuresName,
+			Help: "Total number of failed node patches, by operation and error class.",
+		},
+		[]string{"operation", "class"},
+	)
+
+	// QuarantinesSuppressed counts quarantines the blast-radius guard held
+	// back, by the limit that tripped: per_hour or percent. Any increase
+	// means failing nodes are still schedulable.
+	QuarantinesSuppressed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: QuarantinesSuppressedName,
+			Help: "Total number of quarantines suppressed by the blast-radius guard, by limit.",
+		},
+		[]string{"limit"},
+	)
+
+	// Remediations counts failed pulses remediated before their verdict was
+	// applied, by action (gpu-reset or driver-reload) and result: cleared
+	// (the re-run passed), failed (the re-run failed too) or error (the
+	// action failed or the re-run was inconclusive). A node that is cleared
+	// again and again is hiding a fault a reset only masks.
+	Remediations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: RemediationsName,
+			Help: "Total number of failed pulses remediated before quarantine, by action and result.",
+		},
+		[]string{"action", "result"},
+	)
+
+	// MarkerDisagreements counts nodes whose straggler-shield markers were
+	// found out of step, by kind: taint_removed_externally (the quarantine
+	// was lifted outside straggler-shield), taint_without_condition (a taint
+	// under the quarantine key that straggler-shield did not record),
+	// newer_quarantine (a pass was not applied over a newer quarantine), and
+	// the drift the marker audit repairs: condition_missing,
+	// stale_quarantine and label_missing.
+	MarkerDisagreements = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: MarkerDisagreementsName,
+			Help: "Total number of straggler-shield marker disagreements found on nodes, by kind.",
+		},
+		[]string{"kind"},
+	)
+
+	// ReconcilePhaseDuration splits each reconcile that ran a pulse (fresh or
+	// cached) into the time spent reading the node (get_node), waiting for a
+	// pulse slot (dispatch), pulsing (pulse), patching it (patch) and
+	// everything else (other). Buckets span
+	// 10ms → ~5.5min.
+	ReconcilePhaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    ReconcilePhaseName,
+			Help:    "Time spent per validating reconcile, by phase: get_node, dispatch, pulse, patch, other.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 16),
+		},
+		[]string{"phase"},
+	)
+
+	// ReconcileDuration is the wall-clock total of each validating reconcile:
+	// the time validation adds to node turn-up.
+	ReconcileDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    ReconcileDurationName,
+			Help:    "Total wall-clock duration of reconciles that ran a GPU pulse.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 16),
+		},
+	)
+
+	// ReconcileOverBudget counts validating reconciles that took longer than
+	// the RECONCILE_BUDGET; each also logs its phase breakdown.
+	ReconcileOverBudget = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: ReconcileOverBudgetName,
+			Help: "Total number of validating reconciles that exceeded the latency budget.",
+		},
+	)
+
+	// Nodes is the number of GPU nodes a central controller watches, by pool
+	// and quarantine state (quarantined, suspected, scoped, degraded, healthy). Node
+	// agents do not set it.
+	Nodes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: NodesName,
+			Help: "Number of watched GPU nodes, by pool and quarantine state (central mode only).",
+		},
+		[]string{"pool", "state"},
+	)
+
+	// PulsesInFlight and PulsesQueued are the pulses running and waiting
+	// for a dispatch slot, by rack or power domain (PULSE_DOMAIN_LABEL).
+	PulsesInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: PulsesInFlightName,
+			Help: "Pulses running, by domain.",
+		},
+		[]string{"domain"},
+	)
+	PulsesQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: PulsesQueuedName,
+			Help: "Pulses waiting for a dispatch slot, by domain.",
+		},
+		[]string{"domain"},
+	)
+
+	// DispatchWait is how long each pulse waited for a dispatch slot. Mass
+	// reboots queue pulses for minutes, so buckets span 10ms → ~11min.
+	DispatchWait = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    DispatchWaitName,
+			Help:    "Time pulses waited for a dispatch slot.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 17),
+		},
+	)
+
+	// ValidationsPending is the validation backlog: validating reconciles
+	// between trigger and verdict, whether queued for a slot or pulsing. The
+	// shard label is the replica's CENTRAL_SHARD, empty when unsharded.
+	ValidationsPending = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: ValidationsPendingName,
+			Help: "Validations triggered and awaiting a verdict, by shard.",
+		},
+		[]string{"shard"},
+	)
+
+	// Validations counts validations that reached a verdict, by shard: the
+	// validation throughput.
+	Validations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: ValidationsName,
+			Help: "Total number of validations that reached a verdict, by shard.",
+		},
+		[]string{"shard"},
+	)
+
+	// ShardLeader is 1 while this replica holds its shard's Lease (central
+	// mode with CENTRAL_SHARDS > 1).
+	ShardLeader = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: ShardLeaderName,
+			Help: "1 while this replica holds the Lease for the shard, 0 otherwise (sharded central mode only).",
+		},
+		[]string{"shard"},
+	)
+
+	// PodEvictions counts GPU pods drained from hard-quarantined nodes, by
+	// result: evicted, blocked (a PodDisruptionBudget still refused it when
+	// the drain timed out) or failed.
+	PodEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: PodEvictionsName,
+			Help: "Total number of GPU pod evictions from quarantined nodes, by result.",
+		},
+		[]string{"result"},
+	)
+
+	// Canaries counts distributed training canaries by result: passed,
+	// failed, skipped (the job did not run, e.g. a workload took the GPUs),
+	// unpaired (no partner node passed in time) or dropped (queue full).
+	Canaries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: CanariesName,
+			Help: "Total number of distributed training canaries, by result.",
+		},
+		[]string{"result"},
+	)
+
+	// FaultsInjected counts faults injected by FAULT_INJECTION, by fault:
+	// api_error, patch_conflict, pulse_hang or pulse_crash. Always zero
+	// outside a chaos test.
+	FaultsInjected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: FaultsInjectedName,
+			Help: "Total number of faults injected for chaos testing, by fault.",
+		},
+		[]string{"fault"},
+	)
+
+	// Deliveries counts notification delivery outcomes, by receiver
+	// (aggregator, webhook, notify, slack, pagerduty, slurm) and result: delivered,
+	// retried (one per failed attempt that is retried), dead_lettered or
+	// superseded (dropped for a newer Slurm transition of the node).
+	Deliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: DeliveriesName,
+			Help: "Total number of notification delivery outcomes, by receiver and result.",
+		},
+		[]string{"receiver", "result"},
+	)
 )
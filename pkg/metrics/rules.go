@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RuleGroup is a Prometheus rule group, in the layout shared by a rules file
+// and the spec.groups of a PrometheusRule.
+type RuleGroup struct {
+	Name  string      `json:"name"`
+	Rules []AlertRule `json:"rules"`
+}
+
+// AlertRule is a single Prometheus alerting rule.
+type AlertRule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// AlertOptions tunes the recommended alerts. The zero value of any field
+// takes its default from DefaultAlertOptions.
+type AlertOptions struct {
+	// Job is the scrape job of the agent's /metrics endpoint.
+	Job string
+	// MaxPulseAge is how long a node may go without a fresh pulse. The
+	// default is a day past the 168h PERIODIC_MAX_STALENESS default.
+	MaxPulseAge time.Duration
+	// QuarantineSpike is how many quarantines within SpikeWindow, across
+	// the fleet, count as a spike rather than a bad GPU.
+	QuarantineSpike int
+	SpikeWindow     time.Duration
+	// CVRatio and CVFloor define creeping variance: a device's 6h average
+	// CV above CVRatio times its 7d average and above CVFloor.
+	CVRatio float64
+	CVFloor float64
+}
+
+// DefaultAlertOptions are the thresholds AlertRules fills in for zero fields.
+var DefaultAlertOptions = AlertOptions{
+	Job:             "straggler-shield",
+	MaxPulseAge:     192 * time.Hour,
+	QuarantineSpike: 5,
+	SpikeWindow:     time.Hour,
+	CVRatio:         2,
+	CVFloor:         0.05,
+}
+
+func (o AlertOptions) withDefaults() AlertOptions {
+	d := DefaultAlertOptions
+	if o.Job == "" {
+		o.Job = d.Job
+	}
+	if o.MaxPulseAge <= 0 {
+		o.MaxPulseAge = d.MaxPulseAge
+	}
+	if o.QuarantineSpike <= 0 {
+		o.QuarantineSpike = d.QuarantineSpike
+	}
+	if o.SpikeWindow <= 0 {
+		o.SpikeWindow = d.SpikeWindow
+	}
+	if o.CVRatio <= 0 {
+		o.CVRatio = d.CVRatio
+	}
+	if o.CVFloor <= 0 {
+		o.CVFloor = d.CVFloor
+	}
+	return o
+}
+
+// AlertRules returns the recommended alerts for the collectors in this
+// package. The expressions are built from the metric name constants, so
+// renaming a metric changes the rules with it.
+func AlertRules(o AlertOptions) []RuleGroup {
+	o = o.withDefaults()
+	job := strconv.Quote(o.Job)
+	return []RuleGroup{{
+		Name: "straggler-shield",
+		Rules: []AlertRule{
+			{
+				Alert:  "StragglerShieldAgentDown",
+				Expr:   fmt.Sprintf("up{job=%s} == 0", job),
+				For:    "10m",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "straggler-shield agent {{ $labels.instance }} is down",
+					"description": "Prometheus has not scraped the agent for 10 minutes. GPUs behind it are not being validated.",
+				},
+			},
+			{
+				Alert:  "StragglerShieldNoRecentPulse",
+				Expr:   fmt.Sprintf("time() - max by (node) (%s{job=%s}) > %d", LastPulseName, job, int64(o.MaxPulseAge.Seconds())),
+				For:    "30m",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "No GPU pulse on {{ $labels.node }} for over " + promDuration(o.MaxPulseAge),
+					"description": "The node has not been validated recently. Check the agent logs for skipped or failing periodic pulses.",
+				},
+			},
+			{
+				Alert:  "StragglerShieldQuarantineSpike",
+				Expr:   fmt.Sprintf("sum(increase(%s{job=%s}[%s])) >= %d", StragglerTotalName, job, promDuration(o.SpikeWindow), o.QuarantineSpike),
+				Labels: map[string]string{"severity": "critical"},
+				Annotations: map[string]string{
+					"summary":     "{{ $value | humanize }} nodes quarantined in the last " + promDuration(o.SpikeWindow),
+					"description": "Many quarantines at once usually point at a shared cause - a driver rollout, a threshold change or a cooling fault - rather than bad GPUs.",
+				},
+			},
+			{
+				Alert: "StragglerShieldCVCreeping",
+				Expr: fmt.Sprintf("avg_over_time(%[1]s{job=%[2]s}[6h]) > %[3]s * avg_over_time(%[1]s{job=%[2]s}[7d]) and avg_over_time(%[1]s{job=%[2]s}[6h]) > %[4]s",
+					PulseCVName, job, formatFloat(o.CVRatio), formatFloat(o.CVFloor)),
+				For:    "1h",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "GEMM variance creeping up on {{ $labels.instance }} device {{ $labels.device }}",
+					"description": "The 6h average CV is well above its 7d average. A fail-slow GPU often drifts here before it crosses the quarantine threshold.",
+				},
+			},
+			{
+				Alert:  "StragglerShieldQuarantineGuardTripped",
+				Expr:   fmt.Sprintf("sum by (limit) (increase(%s{job=%s}[15m])) > 0", QuarantinesSuppressedName, job),
+				Labels: map[string]string{"severity": "critical"},
+				Annotations: map[string]string{
+					"summary":     "Quarantines held back by the {{ $labels.limit }} blast-radius limit",
+					"description": "More nodes failed than the guard allows to be quarantined. They are still schedulable. Look for a shared cause before raising the limit.",
+				},
+			},
+			{
+				Alert:  "StragglerShieldPatchForbidden",
+				Expr:   fmt.Sprintf("sum by (operation) (increase(%s{job=%s,class=\"forbidden\"}[15m])) > 0", PatchFailuresName, job),
+				Labels: map[string]string{"severity": "critical"},
+				Annotations: map[string]string{
+					"summary":     "straggler-shield is forbidden to {{ $labels.operation }}",
+					"description": "Node patches are failing RBAC checks. Every quarantine will fail until the role in deploy/rbac.yaml is restored.",
+				},
+			},
+		},
+	}}
+}
+
+// promDuration formats d as a Prometheus duration, such as 1h or 90m.
+func promDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", int64(d.Seconds()))
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
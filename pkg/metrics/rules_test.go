@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestAlertRulesReferenceRegisteredMetrics guards against a metric rename
+// that leaves an alert watching a series nothing exports.
+func TestAlertRulesReferenceRegisteredMetrics(t *testing.T) {
+	t.Parallel()
+
+	registered := map[string]bool{}
+	for _, c := range []prometheus.Collector{PulseDuration, PulseCV, LastPulse, StragglerTotal, CheckFailures, PatchFailures, MarkerDisagreements, QuarantinesSuppressed} {
+		ch := make(chan *prometheus.Desc, 1)
+		go func() { c.Describe(ch); close(ch) }()
+		for d := range ch {
+			registered[regexp.MustCompile(`fqName: "([^"]+)"`).FindStringSubmatch(d.String())[1]] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	series := regexp.MustCompile(`gpu_validator_\w+`)
+	for _, g := range AlertRules(AlertOptions{}) {
+		for _, r := range g.Rules {
+			if r.Alert == "" || r.Expr == "" || r.Labels["severity"] == "" {
+				t.Errorf("rule %+v is missing a name, expression or severity", r)
+			}
+			for _, name := range series.FindAllString(r.Expr, -1) {
+				seen[name] = true
+				if !registered[name] {
+					t.Errorf("%s references %s, which no collector registers", r.Alert, name)
+				}
+			}
+		}
+	}
+	for _, name := range []string{LastPulseName, StragglerTotalName, PulseCVName, PatchFailuresName, QuarantinesSuppressedName} {
+		if !seen[name] {
+			t.Errorf("no rule references %s", name)
+		}
+	}
+}
+
+func TestAlertRulesOptions(t *testing.T) {
+	t.Parallel()
+
+	groups := AlertRules(AlertOptions{Job: "gpu-agents", MaxPulseAge: 48 * time.Hour, QuarantineSpike: 9, SpikeWindow: 30 * time.Minute})
+	exprs := map[string]string{}
+	for _, r := range groups[0].Rules {
+		exprs[r.Alert] = r.Expr
+	}
+	for alert, want := range map[string]string{
+		"StragglerShieldAgentDown":       `up{job="gpu-agents"} == 0`,
+		"StragglerShieldNoRecentPulse":   "> 172800",
+		"StragglerShieldQuarantineSpike": "[30m])) >= 9",
+		"StragglerShieldCVCreeping":      "> 2 * avg_over_time",
+	} {
+		if !strings.Contains(exprs[alert], want) {
+			t.Errorf("%s expr = %q, want it to contain %q", alert, exprs[alert], want)
+		}
+	}
+}
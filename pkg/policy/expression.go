@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// expressionCostLimit bounds the work one evaluation may do, so a runaway
+// comprehension over the report cannot stall a reconcile.
+const expressionCostLimit = 100000
+
+// compileExpression type-checks a CEL decision expression. It sees:
+//
+//	reason  string  the failure reason code, e.g. "high_variance"
+//	report  map     the PulseReport as JSON (pulse_id, worst_mean_ns, devices, ...)
+//	node    map     name, labels and annotations of the node
+//
+// and must return a string.
+func compileExpression(expr string) (cel.Program, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("reason", cel.StringType),
+		cel.Variable("report", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("node", cel.MapType(cel.StringType, cel.DynType)),
+		cel.OptionalTypes(),
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("build CEL environment: %w", err)
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if ast.OutputType() != cel.StringType {
+		return nil, fmt.Errorf("expression returns %s, want string", ast.OutputType())
+	}
+	return env.Program(ast, cel.CostLimit(expressionCostLimit))
+}
+
+// Decide returns the action for a failure with the given reason on node. An
+// Expression is evaluated first; an empty result defers to SeverityFor. If
+// the expression fails at runtime (a missing map key, an unknown severity)
+// SeverityFor is used and the error is returned for the caller to log: a
+// broken site rule must not stop failures from being acted on.
+func (p *Policy) Decide(reason string, node *corev1.Node, report *pulse.PulseReport) (Severity, error) {
+	if p == nil || p.program == nil {
+		return p.SeverityFor(reason), nil
+	}
+	sev, err := p.evaluate(reason, node, report)
+	if err != nil {
+		return p.SeverityFor(reason), fmt.Errorf("policy expression: %w", err)
+	}
+	if sev == "" {
+		return p.SeverityFor(reason), nil
+	}
+	return sev, nil
+}
+
+func (p *Policy) evaluate(reason string, node *corev1.Node, report *pulse.PulseReport) (Severity, error) {
+	// The expression addresses the report by its JSON field names, the
+	// same ones the logs, PulseResults and the aggregator show.
+	var rep map[string]any
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("marshal report: %w", err)
+	}
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return "", fmt.Errorf("unmarshal report: %w", err)
+	}
+	n := map[string]any{"name": "", "labels": map[string]string{}, "annotations": map[string]string{}}
+	if node != nil {
+		n["name"] = node.Name
+		if node.Labels != nil {
+			n["labels"] = node.Labels
+		}
+		if node.Annotations != nil {
+			n["annotations"] = node.Annotations
+		}
+	}
+	out, _, err := p.program.Eval(map[string]any{"reason": reason, "report": rep, "node": n})
+	if err != nil {
+		return "", err
+	}
+	s, ok := out.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("result %v is not a string", out.Value())
+	}
+	sev := Severity(s)
+	if sev != "" && !sev.valid() {
+		return "", fmt.Errorf("unknown severity %q (want quarantine, degrade, degrade-label, warn or \"\")", s)
+	}
+	return sev, nil
+}
@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justin-oleary/straggler-shield/pkg/pulse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDecide(t *testing.T) {
+	t.Parallel()
+
+	burnIn := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-0", Labels: map[string]string{"burn-in": "true"}}}
+	plain := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-1"}}
+	report := &pulse.PulseReport{PulseID: "p1", WorstMeanNS: 40e6, Devices: []pulse.DeviceResult{{Device: 0, MeanNS: 40e6, CV: 0.31}}}
+
+	cases := []struct {
+		name       string
+		severities map[string]Severity
+		expr       string
+		reason     string
+		node       *corev1.Node
+		want       Severity
+		wantErr    bool
+	}{
+		{
+			name:   "burn-in node ignores CV failures",
+			expr:   `reason == "high_variance" && node.labels[?"burn-in"].orValue("") == "true" ? "warn" : ""`,
+			reason: "high_variance", node: burnIn, want: SeverityWarn,
+		},
+		{
+			name:       "empty result defers to severities",
+			severities: map[string]Severity{"high_variance": SeverityDegrade},
+			expr:       `reason == "high_variance" && node.labels[?"burn-in"].orValue("") == "true" ? "warn" : ""`,
+			reason:     "high_variance", node: plain, want: SeverityDegrade,
+		},
+		{
+			name:   "report fields",
+			expr:   `report.devices.exists(d, d.cv > 0.3) && report.worst_mean_ns < 50000000 ? "degrade" : ""`,
+			reason: "high_variance", node: plain, want: SeverityDegrade,
+		},
+		{
+			name:   "runtime error falls back",
+			expr:   `node.labels["burn-in"] == "true" ? "warn" : ""`,
+			reason: "high_variance", node: plain, want: SeverityQuarantine, wantErr: true,
+		},
+		{
+			name:   "unknown severity falls back",
+			expr:   `"ignore"`,
+			reason: "near_threshold", node: plain, want: SeverityDegrade, wantErr: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &Policy{Severities: tc.severities, Expression: tc.expr}
+			if err := p.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			got, err := p.Decide(tc.reason, tc.node, report)
+			if got != tc.want || (err != nil) != tc.wantErr {
+				t.Errorf("Decide = %q, %v; want %q, error %v", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateExpression(t *testing.T) {
+	t.Parallel()
+
+	for expr, want := range map[string]string{
+		`reason ==`:            "Syntax error",
+		`report.worst_mean_ns`: "want string",
+		`reasons == "x"`:       "undeclared reference",
+	} {
+		p := &Policy{Expression: expr}
+		if err := p.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%q) = %v, want an error containing %q", expr, err, want)
+		}
+	}
+}
@@ -0,0 +1,466 @@
+// Package policy holds the operator-supplied decision policy: how the
+// controller acts on each class of pulse failure. The policy is loaded once
+// at startup from a YAML or JSON file (POLICY_FILE); a missing file means the
+// built-in behaviour of quarantining on every failure.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Severity is the action taken when a check fails.
+type Severity string
+
+const (
+	// SeverityQuarantine taints the node so no new work lands on it.
+	SeverityQuarantine Severity = "quarantine"
+
+	// SeverityDegrade puts the node in the degraded tier: the
+	// straggler-shield.io/degraded label plus a PreferNoSchedule taint, which
+	// steers new large jobs elsewhere without evicting capacity outright.
+	SeverityDegrade Severity = "degrade"
+
+	// SeverityDegradeLabel applies only the straggler-shield.io/degraded
+	// label, so schedulers can steer around the node voluntarily.
+	SeverityDegradeLabel Severity = "degrade-label"
+
+	// SeverityWarn records the failure in the node condition, metrics and
+	// logs but leaves scheduling untouched. Useful when rolling out a new
+	// check whose false-positive rate is not yet known.
+	SeverityWarn Severity = "warn"
+)
+
+// Handling is how a failure the pulse could not turn into a verdict is acted
+// on (see pulse.IsInconclusive): a stage timeout, unreadable GPU stats, a GPU
+// busy with another workload, or a GEMM below the utilization floor.
+type Handling string
+
+const (
+	// HandlingRetry leaves the node as it is and pulses it again later.
+	// After MaxInconclusiveRetries inconclusive pulses in a row the failure
+	// is acted on by its severity.
+	HandlingRetry Handling = "retry"
+
+	// HandlingWarn records the failure like SeverityWarn.
+	HandlingWarn Handling = "warn"
+
+	// HandlingQuarantine taints the node like SeverityQuarantine.
+	HandlingQuarantine Handling = "quarantine"
+)
+
+// MaxInconclusiveRetries bounds HandlingRetry, so a node whose GPU stats can
+// never be read is not pulsed again indefinitely.
+const MaxInconclusiveRetries = 3
+
+// Severity returns the severity a warn or quarantine handling acts with, or
+// "" for retry.
+func (h Handling) Severity() Severity {
+	switch h {
+	case HandlingWarn:
+		return SeverityWarn
+	case HandlingQuarantine:
+		return SeverityQuarantine
+	}
+	return ""
+}
+
+func (h Handling) valid() bool {
+	switch h {
+	case HandlingRetry, HandlingWarn, HandlingQuarantine:
+		return true
+	}
+	return false
+}
+
+// WorkloadClass is a kind of workload a failure can make a node unfit for.
+type WorkloadClass string
+
+const (
+	// WorkloadTraining is multi-GPU and multi-node training, bound by
+	// collectives: one slow GPU or link stalls every rank.
+	WorkloadTraining WorkloadClass = "training"
+
+	// WorkloadSingleGPU is a batch job on one GPU, which only runs slower
+	// on a slow device.
+	WorkloadSingleGPU WorkloadClass = "single-gpu"
+
+	// WorkloadInference is serving, which is sensitive to tail latency but
+	// rarely spans GPUs.
+	WorkloadInference WorkloadClass = "inference"
+)
+
+// WorkloadClassesAll lists every workload class, in the order they are
+// reported.
+var WorkloadClassesAll = []WorkloadClass{WorkloadTraining, WorkloadSingleGPU, WorkloadInference}
+
+func (w WorkloadClass) valid() bool {
+	return slices.Contains(WorkloadClassesAll, w)
+}
+
+func (s Severity) valid() bool {
+	switch s {
+	case SeverityQuarantine, SeverityDegrade, SeverityDegradeLabel, SeverityWarn:
+		return true
+	}
+	return false
+}
+
+// Policy is the decision policy document.
+//
+//	severities:
+//	  interconnect_degraded: warn
+//	  high_variance: degrade
+//	  near_threshold: degrade-label
+//	inconclusive: retry
+//	cvCeilings:
+//	  B200: 0.40
+//	readyWindows:
+//	  pools:
+//	    p5.48xlarge: 15m
+//	taints:
+//	  interconnect_degraded:
+//	    value: fabric
+//	workloadClasses:
+//	  impacts:
+//	    latency_threshold_exceeded: [training, inference]
+//	expression: |
+//	  reason == "high_variance" && node.labels[?"burn-in"].orValue("") == "true" ? "warn" : ""
+type Policy struct {
+	// Severities maps failure reason codes (Reasons) to the action taken;
+	// Validate rejects any other key. Reasons not listed quarantine, except
+	// near_threshold and canary_failed which default to degrade and
+	// inconclusive which defaults to warn.
+	Severities map[string]Severity `json:"severities,omitempty"`
+
+	// SoftQuarantine makes a node's first quarantine a PreferNoSchedule
+	// taint; only a second consecutive failure (confirmation) escalates it
+	// to NoSchedule. Limits the blast radius of one noisy measurement on
+	// scarce capacity.
+	SoftQuarantine bool `json:"softQuarantine,omitempty"`
+
+	// Inconclusive handles every failure that is not a verdict on the node
+	// (pulse.IsInconclusive) the same way, whatever its reason. Empty leaves
+	// them to Severities and Expression, where stage_timeout quarantines and
+	// inconclusive warns by default.
+	Inconclusive Handling `json:"inconclusive,omitempty"`
+
+	// CVCeilings overrides the calibrated coefficient-of-variation ceiling
+	// per GPU architecture key (B200, GB200, H100, H200, A100). A
+	// PULSE_CV_MAX_<ARCH> env var still wins.
+	CVCeilings map[string]float64 `json:"cvCeilings,omitempty"`
+
+	// Taints sets the quarantine taint's value and effect per reason code,
+	// so workloads can tolerate one failure class and not the others: a
+	// node whose NVLink fabric failed is useless for distributed training
+	// but can still serve single-GPU inference pods tolerating
+	// <key>=fabric. The key stays the configured quarantine key, so
+	// every quarantine is still found by it.
+	Taints map[string]Taint `json:"taints,omitempty"`
+
+	// WorkloadClasses scopes a quarantine to the workload classes a failure
+	// impacts: a failure that leaves some classes unaffected taints the node
+	// for the impacted ones only, instead of taking all of it away. Nil
+	// quarantines the whole node on every failure.
+	WorkloadClasses *WorkloadClasses `json:"workloadClasses,omitempty"`
+
+	// ReadyWindows overrides READY_WINDOW_SECONDS per node pool, so slow
+	// POSTing HGX nodes and fast-booting VMs each get a window that fits.
+	ReadyWindows *ReadyWindows `json:"readyWindows,omitempty"`
+
+	// Expression is a CEL expression deciding the action for each failure
+	// from its reason, the pulse report and the node's name, labels and
+	// annotations (see Decide). It returns a severity, or "" to use
+	// Severities. Validate compiles it.
+	Expression string `json:"expression,omitempty"`
+
+	program cel.Program
+}
+
+// Taint is the quarantine taint for one failure class.
+type Taint struct {
+	// Value is written as the taint value instead of the measured pulse
+	// duration. Required, so the class can be told apart and tolerated.
+	Value string `json:"value"`
+
+	// Effect overrides the configured effect: NoSchedule,
+	// PreferNoSchedule or NoExecute. Empty keeps it.
+	Effect corev1.TaintEffect `json:"effect,omitempty"`
+}
+
+// WorkloadClasses maps failure classes to the workload classes they impact.
+type WorkloadClasses struct {
+	// Impacts maps a reason code to the workload classes it makes the node
+	// unfit for. Reasons not listed use the built-in impacts: a degraded
+	// interconnect only affects training, and high variance training and
+	// inference; every other failure affects all classes.
+	Impacts map[string][]WorkloadClass `json:"impacts,omitempty"`
+}
+
+// defaultImpacts are the workload classes a failure impacts when
+// WorkloadClasses.Impacts does not list it. Anything not here impacts every
+// class.
+var defaultImpacts = map[string][]WorkloadClass{
+	// NVLink and P2P only carry collectives; one GPU still computes at
+	// full speed.
+	"interconnect_degraded": {WorkloadTraining},
+	// Jitter stalls synchronous steps and shows in serving tail latency,
+	// but a batch job only finishes a little later.
+	"high_variance": {WorkloadTraining, WorkloadInference},
+}
+
+// ReadyWindows is how recently a node must have turned Ready, per pool, for
+// a watch event to pulse it.
+type ReadyWindows struct {
+	// Label is the node label naming the pool. Empty uses
+	// node.kubernetes.io/instance-type, like GPU_POOL_LABEL.
+	Label string `json:"label,omitempty"`
+
+	// Pools maps a pool (the label's value) to a Go duration such as "15m".
+	// Nodes in other pools use READY_WINDOW_SECONDS.
+	Pools map[string]string `json:"pools"`
+
+	windows map[string]time.Duration
+}
+
+// Default returns the built-in policy: quarantine on every failure.
+func Default() *Policy {
+	return &Policy{}
+}
+
+// Load reads and validates a policy file. YAML and JSON are both accepted.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policy: %w", err)
+	}
+	var p Policy
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("parse policy %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Validate rejects unknown severities so a typo cannot silently downgrade a
+// check to the default, and compiles Expression so a syntax or type error
+// fails startup rather than the first failure.
+func (p *Policy) Validate() error {
+	for reason, sev := range p.Severities {
+		if err := knownReason(reason); err != nil {
+			return fmt.Errorf("severities: %w", err)
+		}
+		if !sev.valid() {
+			return fmt.Errorf("severity for %q: unknown value %q (want quarantine, degrade, degrade-label or warn)", reason, sev)
+		}
+	}
+	if p.Inconclusive != "" && !p.Inconclusive.valid() {
+		return fmt.Errorf("inconclusive: unknown handling %q (want retry, warn or quarantine)", p.Inconclusive)
+	}
+	if p.Expression != "" {
+		prg, err := compileExpression(p.Expression)
+		if err != nil {
+			return fmt.Errorf("expression: %w", err)
+		}
+		p.program = prg
+	}
+	for arch, v := range p.CVCeilings {
+		if v <= 0 || v > 1 {
+			return fmt.Errorf("CV ceiling for %q: %v is not a fraction in (0,1]", arch, v)
+		}
+	}
+	for reason, t := range p.Taints {
+		if err := knownReason(reason); err != nil {
+			return fmt.Errorf("taints: %w", err)
+		}
+		if t.Value == "" {
+			return fmt.Errorf("taint for %q: value is required", reason)
+		}
+		if errs := validation.IsValidLabelValue(t.Value); len(errs) > 0 {
+			return fmt.Errorf("taint for %q: value %q: %s", reason, t.Value, strings.Join(errs, "; "))
+		}
+		switch t.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("taint for %q: effect %q: want NoSchedule, PreferNoSchedule or NoExecute", reason, t.Effect)
+		}
+	}
+	if wc := p.WorkloadClasses; wc != nil {
+		for reason, classes := range wc.Impacts {
+			if err := knownReason(reason); err != nil {
+				return fmt.Errorf("workload classes: %w", err)
+			}
+			if len(classes) == 0 {
+				return fmt.Errorf("workload classes for %q: at least one class is required", reason)
+			}
+			for i, class := range classes {
+				if !class.valid() {
+					return fmt.Errorf("workload classes for %q: unknown class %q (want training, single-gpu or inference)", reason, class)
+				}
+				if slices.Contains(classes[:i], class) {
+					return fmt.Errorf("workload classes for %q: %q listed twice", reason, class)
+				}
+			}
+			if _, ok := p.Taints[reason]; ok {
+				return fmt.Errorf("workload classes for %q: the reason also has a taint; set one or the other", reason)
+			}
+		}
+	}
+	if rw := p.ReadyWindows; rw != nil {
+		rw.windows = make(map[string]time.Duration, len(rw.Pools))
+		for pool, s := range rw.Pools {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("ready window for pool %q: %q is not a positive duration", pool, s)
+			}
+			rw.windows[pool] = d
+		}
+	}
+	return nil
+}
+
+// TaintFor returns the quarantine taint set for a failure reason, if any.
+// Nil-safe.
+func (p *Policy) TaintFor(reason string) (Taint, bool) {
+	if p == nil {
+		return Taint{}, false
+	}
+	t, ok := p.Taints[reason]
+	return t, ok
+}
+
+// IsClassTaintValue reports whether value is the taint value of some
+// failure class in Taints. Nil-safe.
+func (p *Policy) IsClassTaintValue(value string) bool {
+	if p == nil {
+		return false
+	}
+	for _, t := range p.Taints {
+		if t.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ImpactedClasses returns the workload classes a failure reason makes a node
+// unfit for, in WorkloadClassesAll order, when that is only some of them.
+// scoped is false when the whole node should be quarantined: WorkloadClasses
+// is not set, the failure impacts every class, or Taints sets a taint for
+// the reason. Nil-safe.
+func (p *Policy) ImpactedClasses(reason string) (classes []WorkloadClass, scoped bool) {
+	if p == nil || p.WorkloadClasses == nil {
+		return nil, false
+	}
+	if _, ok := p.Taints[reason]; ok {
+		return nil, false
+	}
+	impacts, ok := p.WorkloadClasses.Impacts[reason]
+	if !ok {
+		impacts = defaultImpacts[reason]
+	}
+	for _, class := range WorkloadClassesAll {
+		if slices.Contains(impacts, class) {
+			classes = append(classes, class)
+		}
+	}
+	if len(classes) == 0 || len(classes) == len(WorkloadClassesAll) {
+		return nil, false
+	}
+	return classes, true
+}
+
+// ReadyWindowPools returns the pool label and the Ready window of each pool
+// listed in ReadyWindows, or no windows if there are none. Nil-safe; the
+// policy must have been validated.
+func (p *Policy) ReadyWindowPools() (label string, windows map[string]time.Duration) {
+	if p == nil || p.ReadyWindows == nil {
+		return "", nil
+	}
+	return p.ReadyWindows.Label, p.ReadyWindows.windows
+}
+
+// CVCeiling returns the policy's CV ceiling for an architecture key, if set.
+func (p *Policy) CVCeiling(arch string) (float64, bool) {
+	if p == nil || arch == "" {
+		return 0, false
+	}
+	v, ok := p.CVCeilings[arch]
+	return v, ok
+}
+
+// Reasons are the failure reason codes a pulse is classified under: the
+// reason label of gpu_validator_check_failures_total and the keys of
+// Severities, Taints and WorkloadClasses.Impacts.
+var Reasons = []string{
+	"latency_threshold_exceeded",
+	"high_variance",
+	"interconnect_degraded",
+	"clock_derated",
+	"pcie_degraded",
+	"low_power",
+	"near_threshold",
+	"inconclusive",
+	"stage_timeout",
+	"pulse_crashed",
+	"misconfiguration",
+	"gpu_count_decreased",
+	"pre_flight_failure",
+	"xid_error",
+	"row_remap_failure",
+	"ecc_errors",
+	"thermal_recovery",
+	"external_check",
+	"canary_failed",
+}
+
+func knownReason(reason string) error {
+	if !slices.Contains(Reasons, reason) {
+		return fmt.Errorf("unknown reason code %q (see the reason label of gpu_validator_check_failures_total)", reason)
+	}
+	return nil
+}
+
+// defaultSeverities apply to reasons the policy does not list. Anything not
+// here quarantines.
+var defaultSeverities = map[string]Severity{
+	// Passed every hard threshold but only just — degraded tier, not quarantine.
+	"near_threshold": SeverityDegrade,
+	// Two nodes share the verdict, and one of them may be healthy.
+	"canary_failed": SeverityDegrade,
+	// The GEMM never loaded the GPU, so the pulse says nothing about it:
+	// recorded as not passing, but no grounds to take capacity away.
+	"inconclusive": SeverityWarn,
+}
+
+// InconclusiveHandling returns how inconclusive failures are handled, or ""
+// if they are left to the severities. Nil-safe.
+func (p *Policy) InconclusiveHandling() Handling {
+	if p == nil {
+		return ""
+	}
+	return p.Inconclusive
+}
+
+// SeverityFor returns the configured action for a failure reason.
+func (p *Policy) SeverityFor(reason string) Severity {
+	if p != nil {
+		if sev, ok := p.Severities[reason]; ok {
+			return sev
+		}
+	}
+	if sev, ok := defaultSeverities[reason]; ok {
+		return sev
+	}
+	return SeverityQuarantine
+}
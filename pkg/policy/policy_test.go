@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestInconclusiveHandling(t *testing.T) {
+	t.Parallel()
+
+	if h := (*Policy)(nil).InconclusiveHandling(); h != "" {
+		t.Errorf("nil policy handling = %q, want none", h)
+	}
+	for h, want := range map[Handling]Severity{HandlingWarn: SeverityWarn, HandlingQuarantine: SeverityQuarantine, HandlingRetry: ""} {
+		p := &Policy{Inconclusive: h}
+		if err := p.Validate(); err != nil {
+			t.Errorf("Validate(%q): %v", h, err)
+		}
+		if got := h.Severity(); got != want {
+			t.Errorf("%q.Severity() = %q, want %q", h, got, want)
+		}
+	}
+	if err := (&Policy{Inconclusive: "ignore"}).Validate(); err == nil {
+		t.Error("Validate accepted unknown handling \"ignore\"")
+	}
+}
+
+func TestReadyWindows(t *testing.T) {
+	t.Parallel()
+
+	if label, windows := (*Policy)(nil).ReadyWindowPools(); label != "" || windows != nil {
+		t.Errorf("nil policy ready windows = %q %v, want none", label, windows)
+	}
+	p := &Policy{ReadyWindows: &ReadyWindows{Label: "pool", Pools: map[string]string{"hgx": "15m", "vm": "90s"}}}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if label, windows := p.ReadyWindowPools(); label != "pool" || windows["hgx"] != 15*time.Minute || windows["vm"] != 90*time.Second {
+		t.Errorf("ReadyWindowPools = %q %v", label, windows)
+	}
+	for _, bad := range []string{"15", "-1m", "0s"} {
+		if err := (&Policy{ReadyWindows: &ReadyWindows{Pools: map[string]string{"hgx": bad}}}).Validate(); err == nil {
+			t.Errorf("Validate accepted ready window %q", bad)
+		}
+	}
+}
+
+func TestTaints(t *testing.T) {
+	t.Parallel()
+
+	p := &Policy{Taints: map[string]Taint{"interconnect_degraded": {Value: "fabric", Effect: "PreferNoSchedule"}}}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got, ok := p.TaintFor("interconnect_degraded"); !ok || got.Value != "fabric" {
+		t.Errorf("TaintFor = %+v, %v", got, ok)
+	}
+	if _, ok := (*Policy)(nil).TaintFor("interconnect_degraded"); ok {
+		t.Error("nil policy has a class taint")
+	}
+	if !p.IsClassTaintValue("fabric") || p.IsClassTaintValue("600ms") {
+		t.Error("IsClassTaintValue misreports")
+	}
+	for _, bad := range []Taint{{}, {Value: "no spaces"}, {Value: "fabric", Effect: "Evict"}} {
+		if err := (&Policy{Taints: map[string]Taint{"high_variance": bad}}).Validate(); err == nil {
+			t.Errorf("Validate accepted taint %+v", bad)
+		}
+	}
+}
+
+func TestImpactedClasses(t *testing.T) {
+	t.Parallel()
+
+	if _, scoped := (*Policy)(nil).ImpactedClasses("interconnect_degraded"); scoped {
+		t.Error("nil policy scopes a quarantine")
+	}
+	p := &Policy{
+		Taints: map[string]Taint{"high_variance": {Value: "jitter"}},
+		WorkloadClasses: &WorkloadClasses{Impacts: map[string][]WorkloadClass{
+			"latency_threshold_exceeded": {WorkloadInference, WorkloadTraining},
+			"pre_flight_failure":         {WorkloadInference, WorkloadSingleGPU, WorkloadTraining},
+		}},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for reason, want := range map[string][]WorkloadClass{
+		"interconnect_degraded":      {WorkloadTraining},
+		"latency_threshold_exceeded": {WorkloadTraining, WorkloadInference},
+		"high_variance":              nil, // its taint wins
+		"pre_flight_failure":         nil, // every class
+		"stage_timeout":              nil,
+	} {
+		got, scoped := p.ImpactedClasses(reason)
+		if scoped != (want != nil) || !slices.Equal(got, want) {
+			t.Errorf("ImpactedClasses(%q) = %v, %v, want %v", reason, got, scoped, want)
+		}
+	}
+	impacts := func(classes ...WorkloadClass) *WorkloadClasses {
+		return &WorkloadClasses{Impacts: map[string][]WorkloadClass{"latency_threshold_exceeded": classes}}
+	}
+	for _, bad := range []*Policy{
+		{WorkloadClasses: impacts()},
+		{WorkloadClasses: impacts("batch")},
+		{WorkloadClasses: impacts(WorkloadTraining, WorkloadTraining)},
+		{WorkloadClasses: impacts(WorkloadTraining), Taints: map[string]Taint{"latency_threshold_exceeded": {Value: "slow"}}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate accepted impacts %v with taints %v", bad.WorkloadClasses.Impacts, bad.Taints)
+		}
+	}
+}
+
+func TestUnknownReason(t *testing.T) {
+	t.Parallel()
+
+	for _, bad := range []*Policy{
+		{Severities: map[string]Severity{"high_varience": SeverityWarn}},
+		{Taints: map[string]Taint{"interconect_degraded": {Value: "fabric"}}},
+		{WorkloadClasses: &WorkloadClasses{Impacts: map[string][]WorkloadClass{"latency": {WorkloadTraining}}}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate accepted %+v", bad)
+		}
+	}
+	severities := make(map[string]Severity, len(Reasons))
+	for _, reason := range Reasons {
+		severities[reason] = SeverityWarn
+	}
+	if err := (&Policy{Severities: severities}).Validate(); err != nil {
+		t.Errorf("Validate rejected a known reason: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package pulse
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ArchTableEnv names a YAML or JSON file of architecture calibrations, for
+// example mounted from a ConfigMap. Its entries are matched against the GPU
+// name before the built-in table, so a new SKU, or a recalibrated one, needs
+// no rebuild:
+//
+//	architectures:
+//	- keys: [L40S]
+//	  thresholdMs: 15
+//	  maxCV: 0.12
+//	  minPowerFraction: 0.35
+//
+// A file that cannot be loaded is ignored in favour of the built-in table;
+// the agent rejects it at startup (see LoadArchTable). The file is read once
+// per process, so a changed file takes effect when the agent restarts.
+const ArchTableEnv = "PULSE_ARCH_TABLE"
+
+// ArchTable is the content of an ArchTableEnv file.
+type ArchTable struct {
+	// Architectures are matched in order, the first key that is a whole
+	// word of the upper-cased GPU name winning.
+	Architectures []ArchEntry `json:"architectures"`
+}
+
+// ArchEntry is the calibration of one architecture.
+type ArchEntry struct {
+	// Keys are upper-case words of the GPU name, e.g. "H20". Each also
+	// names the architecture's PULSE_CV_MAX_<KEY> override.
+	Keys        []string `json:"keys"`
+	ThresholdMS int64    `json:"thresholdMs"`
+	MaxCV       float64  `json:"maxCV"`
+	// MinPowerFraction is the power floor as a fraction of TDP; zero
+	// disables the power check on the architecture.
+	MinPowerFraction float64 `json:"minPowerFraction,omitempty"`
+}
+
+// archKeyRe is the form of an architecture key: safe to embed in an
+// environment variable name.
+var archKeyRe = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
+// LoadArchTable reads and validates the architecture table at path.
+func LoadArchTable(path string) (*ArchTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t ArchTable
+	if err := yaml.UnmarshalStrict(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Validate rejects an empty table, entries without keys, malformed or
+// repeated keys and out-of-range limits.
+func (t *ArchTable) Validate() error {
+	if len(t.Architectures) == 0 {
+		return fmt.Errorf("architectures: at least one entry is required")
+	}
+	seen := make(map[string]bool)
+	for i, a := range t.Architectures {
+		if len(a.Keys) == 0 {
+			return fmt.Errorf("architectures[%d]: keys: at least one key is required", i)
+		}
+		for _, key := range a.Keys {
+			if !archKeyRe.MatchString(key) {
+				return fmt.Errorf("architectures[%d]: key %q: want upper-case letters, digits and underscores", i, key)
+			}
+			if seen[key] {
+				return fmt.Errorf("architectures[%d]: key %q listed twice", i, key)
+			}
+			seen[key] = true
+		}
+		if a.ThresholdMS <= 0 {
+			return fmt.Errorf("architectures[%d]: thresholdMs: %d is not positive", i, a.ThresholdMS)
+		}
+		if a.MaxCV <= 0 || a.MaxCV > 1 {
+			return fmt.Errorf("architectures[%d]: maxCV: %v is not a fraction in (0,1]", i, a.MaxCV)
+		}
+		if a.MinPowerFraction < 0 || a.MinPowerFraction > 1 {
+			return fmt.Errorf("architectures[%d]: minPowerFraction: %v is not a fraction in [0,1]", i, a.MinPowerFraction)
+		}
+	}
+	return nil
+}
+
+// calibrations converts the table's entries.
+func (t *ArchTable) calibrations() []archCalibration {
+	cals := make([]archCalibration, 0, len(t.Architectures))
+	for _, a := range t.Architectures {
+		cals = append(cals, archCalibration{
+			keys:             a.Keys,
+			threshold:        time.Duration(a.ThresholdMS) * time.Millisecond,
+			maxCV:            a.MaxCV,
+			minPowerFraction: a.MinPowerFraction,
+		})
+	}
+	return cals
+}
+
+// archTableCache holds the last ArchTableEnv file loaded, so GPU detection
+// and CV lookups do not re-read it. A changed path is loaded afresh; a
+// changed file at the same path is not.
+var archTableCache struct {
+	sync.Mutex
+	path string
+	cals []archCalibration
+}
+
+// calibrations returns the architecture calibrations in match order: the
+// ArchTableEnv file's entries, if it is set and loads, then the built-in
+// archCalibrations. The first n came from the file at table.
+func calibrations() (cals []archCalibration, table string, n int) {
+	path := strings.TrimSpace(os.Getenv(ArchTableEnv))
+	if path == "" {
+		return archCalibrations, "", 0
+	}
+	archTableCache.Lock()
+	defer archTableCache.Unlock()
+	if archTableCache.path != path {
+		archTableCache.path, archTableCache.cals = path, nil
+		if t, err := LoadArchTable(path); err == nil {
+			archTableCache.cals = t.calibrations()
+		}
+	}
+	n = len(archTableCache.cals)
+	return append(archTableCache.cals[:n:n], archCalibrations...), path, n
+}
@@ -0,0 +1,104 @@
+package pulse
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeArchTable(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "arch.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadArchTable(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"valid", "architectures:\n- keys: [L40S]\n  thresholdMs: 60\n  maxCV: 0.25\n  minPowerFraction: 0.35\n", ""},
+		{"json", `{"architectures": [{"keys": ["H20"], "thresholdMs": 40, "maxCV": 0.3}]}`, ""},
+		{"empty", "architectures: []\n", "at least one entry"},
+		{"no keys", "architectures:\n- thresholdMs: 60\n  maxCV: 0.25\n", "at least one key"},
+		{"lower-case key", "architectures:\n- keys: [l40s]\n  thresholdMs: 60\n  maxCV: 0.25\n", `key "l40s"`},
+		{"repeated key", "architectures:\n- keys: [L40S]\n  thresholdMs: 60\n  maxCV: 0.25\n- keys: [L40S]\n  thresholdMs: 50\n  maxCV: 0.25\n", "listed twice"},
+		{"zero threshold", "architectures:\n- keys: [L40S]\n  maxCV: 0.25\n", "thresholdMs"},
+		{"CV above one", "architectures:\n- keys: [L40S]\n  thresholdMs: 60\n  maxCV: 1.5\n", "maxCV"},
+		{"power fraction above one", "architectures:\n- keys: [L40S]\n  thresholdMs: 60\n  maxCV: 0.25\n  minPowerFraction: 2\n", "minPowerFraction"},
+		{"unknown field", "architectures:\n- keys: [L40S]\n  threshold: 60ms\n  maxCV: 0.25\n", `unknown field "threshold"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadArchTable(writeArchTable(t, tc.body))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadArchTable = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("LoadArchTable = %v, want an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDetectArchFromTable(t *testing.T) {
+	dir := t.TempDir()
+	writeCheck(t, dir, "nvidia-smi", `echo "NVIDIA H100 80GB HBM3"`)
+	t.Setenv("PATH", dir)
+	table := writeArchTable(t, "architectures:\n- keys: [L40S]\n  thresholdMs: 60\n  maxCV: 0.25\n- keys: [H100]\n  thresholdMs: 30\n  maxCV: 0.3\n  minPowerFraction: 0.35\n")
+	t.Setenv(ArchTableEnv, table)
+
+	cal, prov := detectArch()
+	if cal.threshold != 30*time.Millisecond || cal.maxCV != 0.3 || cal.minPowerFraction != 0.35 {
+		t.Errorf("calibration = %+v, want the table's H100 entry", cal)
+	}
+	if prov.Source != SourceDetected || prov.Matched != "H100" || prov.Table != table {
+		t.Errorf("provenance = %+v, want H100 detected from %s", prov, table)
+	}
+	if keys := ArchKeys(); keys[0] != "L40S" || slices.Index(keys, "H100") != 1 || !slices.Contains(keys, "A100") {
+		t.Errorf("ArchKeys = %q, want the table's keys first, each once, then the built-in ones", keys)
+	}
+	if got := CalibratedMaxCV("L40S"); got != 0.25 {
+		t.Errorf("CalibratedMaxCV(L40S) = %v, want 0.25", got)
+	}
+}
+
+func TestMatchArchWholeWords(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv(ArchTableEnv, writeArchTable(t, "architectures:\n- keys: [H20]\n  thresholdMs: 40\n  maxCV: 0.3\n- keys: [L40]\n  thresholdMs: 70\n  maxCV: 0.3\n"))
+
+	for gpu, want := range map[string]string{
+		"NVIDIA H20":            "H20",
+		"NVIDIA H200":           "H200",
+		"NVIDIA H100-SXM5-80GB": "H100",
+		"NVIDIA GB200":          "GB200",
+		"NVIDIA L40S":           "",
+		"NVIDIA L40":            "L40",
+	} {
+		if _, prov := matchArch(gpu); prov.Matched != want {
+			t.Errorf("matchArch(%q) matched %q, want %q", gpu, prov.Matched, want)
+		}
+	}
+}
+
+func TestDetectArchBadTableFallsBack(t *testing.T) {
+	dir := t.TempDir()
+	writeCheck(t, dir, "nvidia-smi", `echo "NVIDIA A100-SXM4-80GB"`)
+	t.Setenv("PATH", dir)
+	t.Setenv(ArchTableEnv, writeArchTable(t, "architectures:\n- keys: [A100]\n  thresholdMs: -1\n  maxCV: 0.3\n"))
+
+	cal, prov := detectArch()
+	if cal.threshold != 25*time.Millisecond || prov.Matched != "A100" || prov.Table != "" {
+		t.Errorf("detectArch = %+v, %+v, want the built-in A100 calibration", cal, prov)
+	}
+}
@@ -0,0 +1,95 @@
+package pulse
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// baselineFile holds the node's own GEMM latency baseline, under StateDir().
+// Like deviceCountFile it lives on the host so it outlives the agent; the
+// architecture threshold cannot see cooling or binning differences between
+// nodes of the same SKU, the baseline can.
+const baselineFile = "baseline.json"
+
+// baseline is the per-device mean GEMM latency recorded by the first healthy
+// pulse on a node. Later pulses fail a device whose mean regresses beyond
+// Snapshot.BaselineFactor times its baseline.
+type baseline struct {
+	RecordedAt time.Time     `json:"recorded_at"`
+	PulseID    string        `json:"pulse_id"`
+	MeanNS     map[int]int64 `json:"mean_ns"` // keyed by device index
+}
+
+// loadBaseline reads the baseline from dir. A missing or unreadable file
+// returns nil: there is nothing to compare against yet.
+func loadBaseline(dir string) *baseline {
+	data, err := os.ReadFile(filepath.Join(dir, baselineFile))
+	if err != nil {
+		return nil
+	}
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil || len(b.MeanNS) == 0 {
+		return nil
+	}
+	return &b
+}
+
+// checkBaseline fails with ErrStragglerDetected if dev's mean exceeds factor
+// times its baseline. A nil baseline, a device it does not cover, or a zero
+// factor passes.
+func checkBaseline(b *baseline, dev int, mean time.Duration, factor float64) error {
+	base := b.mean(dev)
+	if base == 0 || factor <= 0 {
+		return nil
+	}
+	if limit := time.Duration(factor * float64(base)); mean > limit {
+		return &PulseFailure{
+			Cause:          fmt.Errorf("GPU %d: %w (mean=%v, %.1f× node baseline %v)", dev, ErrStragglerDetected, mean, float64(mean)/float64(base), base),
+			MeasuredValue:  float64(mean.Milliseconds()),
+			ThresholdValue: float64(limit.Milliseconds()),
+			Unit:           "ms",
+		}
+	}
+	return nil
+}
+
+// mean returns dev's baseline mean, or zero. Nil-safe.
+func (b *baseline) mean(dev int) time.Duration {
+	if b == nil {
+		return 0
+	}
+	return time.Duration(b.MeanNS[dev])
+}
+
+// recordBaseline persists the device means of report as the node's baseline
+// if none exists yet. Call it only after a healthy pulse (no failure and no
+// near-threshold finding). Best effort, like recordDeviceCount. To
+// recalibrate after a hardware change, delete the file on the node.
+func recordBaseline(dir string, report *PulseReport) {
+	if dir == "" || len(report.Devices) == 0 {
+		return
+	}
+	path := filepath.Join(dir, baselineFile)
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	b := baseline{RecordedAt: time.Now().UTC(), PulseID: report.PulseID, MeanNS: make(map[int]int64, len(report.Devices))}
+	for _, d := range report.Devices {
+		b.MeanNS[d.Device] = d.MeanNS
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
@@ -0,0 +1,64 @@
+package pulse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBaselineCheck(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		recorded time.Duration // device 0 baseline; 0 = no state file
+		mean     time.Duration
+		factor   float64
+		wantErr  bool
+	}{
+		{name: "first pulse — nothing recorded", mean: 30 * time.Millisecond, factor: 2},
+		{name: "within the allowed regression", recorded: 10 * time.Millisecond, mean: 19 * time.Millisecond, factor: 2},
+		{name: "regressed beyond twice the baseline", recorded: 10 * time.Millisecond, mean: 21 * time.Millisecond, factor: 2, wantErr: true},
+		{name: "baseline off", recorded: 10 * time.Millisecond, mean: 50 * time.Millisecond},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if tc.recorded > 0 {
+				recordBaseline(dir, &PulseReport{Devices: []DeviceResult{{Device: 0, MeanNS: tc.recorded.Nanoseconds()}}})
+			}
+
+			err := checkBaseline(loadBaseline(dir), 0, tc.mean, tc.factor)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr {
+				return
+			}
+			if !errors.Is(err, ErrStragglerDetected) {
+				t.Errorf("err = %v, want ErrStragglerDetected", err)
+			}
+			var pf *PulseFailure
+			if !errors.As(err, &pf) || pf.MeasuredValue != 21 || pf.ThresholdValue != 20 || pf.Unit != "ms" {
+				t.Errorf("failure detail = %+v", pf)
+			}
+		})
+	}
+}
+
+func TestBaselineRecordedOnce(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	recordBaseline(dir, &PulseReport{PulseID: "first", Devices: []DeviceResult{{Device: 0, MeanNS: 10}}})
+	recordBaseline(dir, &PulseReport{PulseID: "second", Devices: []DeviceResult{{Device: 0, MeanNS: 99}}})
+
+	b := loadBaseline(dir)
+	if b == nil || b.PulseID != "first" || b.mean(0) != 10 {
+		t.Errorf("baseline = %+v, want the first pulse's", b)
+	}
+}
@@ -0,0 +1,41 @@
+package pulse
+
+import "sort"
+
+// CheckSettings are the pulse settings read from the environment on each
+// pulse rather than held in Snapshot. With a Snapshot they describe every
+// limit and check a pulse applies, for reports that must stand on their own.
+type CheckSettings struct {
+	// XIDCodes are the XIDs that fail pre-flight, sorted; empty when the
+	// kernel log scan is off (see XIDCodesEnv).
+	XIDCodes []int `json:"xid_codes"`
+	// ClockReset is whether stuck clocks are reset and checked again (see
+	// ClockResetEnv).
+	ClockReset bool `json:"clock_reset"`
+	// CooldownRetries and CooldownWaitMS are the pre-flight checks run
+	// again while hot GPUs cool, and the wait before each (see
+	// CooldownSettings).
+	CooldownRetries int   `json:"cooldown_retries"`
+	CooldownWaitMS  int64 `json:"cooldown_wait_ms"`
+	// ExternalChecks are the operator-supplied checks (see
+	// ExternalChecksEnv).
+	ExternalChecks []string `json:"external_checks,omitempty"`
+}
+
+// ActiveChecks returns the CheckSettings the next pulse in this process will
+// apply.
+func ActiveChecks() CheckSettings {
+	codes := make([]int, 0, len(DefaultXIDCodes))
+	for c := range xidCodes() {
+		codes = append(codes, c)
+	}
+	sort.Ints(codes)
+	retries, wait := CooldownSettings()
+	return CheckSettings{
+		XIDCodes:        codes,
+		ClockReset:      ClockResetEnabled(),
+		CooldownRetries: retries,
+		CooldownWaitMS:  wait.Milliseconds(),
+		ExternalChecks:  ExternalChecks(),
+	}
+}
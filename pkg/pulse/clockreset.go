@@ -0,0 +1,69 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// ClockResetEnv turns off the clock reset tried when the post-pulse clock
+// check fails; see ClockReset. Set to "false" to quarantine stuck clocks
+// straight away.
+const ClockResetEnv = "PULSE_CLOCK_RESET"
+
+// clockResetCommands clear the driver-side clock settings that hold a GPU
+// below its maximum: locked GPU clocks, then application clocks.
+var clockResetCommands = [][]string{
+	{"nvidia-smi", "--reset-gpu-clocks"},
+	{"nvidia-smi", "--reset-applications-clocks"},
+}
+
+// ClockReset records a reset of stuck clocks and the clock check run again
+// after it. Many derated-clock states are left behind in the driver by a
+// thermal or power event, or a forgotten clock lock, and clear without a
+// reboot.
+type ClockReset struct {
+	// FirstError is the clock check failure that prompted the reset.
+	FirstError string `json:"first_error"`
+	// Error is why the check could not run again: the reset or the load
+	// before the second check failed. The first failure then stands.
+	Error string `json:"error,omitempty"`
+	// Recovered is set when the second check passed.
+	Recovered bool `json:"recovered"`
+}
+
+// ClockResetEnabled reports whether a failed clock check is retried after a
+// clock reset. On unless ClockResetEnv is "false".
+func ClockResetEnabled() bool {
+	return os.Getenv(ClockResetEnv) != "false"
+}
+
+// validateClocksReset is validateClocks with one retry: if the clocks are
+// stuck, they are reset (see clockResetCommands), load runs the GPUs up
+// again, and the clocks are checked a second time against the throttle
+// reasons load sampled. A nil load checks them again straight after the
+// reset, without throttle reasons. reset is nil when none was tried: the
+// clocks passed, the check timed out, the GPU is in the hardware thermal
+// slowdown (a reset does not cool it), its PCIe link is degraded, or
+// ClockResetEnv turned it off.
+func validateClocksReset(th Snapshot, g *gaps, throttle []uint64, load func() ([]uint64, error)) (reset *ClockReset, err error) {
+	err = validateClocks(th, g, throttle)
+	if err == nil || errors.Is(err, ErrStageTimeout) || errors.Is(err, errThermalSlowdown) || errors.Is(err, ErrPCIeDegraded) || !ClockResetEnabled() {
+		return nil, err
+	}
+	reset = &ClockReset{FirstError: err.Error()}
+	ctx, cancel := context.WithTimeout(context.Background(), th.ClockCheckTimeout)
+	rerr := runCommands(ctx, clockResetCommands)
+	cancel()
+	throttle = nil
+	if rerr == nil && load != nil {
+		throttle, rerr = load()
+	}
+	if rerr != nil {
+		reset.Error = rerr.Error()
+		return reset, err
+	}
+	err = validateClocks(th, g, throttle)
+	reset.Recovered = err == nil
+	return reset, err
+}
@@ -0,0 +1,77 @@
+package pulse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateClocksReset(t *testing.T) {
+	cases := []struct {
+		name          string
+		reset         string // nvidia-smi's handling of --reset-gpu-clocks
+		disabled      bool
+		wantErr       bool
+		wantReset     bool
+		wantRecovered bool
+		wantResetErr  string
+	}{
+		{"reset clears stuck clocks", `: >"$STATE/reset"`, false, false, true, true, ""},
+		{"clocks stay stuck", `:`, false, true, true, false, ""},
+		{"reset fails", `echo "Insufficient Permissions" >&2; exit 4`, false, true, true, false, "Insufficient Permissions"},
+		{"reset turned off", `: >"$STATE/reset"`, true, true, false, false, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("PATH", dir)
+			t.Setenv("STATE", dir)
+			if tc.disabled {
+				t.Setenv(ClockResetEnv, "false")
+			}
+			writeCheck(t, dir, "nvidia-smi", `case "$1" in
+--reset-gpu-clocks) `+tc.reset+`; exit 0 ;;
+--reset-applications-clocks) exit 0 ;;
+esac
+if [ -f "$STATE/reset" ]; then clock=1980; else clock=600; fi
+echo "$clock, 1980, 40, 0, 0, GPU-0, 1320, 00000000:01:00.0, 0"`)
+
+			loads := 0
+			reset, err := validateClocksReset(validSnapshot(), &gaps{}, nil, func() ([]uint64, error) { loads++; return nil, nil })
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			if (reset != nil) != tc.wantReset {
+				t.Fatalf("reset = %+v, want one %v", reset, tc.wantReset)
+			}
+			if reset == nil {
+				return
+			}
+			if !strings.Contains(reset.FirstError, "SM clock 600MHz") {
+				t.Errorf("FirstError = %q, want the first check's failure", reset.FirstError)
+			}
+			if reset.Recovered != tc.wantRecovered {
+				t.Errorf("Recovered = %v, want %v", reset.Recovered, tc.wantRecovered)
+			}
+			if tc.wantResetErr != "" {
+				if !strings.Contains(reset.Error, tc.wantResetErr) || loads != 0 {
+					t.Errorf("Error = %q after %d loads, want %q and no load", reset.Error, loads, tc.wantResetErr)
+				}
+			} else if loads != 1 {
+				t.Errorf("load ran %d times, want once", loads)
+			}
+		})
+	}
+}
+
+func TestValidateClocksResetLoadFails(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	writeCheck(t, dir, "nvidia-smi", `case "$1" in --reset-*) exit 0 ;; esac
+echo "600, 1980, 40, 0, 0, GPU-0, 1320, 00000000:01:00.0, 0"`)
+
+	reset, err := validateClocksReset(validSnapshot(), &gaps{}, nil, func() ([]uint64, error) { return nil, errors.New("gemm_run: out of memory") })
+	if err == nil || reset == nil || reset.Recovered || !strings.Contains(reset.Error, "out of memory") {
+		t.Errorf("validateClocksReset = %+v, %v; want the first failure and the load error recorded", reset, err)
+	}
+}
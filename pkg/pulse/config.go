@@ -6,41 +6,166 @@ import (
 	"time"
 )
 
-// stragglerThreshold is the mean-latency ceiling per device.
-// Resolution order:
+// Thresholds are resolved from the environment once at package init into the
+// active Config (see Active). The env vars below only set the initial values;
+// embedders can read and replace them at runtime through the Config.
+
+// envStragglerThreshold resolves the initial mean-latency ceiling per device:
 //  1. PULSE_THRESHOLD_MS env var (operator override, always wins)
-//  2. detectGPUThreshold() — architecture-calibrated value from nvidia-smi
-//  3. 500ms fallback if nvidia-smi is unavailable or GPU is unrecognized
-var stragglerThreshold = func() time.Duration {
+//  2. cal — the architecture calibration detected from the GPU name
+//  3. 500ms fallback if the GPU is unavailable or unrecognized (cal is then
+//     fallbackCalibration)
+//
+// prov is the detection result; an override only changes its Source.
+func envStragglerThreshold(cal archCalibration, prov Provenance) (time.Duration, Provenance) {
 	if s := os.Getenv("PULSE_THRESHOLD_MS"); s != "" {
 		if v, err := strconv.ParseInt(s, 10, 64); err == nil && v > 0 {
-			return time.Duration(v) * time.Millisecond
+			prov.Source = SourceEnv
+			return time.Duration(v) * time.Millisecond, prov
 		}
 	}
-	return detectGPUThreshold()
-}()
+	return cal.threshold, prov
+}
 
-// maxCoefficientOfVar is the CV ceiling across runs on a single device.
-// Override with PULSE_CV_MAX (float, e.g. "0.20").
-var maxCoefficientOfVar = envFloat64("PULSE_CV_MAX", 0.20)
+// CVOverrideEnv is the per-SKU CV ceiling variable for an architecture key
+// (see ArchKeys), e.g. PULSE_CV_MAX_B200.
+func CVOverrideEnv(arch string) string { return "PULSE_CV_MAX_" + arch }
 
-// minP2PBandwidthGBs is the minimum acceptable NVLink/PCIe P2P bandwidth.
-// Override with P2P_MIN_GBS (float, e.g. "5.0").
-var minP2PBandwidthGBs = envFloat64("P2P_MIN_GBS", 5.0)
+// envMaxCV resolves the initial CV ceiling:
+//  1. PULSE_CV_MAX_<ARCH> for the matched architecture (per-SKU override)
+//  2. PULSE_CV_MAX (fleet-wide override)
+//  3. cal — the architecture calibration, or 0.20 for unknown hardware
+func envMaxCV(cal archCalibration, prov Provenance) (float64, ThresholdSource) {
+	if prov.Matched != "" {
+		if v := envFloat64(CVOverrideEnv(prov.Matched), 0); v > 0 {
+			return v, SourceEnv
+		}
+	}
+	if v := envFloat64("PULSE_CV_MAX", 0); v > 0 {
+		return v, SourceEnv
+	}
+	if prov.Source == SourceFallback {
+		return cal.maxCV, SourceFallback
+	}
+	return cal.maxCV, SourceDetected
+}
 
-// maxIdleTempC is the GPU temperature ceiling at pre-flight.
-// Override with IDLE_TEMP_MAX (integer Celsius).
-var maxIdleTempC = envInt("IDLE_TEMP_MAX", 70)
+// EnvSnapshot resolves every threshold from the environment and GPU
+// detection as at startup:
+//
+//	PULSE_THRESHOLD_MS         mean GEMM latency ceiling per device
+//	PULSE_CV_MAX_<ARCH>        CV ceiling on one architecture, e.g. _B200
+//	PULSE_CV_MAX               CV ceiling across runs on a device
+//	PULSE_BASELINE_FACTOR      regression over the node baseline (2; 0 disables)
+//	PULSE_JITTER_FLOOR         σ below which CV is ignored (250µs; 0 disables)
+//	P2P_MIN_GBS                minimum NVLink/PCIe P2P bandwidth (5.0)
+//	IDLE_TEMP_MAX              pre-flight GPU temperature ceiling, °C (70)
+//	PULSE_MIN_SM_UTIL          peak SM utilization floor, fraction (0 disables)
+//	PULSE_MIN_POWER_FRACTION   power floor under load, fraction of the default
+//	                           limit (off unless set or in the arch table)
+//	PULSE_DEGRADED_FRACTION    degraded band, fraction of each limit (0.8)
+//	PULSE_TIMEOUT_PREFLIGHT    \
+//	PULSE_TIMEOUT_GEMM_RUN      | per-stage timeouts (30s, 60s, 30s, 30s)
+//	PULSE_TIMEOUT_P2P_LINK      |
+//	PULSE_TIMEOUT_CLOCK_CHECK  /
+//	PULSE_TIMEOUT_EXTERNAL_CHECK  each external check (5m)
+//
+// The latency threshold, CV ceiling and power floor default to the detected
+// architecture's calibration, from the PULSE_ARCH_TABLE file or the built-in
+// table (see ArchTableEnv). The SM clock floor is not env-configurable and starts at 0.5
+// of max.
+func EnvSnapshot() Snapshot {
+	cal, detected := detectArch()
+	threshold, prov := envStragglerThreshold(cal, detected)
+	maxCV, cvSource := envMaxCV(cal, detected)
+	prov.MaxCVSource = cvSource
+	return Snapshot{
+		StragglerThreshold:   threshold,
+		ThresholdProvenance:  prov,
+		MaxCV:                maxCV,
+		BaselineFactor:       envBaselineFactor(),
+		JitterFloor:          envJitterFloor(),
+		MaxHostLoad:          envLimit("PULSE_MAX_HOST_LOAD", 1.0),
+		MaxCPUSteal:          envLimit("PULSE_MAX_CPU_STEAL", 0.1),
+		MinP2PBandwidthGBs:   envFloat64("P2P_MIN_GBS", 5.0),
+		MaxIdleTempC:         envInt("IDLE_TEMP_MAX", 70),
+		MinClockFraction:     0.5,
+		MinSMUtilization:     envLimit("PULSE_MIN_SM_UTIL", 0),
+		MinPowerFraction:     envLimit("PULSE_MIN_POWER_FRACTION", cal.minPowerFraction),
+		DegradedFraction:     envFloat64("PULSE_DEGRADED_FRACTION", 0.8),
+		PreflightTimeout:     envDuration("PULSE_TIMEOUT_PREFLIGHT", 30*time.Second),
+		GEMMRunTimeout:       envDuration("PULSE_TIMEOUT_GEMM_RUN", 60*time.Second),
+		P2PLinkTimeout:       envDuration("PULSE_TIMEOUT_P2P_LINK", 30*time.Second),
+		ClockCheckTimeout:    envDuration("PULSE_TIMEOUT_CLOCK_CHECK", 30*time.Second),
+		ExternalCheckTimeout: envDuration("PULSE_TIMEOUT_EXTERNAL_CHECK", 5*time.Minute),
+	}
+}
 
-// minClockFraction is the post-pulse SM clock floor as a fraction of device
-// maximum. Not env-configurable — changing requires recompile.
-const minClockFraction = 0.5
+// StateDir returns the directory for host-persistent pulse state (see
+// devicecount.go), so the agent can keep its own state (the decision journal)
+// alongside the pulse's. Mount it from a hostPath so it outlives the
+// container. Override with PULSE_STATE_DIR; it is read on each call, so a
+// value exported from an agent flag applies.
+func StateDir() string {
+	if s := os.Getenv("PULSE_STATE_DIR"); s != "" {
+		return s
+	}
+	return "/var/lib/straggler-shield"
+}
+
+// active is the process-wide Config read by every pulse.
+var active = NewConfig(EnvSnapshot())
+
+// Active returns the process-wide Config. Changes take effect at the start of
+// the next pulse; a pulse in flight keeps the values it started with. An
+// isolated runner (subprocess or pod) resolves its own Config from its
+// environment, so runtime changes here do not reach it.
+func Active() *Config { return active }
 
 // ThresholdMS returns the active GEMM latency threshold in milliseconds —
 // either the env-var override or the architecture-calibrated value.
 // Exported for the benchmark harness and structured log context.
 func ThresholdMS() int64 {
-	return stragglerThreshold.Milliseconds()
+	return active.StragglerThreshold().Milliseconds()
+}
+
+// envBaselineFactor reads PULSE_BASELINE_FACTOR; zero turns the node
+// baseline check off.
+func envBaselineFactor() float64 {
+	if s := os.Getenv("PULSE_BASELINE_FACTOR"); s != "" {
+		if v, err := strconv.ParseFloat(s, 64); err == nil && (v == 0 || v > 1) {
+			return v
+		}
+	}
+	return 2
+}
+
+// defaultJitterFloor is the jitter floor for device-timed runs (see
+// runDevicePulse). CUDA event timing leaves tens of microseconds of
+// run-to-run noise on a healthy GPU, not the milliseconds of host scheduling
+// noise the floor once had to absorb, so a floor in milliseconds would hide
+// a fail-slow B200, whose whole run is about 1.5ms.
+const defaultJitterFloor = 250 * time.Microsecond
+
+// envJitterFloor reads PULSE_JITTER_FLOOR. Unlike the other durations it
+// accepts zero, which turns the floor off.
+func envJitterFloor() time.Duration {
+	if s := os.Getenv("PULSE_JITTER_FLOOR"); s != "" {
+		if v, err := time.ParseDuration(s); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return defaultJitterFloor
+}
+
+// envLimit reads an optional non-negative limit; zero turns it off.
+func envLimit(key string, def float64) float64 {
+	if s := os.Getenv(key); s != "" {
+		if v, err := strconv.ParseFloat(s, 64); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return def
 }
 
 func envFloat64(key string, def float64) float64 {
@@ -60,3 +185,12 @@ func envInt(key string, def int) int {
 	}
 	return def
 }
+
+func envDuration(key string, def time.Duration) time.Duration {
+	if s := os.Getenv(key); s != "" {
+		if v, err := time.ParseDuration(s); err == nil && v > 0 {
+			return v
+		}
+	}
+	return def
+}
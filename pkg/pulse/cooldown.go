@@ -0,0 +1,82 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// CooldownRetriesEnv is how many times a pre-flight check that failed
+	// only on idle temperature is run again after a cool-down wait (3). Set
+	// to 0 to fail hot GPUs straight away.
+	CooldownRetriesEnv = "PULSE_COOLDOWN_RETRIES"
+	// CooldownWaitEnv is the wait before each of those checks (2m).
+	CooldownWaitEnv = "PULSE_COOLDOWN_WAIT"
+)
+
+const (
+	defaultCooldownRetries = 3
+	defaultCooldownWait    = 2 * time.Minute
+)
+
+// Cooldown records the pre-flight checks run again while hot GPUs cooled. A
+// node fresh from a heavy training job can sit above the idle ceiling for
+// minutes without anything being wrong with it.
+type Cooldown struct {
+	// FirstError is the temperature failure that started the wait.
+	FirstError string `json:"first_error"`
+	// Rechecks is how many times the pre-flight check ran again.
+	Rechecks int `json:"rechecks"`
+	// WaitedNS is the time spent waiting.
+	WaitedNS int64 `json:"waited_ns"`
+	// Recovered is set when the GPUs cooled below the ceiling.
+	Recovered bool `json:"recovered"`
+}
+
+// CooldownSettings returns the cool-down checks and the wait before each,
+// from CooldownRetriesEnv and CooldownWaitEnv. Invalid values take the
+// defaults.
+func CooldownSettings() (retries int, wait time.Duration) {
+	retries = defaultCooldownRetries
+	if s := os.Getenv(CooldownRetriesEnv); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			retries = v
+		}
+	}
+	return retries, envDuration(CooldownWaitEnv, defaultCooldownWait)
+}
+
+// preflightCooldown is preflight with cool-down retries: while the check
+// fails on idle temperature alone, it waits and runs it again, up to the
+// CooldownSettings. Any other pre-flight failure, including one found only
+// once the GPUs have cooled, stands at once. cooldown is nil when the first
+// check did not fail on temperature or retries are off. A wait cut short by
+// ctx returns ErrInconclusive: the GPUs were neither shown hot nor cool.
+func preflightCooldown(ctx context.Context, th Snapshot, g *gaps) (stats []gpuStats, cooldown *Cooldown, err error) {
+	stats, err = preflight(th, g)
+	retries, wait := CooldownSettings()
+	if !errors.Is(err, ErrIdleTemperature) || retries == 0 {
+		return stats, nil, err
+	}
+	cooldown = &Cooldown{FirstError: err.Error()}
+	for cooldown.Rechecks < retries && errors.Is(err, ErrIdleTemperature) {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return stats, cooldown, fmt.Errorf("%w: cool-down wait cancelled: %w", ErrInconclusive, ctx.Err())
+		case <-timer.C:
+		}
+		cooldown.WaitedNS += int64(wait)
+		cooldown.Rechecks++
+		// Only the last check's gaps are reported.
+		*g = gaps{}
+		stats, err = preflight(th, g)
+	}
+	cooldown.Recovered = !errors.Is(err, ErrIdleTemperature)
+	return stats, cooldown, err
+}
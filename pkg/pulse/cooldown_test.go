@@ -0,0 +1,91 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreflightCooldown(t *testing.T) {
+	cases := []struct {
+		name          string
+		hotChecks     int    // checks that read the GPU above the ceiling
+		ecc           string // uncorrectable ECC count once cool
+		retries       string
+		wantErr       error
+		wantCooldown  bool
+		wantRechecks  int
+		wantRecovered bool
+	}{
+		{"cool GPUs", 0, "0", "", nil, false, 0, false},
+		{"cools on the second recheck", 2, "0", "", nil, true, 2, true},
+		{"never cools", 10, "0", "", ErrIdleTemperature, true, 3, false},
+		{"retries off", 10, "0", "0", ErrIdleTemperature, false, 0, false},
+		{"fault found once cool", 1, "2", "", nil, true, 1, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("PATH", dir)
+			t.Setenv("STATE", dir)
+			t.Setenv("PULSE_STATE_DIR", dir)
+			t.Setenv(CooldownWaitEnv, "1ms")
+			t.Setenv(CooldownRetriesEnv, tc.retries)
+			writeCheck(t, dir, "nvidia-smi", `case "$*" in *remapped*) exit 1 ;; esac
+n=0
+if [ -f "$STATE/n" ]; then read n <"$STATE/n"; fi
+echo $((n+1)) >"$STATE/n"
+if [ "$n" -lt `+strconv.Itoa(tc.hotChecks)+` ]; then temp=85 ecc=0; else temp=40 ecc=`+tc.ecc+`; fi
+echo "1980, 1980, $temp, $ecc, 0, GPU-0, 1320, 00000000:01:00.0, $ecc"`)
+
+			_, cooldown, err := preflightCooldown(context.Background(), validSnapshot(), &gaps{})
+			switch {
+			case tc.wantErr != nil && !errors.Is(err, tc.wantErr):
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			case tc.wantErr == nil && errors.Is(err, ErrIdleTemperature):
+				t.Fatalf("err = %v, want the GPUs cooled", err)
+			case tc.ecc != "0" && !errors.Is(err, ErrECCErrors):
+				t.Fatalf("err = %v, want the ECC errors found once cool", err)
+			}
+			if (cooldown != nil) != tc.wantCooldown {
+				t.Fatalf("cooldown = %+v, want one %v", cooldown, tc.wantCooldown)
+			}
+			if cooldown == nil {
+				return
+			}
+			if cooldown.Rechecks != tc.wantRechecks || cooldown.Recovered != tc.wantRecovered {
+				t.Errorf("cooldown = %+v, want %d rechecks, recovered %v", cooldown, tc.wantRechecks, tc.wantRecovered)
+			}
+			if cooldown.WaitedNS != int64(tc.wantRechecks)*int64(time.Millisecond) {
+				t.Errorf("WaitedNS = %d, want %d waits of 1ms", cooldown.WaitedNS, tc.wantRechecks)
+			}
+			if !strings.Contains(cooldown.FirstError, "85°C exceeds 70°C") {
+				t.Errorf("FirstError = %q, want the first temperature failure", cooldown.FirstError)
+			}
+		})
+	}
+}
+
+func TestPreflightCooldownCancelled(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	t.Setenv("PULSE_STATE_DIR", dir)
+	t.Setenv(CooldownWaitEnv, "1h")
+	writeCheck(t, dir, "nvidia-smi", `case "$*" in *remapped*) exit 1 ;; esac
+echo "1980, 1980, 85, 0, 0, GPU-0, 1320, 00000000:01:00.0, 0"`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, cooldown, err := preflightCooldown(ctx, validSnapshot(), &gaps{})
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Fatalf("cancelled cool-down waited %v", waited)
+	}
+	// Cut short, the hot reading is not a verdict.
+	if !errors.Is(err, ErrInconclusive) || errors.Is(err, ErrIdleTemperature) || cooldown == nil || cooldown.Rechecks != 0 {
+		t.Errorf("err = %v, cooldown = %+v; want inconclusive before any recheck", err, cooldown)
+	}
+}
@@ -0,0 +1,55 @@
+package pulse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// deviceCountFile holds the GPU count seen by the last passing pulse, under
+// StateDir(). It lives on the host (hostPath), not in the container, so it
+// survives agent restarts and node reboots — the reboot is exactly when a GPU
+// falls off the bus.
+const deviceCountFile = "device-count"
+
+// checkDeviceCount fails with ErrDeviceCountDecreased if fewer GPUs are
+// visible now than at the last passing pulse. A missing or unreadable state
+// file (first pulse, no hostPath mount) passes: there is nothing to compare
+// against.
+func checkDeviceCount(dir string, count int) error {
+	data, err := os.ReadFile(filepath.Join(dir, deviceCountFile))
+	if err != nil {
+		return nil
+	}
+	prev, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || count >= prev {
+		return nil
+	}
+	return &PulseFailure{
+		Cause:          fmt.Errorf("%w: %d visible, %d at last passing pulse", ErrDeviceCountDecreased, count, prev),
+		MeasuredValue:  float64(count),
+		ThresholdValue: float64(prev),
+		Unit:           "gpus",
+	}
+}
+
+// recordDeviceCount persists count after a passing pulse. Best effort: a
+// failed write only disables the check until a later write succeeds. A
+// shrunken count is never recorded, since that pulse failed; an operator who
+// removes a GPU on purpose deletes the file. A grown count (repaired node) is
+// recorded by the next passing pulse.
+func recordDeviceCount(dir string, count int) {
+	if dir == "" {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	tmp := filepath.Join(dir, deviceCountFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(count)+"\n"), 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, filepath.Join(dir, deviceCountFile))
+}
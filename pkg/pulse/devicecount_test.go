@@ -0,0 +1,49 @@
+package pulse
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeviceCountCheck(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		recorded int // 0 = no state file
+		count    int
+		wantErr  bool
+	}{
+		{name: "first pulse — nothing recorded", count: 8},
+		{name: "unchanged", recorded: 8, count: 8},
+		{name: "repaired node — count grew", recorded: 7, count: 8},
+		{name: "GPU lost after reboot", recorded: 8, count: 7, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if tc.recorded > 0 {
+				recordDeviceCount(dir, tc.recorded)
+			}
+
+			err := checkDeviceCount(dir, tc.count)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr {
+				return
+			}
+			if !errors.Is(err, ErrDeviceCountDecreased) {
+				t.Errorf("err = %v, want ErrDeviceCountDecreased", err)
+			}
+			var pf *PulseFailure
+			if !errors.As(err, &pf) || pf.MeasuredValue != float64(tc.count) || pf.ThresholdValue != float64(tc.recorded) {
+				t.Errorf("failure detail = %+v", pf)
+			}
+		})
+	}
+}
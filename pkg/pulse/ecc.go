@@ -0,0 +1,174 @@
+package pulse
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// eccFile holds each device's corrected ECC count at the previous pulse,
+// under StateDir(). Corrected errors never fail a pulse, but a count that
+// keeps growing is the usual prelude to uncorrectable ones and row remaps.
+const eccFile = "ecc-corrected.json"
+
+// eccHistoryFile holds each GPU's aggregate uncorrectable ECC count at its
+// last clean pulse, and the boot it was taken on, under StateDir(). It lets
+// the pre-flight check tell new uncorrectable errors from old ones the
+// InfoROM has carried across reboots. See qualifyECC.
+const eccHistoryFile = "ecc-uncorrected.json"
+
+// bootIDPath is the kernel's random ID for the current boot.
+const bootIDPath = "/proc/sys/kernel/random/boot_id"
+
+// eccRecord is one GPU's entry in eccHistoryFile.
+type eccRecord struct {
+	Aggregate int    `json:"aggregate"`
+	BootID    string `json:"boot_id,omitempty"`
+}
+
+// bootID returns the current boot's ID, or "" if it cannot be read.
+func bootID() string {
+	data, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// eccKey keys device i in eccHistoryFile by its UUID, so a swapped GPU does
+// not inherit its slot's history.
+func eccKey(i int, s gpuStats) string {
+	if s.UUID != "" {
+		return s.UUID
+	}
+	return "gpu" + strconv.Itoa(i)
+}
+
+func readECCHistory(dir string) map[string]eccRecord {
+	history := make(map[string]eccRecord)
+	if dir != "" {
+		if data, err := os.ReadFile(filepath.Join(dir, eccHistoryFile)); err == nil {
+			_ = json.Unmarshal(data, &history)
+		}
+	}
+	return history
+}
+
+// qualifyECC sets each device's FreshECC: its uncorrectable errors since the
+// last boot or clean pulse, read against the history in dir.
+//
+//   - The volatile count covers the current boot. On the boot of the device's
+//     last clean pulse, growth of the aggregate count since it is counted
+//     too, in case the driver under-reports the volatile one.
+//   - Without a volatile count, the aggregate growth since the last clean
+//     pulse is used, whatever the boot.
+//   - With neither a volatile count nor a history, the whole aggregate count
+//     is: nothing tells old errors from new ones.
+//
+// An aggregate count below the recorded one means it was cleared; all of it
+// is new.
+func qualifyECC(dir, boot string, stats []gpuStats) {
+	history := readECCHistory(dir)
+	for i := range stats {
+		s := &stats[i]
+		rec, ok := history[eccKey(i, *s)]
+		growth := s.ECCErrors
+		if ok && s.ECCErrors >= rec.Aggregate {
+			growth = s.ECCErrors - rec.Aggregate
+		}
+		switch {
+		case s.VolatileECC >= 0:
+			s.FreshECC = s.VolatileECC
+			if ok && boot != "" && rec.BootID == boot {
+				s.FreshECC = max(s.FreshECC, growth)
+			}
+		case ok:
+			s.FreshECC = growth
+		default:
+			s.FreshECC = s.ECCErrors
+		}
+	}
+}
+
+// recordECCHistory records the pre-flight aggregate count of each device with
+// no fresh uncorrectable errors (see qualifyECC) as its last clean pulse on
+// boot. It is called once a pulse has passed, near-threshold included; a
+// failed or inconclusive pulse leaves the history as it was, so errors it saw
+// are still counted by the next pulse on the same boot, and by every later
+// one if the device does not report a volatile count. Best effort, like
+// eccTrend.
+func recordECCHistory(dir, boot string, stats []gpuStats) {
+	if dir == "" || len(stats) == 0 {
+		return
+	}
+	history := readECCHistory(dir)
+	for i, s := range stats {
+		if s.FreshECC == 0 {
+			history[eccKey(i, s)] = eccRecord{Aggregate: s.ECCErrors, BootID: boot}
+		}
+	}
+	data, err := json.Marshal(history)
+	if err != nil || os.MkdirAll(dir, 0o755) != nil {
+		return
+	}
+	tmp := filepath.Join(dir, eccHistoryFile+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, filepath.Join(dir, eccHistoryFile))
+}
+
+// eccTrend turns the pre-flight stats into report readings, each with the
+// growth of its corrected ECC count since the count recorded in dir, and
+// records the new counts. The uncorrectable history is only recorded once
+// the pulse passes (see recordECCHistory). A count below the recorded one means the driver
+// reloaded and reset the volatile counter: all of it is new. Devices without
+// a recorded count (the first pulse) have no trend yet. Best effort, like
+// recordDeviceCount.
+func eccTrend(dir string, stats []gpuStats) []GPUReading {
+	if len(stats) == 0 {
+		return nil
+	}
+	prev := make(map[int]int)
+	if dir != "" {
+		if data, err := os.ReadFile(filepath.Join(dir, eccFile)); err == nil {
+			_ = json.Unmarshal(data, &prev)
+		}
+	}
+	readings := make([]GPUReading, len(stats))
+	counts := make(map[int]int, len(stats))
+	for i, s := range stats {
+		counts[i] = s.CorrectedECC
+		r := GPUReading{
+			Device:                 i,
+			TempC:                  s.TempC,
+			ECCUncorrected:         s.ECCErrors,
+			ECCUncorrectedVolatile: max(s.VolatileECC, 0),
+			ECCUncorrectedFresh:    s.FreshECC,
+			ECCCorrected:           s.CorrectedECC,
+			GPUIdentity:            s.GPUIdentity,
+		}
+		if p, ok := prev[i]; ok {
+			r.ECCCorrectedDelta = s.CorrectedECC - p
+			if s.CorrectedECC < p {
+				r.ECCCorrectedDelta = s.CorrectedECC
+			}
+		}
+		readings[i] = r
+	}
+	if dir == "" {
+		return readings
+	}
+	data, err := json.Marshal(counts)
+	if err != nil || os.MkdirAll(dir, 0o755) != nil {
+		return readings
+	}
+	tmp := filepath.Join(dir, eccFile+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return readings
+	}
+	_ = os.Rename(tmp, filepath.Join(dir, eccFile))
+	return readings
+}
@@ -0,0 +1,85 @@
+package pulse
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestECCTrend(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	pulses := []struct {
+		counts []int
+		want   []int
+	}{
+		{counts: []int{3, 0}, want: []int{0, 0}},  // first pulse: no trend yet
+		{counts: []int{10, 0}, want: []int{7, 0}}, // growth since the last pulse
+		{counts: []int{10, 4}, want: []int{0, 4}}, // unchanged, and new errors on GPU 1
+		{counts: []int{2, 4}, want: []int{2, 0}},  // driver reload reset the counter
+	}
+	for i, p := range pulses {
+		stats := make([]gpuStats, len(p.counts))
+		for dev, n := range p.counts {
+			stats[dev] = gpuStats{TempC: 40, CorrectedECC: n}
+		}
+		readings := eccTrend(dir, stats)
+		for dev, r := range readings {
+			if r.ECCCorrected != p.counts[dev] || r.ECCCorrectedDelta != p.want[dev] {
+				t.Errorf("pulse %d GPU %d: corrected %d delta %d, want %d delta %d", i, dev, r.ECCCorrected, r.ECCCorrectedDelta, p.counts[dev], p.want[dev])
+			}
+		}
+	}
+	if eccTrend(dir, nil) != nil {
+		t.Error("no pre-flight stats should give no readings")
+	}
+	if _, err := os.Stat(filepath.Join(dir, eccHistoryFile)); !os.IsNotExist(err) {
+		t.Errorf("eccTrend wrote %s before the pulse passed (stat: %v)", eccHistoryFile, err)
+	}
+}
+
+func TestQualifyECC(t *testing.T) {
+	t.Parallel()
+
+	gpu := func(uuid string, aggregate, volatile int) gpuStats {
+		return gpuStats{ECCErrors: aggregate, VolatileECC: volatile, GPUIdentity: GPUIdentity{UUID: uuid}}
+	}
+	pulses := []struct {
+		boot  string
+		stats []gpuStats
+		want  []int
+	}{
+		// First pulse: old aggregate errors are ignored when the volatile
+		// count is reported, and all counted when it is not.
+		{"b1", []gpuStats{gpu("GPU-a", 5, 0), gpu("GPU-b", 2, -1)}, []int{0, 2}},
+		// GPU-b is replaced; the new GPU starts with its own history, and
+		// the pulse passes.
+		{"b1", []gpuStats{gpu("GPU-a", 5, 0), gpu("GPU-c", 0, -1)}, []int{0, 0}},
+		// GPU-a took a new error this boot; the volatile count sees it.
+		{"b1", []gpuStats{gpu("GPU-a", 6, 1), gpu("GPU-c", 0, -1)}, []int{1, 0}},
+		// After a reboot the volatile count is clear: the old error is not
+		// counted, and the pulse passes.
+		{"b2", []gpuStats{gpu("GPU-a", 6, 0), gpu("GPU-c", 0, -1)}, []int{0, 0}},
+		// GPU-a's aggregate grows on the boot of its last clean pulse while
+		// the driver under-reports the volatile count.
+		{"b2", []gpuStats{gpu("GPU-a", 8, 0), gpu("GPU-c", 0, -1)}, []int{2, 0}},
+		// That failed pulse recorded nothing. Without a volatile count,
+		// GPU-c's growth since its clean pulse counts on any boot.
+		{"b3", []gpuStats{gpu("GPU-a", 8, 0), gpu("GPU-c", 3, -1)}, []int{0, 3}},
+	}
+	dir := t.TempDir()
+	for i, p := range pulses {
+		qualifyECC(dir, p.boot, p.stats)
+		for dev, s := range p.stats {
+			if s.FreshECC != p.want[dev] {
+				t.Errorf("pulse %d GPU %d: fresh %d, want %d", i, dev, s.FreshECC, p.want[dev])
+			}
+		}
+		// Only a passing pulse, one without fresh errors, is recorded.
+		if !slices.ContainsFunc(p.want, func(n int) bool { return n > 0 }) {
+			recordECCHistory(dir, p.boot, p.stats)
+		}
+	}
+}
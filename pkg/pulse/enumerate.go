@@ -0,0 +1,98 @@
+package pulse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// procGPUsDir has one entry per GPU (named by PCI bus ID) that the kernel
+// driver has bound, independent of CUDA and NVML.
+const procGPUsDir = "/proc/driver/nvidia/gpus"
+
+// countSource is one independent way of enumerating GPUs.
+type countSource struct {
+	name  string
+	count func(ctx context.Context) (int, error)
+}
+
+// enumerationSources returns the secondary sources checked against CUDA's
+// count: NVML (directly on nvml builds, else through nvidia-smi -L, which
+// enumerates via libnvidia-ml) and
+// the kernel driver's procfs listing.
+func enumerationSources() []countSource {
+	return []countSource{
+		{name: "nvml", count: nvmlDeviceCount},
+		{name: "procfs", count: procDeviceCount},
+	}
+}
+
+// crossCheckDeviceCount compares CUDA's device count against each source.
+// Disagreement means the driver, container runtime or visibility env
+// (NVIDIA_VISIBLE_DEVICES, CUDA_VISIBLE_DEVICES) is misconfigured — a pulse
+// over the CUDA-visible subset would pass a node it never fully tested. A
+// source that is unavailable (no nvidia-smi, no procfs mount) is skipped and
+// recorded in g.
+func crossCheckDeviceCount(ctx context.Context, cudaCount int, sources []countSource, g *gaps) error {
+	var seen []string
+	mismatch, other := false, 0
+	for _, src := range sources {
+		n, err := src.count(ctx)
+		if err != nil {
+			g.skip("enumeration", src.name+"_unavailable")
+			continue
+		}
+		seen = append(seen, fmt.Sprintf("%s=%d", src.name, n))
+		if n != cudaCount && !mismatch {
+			mismatch, other = true, n
+		}
+	}
+	if !mismatch {
+		return nil
+	}
+	return &PulseFailure{
+		Cause: fmt.Errorf("%w: cuda=%d, %s", ErrEnumerationMismatch,
+			cudaCount, strings.Join(seen, ", ")),
+		MeasuredValue:  float64(cudaCount),
+		ThresholdValue: float64(other),
+		Unit:           "gpus",
+	}
+}
+
+// nvmlDeviceCount asks libnvidia-ml directly on nvml builds, falling back to
+// counting the "GPU n:" lines of nvidia-smi -L.
+func nvmlDeviceCount(ctx context.Context) (int, error) {
+	if n, err := nvmlCount(ctx); err == nil {
+		return n, nil
+	}
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "-L").Output()
+	if err != nil {
+		return 0, fmt.Errorf("nvidia-smi -L: %w", err)
+	}
+	n := 0
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "GPU ") {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func procDeviceCount(context.Context) (int, error) {
+	entries, err := os.ReadDir(procGPUsDir)
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// checkEnumeration runs crossCheckDeviceCount against the real sources,
+// bounded by the preflight timeout.
+func checkEnumeration(cudaCount int, timeout time.Duration, g *gaps) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return crossCheckDeviceCount(ctx, cudaCount, enumerationSources(), g)
+}
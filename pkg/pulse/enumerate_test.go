@@ -0,0 +1,87 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func fixedCount(n int, err error) func(context.Context) (int, error) {
+	return func(context.Context) (int, error) { return n, err }
+}
+
+func TestCrossCheckDeviceCount(t *testing.T) {
+	t.Parallel()
+
+	unavailable := errors.New("not available")
+	cases := []struct {
+		name    string
+		cuda    int
+		sources []countSource
+		wantErr bool
+		skipped []string
+	}{
+		{
+			name:    "all agree",
+			cuda:    8,
+			sources: []countSource{{"nvml", fixedCount(8, nil)}, {"procfs", fixedCount(8, nil)}},
+		},
+		{
+			name:    "sources unavailable — nothing to compare",
+			cuda:    8,
+			sources: []countSource{{"nvml", fixedCount(0, unavailable)}, {"procfs", fixedCount(0, unavailable)}},
+			skipped: []string{"nvml_unavailable", "procfs_unavailable"},
+		},
+		{
+			name:    "procfs not mounted",
+			cuda:    8,
+			sources: []countSource{{"nvml", fixedCount(8, nil)}, {"procfs", fixedCount(0, unavailable)}},
+			skipped: []string{"procfs_unavailable"},
+		},
+		{
+			// CUDA_VISIBLE_DEVICES truncated to four devices.
+			name:    "cuda sees a subset",
+			cuda:    4,
+			sources: []countSource{{"nvml", fixedCount(8, nil)}, {"procfs", fixedCount(8, nil)}},
+			wantErr: true,
+		},
+		{
+			// NVIDIA_VISIBLE_DEVICES hides GPUs from NVML, but the driver
+			// has all eight bound.
+			name:    "procfs disagrees",
+			cuda:    7,
+			sources: []countSource{{"nvml", fixedCount(7, nil)}, {"procfs", fixedCount(8, nil)}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var unchecked gaps
+			err := crossCheckDeviceCount(context.Background(), tc.cuda, tc.sources, &unchecked)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			var skipped []string
+			for _, s := range unchecked.skipped {
+				if s.Check != "enumeration" {
+					t.Errorf("skipped check %q, want enumeration", s.Check)
+				}
+				skipped = append(skipped, s.Why)
+			}
+			if fmt.Sprint(skipped) != fmt.Sprint(tc.skipped) {
+				t.Errorf("skipped %v, want %v", skipped, tc.skipped)
+			}
+			if err := unchecked.err(); err != nil {
+				t.Errorf("a skipped source made the pulse inconclusive: %v", err)
+			}
+			if tc.wantErr && !errors.Is(err, ErrEnumerationMismatch) {
+				t.Errorf("err = %v, want ErrEnumerationMismatch", err)
+			}
+		})
+	}
+}
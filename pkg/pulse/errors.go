@@ -1,11 +1,15 @@
 package pulse
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	// ErrStragglerDetected is returned when mean GEMM latency across all runs
 	// on any device exceeds the threshold, or when the post-pulse clock check
-	// confirms a power-derated state under load.
+	// confirms a power-derated state under load (then with ErrClockDerated).
 	ErrStragglerDetected = errors.New("straggler detected: GPU pulse latency exceeded threshold")
 
 	// ErrHighVariance is returned when mean latency is acceptable but the
@@ -19,14 +23,123 @@ var (
 	// the link as unavailable. An NVLink failure that allows GEMM to pass but
 	// causes AllReduce to stall is the canonical SUNK straggler scenario.
 	ErrInterconnectDegraded = errors.New("straggler detected: NVLink/P2P bandwidth below threshold")
+
+	// ErrStageTimeout is returned when a single pipeline stage (preflight,
+	// one GEMM run, one P2P link, or the clock check) exceeds its configured
+	// timeout. It is a hard failure rather than a straggler verdict: the node
+	// is quarantined because it could not be validated, not because it was slow.
+	ErrStageTimeout = errors.New("pulse stage timed out")
+
+	// ErrPulseCrashed is returned by Runner when the process running the pulse
+	// died (segfault in the CUDA library, abort, OOM kill) or exited without a
+	// usable result. Treated as a hard failure.
+	ErrPulseCrashed = errors.New("pulse child process crashed")
+
+	// ErrNearThreshold is returned when every check passed but at least one
+	// measurement sits inside the degraded band just short of its threshold
+	// (see Snapshot.DegradedFraction). Not a straggler verdict: the default
+	// policy puts such nodes in the degraded tier rather than quarantining them.
+	ErrNearThreshold = errors.New("within degraded margin of threshold")
+
+	// ErrDeviceCountDecreased is returned when fewer GPUs are visible than at
+	// the last passing pulse (e.g. 8→7 after a reboot). The surviving devices
+	// may all pass, so without this check the lost capacity goes unnoticed.
+	ErrDeviceCountDecreased = errors.New("GPU count decreased since last passing pulse")
+
+	// ErrEnumerationMismatch is returned when CUDA, NVML and the kernel
+	// driver disagree on the number of GPUs. That points at the driver or
+	// container runtime (e.g. a truncated NVIDIA_VISIBLE_DEVICES), not the
+	// hardware, and a pulse over the CUDA-visible subset would be partial.
+	ErrEnumerationMismatch = errors.New("GPU enumeration mismatch between CUDA, NVML and driver")
+
+	// ErrGPUsNotVisible is returned when the process running the pulse sees
+	// fewer GPUs than the node advertises — a DaemonSet or runtime
+	// misconfiguration that would otherwise validate only a subset.
+	ErrGPUsNotVisible = errors.New("container sees fewer GPUs than the node advertises")
+
+	// ErrXIDEvent is returned by the pre-flight check when the kernel log
+	// shows a hardware XID (see DefaultXIDCodes) since boot. ECC counters
+	// miss many of these, such as a GPU that fell off the bus. The XID code
+	// is the PulseFailure's MeasuredValue.
+	ErrXIDEvent = errors.New("NVIDIA XID error in kernel log")
+
+	// ErrRowRemap is returned by the pre-flight check when a GPU reports a
+	// failed or pending HBM row remap. Either leaves a faulty memory row in
+	// service and predicts uncorrectable errors under load. The count of rows
+	// remapped for uncorrectable errors is the PulseFailure's MeasuredValue.
+	ErrRowRemap = errors.New("HBM row remap failed or pending")
+
+	// ErrIdleTemperature is returned by the pre-flight check when a GPU is
+	// above Snapshot.MaxIdleTempC before the GEMM runs, so its timings would
+	// be skewed by thermal throttling. A node fresh from heavy load often
+	// only needs minutes to cool; see Cooldown.
+	ErrIdleTemperature = errors.New("GPU idle temperature above pre-flight ceiling")
+
+	// ErrThermalRecovery is ErrIdleTemperature under the name of the failure
+	// it stands for: thermal recovery incomplete. Either matches.
+	ErrThermalRecovery = ErrIdleTemperature
+
+	// ErrECCErrors is returned by the pre-flight check when a GPU has
+	// uncorrectable ECC errors since the last boot or its last clean pulse
+	// (see qualifyECC): its HBM faulted, most likely during the failure that
+	// brought the node to the pulse. The count of fresh errors is the
+	// PulseFailure's MeasuredValue.
+	ErrECCErrors = errors.New("uncorrectable ECC errors")
+
+	// ErrClockDerated is returned with ErrStragglerDetected when the
+	// post-pulse clock check finds a GPU's SM clock below
+	// Snapshot.MinClockFraction of its maximum under load, or the board in
+	// the hardware thermal slowdown. It tells a GPU stuck in a derated
+	// state apart from one whose GEMM was slow.
+	ErrClockDerated = errors.New("GPU clocks derated under load")
+
+	// ErrPCIeDegraded is returned when a GPU's PCIe link trained narrower
+	// than the GPU and its slot support, as links do after an error. GEMM
+	// runs from device memory and passes; data loading and host transfers
+	// crawl. The lanes found and expected are the PulseFailure's
+	// MeasuredValue and ThresholdValue.
+	ErrPCIeDegraded = errors.New("PCIe link degraded")
+
+	// ErrLowPower is returned when a GPU's board power stayed below
+	// Snapshot.MinPowerFraction of its default limit under the GEMM load,
+	// the mark of a device held at a low power cap. It runs a little slow on
+	// every job without crossing the latency ceiling. The peak draw and the
+	// floor, in watts, are the PulseFailure's MeasuredValue and
+	// ThresholdValue.
+	ErrLowPower = errors.New("GPU power draw below expected under load")
+
+	// ErrInconclusive is returned when no check failed but the pulse could
+	// not show the node healthy: the GPU stats could not be read before or
+	// after the GEMM, a GPU was already busy with another workload, or the
+	// GEMM never drove a device's SM utilization up to
+	// Snapshot.MinSMUtilization. A pass would mask the gap; the node is
+	// neither healthy nor a straggler. For the utilization floor the peak
+	// utilization is the PulseFailure's MeasuredValue. The memory guard (see
+	// MemoryReserveEnv) fails the same way, before any GPU work. See
+	// IsInconclusive.
+	ErrInconclusive = errors.New("pulse inconclusive")
+
+	// ErrExternalCheck is returned when an operator-supplied check (see
+	// ExternalChecksEnv) reports a failure. The check's own measured and
+	// threshold values, if any, are the PulseFailure's.
+	ErrExternalCheck = errors.New("external check failed")
+
+	// ErrNoCUDA is returned by every pulse in a binary built without the
+	// cuda tag. Nothing was measured, so it says nothing about the node.
+	ErrNoCUDA = errors.New("built without cuda support")
 )
 
 // IsStragglerErr reports whether err indicates the node should be quarantined.
-// Covers all three failure modes so callers use a single predicate.
+// Covers the three straggler verdicts and the hardware faults found around
+// the pulse (ECC errors, incomplete thermal recovery, derated clocks) so
+// callers use a single predicate.
 func IsStragglerErr(err error) bool {
 	return errors.Is(err, ErrStragglerDetected) ||
 		errors.Is(err, ErrHighVariance) ||
-		errors.Is(err, ErrInterconnectDegraded)
+		errors.Is(err, ErrInterconnectDegraded) ||
+		errors.Is(err, ErrECCErrors) ||
+		errors.Is(err, ErrThermalRecovery) ||
+		errors.Is(err, ErrClockDerated)
 }
 
 // PulseFailure wraps a sentinel error with the measured value and threshold
@@ -35,10 +148,41 @@ func IsStragglerErr(err error) bool {
 // existing predicate checks (IsStragglerErr, errors.Is) continue to work.
 type PulseFailure struct {
 	Cause          error
-	MeasuredValue  float64 // CV ratio, bandwidth GB/s, or latency ms
+	MeasuredValue  float64 // CV ratio, bandwidth GB/s, latency ms, GPU count, XID code, or watts
 	ThresholdValue float64
-	Unit           string // "ms", "cv", "gbs"
+	Unit           string // "ms", "cv", "gbs", "gpus", "lanes", "xid", "rows", "ecc", "util", "w", "mib", or an external check's
+	// GPU identifies the physical device a per-device failure was measured
+	// on, when known; nil for node-wide and link failures.
+	GPU *GPUIdentity
+	// Arch names the architecture calibration ThresholdValue came from when
+	// it is not the node's: on a node mixing GPU models each device is
+	// judged against its own (see deviceSnapshot). Empty otherwise.
+	Arch string
+	// ThrottleReasons names the clock throttle reasons seen active on the
+	// failing GPU while its GEMM ran, e.g. "sw_power_cap", for a clock
+	// failure. Nil otherwise or when none were.
+	ThrottleReasons []string
+}
+
+// stageTimeout builds the PulseFailure for a stage that overran its limit.
+// stage is one of "preflight", "gemm_run", "p2p_link", "clock_check" or
+// "external_check <name>".
+func stageTimeout(stage string, limit time.Duration) error {
+	return &PulseFailure{
+		Cause:          fmt.Errorf("%s: %w after %v", stage, ErrStageTimeout, limit),
+		MeasuredValue:  float64(limit.Milliseconds()),
+		ThresholdValue: float64(limit.Milliseconds()),
+		Unit:           "ms",
+	}
 }
 
 func (f *PulseFailure) Error() string { return f.Cause.Error() }
+
+// thresholdFinding reports whether err is a measurement that crossed a
+// threshold, after which the pulse can go on measuring other components. A
+// stage timeout is not one: the device may be hung.
+func thresholdFinding(err error) bool {
+	var detail *PulseFailure
+	return errors.As(err, &detail) && !errors.Is(err, ErrStageTimeout)
+}
 func (f *PulseFailure) Unwrap() error { return f.Cause }
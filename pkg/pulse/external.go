@@ -0,0 +1,162 @@
+package pulse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExternalChecksEnv lists operator-supplied check executables, comma
+// separated, run after the GEMM and P2P stages of every pulse (see
+// runExternalChecks). An entry ending in ".wasm" is a WebAssembly module,
+// run in-process in a sandbox instead (see wasm.go). It is read on each
+// pulse, like StateDir.
+const ExternalChecksEnv = "PULSE_EXTERNAL_CHECKS"
+
+// ExternalChecks returns the check executables listed in ExternalChecksEnv.
+func ExternalChecks() []string {
+	var paths []string
+	for _, p := range strings.Split(os.Getenv(ExternalChecksEnv), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// CheckTimeoutEnv hands an external check its time budget in milliseconds,
+// so it can size its own diagnostics to fit. The check is killed when the
+// budget runs out.
+const CheckTimeoutEnv = "PULSE_CHECK_TIMEOUT_MS"
+
+// CheckOutput is the contract for an external check. The check writes
+// exactly one CheckOutput as a JSON object on stdout and exits 0; the
+// verdict is in Passed, not the exit status. A failed check becomes an
+// ErrExternalCheck PulseFailure carrying the measured and threshold values,
+// Unit ("check" when empty) and, if Device is set, the GPU's identity.
+// Anything else on stdout, or none at all, leaves the check unperformed and
+// the pulse inconclusive.
+type CheckOutput struct {
+	Passed         bool    `json:"passed"`
+	Message        string  `json:"message,omitempty"`
+	MeasuredValue  float64 `json:"measured_value,omitempty"`
+	ThresholdValue float64 `json:"threshold_value,omitempty"`
+	Unit           string  `json:"unit,omitempty"`
+	// Device is the index of the GPU the check found at fault, if any.
+	Device *int `json:"device,omitempty"`
+}
+
+// ExternalResult is one external check's outcome in the report. Name is the
+// executable's base name. Error is set when the check failed or could not
+// produce a result.
+type ExternalResult struct {
+	Name       string       `json:"name"`
+	DurationMS int64        `json:"duration_ms"`
+	Output     *CheckOutput `json:"output,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// runExternalChecks runs each check in paths in turn, bounded by timeout,
+// and records it in report. A failed check is a threshold finding: the
+// remaining checks still run, and the first failure is returned. A check
+// that cannot run or writes no valid result is recorded in g. A check that
+// overruns ends the stage with ErrStageTimeout.
+func runExternalChecks(report *PulseReport, paths []string, timeout time.Duration, g *gaps) error {
+	var failErr error
+	for _, path := range paths {
+		name := filepath.Base(path)
+		start := time.Now()
+		out, err := runExternalCheck(report, path, timeout)
+		res := ExternalResult{Name: name, DurationMS: time.Since(start).Milliseconds(), Output: out}
+		switch {
+		case errors.Is(err, ErrStageTimeout):
+			res.Error = err.Error()
+			report.External = append(report.External, res)
+			return err
+		case err != nil:
+			res.Error = err.Error()
+			g.add("external check %s gave no result (%v)", name, err)
+		case !out.Passed:
+			failure := checkFailure(name, out, report)
+			res.Error = failure.Error()
+			if failErr == nil {
+				failErr = failure
+			}
+		}
+		report.External = append(report.External, res)
+	}
+	return failErr
+}
+
+// runExternalCheck runs one check and decodes its CheckOutput.
+func runExternalCheck(report *PulseReport, path string, timeout time.Duration) (*CheckOutput, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	var runErr error
+	if isWASMCheck(path) {
+		var verdict []byte
+		if verdict, runErr = runWASMCheck(ctx, path, report); runErr == nil {
+			stdout.Write(verdict)
+		}
+	} else {
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Env = append(os.Environ(),
+			PulseIDEnv+"="+report.PulseID,
+			CheckTimeoutEnv+"="+strconv.FormatInt(timeout.Milliseconds(), 10),
+		)
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		// A killed script can leave children holding stdout open.
+		cmd.WaitDelay = time.Second
+		runErr = cmd.Run()
+	}
+	if ctx.Err() != nil {
+		return nil, stageTimeout("external_check "+filepath.Base(path), timeout)
+	}
+
+	var out CheckOutput
+	if err := json.NewDecoder(&stdout).Decode(&out); err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("decode result: %w", err)
+	}
+	return &out, nil
+}
+
+// checkFailure is the PulseFailure for a failed external check.
+func checkFailure(name string, out *CheckOutput, report *PulseReport) *PulseFailure {
+	msg := out.Message
+	if msg == "" {
+		msg = "no message"
+	}
+	unit := out.Unit
+	if unit == "" {
+		unit = "check"
+	}
+	var gpu *GPUIdentity
+	if out.Device != nil {
+		gpu = report.gpuIdentity(*out.Device)
+		msg = describeGPU(*out.Device, gpu) + ": " + msg
+	}
+	return &PulseFailure{
+		Cause:          fmt.Errorf("external check %s: %w: %s", name, ErrExternalCheck, msg),
+		MeasuredValue:  out.MeasuredValue,
+		ThresholdValue: out.ThresholdValue,
+		Unit:           unit,
+		GPU:            gpu,
+	}
+}
+
+// isWASMCheck reports whether the check at path is a WebAssembly module.
+func isWASMCheck(path string) bool { return strings.HasSuffix(path, ".wasm") }
@@ -0,0 +1,139 @@
+package pulse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCheck writes an executable shell script named name into dir.
+func writeCheck(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExternalChecks(t *testing.T) {
+	t.Setenv(ExternalChecksEnv, " /opt/a, ,/opt/b ")
+	if got := ExternalChecks(); len(got) != 2 || got[0] != "/opt/a" || got[1] != "/opt/b" {
+		t.Errorf("ExternalChecks = %q, want [/opt/a /opt/b]", got)
+	}
+	t.Setenv(ExternalChecksEnv, "")
+	if got := ExternalChecks(); got != nil {
+		t.Errorf("ExternalChecks unset = %q, want none", got)
+	}
+}
+
+func TestRunExternalChecks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	pass := writeCheck(t, dir, "pass", `echo "{\"passed\": true, \"message\": \"pulse $PULSE_ID budget $PULSE_CHECK_TIMEOUT_MS\"}"`)
+	fail := writeCheck(t, dir, "fabric", `echo '{"passed": false, "message": "fabric test failed", "measured_value": 180, "threshold_value": 200, "unit": "gbs", "device": 1}'`)
+	garbage := writeCheck(t, dir, "garbage", `echo "not json"`)
+	crash := writeCheck(t, dir, "crash", `exit 3`)
+
+	report := &PulseReport{
+		PulseID: "p-1",
+		GPUs:    []GPUReading{{Device: 1, GPUIdentity: GPUIdentity{UUID: "GPU-5d5ba0d6"}}},
+	}
+	var unchecked gaps
+	err := runExternalChecks(report, []string{pass, fail, garbage, crash}, time.Minute, &unchecked)
+
+	var detail *PulseFailure
+	if !errors.Is(err, ErrExternalCheck) || !errors.As(err, &detail) || !thresholdFinding(err) {
+		t.Fatalf("err = %v, want an ErrExternalCheck threshold finding", err)
+	}
+	if detail.MeasuredValue != 180 || detail.ThresholdValue != 200 || detail.Unit != "gbs" {
+		t.Errorf("failure = %+v, want 180 of 200 gbs", detail)
+	}
+	if detail.GPU == nil || detail.GPU.UUID != "GPU-5d5ba0d6" || !strings.Contains(err.Error(), "GPU 1 (GPU-5d5ba0d6): fabric test failed") {
+		t.Errorf("failure %v does not name GPU 1's identity", err)
+	}
+
+	if len(report.External) != 4 {
+		t.Fatalf("report.External = %+v, want 4 results", report.External)
+	}
+	if got := report.External[0]; got.Name != "pass" || got.Error != "" || got.Output == nil || got.Output.Message != "pulse p-1 budget 60000" {
+		t.Errorf("passing check = %+v", got)
+	}
+	for _, res := range report.External[1:] {
+		if res.Error == "" {
+			t.Errorf("check %s recorded no error", res.Name)
+		}
+	}
+
+	// The checks that gave no result leave the pulse inconclusive.
+	gapErr := unchecked.err()
+	if !IsInconclusive(gapErr) || !strings.Contains(gapErr.Error(), "external check garbage") || !strings.Contains(gapErr.Error(), "external check crash") {
+		t.Errorf("gaps = %v, want both resultless checks listed", gapErr)
+	}
+
+	var components []string
+	for _, f := range report.Failures() {
+		components = append(components, f.Component)
+	}
+	if got := strings.Join(components, ","); got != "check:fabric,check:garbage,check:crash" {
+		t.Errorf("Failures components = %s", got)
+	}
+}
+
+func TestRunExternalChecksUnitless(t *testing.T) {
+	t.Parallel()
+
+	fail := writeCheck(t, t.TempDir(), "diag", `echo '{"passed": false}'`)
+	err := runExternalChecks(&PulseReport{}, []string{fail}, time.Minute, &gaps{})
+	var detail *PulseFailure
+	if !errors.As(err, &detail) || detail.Unit != "check" || detail.GPU != nil {
+		t.Fatalf("err = %#v, want a unitless failure with no GPU", err)
+	}
+	if got, want := err.Error(), "external check diag: external check failed: no message"; got != want {
+		t.Errorf("err = %q, want %q", got, want)
+	}
+}
+
+func TestRunWASMCheckWithoutSupport(t *testing.T) {
+	t.Parallel()
+
+	if WASMSupported {
+		t.Skip("built with the wazero tag")
+	}
+	path := filepath.Join(t.TempDir(), "policy.wasm")
+	if err := os.WriteFile(path, []byte("\x00asm\x01\x00\x00\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var unchecked gaps
+	if err := runExternalChecks(&PulseReport{}, []string{path}, time.Minute, &unchecked); err != nil {
+		t.Fatalf("err = %v, want the check recorded as a gap", err)
+	}
+	if err := unchecked.err(); !IsInconclusive(err) || !strings.Contains(err.Error(), "external check policy.wasm gave no result (built without wasm support)") {
+		t.Errorf("gaps = %v", err)
+	}
+}
+
+func TestRunExternalChecksTimeout(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	slow := writeCheck(t, dir, "slow", `exec sleep 10`)
+	never := writeCheck(t, dir, "never", `echo '{"passed": true}'`)
+
+	report := &PulseReport{}
+	start := time.Now()
+	err := runExternalChecks(report, []string{slow, never}, 100*time.Millisecond, &gaps{})
+	if !errors.Is(err, ErrStageTimeout) || !strings.HasPrefix(err.Error(), "external_check slow:") {
+		t.Fatalf("err = %v, want an external_check slow stage timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timed-out check took %v to stop", elapsed)
+	}
+	if len(report.External) != 1 || report.External[0].Name != "slow" {
+		t.Errorf("report.External = %+v, want only the timed-out check", report.External)
+	}
+}
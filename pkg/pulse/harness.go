@@ -0,0 +1,85 @@
+package pulse
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// The measurement harness keeps host-side jitter out of the GEMM timings: a
+// timed run executes on a goroutine locked to its own OS thread, optionally at
+// raised scheduling priority, while the garbage collector is paused if the
+// pulse has the process to itself. CUDA event timing already excludes most
+// host noise, but a descheduled or GC-stalled thread still delays the launch
+// and the synchronisation that the stage timeout and the device see.
+
+// MeasureNice returns the nice value for measurement threads from
+// PULSE_MEASURE_NICE (-20 to 19; 0, the default, leaves the priority
+// unchanged). Negative values need CAP_SYS_NICE; without it the thread keeps
+// its priority. It is read on each call, so a value exported from an agent
+// flag applies.
+func MeasureNice() int {
+	if s := os.Getenv("PULSE_MEASURE_NICE"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= -20 && v <= 19 {
+			return v
+		}
+	}
+	return 0
+}
+
+// pinMeasurement locks the calling goroutine to its OS thread for the rest of
+// its life and applies nice to that thread. The goroutine must exit without
+// unlocking, so the runtime discards the thread and its priority instead of
+// returning it to the scheduler pool. Priority changes are best effort.
+func pinMeasurement(nice int) {
+	runtime.LockOSThread()
+	if nice != 0 {
+		_ = setThreadNice(nice)
+	}
+}
+
+// runnerProcess is set by ServeRunner: the process exists only to run this
+// pulse, so nothing else is affected when its collector pauses.
+var runnerProcess atomic.Bool
+
+var gcPause struct {
+	sync.Mutex
+	depth   int
+	percent int
+}
+
+// pauseGC runs a collection and then disables the garbage collector until
+// the returned resume is called. Nested and concurrent pauses are counted, so
+// the collector resumes with its previous setting after the last one ends.
+// The GC percent is process-wide, so outside a runner process (an in-process
+// pulse sharing the agent with its informers and controller) pauseGC does
+// nothing. Within one, a pause lasts at most one device's runs, each bounded
+// by the GEMM stage timeout, and the Go memory limit still forces a
+// collection if the heap reaches it.
+func pauseGC() (resume func()) {
+	if !runnerProcess.Load() {
+		return func() {}
+	}
+	gcPause.Lock()
+	defer gcPause.Unlock()
+	if gcPause.depth == 0 {
+		runtime.GC()
+		gcPause.percent = debug.SetGCPercent(-1)
+	}
+	gcPause.depth++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			gcPause.Lock()
+			defer gcPause.Unlock()
+			gcPause.depth--
+			if gcPause.depth == 0 {
+				debug.SetGCPercent(gcPause.percent)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package pulse
+
+import "syscall"
+
+// setThreadNice sets the nice value of the calling OS thread. On Linux
+// PRIO_PROCESS with a thread ID addresses that thread alone.
+func setThreadNice(nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), nice)
+}
@@ -0,0 +1,29 @@
+package pulse
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestPinMeasurementRaisesNiceOnItsThreadOnly(t *testing.T) {
+	t.Parallel()
+
+	type result struct{ tid, prio int }
+	done := make(chan result)
+	go func() {
+		// Raising nice needs no privilege, unlike lowering it.
+		pinMeasurement(5)
+		tid := syscall.Gettid()
+		prio, _ := syscall.Getpriority(syscall.PRIO_PROCESS, tid)
+		done <- result{tid, prio}
+	}()
+	r := <-done
+
+	// The kernel returns 20 - nice.
+	if r.prio != 15 {
+		t.Errorf("measurement thread priority = %d, want 15 (nice 5)", r.prio)
+	}
+	if prio, _ := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid()); prio == 15 {
+		t.Errorf("nice leaked from thread %d to the test's thread", r.tid)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package pulse
+
+import "errors"
+
+// setThreadNice is unsupported off Linux; measurement threads keep their
+// priority.
+func setThreadNice(int) error { return errors.New("thread priority is only supported on linux") }
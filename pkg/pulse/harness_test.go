@@ -0,0 +1,37 @@
+package pulse
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+// Not parallel: the GC percent is process-wide.
+func TestPauseGC(t *testing.T) {
+	prev := debug.SetGCPercent(150)
+	defer debug.SetGCPercent(prev)
+
+	// An in-process pulse leaves the agent's collector alone.
+	pauseGC()()
+	resume := pauseGC()
+	if got := debug.SetGCPercent(150); got != 150 {
+		t.Fatalf("GC percent outside a runner process = %d, want 150 untouched", got)
+	}
+	resume()
+
+	runnerProcess.Store(true)
+	defer runnerProcess.Store(false)
+	outer := pauseGC()
+	inner := pauseGC()
+	if got := debug.SetGCPercent(-1); got != -1 {
+		t.Fatalf("GC percent while paused = %d, want -1", got)
+	}
+	inner()
+	inner() // a second call is a no-op
+	if got := debug.SetGCPercent(-1); got != -1 {
+		t.Fatalf("GC resumed with an outer pause still held (percent %d)", got)
+	}
+	outer()
+	if got := debug.SetGCPercent(150); got != 150 {
+		t.Errorf("GC percent after resume = %d, want the previous 150", got)
+	}
+}
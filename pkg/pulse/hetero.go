@@ -0,0 +1,126 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DeviceThresholds are the limits one device was judged against on a node
+// that mixes GPU models, where they differ from the node's (see
+// deviceSnapshot).
+type DeviceThresholds struct {
+	// Arch is the architecture key the device's name matched, or
+	// "fallback" for unrecognised hardware (see archLabel).
+	Arch                 string  `json:"arch"`
+	StragglerThresholdMS int64   `json:"straggler_threshold_ms"`
+	MaxCV                float64 `json:"max_cv"`
+	MinPowerFraction     float64 `json:"min_power_fraction,omitempty"`
+}
+
+// deviceSnapshot returns th as it applies to a device named name. th is
+// resolved from GPU 0 (see EnvSnapshot); on a node that mixes GPU models a
+// device of another architecture is judged against its own calibration
+// instead, with the same overrides applied: PULSE_THRESHOLD_MS still sets
+// every device's latency threshold, the device's own PULSE_CV_MAX_<ARCH>
+// or PULSE_CV_MAX its CV ceiling, and PULSE_MIN_POWER_FRACTION its power
+// floor. Thresholds set at runtime apply to every device. ok is false when
+// the device shares the node's architecture, its name is unknown, or
+// nothing changed.
+func deviceSnapshot(th Snapshot, name string) (dev Snapshot, ok bool) {
+	node := th.ThresholdProvenance
+	if name == "" || node.Source == SourceRuntime {
+		return th, false
+	}
+	cal, prov := matchArch(name)
+	if prov.Matched == node.Matched {
+		return th, false
+	}
+	dev = th
+	if node.Source == SourceEnv {
+		prov.Source = SourceEnv
+	} else {
+		dev.StragglerThreshold = cal.threshold
+	}
+	if node.MaxCVSource == SourceRuntime {
+		prov.MaxCVSource = SourceRuntime
+	} else {
+		dev.MaxCV, prov.MaxCVSource = envMaxCV(cal, prov)
+	}
+	if th.MinPowerFraction == calibrationOf(node.Matched).minPowerFraction {
+		dev.MinPowerFraction = envLimit("PULSE_MIN_POWER_FRACTION", cal.minPowerFraction)
+	}
+	prov.ResolvedAt = node.ResolvedAt
+	dev.ThresholdProvenance = prov
+	return dev, dev.StragglerThreshold != th.StragglerThreshold || dev.MaxCV != th.MaxCV ||
+		dev.MinPowerFraction != th.MinPowerFraction
+}
+
+// deviceThresholds returns the limits in th for a DeviceResult.
+func deviceThresholds(th Snapshot) *DeviceThresholds {
+	return &DeviceThresholds{
+		Arch:                 archLabel(th),
+		StragglerThresholdMS: th.StragglerThreshold.Milliseconds(),
+		MaxCV:                th.MaxCV,
+		MinPowerFraction:     th.MinPowerFraction,
+	}
+}
+
+// withArch records on the PulseFailure in err that its threshold came from
+// th's architecture calibration rather than the node's. Other errors are
+// returned unchanged.
+func withArch(err error, th Snapshot) error {
+	var detail *PulseFailure
+	if errors.As(err, &detail) && detail.Arch == "" {
+		detail.Arch = archLabel(th)
+	}
+	return err
+}
+
+// archLabel names the calibration th was resolved from: the matched
+// architecture key, or "fallback".
+func archLabel(th Snapshot) string {
+	if th.ThresholdProvenance.Matched == "" {
+		return string(SourceFallback)
+	}
+	return th.ThresholdProvenance.Matched
+}
+
+// queryGPUNames returns the name of every visible GPU in index order,
+// through NVML when available and nvidia-smi otherwise.
+func queryGPUNames(ctx context.Context) ([]string, error) {
+	names, err := nvmlGPUNames(ctx)
+	if err == nil || isDeadline(ctx, err) {
+		return names, err
+	}
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=name", "--format=csv,noheader").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		names = append(names, strings.TrimSpace(line))
+	}
+	return names, nil
+}
+
+// deviceNames returns the GPU names for deviceSnapshot, or nil if they could
+// not be read, in which case every device is judged against th.
+func deviceNames(th Snapshot) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), th.PreflightTimeout)
+	defer cancel()
+	names, err := queryGPUNames(ctx)
+	if err != nil {
+		return nil
+	}
+	return names
+}
+
+// nameOf returns device dev's name from names, or "" if it was not read.
+func nameOf(names []string, dev int) string {
+	if dev < 0 || dev >= len(names) {
+		return ""
+	}
+	return names[dev]
+}
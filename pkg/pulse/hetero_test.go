@@ -0,0 +1,108 @@
+package pulse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeviceSnapshot(t *testing.T) {
+	h100 := func() Snapshot {
+		th := validSnapshot()
+		th.StragglerThreshold, th.MaxCV = 10*time.Millisecond, 0.12
+		th.ThresholdProvenance = Provenance{Source: SourceDetected, GPUName: "NVIDIA H100 80GB HBM3", Matched: "H100", MaxCVSource: SourceDetected}
+		return th
+	}
+	cases := []struct {
+		name      string
+		env       map[string]string
+		th        func() Snapshot
+		gpu       string
+		wantMixed bool
+		want      DeviceThresholds
+	}{
+		{name: "same architecture", th: h100, gpu: "NVIDIA H100 PCIe"},
+		{name: "name unknown", th: h100, gpu: ""},
+		{name: "other architecture", th: h100, gpu: "NVIDIA A100-SXM4-80GB", wantMixed: true,
+			want: DeviceThresholds{Arch: "A100", StragglerThresholdMS: 25, MaxCV: 0.10}},
+		{name: "unrecognised GPU", th: h100, gpu: "NVIDIA L4", wantMixed: true,
+			want: DeviceThresholds{Arch: "fallback", StragglerThresholdMS: 500, MaxCV: 0.20}},
+		{name: "latency override applies to every device", th: func() Snapshot {
+			th := h100()
+			th.StragglerThreshold, th.ThresholdProvenance.Source = 80*time.Millisecond, SourceEnv
+			return th
+		}, gpu: "NVIDIA A100-SXM4-80GB", wantMixed: true,
+			want: DeviceThresholds{Arch: "A100", StragglerThresholdMS: 80, MaxCV: 0.10}},
+		{name: "device's own CV override", env: map[string]string{"PULSE_CV_MAX_A100": "0.3"}, th: h100, gpu: "NVIDIA A100-SXM4-80GB", wantMixed: true,
+			want: DeviceThresholds{Arch: "A100", StragglerThresholdMS: 25, MaxCV: 0.3}},
+		{name: "power floor override applies to every device", env: map[string]string{"PULSE_MIN_POWER_FRACTION": "0.2"}, th: func() Snapshot {
+			th := h100()
+			th.MinPowerFraction = 0.2
+			return th
+		}, gpu: "NVIDIA A100-SXM4-80GB", wantMixed: true,
+			want: DeviceThresholds{Arch: "A100", StragglerThresholdMS: 25, MaxCV: 0.10, MinPowerFraction: 0.2}},
+		{name: "runtime thresholds apply to every device", th: func() Snapshot {
+			th := h100()
+			th.ThresholdProvenance = runtimeProvenance()
+			return th
+		}, gpu: "NVIDIA A100-SXM4-80GB"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range append(ArchKeys(), "") {
+				name := "PULSE_CV_MAX"
+				if key != "" {
+					name = CVOverrideEnv(key)
+				}
+				t.Setenv(name, "")
+			}
+			t.Setenv("PULSE_MIN_POWER_FRACTION", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			th := tc.th()
+			dev, mixed := deviceSnapshot(th, tc.gpu)
+			if mixed != tc.wantMixed {
+				t.Fatalf("mixed = %v, want %v", mixed, tc.wantMixed)
+			}
+			if !mixed {
+				if dev.StragglerThreshold != th.StragglerThreshold || dev.MaxCV != th.MaxCV {
+					t.Errorf("snapshot changed without a mixed device: %+v", dev)
+				}
+				return
+			}
+			if got := *deviceThresholds(dev); got != tc.want {
+				t.Errorf("device thresholds = %+v, want %+v", got, tc.want)
+			}
+			if dev.ThresholdProvenance.GPUName != tc.gpu {
+				t.Errorf("provenance GPU = %q, want %q", dev.ThresholdProvenance.GPUName, tc.gpu)
+			}
+		})
+	}
+}
+
+func TestWithArch(t *testing.T) {
+	th := validSnapshot()
+	th.ThresholdProvenance.Matched = "A100"
+	err := withArch(&PulseFailure{Cause: ErrStragglerDetected, Unit: "ms"}, th)
+	var detail *PulseFailure
+	if !errors.As(err, &detail) || detail.Arch != "A100" {
+		t.Errorf("withArch = %+v, want arch A100", detail)
+	}
+	if err := withArch(nil, th); err != nil {
+		t.Errorf("withArch(nil) = %v", err)
+	}
+}
+
+func TestQueryGPUNames(t *testing.T) {
+	dir := t.TempDir()
+	writeCheck(t, dir, "nvidia-smi", `printf 'NVIDIA H100 80GB HBM3\nNVIDIA A100-SXM4-80GB\n'`)
+	t.Setenv("PATH", dir)
+	names := deviceNames(validSnapshot())
+	if len(names) != 2 || names[0] != "NVIDIA H100 80GB HBM3" || names[1] != "NVIDIA A100-SXM4-80GB" {
+		t.Errorf("deviceNames = %q", names)
+	}
+	if nameOf(names, 2) != "" {
+		t.Errorf("nameOf past the end = %q, want empty", nameOf(names, 2))
+	}
+}
@@ -0,0 +1,107 @@
+package pulse
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GPUIdentity names the physical GPU behind a device index. Indexes follow
+// enumeration order, which a reboot or driver reload can reshuffle; the
+// UUID, board serial and PCI bus ID do not, so they are what an RMA ticket
+// or a technician pulling the board needs. Fields the driver does not
+// report (the serial on consumer parts) are empty.
+//
+// Identities are read with the pre-flight stats through NVML or nvidia-smi,
+// which enumerate by PCI bus ID. CUDA's indexes only match under
+// CUDA_DEVICE_ORDER=PCI_BUS_ID, which the CUDA build sets unless the
+// environment already does.
+type GPUIdentity struct {
+	UUID     string `json:"uuid,omitempty"`
+	Serial   string `json:"serial,omitempty"`
+	PCIBusID string `json:"pci_bus_id,omitempty"`
+}
+
+func (id GPUIdentity) String() string {
+	var parts []string
+	for _, p := range []struct{ name, v string }{{"", id.UUID}, {"serial ", id.Serial}, {"PCI ", id.PCIBusID}} {
+		if p.v != "" {
+			parts = append(parts, p.name+p.v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// identityOf returns device dev's identity from the pre-flight stats, or
+// nil if it was not read.
+func identityOf(stats []gpuStats, dev int) *GPUIdentity {
+	if dev < 0 || dev >= len(stats) || stats[dev].GPUIdentity == (GPUIdentity{}) {
+		return nil
+	}
+	id := stats[dev].GPUIdentity
+	return &id
+}
+
+// describeGPU names device dev in an error message: "GPU 3", followed by
+// its identity when known.
+func describeGPU(dev int, id *GPUIdentity) string {
+	if id == nil {
+		return "GPU " + strconv.Itoa(dev)
+	}
+	return fmt.Sprintf("GPU %d (%s)", dev, id)
+}
+
+// withIdentity attaches id to the PulseFailure in err, unless it already
+// names a GPU. Other errors are returned unchanged.
+func withIdentity(err error, id *GPUIdentity) error {
+	var detail *PulseFailure
+	if id != nil && errors.As(err, &detail) && detail.GPU == nil {
+		detail.GPU = id
+	}
+	return err
+}
+
+// Device label values for the per-device metrics (see DeviceLabel).
+const (
+	DeviceLabelIndex = "index"
+	DeviceLabelUUID  = "uuid"
+)
+
+// DeviceLabel returns what the per-device metrics' "device" label carries:
+// DeviceLabelIndex, the CUDA index (the default), or DeviceLabelUUID, the
+// GPU's UUID, so a series follows the board across reboots. Set with
+// PULSE_DEVICE_LABEL; it is read on each call, like StateDir.
+func DeviceLabel() string {
+	if os.Getenv("PULSE_DEVICE_LABEL") == DeviceLabelUUID {
+		return DeviceLabelUUID
+	}
+	return DeviceLabelIndex
+}
+
+// knownGPUs maps device index to the UUID read at the last pre-flight, for
+// deviceLabel. Runner results replay theirs before their device stats (see
+// RunnerResult.Record).
+var knownGPUs sync.Map
+
+// rememberGPUs records the UUIDs in readings for deviceLabel.
+func rememberGPUs(readings []GPUReading) {
+	for _, r := range readings {
+		if r.UUID != "" {
+			knownGPUs.Store(r.Device, r.UUID)
+		}
+	}
+}
+
+// deviceLabel is the "device" label value for dev. A UUID label falls back
+// to the index while dev's UUID is unknown.
+func deviceLabel(dev int) string {
+	if DeviceLabel() == DeviceLabelUUID {
+		if uuid, ok := knownGPUs.Load(dev); ok {
+			return uuid.(string)
+		}
+	}
+	return strconv.Itoa(dev)
+}
@@ -0,0 +1,65 @@
+package pulse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIdentityInFailures(t *testing.T) {
+	t.Parallel()
+
+	stats := []gpuStats{
+		{},
+		{GPUIdentity: GPUIdentity{UUID: "GPU-5d5ba0d6", Serial: "1652221001234", PCIBusID: "00000000:07:00.0"}},
+	}
+	if id := identityOf(stats, 0); id != nil {
+		t.Errorf("identityOf(unread) = %v, want nil", id)
+	}
+	if id := identityOf(stats, 4); id != nil {
+		t.Errorf("identityOf(out of range) = %v, want nil", id)
+	}
+	if got, want := describeGPU(1, identityOf(stats, 1)), "GPU 1 (GPU-5d5ba0d6, serial 1652221001234, PCI 00000000:07:00.0)"; got != want {
+		t.Errorf("describeGPU = %q, want %q", got, want)
+	}
+	if got := describeGPU(0, nil); got != "GPU 0" {
+		t.Errorf("describeGPU without identity = %q, want GPU 0", got)
+	}
+
+	err := checkRowRemaps([]rowRemap{{}, {Pending: true, Uncorrectable: 2}}, stats)
+	var detail *PulseFailure
+	if !errors.As(err, &detail) || detail.GPU == nil || detail.GPU.Serial != "1652221001234" {
+		t.Fatalf("checkRowRemaps = %#v, want a failure naming serial 1652221001234", err)
+	}
+
+	// withIdentity fills an unnamed PulseFailure, keeps one already named
+	// and leaves other errors alone.
+	other := &GPUIdentity{UUID: "GPU-other"}
+	named := withIdentity(fmt.Errorf("wrapped: %w", &PulseFailure{Cause: ErrHighVariance}), identityOf(stats, 1))
+	if errors.As(named, &detail); detail.GPU == nil || detail.GPU.UUID != "GPU-5d5ba0d6" {
+		t.Errorf("withIdentity did not attach the identity: %+v", detail)
+	}
+	if errors.As(withIdentity(named, other), &detail); detail.GPU.UUID != "GPU-5d5ba0d6" {
+		t.Errorf("withIdentity replaced an identity with %v", detail.GPU)
+	}
+	plain := errors.New("cuda error on GPU 0 run 1 (rc=1)")
+	if got := withIdentity(plain, other); got != plain {
+		t.Errorf("withIdentity changed a plain error: %v", got)
+	}
+}
+
+func TestDeviceLabel(t *testing.T) {
+	const dev = 95 // unused by other tests
+	rememberGPUs([]GPUReading{{Device: dev, GPUIdentity: GPUIdentity{UUID: "GPU-5d5ba0d6"}}})
+
+	if got := deviceLabel(dev); got != "95" {
+		t.Errorf("default label = %q, want the index", got)
+	}
+	t.Setenv("PULSE_DEVICE_LABEL", DeviceLabelUUID)
+	if got := deviceLabel(dev); got != "GPU-5d5ba0d6" {
+		t.Errorf("uuid label = %q, want GPU-5d5ba0d6", got)
+	}
+	if got := deviceLabel(dev + 1); got != "96" {
+		t.Errorf("uuid label for an unknown GPU = %q, want the index", got)
+	}
+}
@@ -0,0 +1,62 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IsInconclusive reports whether err means the pulse could not reach a
+// verdict on the node: a stage timed out (ErrStageTimeout), or the pipeline
+// ran but could not show the node healthy (ErrInconclusive) — GPU stats
+// unreadable, a GPU already busy with another workload, or a GEMM that never
+// reached the utilization floor. Neither a pass nor a straggler verdict;
+// callers choose how to handle it (see policy.Inconclusive).
+func IsInconclusive(err error) bool {
+	return errors.Is(err, ErrInconclusive) || errors.Is(err, ErrStageTimeout)
+}
+
+// busyUtilization is the SM utilization above which a GPU is taken to be
+// running another workload before its GEMM runs start. Its timings would
+// measure the contention, not the device.
+const busyUtilization = 0.1
+
+// gaps collects the checks a pulse could not perform. A pulse with gaps
+// that otherwise passes is inconclusive rather than healthy. Checks skipped
+// by design when what they need is unavailable (see SkippedCheck) are
+// collected too, but leave the verdict alone.
+type gaps struct {
+	missing []string
+	skipped []SkippedCheck
+}
+
+func (g *gaps) add(format string, args ...any) {
+	g.missing = append(g.missing, fmt.Sprintf(format, args...))
+}
+
+// skip records that check was skipped, and why. Nil-safe, so helpers can
+// be called without a collector.
+func (g *gaps) skip(check, why string) {
+	if g != nil {
+		g.skipped = append(g.skipped, SkippedCheck{Check: check, Why: why})
+	}
+}
+
+// err returns an ErrInconclusive error listing every gap, or nil.
+func (g gaps) err() error {
+	if len(g.missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInconclusive, strings.Join(g.missing, "; "))
+}
+
+// gpuBusy reads dev's utilization once and reports whether it is above
+// busyUtilization, with the reading. A failed read is not evidence either
+// way and reports not busy.
+func gpuBusy(dev int, query utilizationQuery) (bool, Utilization) {
+	ctx, cancel := context.WithTimeout(context.Background(), utilizationTimeout)
+	defer cancel()
+	u, err := query(ctx, dev)
+	return err == nil && u.SM > busyUtilization, u
+}
@@ -0,0 +1,55 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIsInconclusive(t *testing.T) {
+	t.Parallel()
+
+	var unchecked gaps
+	if err := unchecked.err(); err != nil {
+		t.Fatalf("no gaps: %v", err)
+	}
+	unchecked.add("pre-flight GPU stats unavailable (%v)", errors.New("nvidia-smi: not found"))
+	unchecked.add("GPU %d busy before its runs", 3)
+	err := unchecked.err()
+	if !IsInconclusive(err) || !strings.Contains(err.Error(), "nvidia-smi: not found); GPU 3 busy") {
+		t.Errorf("gaps error = %v, want an inconclusive error listing both gaps", err)
+	}
+
+	for _, err := range []error{
+		fmt.Errorf("gemm_run: %w after 1m0s", ErrStageTimeout),
+		&PulseFailure{Cause: fmt.Errorf("GPU 0: %w", ErrInconclusive)},
+	} {
+		if !IsInconclusive(err) {
+			t.Errorf("IsInconclusive(%v) = false", err)
+		}
+	}
+	for _, err := range []error{nil, ErrStragglerDetected, ErrNearThreshold, ErrNoCUDA} {
+		if IsInconclusive(err) {
+			t.Errorf("IsInconclusive(%v) = true", err)
+		}
+	}
+}
+
+func TestGPUBusy(t *testing.T) {
+	t.Parallel()
+
+	reading := func(sm float64, err error) utilizationQuery {
+		return func(context.Context, int) (Utilization, error) { return Utilization{SM: sm}, err }
+	}
+	if busy, u := gpuBusy(0, reading(0.85, nil)); !busy || u.SM != 0.85 {
+		t.Errorf("GPU at 85%% SM: busy %v, reading %+v", busy, u)
+	}
+	if busy, _ := gpuBusy(0, reading(0.02, nil)); busy {
+		t.Error("idle GPU reported busy")
+	}
+	if busy, _ := gpuBusy(0, reading(0.9, errors.New("nvml: timeout"))); busy {
+		t.Error("failed read reported busy")
+	}
+}
@@ -0,0 +1,172 @@
+package pulse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HostLoad is the host activity observed while a device's GEMM runs
+// executed. A busy host delays kernel launches and event records, which
+// shows up as run-to-run variance the GPU is not responsible for.
+type HostLoad struct {
+	// LoadPerCPU is the 1-minute load average divided by the host's CPUs,
+	// the higher of the samples before and after the runs.
+	LoadPerCPU float64 `json:"load_per_cpu"`
+	// Steal is the fraction of CPU time the hypervisor took from this guest
+	// during the runs.
+	Steal float64 `json:"steal"`
+}
+
+// hostSample is one reading of /proc/loadavg and the aggregate cpu line of
+// /proc/stat.
+type hostSample struct {
+	load1        float64
+	cpus         int
+	steal, total uint64
+}
+
+// sampleHost reads a hostSample from the proc filesystem mounted at proc.
+func sampleHost(proc string) (hostSample, error) {
+	var s hostSample
+	data, err := os.ReadFile(filepath.Join(proc, "loadavg"))
+	if err != nil {
+		return s, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return s, errors.New("empty loadavg")
+	}
+	if s.load1, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return s, fmt.Errorf("parse loadavg: %w", err)
+	}
+
+	f, err := os.Open(filepath.Join(proc, "stat"))
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		switch {
+		case len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu"):
+			continue
+		case fields[0] != "cpu":
+			s.cpus++
+			continue
+		}
+		// cpu user nice system idle iowait irq softirq steal guest guest_nice;
+		// guest time is already counted in user and nice.
+		for i, v := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return s, fmt.Errorf("parse stat: %w", err)
+			}
+			s.total += n
+			if i == 7 {
+				s.steal = n
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return s, err
+	}
+	if s.cpus == 0 {
+		return s, errors.New("no per-CPU lines in stat")
+	}
+	return s, nil
+}
+
+// hostLoadBetween summarises the host activity between two samples.
+func hostLoadBetween(before, after hostSample) HostLoad {
+	h := HostLoad{LoadPerCPU: max(before.load1, after.load1) / float64(after.cpus)}
+	if after.total > before.total && after.steal >= before.steal {
+		h.Steal = float64(after.steal-before.steal) / float64(after.total-before.total)
+	}
+	return h
+}
+
+// interfered reports whether h exceeds either interference limit in th. A
+// zero limit is not checked.
+func (th Snapshot) interfered(h HostLoad) bool {
+	return (th.MaxHostLoad > 0 && h.LoadPerCPU > th.MaxHostLoad) ||
+		(th.MaxCPUSteal > 0 && h.Steal > th.MaxCPUSteal)
+}
+
+// guardedRun is the outcome of one device's GEMM runs under guardInterference.
+type guardedRun struct {
+	mean time.Duration
+	cv   float64
+	err  error
+	// host is the load observed during the runs that produced the result;
+	// contaminated reports whether it exceeded the limits.
+	host         HostLoad
+	contaminated bool
+	rerun        bool
+	// downgraded is the ErrNearThreshold finding that replaced a variance
+	// failure measured on a busy host both times, for the degraded band.
+	downgraded error
+}
+
+// guardInterference runs one device's GEMM passes with host load sampled
+// around them. Only variance findings are guarded: a busy host stretches
+// individual launches, so it inflates CV, but event-timed means hold up. A
+// variance failure on a contaminated run is re-run once. If the re-run is
+// clean its verdict stands; if it is contaminated too, the failure is
+// down-weighted to ErrNearThreshold, so the node is marked degraded rather
+// than quarantined on evidence the host may have produced. If the host
+// cannot be sampled the run is judged as is.
+func guardInterference(dev int, th Snapshot, sample func() (hostSample, error), run func() (time.Duration, float64, error)) guardedRun {
+	if th.MaxHostLoad <= 0 && th.MaxCPUSteal <= 0 {
+		mean, cv, err := run()
+		return guardedRun{mean: mean, cv: cv, err: err}
+	}
+
+	var r guardedRun
+	for attempt := 0; attempt < 2; attempt++ {
+		before, berr := sample()
+		r.mean, r.cv, r.err = run()
+		after, aerr := sample()
+		if berr != nil || aerr != nil {
+			r.host, r.contaminated = HostLoad{}, false
+			return r
+		}
+		r.host = hostLoadBetween(before, after)
+		r.contaminated = th.interfered(r.host)
+		if !r.contaminated || !errors.Is(r.err, ErrHighVariance) {
+			return r
+		}
+		if attempt == 0 {
+			r.rerun = true
+		}
+	}
+
+	r.downgraded = &PulseFailure{
+		Cause: fmt.Errorf("GPU %d: %w (cv=%.3f over the %.2f ceiling, discounted: host load %.2f per CPU, steal %.0f%%)",
+			dev, ErrNearThreshold, r.cv, th.MaxCV, r.host.LoadPerCPU, 100*r.host.Steal),
+		MeasuredValue:  r.cv,
+		ThresholdValue: th.MaxCV,
+		Unit:           "cv",
+	}
+	r.err = nil
+	return r
+}
+
+// hostLoad returns the observed load for the report when the run was
+// contaminated, or nil: a quiet host is not worth the report space.
+func (r guardedRun) hostLoad() *HostLoad {
+	if !r.contaminated {
+		return nil
+	}
+	h := r.host
+	return &h
+}
@@ -0,0 +1,121 @@
+package pulse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSampleHost(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("loadavg", "6.00 4.10 3.05 7/1024 31337\n")
+	write("stat", "cpu  100 0 50 800 10 0 0 40 20 0\ncpu0 50 0 25 400 5 0 0 20 10 0\ncpu1 50 0 25 400 5 0 0 20 10 0\nintr 12345\n")
+
+	s, err := sampleHost(dir)
+	if err != nil {
+		t.Fatalf("sampleHost: %v", err)
+	}
+	if s.load1 != 6 || s.cpus != 2 || s.steal != 40 || s.total != 1000 {
+		t.Errorf("sample = %+v, want load1=6 cpus=2 steal=40 total=1000 (guest excluded)", s)
+	}
+
+	h := hostLoadBetween(s, hostSample{load1: 2, cpus: 2, steal: 90, total: 1200})
+	if h.LoadPerCPU != 3 || h.Steal != 0.25 {
+		t.Errorf("hostLoadBetween = %+v, want load 3 per CPU, steal 0.25", h)
+	}
+}
+
+func TestGuardInterference(t *testing.T) {
+	t.Parallel()
+
+	var (
+		quiet = hostSample{load1: 0.5, cpus: 8}
+		busy  = hostSample{load1: 40, cpus: 8}
+		noisy = &PulseFailure{Cause: ErrHighVariance}
+		slow  = &PulseFailure{Cause: ErrStragglerDetected}
+	)
+
+	cases := []struct {
+		name       string
+		hosts      []hostSample // host state during each attempt
+		results    []error      // run outcome of each attempt
+		wantRuns   int
+		wantErr    error
+		wantNear   bool
+		wantDirty  bool
+		wantRerun  bool
+		disableAll bool
+	}{
+		{name: "quiet host keeps the variance failure", hosts: []hostSample{quiet}, results: []error{noisy}, wantRuns: 1, wantErr: ErrHighVariance},
+		{name: "busy host with a pass is only marked", hosts: []hostSample{busy}, results: []error{nil}, wantRuns: 1, wantDirty: true},
+		{name: "latency failure is not discounted", hosts: []hostSample{busy}, results: []error{slow}, wantRuns: 1, wantErr: ErrStragglerDetected, wantDirty: true},
+		{name: "clean re-run decides", hosts: []hostSample{busy, quiet}, results: []error{noisy, nil}, wantRuns: 2, wantRerun: true},
+		{name: "clean re-run can still fail", hosts: []hostSample{busy, quiet}, results: []error{noisy, noisy}, wantRuns: 2, wantErr: ErrHighVariance, wantRerun: true},
+		{name: "busy twice is down-weighted", hosts: []hostSample{busy, busy}, results: []error{noisy, noisy}, wantRuns: 2, wantNear: true, wantDirty: true, wantRerun: true},
+		{name: "limits off never re-runs", hosts: []hostSample{busy}, results: []error{noisy}, wantRuns: 1, wantErr: ErrHighVariance, disableAll: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			th := validSnapshot()
+			th.MaxHostLoad, th.MaxCPUSteal = 1, 0.1
+			if tc.disableAll {
+				th.MaxHostLoad, th.MaxCPUSteal = 0, 0
+			}
+			runs, samples := 0, 0
+			sample := func() (hostSample, error) {
+				s := tc.hosts[samples/2]
+				samples++
+				return s, nil
+			}
+			run := func() (time.Duration, float64, error) {
+				err := tc.results[runs]
+				runs++
+				return 40 * time.Millisecond, 0.5, err
+			}
+
+			g := guardInterference(0, th, sample, run)
+			if runs != tc.wantRuns {
+				t.Errorf("runs = %d, want %d", runs, tc.wantRuns)
+			}
+			if tc.wantErr == nil && g.err != nil || tc.wantErr != nil && !errors.Is(g.err, tc.wantErr) {
+				t.Errorf("err = %v, want %v", g.err, tc.wantErr)
+			}
+			if near := errors.Is(g.downgraded, ErrNearThreshold); near != tc.wantNear {
+				t.Errorf("downgraded = %v, want near-threshold %v", g.downgraded, tc.wantNear)
+			}
+			if g.contaminated != tc.wantDirty || g.rerun != tc.wantRerun {
+				t.Errorf("contaminated=%v rerun=%v, want %v %v", g.contaminated, g.rerun, tc.wantDirty, tc.wantRerun)
+			}
+			if (g.hostLoad() != nil) != tc.wantDirty {
+				t.Errorf("hostLoad = %+v, want reported only when contaminated", g.hostLoad())
+			}
+		})
+	}
+}
+
+func TestGuardInterferenceUnreadableHost(t *testing.T) {
+	t.Parallel()
+
+	th := validSnapshot()
+	th.MaxHostLoad = 1
+	runs := 0
+	g := guardInterference(0, th,
+		func() (hostSample, error) { return hostSample{}, os.ErrNotExist },
+		func() (time.Duration, float64, error) { runs++; return 40 * time.Millisecond, 0.5, ErrHighVariance })
+	if runs != 1 || !errors.Is(g.err, ErrHighVariance) || g.contaminated {
+		t.Errorf("runs=%d err=%v contaminated=%v, want the run judged as is", runs, g.err, g.contaminated)
+	}
+}
@@ -0,0 +1,183 @@
+package pulse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ProtocolVersion is the version of the runner result protocol. A runner
+// writes exactly one RunnerResult as a JSON object on stdout and exits 0;
+// anything else on stdout is a protocol violation. Bump on incompatible
+// changes — the agent rejects results carrying a different version, which
+// keeps a mismatched runner image from silently passing nodes.
+const ProtocolVersion = 1
+
+// RunnerResult is the wire format shared by the agent and any pulse runner
+// (the re-exec'd agent or the standalone pulse-runner binary).
+type RunnerResult struct {
+	Version   int            `json:"version"`
+	ElapsedNS int64          `json:"elapsed_ns"`
+	Devices   []RunnerDevice `json:"devices,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Kind      string         `json:"kind,omitempty"` // sentinel name; see errorKinds
+	Measured  float64        `json:"measured_value,omitempty"`
+	Threshold float64        `json:"threshold_value,omitempty"`
+	Unit      string         `json:"unit,omitempty"`
+	// GPU is the failing device's identity (PulseFailure.GPU), if known.
+	GPU *GPUIdentity `json:"gpu,omitempty"`
+	// Arch is the failing device's calibration (PulseFailure.Arch), if not
+	// the node's.
+	Arch string `json:"threshold_arch,omitempty"`
+	// ThrottleReasons are the failing device's active clock throttle
+	// reasons (PulseFailure.ThrottleReasons), if any.
+	ThrottleReasons []string `json:"throttle_reasons,omitempty"`
+
+	// Report is the runner's full PulseReport. Optional: runners predating
+	// it omit the field, and PulseReport falls back to Devices.
+	Report *PulseReport `json:"report,omitempty"`
+}
+
+// RunnerDevice is the per-device GEMM result, replayed into the agent's
+// Prometheus collectors since the runner's own registry is never scraped.
+type RunnerDevice struct {
+	Device      int          `json:"device"`
+	MeanNS      int64        `json:"mean_ns"`
+	CV          float64      `json:"cv"`
+	Utilization *Utilization `json:"utilization,omitempty"`
+}
+
+// errorKinds maps sentinel errors to stable names so errors.Is keeps working
+// after the error crosses the process boundary. Names are part of the protocol.
+var errorKinds = []struct {
+	kind     string
+	sentinel error
+}{
+	{"clock_derated", ErrClockDerated}, // before straggler, which it wraps
+	{"straggler", ErrStragglerDetected},
+	{"high_variance", ErrHighVariance},
+	{"interconnect", ErrInterconnectDegraded},
+	{"stage_timeout", ErrStageTimeout},
+	{"crashed", ErrPulseCrashed},
+	{"near_threshold", ErrNearThreshold},
+	{"inconclusive", ErrInconclusive},
+	{"device_count_decreased", ErrDeviceCountDecreased},
+	{"enumeration_mismatch", ErrEnumerationMismatch},
+	{"gpus_not_visible", ErrGPUsNotVisible},
+	{"xid", ErrXIDEvent},
+	{"row_remap", ErrRowRemap},
+	{"ecc", ErrECCErrors},
+	{"idle_temperature", ErrIdleTemperature},
+	{"pcie_degraded", ErrPCIeDegraded},
+	{"low_power", ErrLowPower},
+	{"external_check", ErrExternalCheck},
+}
+
+// remoteError carries a runner's error message verbatim while still matching
+// the sentinel it wrapped on the other side.
+type remoteError struct {
+	msg      string
+	sentinel error
+}
+
+func (e *remoteError) Error() string { return e.msg }
+func (e *remoteError) Unwrap() error { return e.sentinel }
+
+// NewRunnerResult encodes a pulse outcome for the wire.
+func NewRunnerResult(elapsed time.Duration, devices []RunnerDevice, err error) RunnerResult {
+	res := RunnerResult{Version: ProtocolVersion, ElapsedNS: elapsed.Nanoseconds(), Devices: devices}
+	if err == nil {
+		return res
+	}
+	res.Error = err.Error()
+	for _, k := range errorKinds {
+		if errors.Is(err, k.sentinel) {
+			res.Kind = k.kind
+			break
+		}
+	}
+	var detail *PulseFailure
+	if errors.As(err, &detail) {
+		res.Measured = detail.MeasuredValue
+		res.Threshold = detail.ThresholdValue
+		res.Unit = detail.Unit
+		res.GPU = detail.GPU
+		res.Arch = detail.Arch
+		res.ThrottleReasons = detail.ThrottleReasons
+	}
+	return res
+}
+
+// ReadRunnerResult decodes and version-checks a single result from r.
+func ReadRunnerResult(r io.Reader) (RunnerResult, error) {
+	var res RunnerResult
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return res, fmt.Errorf("decode runner result: %w", err)
+	}
+	if res.Version != ProtocolVersion {
+		return res, fmt.Errorf("runner protocol version %d, want %d", res.Version, ProtocolVersion)
+	}
+	return res, nil
+}
+
+// Record replays the runner's per-device stats and skipped checks into this
+// process's Prometheus collectors, tagged with pulseID.
+func (r RunnerResult) Record(pulseID string) {
+	if r.Report != nil {
+		rememberGPUs(r.Report.GPUs)
+	}
+	for _, d := range r.Devices {
+		observeDevice(pulseID, d.Device, time.Duration(d.MeanNS), d.CV, d.Utilization)
+	}
+	if r.Report != nil {
+		recordSkipped(r.Report.Skipped)
+	}
+}
+
+// PulseReport returns the runner's report, or one rebuilt from Devices if the
+// runner sent none. The pulse ID is set to id when the report lacks one.
+func (r RunnerResult) PulseReport(id string) *PulseReport {
+	report := r.Report
+	if report == nil {
+		report = &PulseReport{WorstMeanNS: r.ElapsedNS}
+		for _, d := range r.Devices {
+			report.Devices = append(report.Devices, DeviceResult{Device: d.Device, MeanNS: d.MeanNS, CV: d.CV, Utilization: d.Utilization})
+		}
+	}
+	if report.PulseID == "" {
+		report.PulseID = id
+	}
+	return report
+}
+
+// Elapsed returns the worst-case mean duration reported by the runner.
+func (r RunnerResult) Elapsed() time.Duration { return time.Duration(r.ElapsedNS) }
+
+// Err rebuilds the runner's error, restoring the sentinel and PulseFailure
+// detail so callers can use errors.Is and errors.As as with RunPulse.
+func (r RunnerResult) Err() error {
+	if r.Error == "" {
+		return nil
+	}
+	var cause error = errors.New(r.Error)
+	for _, k := range errorKinds {
+		if k.kind == r.Kind {
+			cause = &remoteError{msg: r.Error, sentinel: k.sentinel}
+			break
+		}
+	}
+	if r.Unit == "" {
+		return cause
+	}
+	return &PulseFailure{
+		Cause:           cause,
+		MeasuredValue:   r.Measured,
+		ThresholdValue:  r.Threshold,
+		Unit:            r.Unit,
+		GPU:             r.GPU,
+		Arch:            r.Arch,
+		ThrottleReasons: r.ThrottleReasons,
+	}
+}
@@ -0,0 +1,108 @@
+package pulse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// deviceMargin returns an ErrNearThreshold failure if a passing device's mean
+// latency or CV sits inside the degraded band, or nil. Latency is checked
+// first since it is the stronger signal.
+func deviceMargin(dev int, mean time.Duration, cv float64, th Snapshot) error {
+	if limit := float64(th.StragglerThreshold) * th.DegradedFraction; float64(mean) > limit {
+		return &PulseFailure{
+			Cause:          fmt.Errorf("%s: %w (mean=%v, %.0f%% of %v threshold)", gpuLabel(dev), ErrNearThreshold, mean, 100*float64(mean)/float64(th.StragglerThreshold), th.StragglerThreshold),
+			MeasuredValue:  float64(mean.Milliseconds()),
+			ThresholdValue: float64(th.StragglerThreshold.Milliseconds()),
+			Unit:           "ms",
+		}
+	}
+	if highVariance(mean, cv, th.MaxCV*th.DegradedFraction, th) {
+		return &PulseFailure{
+			Cause:          fmt.Errorf("%s: %w (cv=%.3f, ceiling %.2f)", gpuLabel(dev), ErrNearThreshold, cv, th.MaxCV),
+			MeasuredValue:  cv,
+			ThresholdValue: th.MaxCV,
+			Unit:           "cv",
+		}
+	}
+	return nil
+}
+
+// highVariance reports whether a device's run-to-run variation is a finding
+// against the CV ceiling limit: cv above it, with a standard deviation (cv ×
+// mean) of at least th.JitterFloor. Below the floor the spread is timing noise
+// however large it is relative to a short mean.
+func highVariance(mean time.Duration, cv, limit float64, th Snapshot) bool {
+	return cv > limit && time.Duration(cv*float64(mean)) >= th.JitterFloor
+}
+
+// p2pMargin returns an ErrNearThreshold failure if a passing link's bandwidth
+// is within the degraded band above the minimum, or nil.
+func p2pMargin(src, dst int, bw float64, th Snapshot) error {
+	if bw < th.MinP2PBandwidthGBs/th.DegradedFraction {
+		return &PulseFailure{
+			Cause:          fmt.Errorf("GPU %d→%d: %w (%.2f GB/s, minimum %.1f GB/s)", src, dst, ErrNearThreshold, bw, th.MinP2PBandwidthGBs),
+			MeasuredValue:  bw,
+			ThresholdValue: th.MinP2PBandwidthGBs,
+			Unit:           "gbs",
+		}
+	}
+	return nil
+}
+
+// RejudgeCV returns the failure reason a pulse classified as reason would
+// have been given had its devices been judged against a CV ceiling of maxCV
+// instead of r.Thresholds.MaxCV, so a ceiling change can be reviewed against
+// recorded pulses. Only a variance verdict can move: a failure of any other
+// kind is returned as is, as is a report with a failed link or external
+// check, since the variance finding may have masked its reason. Devices are
+// judged in order, as the pulse judges them, and a variance finding on a
+// contaminated host is discounted to near_threshold.
+func RejudgeCV(r *PulseReport, reason string, maxCV float64) string {
+	switch reason {
+	case "", "near_threshold", "high_variance":
+	default:
+		return reason
+	}
+	if r == nil || len(r.Devices) == 0 || maxCV == r.Thresholds.MaxCV {
+		return reason
+	}
+	for _, l := range r.Links {
+		if l.Error != "" {
+			return reason
+		}
+	}
+	for _, e := range r.External {
+		if e.Error != "" {
+			return reason
+		}
+	}
+
+	th := r.Thresholds
+	th.MaxCV = maxCV
+	near := false
+	for _, d := range r.Devices {
+		if d.Error != "" && !strings.Contains(d.Error, ErrHighVariance.Error()) {
+			return reason
+		}
+		mean := time.Duration(d.MeanNS)
+		switch {
+		case highVariance(mean, d.CV, maxCV, th) && !d.Contaminated:
+			return "high_variance"
+		case highVariance(mean, d.CV, maxCV, th):
+			near = true
+		case deviceMargin(d.Device, mean, d.CV, th) != nil:
+			near = true
+		}
+	}
+	for _, l := range r.Links {
+		if p2pMargin(l.Src, l.Dst, l.BandwidthGBs, th) != nil {
+			near = true
+		}
+	}
+	if near {
+		return "near_threshold"
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package pulse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJitterFloor(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		mean     time.Duration
+		cv       float64
+		floor    time.Duration
+		wantHigh bool
+		wantNear bool
+	}{
+		{"fast GPU with sub-ms noise is ignored", 4 * time.Millisecond, 0.30, 2 * time.Millisecond, false, false},
+		{"same CV on a slow GPU is high variance", 60 * time.Millisecond, 0.30, 2 * time.Millisecond, true, false},
+		{"floor off judges CV alone", 4 * time.Millisecond, 0.30, 0, true, false},
+		{"near-threshold band honours the floor", 4 * time.Millisecond, 0.18, 2 * time.Millisecond, false, false},
+		{"near-threshold above the floor", 20 * time.Millisecond, 0.18, 2 * time.Millisecond, false, true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			th := validSnapshot()
+			th.JitterFloor = tc.floor
+			if got := highVariance(tc.mean, tc.cv, th.MaxCV, th); got != tc.wantHigh {
+				t.Errorf("highVariance = %v, want %v", got, tc.wantHigh)
+			}
+			if tc.wantHigh {
+				return
+			}
+			err := deviceMargin(0, tc.mean, tc.cv, th)
+			if near := errors.Is(err, ErrNearThreshold); near != tc.wantNear {
+				t.Errorf("deviceMargin = %v, want near-threshold %v", err, tc.wantNear)
+			}
+		})
+	}
+}
+
+// TestDeviceTimedFailSlow checks that the default jitter floor, sized for
+// device-timed runs, still fails a B200 whose runs intermittently stall, and
+// passes one with healthy device-timed noise.
+func TestDeviceTimedFailSlow(t *testing.T) {
+	t.Setenv("PULSE_JITTER_FLOOR", "")
+
+	ms := func(f float64) time.Duration { return time.Duration(f * float64(time.Millisecond)) }
+	cases := []struct {
+		name     string
+		runs     []time.Duration
+		wantHigh bool
+	}{
+		{"stalling runs", []time.Duration{ms(1.5), ms(1.5), ms(3.1), ms(1.5), ms(3.1)}, true},
+		{"healthy device-timed noise", []time.Duration{ms(1.50), ms(1.53), ms(1.48), ms(1.51), ms(1.55)}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stats runStats
+			for _, d := range tc.runs {
+				stats.add(d)
+			}
+			th := validSnapshot()
+			th.MaxCV, th.JitterFloor = calibrationOf("B200").maxCV, envJitterFloor()
+			if got := highVariance(stats.Mean(), stats.CV(), th.MaxCV, th); got != tc.wantHigh {
+				t.Errorf("highVariance(mean=%v, cv=%.3f) = %v, want %v", stats.Mean(), stats.CV(), got, tc.wantHigh)
+			}
+		})
+	}
+}
+
+func TestRejudgeCV(t *testing.T) {
+	t.Parallel()
+
+	report := func(devices ...DeviceResult) *PulseReport {
+		return &PulseReport{Thresholds: validSnapshot(), Devices: devices}
+	}
+	steady := DeviceResult{Device: 0, MeanNS: int64(20 * time.Millisecond), CV: 0.05}
+	jittery := DeviceResult{Device: 1, MeanNS: int64(20 * time.Millisecond), CV: 0.25,
+		Error: "GPU 1: " + ErrHighVariance.Error() + " (cv=0.250)"}
+	noisy := DeviceResult{Device: 1, MeanNS: int64(20 * time.Millisecond), CV: 0.25, Contaminated: true}
+	slow := DeviceResult{Device: 0, MeanNS: int64(50 * time.Millisecond), CV: 0.01,
+		Error: "GPU 0: " + ErrStragglerDetected.Error()}
+
+	cases := []struct {
+		name   string
+		report *PulseReport
+		reason string
+		maxCV  float64
+		want   string
+	}{
+		{"higher ceiling clears a variance failure", report(steady, jittery), "high_variance", 0.40, ""},
+		{"ceiling inside the band leaves it near", report(steady, jittery), "high_variance", 0.30, "near_threshold"},
+		{"lower ceiling fails a pass", report(steady), "", 0.04, "high_variance"},
+		{"same ceiling keeps the verdict", report(steady, jittery), "high_variance", 0.20, "high_variance"},
+		{"contaminated host is discounted", report(steady, noisy), "near_threshold", 0.10, "near_threshold"},
+		{"latency failure stands", report(slow, jittery), "latency_threshold_exceeded", 0.40, "latency_threshold_exceeded"},
+		{"device with another finding stands", report(slow, jittery), "high_variance", 0.40, "high_variance"},
+		{"no devices", report(), "", 0.01, ""},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := RejudgeCV(tc.report, tc.reason, tc.maxCV); got != tc.want {
+				t.Errorf("RejudgeCV = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
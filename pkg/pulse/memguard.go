@@ -0,0 +1,154 @@
+package pulse
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MemoryReserveEnv is the host memory, in MiB, a pulse needs on top of what
+// the agent's container already uses: the CUDA runtime's context, the
+// GEMM's host matrices and an isolated runner process. Most of it is
+// allocated through CGO, outside the Go heap and its GOMEMLIMIT. A pulse
+// whose container cannot grant that much fails inconclusive before any GPU
+// work, rather than the kernel OOM-killing the agent mid-validation. Zero
+// disables the guard. Read on each pulse.
+const MemoryReserveEnv = "PULSE_MEMORY_RESERVE_MIB"
+
+// DefaultMemoryReserveMiB is the MemoryReserveEnv default.
+const DefaultMemoryReserveMiB = 256
+
+// cgroupRoot is where the container's own cgroup is mounted, under a cgroup
+// namespace.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// unlimitedV1 is the smallest value cgroup v1 reports for an unlimited
+// memory limit: the page-rounded maximum int64.
+const unlimitedV1 = 1 << 62
+
+// MemoryReserve returns the MemoryReserveEnv setting in bytes. A malformed
+// or negative value reads as the default.
+func MemoryReserve() int64 {
+	mib := int64(DefaultMemoryReserveMiB)
+	if s := os.Getenv(MemoryReserveEnv); s != "" {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil && v >= 0 {
+			mib = v
+		}
+	}
+	return mib << 20
+}
+
+// ContainerLimits are the memory and CPU the process's cgroup allows. Zero
+// means unlimited or unreadable.
+type ContainerLimits struct {
+	MemoryBytes int64
+	CPUs        float64
+}
+
+// ReadContainerLimits reads the limits of the cgroup this process runs in.
+func ReadContainerLimits() ContainerLimits {
+	return containerLimits(cgroupRoot)
+}
+
+// containerLimits reads the limits under root, cgroup v2 first, then v1.
+func containerLimits(root string) ContainerLimits {
+	var l ContainerLimits
+	l.MemoryBytes, _, _ = cgroupMemory(root)
+	if data, err := os.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		// "<quota> <period>", quota "max" when unlimited.
+		if f := strings.Fields(string(data)); len(f) == 2 {
+			quota, err1 := strconv.ParseFloat(f[0], 64)
+			period, err2 := strconv.ParseFloat(f[1], 64)
+			if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+				l.CPUs = quota / period
+			}
+		}
+		return l
+	}
+	quota, err1 := readInt(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	period, err2 := readInt(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+		l.CPUs = float64(quota) / float64(period)
+	}
+	return l
+}
+
+// cgroupMemory returns the memory limit of the cgroup under root and its
+// working set: usage less the inactive page cache the kernel reclaims
+// before it OOM-kills, as the kubelet counts it. ok is false when the limit
+// is unset or unreadable.
+func cgroupMemory(root string) (limit, used int64, ok bool) {
+	files := []struct{ limit, usage, stat, inactive string }{
+		{"memory.max", "memory.current", "memory.stat", "inactive_file"},
+		{"memory/memory.limit_in_bytes", "memory/memory.usage_in_bytes", "memory/memory.stat", "total_inactive_file"},
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(root, f.limit))
+		if err != nil {
+			continue
+		}
+		limit, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil || limit >= unlimitedV1 {
+			// "max" on v2.
+			return 0, 0, false
+		}
+		used, err = readInt(filepath.Join(root, f.usage))
+		if err != nil {
+			return 0, 0, false
+		}
+		used = max(used-statValue(filepath.Join(root, f.stat), f.inactive), 0)
+		return limit, used, true
+	}
+	return 0, 0, false
+}
+
+// checkMemoryHeadroom returns an ErrInconclusive failure if the cgroup under
+// root has less than reserve bytes left below its limit. Without a limit,
+// or with a zero reserve, the guard passes. MeasuredValue and ThresholdValue
+// are the headroom and the reserve in MiB.
+func checkMemoryHeadroom(root string, reserve int64) error {
+	if reserve <= 0 {
+		return nil
+	}
+	limit, used, ok := cgroupMemory(root)
+	if !ok || limit-used >= reserve {
+		return nil
+	}
+	free := max(limit-used, 0)
+	return &PulseFailure{
+		Cause: fmt.Errorf("%w: memory guard: %d MiB free below the container's %d MiB limit, a pulse needs %d MiB (see %s)",
+			ErrInconclusive, free>>20, limit>>20, reserve>>20, MemoryReserveEnv),
+		MeasuredValue:  float64(free >> 20),
+		ThresholdValue: float64(reserve >> 20),
+		Unit:           "mib",
+	}
+}
+
+// readInt reads a file holding one integer.
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// statValue returns key's value in a memory.stat file, or 0.
+func statValue(path, key string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if k, v, ok := strings.Cut(sc.Text(), " "); ok && k == key {
+			n, _ := strconv.ParseInt(v, 10, 64)
+			return n
+		}
+	}
+	return 0
+}
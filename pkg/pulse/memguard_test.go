@@ -0,0 +1,126 @@
+package pulse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCgroup writes files, relative to a fresh cgroup root, and returns it.
+func writeCgroup(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCgroupMemory(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		files     map[string]string
+		wantLimit int64
+		wantUsed  int64
+		wantOK    bool
+	}{
+		{"v2", map[string]string{
+			"memory.max":     "536870912\n",
+			"memory.current": "314572800\n",
+			"memory.stat":    "anon 200000000\ninactive_file 104857600\nactive_file 1000\n",
+		}, 512 << 20, 200 << 20, true},
+		{"v2 unlimited", map[string]string{"memory.max": "max\n", "memory.current": "1000\n"}, 0, 0, false},
+		{"v1", map[string]string{
+			"memory/memory.limit_in_bytes": "1073741824\n",
+			"memory/memory.usage_in_bytes": "209715200\n",
+			"memory/memory.stat":           "cache 0\ntotal_inactive_file 0\n",
+		}, 1 << 30, 200 << 20, true},
+		{"v1 unlimited", map[string]string{
+			"memory/memory.limit_in_bytes": "9223372036854771712\n",
+			"memory/memory.usage_in_bytes": "1000\n",
+		}, 0, 0, false},
+		{"no cgroup", nil, 0, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			limit, used, ok := cgroupMemory(writeCgroup(t, tc.files))
+			if limit != tc.wantLimit || used != tc.wantUsed || ok != tc.wantOK {
+				t.Errorf("cgroupMemory = %d, %d, %v; want %d, %d, %v", limit, used, ok, tc.wantLimit, tc.wantUsed, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckMemoryHeadroom(t *testing.T) {
+	t.Parallel()
+
+	root := writeCgroup(t, map[string]string{
+		"memory.max":     "536870912\n",
+		"memory.current": "419430400\n",
+	})
+	err := checkMemoryHeadroom(root, 256<<20)
+	var detail *PulseFailure
+	if !IsInconclusive(err) || !errors.As(err, &detail) {
+		t.Fatalf("checkMemoryHeadroom = %v, want an inconclusive failure", err)
+	}
+	if detail.MeasuredValue != 112 || detail.ThresholdValue != 256 || detail.Unit != "mib" {
+		t.Errorf("failure = %g/%g %s, want 112/256 mib", detail.MeasuredValue, detail.ThresholdValue, detail.Unit)
+	}
+	if !strings.Contains(err.Error(), "112 MiB free below the container's 512 MiB limit, a pulse needs 256 MiB") {
+		t.Errorf("err = %q", err)
+	}
+	if err := checkMemoryHeadroom(root, 64<<20); err != nil {
+		t.Errorf("checkMemoryHeadroom with room = %v, want nil", err)
+	}
+	if err := checkMemoryHeadroom(root, 0); err != nil {
+		t.Errorf("checkMemoryHeadroom disabled = %v, want nil", err)
+	}
+	if err := checkMemoryHeadroom(writeCgroup(t, nil), 256<<20); err != nil {
+		t.Errorf("checkMemoryHeadroom without a limit = %v, want nil", err)
+	}
+}
+
+func TestContainerLimits(t *testing.T) {
+	t.Parallel()
+
+	v2 := containerLimits(writeCgroup(t, map[string]string{"memory.max": "536870912\n", "memory.current": "0\n", "cpu.max": "150000 100000\n"}))
+	if v2 != (ContainerLimits{MemoryBytes: 512 << 20, CPUs: 1.5}) {
+		t.Errorf("v2 limits = %+v", v2)
+	}
+	if l := containerLimits(writeCgroup(t, map[string]string{"cpu.max": "max 100000\n"})); l != (ContainerLimits{}) {
+		t.Errorf("unlimited v2 = %+v, want zeros", l)
+	}
+	v1 := containerLimits(writeCgroup(t, map[string]string{"cpu/cpu.cfs_quota_us": "200000\n", "cpu/cpu.cfs_period_us": "100000\n"}))
+	if v1.CPUs != 2 {
+		t.Errorf("v1 CPUs = %v, want 2", v1.CPUs)
+	}
+	if l := containerLimits(writeCgroup(t, map[string]string{"cpu/cpu.cfs_quota_us": "-1\n", "cpu/cpu.cfs_period_us": "100000\n"})); l.CPUs != 0 {
+		t.Errorf("unlimited v1 CPUs = %v, want 0", l.CPUs)
+	}
+}
+
+func TestMemoryReserve(t *testing.T) {
+	t.Setenv(MemoryReserveEnv, "")
+	if got := MemoryReserve(); got != DefaultMemoryReserveMiB<<20 {
+		t.Errorf("default = %d", got)
+	}
+	t.Setenv(MemoryReserveEnv, "0")
+	if got := MemoryReserve(); got != 0 {
+		t.Errorf("0 = %d, want the guard off", got)
+	}
+	t.Setenv(MemoryReserveEnv, "-5")
+	if got := MemoryReserve(); got != DefaultMemoryReserveMiB<<20 {
+		t.Errorf("negative = %d, want the default", got)
+	}
+}
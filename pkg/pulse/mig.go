@@ -0,0 +1,200 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIGInstanceEnv is set, with CUDA_VISIBLE_DEVICES, on the child that
+// validates one MIG instance (see runMIG). Its value is the instance's UUID.
+// A pipeline that finds it set only runs the GEMM on its one visible device;
+// the parent owns every other stage.
+const MIGInstanceEnv = "PULSE_MIG_INSTANCE"
+
+// MIGInstance identifies one MIG partition: a compute instance inside a GPU
+// instance on a physical GPU. UUID is what CUDA_VISIBLE_DEVICES and the
+// device plugin address it by, so it is the handle for fencing the
+// partition. GPUInstance and ComputeInstance are -1 when only nvidia-smi was
+// available to enumerate it.
+type MIGInstance struct {
+	GPU             int    `json:"gpu"`
+	GPUInstance     int    `json:"gpu_instance"`
+	ComputeInstance int    `json:"compute_instance"`
+	Profile         string `json:"profile,omitempty"`
+	UUID            string `json:"uuid"`
+}
+
+func (m MIGInstance) String() string {
+	return fmt.Sprintf("%s (GPU %d %s, GI %d, CI %d)", m.UUID, m.GPU, m.Profile, m.GPUInstance, m.ComputeInstance)
+}
+
+// migTarget is one CUDA device a MIG-mode pulse validates in a child of its
+// own: a MIG instance, or a whole GPU without MIG on a node that mixes both.
+type migTarget struct {
+	GPU  int
+	UUID string
+	MIG  *MIGInstance
+}
+
+func (t migTarget) String() string {
+	if t.MIG != nil {
+		return t.MIG.String()
+	}
+	return fmt.Sprintf("GPU %d (%s)", t.GPU, t.UUID)
+}
+
+// migTargets lists the node's MIG instances and non-MIG GPUs through NVML,
+// or nvidia-smi -L when NVML is unavailable. It returns nil if no GPU has
+// MIG enabled.
+func migTargets(ctx context.Context) ([]migTarget, error) {
+	targets, err := nvmlMIGTargets(ctx)
+	if !errors.Is(err, errNVMLUnavailable) {
+		return targets, err
+	}
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "-L").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi -L: %w", err)
+	}
+	return parseMIGList(string(out)), nil
+}
+
+// parseMIGList reads the targets from nvidia-smi -L, which lists each GPU
+// followed by its MIG devices:
+//
+//	GPU 0: NVIDIA A100-SXM4-80GB (UUID: GPU-5d5ba0d6-…)
+//	  MIG 3g.40gb     Device  0: (UUID: MIG-c6d4f1ef-…)
+//
+// nvidia-smi does not print GPU or compute instance IDs.
+func parseMIGList(out string) []migTarget {
+	var targets []migTarget
+	mig := false
+	gpu, gpuUUID, partitioned := -1, "", false
+	flush := func() {
+		if gpu >= 0 && !partitioned {
+			targets = append(targets, migTarget{GPU: gpu, UUID: gpuUUID})
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		uuid := listedUUID(line)
+		switch {
+		case strings.HasPrefix(line, "GPU "):
+			flush()
+			idx, _, _ := strings.Cut(strings.TrimPrefix(line, "GPU "), ":")
+			n, err := strconv.Atoi(idx)
+			if err != nil {
+				gpu = -1
+				continue
+			}
+			gpu, gpuUUID, partitioned = n, uuid, false
+		case strings.HasPrefix(line, "MIG ") && gpu >= 0 && uuid != "":
+			mig, partitioned = true, true
+			profile := strings.Fields(line)[1]
+			targets = append(targets, migTarget{GPU: gpu, UUID: uuid, MIG: &MIGInstance{
+				GPU: gpu, GPUInstance: -1, ComputeInstance: -1, Profile: profile, UUID: uuid,
+			}})
+		}
+	}
+	flush()
+	if !mig {
+		return nil
+	}
+	return targets
+}
+
+// listedUUID returns the UUID in a "(UUID: …)" suffix, or "".
+func listedUUID(line string) string {
+	_, rest, ok := strings.Cut(line, "(UUID: ")
+	if !ok {
+		return ""
+	}
+	uuid, _, _ := strings.Cut(rest, ")")
+	return uuid
+}
+
+// migProfile returns the profile from a MIG device name such as
+// "NVIDIA A100-SXM4-80GB MIG 3g.40gb".
+func migProfile(name string) string {
+	if i := strings.LastIndex(name, "MIG "); i >= 0 {
+		return name[i+len("MIG "):]
+	}
+	return name
+}
+
+// migRun validates one target in a child process and returns its result.
+type migRun func(t migTarget) (RunnerResult, error)
+
+// childRun is the migRun of a real pulse: the current binary re-executed
+// as a Runner that sees only t.
+func childRun(pulseID string) migRun {
+	ctx := WithPulseID(context.Background(), pulseID)
+	return func(t migTarget) (RunnerResult, error) {
+		return Runner{Env: []string{"CUDA_VISIBLE_DEVICES=" + t.UUID, MIGInstanceEnv + "=" + t.UUID}}.run(ctx)
+	}
+}
+
+// runMIG is the device stage on a node with MIG enabled. CUDA shows a
+// process at most one MIG instance, so each target is validated by run in a
+// child of its own and judged there like a whole GPU. Results are recorded
+// against the physical GPU, with its identity from the pre-flight readings. Like the device loop, a threshold finding lets
+// the remaining targets run, and any other error ends the stage. It returns
+// the first threshold finding, else the first near-threshold one, else nil.
+func runMIG(report *PulseReport, targets []migTarget, observe deviceObserver, run migRun) error {
+	var failErr, marginErr error
+	for _, t := range targets {
+		res, err := run(t)
+		var mean time.Duration
+		var cv float64
+		if err == nil {
+			if len(res.Devices) > 0 {
+				mean, cv = time.Duration(res.Devices[0].MeanNS), res.Devices[0].CV
+			}
+			err = res.Err()
+		} else {
+			err = fmt.Errorf("%s: %w", t, err)
+		}
+		id := report.gpuIdentity(t.GPU)
+		err = withIdentity(err, id)
+		observe(t.GPU, mean, cv, nil)
+		d := DeviceResult{Device: t.GPU, MIG: t.MIG, MeanNS: mean.Nanoseconds(), CV: cv, GPU: id}
+		if mean.Nanoseconds() > report.WorstMeanNS {
+			report.WorstMeanNS = mean.Nanoseconds()
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrNearThreshold):
+			if marginErr == nil {
+				marginErr = err
+			}
+		case thresholdFinding(err):
+			d.Error = err.Error()
+			if failErr == nil {
+				failErr = err
+			}
+		default:
+			d.Error = err.Error()
+			report.Devices = append(report.Devices, d)
+			return err
+		}
+		report.Devices = append(report.Devices, d)
+	}
+	if failErr != nil {
+		return failErr
+	}
+	return marginErr
+}
+
+// gpuLabel names device dev in failures: "GPU <dev>", or in a child
+// validating one MIG instance, where dev is always 0, the instance's UUID.
+func gpuLabel(dev int) string {
+	if uuid := os.Getenv(MIGInstanceEnv); uuid != "" {
+		return uuid
+	}
+	return "GPU " + strconv.Itoa(dev)
+}
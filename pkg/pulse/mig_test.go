@@ -0,0 +1,127 @@
+package pulse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMIGList(t *testing.T) {
+	out := `GPU 0: NVIDIA A100-SXM4-80GB (UUID: GPU-aaaa)
+  MIG 3g.40gb     Device  0: (UUID: MIG-0000)
+  MIG 3g.40gb     Device  1: (UUID: MIG-0001)
+GPU 1: NVIDIA A100-SXM4-80GB (UUID: GPU-bbbb)
+`
+	got := parseMIGList(out)
+	if len(got) != 3 {
+		t.Fatalf("got %d targets, want 3: %v", len(got), got)
+	}
+	if got[0].MIG == nil || got[0].UUID != "MIG-0000" || got[0].MIG.Profile != "3g.40gb" || got[0].MIG.GPUInstance != -1 {
+		t.Errorf("target 0 = %+v, want MIG-0000 3g.40gb on GPU 0", got[0])
+	}
+	if got[1].UUID != "MIG-0001" || got[1].GPU != 0 {
+		t.Errorf("target 1 = %+v, want MIG-0001 on GPU 0", got[1])
+	}
+	if got[2].MIG != nil || got[2].GPU != 1 || got[2].UUID != "GPU-bbbb" {
+		t.Errorf("target 2 = %+v, want whole GPU 1", got[2])
+	}
+
+	if got := parseMIGList("GPU 0: NVIDIA H100 (UUID: GPU-aaaa)\nGPU 1: NVIDIA H100 (UUID: GPU-bbbb)\n"); got != nil {
+		t.Errorf("without MIG got %v, want nil", got)
+	}
+}
+
+func TestMigProfile(t *testing.T) {
+	for name, want := range map[string]string{
+		"NVIDIA A100-SXM4-80GB MIG 3g.40gb": "3g.40gb",
+		"NVIDIA H100 80GB HBM3 MIG 1g.10gb": "1g.10gb",
+		"1g.5gb":                            "1g.5gb",
+	} {
+		if got := migProfile(name); got != want {
+			t.Errorf("migProfile(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRunMIG(t *testing.T) {
+	inst := func(gpu, gi int, uuid string) migTarget {
+		return migTarget{GPU: gpu, UUID: uuid, MIG: &MIGInstance{GPU: gpu, GPUInstance: gi, ComputeInstance: 0, Profile: "3g.40gb", UUID: uuid}}
+	}
+	healthy, slow, crashed := inst(0, 1, "MIG-0000"), inst(0, 2, "MIG-0001"), inst(1, 1, "MIG-0002")
+	result := func(mean time.Duration, err error) RunnerResult {
+		return NewRunnerResult(mean, []RunnerDevice{{MeanNS: mean.Nanoseconds(), CV: 0.01}}, err)
+	}
+	straggler := &PulseFailure{
+		Cause:         fmt.Errorf("MIG-0001: %w (mean=50ms)", ErrStragglerDetected),
+		MeasuredValue: 50, ThresholdValue: 35, Unit: "ms",
+	}
+	run := func(t migTarget) (RunnerResult, error) {
+		switch t.UUID {
+		case "MIG-0001":
+			return result(50*time.Millisecond, straggler), nil
+		case "MIG-0002":
+			return RunnerResult{}, ErrPulseCrashed
+		}
+		return result(20*time.Millisecond, nil), nil
+	}
+	var observed []int
+	observe := func(dev int, _ time.Duration, _ float64, _ *Utilization) { observed = append(observed, dev) }
+
+	t.Run("straggler", func(t *testing.T) {
+		observed = nil
+		report := &PulseReport{}
+		err := runMIG(report, []migTarget{healthy, slow}, observe, run)
+		if !errors.Is(err, ErrStragglerDetected) {
+			t.Fatalf("err = %v, want ErrStragglerDetected", err)
+		}
+		if len(report.Devices) != 2 || report.Devices[1].MIG.UUID != "MIG-0001" || report.Devices[1].Error == "" {
+			t.Fatalf("devices = %+v, want the straggler recorded against MIG-0001", report.Devices)
+		}
+		if report.Devices[0].Error != "" || report.Devices[0].MeanNS != (20*time.Millisecond).Nanoseconds() {
+			t.Errorf("healthy instance = %+v", report.Devices[0])
+		}
+		if report.WorstMeanNS != (50 * time.Millisecond).Nanoseconds() {
+			t.Errorf("WorstMeanNS = %d, want 50ms", report.WorstMeanNS)
+		}
+		f := report.Failures()
+		if len(f) != 1 || f[0].Component != "MIG-0001" {
+			t.Errorf("Failures() = %+v, want one on MIG-0001", f)
+		}
+		if fmt.Sprint(observed) != "[0 0]" {
+			t.Errorf("observed devices %v, want [0 0]", observed)
+		}
+	})
+
+	t.Run("crash ends the stage", func(t *testing.T) {
+		report := &PulseReport{}
+		err := runMIG(report, []migTarget{slow, crashed, healthy}, observe, run)
+		if !errors.Is(err, ErrPulseCrashed) || !strings.Contains(err.Error(), "MIG-0002") {
+			t.Fatalf("err = %v, want ErrPulseCrashed naming MIG-0002", err)
+		}
+		if len(report.Devices) != 2 {
+			t.Errorf("got %d devices, want the stage to stop at the crash", len(report.Devices))
+		}
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		report := &PulseReport{}
+		if err := runMIG(report, []migTarget{healthy, {GPU: 1, UUID: "GPU-bbbb"}}, observe, run); err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		if report.Devices[1].MIG != nil || report.Devices[1].Device != 1 {
+			t.Errorf("whole GPU = %+v, want device 1 without MIG", report.Devices[1])
+		}
+	})
+}
+
+func TestGPULabel(t *testing.T) {
+	if got := gpuLabel(3); got != "GPU 3" {
+		t.Errorf("gpuLabel(3) = %q, want GPU 3", got)
+	}
+	t.Setenv(MIGInstanceEnv, "MIG-0000")
+	if got := gpuLabel(0); got != "MIG-0000" {
+		t.Errorf("in a MIG child gpuLabel(0) = %q, want the instance UUID", got)
+	}
+}
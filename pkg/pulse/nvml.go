@@ -0,0 +1,369 @@
+//go:build nvml
+
+package pulse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// withNVML initialises libnvidia-ml, runs fn and shuts the library down again.
+// NVML init is reference counted, so concurrent callers are safe. fn runs on
+// its own goroutine so a wedged driver call cannot outlive ctx; the goroutine
+// is abandoned, as with a killed nvidia-smi.
+func withNVML[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		if ret := nvml.Init(); ret != nvml.SUCCESS {
+			r.err = fmt.Errorf("%w: init: %v", errNVMLUnavailable, ret.Error())
+			done <- r
+			return
+		}
+		defer nvml.Shutdown()
+		r.v, r.err = fn()
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// nvmlDevices returns a handle for every visible device, in index order.
+func nvmlDevices() ([]nvml.Device, error) {
+	n, ret := nvml.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("nvml: device count: %v", ret.Error())
+	}
+	devs := make([]nvml.Device, n)
+	for i := range devs {
+		d, ret := nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("nvml: device %d handle: %v", i, ret.Error())
+		}
+		devs[i] = d
+	}
+	return devs, nil
+}
+
+// nvmlQueryAll is the NVML equivalent of queryAllSMI. A metric the device does
+// not support (NOT_SUPPORTED, e.g. ECC on consumer parts) reads as 0, matching
+// nvidia-smi's "N/A".
+func nvmlQueryAll(ctx context.Context) ([]gpuStats, error) {
+	return withNVML(ctx, func() ([]gpuStats, error) {
+		devs, err := nvmlDevices()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]gpuStats, len(devs))
+		for i, d := range devs {
+			sm, ret := d.GetClockInfo(nvml.CLOCK_SM)
+			if err := nvmlErr(i, "sm clock", ret); err != nil {
+				return nil, err
+			}
+			maxSM, ret := d.GetMaxClockInfo(nvml.CLOCK_SM)
+			if err := nvmlErr(i, "max sm clock", ret); err != nil {
+				return nil, err
+			}
+			temp, ret := d.GetTemperature(nvml.TEMPERATURE_GPU)
+			if err := nvmlErr(i, "temperature", ret); err != nil {
+				return nil, err
+			}
+			ecc, ret := d.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, nvml.AGGREGATE_ECC)
+			if err := nvmlErr(i, "ecc errors", ret); err != nil {
+				return nil, err
+			}
+			corrected, ret := d.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_CORRECTED, nvml.VOLATILE_ECC)
+			if err := nvmlErr(i, "corrected ecc errors", ret); err != nil {
+				return nil, err
+			}
+			volatile, ret := d.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, nvml.VOLATILE_ECC)
+			if err := nvmlErr(i, "volatile ecc errors", ret); err != nil {
+				return nil, err
+			}
+			volatileECC := int(volatile)
+			if ret == nvml.ERROR_NOT_SUPPORTED {
+				volatileECC = -1
+			}
+			uuid, ret := d.GetUUID()
+			if err := nvmlErr(i, "uuid", ret); err != nil {
+				return nil, err
+			}
+			serial, ret := d.GetSerial()
+			if err := nvmlErr(i, "serial", ret); err != nil {
+				return nil, err
+			}
+			pci, ret := d.GetPciInfo()
+			if err := nvmlErr(i, "pci info", ret); err != nil {
+				return nil, err
+			}
+			result[i] = gpuStats{
+				SMClockMHz:    int(sm),
+				MaxSMClockMHz: int(maxSM),
+				TempC:         int(temp),
+				ECCErrors:     int(ecc),
+				CorrectedECC:  int(corrected),
+				VolatileECC:   volatileECC,
+				GPUIdentity:   GPUIdentity{UUID: uuid, Serial: serial, PCIBusID: pciBusID(pci)},
+			}
+		}
+		return result, nil
+	})
+}
+
+// nvmlRowRemaps is the NVML equivalent of the nvidia-smi remapped-rows query.
+// Devices without row remapping (NOT_SUPPORTED, pre-Ampere) read as zero.
+func nvmlRowRemaps(ctx context.Context) ([]rowRemap, error) {
+	return withNVML(ctx, func() ([]rowRemap, error) {
+		devs, err := nvmlDevices()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]rowRemap, len(devs))
+		for i, d := range devs {
+			corr, unc, pending, failed, ret := d.GetRemappedRows()
+			if err := nvmlErr(i, "remapped rows", ret); err != nil {
+				return nil, err
+			}
+			result[i] = rowRemap{Correctable: corr, Uncorrectable: unc, Pending: pending, Failed: failed}
+		}
+		return result, nil
+	})
+}
+
+// nvmlPCIeLinks is the NVML equivalent of the nvidia-smi PCIe link query.
+// Values a device does not report (NOT_SUPPORTED) read as zero.
+func nvmlPCIeLinks(ctx context.Context) ([]pcieLink, error) {
+	return withNVML(ctx, func() ([]pcieLink, error) {
+		devs, err := nvmlDevices()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]pcieLink, len(devs))
+		for i, d := range devs {
+			var l pcieLink
+			for _, q := range []struct {
+				what string
+				into *int
+				get  func() (int, nvml.Return)
+			}{
+				{"pcie link generation", &l.Gen, d.GetCurrPcieLinkGeneration},
+				{"max pcie link generation", &l.MaxGen, d.GetMaxPcieLinkGeneration},
+				{"pcie link width", &l.Width, d.GetCurrPcieLinkWidth},
+				{"max pcie link width", &l.MaxWidth, d.GetMaxPcieLinkWidth},
+			} {
+				v, ret := q.get()
+				if err := nvmlErr(i, q.what, ret); err != nil {
+					return nil, err
+				}
+				if ret == nvml.SUCCESS {
+					*q.into = v
+				}
+			}
+			result[i] = l
+		}
+		return result, nil
+	})
+}
+
+// nvmlUtilization reads dev's SM and memory utilization over NVML's last
+// sample period.
+func nvmlUtilization(ctx context.Context, dev int) (Utilization, error) {
+	return withNVML(ctx, func() (Utilization, error) {
+		d, ret := nvml.DeviceGetHandleByIndex(dev)
+		if ret != nvml.SUCCESS {
+			return Utilization{}, fmt.Errorf("nvml: device %d handle: %v", dev, ret.Error())
+		}
+		u, ret := d.GetUtilizationRates()
+		if ret != nvml.SUCCESS {
+			return Utilization{}, fmt.Errorf("nvml: device %d utilization: %v", dev, ret.Error())
+		}
+		return Utilization{SM: float64(u.Gpu) / 100, Memory: float64(u.Memory) / 100}, nil
+	})
+}
+
+// nvmlThrottleReason reads dev's active clocks event reasons, the NVML
+// equivalent of nvidia-smi's clocks_throttle_reasons.active.
+func nvmlThrottleReason(ctx context.Context, dev int) (uint64, error) {
+	return withNVML(ctx, func() (uint64, error) {
+		d, ret := nvml.DeviceGetHandleByIndex(dev)
+		if ret != nvml.SUCCESS {
+			return 0, fmt.Errorf("nvml: device %d handle: %v", dev, ret.Error())
+		}
+		mask, ret := d.GetCurrentClocksEventReasons()
+		if ret != nvml.SUCCESS {
+			return 0, fmt.Errorf("nvml: device %d clocks event reasons: %v", dev, ret.Error())
+		}
+		return mask, nil
+	})
+}
+
+// nvmlPower reads dev's power draw and limits, converted from milliwatts.
+// Limits the device does not report (NOT_SUPPORTED) read as zero.
+func nvmlPower(ctx context.Context, dev int) (Power, error) {
+	return withNVML(ctx, func() (Power, error) {
+		d, ret := nvml.DeviceGetHandleByIndex(dev)
+		if ret != nvml.SUCCESS {
+			return Power{}, fmt.Errorf("nvml: device %d handle: %v", dev, ret.Error())
+		}
+		draw, ret := d.GetPowerUsage()
+		if ret != nvml.SUCCESS {
+			return Power{}, fmt.Errorf("nvml: device %d power usage: %v", dev, ret.Error())
+		}
+		p := Power{DrawW: float64(draw) / 1000}
+		for _, q := range []struct {
+			what string
+			into *float64
+			get  func() (uint32, nvml.Return)
+		}{
+			{"enforced power limit", &p.EnforcedLimitW, d.GetEnforcedPowerLimit},
+			{"default power limit", &p.DefaultLimitW, d.GetPowerManagementDefaultLimit},
+		} {
+			mw, ret := q.get()
+			if err := nvmlErr(dev, q.what, ret); err != nil {
+				return Power{}, err
+			}
+			if ret == nvml.SUCCESS {
+				*q.into = float64(mw) / 1000
+			}
+		}
+		return p, nil
+	})
+}
+
+// nvmlGPUName returns the name of device 0.
+func nvmlGPUName(ctx context.Context) (string, error) {
+	return withNVML(ctx, func() (string, error) {
+		d, ret := nvml.DeviceGetHandleByIndex(0)
+		if ret != nvml.SUCCESS {
+			return "", fmt.Errorf("nvml: device 0 handle: %v", ret.Error())
+		}
+		name, ret := d.GetName()
+		if ret != nvml.SUCCESS {
+			return "", fmt.Errorf("nvml: device 0 name: %v", ret.Error())
+		}
+		return name, nil
+	})
+}
+
+// nvmlGPUNames returns the name of every visible GPU, in index order.
+func nvmlGPUNames(ctx context.Context) ([]string, error) {
+	return withNVML(ctx, func() ([]string, error) {
+		devs, err := nvmlDevices()
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(devs))
+		for i, d := range devs {
+			name, ret := d.GetName()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("nvml: device %d name: %v", i, ret.Error())
+			}
+			names[i] = name
+		}
+		return names, nil
+	})
+}
+
+// nvmlCount returns NVML's device count.
+func nvmlCount(ctx context.Context) (int, error) {
+	return withNVML(ctx, func() (int, error) {
+		n, ret := nvml.DeviceGetCount()
+		if ret != nvml.SUCCESS {
+			return 0, fmt.Errorf("nvml: device count: %v", ret.Error())
+		}
+		return n, nil
+	})
+}
+
+// nvmlMIGTargets is the NVML equivalent of parsing nvidia-smi -L, with the
+// GPU and compute instance IDs nvidia-smi does not print. A device that does
+// not support MIG (NOT_SUPPORTED) is a whole-GPU target.
+func nvmlMIGTargets(ctx context.Context) ([]migTarget, error) {
+	return withNVML(ctx, func() ([]migTarget, error) {
+		devs, err := nvmlDevices()
+		if err != nil {
+			return nil, err
+		}
+		var targets []migTarget
+		mig := false
+		for i, d := range devs {
+			current, _, ret := d.GetMigMode()
+			if err := nvmlErr(i, "mig mode", ret); err != nil {
+				return nil, err
+			}
+			if ret == nvml.ERROR_NOT_SUPPORTED || current != nvml.DEVICE_MIG_ENABLE {
+				uuid, ret := d.GetUUID()
+				if err := nvmlErr(i, "uuid", ret); err != nil {
+					return nil, err
+				}
+				targets = append(targets, migTarget{GPU: i, UUID: uuid})
+				continue
+			}
+			mig = true
+			n, ret := d.GetMaxMigDeviceCount()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("nvml: device %d max mig devices: %v", i, ret.Error())
+			}
+			for j := 0; j < n; j++ {
+				m, ret := d.GetMigDeviceHandleByIndex(j)
+				if ret == nvml.ERROR_NOT_FOUND {
+					continue
+				}
+				if ret != nvml.SUCCESS {
+					return nil, fmt.Errorf("nvml: device %d mig device %d handle: %v", i, j, ret.Error())
+				}
+				inst := MIGInstance{GPU: i}
+				var name string
+				for _, r := range []struct {
+					what string
+					get  func() nvml.Return
+				}{
+					{"uuid", func() (ret nvml.Return) { inst.UUID, ret = m.GetUUID(); return }},
+					{"gpu instance", func() (ret nvml.Return) { inst.GPUInstance, ret = m.GetGpuInstanceId(); return }},
+					{"compute instance", func() (ret nvml.Return) { inst.ComputeInstance, ret = m.GetComputeInstanceId(); return }},
+					{"name", func() (ret nvml.Return) { name, ret = m.GetName(); return }},
+				} {
+					if ret := r.get(); ret != nvml.SUCCESS {
+						return nil, fmt.Errorf("nvml: device %d mig device %d %s: %v", i, j, r.what, ret.Error())
+					}
+				}
+				inst.Profile = migProfile(name)
+				targets = append(targets, migTarget{GPU: i, UUID: inst.UUID, MIG: &inst})
+			}
+		}
+		if !mig {
+			return nil, nil
+		}
+		return targets, nil
+	})
+}
+
+// pciBusID formats pci like nvidia-smi's pci.bus_id, e.g. 00000000:07:00.0.
+// The zero value of an unsupported query formats as "".
+func pciBusID(pci nvml.PciInfo) string {
+	if pci == (nvml.PciInfo{}) {
+		return ""
+	}
+	return fmt.Sprintf("%08X:%02X:%02X.0", pci.Domain, pci.Bus, pci.Device)
+}
+
+// nvmlErr converts ret into an error, treating NOT_SUPPORTED as success.
+// Callers rely on the zero value left in the output for unsupported metrics.
+func nvmlErr(dev int, what string, ret nvml.Return) error {
+	if ret == nvml.SUCCESS || ret == nvml.ERROR_NOT_SUPPORTED {
+		return nil
+	}
+	return fmt.Errorf("nvml: device %d %s: %v", dev, what, ret.Error())
+}
@@ -0,0 +1,30 @@
+//go:build !nvml
+
+package pulse
+
+import "context"
+
+// Without the nvml tag every NVML query reports errNVMLUnavailable and callers
+// fall back to exec'ing nvidia-smi.
+
+func nvmlQueryAll(context.Context) ([]gpuStats, error) { return nil, errNVMLUnavailable }
+
+func nvmlGPUName(context.Context) (string, error) { return "", errNVMLUnavailable }
+
+func nvmlGPUNames(context.Context) ([]string, error) { return nil, errNVMLUnavailable }
+
+func nvmlCount(context.Context) (int, error) { return 0, errNVMLUnavailable }
+
+func nvmlRowRemaps(context.Context) ([]rowRemap, error) { return nil, errNVMLUnavailable }
+
+func nvmlPCIeLinks(context.Context) ([]pcieLink, error) { return nil, errNVMLUnavailable }
+
+func nvmlUtilization(context.Context, int) (Utilization, error) {
+	return Utilization{}, errNVMLUnavailable
+}
+
+func nvmlPower(context.Context, int) (Power, error) { return Power{}, errNVMLUnavailable }
+
+func nvmlThrottleReason(context.Context, int) (uint64, error) { return 0, errNVMLUnavailable }
+
+func nvmlMIGTargets(context.Context) ([]migTarget, error) { return nil, errNVMLUnavailable }
@@ -0,0 +1,82 @@
+package pulse
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// pcieLink is one device's PCIe link as negotiated and at its best. Max is
+// the most the GPU and the slot it sits in support together. Zero values are
+// unreported.
+type pcieLink struct {
+	Gen, MaxGen     int
+	Width, MaxWidth int
+}
+
+// checkPCIeLinks fails with ErrPCIeDegraded on the first device whose link
+// trained narrower than MaxWidth. A GPU retrained to x4 after a link error
+// passes every GEMM but starves data loading. The generation is reported but
+// not judged: an idle GPU drops its link generation to save power and only
+// trains it back up under host transfers, which the pulse does not make. Its
+// width it keeps. MeasuredValue and ThresholdValue are the lanes found and
+// expected; the failure names the GPU by its identity in stats when known.
+// stage prefixes the message.
+func checkPCIeLinks(stage string, links []pcieLink, stats []gpuStats) error {
+	for i, l := range links {
+		if l.MaxWidth == 0 || l.Width == 0 || l.Width >= l.MaxWidth {
+			continue
+		}
+		id := identityOf(stats, i)
+		return &PulseFailure{
+			Cause: fmt.Errorf("%s: %w: %s: link x%d Gen%d, expected x%d Gen%d",
+				stage, ErrPCIeDegraded, describeGPU(i, id), l.Width, l.Gen, l.MaxWidth, l.MaxGen),
+			MeasuredValue:  float64(l.Width),
+			ThresholdValue: float64(l.MaxWidth),
+			Unit:           "lanes",
+			GPU:            id,
+		}
+	}
+	return nil
+}
+
+// queryPCIeLinks returns the PCIe link of every visible GPU, through NVML
+// when available and nvidia-smi otherwise, as queryAllGPUs does.
+func queryPCIeLinks(ctx context.Context) ([]pcieLink, error) {
+	links, err := nvmlPCIeLinks(ctx)
+	if err == nil || isDeadline(ctx, err) {
+		return links, err
+	}
+	out, err := exec.CommandContext(ctx,
+		"nvidia-smi",
+		"--query-gpu=pcie.link.gen.current,pcie.link.gen.max,pcie.link.width.current,pcie.link.width.max",
+		"--format=csv,noheader,nounits",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	return parsePCIeLinks(string(out))
+}
+
+// parsePCIeLinks parses the nvidia-smi PCIe link query, one row per device in
+// index order. "N/A" reads as zero.
+func parsePCIeLinks(out string) ([]pcieLink, error) {
+	var links []pcieLink
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("nvidia-smi: unexpected field count in %q", line)
+		}
+		v := make([]int, len(fields))
+		for i, f := range fields {
+			v[i], _ = strconv.Atoi(strings.TrimSpace(f))
+		}
+		links = append(links, pcieLink{Gen: v[0], MaxGen: v[1], Width: v[2], MaxWidth: v[3]})
+	}
+	return links, nil
+}
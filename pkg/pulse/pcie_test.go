@@ -0,0 +1,63 @@
+package pulse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePCIeLinks(t *testing.T) {
+	links, err := parsePCIeLinks("4, 4, 16, 16\n1, 5, 16, 16\n[N/A], [N/A], [N/A], [N/A]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pcieLink{{4, 4, 16, 16}, {1, 5, 16, 16}, {}}
+	if len(links) != len(want) {
+		t.Fatalf("links = %+v, want %+v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d = %+v, want %+v", i, links[i], want[i])
+		}
+	}
+	if _, err := parsePCIeLinks("4, 4\n"); err == nil {
+		t.Error("parsePCIeLinks accepted a short row")
+	}
+}
+
+func TestCheckPCIeLinks(t *testing.T) {
+	stats := []gpuStats{{}, {GPUIdentity: GPUIdentity{UUID: "GPU-1"}}}
+	cases := []struct {
+		name     string
+		links    []pcieLink
+		wantUnit string
+		wantMsg  string
+		wantVals [2]float64
+	}{
+		{"full link", []pcieLink{{5, 5, 16, 16}, {5, 5, 16, 16}}, "", "", [2]float64{}},
+		{"idle generation ignored", []pcieLink{{1, 5, 16, 16}}, "", "", [2]float64{}},
+		{"narrow link", []pcieLink{{5, 5, 16, 16}, {2, 4, 4, 16}}, "lanes", "GPU 1 (GPU-1): link x4 Gen2, expected x16 Gen4", [2]float64{4, 16}},
+		{"unreported", []pcieLink{{0, 0, 0, 0}}, "", "", [2]float64{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkPCIeLinks("pre-flight", tc.links, stats)
+			if tc.wantUnit == "" {
+				if err != nil {
+					t.Fatalf("checkPCIeLinks = %v, want a pass", err)
+				}
+				return
+			}
+			var detail *PulseFailure
+			if !errors.Is(err, ErrPCIeDegraded) || !errors.As(err, &detail) {
+				t.Fatalf("checkPCIeLinks = %v, want ErrPCIeDegraded", err)
+			}
+			if detail.Unit != tc.wantUnit || detail.MeasuredValue != tc.wantVals[0] || detail.ThresholdValue != tc.wantVals[1] {
+				t.Errorf("failure = %g/%g %s, want %g/%g %s", detail.MeasuredValue, detail.ThresholdValue, detail.Unit, tc.wantVals[0], tc.wantVals[1], tc.wantUnit)
+			}
+			if !strings.Contains(err.Error(), tc.wantMsg) {
+				t.Errorf("err = %q, want %q", err, tc.wantMsg)
+			}
+		})
+	}
+}
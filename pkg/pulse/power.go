@@ -0,0 +1,103 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Power is a device's board power during its GEMM runs, in watts. NVML
+// averages the draw over about a second, longer than the runs themselves, so
+// the peak reading lags the load and understates a healthy board's draw.
+// That is why the power floor is off unless calibrated.
+type Power struct {
+	// DrawW is the peak draw read during the runs.
+	DrawW float64 `json:"draw_w"`
+	// EnforcedLimitW is the lowest power limit enforced during the runs:
+	// the lesser of the operator's cap and any the driver applied.
+	EnforcedLimitW float64 `json:"enforced_limit_w,omitempty"`
+	// DefaultLimitW is the board's default power limit, its TDP; zero if
+	// not reported.
+	DefaultLimitW float64 `json:"default_limit_w,omitempty"`
+}
+
+type powerQuery func(ctx context.Context, dev int) (Power, error)
+
+// samplePower polls dev's power during its runs like sampleUtilization, and
+// returns the peak draw with the lowest enforced limit seen. Stop returns
+// nil if no read succeeded.
+func samplePower(dev int, poll, final powerQuery) (stop func() *Power) {
+	return samplePeak(dev, poll, final, func(peak *Power, p Power) {
+		peak.DrawW = max(peak.DrawW, p.DrawW)
+		if p.EnforcedLimitW > 0 && (peak.EnforcedLimitW == 0 || p.EnforcedLimitW < peak.EnforcedLimitW) {
+			peak.EnforcedLimitW = p.EnforcedLimitW
+		}
+		peak.DefaultLimitW = max(peak.DefaultLimitW, p.DefaultLimitW)
+	})
+}
+
+// checkPower returns an ErrLowPower failure if dev's peak draw stayed below
+// fraction of its default power limit. A GEMM that keeps every SM busy
+// draws a steady share of TDP on a healthy board; one held at a low power
+// cap runs slower by a margin too small for the latency ceiling to catch.
+// The enforced limit is named when it sits below the default, the usual
+// cause. A device whose power or default limit could not be read is not
+// judged, and a zero fraction disables the check.
+func checkPower(dev int, p *Power, fraction float64) error {
+	if p == nil || fraction <= 0 || p.DefaultLimitW <= 0 {
+		return nil
+	}
+	floor := fraction * p.DefaultLimitW
+	if p.DrawW >= floor {
+		return nil
+	}
+	var capped string
+	if p.EnforcedLimitW > 0 && p.EnforcedLimitW < p.DefaultLimitW {
+		capped = fmt.Sprintf(" (power capped at %.0fW)", p.EnforcedLimitW)
+	}
+	return &PulseFailure{
+		Cause: fmt.Errorf("%s: %w: peak draw %.0fW below %.0f%% of the %.0fW default limit%s",
+			gpuLabel(dev), ErrLowPower, p.DrawW, 100*fraction, p.DefaultLimitW, capped),
+		MeasuredValue:  p.DrawW,
+		ThresholdValue: floor,
+		Unit:           "w",
+	}
+}
+
+// queryPower reads dev's power through NVML, or nvidia-smi when NVML is
+// unavailable.
+func queryPower(ctx context.Context, dev int) (Power, error) {
+	p, err := nvmlPower(ctx, dev)
+	if !errors.Is(err, errNVMLUnavailable) {
+		return p, err
+	}
+	out, err := exec.CommandContext(ctx,
+		"nvidia-smi",
+		"--query-gpu=power.draw,enforced.power.limit,power.default_limit",
+		"--format=csv,noheader,nounits",
+		"--id="+strconv.Itoa(dev),
+	).Output()
+	if err != nil {
+		return Power{}, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	return parsePower(string(out))
+}
+
+// parsePower parses nvidia-smi's "<draw>, <enforced limit>, <default limit>"
+// line, in watts. The limits read as zero when "N/A"; the draw is required.
+func parsePower(line string) (Power, error) {
+	fields := strings.Split(strings.TrimSpace(line), ", ")
+	if len(fields) != 3 {
+		return Power{}, fmt.Errorf("nvidia-smi: unexpected power %q", line)
+	}
+	draw, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Power{}, fmt.Errorf("nvidia-smi: unexpected power %q", line)
+	}
+	limit, _ := strconv.ParseFloat(fields[1], 64)
+	def, _ := strconv.ParseFloat(fields[2], 64)
+	return Power{DrawW: draw, EnforcedLimitW: limit, DefaultLimitW: def}, nil
+}
@@ -0,0 +1,90 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSamplePower(t *testing.T) {
+	t.Parallel()
+
+	var polls atomic.Int32
+	poll := func(context.Context, int) (Power, error) {
+		if polls.Add(1) == 1 {
+			return Power{DrawW: 610, EnforcedLimitW: 700, DefaultLimitW: 700}, nil
+		}
+		return Power{DrawW: 120, EnforcedLimitW: 300, DefaultLimitW: 700}, nil
+	}
+	final := func(context.Context, int) (Power, error) {
+		return Power{DrawW: 90, EnforcedLimitW: 700, DefaultLimitW: 700}, nil
+	}
+	stop := samplePower(0, poll, final)
+	time.Sleep(5 * utilizationInterval / 2)
+	got := stop()
+	if got == nil || *got != (Power{DrawW: 610, EnforcedLimitW: 300, DefaultLimitW: 700}) {
+		t.Errorf("power = %+v, want the peak draw and the lowest enforced limit", got)
+	}
+}
+
+func TestParsePower(t *testing.T) {
+	t.Parallel()
+
+	got, err := parsePower("612.35, 700.00, 700.00\n")
+	if err != nil || got != (Power{DrawW: 612.35, EnforcedLimitW: 700, DefaultLimitW: 700}) {
+		t.Errorf("parsePower = %+v, %v", got, err)
+	}
+	if got, err := parsePower("75.20, [N/A], [N/A]"); err != nil || got != (Power{DrawW: 75.2}) {
+		t.Errorf("parsePower without limits = %+v, %v", got, err)
+	}
+	for _, line := range []string{"[N/A], 700.00, 700.00", "612.35", ""} {
+		if _, err := parsePower(line); err == nil {
+			t.Errorf("parsePower(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+func TestCheckPower(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		power    *Power
+		fraction float64
+		wantMsg  string
+	}{
+		{"full draw", &Power{DrawW: 520, EnforcedLimitW: 700, DefaultLimitW: 700}, 0.3, ""},
+		{"capped", &Power{DrawW: 143, EnforcedLimitW: 150, DefaultLimitW: 700}, 0.3, "GPU 2: GPU power draw below expected under load: peak draw 143W below 30% of the 700W default limit (power capped at 150W)"},
+		{"low without a cap", &Power{DrawW: 143, EnforcedLimitW: 700, DefaultLimitW: 700}, 0.3, "700W default limit"},
+		{"unread", nil, 0.3, ""},
+		{"default limit unreported", &Power{DrawW: 10}, 0.3, ""},
+		{"disabled", &Power{DrawW: 10, DefaultLimitW: 700}, 0, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkPower(2, tc.power, tc.fraction)
+			if tc.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("checkPower = %v, want a pass", err)
+				}
+				return
+			}
+			var detail *PulseFailure
+			if !errors.Is(err, ErrLowPower) || !errors.As(err, &detail) {
+				t.Fatalf("checkPower = %v, want ErrLowPower", err)
+			}
+			if detail.Unit != "w" || detail.MeasuredValue != 143 || detail.ThresholdValue != 210 {
+				t.Errorf("failure = %g/%g %s, want 143/210 w", detail.MeasuredValue, detail.ThresholdValue, detail.Unit)
+			}
+			if !strings.Contains(err.Error(), tc.wantMsg) {
+				t.Errorf("err = %q, want %q", err, tc.wantMsg)
+			}
+			if strings.Contains(err.Error(), "capped") != (tc.power.EnforcedLimitW < tc.power.DefaultLimitW) {
+				t.Errorf("err = %q, want the cap named only when below the default", err)
+			}
+		})
+	}
+}
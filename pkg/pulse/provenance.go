@@ -0,0 +1,43 @@
+package pulse
+
+import "time"
+
+// ThresholdSource says where an active threshold came from.
+type ThresholdSource string
+
+const (
+	// SourceEnv: environment override (PULSE_THRESHOLD_MS, PULSE_CV_MAX).
+	SourceEnv ThresholdSource = "env"
+	// SourceDetected: architecture calibration matched the GPU name.
+	SourceDetected ThresholdSource = "detected"
+	// SourceFallback: nvidia-smi unavailable or GPU unrecognised; 500ms default.
+	SourceFallback ThresholdSource = "fallback"
+	// SourceRuntime: set through Config after startup.
+	SourceRuntime ThresholdSource = "runtime"
+	// SourcePolicy: per-SKU CV ceiling from the policy file (cvCeilings).
+	SourcePolicy ThresholdSource = "policy"
+)
+
+// Provenance records how the latency threshold was chosen. "Why is the
+// threshold 10ms" is the first question in every false-positive review; this
+// answers it from the report alone.
+type Provenance struct {
+	Source ThresholdSource `json:"source"`
+	// GPUName is the nvidia-smi name that detection ran against; empty when
+	// the thresholds were set at runtime.
+	GPUName string `json:"gpu_name,omitempty"`
+	// Matched is the architecture key the name matched (e.g. "H100").
+	Matched string `json:"matched,omitempty"`
+	// Table is the ArchTableEnv file the matched entry came from; empty for
+	// the built-in table.
+	Table string `json:"table,omitempty"`
+	// MaxCVSource says where the CV ceiling came from: env (PULSE_CV_MAX or
+	// its per-SKU form), detected (architecture calibration), fallback,
+	// policy or runtime.
+	MaxCVSource ThresholdSource `json:"max_cv_source,omitempty"`
+	ResolvedAt  time.Time       `json:"resolved_at"`
+}
+
+func runtimeProvenance() Provenance {
+	return Provenance{Source: SourceRuntime, ResolvedAt: time.Now().UTC()}
+}
@@ -9,45 +9,216 @@ package pulse
 */
 import "C"
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
-	"strconv"
+	"os"
 	"time"
-
-	"github.com/justin-oleary/straggler-shield/pkg/metrics"
 )
 
+func init() {
+	// NVML and nvidia-smi enumerate by PCI bus ID; make CUDA agree, so an
+	// index names the same GPU in both (see GPUIdentity). The runtime reads
+	// it when it initializes, on the first pulse.
+	if os.Getenv("CUDA_DEVICE_ORDER") == "" {
+		os.Setenv("CUDA_DEVICE_ORDER", "PCI_BUS_ID")
+	}
+}
+
 // pulseRuns is the number of timed GEMM passes per device per validation cycle.
 const pulseRuns = 5
 
 // RunPulse executes the full multi-GPU validation pipeline:
-//  1. Pre-flight: ECC + idle temperature check on all devices
-//  2. Per-device: N timed GEMM passes; records duration and CV to Prometheus
+//  0. Memory guard: enough container memory left for the pulse (see
+//     MemoryReserveEnv)
+//  1. Pre-flight: ECC + idle temperature check on all devices, waiting for
+//     hot GPUs to cool (see CooldownRetriesEnv)
+//  2. Per-device: N timed GEMM passes; records duration, CV and peak
+//     utilization to Prometheus, and checks the power drawn under load
 //  3. P2P ring: bandwidth check along the ring 0→1→…→N-1→0
-//  4. Post-pulse: clock frequency validation on all devices
+//  4. External checks: operator-supplied executables (see external.go)
+//  5. Post-pulse: clock frequency validation on all devices
 //
-// Returns the worst-case mean duration and the first error encountered.
-// Any device failure causes the entire node to be quarantined.
+// Returns the worst-case mean duration and the first error encountered; see
+// RunPulseReport for the full per-device evidence.
+// Any device failure causes the entire node to be quarantined. If every check
+// passes but a measurement sits inside the degraded band (see margin.go),
+// ErrNearThreshold is returned instead of nil; if a check could not be
+// performed, ErrInconclusive (see IsInconclusive). Each stage runs
+// under its own timeout (see Snapshot); an overrun returns ErrStageTimeout.
 func RunPulse() (time.Duration, error) {
-	if err := preflight(); err != nil {
-		return 0, err
+	return RunPulseContext(context.Background())
+}
+
+// RunPulseContext is RunPulse with the pulse ID taken from ctx (see
+// WithPulseID); it tags the recorded device metrics as exemplars.
+func RunPulseContext(ctx context.Context) (time.Duration, error) {
+	report, err := RunPulseReport(ctx)
+	return report.Elapsed(), err
+}
+
+// RunPulseReport runs the pipeline like RunPulseContext and returns the full
+// PulseReport alongside the error. The report is never nil.
+func RunPulseReport(ctx context.Context) (*PulseReport, error) {
+	id := PulseIDFrom(ctx)
+	report, err := runPipeline(ctx, id, ExpectedGPUsFrom(ctx), observerFor(id))
+	recordSkipped(report.Skipped)
+	return report, err
+}
+
+// runPipeline is RunPulseReport with the per-device metrics sink injected, so
+// the isolated child process can ship device stats back to the parent instead
+// of recording them in a registry nobody scrapes.
+// expected is the node's advertised GPU count (0 = unknown). ctx cuts short
+// the cool-down wait.
+func runPipeline(ctx context.Context, pulseID string, expected int, observe deviceObserver) (*PulseReport, error) {
+	// Thresholds are fixed for the whole pulse; see Snapshot.
+	th := active.Snapshot()
+	report := newReport(pulseID, th)
+	// Nothing is loaded yet that the kernel could OOM-kill mid-validation.
+	if err := checkMemoryHeadroom(cgroupRoot, MemoryReserve()); err != nil {
+		return report, err
+	}
+	if os.Getenv(MIGInstanceEnv) != "" {
+		return runInstance(report, th, observe)
+	}
+
+	// unchecked lists the checks that could not be performed; see gaps.
+	var unchecked gaps
+	defer func() { report.Skipped = unchecked.skipped }()
+	stats, cooldown, err := preflightCooldown(ctx, th, &unchecked)
+	report.Cooldown = cooldown
+	report.Preflight = stageResult(err)
+	report.GPUs = eccTrend(StateDir(), stats)
+	rememberGPUs(report.GPUs)
+	if err != nil {
+		return report, err
+	}
+
+	migCtx, cancel := context.WithTimeout(ctx, th.PreflightTimeout)
+	targets, err := migTargets(migCtx)
+	cancel()
+	if err != nil {
+		unchecked.add("MIG mode unknown (%v)", err)
+	}
+	if len(targets) > 0 {
+		return runMIGPipeline(report, th, stats, targets, observe, &unchecked)
 	}
 
 	count := deviceCount()
+	report.DeviceCount = count
+	if err := checkEnumeration(count, th.PreflightTimeout, &unchecked); err != nil {
+		return report, err
+	}
+	if expected <= 0 {
+		unchecked.skip("visibility", "capacity_unknown")
+	}
+	if err := checkVisibility(expected, count, os.Getenv("NVIDIA_VISIBLE_DEVICES"), "/dev"); err != nil {
+		return report, err
+	}
+	if err := checkDeviceCount(StateDir(), count); err != nil {
+		return report, err
+	}
 
-	var worstMean time.Duration
-	for dev := 0; dev < count; dev++ {
-		mean, cv, err := runDevicePulse(dev)
+	// The node's own baseline, if recorded, tightens the latency check.
+	var base *baseline
+	if th.BaselineFactor > 0 {
+		if base = loadBaseline(StateDir()); base == nil {
+			unchecked.skip("baseline", "not_recorded")
+		}
+	}
+
+	// marginErr holds the first near-threshold finding. It only surfaces if
+	// every hard check passes — a real failure always takes precedence.
+	var marginErr error
+	// failErr is the first threshold finding. The remaining devices and
+	// links are still measured so the report lists every failing component;
+	// any other error ends the pulse at once.
+	var failErr error
+	var failMean time.Duration
 
-		devLabel := strconv.Itoa(dev)
-		metrics.PulseDuration.WithLabelValues(devLabel).Observe(mean.Seconds())
-		metrics.PulseCV.WithLabelValues(devLabel).Set(cv)
+	// A node mixing GPU models judges each device against its own
+	// architecture's calibration; see deviceSnapshot.
+	names := deviceNames(th)
+	// throttle holds the throttle reasons sampled during each device's
+	// runs, for the clock check.
+	throttle := make([]uint64, count)
+	for dev := 0; dev < count; dev++ {
+		dth, mixed := deviceSnapshot(th, nameOf(names, dev))
+		busy, before := gpuBusy(dev, queryUtilization)
+		if busy {
+			unchecked.add("GPU %d busy before its runs (SM %.0f%%)", dev, 100*before.SM)
+		}
+		stopUtil := sampleUtilization(dev, nvmlUtilization, queryUtilization)
+		stopPower := samplePower(dev, nvmlPower, queryPower)
+		stopThrottle := sampleThrottle(dev, nvmlThrottleReason, queryThrottleReason)
+		// runs are the durations of the attempt that stands, after a rerun.
+		var runs []int64
+		g := guardInterference(dev, dth, func() (hostSample, error) { return sampleHost("/proc") }, func() (time.Duration, float64, error) {
+			mean, cv, r, err := runDevicePulse(dev, dth)
+			runs = r
+			return mean, cv, err
+		})
+		util, power, active := stopUtil(), stopPower(), stopThrottle()
+		if active != nil {
+			throttle[dev] = *active
+		} else {
+			unchecked.skip("throttle", "unavailable")
+		}
+		if util == nil {
+			unchecked.skip("utilization", "unavailable")
+		}
+		if power == nil && dth.MinPowerFraction > 0 {
+			unchecked.skip("power", "unavailable")
+		}
+		mean, cv, err := g.mean, g.cv, g.err
+		if err == nil {
+			err = checkBaseline(base, dev, mean, th.BaselineFactor)
+		}
+		if err == nil {
+			err = checkPower(dev, power, dth.MinPowerFraction)
+		}
+		id := identityOf(stats, dev)
+		err = withIdentity(err, id)
+		var devThresholds *DeviceThresholds
+		if mixed {
+			err = withArch(err, dth)
+			devThresholds = deviceThresholds(dth)
+		}
+		observe(dev, mean, cv, util)
+		report.Devices = append(report.Devices, DeviceResult{
+			Device: dev, MeanNS: mean.Nanoseconds(), CV: cv, RunsNS: runs, GPU: id,
+			Thresholds: devThresholds, BaselineNS: base.mean(dev).Nanoseconds(), Error: errString(err),
+			Contaminated: g.contaminated, HostLoad: g.hostLoad(), Rerun: g.rerun,
+			Utilization: util, Power: power, ThrottleReasons: throttleReasons(throttle[dev]),
+		})
 
+		if err != nil && !thresholdFinding(err) {
+			report.WorstMeanNS = mean.Nanoseconds()
+			return report, err
+		}
+		if busy {
+			// The runs shared the GPU, so neither a finding nor a margin
+			// says anything about the device.
+			continue
+		}
 		if err != nil {
-			return mean, err
+			if failErr == nil {
+				failErr, failMean = err, mean
+			}
+			continue
 		}
-		if mean > worstMean {
-			worstMean = mean
+		if mean.Nanoseconds() > report.WorstMeanNS {
+			report.WorstMeanNS = mean.Nanoseconds()
+		}
+		if marginErr == nil {
+			marginErr = withIdentity(g.downgraded, id)
+			if marginErr == nil {
+				marginErr = withIdentity(deviceMargin(dev, mean, cv, dth), id)
+			}
+			if mixed {
+				marginErr = withArch(marginErr, dth)
+			}
 		}
 	}
 
@@ -55,106 +226,272 @@ func RunPulse() (time.Duration, error) {
 	// Catches any single broken NVLink segment, including links that do not
 	// involve GPU 0, which a star check from GPU 0 would miss entirely.
 	// Skip on single-GPU nodes where no inter-device links exist.
+	if count == 1 {
+		unchecked.skip("p2p", "single_gpu")
+	}
 	if count > 1 {
 		for i := 0; i < count; i++ {
-			if err := checkP2P(i, (i+1)%count); err != nil {
-				return worstMean, err
+			bw, err := checkP2P(i, (i+1)%count, th)
+			report.Links = append(report.Links, LinkResult{
+				Src: i, Dst: (i + 1) % count, BandwidthGBs: bw, Error: errString(err),
+			})
+			if err != nil && !thresholdFinding(err) {
+				return report, err
+			}
+			if err != nil {
+				if failErr == nil {
+					failErr = err
+				}
+				continue
+			}
+			if marginErr == nil {
+				marginErr = p2pMargin(i, (i+1)%count, bw, th)
 			}
 		}
 	}
 
-	if err := validateClocks(); err != nil {
-		return worstMean, &PulseFailure{
-			Cause:          fmt.Errorf("%w: %v", ErrStragglerDetected, err),
-			MeasuredValue:  float64(worstMean.Milliseconds()),
-			ThresholdValue: float64(stragglerThreshold.Milliseconds()),
-			Unit:           "ms",
+	// Operator-supplied checks run last, once the GPUs have been loaded.
+	if err := runExternalChecks(report, ExternalChecks(), th.ExternalCheckTimeout, &unchecked); err != nil {
+		if !thresholdFinding(err) {
+			return report, err
 		}
+		if failErr == nil {
+			failErr = err
+		}
+	}
+
+	if failErr != nil {
+		if failMean > 0 {
+			report.WorstMeanNS = failMean.Nanoseconds()
+		}
+		return report, failErr
+	}
+
+	// Stuck clocks are reset and checked again under a fresh GEMM load;
+	// the verdict of those runs is already in.
+	load := func() ([]uint64, error) {
+		reloaded := make([]uint64, count)
+		for dev := 0; dev < count; dev++ {
+			dth, _ := deviceSnapshot(th, nameOf(names, dev))
+			stop := sampleThrottle(dev, nvmlThrottleReason, queryThrottleReason)
+			_, _, _, err := runDevicePulse(dev, dth)
+			if active := stop(); active != nil {
+				reloaded[dev] = *active
+			}
+			if err != nil && !thresholdFinding(err) {
+				return nil, fmt.Errorf("reload %s after clock reset: %w", gpuLabel(dev), err)
+			}
+		}
+		return reloaded, nil
+	}
+	if err := checkClocks(report, th, &unchecked, throttle, load); err != nil {
+		return report, err
+	}
+
+	// Near-threshold and inconclusive pulses still count the devices, but
+	// neither is healthy enough to become the node's baseline. A pulse that
+	// could not check everything proves nothing, so inconclusive outranks
+	// the degraded band.
+	recordDeviceCount(StateDir(), count)
+	if err := checkUtilization(report.Devices, th.MinSMUtilization); err != nil {
+		return report, err
+	}
+	if err := unchecked.err(); err != nil {
+		return report, err
+	}
+	recordECCHistory(StateDir(), bootID(), stats)
+	if th.BaselineFactor > 0 && marginErr == nil {
+		recordBaseline(StateDir(), report)
+	}
+	return report, marginErr
+}
+
+// runMIGPipeline is the rest of the pipeline on a node with MIG enabled:
+// each instance, and each GPU without MIG, is validated in a child of its
+// own (see runMIG), then external checks run and clocks are checked. Enumeration, device count, P2P,
+// baseline, busy, utilization-floor and power checks, and throttle-reason
+// sampling, address physical devices and are skipped.
+func runMIGPipeline(report *PulseReport, th Snapshot, stats []gpuStats, targets []migTarget, observe deviceObserver, unchecked *gaps) (*PulseReport, error) {
+	report.DeviceCount = len(targets)
+	for _, check := range []string{"enumeration", "visibility", "device_count", "utilization", "power", "p2p", "throttle"} {
+		unchecked.skip(check, "mig")
+	}
+	if th.BaselineFactor > 0 {
+		unchecked.skip("baseline", "mig")
+	}
+	marginErr := runMIG(report, targets, observe, childRun(report.PulseID))
+	var failErr error
+	if marginErr != nil && !errors.Is(marginErr, ErrNearThreshold) {
+		if !thresholdFinding(marginErr) {
+			return report, marginErr
+		}
+		failErr, marginErr = marginErr, nil
+	}
+	if err := runExternalChecks(report, ExternalChecks(), th.ExternalCheckTimeout, unchecked); err != nil {
+		if !thresholdFinding(err) {
+			return report, err
+		}
+		if failErr == nil {
+			failErr = err
+		}
+	}
+	if failErr != nil {
+		return report, failErr
 	}
+	if err := checkClocks(report, th, unchecked, nil, nil); err != nil {
+		return report, err
+	}
+	if err := unchecked.err(); err != nil {
+		return report, err
+	}
+	recordECCHistory(StateDir(), bootID(), stats)
+	return report, marginErr
+}
 
-	return worstMean, nil
+// runInstance is the pipeline of a child validating one MIG instance, its
+// only visible device: the GEMM runs, judged like a whole GPU.
+func runInstance(report *PulseReport, th Snapshot, observe deviceObserver) (*PulseReport, error) {
+	report.DeviceCount = 1
+	mean, cv, runs, err := runDevicePulse(0, th)
+	observe(0, mean, cv, nil)
+	report.Devices = append(report.Devices, DeviceResult{MeanNS: mean.Nanoseconds(), CV: cv, RunsNS: runs, Error: errString(err)})
+	report.WorstMeanNS = mean.Nanoseconds()
+	if err != nil {
+		return report, err
+	}
+	return report, deviceMargin(0, mean, cv, th)
+}
+
+// checkClocks runs the post-pulse clock validation, with a clock reset and a
+// second check under load if the clocks are stuck (see validateClocksReset),
+// and records it in report. Throttled clocks are a straggler finding, and
+// carry the failing GPU and its throttle reasons.
+func checkClocks(report *PulseReport, th Snapshot, unchecked *gaps, throttle []uint64, load func() ([]uint64, error)) error {
+	reset, err := validateClocksReset(th, unchecked, throttle, load)
+	report.ClockReset = reset
+	report.Clocks = stageResult(err)
+	if err == nil || errors.Is(err, ErrStageTimeout) || errors.Is(err, ErrPCIeDegraded) {
+		return err
+	}
+	failure := &PulseFailure{
+		Cause:          fmt.Errorf("%w: %w", ErrStragglerDetected, err),
+		MeasuredValue:  float64(report.Elapsed().Milliseconds()),
+		ThresholdValue: float64(th.StragglerThreshold.Milliseconds()),
+		Unit:           "ms",
+	}
+	var clock *PulseFailure
+	if errors.As(err, &clock) {
+		failure.GPU, failure.ThrottleReasons = clock.GPU, clock.ThrottleReasons
+	}
+	return failure
 }
 
 // runDevicePulse runs pulseRuns timed GEMM passes on deviceID and returns the
-// mean duration, coefficient of variation, and any error encountered.
-func runDevicePulse(deviceID int) (mean time.Duration, cv float64, err error) {
-	durations := make([]time.Duration, pulseRuns)
+// mean duration, coefficient of variation, the duration of each completed
+// run in nanoseconds, and any error encountered. The statistics accumulate
+// as each run completes (see runStats). Each duration is device time
+// measured with CUDA events (see run_gpu_pulse), and the latency thresholds,
+// CV ceilings (archCalibrations) and jitter floor (defaultJitterFloor) are
+// calibrated for device time. Runs execute in the measurement harness (see
+// harness.go).
+func runDevicePulse(deviceID int, th Snapshot) (mean time.Duration, cv float64, runs []int64, err error) {
+	var stats runStats
+	defer pauseGC()()
+	nice := MeasureNice()
 
-	for i := range durations {
+	for i := 0; i < pulseRuns; i++ {
+		var rc C.int
+		var deviceMS C.float
 		start := time.Now()
-		rc := C.run_gpu_pulse(C.int(deviceID))
-		elapsed := time.Since(start)
+		if err := withStageTimeout("gemm_run", th.GEMMRunTimeout, func() error {
+			pinMeasurement(nice)
+			rc = C.run_gpu_pulse(C.int(deviceID), &deviceMS)
+			return nil
+		}); err != nil {
+			return time.Since(start), 0, stats.RunsNS(), fmt.Errorf("%s run %d: %w", gpuLabel(deviceID), i+1, err)
+		}
+		// Device time from CUDA events; host time only bounds the stage.
+		elapsed := time.Duration(float64(deviceMS) * float64(time.Millisecond))
 
 		switch int(rc) {
 		case int(C.GPU_PULSE_OK):
 			// ok
 		case int(C.GPU_PULSE_ERR_CUDA):
-			return elapsed, 0, fmt.Errorf("cuda error on GPU %d run %d (rc=%d)", deviceID, i+1, int(rc))
+			return elapsed, 0, stats.RunsNS(), fmt.Errorf("cuda error on %s run %d (rc=%d)", gpuLabel(deviceID), i+1, int(rc))
 		case int(C.GPU_PULSE_ERR_OOM):
-			return elapsed, 0, fmt.Errorf("out of device memory on GPU %d run %d (rc=%d)", deviceID, i+1, int(rc))
+			return elapsed, 0, stats.RunsNS(), fmt.Errorf("out of device memory on %s run %d (rc=%d)", gpuLabel(deviceID), i+1, int(rc))
 		default:
-			return elapsed, 0, fmt.Errorf("gpu_pulse returned code %d on GPU %d run %d", int(rc), deviceID, i+1)
+			return elapsed, 0, stats.RunsNS(), fmt.Errorf("gpu_pulse returned code %d on %s run %d", int(rc), gpuLabel(deviceID), i+1)
 		}
-		durations[i] = elapsed
+		stats.add(elapsed)
 	}
 
-	mean, cv = computeStats(durations)
+	mean, cv, runs = stats.Mean(), stats.CV(), stats.RunsNS()
 
-	if mean > stragglerThreshold {
-		return mean, cv, &PulseFailure{
-			Cause:          fmt.Errorf("GPU %d: %w (mean=%v)", deviceID, ErrStragglerDetected, mean),
+	if mean > th.StragglerThreshold {
+		return mean, cv, runs, &PulseFailure{
+			Cause:          fmt.Errorf("%s: %w (mean=%v)", gpuLabel(deviceID), ErrStragglerDetected, mean),
 			MeasuredValue:  float64(mean.Milliseconds()),
-			ThresholdValue: float64(stragglerThreshold.Milliseconds()),
+			ThresholdValue: float64(th.StragglerThreshold.Milliseconds()),
 			Unit:           "ms",
 		}
 	}
-	if cv > maxCoefficientOfVar {
-		return mean, cv, &PulseFailure{
-			Cause:          fmt.Errorf("GPU %d: %w (cv=%.3f)", deviceID, ErrHighVariance, cv),
+	if highVariance(mean, cv, th.MaxCV, th) {
+		return mean, cv, runs, &PulseFailure{
+			Cause:          fmt.Errorf("%s: %w (cv=%.3f, σ=%v)", gpuLabel(deviceID), ErrHighVariance, cv, time.Duration(cv*float64(mean))),
 			MeasuredValue:  cv,
-			ThresholdValue: maxCoefficientOfVar,
+			ThresholdValue: th.MaxCV,
 			Unit:           "cv",
 		}
 	}
-	return mean, cv, nil
+	return mean, cv, runs, nil
 }
 
 // checkP2P times a 100 MiB cudaMemcpyPeer from src to dst and returns
 // ErrInterconnectDegraded if the link is unavailable or bandwidth is too low.
-// Called in ring order by RunPulse.
-func checkP2P(src, dst int) error {
-	var bwGBs C.double
-	rc := C.run_p2p_check(C.int(src), C.int(dst), &bwGBs)
+// The measured bandwidth is returned for margin evaluation.
+// Called in ring order by RunPulse; each link is bounded by th.P2PLinkTimeout.
+func checkP2P(src, dst int, th Snapshot) (float64, error) {
+	var (
+		bwGBs C.double
+		rc    C.int
+	)
+	if err := withStageTimeout("p2p_link", th.P2PLinkTimeout, func() error {
+		rc = C.run_p2p_check(C.int(src), C.int(dst), &bwGBs)
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("GPU %d→%d: %w", src, dst, err)
+	}
 
 	switch int(rc) {
 	case int(C.GPU_PULSE_OK):
 		// ok — fall through to bandwidth check
 	case int(C.GPU_PULSE_ERR_P2P):
-		return &PulseFailure{
+		return 0, &PulseFailure{
 			Cause:          fmt.Errorf("GPU %d→%d: %w (peer access unavailable)", src, dst, ErrInterconnectDegraded),
 			MeasuredValue:  0,
-			ThresholdValue: minP2PBandwidthGBs,
+			ThresholdValue: th.MinP2PBandwidthGBs,
 			Unit:           "gbs",
 		}
 	default:
-		return &PulseFailure{
+		return 0, &PulseFailure{
 			Cause:          fmt.Errorf("GPU %d→%d: %w (p2p check rc=%d)", src, dst, ErrInterconnectDegraded, int(rc)),
 			MeasuredValue:  0,
-			ThresholdValue: minP2PBandwidthGBs,
+			ThresholdValue: th.MinP2PBandwidthGBs,
 			Unit:           "gbs",
 		}
 	}
 
 	bw := float64(bwGBs)
-	if bw < minP2PBandwidthGBs {
-		return &PulseFailure{
-			Cause:          fmt.Errorf("GPU %d→%d: %w (%.2f GB/s < %.1f GB/s minimum)", src, dst, ErrInterconnectDegraded, bw, minP2PBandwidthGBs),
+	if bw < th.MinP2PBandwidthGBs {
+		return bw, &PulseFailure{
+			Cause:          fmt.Errorf("GPU %d→%d: %w (%.2f GB/s < %.1f GB/s minimum)", src, dst, ErrInterconnectDegraded, bw, th.MinP2PBandwidthGBs),
 			MeasuredValue:  bw,
-			ThresholdValue: minP2PBandwidthGBs,
+			ThresholdValue: th.MinP2PBandwidthGBs,
 			Unit:           "gbs",
 		}
 	}
-	return nil
+	return bw, nil
 }
 
 // deviceCount returns the number of CUDA-visible GPUs. Returns 1 on error so
@@ -166,25 +503,3 @@ func deviceCount() int {
 	}
 	return n
 }
-
-// computeStats returns the mean duration and coefficient of variation (σ/μ).
-func computeStats(durations []time.Duration) (mean time.Duration, cv float64) {
-	var sum int64
-	for _, d := range durations {
-		sum += d.Nanoseconds()
-	}
-	meanNs := sum / int64(len(durations))
-
-	var variance float64
-	for _, d := range durations {
-		delta := float64(d.Nanoseconds() - meanNs)
-		variance += delta * delta
-	}
-	variance /= float64(len(durations))
-
-	mean = time.Duration(meanNs)
-	if meanNs > 0 {
-		cv = math.Sqrt(variance) / float64(meanNs)
-	}
-	return
-}
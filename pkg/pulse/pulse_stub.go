@@ -3,12 +3,29 @@
 package pulse
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"time"
 )
 
 // RunPulse is a stub used when building without the cuda tag.
 // Compile with -tags cuda on a GPU host to get the real implementation.
 func RunPulse() (time.Duration, error) {
-	return 0, errors.New("built without cuda support: recompile with -tags cuda")
+	return RunPulseContext(context.Background())
+}
+
+// RunPulseContext mirrors the CUDA build; it always fails.
+func RunPulseContext(ctx context.Context) (time.Duration, error) {
+	report, err := RunPulseReport(ctx)
+	return report.Elapsed(), err
+}
+
+// RunPulseReport mirrors the CUDA build; it always fails with an empty report.
+func RunPulseReport(ctx context.Context) (*PulseReport, error) {
+	id := PulseIDFrom(ctx)
+	return runPipeline(ctx, id, ExpectedGPUsFrom(ctx), observerFor(id))
+}
+
+func runPipeline(_ context.Context, pulseID string, _ int, _ deviceObserver) (*PulseReport, error) {
+	return newReport(pulseID, active.Snapshot()), fmt.Errorf("%w: recompile with -tags cuda", ErrNoCUDA)
 }
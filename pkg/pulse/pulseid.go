@@ -0,0 +1,37 @@
+package pulse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// PulseIDEnv passes the pulse ID to an isolated runner (child process or
+// runner pod) so its report carries the controller's ID.
+const PulseIDEnv = "PULSE_ID"
+
+// pulseIDKey is the context key for the current pulse ID.
+type pulseIDKey struct{}
+
+// NewPulseID returns a random 128-bit ID as 32 lowercase hex characters —
+// the W3C trace-id format, so the same value can be used as a trace ID and
+// exemplar links resolve in tracing backends without translation.
+func NewPulseID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithPulseID returns a context carrying id. The controller sets it once per
+// validation so metrics exemplars, logs and node conditions share one ID.
+func WithPulseID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, pulseIDKey{}, id)
+}
+
+// PulseIDFrom returns the pulse ID carried by ctx, or a fresh one if none.
+func PulseIDFrom(ctx context.Context) string {
+	if id, ok := ctx.Value(pulseIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return NewPulseID()
+}
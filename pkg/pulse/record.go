@@ -0,0 +1,47 @@
+package pulse
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+)
+
+// deviceObserver receives the per-device result of each GEMM pulse. util is
+// nil when utilization could not be read.
+type deviceObserver func(device int, mean time.Duration, cv float64, util *Utilization)
+
+// observeDevice records a device result to the Prometheus collectors. The
+// duration observation carries a pulse_id exemplar, so a latency spike on a
+// dashboard links straight to the logs and evidence of the pulse behind it.
+// Exemplars are only exposed when the metrics endpoint negotiates OpenMetrics.
+// The utilization gauges keep their last value when util is nil.
+func observeDevice(pulseID string, device int, mean time.Duration, cv float64, util *Utilization) {
+	devLabel := deviceLabel(device)
+	obs := metrics.PulseDuration.WithLabelValues(devLabel)
+	if eo, ok := obs.(prometheus.ExemplarObserver); ok && pulseID != "" {
+		eo.ObserveWithExemplar(mean.Seconds(), prometheus.Labels{"pulse_id": pulseID})
+	} else {
+		obs.Observe(mean.Seconds())
+	}
+	metrics.PulseCV.WithLabelValues(devLabel).Set(cv)
+	if util != nil {
+		metrics.PulseSMUtilization.WithLabelValues(devLabel).Set(util.SM)
+		metrics.PulseMemoryUtilization.WithLabelValues(devLabel).Set(util.Memory)
+	}
+}
+
+// recordSkipped counts each skipped check in metrics.ChecksSkipped.
+func recordSkipped(skipped []SkippedCheck) {
+	for _, s := range skipped {
+		metrics.ChecksSkipped.WithLabelValues(s.Check, s.Why).Inc()
+	}
+}
+
+// observerFor binds observeDevice to one pulse ID.
+func observerFor(pulseID string) deviceObserver {
+	return func(device int, mean time.Duration, cv float64, util *Utilization) {
+		observeDevice(pulseID, device, mean, cv, util)
+	}
+}
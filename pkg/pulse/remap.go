@@ -0,0 +1,102 @@
+package pulse
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// rowRemap is one device's HBM row-remapping state (Ampere and later).
+// Pending remaps take effect at the next GPU reset; a failed remap means a
+// bank has run out of spare rows.
+type rowRemap struct {
+	Correctable   int // rows remapped for correctable errors
+	Uncorrectable int // rows remapped for uncorrectable errors
+	Pending       bool
+	Failed        bool
+}
+
+// checkRowRemaps fails with ErrRowRemap on the first device with a failed
+// or pending remap. A failed remap leaves the faulty row in service; a
+// pending one leaves it in service until the GPU is reset. Remaps that have
+// already taken effect are not a failure. MeasuredValue is the device's
+// count of rows remapped for uncorrectable errors; the failure names the GPU
+// by its identity in stats when known.
+func checkRowRemaps(remaps []rowRemap, stats []gpuStats) error {
+	for i, r := range remaps {
+		var state string
+		switch {
+		case r.Failed:
+			state = "row remap failed (no spare rows left in the bank)"
+		case r.Pending:
+			state = "row remap pending until the GPU is reset"
+		default:
+			continue
+		}
+		id := identityOf(stats, i)
+		return &PulseFailure{
+			Cause: fmt.Errorf("pre-flight: %w: %s: %s; %d uncorrectable, %d correctable row(s) remapped",
+				ErrRowRemap, describeGPU(i, id), state, r.Uncorrectable, r.Correctable),
+			MeasuredValue: float64(r.Uncorrectable),
+			Unit:          "rows",
+			GPU:           id,
+		}
+	}
+	return nil
+}
+
+// queryRowRemaps returns the row-remapping state of every visible GPU, through
+// NVML when available and nvidia-smi otherwise, as queryAllGPUs does.
+// Devices without row remapping report zero values.
+func queryRowRemaps(ctx context.Context) ([]rowRemap, error) {
+	remaps, err := nvmlRowRemaps(ctx)
+	if err == nil || isDeadline(ctx, err) {
+		return remaps, err
+	}
+	out, err := exec.CommandContext(ctx,
+		"nvidia-smi",
+		"--query-remapped-rows=remapped_rows.correctable,remapped_rows.uncorrectable,remapped_rows.pending,remapped_rows.failure",
+		"--format=csv,noheader,nounits",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	return parseRemappedRows(string(out))
+}
+
+// parseRemappedRows parses nvidia-smi --query-remapped-rows CSV output, one
+// row per device in index order. Flags read as set for "1", "Yes" or "True";
+// "N/A" reads as zero.
+func parseRemappedRows(out string) ([]rowRemap, error) {
+	count := func(s string) int {
+		v, _ := strconv.Atoi(strings.TrimSpace(s))
+		return v
+	}
+	flag := func(s string) bool {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "1", "yes", "true":
+			return true
+		}
+		return false
+	}
+
+	var result []rowRemap
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ", ")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("nvidia-smi: unexpected field count in %q", line)
+		}
+		result = append(result, rowRemap{
+			Correctable:   count(fields[0]),
+			Uncorrectable: count(fields[1]),
+			Pending:       flag(fields[2]),
+			Failed:        flag(fields[3]),
+		})
+	}
+	return result, nil
+}
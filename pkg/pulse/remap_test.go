@@ -0,0 +1,58 @@
+package pulse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckRowRemaps(t *testing.T) {
+	t.Parallel()
+
+	const smi = `0, 0, No, No
+3, 1, Yes, No
+0, 2, 0, 1
+[N/A], [N/A], [N/A], [N/A]
+`
+	all, err := parseRemappedRows(smi)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("parseRemappedRows = %v, %v; want 4 devices", all, err)
+	}
+
+	cases := []struct {
+		name    string
+		remaps  []rowRemap
+		wantMsg string // "" = pass
+		wantVal float64
+	}{
+		{name: "clean and unsupported devices pass", remaps: []rowRemap{all[0], all[3]}},
+		{name: "applied remaps pass", remaps: []rowRemap{{Correctable: 5, Uncorrectable: 1}}},
+		{name: "pending remap", remaps: all[:2], wantMsg: "GPU 1: row remap pending until the GPU is reset; 1 uncorrectable, 3 correctable", wantVal: 1},
+		{name: "failed remap", remaps: []rowRemap{all[0], all[0], all[2]}, wantMsg: "GPU 2: row remap failed", wantVal: 2},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkRowRemaps(tc.remaps, nil)
+			if tc.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("checkRowRemaps = %v, want pass", err)
+				}
+				return
+			}
+			var detail *PulseFailure
+			if !errors.Is(err, ErrRowRemap) || !errors.As(err, &detail) || detail.MeasuredValue != tc.wantVal || detail.Unit != "rows" {
+				t.Fatalf("checkRowRemaps = %#v, want ErrRowRemap with %v rows", err, tc.wantVal)
+			}
+			if !strings.Contains(err.Error(), tc.wantMsg) {
+				t.Errorf("message = %q, want it to contain %q", err, tc.wantMsg)
+			}
+		})
+	}
+
+	if _, err := parseRemappedRows("0, 0, No"); err == nil {
+		t.Error("short row accepted")
+	}
+}
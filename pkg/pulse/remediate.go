@@ -0,0 +1,81 @@
+package pulse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// RemediationEnv selects the remediation tried on a failed pulse before the
+// node is quarantined; see Remediate. Unset tries none.
+const RemediationEnv = "PULSE_REMEDIATION"
+
+// Remediation actions.
+const (
+	// RemediationGPUReset resets every GPU with nvidia-smi --gpu-reset.
+	// NVLink-connected GPUs must be reset together, so no single device is
+	// targeted. It fails while any process holds a GPU.
+	RemediationGPUReset = "gpu-reset"
+	// RemediationDriverReload unloads and reloads the NVIDIA kernel
+	// modules. It needs the host's /lib/modules and fails while anything,
+	// including a persistence daemon, holds the driver open.
+	RemediationDriverReload = "driver-reload"
+)
+
+// remediationCommands are the commands each action runs, in order.
+var remediationCommands = map[string][][]string{
+	RemediationGPUReset: {{"nvidia-smi", "--gpu-reset"}},
+	RemediationDriverReload: {
+		{"modprobe", "-r", "-a", "nvidia_uvm", "nvidia_drm", "nvidia_modeset", "nvidia"},
+		{"modprobe", "-a", "nvidia", "nvidia_uvm"},
+	},
+}
+
+// Remediation records an attempt to clear a pulse failure by running an
+// action and pulsing the node again.
+type Remediation struct {
+	// Action is RemediationGPUReset or RemediationDriverReload.
+	Action string `json:"action"`
+	// Error is why the remediation gave no verdict: the action failed or
+	// the re-run was inconclusive. The report is then the first pulse's.
+	Error string `json:"error,omitempty"`
+	// FailedPulseID and FailedError are the pulse that failed first.
+	FailedPulseID string `json:"failed_pulse_id"`
+	FailedError   string `json:"failed_error"`
+}
+
+// ValidRemediation reports whether action is a known remediation.
+func ValidRemediation(action string) bool {
+	_, ok := remediationCommands[action]
+	return ok
+}
+
+// Remediate runs a remediation action on this node's GPUs. The caller must
+// make sure no pulse is running and that it holds no CUDA context itself:
+// pulses must run in a child process (subprocess isolation).
+func Remediate(ctx context.Context, action string) error {
+	cmds, ok := remediationCommands[action]
+	if !ok {
+		return fmt.Errorf("unknown remediation %q: want %s or %s", action, RemediationGPUReset, RemediationDriverReload)
+	}
+	return runCommands(ctx, cmds)
+}
+
+// runCommands runs each command in turn, stopping at the first that fails.
+// The error names the command and carries its stderr.
+func runCommands(ctx context.Context, cmds [][]string) error {
+	for _, args := range cmds {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%s: %w: %s", strings.Join(args, " "), err, msg)
+			}
+			return fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+		}
+	}
+	return nil
+}
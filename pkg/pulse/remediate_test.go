@@ -0,0 +1,27 @@
+package pulse
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRemediate(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+
+	writeCheck(t, dir, "nvidia-smi", `[ "$1" = --gpu-reset ] || exit 9`)
+	if err := Remediate(context.Background(), RemediationGPUReset); err != nil {
+		t.Errorf("gpu-reset: %v", err)
+	}
+
+	writeCheck(t, dir, "modprobe", `echo "modprobe: FATAL: Module nvidia is in use." >&2; exit 1`)
+	err := Remediate(context.Background(), RemediationDriverReload)
+	if err == nil || !strings.Contains(err.Error(), "modprobe -r -a nvidia_uvm") || !strings.Contains(err.Error(), "Module nvidia is in use") {
+		t.Errorf("driver-reload err = %v, want the failing command and its stderr", err)
+	}
+
+	if err := Remediate(context.Background(), "reboot"); err == nil || ValidRemediation("reboot") {
+		t.Error("unknown remediation accepted")
+	}
+}
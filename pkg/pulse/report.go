@@ -0,0 +1,224 @@
+package pulse
+
+import (
+	"fmt"
+	"time"
+)
+
+// PulseReport is the complete evidence of one pulse: every per-device and
+// per-link measurement, the outcome of each stage, and the thresholds they
+// were judged against. RunPulse reduces it to the worst-case mean; callers
+// that log or persist evidence use RunPulseReport instead.
+//
+// Durations are in nanoseconds to match the runner wire format, since the
+// report travels inside RunnerResult. Stages that never ran (because an
+// earlier one failed) are left nil or empty.
+type PulseReport struct {
+	PulseID     string    `json:"pulse_id"`
+	StartedAt   time.Time `json:"started_at"`
+	WorstMeanNS int64     `json:"worst_mean_ns"`
+	DeviceCount int       `json:"device_count,omitempty"`
+	Thresholds  Snapshot  `json:"thresholds"`
+
+	Preflight *StageResult   `json:"preflight,omitempty"`
+	GPUs      []GPUReading   `json:"gpus,omitempty"`
+	Devices   []DeviceResult `json:"devices,omitempty"`
+	Links     []LinkResult   `json:"links,omitempty"`
+	Clocks    *StageResult   `json:"clocks,omitempty"`
+	// External lists the operator-supplied checks run (see
+	// ExternalChecksEnv).
+	External []ExternalResult `json:"external,omitempty"`
+	// Skipped lists the checks skipped because what they need was
+	// unavailable; see SkippedCheck.
+	Skipped []SkippedCheck `json:"skipped,omitempty"`
+	// Remediation is set when a failed pulse was remediated (see
+	// Remediate) before its verdict was applied.
+	Remediation *Remediation `json:"remediation,omitempty"`
+	// ClockReset is set when stuck clocks were reset and checked again
+	// (see ClockResetEnv).
+	ClockReset *ClockReset `json:"clock_reset,omitempty"`
+	// Cooldown is set when the pre-flight check waited for hot GPUs to
+	// cool (see CooldownRetriesEnv).
+	Cooldown *Cooldown `json:"cooldown,omitempty"`
+}
+
+// StageResult is the outcome of a pass/fail stage (preflight, clock check).
+type StageResult struct {
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// GPUReading is one device's pre-flight temperature and ECC counters, with
+// the identity of the GPU behind the index. ECCUncorrected is the aggregate
+// count, ECCUncorrectedVolatile the count since the driver loaded, and
+// ECCUncorrectedFresh the count since the last boot or clean pulse that the
+// pre-flight check acts on (see qualifyECC). ECCCorrectedDelta is the growth
+// of the corrected count since the previous pulse on the node (see ecc.go);
+// zero on the first pulse.
+type GPUReading struct {
+	Device                 int `json:"device"`
+	TempC                  int `json:"temp_c"`
+	ECCUncorrected         int `json:"ecc_uncorrected,omitempty"`
+	ECCUncorrectedVolatile int `json:"ecc_uncorrected_volatile,omitempty"`
+	ECCUncorrectedFresh    int `json:"ecc_uncorrected_fresh,omitempty"`
+	ECCCorrected           int `json:"ecc_corrected,omitempty"`
+	ECCCorrectedDelta      int `json:"ecc_corrected_delta,omitempty"`
+	GPUIdentity
+}
+
+// DeviceResult is one device's GEMM timing.
+type DeviceResult struct {
+	Device int `json:"device"`
+	// MIG is the instance measured when the GPU is MIG-partitioned (see
+	// runMIG); Device is then its physical GPU.
+	MIG    *MIGInstance `json:"mig,omitempty"`
+	MeanNS int64        `json:"mean_ns"`
+	CV     float64      `json:"cv"`
+	// RunsNS are the durations of the timed GEMM runs behind MeanNS and
+	// CV, in run order, for callers that compute their own statistics. A
+	// failed run is not included.
+	RunsNS []int64 `json:"runs_ns,omitempty"`
+	// GPU identifies the physical device, when its identity was read.
+	GPU *GPUIdentity `json:"gpu,omitempty"`
+	// Thresholds are the limits the device was judged against when they
+	// differ from the node's Thresholds, on a node mixing GPU models.
+	Thresholds *DeviceThresholds `json:"thresholds,omitempty"`
+	// BaselineNS is the node baseline mean the device was judged against,
+	// if one was recorded (see baseline.go).
+	BaselineNS int64 `json:"baseline_ns,omitempty"`
+	// Contaminated marks runs measured while the host exceeded the
+	// interference limits; HostLoad is what was observed. Rerun means a
+	// variance failure on a busy host was measured a second time.
+	Contaminated bool      `json:"contaminated,omitempty"`
+	HostLoad     *HostLoad `json:"host_load,omitempty"`
+	Rerun        bool      `json:"rerun,omitempty"`
+	// Utilization is the peak observed during the runs; nil if it could
+	// not be read.
+	Utilization *Utilization `json:"utilization,omitempty"`
+	// Power is the board power during the runs; nil if it could not be
+	// read.
+	Power *Power `json:"power,omitempty"`
+	// ThrottleReasons are the clock throttle reasons seen active during
+	// the runs, gpu_idle aside; empty if none were or they could not be
+	// read.
+	ThrottleReasons []string `json:"throttle_reasons,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// SkippedCheck is one check a pulse skipped because what it needs was
+// unavailable. Check names the check; Why is one of a fixed set per check:
+//
+//	xid          kernel_log_unavailable
+//	preflight    stats_unavailable (ECC and idle temperature)
+//	row_remap    unsupported
+//	pcie_width   unsupported
+//	enumeration  nvml_unavailable, procfs_unavailable, mig
+//	visibility   capacity_unknown, mig
+//	device_count mig
+//	baseline     not_recorded, mig
+//	utilization  unavailable, mig
+//	power        unavailable, mig
+//	p2p          single_gpu, mig
+//	clocks       stats_unavailable, max_clock_unreported
+//	throttle     unavailable, mig
+//
+// A check skipped for several devices is listed once per device.
+type SkippedCheck struct {
+	Check string `json:"check"`
+	Why   string `json:"why"`
+}
+
+// LinkResult is one P2P ring segment's measured bandwidth.
+type LinkResult struct {
+	Src          int     `json:"src"`
+	Dst          int     `json:"dst"`
+	BandwidthGBs float64 `json:"bandwidth_gbs"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// ComponentFailure is one failed stage, device or link of a pulse.
+type ComponentFailure struct {
+	// Component is "preflight", "gpu<N>", a MIG instance UUID,
+	// "link<src>-<dst>", "check:<name>" for an external check, or "clocks".
+	Component string `json:"component"`
+	Error     string `json:"error"`
+	// GPU is the physical device behind a "gpu<N>" component, when known.
+	GPU *GPUIdentity `json:"gpu,omitempty"`
+}
+
+// Failures lists every failed component of the report in pipeline order.
+// A pulse measures every device and link past a threshold finding, so a
+// partial-node failure lists each bad GPU and link; a hard error ends the
+// pulse and is listed last. Nil-safe.
+func (r *PulseReport) Failures() []ComponentFailure {
+	if r == nil {
+		return nil
+	}
+	var out []ComponentFailure
+	if r.Preflight != nil && r.Preflight.Error != "" {
+		out = append(out, ComponentFailure{Component: "preflight", Error: r.Preflight.Error})
+	}
+	for _, d := range r.Devices {
+		if d.Error == "" {
+			continue
+		}
+		component := fmt.Sprintf("gpu%d", d.Device)
+		if d.MIG != nil {
+			component = d.MIG.UUID
+		}
+		out = append(out, ComponentFailure{Component: component, Error: d.Error, GPU: d.GPU})
+	}
+	for _, l := range r.Links {
+		if l.Error != "" {
+			out = append(out, ComponentFailure{Component: fmt.Sprintf("link%d-%d", l.Src, l.Dst), Error: l.Error})
+		}
+	}
+	for _, e := range r.External {
+		if e.Error != "" {
+			out = append(out, ComponentFailure{Component: "check:" + e.Name, Error: e.Error})
+		}
+	}
+	if r.Clocks != nil && r.Clocks.Error != "" {
+		out = append(out, ComponentFailure{Component: "clocks", Error: r.Clocks.Error})
+	}
+	return out
+}
+
+// gpuIdentity returns device dev's identity from the pre-flight readings,
+// or nil if it was not read.
+func (r *PulseReport) gpuIdentity(dev int) *GPUIdentity {
+	for _, g := range r.GPUs {
+		if g.Device == dev && g.GPUIdentity != (GPUIdentity{}) {
+			id := g.GPUIdentity
+			return &id
+		}
+	}
+	return nil
+}
+
+// Elapsed returns the worst-case device mean, the value RunPulse returns.
+// Nil-safe.
+func (r *PulseReport) Elapsed() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return time.Duration(r.WorstMeanNS)
+}
+
+func newReport(pulseID string, th Snapshot) *PulseReport {
+	return &PulseReport{PulseID: pulseID, StartedAt: time.Now().UTC(), Thresholds: th}
+}
+
+func stageResult(err error) *StageResult {
+	if err != nil {
+		return &StageResult{Error: err.Error()}
+	}
+	return &StageResult{Passed: true}
+}
+
+func errString(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
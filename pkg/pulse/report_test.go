@@ -0,0 +1,34 @@
+package pulse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReportFailures(t *testing.T) {
+	t.Parallel()
+
+	r := &PulseReport{
+		Preflight: &StageResult{Passed: true},
+		Devices: []DeviceResult{
+			{Device: 0},
+			{Device: 1, Error: "GPU 1: straggler detected: GPU pulse latency exceeded threshold (mean=612ms)"},
+			{Device: 2, Error: "GPU 2: straggler detected: high run-to-run variance (fail-slow pattern) (cv=0.410, σ=20ms)"},
+		},
+		Links: []LinkResult{
+			{Src: 0, Dst: 1},
+			{Src: 1, Dst: 2, Error: "GPU 1→2: straggler detected: NVLink/P2P bandwidth below threshold (3.10 GB/s < 5.0 GB/s minimum)"},
+		},
+	}
+	want := []ComponentFailure{
+		{Component: "gpu1", Error: r.Devices[1].Error},
+		{Component: "gpu2", Error: r.Devices[2].Error},
+		{Component: "link1-2", Error: r.Links[1].Error},
+	}
+	if got := r.Failures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Failures() = %+v, want %+v", got, want)
+	}
+	if got := (*PulseReport)(nil).Failures(); got != nil {
+		t.Errorf("nil report Failures() = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,81 @@
+package pulse
+
+import "errors"
+
+// riskFloor is the fraction of a limit below which a measurement adds nothing
+// to the risk score. A healthy node sits well inside its limits and should
+// score zero, not a constant offset.
+const riskFloor = 0.5
+
+// riskECCDelta is the corrected ECC growth between two pulses that on its own
+// scores 1.
+const riskECCDelta = 100
+
+// Risk returns a 0–1 estimate of how close the node is to being caught as a
+// straggler, for scheduling and capacity tools that make soft decisions:
+// prefer low-risk nodes for a long job, drain high-risk ones first. It
+// combines four signals, each scoring 0 up to riskFloor of its limit and 1 at
+// the limit:
+//
+//   - latency: the worst device mean against StragglerThreshold
+//   - variance: the highest device CV against MaxCV
+//   - temperature: the hottest pre-flight reading against MaxIdleTempC
+//   - ECC: corrected error growth since the previous pulse, against riskECCDelta
+//
+// as independent chances, 1 − ∏(1 − s), so two signals in the margin score
+// higher than either alone. A failed pulse scores 1; a near-threshold pass
+// or an inconclusive pulse is scored from its measurements. Nil-safe.
+func Risk(report *PulseReport, err error) float64 {
+	if err != nil && !errors.Is(err, ErrNearThreshold) && !errors.Is(err, ErrInconclusive) {
+		return 1
+	}
+	if report == nil {
+		return 0
+	}
+	th := report.Thresholds
+	var worstCV float64
+	for _, d := range report.Devices {
+		if d.CV > worstCV {
+			worstCV = d.CV
+		}
+	}
+	var hottest, eccDelta int
+	for _, g := range report.GPUs {
+		if g.TempC > hottest {
+			hottest = g.TempC
+		}
+		if g.ECCCorrectedDelta > eccDelta {
+			eccDelta = g.ECCCorrectedDelta
+		}
+	}
+	signals := []float64{
+		marginSignal(float64(report.WorstMeanNS), float64(th.StragglerThreshold)),
+		marginSignal(worstCV, th.MaxCV),
+		marginSignal(float64(hottest), float64(th.MaxIdleTempC)),
+		clamp01(float64(eccDelta) / riskECCDelta),
+	}
+	healthy := 1.0
+	for _, s := range signals {
+		healthy *= 1 - s
+	}
+	return 1 - healthy
+}
+
+// marginSignal scores v against limit: 0 up to riskFloor of it, rising
+// linearly to 1 at the limit. An unset limit scores 0.
+func marginSignal(v, limit float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return clamp01((v/limit - riskFloor) / (1 - riskFloor))
+}
+
+func clamp01(v float64) float64 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	}
+	return v
+}
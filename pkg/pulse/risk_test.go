@@ -0,0 +1,47 @@
+package pulse
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRisk(t *testing.T) {
+	t.Parallel()
+
+	th := Snapshot{StragglerThreshold: 20 * time.Millisecond, MaxCV: 0.2, MaxIdleTempC: 80}
+	report := func(mean time.Duration, cv float64, tempC, eccDelta int) *PulseReport {
+		return &PulseReport{
+			Thresholds:  th,
+			WorstMeanNS: mean.Nanoseconds(),
+			Devices:     []DeviceResult{{Device: 0, MeanNS: mean.Nanoseconds(), CV: cv}},
+			GPUs:        []GPUReading{{Device: 0, TempC: tempC, ECCCorrectedDelta: eccDelta}},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		report *PulseReport
+		err    error
+		want   float64
+	}{
+		{name: "healthy node scores zero", report: report(8*time.Millisecond, 0.05, 40, 0), want: 0},
+		{name: "latency at three quarters of the threshold", report: report(15*time.Millisecond, 0.05, 40, 0), want: 0.5},
+		{name: "two signals in the margin", report: report(15*time.Millisecond, 0.15, 40, 0), want: 0.75},
+		{name: "corrected ECC growth", report: report(8*time.Millisecond, 0.05, 40, 50), want: 0.5},
+		{name: "hot idle GPU", report: report(8*time.Millisecond, 0.05, 60, 0), want: 0.5},
+		{name: "near-threshold pass is scored", report: report(15*time.Millisecond, 0.05, 40, 0), err: ErrNearThreshold, want: 0.5},
+		{name: "failed pulse", report: report(8*time.Millisecond, 0.05, 40, 0), err: fmt.Errorf("GPU 0: %w", ErrStragglerDetected), want: 1},
+		{name: "nil report", want: 0},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := Risk(tc.report, tc.err); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("Risk = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
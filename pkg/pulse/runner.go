@@ -0,0 +1,170 @@
+package pulse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// ChildArg is the hidden first argument that turns the agent binary into a
+// one-shot pulse runner. main() checks for it before doing anything else and
+// hands control to ServeRunner.
+const ChildArg = "__pulse-child"
+
+// ServeRunner runs the pulse pipeline and writes its RunnerResult to w.
+// A CGO fault kills the process before anything is written, which the agent
+// detects as a missing result. The verdict is carried in the result, not the
+// exit status. The process must do nothing else: the measurement harness
+// pauses its garbage collector while devices are timed.
+func ServeRunner(w io.Writer) error {
+	runnerProcess.Store(true)
+	id := os.Getenv(PulseIDEnv)
+	if id == "" {
+		id = NewPulseID()
+	}
+	var devices []RunnerDevice
+	// The parent kills this process to cancel it.
+	report, err := runPipeline(context.Background(), id, expectedGPUsFromEnv(), func(device int, mean time.Duration, cv float64, util *Utilization) {
+		devices = append(devices, RunnerDevice{Device: device, MeanNS: mean.Nanoseconds(), CV: cv, Utilization: util})
+	})
+	res := NewRunnerResult(report.Elapsed(), devices, err)
+	res.Report = report
+	return json.NewEncoder(w).Encode(res)
+}
+
+// Runner executes the pulse in a separate process speaking the runner
+// protocol (see ipc.go), so a segfault inside libgpupulse or the CUDA runtime
+// cannot take down the agent. With an empty Path it re-executes the current
+// binary with ChildArg; otherwise it runs an external runner such as
+// cmd/pulse-runner, which lets the agent itself be built without -tags cuda.
+type Runner struct {
+	Path string
+	Args []string
+	// Env is added to the runner's environment, after the agent's own.
+	Env []string
+	// Timeout bounds the runner's whole run, on top of the per-stage
+	// timeouts inside it, so a runner wedged where no stage timeout reaches
+	// (a CUDA call that never returns, a stuck driver unload) cannot hold
+	// the node's pulse forever. Defaults to 10m.
+	Timeout time.Duration
+}
+
+func (r Runner) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return 10 * time.Minute
+}
+
+// Run has the same contract as RunPulse. A runner that dies or exits without
+// a usable result returns ErrPulseCrashed.
+func (r Runner) Run() (time.Duration, error) {
+	return r.RunContext(context.Background())
+}
+
+// RunContext is Run with the pulse ID taken from ctx for the recorded
+// metrics exemplars. The child is killed when ctx is done or Timeout runs
+// out; the latter fails the pulse with ErrStageTimeout.
+func (r Runner) RunContext(ctx context.Context) (time.Duration, error) {
+	report, err := r.RunReport(ctx)
+	return report.Elapsed(), err
+}
+
+// RunReport is RunContext returning the child's full PulseReport. The pulse
+// ID is handed to the child in PulseIDEnv so both sides report the same ID.
+// The report is never nil; for a crashed child it carries only the ID.
+func (r Runner) RunReport(ctx context.Context) (*PulseReport, error) {
+	id := PulseIDFrom(ctx)
+	start := time.Now()
+	res, err := r.run(ctx)
+	if err != nil {
+		return &PulseReport{PulseID: id, StartedAt: start.UTC(), WorstMeanNS: time.Since(start).Nanoseconds()}, err
+	}
+	res.Record(id)
+	return res.PulseReport(id), res.Err()
+}
+
+// run executes the runner and reads its result. A runner that could not be
+// started, died, or wrote no usable result returns ErrPulseCrashed; one
+// killed at Timeout returns ErrStageTimeout, and one killed because ctx is
+// done returns ctx's error.
+func (r Runner) run(ctx context.Context) (RunnerResult, error) {
+	path, args := r.Path, r.Args
+	if path == "" {
+		self, err := os.Executable()
+		if err != nil {
+			return RunnerResult{}, fmt.Errorf("resolve agent binary for pulse runner: %w", err)
+		}
+		path, args = self, []string{ChildArg}
+	}
+
+	timeout := r.timeout()
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := cappedBuffer{max: maxRunnerOutput}
+	cmd := exec.CommandContext(runCtx, path, args...)
+	cmd.Env = append(os.Environ(),
+		PulseIDEnv+"="+PulseIDFrom(ctx),
+		ExpectedGPUsEnv+"="+strconv.Itoa(ExpectedGPUsFrom(ctx)),
+	)
+	cmd.Env = append(cmd.Env, r.Env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// A killed runner can leave children holding stdout open.
+	cmd.WaitDelay = time.Second
+	runErr := cmd.Run()
+	if runCtx.Err() != nil {
+		if err := ctx.Err(); err != nil {
+			return RunnerResult{}, err
+		}
+		return RunnerResult{}, stageTimeout("pulse runner", timeout)
+	}
+	if stdout.over {
+		return RunnerResult{}, fmt.Errorf("%w: runner wrote more than %d bytes of result", ErrPulseCrashed, maxRunnerOutput)
+	}
+
+	res, err := ReadRunnerResult(&stdout.Buffer)
+	if err != nil {
+		if runErr != nil {
+			err = runErr
+		}
+		return res, fmt.Errorf("%w: %v", ErrPulseCrashed, err)
+	}
+	return res, nil
+}
+
+// maxRunnerOutput bounds the result a runner may write. A full report for an
+// eight-GPU node is a few KiB; a runner flooding its stdout is broken, and
+// must not take the agent's memory with it.
+const maxRunnerOutput = 1 << 20
+
+// cappedBuffer is a bytes.Buffer that stops growing at max bytes and drops
+// the rest, marking itself over. Writes never fail, so the runner is not
+// killed by a broken pipe before its exit status is known.
+type cappedBuffer struct {
+	bytes.Buffer
+	max  int
+	over bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); len(p) > room {
+		b.over = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// RunPulseIsolated runs the pulse in a re-executed copy of the current binary.
+// Equivalent to Runner{}.Run.
+func RunPulseIsolated() (time.Duration, error) {
+	return Runner{}.Run()
+}
@@ -0,0 +1,274 @@
+package pulse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+)
+
+func TestRunnerResultRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		err      error
+		sentinel error // nil = plain error without a sentinel
+		wantUnit string
+		wantGPU  *GPUIdentity
+		wantArch string
+	}{
+		{
+			name: "variance failure keeps sentinel and detail",
+			err: &PulseFailure{
+				Cause:          fmt.Errorf("GPU 3: %w (cv=0.412)", ErrHighVariance),
+				MeasuredValue:  0.412,
+				ThresholdValue: 0.20,
+				Unit:           "cv",
+				GPU:            &GPUIdentity{UUID: "GPU-5d5ba0d6", Serial: "1652221001234", PCIBusID: "00000000:07:00.0"},
+				Arch:           "A100",
+			},
+			sentinel: ErrHighVariance,
+			wantUnit: "cv",
+			wantGPU:  &GPUIdentity{UUID: "GPU-5d5ba0d6", Serial: "1652221001234", PCIBusID: "00000000:07:00.0"},
+			wantArch: "A100",
+		},
+		{
+			name: "ECC failure keeps sentinel and detail",
+			err: &PulseFailure{
+				Cause:         fmt.Errorf("pre-flight GPU 2: %w: 3 since last boot or clean pulse (3 aggregate)", ErrECCErrors),
+				MeasuredValue: 3,
+				Unit:          "ecc",
+			},
+			sentinel: ErrECCErrors,
+			wantUnit: "ecc",
+		},
+		{
+			name: "derated clock keeps its own sentinel",
+			err: &PulseFailure{
+				Cause:          fmt.Errorf("%w: post-pulse GPU 0: %w: SM clock 600MHz below 50%% of max 1980MHz", ErrStragglerDetected, ErrClockDerated),
+				MeasuredValue:  41,
+				ThresholdValue: 500,
+				Unit:           "ms",
+			},
+			sentinel: ErrClockDerated,
+			wantUnit: "ms",
+		},
+		{
+			name:     "stage timeout keeps sentinel",
+			err:      stageTimeout("preflight", 30*time.Second),
+			sentinel: ErrStageTimeout,
+			wantUnit: "ms",
+		},
+		{
+			name: "unclassified error stays opaque",
+			err:  errors.New("cuda error on GPU 0 run 1 (rc=1)"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var wire bytes.Buffer
+			if err := json.NewEncoder(&wire).Encode(NewRunnerResult(40*time.Millisecond, nil, tc.err)); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			res, err := ReadRunnerResult(&wire)
+			if err != nil {
+				t.Fatalf("ReadRunnerResult: %v", err)
+			}
+
+			got := res.Err()
+			if got == nil {
+				t.Fatal("Err() returned nil for a failed result")
+			}
+			if got.Error() != tc.err.Error() {
+				t.Errorf("message=%q, want %q", got.Error(), tc.err.Error())
+			}
+			if tc.sentinel != nil && !errors.Is(got, tc.sentinel) {
+				t.Errorf("errors.Is(%v) = false after round trip", tc.sentinel)
+			}
+			if tc.sentinel == nil && IsStragglerErr(got) {
+				t.Errorf("unclassified error matched IsStragglerErr")
+			}
+
+			var detail *PulseFailure
+			gotDetail := errors.As(got, &detail)
+			if gotDetail != (tc.wantUnit != "") {
+				t.Fatalf("errors.As PulseFailure = %v, want %v", gotDetail, tc.wantUnit != "")
+			}
+			if gotDetail && detail.Unit != tc.wantUnit {
+				t.Errorf("detail unit=%q, want %q", detail.Unit, tc.wantUnit)
+			}
+			if gotDetail && fmt.Sprint(detail.GPU) != fmt.Sprint(tc.wantGPU) {
+				t.Errorf("detail GPU=%v, want %v", detail.GPU, tc.wantGPU)
+			}
+			if gotDetail && detail.Arch != tc.wantArch {
+				t.Errorf("detail arch=%q, want %q", detail.Arch, tc.wantArch)
+			}
+		})
+	}
+}
+
+func TestReadRunnerResultRejectsVersionMismatch(t *testing.T) {
+	t.Parallel()
+
+	_, err := ReadRunnerResult(strings.NewReader(`{"version":99,"elapsed_ns":1}`))
+	if err == nil {
+		t.Fatal("ReadRunnerResult accepted an unknown protocol version")
+	}
+}
+
+func TestRecordAttachesPulseIDExemplar(t *testing.T) {
+	t.Parallel()
+
+	const device = 97 // unused by other tests, so the series is ours alone
+	id := NewPulseID()
+	if len(id) != 32 {
+		t.Fatalf("pulse ID %q: want 32 hex chars", id)
+	}
+
+	res := NewRunnerResult(30*time.Millisecond,
+		[]RunnerDevice{{Device: device, MeanNS: (30 * time.Millisecond).Nanoseconds(), CV: 0.01}}, nil)
+	res.Record(id)
+
+	var m dto.Metric
+	h := metrics.PulseDuration.WithLabelValues(fmt.Sprint(device)).(interface{ Write(*dto.Metric) error })
+	if err := h.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range m.GetHistogram().GetBucket() {
+		ex := b.GetExemplar()
+		if ex == nil {
+			continue
+		}
+		for _, l := range ex.GetLabel() {
+			if l.GetName() == "pulse_id" && l.GetValue() == id {
+				return
+			}
+		}
+	}
+	t.Errorf("no bucket carries exemplar pulse_id=%s", id)
+}
+
+func TestRecordCountsSkippedChecks(t *testing.T) {
+	t.Parallel()
+
+	counter := metrics.ChecksSkipped.WithLabelValues("clocks", "max_clock_unreported")
+	before := testutil.ToFloat64(counter)
+
+	res := NewRunnerResult(0, nil, nil)
+	res.Report = &PulseReport{Skipped: []SkippedCheck{
+		{Check: "clocks", Why: "max_clock_unreported"},
+		{Check: "clocks", Why: "max_clock_unreported"},
+	}}
+	res.Record(NewPulseID())
+
+	if got := testutil.ToFloat64(counter) - before; got != 2 {
+		t.Errorf("checks skipped grew by %v, want 2 (one per device)", got)
+	}
+}
+
+func TestRunnerResultCarriesReport(t *testing.T) {
+	t.Parallel()
+
+	th := validSnapshot()
+	th.ThresholdProvenance = Provenance{Source: SourceDetected, GPUName: "NVIDIA H100 80GB HBM3", Matched: "H100"}
+	sent := &PulseReport{
+		PulseID:     NewPulseID(),
+		WorstMeanNS: (9 * time.Millisecond).Nanoseconds(),
+		DeviceCount: 2,
+		Thresholds:  th,
+		Preflight:   &StageResult{Passed: true},
+		Devices: []DeviceResult{
+			{Device: 0, MeanNS: (8 * time.Millisecond).Nanoseconds(), CV: 0.01},
+			{Device: 1, MeanNS: (9 * time.Millisecond).Nanoseconds(), CV: 0.02},
+		},
+		Links: []LinkResult{{Src: 0, Dst: 1, BandwidthGBs: 180}, {Src: 1, Dst: 0, BandwidthGBs: 2.1, Error: "degraded"}},
+	}
+	res := NewRunnerResult(sent.Elapsed(), nil, nil)
+	res.Report = sent
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(res); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadRunnerResult(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := got.PulseReport("ignored")
+	if report.PulseID != sent.PulseID {
+		t.Errorf("pulse ID = %q, want the runner's %q", report.PulseID, sent.PulseID)
+	}
+	if len(report.Devices) != 2 || len(report.Links) != 2 || report.Links[1].Error != "degraded" {
+		t.Errorf("devices/links lost in transit: %+v", report)
+	}
+	if report.Thresholds != th {
+		t.Errorf("thresholds = %+v, want %+v", report.Thresholds, th)
+	}
+}
+
+func TestRunnerResultWithoutReportFallsBackToDevices(t *testing.T) {
+	t.Parallel()
+
+	res := NewRunnerResult(5*time.Millisecond, []RunnerDevice{{Device: 3, MeanNS: 5e6, CV: 0.04}}, nil)
+	report := res.PulseReport("abc")
+	if report.PulseID != "abc" || report.Elapsed() != 5*time.Millisecond {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Devices) != 1 || report.Devices[0].Device != 3 {
+		t.Errorf("devices = %+v", report.Devices)
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	t.Parallel()
+
+	b := cappedBuffer{max: 8}
+	for _, s := range []string{"abc", "defgh", "ijk"} {
+		if n, err := b.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v; want the whole write accepted", s, n, err)
+		}
+	}
+	if b.String() != "abcdefgh" || !b.over {
+		t.Errorf("buffer = %q, over %v; want the first 8 bytes and over", b.String(), b.over)
+	}
+}
+
+func TestRunnerTimeout(t *testing.T) {
+	t.Parallel()
+
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep binary")
+	}
+	r := Runner{Path: sleep, Args: []string{"60"}, Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	_, err = r.RunReport(context.Background())
+	if !errors.Is(err, ErrStageTimeout) {
+		t.Errorf("hung runner: %v, want ErrStageTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("hung runner held the pulse for %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.RunReport(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled pulse: %v, want context.Canceled", err)
+	}
+}
@@ -1,8 +1,11 @@
 package pulse
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -12,12 +15,35 @@ type gpuStats struct {
 	SMClockMHz    int
 	MaxSMClockMHz int
 	TempC         int
-	ECCErrors     int
+	// ECCErrors is the aggregate uncorrectable ECC count, kept in the
+	// InfoROM across reboots. Some drivers carry errors from long before the
+	// current failure in it, so the pre-flight check acts on FreshECC.
+	ECCErrors int
+	// VolatileECC is the uncorrectable count since the driver loaded, -1
+	// when the device does not report it.
+	VolatileECC int
+	// FreshECC is the uncorrectable count since the last boot or clean
+	// pulse; see qualifyECC.
+	FreshECC int
+	// CorrectedECC is the volatile corrected ECC count, reset by a driver
+	// reload. Corrected errors do not fail a pulse; their growth between
+	// pulses feeds the risk score (see risk.go).
+	CorrectedECC int
+	GPUIdentity
 }
 
-// DetectGPUName returns the name of GPU 0 as reported by nvidia-smi, or
-// "unknown" if nvidia-smi is unavailable. Exported for the benchmark harness.
+// errNVMLUnavailable is returned by the NVML queries when the binary was built
+// without the nvml tag or libnvidia-ml could not be loaded. Callers fall back
+// to nvidia-smi.
+var errNVMLUnavailable = errors.New("nvml unavailable")
+
+// DetectGPUName returns the name of GPU 0 as reported by NVML (nvml builds) or
+// nvidia-smi, or "unknown" if neither is available. Exported for the benchmark
+// harness.
 func DetectGPUName() string {
+	if name, err := nvmlGPUName(context.Background()); err == nil && name != "" {
+		return name
+	}
 	out, err := exec.Command(
 		"nvidia-smi", "--query-gpu=name", "--format=csv,noheader", "--id=0",
 	).Output()
@@ -33,91 +59,286 @@ func DetectGPUName() string {
 	return name
 }
 
-// detectGPUThreshold maps the detected GPU architecture to a calibrated GEMM
-// latency threshold. Thresholds are derived from nominal FP32 GEMM performance
-// on each architecture at P0 clocks with 20× headroom removed for tighter
-// detection, then rounded to the nearest 5ms for operational margin.
+// archCalibration is the calibrated GEMM latency threshold, CV ceiling and
+// power floor for one GPU architecture.
+type archCalibration struct {
+	keys             []string
+	threshold        time.Duration
+	maxCV            float64
+	minPowerFraction float64
+}
+
+// archCalibrations maps GPU architectures to calibrated limits, matched in
+// order against the GPU name after any ArchTableEnv entries. Latency
+// thresholds are derived from the device time of the pulse GEMM on each
+// architecture at P0 clocks (see runDevicePulse), with about 4× headroom.
 //
-// Architecture reference points (2048×2048 FP32 GEMM at P0):
+// Architecture reference points (2048×2048 FP32 GEMM at P0, device time):
 //
-//	A100 SXM4:  ~25ms  → threshold 100ms  (4× headroom)
-//	H100 SXM5:  ~8ms   → threshold  35ms  (4× headroom)
-//	H200:       ~7ms   → threshold  35ms  (shared with H100)
-//	B200/GB200: ~3ms   → threshold  15ms  (5× headroom; Blackwell SM counts)
+//	A100 SXM4:  ~6ms    → threshold 25ms
+//	H100 SXM5:  ~2.5ms  → threshold 10ms
+//	H200:       ~2.3ms  → threshold 10ms  (shared with H100)
+//	B200/GB200: ~1.5ms  → threshold  6ms
 //
-// Falls back to 500ms for unrecognized or unavailable hardware.
-func detectGPUThreshold() time.Duration {
-	name := strings.ToUpper(DetectGPUName())
-	switch {
-	case strings.Contains(name, "B200") || strings.Contains(name, "GB200"):
-		return 15 * time.Millisecond
-	case strings.Contains(name, "H100") || strings.Contains(name, "H200"):
-		return 35 * time.Millisecond
-	case strings.Contains(name, "A100"):
-		return 100 * time.Millisecond
-	default:
-		return 500 * time.Millisecond
+// Device time leaves out host allocation, copies and scheduling, which made
+// up most of a host-timed run, so these are about a quarter of the
+// host-time calibration they replace (100ms, 35ms and 15ms).
+//
+// CV ceilings are also for device time. A healthy GPU's device-timed runs
+// agree to within a few percent, so every ceiling sits well below the
+// host-time ones (0.20–0.35), which had to absorb launch and scheduler
+// noise. The shortest GEMM keeps a little more room, as boost-clock settling
+// is a larger share of a 1.5ms run. The jitter floor (defaultJitterFloor)
+// guards against the remaining noise.
+//
+// No built-in entry has a power floor. NVML averages the draw over about a
+// second, longer than a device's whole pulse, so the reading lags the load
+// by an amount that varies with the board. A floor is opt-in, through
+// PULSE_MIN_POWER_FRACTION or an ArchTableEnv entry calibrated on the fleet.
+var archCalibrations = []archCalibration{
+	{keys: []string{"B200", "GB200"}, threshold: 6 * time.Millisecond, maxCV: 0.15},
+	{keys: []string{"H100", "H200"}, threshold: 10 * time.Millisecond, maxCV: 0.12},
+	{keys: []string{"A100"}, threshold: 25 * time.Millisecond, maxCV: 0.10},
+}
+
+// fallbackCalibration applies to unrecognized or unavailable hardware.
+var fallbackCalibration = archCalibration{threshold: 500 * time.Millisecond, maxCV: 0.20}
+
+// ArchKeys returns the architecture keys GPU names are matched against, e.g.
+// for per-SKU settings such as PULSE_CV_MAX_H100. Keys from an ArchTableEnv
+// file come first; each key is listed once.
+func ArchKeys() []string {
+	var keys []string
+	cals, _, _ := calibrations()
+	for _, arch := range cals {
+		for _, key := range arch.keys {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// CalibratedMaxCV returns the calibrated CV ceiling for an architecture key,
+// or the fallback ceiling for an unknown one.
+func CalibratedMaxCV(arch string) float64 {
+	return calibrationOf(arch).maxCV
+}
+
+// calibrationOf returns the calibration of an architecture key, or
+// fallbackCalibration for an unknown one.
+func calibrationOf(arch string) archCalibration {
+	cals, _, _ := calibrations()
+	for _, cal := range cals {
+		if slices.Contains(cal.keys, arch) {
+			return cal
+		}
 	}
+	return fallbackCalibration
+}
+
+// detectArch maps the detected GPU name to its architecture calibration (see
+// matchArch).
+func detectArch() (archCalibration, Provenance) {
+	return matchArch(DetectGPUName())
+}
+
+// matchArch maps a GPU name to its architecture calibration: the first
+// whose key is a whole word of the upper-cased name (see hasArchKey). The
+// returned Provenance names the GPU, the architecture key that matched and the table
+// it came from, or has Source SourceFallback with fallbackCalibration.
+func matchArch(gpu string) (archCalibration, Provenance) {
+	p := Provenance{Source: SourceDetected, GPUName: gpu, ResolvedAt: time.Now().UTC()}
+	name := strings.ToUpper(gpu)
+	cals, table, n := calibrations()
+	for i, arch := range cals {
+		for _, key := range arch.keys {
+			if hasArchKey(name, key) {
+				p.Matched = key
+				if i < n {
+					p.Table = table
+				}
+				return arch, p
+			}
+		}
+	}
+	p.Source = SourceFallback
+	return fallbackCalibration, p
+}
+
+// hasArchKey reports whether key occurs in name with no letter or digit on
+// either side, so "H20" does not match "NVIDIA H200" and "L40" does not
+// match "NVIDIA L40S", while "H100" matches "NVIDIA H100-SXM5-80GB".
+func hasArchKey(name, key string) bool {
+	for i := 0; ; {
+		j := strings.Index(name[i:], key)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(key)
+		if (start == 0 || !isAlnum(name[start-1])) && (end == len(name) || !isAlnum(name[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isAlnum(b byte) bool {
+	return 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9'
 }
 
 // preflight checks every visible GPU for hard disqualifiers before the pulse
-// workload runs. Returns a non-nil error on the first device that has:
-//   - Uncorrectable ECC errors since last boot (bad HBM — no pulse needed)
-//   - Idle temperature above maxIdleTempC (thermal recovery not complete)
+// workload runs. Returns a non-nil error if the kernel log shows a hardware
+// XID since boot (ErrXIDEvent), or on the first device that has:
+//   - Uncorrectable ECC errors since the last boot or clean pulse
+//     (ErrECCErrors; bad HBM — no pulse needed; see qualifyECC)
+//   - Idle temperature above th.MaxIdleTempC (ErrIdleTemperature)
+//   - A PCIe link narrower than it can train (ErrPCIeDegraded)
+//   - A failed or pending HBM row remap (ErrRowRemap)
 //
-// Proceeds silently if nvidia-smi is unavailable.
-func preflight() error {
-	stats, err := queryAllSMI()
+// The per-device readings are returned for the report, nil if they were
+// never taken. If neither NVML nor nvidia-smi can read them the pulse goes
+// ahead with the gap recorded in g, so it cannot pass unchecked. Proceeds
+// silently if the kernel log cannot be read. A hung query is abandoned after
+// th.PreflightTimeout and reported as ErrStageTimeout.
+func preflight(th Snapshot, g *gaps) ([]gpuStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), th.PreflightTimeout)
+	defer cancel()
+
+	if codes := xidCodes(); len(codes) > 0 {
+		log, err := kernelLog(ctx)
+		if isDeadline(ctx, err) {
+			return nil, stageTimeout("preflight", th.PreflightTimeout)
+		}
+		if err != nil {
+			g.skip("xid", "kernel_log_unavailable")
+		} else {
+			if err := checkXIDs(log, codes); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	stats, err := queryAllGPUs(ctx)
+	if isDeadline(ctx, err) {
+		return nil, stageTimeout("preflight", th.PreflightTimeout)
+	}
 	if err != nil {
-		return nil // nvidia-smi absent or GPU not yet visible — proceed to pulse
+		g.add("pre-flight GPU stats unavailable (%v)", err)
+		g.skip("preflight", "stats_unavailable")
+		return nil, nil
 	}
 
+	qualifyECC(StateDir(), bootID(), stats)
 	for i, s := range stats {
 		// Uncorrectable ECC errors indicate HBM instability. Per NVIDIA docs,
-		// >8 per bank triggers row remapping; any nonzero count post-reboot
+		// >8 per bank triggers row remapping; any new count post-reboot
 		// means the device had memory faults during the failure event.
-		if s.ECCErrors > 0 {
-			return fmt.Errorf("pre-flight GPU %d: %d uncorrectable ECC error(s) since last boot — quarantining without pulse", i, s.ECCErrors)
+		if s.FreshECC > 0 {
+			id := identityOf(stats, i)
+			return stats, &PulseFailure{
+				Cause:         fmt.Errorf("pre-flight %s: %w: %d since last boot or clean pulse (%d aggregate) — quarantining without pulse", describeGPU(i, id), ErrECCErrors, s.FreshECC, s.ECCErrors),
+				MeasuredValue: float64(s.FreshECC),
+				Unit:          "ecc",
+				GPU:           id,
+			}
 		}
-		if s.TempC > maxIdleTempC {
-			return fmt.Errorf("pre-flight GPU %d: idle temperature %d°C exceeds %d°C threshold (thermal recovery incomplete)", i, s.TempC, maxIdleTempC)
+		if s.TempC > th.MaxIdleTempC {
+			return stats, fmt.Errorf("pre-flight %s: %w: %d°C exceeds %d°C (thermal recovery incomplete)", describeGPU(i, identityOf(stats, i)), ErrIdleTemperature, s.TempC, th.MaxIdleTempC)
 		}
 	}
-	return nil
+
+	links, err := queryPCIeLinks(ctx)
+	if isDeadline(ctx, err) {
+		return stats, stageTimeout("preflight", th.PreflightTimeout)
+	}
+	if err != nil {
+		g.skip("pcie_width", "unsupported")
+	} else if err := checkPCIeLinks("pre-flight", links, stats); err != nil {
+		return stats, err
+	}
+
+	remaps, err := queryRowRemaps(ctx)
+	if isDeadline(ctx, err) {
+		return stats, stageTimeout("preflight", th.PreflightTimeout)
+	}
+	if err != nil {
+		// Row remapping not reported (pre-Ampere or older driver).
+		g.skip("row_remap", "unsupported")
+		return stats, nil
+	}
+	return stats, checkRowRemaps(remaps, stats)
 }
 
 // validateClocks queries all GPUs after the pulse workload to confirm each
 // reached P0 under load. Catches the "clock speed stickiness" failure mode
-// where clocks remain derated after a thermal event.
-func validateClocks() error {
-	stats, err := queryAllSMI()
+// where clocks remain derated after a thermal event (ErrClockDerated).
+// throttle holds each GPU's throttle reasons sampled during its runs (see
+// sampleThrottle), zero where none were read. A clock below the floor is
+// reported with them, and the hardware thermal slowdown fails the check even
+// when the clock clears the floor (errThermalSlowdown). A failure is a
+// PulseFailure carrying the GPU and its reasons, with no measurement of its
+// own. Clocks that cannot be read are recorded in g. Bounded by
+// th.ClockCheckTimeout.
+func validateClocks(th Snapshot, g *gaps, throttle []uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), th.ClockCheckTimeout)
+	defer cancel()
+
+	stats, err := queryAllGPUs(ctx)
+	if isDeadline(ctx, err) {
+		return stageTimeout("clock_check", th.ClockCheckTimeout)
+	}
 	if err != nil {
-		return nil // degrade gracefully
+		g.add("post-pulse clocks unavailable (%v)", err)
+		g.skip("clocks", "stats_unavailable")
+		return nil
 	}
-
 	for i, s := range stats {
+		active := throttleAt(throttle, i)
+		id := identityOf(stats, i)
+		var cause error
 		if s.MaxSMClockMHz == 0 {
-			continue // driver did not report max clock
-		}
-		threshold := int(float64(s.MaxSMClockMHz) * minClockFraction)
-		if s.SMClockMHz < threshold {
-			return fmt.Errorf(
-				"post-pulse GPU %d: SM clock %dMHz below %.0f%% of max %dMHz — stuck in power-derated state under load",
-				i, s.SMClockMHz, minClockFraction*100, s.MaxSMClockMHz,
+			g.skip("clocks", "max_clock_unreported")
+		} else if threshold := int(float64(s.MaxSMClockMHz) * th.MinClockFraction); s.SMClockMHz < threshold {
+			cause = fmt.Errorf(
+				"post-pulse %s: %w: SM clock %dMHz below %.0f%% of max %dMHz — stuck in power-derated state under load%s",
+				describeGPU(i, id), ErrClockDerated, s.SMClockMHz, th.MinClockFraction*100, s.MaxSMClockMHz, throttleSuffix(active),
 			)
 		}
+		if cause == nil && active&throttleHWThermalSlowdown != 0 {
+			cause = fmt.Errorf("post-pulse %s: %w: %w under load, SM clock now %dMHz of max %dMHz%s",
+				describeGPU(i, id), ErrClockDerated, errThermalSlowdown, s.SMClockMHz, s.MaxSMClockMHz, throttleSuffix(active))
+		}
+		if cause != nil {
+			return &PulseFailure{Cause: cause, GPU: id, ThrottleReasons: throttleReasons(active)}
+		}
 	}
 	return nil
 }
 
+// queryAllGPUs returns stats for every visible GPU, read through libnvidia-ml
+// when the binary is built with the nvml tag and the library loads, and from
+// nvidia-smi otherwise. NVML avoids a process spawn per stage and the CSV
+// parsing that comes with it. A deadline is not retried through nvidia-smi —
+// the stage is already out of time.
+func queryAllGPUs(ctx context.Context) ([]gpuStats, error) {
+	stats, err := nvmlQueryAll(ctx)
+	if err == nil || isDeadline(ctx, err) {
+		return stats, err
+	}
+	return queryAllSMI(ctx)
+}
+
 // queryAllSMI returns stats for every visible GPU. The nvidia-smi output
 // without --id returns one CSV row per device in ascending device order.
 // In a DaemonSet the container sees only its assigned GPUs via the device
 // plugin, so this always reflects the actual local device topology.
-func queryAllSMI() ([]gpuStats, error) {
-	out, err := exec.Command(
+// The process is killed when ctx expires.
+func queryAllSMI(ctx context.Context) ([]gpuStats, error) {
+	out, err := exec.CommandContext(ctx,
 		"nvidia-smi",
-		"--query-gpu=clocks.sm,clocks.max.sm,temperature.gpu,ecc.errors.uncorrected.aggregate.total",
+		"--query-gpu=clocks.sm,clocks.max.sm,temperature.gpu,ecc.errors.uncorrected.aggregate.total,ecc.errors.corrected.volatile.total,uuid,serial,pci.bus_id,ecc.errors.uncorrected.volatile.total",
 		"--format=csv,noheader,nounits",
 		// no --id: query all visible devices
 	).Output()
@@ -133,6 +354,21 @@ func queryAllSMI() ([]gpuStats, error) {
 		v, _ := strconv.Atoi(s)
 		return v
 	}
+	// parseCount is parse with -1 for a counter the device does not report.
+	parseCount := func(s string) int {
+		s = strings.TrimSpace(s)
+		if s == "N/A" || s == "[N/A]" || s == "[Not Supported]" {
+			return -1
+		}
+		return parse(s)
+	}
+	parseString := func(s string) string {
+		s = strings.TrimSpace(s)
+		if s == "N/A" || s == "[N/A]" {
+			return ""
+		}
+		return s
+	}
 
 	var result []gpuStats
 	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
@@ -140,7 +376,7 @@ func queryAllSMI() ([]gpuStats, error) {
 			continue
 		}
 		fields := strings.Split(line, ", ")
-		if len(fields) != 4 {
+		if len(fields) != 9 {
 			return nil, fmt.Errorf("nvidia-smi: unexpected field count in %q", line)
 		}
 		result = append(result, gpuStats{
@@ -148,6 +384,13 @@ func queryAllSMI() ([]gpuStats, error) {
 			MaxSMClockMHz: parse(fields[1]),
 			TempC:         parse(fields[2]),
 			ECCErrors:     parse(fields[3]),
+			CorrectedECC:  parse(fields[4]),
+			VolatileECC:   parseCount(fields[8]),
+			GPUIdentity: GPUIdentity{
+				UUID:     parseString(fields[5]),
+				Serial:   parseString(fields[6]),
+				PCIBusID: parseString(fields[7]),
+			},
 		})
 	}
 	return result, nil
@@ -0,0 +1,371 @@
+package pulse
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Snapshot is a consistent set of pulse thresholds. Each pulse takes one
+// Snapshot at the start and judges every device, link and stage against it,
+// so a concurrent update cannot mix old and new limits within one verdict.
+type Snapshot struct {
+	// StragglerThreshold is the mean GEMM latency ceiling per device.
+	StragglerThreshold time.Duration
+	// ThresholdProvenance records how StragglerThreshold was chosen.
+	ThresholdProvenance Provenance
+	// MaxCV is the coefficient-of-variation ceiling across runs on a device.
+	MaxCV float64
+	// BaselineFactor fails a device whose mean exceeds this multiple of the
+	// node's own recorded baseline (see baseline.go), on top of
+	// StragglerThreshold. Zero disables the baseline.
+	BaselineFactor float64
+	// JitterFloor is the run-to-run standard deviation below which a device's
+	// CV is ignored. On fast GPUs timing noise of tens of microseconds alone
+	// can push CV over MaxCV. Zero judges CV alone.
+	JitterFloor time.Duration
+	// MaxHostLoad and MaxCPUSteal mark a device's runs as contaminated when
+	// the host's 1-minute load average per CPU, or the fraction of CPU time
+	// stolen by the hypervisor, exceeds them (see interference.go). Zero
+	// disables each check.
+	MaxHostLoad float64
+	MaxCPUSteal float64
+	// MinP2PBandwidthGBs is the minimum NVLink/PCIe P2P bandwidth per link.
+	MinP2PBandwidthGBs float64
+	// MaxIdleTempC is the GPU temperature ceiling at pre-flight.
+	MaxIdleTempC int
+	// MinClockFraction is the post-pulse SM clock floor as a fraction of max.
+	MinClockFraction float64
+	// MinSMUtilization is the peak SM utilization, as a fraction, every
+	// sampled device must reach during its GEMM runs; a pulse that passes
+	// below it fails with ErrInconclusive. Zero disables the check.
+	MinSMUtilization float64
+	// MinPowerFraction is the board power, as a fraction of its default
+	// limit, every sampled device must draw at its peak during its GEMM
+	// runs; below it the device fails with ErrLowPower. Zero disables the
+	// check.
+	MinPowerFraction float64
+	// DegradedFraction defines the degraded band: a passing measurement
+	// beyond this fraction of its limit (latency or CV above it, P2P bandwidth
+	// below min/fraction) is reported as ErrNearThreshold.
+	DegradedFraction float64
+
+	// Per-stage timeouts. Each stage has its own budget so a hung
+	// nvidia-smi does not mask a healthy GEMM (or vice versa); a stage that
+	// overruns fails with ErrStageTimeout naming the stage.
+	PreflightTimeout  time.Duration
+	GEMMRunTimeout    time.Duration
+	P2PLinkTimeout    time.Duration
+	ClockCheckTimeout time.Duration
+	// ExternalCheckTimeout bounds each operator-supplied check (see
+	// ExternalChecksEnv).
+	ExternalCheckTimeout time.Duration
+}
+
+// Validate rejects values that would make every pulse pass or fail
+// regardless of the hardware.
+func (s Snapshot) Validate() error {
+	switch {
+	case s.StragglerThreshold <= 0:
+		return fmt.Errorf("straggler threshold must be positive, got %v", s.StragglerThreshold)
+	case s.MaxCV <= 0:
+		return fmt.Errorf("max CV must be positive, got %v", s.MaxCV)
+	case s.BaselineFactor != 0 && s.BaselineFactor <= 1:
+		return fmt.Errorf("baseline factor must be above 1 or 0 (off), got %v", s.BaselineFactor)
+	case s.JitterFloor < 0:
+		return fmt.Errorf("jitter floor must not be negative, got %v", s.JitterFloor)
+	case s.MaxHostLoad < 0:
+		return fmt.Errorf("max host load must not be negative, got %v", s.MaxHostLoad)
+	case s.MaxCPUSteal < 0 || s.MaxCPUSteal > 1:
+		return fmt.Errorf("max CPU steal must be in [0,1], got %v", s.MaxCPUSteal)
+	case s.MinP2PBandwidthGBs <= 0:
+		return fmt.Errorf("min P2P bandwidth must be positive, got %v", s.MinP2PBandwidthGBs)
+	case s.MaxIdleTempC <= 0:
+		return fmt.Errorf("max idle temperature must be positive, got %d", s.MaxIdleTempC)
+	case s.MinClockFraction <= 0 || s.MinClockFraction > 1:
+		return fmt.Errorf("min clock fraction must be in (0,1], got %v", s.MinClockFraction)
+	case s.MinSMUtilization < 0 || s.MinSMUtilization > 1:
+		return fmt.Errorf("min SM utilization must be in [0,1], got %v", s.MinSMUtilization)
+	case s.MinPowerFraction < 0 || s.MinPowerFraction > 1:
+		return fmt.Errorf("min power fraction must be in [0,1], got %v", s.MinPowerFraction)
+	case s.DegradedFraction <= 0 || s.DegradedFraction > 1:
+		return fmt.Errorf("degraded fraction must be in (0,1], got %v", s.DegradedFraction)
+	case s.PreflightTimeout <= 0 || s.GEMMRunTimeout <= 0 || s.P2PLinkTimeout <= 0 || s.ClockCheckTimeout <= 0 || s.ExternalCheckTimeout <= 0:
+		return fmt.Errorf("stage timeouts must be positive")
+	}
+	return nil
+}
+
+// snapshotJSON is the wire form of Snapshot: durations in milliseconds so
+// reports read the same units as PulseFailure.
+type snapshotJSON struct {
+	StragglerThresholdMS   int64      `json:"straggler_threshold_ms"`
+	ThresholdProvenance    Provenance `json:"threshold_provenance"`
+	MaxCV                  float64    `json:"max_cv"`
+	BaselineFactor         float64    `json:"baseline_factor,omitempty"`
+	JitterFloorMS          float64    `json:"jitter_floor_ms"`
+	MaxHostLoad            float64    `json:"max_host_load"`
+	MaxCPUSteal            float64    `json:"max_cpu_steal"`
+	MinP2PBandwidthGBs     float64    `json:"min_p2p_bandwidth_gbs"`
+	MaxIdleTempC           int        `json:"max_idle_temp_c"`
+	MinClockFraction       float64    `json:"min_clock_fraction"`
+	MinSMUtilization       float64    `json:"min_sm_utilization,omitempty"`
+	MinPowerFraction       float64    `json:"min_power_fraction,omitempty"`
+	DegradedFraction       float64    `json:"degraded_fraction"`
+	PreflightTimeoutMS     int64      `json:"preflight_timeout_ms"`
+	GEMMRunTimeoutMS       int64      `json:"gemm_run_timeout_ms"`
+	P2PLinkTimeoutMS       int64      `json:"p2p_link_timeout_ms"`
+	ClockCheckTimeoutMS    int64      `json:"clock_check_timeout_ms"`
+	ExternalCheckTimeoutMS int64      `json:"external_check_timeout_ms,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshotJSON{
+		StragglerThresholdMS:   s.StragglerThreshold.Milliseconds(),
+		ThresholdProvenance:    s.ThresholdProvenance,
+		MaxCV:                  s.MaxCV,
+		BaselineFactor:         s.BaselineFactor,
+		JitterFloorMS:          float64(s.JitterFloor) / float64(time.Millisecond),
+		MaxHostLoad:            s.MaxHostLoad,
+		MaxCPUSteal:            s.MaxCPUSteal,
+		MinP2PBandwidthGBs:     s.MinP2PBandwidthGBs,
+		MaxIdleTempC:           s.MaxIdleTempC,
+		MinClockFraction:       s.MinClockFraction,
+		MinSMUtilization:       s.MinSMUtilization,
+		MinPowerFraction:       s.MinPowerFraction,
+		DegradedFraction:       s.DegradedFraction,
+		PreflightTimeoutMS:     s.PreflightTimeout.Milliseconds(),
+		GEMMRunTimeoutMS:       s.GEMMRunTimeout.Milliseconds(),
+		P2PLinkTimeoutMS:       s.P2PLinkTimeout.Milliseconds(),
+		ClockCheckTimeoutMS:    s.ClockCheckTimeout.Milliseconds(),
+		ExternalCheckTimeoutMS: s.ExternalCheckTimeout.Milliseconds(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reading the MarshalJSON form.
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var j snapshotJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*s = Snapshot{
+		StragglerThreshold:   time.Duration(j.StragglerThresholdMS) * time.Millisecond,
+		ThresholdProvenance:  j.ThresholdProvenance,
+		MaxCV:                j.MaxCV,
+		BaselineFactor:       j.BaselineFactor,
+		JitterFloor:          time.Duration(j.JitterFloorMS * float64(time.Millisecond)),
+		MaxHostLoad:          j.MaxHostLoad,
+		MaxCPUSteal:          j.MaxCPUSteal,
+		MinP2PBandwidthGBs:   j.MinP2PBandwidthGBs,
+		MaxIdleTempC:         j.MaxIdleTempC,
+		MinClockFraction:     j.MinClockFraction,
+		MinSMUtilization:     j.MinSMUtilization,
+		MinPowerFraction:     j.MinPowerFraction,
+		DegradedFraction:     j.DegradedFraction,
+		PreflightTimeout:     time.Duration(j.PreflightTimeoutMS) * time.Millisecond,
+		GEMMRunTimeout:       time.Duration(j.GEMMRunTimeoutMS) * time.Millisecond,
+		P2PLinkTimeout:       time.Duration(j.P2PLinkTimeoutMS) * time.Millisecond,
+		ClockCheckTimeout:    time.Duration(j.ClockCheckTimeoutMS) * time.Millisecond,
+		ExternalCheckTimeout: time.Duration(j.ExternalCheckTimeoutMS) * time.Millisecond,
+	}
+	return nil
+}
+
+// LogValue implements slog.LogValuer so a Snapshot logs as a flat group.
+func (s Snapshot) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int64("straggler_threshold_ms", s.StragglerThreshold.Milliseconds()),
+		slog.String("threshold_source", string(s.ThresholdProvenance.Source)),
+		slog.String("threshold_gpu", s.ThresholdProvenance.GPUName),
+		slog.String("threshold_matched", s.ThresholdProvenance.Matched),
+		slog.String("threshold_table", s.ThresholdProvenance.Table),
+		slog.Float64("max_cv", s.MaxCV),
+		slog.Float64("baseline_factor", s.BaselineFactor),
+		slog.Duration("jitter_floor", s.JitterFloor),
+		slog.Float64("max_host_load", s.MaxHostLoad),
+		slog.Float64("max_cpu_steal", s.MaxCPUSteal),
+		slog.Float64("min_p2p_bandwidth_gbs", s.MinP2PBandwidthGBs),
+		slog.Int("max_idle_temp_c", s.MaxIdleTempC),
+		slog.Float64("min_clock_fraction", s.MinClockFraction),
+		slog.Float64("min_sm_utilization", s.MinSMUtilization),
+		slog.Float64("min_power_fraction", s.MinPowerFraction),
+		slog.Float64("degraded_fraction", s.DegradedFraction),
+		slog.Duration("preflight_timeout", s.PreflightTimeout),
+		slog.Duration("gemm_run_timeout", s.GEMMRunTimeout),
+		slog.Duration("p2p_link_timeout", s.P2PLinkTimeout),
+		slog.Duration("clock_check_timeout", s.ClockCheckTimeout),
+		slog.Duration("external_check_timeout", s.ExternalCheckTimeout),
+	)
+}
+
+// Config holds the thresholds the pulse runs against. It is safe for
+// concurrent use; setters validate and reject values that Validate would.
+type Config struct {
+	mu sync.RWMutex
+	s  Snapshot
+}
+
+// NewConfig returns a Config initialised to s. s is not validated, so the
+// env defaults always produce a usable Config.
+func NewConfig(s Snapshot) *Config {
+	return &Config{s: s}
+}
+
+// Snapshot returns a copy of the current thresholds.
+func (c *Config) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.s
+}
+
+// Set replaces every threshold at once, e.g. on a config hot-reload. A
+// Snapshot without ThresholdProvenance is recorded as a runtime change.
+func (c *Config) Set(s Snapshot) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.ThresholdProvenance.Source == "" {
+		s.ThresholdProvenance = runtimeProvenance()
+	}
+	c.mu.Lock()
+	c.s = s
+	c.mu.Unlock()
+	return nil
+}
+
+// update applies fn to a copy of the thresholds and stores it if valid.
+func (c *Config) update(fn func(*Snapshot)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.s
+	fn(&s)
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	c.s = s
+	return nil
+}
+
+// StragglerThreshold returns the mean GEMM latency ceiling per device.
+func (c *Config) StragglerThreshold() time.Duration { return c.Snapshot().StragglerThreshold }
+
+// SetStragglerThreshold sets the mean GEMM latency ceiling per device and
+// records the change as SourceRuntime.
+func (c *Config) SetStragglerThreshold(d time.Duration) error {
+	return c.update(func(s *Snapshot) {
+		s.StragglerThreshold = d
+		cvSource := s.ThresholdProvenance.MaxCVSource
+		s.ThresholdProvenance = runtimeProvenance()
+		s.ThresholdProvenance.MaxCVSource = cvSource
+	})
+}
+
+// ThresholdProvenance returns how the latency threshold was chosen.
+func (c *Config) ThresholdProvenance() Provenance { return c.Snapshot().ThresholdProvenance }
+
+// MaxCV returns the coefficient-of-variation ceiling.
+func (c *Config) MaxCV() float64 { return c.Snapshot().MaxCV }
+
+// SetMaxCV sets the coefficient-of-variation ceiling and records its source
+// as SourceRuntime.
+func (c *Config) SetMaxCV(v float64) error {
+	return c.update(func(s *Snapshot) {
+		s.MaxCV = v
+		s.ThresholdProvenance.MaxCVSource = SourceRuntime
+	})
+}
+
+// BaselineFactor returns the allowed regression over the node baseline.
+func (c *Config) BaselineFactor() float64 { return c.Snapshot().BaselineFactor }
+
+// SetBaselineFactor sets the allowed regression over the node baseline; zero
+// disables the baseline check.
+func (c *Config) SetBaselineFactor(v float64) error {
+	return c.update(func(s *Snapshot) { s.BaselineFactor = v })
+}
+
+// JitterFloor returns the standard deviation below which CV is ignored.
+func (c *Config) JitterFloor() time.Duration { return c.Snapshot().JitterFloor }
+
+// SetJitterFloor sets the standard deviation below which CV is ignored; zero
+// disables the floor.
+func (c *Config) SetJitterFloor(d time.Duration) error {
+	return c.update(func(s *Snapshot) { s.JitterFloor = d })
+}
+
+// InterferenceLimits returns the host load and CPU steal above which a
+// device's runs count as contaminated.
+func (c *Config) InterferenceLimits() (maxLoad, maxSteal float64) {
+	s := c.Snapshot()
+	return s.MaxHostLoad, s.MaxCPUSteal
+}
+
+// SetInterferenceLimits sets the host load per CPU and CPU steal fraction
+// above which a device's runs count as contaminated; zero disables either.
+func (c *Config) SetInterferenceLimits(maxLoad, maxSteal float64) error {
+	return c.update(func(s *Snapshot) { s.MaxHostLoad, s.MaxCPUSteal = maxLoad, maxSteal })
+}
+
+// MinP2PBandwidthGBs returns the minimum P2P bandwidth per link.
+func (c *Config) MinP2PBandwidthGBs() float64 { return c.Snapshot().MinP2PBandwidthGBs }
+
+// SetMinP2PBandwidthGBs sets the minimum P2P bandwidth per link.
+func (c *Config) SetMinP2PBandwidthGBs(v float64) error {
+	return c.update(func(s *Snapshot) { s.MinP2PBandwidthGBs = v })
+}
+
+// MaxIdleTempC returns the pre-flight temperature ceiling.
+func (c *Config) MaxIdleTempC() int { return c.Snapshot().MaxIdleTempC }
+
+// SetMaxIdleTempC sets the pre-flight temperature ceiling.
+func (c *Config) SetMaxIdleTempC(v int) error {
+	return c.update(func(s *Snapshot) { s.MaxIdleTempC = v })
+}
+
+// MinClockFraction returns the post-pulse SM clock floor.
+func (c *Config) MinClockFraction() float64 { return c.Snapshot().MinClockFraction }
+
+// SetMinClockFraction sets the post-pulse SM clock floor.
+func (c *Config) SetMinClockFraction(v float64) error {
+	return c.update(func(s *Snapshot) { s.MinClockFraction = v })
+}
+
+// MinSMUtilization returns the peak SM utilization floor.
+func (c *Config) MinSMUtilization() float64 { return c.Snapshot().MinSMUtilization }
+
+// SetMinSMUtilization sets the peak SM utilization floor; zero disables it.
+func (c *Config) SetMinSMUtilization(v float64) error {
+	return c.update(func(s *Snapshot) { s.MinSMUtilization = v })
+}
+
+// MinPowerFraction returns the power floor under load.
+func (c *Config) MinPowerFraction() float64 { return c.Snapshot().MinPowerFraction }
+
+// SetMinPowerFraction sets the power floor under load; zero disables it.
+func (c *Config) SetMinPowerFraction(v float64) error {
+	return c.update(func(s *Snapshot) { s.MinPowerFraction = v })
+}
+
+// DegradedFraction returns the degraded band fraction.
+func (c *Config) DegradedFraction() float64 { return c.Snapshot().DegradedFraction }
+
+// SetDegradedFraction sets the degraded band fraction.
+func (c *Config) SetDegradedFraction(v float64) error {
+	return c.update(func(s *Snapshot) { s.DegradedFraction = v })
+}
+
+// SetExternalCheckTimeout sets the timeout for each external check.
+func (c *Config) SetExternalCheckTimeout(v time.Duration) error {
+	return c.update(func(s *Snapshot) { s.ExternalCheckTimeout = v })
+}
+
+// SetStageTimeouts sets the four built-in per-stage timeouts.
+func (c *Config) SetStageTimeouts(preflight, gemmRun, p2pLink, clockCheck time.Duration) error {
+	return c.update(func(s *Snapshot) {
+		s.PreflightTimeout, s.GEMMRunTimeout = preflight, gemmRun
+		s.P2PLinkTimeout, s.ClockCheckTimeout = p2pLink, clockCheck
+	})
+}
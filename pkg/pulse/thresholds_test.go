@@ -0,0 +1,157 @@
+package pulse
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validSnapshot() Snapshot {
+	return Snapshot{
+		StragglerThreshold:   35 * time.Millisecond,
+		MaxCV:                0.20,
+		MinP2PBandwidthGBs:   5.0,
+		MaxIdleTempC:         70,
+		MinClockFraction:     0.5,
+		DegradedFraction:     0.8,
+		PreflightTimeout:     30 * time.Second,
+		GEMMRunTimeout:       60 * time.Second,
+		P2PLinkTimeout:       30 * time.Second,
+		ClockCheckTimeout:    30 * time.Second,
+		ExternalCheckTimeout: 5 * time.Minute,
+	}
+}
+
+func TestConfigSetters(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		set     func(*Config) error
+		wantErr bool
+	}{
+		{"threshold", func(c *Config) error { return c.SetStragglerThreshold(100 * time.Millisecond) }, false},
+		{"zero threshold rejected", func(c *Config) error { return c.SetStragglerThreshold(0) }, true},
+		{"cv", func(c *Config) error { return c.SetMaxCV(0.1) }, false},
+		{"baseline factor", func(c *Config) error { return c.SetBaselineFactor(1.5) }, false},
+		{"baseline factor of 1 rejected", func(c *Config) error { return c.SetBaselineFactor(1) }, true},
+		{"jitter floor off", func(c *Config) error { return c.SetJitterFloor(0) }, false},
+		{"negative jitter floor rejected", func(c *Config) error { return c.SetJitterFloor(-time.Millisecond) }, true},
+		{"interference limits off", func(c *Config) error { return c.SetInterferenceLimits(0, 0) }, false},
+		{"steal above 1 rejected", func(c *Config) error { return c.SetInterferenceLimits(1, 1.5) }, true},
+		{"negative p2p rejected", func(c *Config) error { return c.SetMinP2PBandwidthGBs(-1) }, true},
+		{"clock fraction above 1 rejected", func(c *Config) error { return c.SetMinClockFraction(1.5) }, true},
+		{"degraded fraction", func(c *Config) error { return c.SetDegradedFraction(0.9) }, false},
+		{"zero stage timeout rejected", func(c *Config) error { return c.SetStageTimeouts(time.Second, 0, time.Second, time.Second) }, true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewConfig(validSnapshot())
+			err := tc.set(c)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr && c.Snapshot() != validSnapshot() {
+				t.Errorf("rejected update modified config: %+v", c.Snapshot())
+			}
+		})
+	}
+}
+
+func TestSnapshotJSONUsesMilliseconds(t *testing.T) {
+	t.Parallel()
+
+	b, err := json.Marshal(validSnapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"straggler_threshold_ms":35`, `"gemm_run_timeout_ms":60000`, `"max_cv":0.2`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("snapshot JSON missing %s: %s", want, b)
+		}
+	}
+}
+
+func TestRuntimeThresholdChangeRecordsProvenance(t *testing.T) {
+	t.Parallel()
+
+	s := validSnapshot()
+	s.ThresholdProvenance = Provenance{Source: SourceDetected, GPUName: "NVIDIA H100 80GB HBM3", Matched: "H100"}
+	c := NewConfig(s)
+
+	if err := c.SetMaxCV(0.15); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ThresholdProvenance(); got.Source != SourceDetected || got.MaxCVSource != SourceRuntime {
+		t.Errorf("provenance after SetMaxCV = %+v, want latency source kept and CV source runtime", got)
+	}
+
+	if err := c.SetStragglerThreshold(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	p := c.ThresholdProvenance()
+	if p.Source != SourceRuntime || p.GPUName != "" || p.ResolvedAt.IsZero() {
+		t.Errorf("provenance after runtime change = %+v", p)
+	}
+}
+
+func TestEnvMaxCVPerArchitecture(t *testing.T) {
+	h100 := archCalibrations[1]
+	detected := Provenance{Source: SourceDetected, Matched: "H100"}
+
+	cases := []struct {
+		name       string
+		env        map[string]string
+		cal        archCalibration
+		prov       Provenance
+		wantCV     float64
+		wantSource ThresholdSource
+	}{
+		{name: "calibrated", cal: h100, prov: detected, wantCV: 0.12, wantSource: SourceDetected},
+		{name: "fleet-wide override", env: map[string]string{"PULSE_CV_MAX": "0.3"}, cal: h100, prov: detected, wantCV: 0.3, wantSource: SourceEnv},
+		{name: "per-SKU override beats fleet-wide", env: map[string]string{"PULSE_CV_MAX": "0.3", "PULSE_CV_MAX_H100": "0.4"}, cal: h100, prov: detected, wantCV: 0.4, wantSource: SourceEnv},
+		{name: "other SKU's override ignored", env: map[string]string{"PULSE_CV_MAX_B200": "0.5"}, cal: h100, prov: detected, wantCV: 0.12, wantSource: SourceDetected},
+		{name: "unknown GPU", cal: fallbackCalibration, prov: Provenance{Source: SourceFallback}, wantCV: 0.20, wantSource: SourceFallback},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range append(ArchKeys(), "") {
+				name := "PULSE_CV_MAX"
+				if key != "" {
+					name = CVOverrideEnv(key)
+				}
+				t.Setenv(name, tc.env[name])
+			}
+			cv, source := envMaxCV(tc.cal, tc.prov)
+			if cv != tc.wantCV || source != tc.wantSource {
+				t.Errorf("envMaxCV = %v (%s), want %v (%s)", cv, source, tc.wantCV, tc.wantSource)
+			}
+		})
+	}
+}
+
+func TestActiveChecks(t *testing.T) {
+	t.Setenv(XIDCodesEnv, "79,48")
+	t.Setenv(ClockResetEnv, "false")
+	t.Setenv(CooldownRetriesEnv, "0")
+	t.Setenv(CooldownWaitEnv, "90s")
+	t.Setenv(ExternalChecksEnv, "/opt/checks/ib.sh")
+
+	c := ActiveChecks()
+	if !slices.Equal(c.XIDCodes, []int{48, 79}) || c.ClockReset || c.CooldownRetries != 0 || c.CooldownWaitMS != 90000 ||
+		!slices.Equal(c.ExternalChecks, []string{"/opt/checks/ib.sh"}) {
+		t.Errorf("ActiveChecks = %+v", c)
+	}
+
+	t.Setenv(XIDCodesEnv, "none")
+	if c := ActiveChecks(); c.XIDCodes == nil || len(c.XIDCodes) != 0 {
+		t.Errorf("XIDCodes = %v with the scan off, want an empty list", c.XIDCodes)
+	}
+}
@@ -0,0 +1,134 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Clock throttle reasons, the bits of NVML's clocks event reasons mask
+// (nvmlClocksEventReason*) and of nvidia-smi's clocks_throttle_reasons.active.
+const (
+	throttleGPUIdle           uint64 = 0x1
+	throttleAppClocksSetting  uint64 = 0x2
+	throttleSWPowerCap        uint64 = 0x4
+	throttleHWSlowdown        uint64 = 0x8
+	throttleSyncBoost         uint64 = 0x10
+	throttleSWThermalSlowdown uint64 = 0x20
+	throttleHWThermalSlowdown uint64 = 0x40
+	throttleHWPowerBrake      uint64 = 0x80
+	throttleDisplayClocks     uint64 = 0x100
+)
+
+// throttleNames names each reason in failure messages, in bit order.
+var throttleNames = []struct {
+	bit  uint64
+	name string
+}{
+	{throttleGPUIdle, "gpu_idle"},
+	{throttleAppClocksSetting, "applications_clocks_setting"},
+	{throttleSWPowerCap, "sw_power_cap"},
+	{throttleHWSlowdown, "hw_slowdown"},
+	{throttleSyncBoost, "sync_boost"},
+	{throttleSWThermalSlowdown, "sw_thermal_slowdown"},
+	{throttleHWThermalSlowdown, "hw_thermal_slowdown"},
+	{throttleHWPowerBrake, "hw_power_brake_slowdown"},
+	{throttleDisplayClocks, "display_clock_setting"},
+}
+
+// errThermalSlowdown marks a clock check failed on the hardware thermal
+// slowdown: the GPU is over its shutdown-margin temperature and the board is
+// pulling its clocks down, which a clock reset does not undo.
+var errThermalSlowdown = errors.New("HW thermal slowdown active")
+
+// throttleReasons names the reasons set in mask, in bit order. Unknown bits
+// are named by value.
+func throttleReasons(mask uint64) []string {
+	var names []string
+	for _, r := range throttleNames {
+		if mask&r.bit != 0 {
+			names = append(names, r.name)
+			mask &^= r.bit
+		}
+	}
+	if mask != 0 {
+		names = append(names, fmt.Sprintf("0x%x", mask))
+	}
+	return names
+}
+
+// throttleSuffix describes the reasons active in mask for a clock failure,
+// or returns "" if none are.
+func throttleSuffix(mask uint64) string {
+	if mask == 0 {
+		return ""
+	}
+	return " (active throttle reasons: " + strings.Join(throttleReasons(mask), ", ") + ")"
+}
+
+type throttleQuery func(ctx context.Context, dev int) (uint64, error)
+
+// sampleThrottle polls dev's active throttle reasons during its runs like
+// sampleUtilization, and returns every reason seen active in any read. The
+// board clears most reasons the moment the load stops, so only a read taken
+// while the GEMM runs shows them: poll is NVML only, and without NVML just
+// the final read straight after the last run counts. gpu_idle is dropped, as
+// a read between runs can catch it. Stop returns nil if no read succeeded.
+func sampleThrottle(dev int, poll, final throttleQuery) (stop func() *uint64) {
+	stopPeak := samplePeak(dev, poll, final, func(seen *uint64, mask uint64) {
+		*seen |= mask
+	})
+	return func() *uint64 {
+		seen := stopPeak()
+		if seen != nil {
+			*seen &^= throttleGPUIdle
+		}
+		return seen
+	}
+}
+
+// queryThrottleReason reads dev's active clock throttle reasons mask through
+// NVML, or nvidia-smi when NVML is unavailable.
+func queryThrottleReason(ctx context.Context, dev int) (uint64, error) {
+	mask, err := nvmlThrottleReason(ctx, dev)
+	if !errors.Is(err, errNVMLUnavailable) {
+		return mask, err
+	}
+	out, err := exec.CommandContext(ctx,
+		"nvidia-smi",
+		"--query-gpu=clocks_throttle_reasons.active",
+		"--format=csv,noheader",
+		"--id="+strconv.Itoa(dev),
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	return parseThrottleReason(string(out))
+}
+
+// parseThrottleReason parses nvidia-smi's clocks_throttle_reasons.active
+// output, a hex mask such as 0x0000000000000040. "N/A" and "[Not Supported]"
+// are errors: the device does not report its reasons.
+func parseThrottleReason(out string) (uint64, error) {
+	line := strings.TrimSpace(out)
+	hex, ok := strings.CutPrefix(line, "0x")
+	if !ok {
+		return 0, fmt.Errorf("nvidia-smi: throttle reasons %q", line)
+	}
+	mask, err := strconv.ParseUint(hex, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("nvidia-smi: unexpected throttle reasons %q", line)
+	}
+	return mask, nil
+}
+
+// throttleAt returns device i's mask, zero when it was not read.
+func throttleAt(masks []uint64, i int) uint64 {
+	if i < len(masks) {
+		return masks[i]
+	}
+	return 0
+}
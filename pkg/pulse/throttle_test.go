@@ -0,0 +1,126 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseThrottleReason(t *testing.T) {
+	mask, err := parseThrottleReason("0x0000000000000048\n")
+	if err != nil || mask != 0x48 {
+		t.Errorf("parseThrottleReason = %#x, %v, want 0x48", mask, err)
+	}
+	if got := throttleReasons(0x48 | 0x1000); !slices.Equal(got, []string{"hw_slowdown", "hw_thermal_slowdown", "0x1000"}) {
+		t.Errorf("throttleReasons = %q", got)
+	}
+	for _, out := range []string{"[Not Supported]\n", "Active\n", "0xzz\n"} {
+		if _, err := parseThrottleReason(out); err == nil {
+			t.Errorf("parseThrottleReason(%q) accepted a non-mask", out)
+		}
+	}
+}
+
+func TestSampleThrottle(t *testing.T) {
+	t.Parallel()
+
+	// The power cap is only seen mid-run; the read after the runs has
+	// the GPU idle again.
+	var polls atomic.Int32
+	poll := func(context.Context, int) (uint64, error) {
+		if polls.Add(1) == 2 {
+			return throttleSWPowerCap, nil
+		}
+		return 0, nil
+	}
+	final := func(context.Context, int) (uint64, error) { return throttleGPUIdle, nil }
+	stop := sampleThrottle(0, poll, final)
+	time.Sleep(5 * utilizationInterval / 2)
+	if got := stop(); got == nil || *got != throttleSWPowerCap {
+		t.Errorf("sampled = %v, want sw_power_cap from the runs and gpu_idle dropped", got)
+	}
+
+	failed := func(context.Context, int) (uint64, error) { return 0, errors.New("nvidia-smi: not found") }
+	if got := sampleThrottle(0, failed, failed)(); got != nil {
+		t.Errorf("sampled with no successful read = %v, want nil", *got)
+	}
+}
+
+func TestValidateClocksThrottle(t *testing.T) {
+	cases := []struct {
+		name        string
+		clock       string
+		throttle    []uint64 // sampled during the runs
+		wantErr     string
+		wantThermal bool
+		wantReasons []string
+	}{
+		{"full clocks", "1980", []uint64{0}, "", false, nil},
+		{"power capped below the floor", "600", []uint64{throttleSWPowerCap}, "(active throttle reasons: sw_power_cap)", false, []string{"sw_power_cap"}},
+		{"thermal slowdown above the floor", "1500", []uint64{throttleHWSlowdown | throttleHWThermalSlowdown}, "HW thermal slowdown active under load, SM clock now 1500MHz of max 1980MHz (active throttle reasons: hw_slowdown, hw_thermal_slowdown)", true, []string{"hw_slowdown", "hw_thermal_slowdown"}},
+		{"below the floor, reasons unread", "600", nil, "SM clock 600MHz below", false, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("PATH", dir)
+			writeCheck(t, dir, "nvidia-smi", `echo "`+tc.clock+`, 1980, 40, 0, 0, GPU-0, 1320, 00000000:01:00.0, 0"`)
+
+			var g gaps
+			err := validateClocks(validSnapshot(), &g, tc.throttle)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateClocks = %v, want a pass", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("validateClocks = %v, want %q", err, tc.wantErr)
+			}
+			if !errors.Is(err, ErrClockDerated) {
+				t.Errorf("validateClocks = %v, want ErrClockDerated", err)
+			}
+			if errors.Is(err, errThermalSlowdown) != tc.wantThermal {
+				t.Errorf("thermal slowdown = %v, want %v", errors.Is(err, errThermalSlowdown), tc.wantThermal)
+			}
+			var detail *PulseFailure
+			if !errors.As(err, &detail) || !slices.Equal(detail.ThrottleReasons, tc.wantReasons) || detail.GPU == nil {
+				t.Errorf("failure = %+v, want GPU-0 with throttle reasons %q", detail, tc.wantReasons)
+			}
+		})
+	}
+}
+
+func TestThrottleReasonsCrossRunnerProtocol(t *testing.T) {
+	failure := &PulseFailure{
+		Cause:           fmt.Errorf("%w: post-pulse GPU 0: %w: SM clock 600MHz below 50%% of max 1980MHz", ErrStragglerDetected, ErrClockDerated),
+		MeasuredValue:   600,
+		ThresholdValue:  500,
+		Unit:            "ms",
+		ThrottleReasons: []string{"sw_power_cap"},
+	}
+	var remote *PulseFailure
+	if err := NewRunnerResult(0, nil, failure).Err(); !errors.As(err, &remote) || !slices.Equal(remote.ThrottleReasons, failure.ThrottleReasons) {
+		t.Errorf("throttle reasons after the runner protocol = %+v, want %q", remote, failure.ThrottleReasons)
+	}
+}
+
+func TestValidateClocksResetSkipsThermal(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	t.Setenv("STATE", dir)
+	writeCheck(t, dir, "nvidia-smi", `case "$1" in
+--reset-*) : >"$STATE/reset"; exit 0 ;;
+esac
+echo "1800, 1980, 40, 0, 0, GPU-0, 1320, 00000000:01:00.0, 0"`)
+
+	reset, err := validateClocksReset(validSnapshot(), &gaps{}, []uint64{throttleHWThermalSlowdown}, func() ([]uint64, error) { t.Error("load ran"); return nil, nil })
+	if !errors.Is(err, errThermalSlowdown) || reset != nil {
+		t.Errorf("validateClocksReset = %+v, %v; want the thermal slowdown and no reset", reset, err)
+	}
+}
@@ -0,0 +1,82 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StageCall is a timed stage call in progress, as listed by InFlightStages.
+type StageCall struct {
+	Stage   string    `json:"stage"`
+	Started time.Time `json:"started"`
+	// Abandoned is set once the call outlived its timeout: the pulse has
+	// moved on but the goroutine is still blocked, usually in the driver.
+	Abandoned bool `json:"abandoned,omitempty"`
+}
+
+// stageCalls tracks the calls withStageTimeout is running, abandoned ones
+// included, until they return.
+var stageCalls = struct {
+	sync.Mutex
+	next  uint64
+	calls map[uint64]*StageCall
+}{calls: make(map[uint64]*StageCall)}
+
+// InFlightStages returns the stage calls running in this process, oldest
+// first. A hung CUDA call shows here as an abandoned gemm_run or p2p_link
+// that never returns.
+func InFlightStages() []StageCall {
+	stageCalls.Lock()
+	out := make([]StageCall, 0, len(stageCalls.calls))
+	for _, c := range stageCalls.calls {
+		out = append(out, *c)
+	}
+	stageCalls.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Started.Before(out[j].Started) })
+	return out
+}
+
+// withStageTimeout runs fn on its own goroutine and returns a stage timeout
+// failure if it has not finished within limit. CGO calls cannot be
+// interrupted, so a timed-out fn is abandoned rather than cancelled; callers
+// must return immediately so no further work is queued on the hung device.
+// The call is listed by InFlightStages until fn returns.
+func withStageTimeout(stage string, limit time.Duration, fn func() error) error {
+	call := &StageCall{Stage: stage, Started: time.Now().UTC()}
+	stageCalls.Lock()
+	id := stageCalls.next
+	stageCalls.next++
+	stageCalls.calls[id] = call
+	stageCalls.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		err := fn()
+		stageCalls.Lock()
+		delete(stageCalls.calls, id)
+		stageCalls.Unlock()
+		done <- err
+	}()
+
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		stageCalls.Lock()
+		call.Abandoned = true
+		stageCalls.Unlock()
+		return stageTimeout(stage, limit)
+	}
+}
+
+// isDeadline reports whether err came from a context deadline — used to tell
+// a hung nvidia-smi apart from an absent one.
+func isDeadline(ctx context.Context, err error) bool {
+	return err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
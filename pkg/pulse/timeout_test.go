@@ -0,0 +1,35 @@
+package pulse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInFlightStages(t *testing.T) {
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	err := withStageTimeout("gemm_run", 10*time.Millisecond, func() error {
+		defer close(returned)
+		<-release
+		return nil
+	})
+	if !errors.Is(err, ErrStageTimeout) {
+		t.Fatalf("withStageTimeout = %v, want a stage timeout", err)
+	}
+
+	calls := InFlightStages()
+	if len(calls) != 1 || calls[0].Stage != "gemm_run" || !calls[0].Abandoned {
+		t.Fatalf("InFlightStages = %+v, want one abandoned gemm_run", calls)
+	}
+
+	close(release)
+	<-returned
+	deadline := time.Now().Add(time.Second)
+	for len(InFlightStages()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("InFlightStages = %+v after the call returned, want none", InFlightStages())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
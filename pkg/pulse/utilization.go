@@ -0,0 +1,152 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Utilization is a device's peak SM and memory utilization during its GEMM
+// runs, as fractions 0–1. A pulse that passes without driving the device
+// near full utilization did not really stress it, e.g. because the GEMM is
+// too small for the SKU.
+type Utilization struct {
+	SM     float64 `json:"sm"`
+	Memory float64 `json:"memory"`
+}
+
+const (
+	// utilizationInterval is how often a device is polled during its runs.
+	utilizationInterval = 100 * time.Millisecond
+	// utilizationTimeout bounds one poll, so a wedged query cannot hold up
+	// the pulse past the device's runs.
+	utilizationTimeout = time.Second
+)
+
+type utilizationQuery func(ctx context.Context, dev int) (Utilization, error)
+
+// sampleUtilization polls dev with poll in the background until the returned
+// stop is called, then reads it once more with final and returns the peaks.
+// The last read matters: NVML reports utilization over a trailing sample
+// period of up to a second, longer than a fast device's runs. poll is meant
+// for NVML only and stops at its first error, so no nvidia-smi process is
+// spawned while the runs are timed and the host sampled. Stop returns nil if
+// no read succeeded.
+func sampleUtilization(dev int, poll, final utilizationQuery) (stop func() *Utilization) {
+	return samplePeak(dev, poll, final, func(peak *Utilization, u Utilization) {
+		peak.SM = max(peak.SM, u.SM)
+		peak.Memory = max(peak.Memory, u.Memory)
+	})
+}
+
+// samplePeak is the sampling loop behind sampleUtilization and samplePower:
+// the first read becomes the peak, and merge folds each later one into it.
+func samplePeak[T any](dev int, poll, final func(context.Context, int) (T, error), merge func(peak *T, v T)) (stop func() *T) {
+	var (
+		mu   sync.Mutex
+		peak *T
+	)
+	read := func(ctx context.Context, query func(context.Context, int) (T, error)) error {
+		ctx, cancel := context.WithTimeout(ctx, utilizationTimeout)
+		defer cancel()
+		v, err := query(ctx, dev)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if peak == nil {
+			peak = &v
+		} else {
+			merge(peak, v)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(utilizationInterval)
+		defer t.Stop()
+		for {
+			if err := read(ctx, poll); err != nil && ctx.Err() == nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+
+	return func() *T {
+		cancel()
+		<-done
+		_ = read(context.Background(), final)
+		mu.Lock()
+		defer mu.Unlock()
+		return peak
+	}
+}
+
+// checkUtilization returns an ErrInconclusive failure for the first device
+// whose peak SM utilization stayed below floor, or nil. Devices whose
+// utilization could not be read are not judged, and a zero floor disables
+// the check.
+func checkUtilization(devices []DeviceResult, floor float64) error {
+	if floor <= 0 {
+		return nil
+	}
+	for _, d := range devices {
+		if d.Utilization == nil || d.Utilization.SM >= floor {
+			continue
+		}
+		return &PulseFailure{
+			Cause:          fmt.Errorf("GPU %d: %w: peak SM utilization %.0f%% below the %.0f%% floor", d.Device, ErrInconclusive, 100*d.Utilization.SM, 100*floor),
+			MeasuredValue:  d.Utilization.SM,
+			ThresholdValue: floor,
+			Unit:           "util",
+		}
+	}
+	return nil
+}
+
+// queryUtilization reads dev's utilization through NVML, or nvidia-smi when
+// NVML is unavailable.
+func queryUtilization(ctx context.Context, dev int) (Utilization, error) {
+	u, err := nvmlUtilization(ctx, dev)
+	if !errors.Is(err, errNVMLUnavailable) {
+		return u, err
+	}
+	out, err := exec.CommandContext(ctx,
+		"nvidia-smi",
+		"--query-gpu=utilization.gpu,utilization.memory",
+		"--format=csv,noheader,nounits",
+		"--id="+strconv.Itoa(dev),
+	).Output()
+	if err != nil {
+		return Utilization{}, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	return parseUtilization(string(out))
+}
+
+// parseUtilization parses nvidia-smi's "<sm %>, <memory %>" line.
+func parseUtilization(line string) (Utilization, error) {
+	fields := strings.Split(strings.TrimSpace(line), ", ")
+	if len(fields) != 2 {
+		return Utilization{}, fmt.Errorf("nvidia-smi: unexpected utilization %q", line)
+	}
+	sm, err1 := strconv.Atoi(fields[0])
+	mem, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil {
+		return Utilization{}, fmt.Errorf("nvidia-smi: unexpected utilization %q", line)
+	}
+	return Utilization{SM: float64(sm) / 100, Memory: float64(mem) / 100}, nil
+}
@@ -0,0 +1,98 @@
+package pulse
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/justin-oleary/straggler-shield/pkg/metrics"
+)
+
+func TestSampleUtilization(t *testing.T) {
+	t.Parallel()
+
+	var polls atomic.Int32
+	poll := func(context.Context, int) (Utilization, error) {
+		if polls.Add(1) == 1 {
+			return Utilization{SM: 0.97, Memory: 0.40}, nil
+		}
+		return Utilization{SM: 0.10, Memory: 0.05}, nil
+	}
+	final := func(context.Context, int) (Utilization, error) {
+		return Utilization{SM: 0.50, Memory: 0.62}, nil
+	}
+	stop := sampleUtilization(0, poll, final)
+	time.Sleep(5 * utilizationInterval / 2)
+	got := stop()
+	if got == nil || *got != (Utilization{SM: 0.97, Memory: 0.62}) {
+		t.Errorf("peak = %+v, want SM from the first poll and memory from the final read", got)
+	}
+	if n := polls.Load(); n < 2 {
+		t.Errorf("polled %d time(s) in 2.5 intervals, want at least 2", n)
+	}
+
+	unavailable := func(context.Context, int) (Utilization, error) { return Utilization{}, errNVMLUnavailable }
+	failed := func(context.Context, int) (Utilization, error) {
+		return Utilization{}, errors.New("nvidia-smi: not found")
+	}
+	if got := sampleUtilization(0, unavailable, failed)(); got != nil {
+		t.Errorf("peak with no successful read = %+v, want nil", got)
+	}
+}
+
+func TestParseUtilization(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseUtilization("98, 41\n")
+	if err != nil || got != (Utilization{SM: 0.98, Memory: 0.41}) {
+		t.Errorf("parseUtilization = %+v, %v", got, err)
+	}
+	for _, line := range []string{"[N/A], [N/A]", "98", ""} {
+		if _, err := parseUtilization(line); err == nil {
+			t.Errorf("parseUtilization(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+func TestRecordUtilization(t *testing.T) {
+	t.Parallel()
+
+	const device = 96 // unused by other tests
+	res := NewRunnerResult(30*time.Millisecond, []RunnerDevice{{
+		Device: device, MeanNS: (30 * time.Millisecond).Nanoseconds(), CV: 0.01,
+		Utilization: &Utilization{SM: 0.35, Memory: 0.2},
+	}}, nil)
+	res.Record(NewPulseID())
+
+	if got := testutil.ToFloat64(metrics.PulseSMUtilization.WithLabelValues("96")); got != 0.35 {
+		t.Errorf("SM utilization gauge = %v, want 0.35", got)
+	}
+	if got := res.PulseReport("").Devices[0].Utilization; got == nil || got.Memory != 0.2 {
+		t.Errorf("rebuilt report utilization = %+v, want the runner's", got)
+	}
+}
+
+func TestCheckUtilization(t *testing.T) {
+	t.Parallel()
+
+	devices := []DeviceResult{
+		{Device: 0, Utilization: &Utilization{SM: 0.97}},
+		{Device: 1}, // not sampled
+		{Device: 2, Utilization: &Utilization{SM: 0.42}},
+	}
+	err := checkUtilization(devices, 0.6)
+	var detail *PulseFailure
+	if !errors.Is(err, ErrInconclusive) || !errors.As(err, &detail) || detail.MeasuredValue != 0.42 {
+		t.Fatalf("checkUtilization = %v, want GPU 2 inconclusive at 0.42", err)
+	}
+	if err := checkUtilization(devices, 0); err != nil {
+		t.Errorf("disabled floor: %v", err)
+	}
+	if err := checkUtilization(devices[:2], 0.6); err != nil {
+		t.Errorf("devices at or above the floor: %v", err)
+	}
+}
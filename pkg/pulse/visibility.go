@@ -0,0 +1,117 @@
+package pulse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ExpectedGPUsEnv passes the node's advertised GPU count to an isolated
+// runner, the counterpart of WithExpectedGPUs.
+const ExpectedGPUsEnv = "PULSE_EXPECTED_GPUS"
+
+type expectedGPUsKey struct{}
+
+// WithExpectedGPUs returns a context carrying the number of GPUs the node
+// advertises (its nvidia.com/gpu capacity). The pulse fails with
+// ErrGPUsNotVisible if the process running it cannot see that many.
+func WithExpectedGPUs(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, expectedGPUsKey{}, n)
+}
+
+// ExpectedGPUsFrom returns the count set by WithExpectedGPUs, or 0 (unknown,
+// check skipped).
+func ExpectedGPUsFrom(ctx context.Context) int {
+	n, _ := ctx.Value(expectedGPUsKey{}).(int)
+	return n
+}
+
+// expectedGPUsFromEnv reads ExpectedGPUsEnv in an isolated runner.
+func expectedGPUsFromEnv() int {
+	n, _ := strconv.Atoi(os.Getenv(ExpectedGPUsEnv))
+	return n
+}
+
+// viewCount is one view's GPU count in checkVisibility.
+type viewCount struct {
+	name string
+	n    int
+}
+
+var nvidiaDevRe = regexp.MustCompile(`^nvidia[0-9]+$`)
+
+// checkVisibility verifies that this container can reach every GPU the node
+// advertises. A DaemonSet that requests one nvidia.com/gpu, or a runtime that
+// truncates NVIDIA_VISIBLE_DEVICES, leaves the pulse validating a subset and
+// declaring the whole node healthy. Three views are compared with expected:
+//   - cuda: devices the CUDA runtime enumerated
+//   - env: entries in NVIDIA_VISIBLE_DEVICES ("all" or unset is not limiting)
+//   - dev: /dev/nvidiaN files the device cgroup lets us open
+func checkVisibility(expected, cudaCount int, visibleEnv, devDir string) error {
+	if expected <= 0 {
+		return nil
+	}
+	counts := []viewCount{{"cuda", cudaCount}}
+	if n, ok := envVisibleCount(visibleEnv); ok {
+		counts = append(counts, viewCount{"env", n})
+	}
+	counts = append(counts, viewCount{"dev", openableDevices(devDir)})
+
+	var short []string
+	fewest := expected
+	for _, c := range counts {
+		if c.n < expected {
+			short = append(short, fmt.Sprintf("%s=%d", c.name, c.n))
+			fewest = min(fewest, c.n)
+		}
+	}
+	if len(short) == 0 {
+		return nil
+	}
+	return &PulseFailure{
+		Cause: fmt.Errorf("%w: node advertises %d, container sees %s", ErrGPUsNotVisible,
+			expected, strings.Join(short, ", ")),
+		MeasuredValue:  float64(fewest),
+		ThresholdValue: float64(expected),
+		Unit:           "gpus",
+	}
+}
+
+// envVisibleCount interprets NVIDIA_VISIBLE_DEVICES. ok is false when the
+// value does not limit visibility (unset, empty, "all").
+func envVisibleCount(v string) (n int, ok bool) {
+	switch v = strings.TrimSpace(v); v {
+	case "", "all":
+		return 0, false
+	case "none", "void":
+		return 0, true
+	}
+	return len(strings.Split(v, ",")), true
+}
+
+// openableDevices counts /dev/nvidiaN nodes that can actually be opened; a
+// node present in the mount namespace but denied by the device cgroup fails
+// with EPERM and does not count.
+func openableDevices(devDir string) int {
+	entries, err := os.ReadDir(devDir)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, e := range entries {
+		if !nvidiaDevRe.MatchString(e.Name()) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(devDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		f.Close()
+		n++
+	}
+	return n
+}
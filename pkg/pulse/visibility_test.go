@@ -0,0 +1,63 @@
+package pulse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckVisibility(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		expected int
+		cuda     int
+		env      string
+		devFiles int
+		wantErr  bool
+	}{
+		{name: "expected unknown — skipped", expected: 0, cuda: 1, devFiles: 1},
+		{name: "all eight visible", expected: 8, cuda: 8, env: "all", devFiles: 8},
+		{
+			// Default DaemonSet mistake: nvidia.com/gpu: 1 makes the device
+			// plugin expose a single GPU.
+			name: "device plugin assigned one GPU", expected: 8, cuda: 1,
+			env: "GPU-6c1a1d4e-0c1e-4b8c-9c5e-2f3d4a5b6c7d", devFiles: 1, wantErr: true,
+		},
+		{
+			name: "cgroup hides devices CUDA never opened", expected: 4, cuda: 4,
+			env: "0,1,2,3", devFiles: 2, wantErr: true,
+		},
+		{name: "visibility env truncated", expected: 4, cuda: 4, env: "0,1", devFiles: 4, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for i := 0; i < tc.devFiles; i++ {
+				if err := os.WriteFile(filepath.Join(dir, "nvidia"+string(rune('0'+i))), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Control nodes must not count as GPUs.
+			for _, extra := range []string{"nvidiactl", "nvidia-uvm"} {
+				if err := os.WriteFile(filepath.Join(dir, extra), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := checkVisibility(tc.expected, tc.cuda, tc.env, dir)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr && !errors.Is(err, ErrGPUsNotVisible) {
+				t.Errorf("err = %v, want ErrGPUsNotVisible", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package pulse
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// WebAssembly checks run in-process, for clusters where exec'ing operator
+// binaries is not acceptable. A module gets no WASI: no files, network,
+// clock, environment or processes. Its only view of the node is the host API
+// below, it runs under the external check timeout, and its memory is capped
+// at WASMMemoryEnv.
+//
+// The module imports from "straggler_shield":
+//
+//	report_len() -> i32               size of the pulse report JSON
+//	read_report(ptr i32) -> i32       copy the report JSON to ptr; 0 on success
+//	set_verdict(ptr i32, len i32) -> i32
+//	                                  hand back a CheckOutput JSON; 0 on success
+//
+// and exports "memory" and "check" (no parameters, no results). check reads
+// the report, decides, and calls set_verdict once; the verdict is judged
+// exactly like an executable check's stdout. The report is the pulse so far:
+// every stage before the external checks, including earlier checks' results.
+
+// WASMHostModule is the import module name of the host API.
+const WASMHostModule = "straggler_shield"
+
+// WASMMemoryEnv caps a WASM check's linear memory, in MiB (default 64).
+const WASMMemoryEnv = "PULSE_WASM_MEMORY_MB"
+
+// maxVerdictBytes caps the verdict a module may hand back.
+const maxVerdictBytes = 64 << 10
+
+// errNoWASM is the error for a WASM check in a binary built without the
+// wazero tag. The check gave no result, so the pulse is inconclusive.
+var errNoWASM = errors.New("built without wasm support")
+
+// wasmMemoryPages is the memory limit in 64 KiB WebAssembly pages.
+func wasmMemoryPages() uint32 {
+	mb := 64
+	if n, err := strconv.Atoi(os.Getenv(WASMMemoryEnv)); err == nil && n > 0 {
+		mb = n
+	}
+	return uint32(mb) * 16
+}
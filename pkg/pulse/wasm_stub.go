@@ -0,0 +1,11 @@
+//go:build !wazero
+
+package pulse
+
+import "context"
+
+// WASMSupported reports whether this binary can run WASM checks. Build with
+// the wazero tag to enable them.
+const WASMSupported = false
+
+func runWASMCheck(context.Context, string, *PulseReport) ([]byte, error) { return nil, errNoWASM }
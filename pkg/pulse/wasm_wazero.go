@@ -0,0 +1,83 @@
+//go:build wazero
+
+package pulse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// WASMSupported reports whether this binary can run WASM checks.
+const WASMSupported = true
+
+// runWASMCheck runs the module at path with the host API in wasm.go and
+// returns the verdict it set. The runtime is closed when ctx ends, which
+// aborts a module stuck in a loop.
+func runWASMCheck(ctx context.Context, path string, report *PulseReport) ([]byte, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	input, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("marshal report: %w", err)
+	}
+
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(wasmMemoryPages()).
+		WithCloseOnContextDone(true))
+	defer rt.Close(context.Background())
+
+	var verdict []byte
+	if _, err := rt.NewHostModuleBuilder(WASMHostModule).
+		NewFunctionBuilder().
+		WithFunc(func(context.Context) uint32 { return uint32(len(input)) }).
+		Export("report_len").
+		NewFunctionBuilder().
+		WithFunc(func(_ context.Context, m api.Module, ptr uint32) uint32 {
+			if !m.Memory().Write(ptr, input) {
+				return 1
+			}
+			return 0
+		}).
+		Export("read_report").
+		NewFunctionBuilder().
+		WithFunc(func(_ context.Context, m api.Module, ptr, size uint32) uint32 {
+			b, ok := m.Memory().Read(ptr, size)
+			if !ok || size > maxVerdictBytes {
+				return 1
+			}
+			verdict = append([]byte(nil), b...)
+			return 0
+		}).
+		Export("set_verdict").
+		Instantiate(ctx); err != nil {
+		return nil, fmt.Errorf("host module: %w", err)
+	}
+
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("compile: %w", err)
+	}
+	mod, err := rt.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("check").WithStartFunctions())
+	if err != nil {
+		return nil, fmt.Errorf("instantiate: %w", err)
+	}
+	check := mod.ExportedFunction("check")
+	if check == nil {
+		return nil, errors.New("module exports no check function")
+	}
+	if _, err := check.Call(ctx); err != nil {
+		return nil, fmt.Errorf("check: %w", err)
+	}
+	if verdict == nil {
+		return nil, errors.New("module set no verdict")
+	}
+	return verdict, nil
+}
@@ -0,0 +1,96 @@
+//go:build wazero
+
+package pulse
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// checkModule assembles the smallest module the host API accepts: its check
+// hands verdict back through set_verdict from a data segment at address 0.
+// With exportCheck false it exports no check function.
+func checkModule(verdict string, exportCheck bool) []byte {
+	section := func(id byte, content ...byte) []byte {
+		return append([]byte{id, byte(len(content))}, content...)
+	}
+	name := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+	cat := func(parts ...[]byte) []byte {
+		var b []byte
+		for _, p := range parts {
+			b = append(b, p...)
+		}
+		return b
+	}
+
+	exports := cat([]byte{1}, name("memory"), []byte{0x02, 0})
+	if exportCheck {
+		exports = cat([]byte{2}, name("memory"), []byte{0x02, 0}, name("check"), []byte{0x00, 1})
+	}
+	body := []byte{
+		0,       // no locals
+		0x41, 0, // i32.const 0
+		0x41, byte(len(verdict)), // i32.const len(verdict)
+		0x10, 0, // call set_verdict
+		0x1a, // drop
+		0x0b, // end
+	}
+	return cat(
+		[]byte("\x00asm\x01\x00\x00\x00"),
+		// (i32, i32) -> i32 for set_verdict; () -> () for check.
+		section(1, 2, 0x60, 2, 0x7f, 0x7f, 1, 0x7f, 0x60, 0, 0),
+		section(2, cat([]byte{1}, name(WASMHostModule), name("set_verdict"), []byte{0x00, 0})...),
+		section(3, 1, 1),
+		section(5, 1, 0, 1),
+		section(7, exports...),
+		section(10, cat([]byte{1, byte(len(body))}, body)...),
+		section(11, cat([]byte{1, 0, 0x41, 0, 0x0b, byte(len(verdict))}, []byte(verdict))...),
+	)
+}
+
+func TestRunWASMCheck(t *testing.T) {
+	t.Parallel()
+
+	write := func(t *testing.T, module []byte) string {
+		path := filepath.Join(t.TempDir(), "policy.wasm")
+		if err := os.WriteFile(path, module, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("verdict", func(t *testing.T) {
+		t.Parallel()
+		path := write(t, checkModule(`{"passed":false,"message":"hbm"}`, true))
+		out, err := runExternalCheck(&PulseReport{PulseID: "p1"}, path, time.Minute)
+		if err != nil {
+			t.Fatalf("runExternalCheck: %v", err)
+		}
+		if out.Passed || out.Message != "hbm" {
+			t.Errorf("output = %+v, want a failed check with message hbm", out)
+		}
+	})
+
+	t.Run("no check export", func(t *testing.T) {
+		t.Parallel()
+		path := write(t, checkModule(`{"passed":true}`, false))
+		var unchecked gaps
+		if err := runExternalChecks(&PulseReport{}, []string{path}, time.Minute, &unchecked); err != nil {
+			t.Fatalf("err = %v, want the check recorded as a gap", err)
+		}
+		if err := unchecked.err(); !IsInconclusive(err) {
+			t.Errorf("gaps = %v, want inconclusive", err)
+		}
+	})
+
+	t.Run("not a module", func(t *testing.T) {
+		t.Parallel()
+		path := write(t, []byte("#!/bin/sh\n"))
+		if _, err := runWASMCheck(context.Background(), path, &PulseReport{}); err == nil {
+			t.Error("runWASMCheck accepted a shell script")
+		}
+	})
+}
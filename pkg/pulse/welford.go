@@ -0,0 +1,49 @@
+package pulse
+
+import (
+	"math"
+	"time"
+)
+
+// runStats accumulates a device's run durations in one pass with Welford's
+// online algorithm: the mean and the sum of squared deviations are updated
+// as each run completes, stable even when the spread is tiny next to the
+// mean. The durations themselves are kept for the report.
+type runStats struct {
+	runs []time.Duration
+	mean float64 // ns
+	m2   float64 // sum of squared deviations from the mean, ns²
+}
+
+// add records one run.
+func (s *runStats) add(d time.Duration) {
+	s.runs = append(s.runs, d)
+	delta := float64(d) - s.mean
+	s.mean += delta / float64(len(s.runs))
+	s.m2 += delta * (float64(d) - s.mean)
+}
+
+// Mean returns the mean run duration, zero before the first run.
+func (s *runStats) Mean() time.Duration { return time.Duration(s.mean) }
+
+// CV returns the coefficient of variation (σ/μ), with σ the population
+// standard deviation of the runs. Zero before the first run or for a zero
+// mean.
+func (s *runStats) CV() float64 {
+	if len(s.runs) == 0 || s.mean <= 0 {
+		return 0
+	}
+	return math.Sqrt(s.m2/float64(len(s.runs))) / s.mean
+}
+
+// RunsNS returns the run durations in nanoseconds, in run order.
+func (s *runStats) RunsNS() []int64 {
+	if len(s.runs) == 0 {
+		return nil
+	}
+	ns := make([]int64, len(s.runs))
+	for i, d := range s.runs {
+		ns[i] = d.Nanoseconds()
+	}
+	return ns
+}
@@ -0,0 +1,46 @@
+package pulse
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunStats(t *testing.T) {
+	t.Parallel()
+
+	var s runStats
+	if s.Mean() != 0 || s.CV() != 0 || s.RunsNS() != nil {
+		t.Errorf("empty stats = %v, %v, %v; want zeros", s.Mean(), s.CV(), s.RunsNS())
+	}
+
+	runs := []time.Duration{30 * time.Millisecond, 34 * time.Millisecond, 26 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}
+	for _, d := range runs {
+		s.add(d)
+	}
+	if s.Mean() != 30*time.Millisecond {
+		t.Errorf("Mean = %v, want 30ms", s.Mean())
+	}
+	// Population σ = sqrt((16+16)/5) ms ≈ 2.53ms.
+	if want := math.Sqrt(32.0/5) / 30; math.Abs(s.CV()-want) > 1e-9 {
+		t.Errorf("CV = %v, want %v", s.CV(), want)
+	}
+	if got := s.RunsNS(); !slices.Equal(got, []int64{30e6, 34e6, 26e6, 30e6, 30e6}) {
+		t.Errorf("RunsNS = %v, want the runs in order", got)
+	}
+}
+
+func TestRunStatsStable(t *testing.T) {
+	t.Parallel()
+
+	// A 1ns spread on an hour-long mean: a naive sum of squares loses it
+	// to rounding, Welford's update does not.
+	var s runStats
+	for _, d := range []time.Duration{time.Hour, time.Hour + 2, time.Hour} {
+		s.add(d)
+	}
+	if cv := s.CV(); cv <= 0 || cv > 1e-12 {
+		t.Errorf("CV = %g, want a tiny positive value", cv)
+	}
+}
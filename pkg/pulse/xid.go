@@ -0,0 +1,147 @@
+package pulse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XIDCodesEnv lists the NVIDIA XID codes that quarantine a node when the
+// kernel log shows one since boot: comma-separated integers, or "none" to
+// skip the scan. Unset uses DefaultXIDCodes.
+const XIDCodesEnv = "PULSE_XID_CODES"
+
+// DefaultXIDCodes are XIDs that indicate failing hardware rather than a
+// faulting application, per NVIDIA's XID catalogue:
+//
+//	48       double-bit ECC error
+//	61, 62   internal micro-controller breakpoint / halt
+//	64       ECC page retirement or row remapping failure
+//	74       NVLink error
+//	79       GPU has fallen off the bus
+//	92       high single-bit ECC error rate
+//	95       uncontained ECC error
+//	119, 120 GSP RPC timeout / GSP error
+//
+// Application faults such as XID 13, 31 and 43 are left out: the next job
+// on the GPU is unaffected.
+var DefaultXIDCodes = []int{48, 61, 62, 64, 74, 79, 92, 95, 119, 120}
+
+// xidRe matches the NVIDIA driver's XID report, e.g.
+//
+//	NVRM: Xid (PCI:0000:3b:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.
+var xidRe = regexp.MustCompile(`NVRM: Xid \((?:PCI:)?([^)]*)\): (\d+),\s*(.*)`)
+
+// xidEvent is one XID report from the kernel log.
+type xidEvent struct {
+	Code    int
+	PCI     string
+	Message string
+}
+
+// ParseXIDCodes parses an XIDCodesEnv value into the set of codes that
+// quarantine. "none" returns an empty set.
+func ParseXIDCodes(s string) (map[int]bool, error) {
+	codes := map[int]bool{}
+	if strings.TrimSpace(s) == "none" {
+		return codes, nil
+	}
+	for _, f := range strings.Split(s, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || code <= 0 {
+			return nil, fmt.Errorf("XID code %q: want a positive integer", f)
+		}
+		codes[code] = true
+	}
+	return codes, nil
+}
+
+// xidCodes returns the quarantining XID codes from XIDCodesEnv. A malformed
+// value falls back to DefaultXIDCodes; the agent rejects it at startup.
+func xidCodes() map[int]bool {
+	if s := os.Getenv(XIDCodesEnv); s != "" {
+		if codes, err := ParseXIDCodes(s); err == nil {
+			return codes
+		}
+	}
+	codes := make(map[int]bool, len(DefaultXIDCodes))
+	for _, c := range DefaultXIDCodes {
+		codes[c] = true
+	}
+	return codes
+}
+
+// parseXIDs returns the XID reports in log, in order. log is /dev/kmsg
+// records or dmesg output; anything that is not an XID report is skipped.
+func parseXIDs(log string) []xidEvent {
+	var events []xidEvent
+	for _, line := range strings.Split(log, "\n") {
+		m := xidRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		code, _ := strconv.Atoi(m[2])
+		events = append(events, xidEvent{Code: code, PCI: m[1], Message: strings.TrimSpace(m[3])})
+	}
+	return events
+}
+
+// checkXIDs fails with ErrXIDEvent if log holds an XID in codes. The first
+// such XID is reported, with the other quarantining codes seen listed after
+// it. MeasuredValue is the XID code.
+func checkXIDs(log string, codes map[int]bool) error {
+	var first *xidEvent
+	seen := map[int]bool{}
+	events := parseXIDs(log)
+	for i := range events {
+		if !codes[events[i].Code] {
+			continue
+		}
+		if first == nil {
+			first = &events[i]
+		}
+		seen[events[i].Code] = true
+	}
+	if first == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("XID %d on PCI %s since last boot (%s)", first.Code, first.PCI, first.Message)
+	if len(seen) > 1 {
+		others := make([]int, 0, len(seen))
+		for c := range seen {
+			if c != first.Code {
+				others = append(others, c)
+			}
+		}
+		sort.Ints(others)
+		list := make([]string, len(others))
+		for i, c := range others {
+			list[i] = strconv.Itoa(c)
+		}
+		msg += "; also XID " + strings.Join(list, ", ")
+	}
+	return &PulseFailure{
+		Cause:         fmt.Errorf("pre-flight: %w: %s", ErrXIDEvent, msg),
+		MeasuredValue: float64(first.Code),
+		Unit:          "xid",
+	}
+}
+
+// kernelLog returns the kernel log since boot: /dev/kmsg where it can be
+// read, dmesg otherwise. Both need CAP_SYSLOG when kernel.dmesg_restrict is
+// set. The dmesg process is killed when ctx expires.
+func kernelLog(ctx context.Context) (string, error) {
+	if log, err := readKmsg(); err == nil {
+		return log, nil
+	}
+	out, err := exec.CommandContext(ctx, "dmesg").Output()
+	if err != nil {
+		return "", fmt.Errorf("dmesg: %w", err)
+	}
+	return string(out), nil
+}
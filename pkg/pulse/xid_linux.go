@@ -0,0 +1,45 @@
+package pulse
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+)
+
+// readKmsg reads every record in the kernel ring buffer from /dev/kmsg and
+// returns the NVIDIA driver's, one message per line. The device is read
+// without blocking: os.File would park on it waiting for new records.
+func readKmsg() (string, error) {
+	fd, err := syscall.Open("/dev/kmsg", syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return "", err
+	}
+	defer syscall.Close(fd)
+
+	var log strings.Builder
+	buf := make([]byte, 8192) // a record is at most ~1KiB
+	for {
+		n, err := syscall.Read(fd, buf)
+		switch err {
+		case nil:
+		case syscall.EAGAIN:
+			return log.String(), nil // caught up with the buffer
+		case syscall.EPIPE, syscall.EINTR:
+			continue // a record was overwritten under us, or a signal; read on
+		default:
+			return "", err
+		}
+		if n <= 0 {
+			return log.String(), nil
+		}
+		// "prio,seq,usec,flags;message\n" followed by optional
+		// continuation lines.
+		rec := buf[:n]
+		if i := bytes.IndexByte(rec, ';'); i >= 0 {
+			rec = rec[i+1:]
+		}
+		if bytes.Contains(rec, []byte("NVRM:")) {
+			log.Write(rec)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package pulse
+
+import "errors"
+
+// readKmsg is unsupported off Linux; kernelLog falls back to dmesg.
+func readKmsg() (string, error) { return "", errors.New("/dev/kmsg is only available on linux") }
@@ -0,0 +1,79 @@
+package pulse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckXIDs(t *testing.T) {
+	t.Parallel()
+
+	const kmsg = `NVRM: loading NVIDIA UNIX x86_64 Kernel Module  550.54.15
+NVRM: Xid (PCI:0000:3b:00): 13, pid=4242, name=python, Graphics SM Warp Exception on (GPC 0, TPC 0, SM 0)
+NVRM: Xid (PCI:0000:86:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.
+NVRM: Xid (PCI:0000:3b:00): 48, pid=4242, name=python, An uncorrectable double bit error (DBE) has been detected on GPU in the framebuffer at partition 6, subpartition 0.
+`
+	const dmesg = `[  812.004213] NVRM: Xid (PCI:0000:3b:00): 13, pid=4242, name=python, Graphics Exception`
+	defaults, _ := ParseXIDCodes("48,79")
+
+	cases := []struct {
+		name     string
+		log      string
+		codes    map[int]bool
+		wantCode float64 // 0 = pass
+		wantMsg  string
+	}{
+		{name: "first hardware XID reported, others listed", log: kmsg, codes: defaults, wantCode: 79, wantMsg: "XID 79 on PCI 0000:86:00 since last boot (pid='<unknown>', name=<unknown>, GPU has fallen off the bus.); also XID 48"},
+		{name: "application XID alone passes", log: dmesg, codes: defaults},
+		{name: "application XID quarantines when listed", log: dmesg, codes: map[int]bool{13: true}, wantCode: 13},
+		{name: "no codes disables the check", log: kmsg, codes: map[int]bool{}},
+		{name: "empty log passes", codes: defaults},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkXIDs(tc.log, tc.codes)
+			if tc.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("checkXIDs = %v, want pass", err)
+				}
+				return
+			}
+			var detail *PulseFailure
+			if !errors.Is(err, ErrXIDEvent) || !errors.As(err, &detail) || detail.MeasuredValue != tc.wantCode || detail.Unit != "xid" {
+				t.Fatalf("checkXIDs = %#v, want ErrXIDEvent with XID %v", err, tc.wantCode)
+			}
+			if !strings.Contains(err.Error(), tc.wantMsg) {
+				t.Errorf("message = %q, want it to contain %q", err, tc.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParseXIDCodes(t *testing.T) {
+	t.Parallel()
+
+	if codes, err := ParseXIDCodes(" 79, 48 "); err != nil || len(codes) != 2 || !codes[79] || !codes[48] {
+		t.Errorf("ParseXIDCodes = %v, %v", codes, err)
+	}
+	if codes, err := ParseXIDCodes("none"); err != nil || len(codes) != 0 {
+		t.Errorf("none = %v, %v, want an empty set", codes, err)
+	}
+	for _, bad := range []string{"79,", "xid79", "-1"} {
+		if _, err := ParseXIDCodes(bad); err == nil {
+			t.Errorf("ParseXIDCodes(%q) accepted", bad)
+		}
+	}
+}
+
+func TestXIDKindCrossesProcessBoundary(t *testing.T) {
+	t.Parallel()
+
+	res := NewRunnerResult(0, nil, checkXIDs("NVRM: Xid (PCI:0000:86:00): 79, GPU has fallen off the bus.", map[int]bool{79: true}))
+	if err := res.Err(); !errors.Is(err, ErrXIDEvent) {
+		t.Errorf("decoded error %v does not match ErrXIDEvent", err)
+	}
+}
@@ -0,0 +1,376 @@
+//go:build e2e
+
+// Package e2e runs straggler-shield against a real API server: a kind
+// cluster with the agent deployed from deploy/ on simulated pulses
+// (PULSE_ISOLATION=sim). For each failure class the suite sets
+// SimFailureAnnotation on a node, requests a pulse, and checks the taint,
+// GPUStraggler condition and Event the class must produce. A passing pulse
+// then has to clear them again.
+//
+//	go test -tags e2e ./test/e2e -v -timeout 30m
+//
+// Environment:
+//
+//	E2E_KUBECONFIG    run against this cluster instead of creating one with kind
+//	E2E_SKIP_DEPLOY   the agent is already deployed, e.g. from a fork or your
+//	                  Helm values; it must run with PULSE_ISOLATION=sim
+//	E2E_IMAGE         agent image to deploy; built from test/e2e/Dockerfile
+//	                  and loaded into kind if unset
+//	E2E_TAINT_KEY     the deployed QUARANTINE_TAINT_KEY, if not the default
+//	E2E_KEEP_CLUSTER  keep the kind cluster after the run
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/justin-oleary/straggler-shield/pkg/k8s"
+	"github.com/justin-oleary/straggler-shield/pkg/policy"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	clusterName = "straggler-shield-e2e"
+	namespace   = "straggler-shield"
+	defaultTag  = "straggler-shield:e2e"
+
+	// gpuNodeLabel matches the DaemonSet's nodeSelector in deploy/.
+	gpuNodeLabel = "nvidia.com/gpu.present"
+
+	// timeout bounds each wait for the agent to act on a node.
+	timeout = 2 * time.Minute
+)
+
+// repoRoot is relative to this package, where go test runs.
+var repoRoot = filepath.Join("..", "..")
+
+var client kubernetes.Interface
+
+func TestMain(m *testing.M) {
+	code, err := run(m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "e2e:", err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+func run(m *testing.M) (int, error) {
+	ctx := context.Background()
+	kubeconfig := os.Getenv("E2E_KUBECONFIG")
+	if kubeconfig == "" {
+		dir, err := os.MkdirTemp("", "straggler-shield-e2e")
+		if err != nil {
+			return 0, err
+		}
+		defer os.RemoveAll(dir)
+		kubeconfig = filepath.Join(dir, "kubeconfig")
+		if err := command("kind", "create", "cluster", "--name", clusterName,
+			"--config", filepath.Join(repoRoot, "test", "e2e", "kind.yaml"),
+			"--kubeconfig", kubeconfig, "--wait", "3m"); err != nil {
+			return 0, err
+		}
+		if os.Getenv("E2E_KEEP_CLUSTER") == "" {
+			defer command("kind", "delete", "cluster", "--name", clusterName)
+		}
+	}
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return 0, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	if client, err = kubernetes.NewForConfig(cfg); err != nil {
+		return 0, err
+	}
+	if os.Getenv("E2E_SKIP_DEPLOY") == "" {
+		if err := deploy(ctx, kubeconfig); err != nil {
+			return 0, err
+		}
+	}
+	if err := waitForAgents(ctx); err != nil {
+		return 0, err
+	}
+	return m.Run(), nil
+}
+
+// command runs name with args, streaming its output to the test log.
+func command(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// deploy labels every worker as a GPU node, applies deploy/rbac.yaml and
+// deploys deploy/daemonset.yaml adapted for kind: the agent image, no NVIDIA
+// runtime class, and simulated pulses.
+func deploy(ctx context.Context, kubeconfig string) error {
+	image := os.Getenv("E2E_IMAGE")
+	if image == "" {
+		image = defaultTag
+		if err := command("docker", "build", "-f", filepath.Join(repoRoot, "test", "e2e", "Dockerfile"), "-t", image, repoRoot); err != nil {
+			return err
+		}
+		if err := command("kind", "load", "docker-image", image, "--name", clusterName); err != nil {
+			return err
+		}
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: "!node-role.kubernetes.io/control-plane"})
+	if err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	for _, n := range nodes.Items {
+		patch := fmt.Sprintf(`{"metadata":{"labels":{%q:"true"}}}`, gpuNodeLabel)
+		if _, err := client.CoreV1().Nodes().Patch(ctx, n.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("label node %s: %w", n.Name, err)
+		}
+	}
+
+	if err := command("kubectl", "--kubeconfig", kubeconfig, "apply", "-f", filepath.Join(repoRoot, "deploy", "rbac.yaml")); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(repoRoot, "deploy", "daemonset.yaml"))
+	if err != nil {
+		return err
+	}
+	var ds appsv1.DaemonSet
+	if err := yaml.Unmarshal(data, &ds); err != nil {
+		return fmt.Errorf("parse daemonset.yaml: %w", err)
+	}
+	spec := &ds.Spec.Template.Spec
+	spec.RuntimeClassName = nil
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Image, spec.InitContainers[i].ImagePullPolicy = image, corev1.PullIfNotPresent
+	}
+	for i := range spec.Containers {
+		spec.Containers[i].Image, spec.Containers[i].ImagePullPolicy = image, corev1.PullIfNotPresent
+		spec.Containers[i].Env = append(spec.Containers[i].Env, corev1.EnvVar{Name: "PULSE_ISOLATION", Value: "sim"})
+	}
+	_, err = client.AppsV1().DaemonSets(namespace).Create(ctx, &ds, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		current, gerr := client.AppsV1().DaemonSets(namespace).Get(ctx, ds.Name, metav1.GetOptions{})
+		if gerr != nil {
+			return gerr
+		}
+		ds.ResourceVersion = current.ResourceVersion
+		_, err = client.AppsV1().DaemonSets(namespace).Update(ctx, &ds, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("deploy agent: %w", err)
+	}
+	return nil
+}
+
+// waitForAgents waits until an agent is ready on every GPU node.
+func waitForAgents(ctx context.Context) error {
+	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
+		list, err := client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, ds := range list.Items {
+			s := ds.Status
+			if s.DesiredNumberScheduled > 0 && s.NumberReady == s.DesiredNumberScheduled && s.UpdatedNumberScheduled == s.DesiredNumberScheduled {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("agent DaemonSet in %s not ready: %w", namespace, err)
+	}
+	return nil
+}
+
+// gpuNode returns a node the agent runs on.
+func gpuNode(t *testing.T) string {
+	t.Helper()
+	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{LabelSelector: gpuNodeLabel + "=true"})
+	if err != nil || len(nodes.Items) == 0 {
+		t.Fatalf("no node labelled %s=true (err %v)", gpuNodeLabel, err)
+	}
+	return nodes.Items[0].Name
+}
+
+// requestPulse sets the node's simulated failure class ("" for a pass) and
+// PulseRequestAnnotation in one patch, so the pulse cannot see the old class.
+func requestPulse(t *testing.T, nodeName, class string) {
+	t.Helper()
+	var sim *string
+	if class != "" {
+		sim = &class
+	}
+	requested := time.Now().UTC().Format(time.RFC3339)
+	patch, _ := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": map[string]*string{
+		k8s.SimFailureAnnotation:   sim,
+		k8s.PulseRequestAnnotation: &requested,
+	}}})
+	if _, err := client.CoreV1().Nodes().Patch(context.Background(), nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		t.Fatalf("request pulse: %v", err)
+	}
+}
+
+// waitFor polls the node until check returns nil, failing the test with the
+// last mismatch after timeout.
+func waitFor(t *testing.T, nodeName string, check func(*corev1.Node) error) {
+	t.Helper()
+	var last error
+	err := wait.PollUntilContextTimeout(context.Background(), time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			last = err
+			return false, nil
+		}
+		if _, pending := node.Annotations[k8s.PulseRequestAnnotation]; pending {
+			last = errors.New("pulse request not yet handled")
+			return false, nil
+		}
+		last = check(node)
+		return last == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("%s: %v", nodeName, last)
+	}
+}
+
+// waitForEvent waits for an Event with reason on the node, recorded at or
+// after since.
+func waitForEvent(t *testing.T, nodeName, reason string, since time.Time) {
+	t.Helper()
+	since = since.Truncate(time.Second)
+	err := wait.PollUntilContextTimeout(context.Background(), time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		events, err := client.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			FieldSelector: "involvedObject.kind=Node,involvedObject.name=" + nodeName + ",reason=" + reason,
+		})
+		if err != nil {
+			return false, nil
+		}
+		for _, e := range events.Items {
+			if !e.LastTimestamp.Time.Before(since) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		t.Fatalf("%s: no %s Event since %v", nodeName, reason, since)
+	}
+}
+
+func taint(node *corev1.Node, key string) *corev1.Taint {
+	for i := range node.Spec.Taints {
+		if node.Spec.Taints[i].Key == key {
+			return &node.Spec.Taints[i]
+		}
+	}
+	return nil
+}
+
+func condition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == "GPUStraggler" {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// TestFailureClasses drives every simulated failure class through the
+// agent under the default policy: degrade classes get the degraded label and
+// PreferNoSchedule taint, everything else the quarantine taint, a
+// GPUStraggler=True condition and a StragglerQuarantined Event. A passing
+// pulse must then lift each.
+func TestFailureClasses(t *testing.T) {
+	nodeName := gpuNode(t)
+	taintKey := os.Getenv("E2E_TAINT_KEY")
+	if taintKey == "" {
+		taintKey = k8s.DefaultTaintKey
+	}
+	for _, class := range k8s.SimFailureClasses() {
+		class := class
+		t.Run(class, func(t *testing.T) {
+			severity := (*policy.Policy)(nil).SeverityFor(class)
+
+			start := time.Now()
+			requestPulse(t, nodeName, class)
+			switch severity {
+			case policy.SeverityDegrade:
+				waitFor(t, nodeName, func(node *corev1.Node) error {
+					if got := node.Labels[k8s.DegradedLabel]; got != class {
+						return fmt.Errorf("label %s = %q, want %q", k8s.DegradedLabel, got, class)
+					}
+					if tt := taint(node, k8s.DegradedLabel); tt == nil || tt.Effect != corev1.TaintEffectPreferNoSchedule {
+						return fmt.Errorf("taints %v, want %s:PreferNoSchedule", node.Spec.Taints, k8s.DegradedLabel)
+					}
+					if c := condition(node); c == nil || c.Status != corev1.ConditionFalse || c.Reason != "Degraded" {
+						return fmt.Errorf("GPUStraggler condition %+v, want False/Degraded", c)
+					}
+					return nil
+				})
+			case policy.SeverityWarn:
+				waitFor(t, nodeName, func(node *corev1.Node) error {
+					if c := condition(node); c == nil || c.Status != corev1.ConditionFalse || c.Reason != "WarnOnly" {
+						return fmt.Errorf("GPUStraggler condition %+v, want False/WarnOnly", c)
+					}
+					if tt := taint(node, taintKey); tt != nil {
+						return fmt.Errorf("quarantine taint present on a warn: %v", tt)
+					}
+					return nil
+				})
+			default:
+				waitFor(t, nodeName, func(node *corev1.Node) error {
+					if tt := taint(node, taintKey); tt == nil || tt.Effect != corev1.TaintEffectNoSchedule {
+						return fmt.Errorf("taints %v, want %s:NoSchedule", node.Spec.Taints, taintKey)
+					}
+					if c := condition(node); c == nil || c.Status != corev1.ConditionTrue || c.Reason != "StragglerDetected" {
+						return fmt.Errorf("GPUStraggler condition %+v, want True/StragglerDetected", c)
+					}
+					return nil
+				})
+				waitForEvent(t, nodeName, "StragglerQuarantined", start)
+			}
+			quarantined := severity == policy.SeverityQuarantine
+
+			start = time.Now()
+			requestPulse(t, nodeName, "")
+			waitFor(t, nodeName, func(node *corev1.Node) error {
+				if tt := taint(node, taintKey); tt != nil {
+					return fmt.Errorf("quarantine taint still present: %v", tt)
+				}
+				if tt := taint(node, k8s.DegradedLabel); tt != nil {
+					return fmt.Errorf("degraded taint still present: %v", tt)
+				}
+				if v, ok := node.Labels[k8s.DegradedLabel]; ok {
+					return fmt.Errorf("degraded label still present: %q", v)
+				}
+				if c := condition(node); quarantined && (c == nil || c.Status != corev1.ConditionFalse) {
+					return fmt.Errorf("GPUStraggler condition %+v, want False after a pass", c)
+				}
+				return nil
+			})
+			if quarantined {
+				waitForEvent(t, nodeName, "PulsePassed", start)
+			}
+		})
+	}
+}